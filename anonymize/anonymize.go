@@ -0,0 +1,303 @@
+// Package anonymize rewrites personal data in place, for refreshing staging
+// and development databases from production copies.
+//
+// Rules declare, per table, the transform applied to each sensitive
+// column. Run pages through every table on a unique key and updates the
+// rows batch by batch, each batch in a transaction of its own, reporting
+// its progress as it goes.
+package anonymize
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"slices"
+	"strings"
+
+	"github.com/oarkflow/squealx"
+)
+
+// ErrNoColumns is returned by Run for a Rule without columns.
+var ErrNoColumns = errors.New("anonymize: rule has no columns")
+
+// Rule declares how the columns of a table are anonymized.
+type Rule struct {
+	Table string
+	// Key is a unique column rows are paged on and updated by, "id" by
+	// default.
+	Key string
+	// Where, if set, restricts the rows anonymized to those matching the
+	// SQL condition.
+	Where string
+	// Columns maps the columns to anonymize to their transform.
+	Columns map[string]Transform
+}
+
+// Transform computes the anonymized values of a column. NULL values are
+// kept as they are, except by Nullify and Shuffle.
+type Transform struct {
+	shuffle bool
+	fn      func(v any) any
+}
+
+// Nullify sets the column to NULL.
+func Nullify() Transform {
+	return Transform{fn: func(any) any { return nil }}
+}
+
+// Hash replaces the value with the hex SHA-256 of salt and the value. Equal
+// values hash alike, so the column can still be joined and grouped on.
+func Hash(salt string) Transform {
+	return Transform{fn: func(v any) any {
+		if v == nil {
+			return nil
+		}
+		h := sha256.New()
+		h.Write([]byte(salt))
+		h.Write(valueBytes(v))
+		return hex.EncodeToString(h.Sum(nil))
+	}}
+}
+
+// Shuffle permutes the values of the column among the rows of each batch,
+// keeping its distribution while unlinking values from their rows.
+func Shuffle() Transform {
+	return Transform{shuffle: true}
+}
+
+// Func replaces the value with the result of fn.
+func Func(fn func(v any) any) Transform {
+	return Transform{fn: func(v any) any {
+		if v == nil {
+			return nil
+		}
+		return fn(v)
+	}}
+}
+
+// FakeKind is the kind of value generated by Faker.
+type FakeKind int
+
+const (
+	FakeFirstName FakeKind = iota
+	FakeLastName
+	FakeName
+	FakeEmail
+	FakePhone
+)
+
+var (
+	firstNames = []string{"Alex", "Sam", "Jordan", "Taylor", "Morgan", "Casey", "Riley", "Jamie", "Avery", "Quinn", "Drew", "Reese", "Skyler", "Cameron", "Rowan", "Emerson"}
+	lastNames  = []string{"Smith", "Garcia", "Chen", "Okafor", "Novak", "Silva", "Kowalski", "Haddad", "Tanaka", "Muller", "Rossi", "Larsen", "Moreau", "Singh", "Dubois", "Kim"}
+)
+
+// Faker replaces the value with a fake one of kind. The fake is derived
+// from the value, so that equal values are replaced alike across tables.
+func Faker(kind FakeKind) Transform {
+	return Transform{fn: func(v any) any {
+		if v == nil {
+			return nil
+		}
+		h := fnv.New64a()
+		h.Write(valueBytes(v))
+		r := rand.New(rand.NewSource(int64(h.Sum64())))
+		first, last := firstNames[r.Intn(len(firstNames))], lastNames[r.Intn(len(lastNames))]
+		switch kind {
+		case FakeFirstName:
+			return first
+		case FakeLastName:
+			return last
+		case FakeEmail:
+			return fmt.Sprintf("%s.%s.%d@example.com", strings.ToLower(first), strings.ToLower(last), r.Intn(100000))
+		case FakePhone:
+			return fmt.Sprintf("+1-555-%03d-%04d", r.Intn(1000), r.Intn(10000))
+		}
+		return first + " " + last
+	}}
+}
+
+func valueBytes(v any) []byte {
+	switch v := v.(type) {
+	case []byte:
+		return v
+	case string:
+		return []byte(v)
+	}
+	return []byte(fmt.Sprint(v))
+}
+
+// Progress reports the rows of a table anonymized so far.
+type Progress struct {
+	Table string
+	Rows  int
+	// Total is the number of rows to anonymize in the table, counted when
+	// its anonymization starts.
+	Total int
+}
+
+// Options configures Run.
+type Options struct {
+	// BatchSize is the number of rows updated per transaction, 1000 by
+	// default.
+	BatchSize int
+	// Progress is called once every batch is committed.
+	Progress func(p Progress)
+}
+
+// Run anonymizes the tables of rules, in order.
+func Run(ctx context.Context, db *squealx.DB, rules []Rule, opts Options) error {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1000
+	}
+	for _, rule := range rules {
+		if err := runRule(ctx, db, rule, opts); err != nil {
+			return fmt.Errorf("anonymize: table %s: %w", rule.Table, err)
+		}
+	}
+	return nil
+}
+
+func runRule(ctx context.Context, db *squealx.DB, rule Rule, opts Options) error {
+	if len(rule.Columns) == 0 {
+		return ErrNoColumns
+	}
+	if rule.Key == "" {
+		rule.Key = "id"
+	}
+	dialect := squealx.Dialect(db.DriverName())
+	columns := make([]string, 0, len(rule.Columns))
+	for column := range rule.Columns {
+		columns = append(columns, column)
+	}
+	slices.Sort(columns)
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = squealx.QuoteIdent(dialect, column)
+	}
+	key := squealx.QuoteIdent(dialect, rule.Key)
+
+	progress := Progress{Table: rule.Table}
+	where := ""
+	if rule.Where != "" {
+		where = " WHERE " + rule.Where
+	}
+	if err := db.GetContext(ctx, &progress.Total, "SELECT COUNT(*) FROM "+rule.Table+where); err != nil {
+		return err
+	}
+
+	sets := make([]string, len(quoted))
+	for i, column := range quoted {
+		sets[i] = column + " = ?"
+	}
+	update := db.Rebind("UPDATE " + rule.Table + " SET " + strings.Join(sets, ", ") + " WHERE " + key + " = ?")
+	var after any
+	for {
+		rows, err := readBatch(ctx, db, dialect, rule, key, quoted, opts.BatchSize, after)
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		anonymizeBatch(rule, columns, rows)
+		if err := updateBatch(ctx, db, update, rows); err != nil {
+			return err
+		}
+		after = rows[len(rows)-1][0]
+		progress.Rows += len(rows)
+		if opts.Progress != nil {
+			opts.Progress(progress)
+		}
+		if len(rows) < opts.BatchSize {
+			return nil
+		}
+	}
+}
+
+// readBatch reads the key and columns of the batch of rows following the
+// key after, or the first batch when after is nil.
+func readBatch(ctx context.Context, db *squealx.DB, dialect string, rule Rule, key string, columns []string, size int, after any) ([][]any, error) {
+	var b strings.Builder
+	b.WriteString("SELECT ")
+	if dialect == squealx.DialectMSSQL {
+		fmt.Fprintf(&b, "TOP %d ", size)
+	}
+	b.WriteString(key + ", " + strings.Join(columns, ", ") + " FROM " + rule.Table)
+	var conds []string
+	var args []any
+	if rule.Where != "" {
+		conds = append(conds, "("+rule.Where+")")
+	}
+	if after != nil {
+		conds = append(conds, key+" > ?")
+		args = append(args, after)
+	}
+	if len(conds) > 0 {
+		b.WriteString(" WHERE " + strings.Join(conds, " AND "))
+	}
+	b.WriteString(" ORDER BY " + key)
+	if dialect != squealx.DialectMSSQL {
+		fmt.Fprintf(&b, " LIMIT %d", size)
+	}
+	rows, err := db.QueryxContext(ctx, db.Rebind(b.String()), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var batch [][]any
+	for rows.Next() {
+		values, err := rows.SliceScan()
+		if err != nil {
+			return nil, err
+		}
+		batch = append(batch, values)
+	}
+	return batch, rows.Err()
+}
+
+// anonymizeBatch replaces the column values of rows, which hold the key
+// followed by columns, with their anonymized values.
+func anonymizeBatch(rule Rule, columns []string, rows [][]any) {
+	for i, column := range columns {
+		t := rule.Columns[column]
+		if t.shuffle {
+			rand.Shuffle(len(rows), func(a, b int) {
+				rows[a][i+1], rows[b][i+1] = rows[b][i+1], rows[a][i+1]
+			})
+			continue
+		}
+		if t.fn == nil {
+			continue
+		}
+		for _, row := range rows {
+			row[i+1] = t.fn(row[i+1])
+		}
+	}
+}
+
+// updateBatch writes the anonymized rows in a transaction.
+func updateBatch(ctx context.Context, db *squealx.DB, update string, rows [][]any) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.PreparexContext(ctx, update)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+	args := make([]any, 0)
+	for _, row := range rows {
+		args = append(append(args[:0], row[1:]...), row[0])
+		if _, err := stmt.ExecContext(ctx, args...); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
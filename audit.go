@@ -0,0 +1,30 @@
+package squealx
+
+import (
+	"context"
+	"time"
+)
+
+// AuditEvent is a notable event reported to an AuditSink, such as a
+// replication anomaly found by a consistency check.
+type AuditEvent struct {
+	Kind   string
+	Time   time.Time
+	Query  string
+	Args   []any
+	Detail map[string]any
+}
+
+// AuditSink receives audit events. Implementations must be safe for
+// concurrent use.
+type AuditSink interface {
+	Audit(ctx context.Context, event AuditEvent)
+}
+
+// AuditFunc is an AuditSink calling itself.
+type AuditFunc func(ctx context.Context, event AuditEvent)
+
+// Audit calls f.
+func (f AuditFunc) Audit(ctx context.Context, event AuditEvent) {
+	f(ctx, event)
+}
@@ -0,0 +1,644 @@
+// Package backup dumps tables to and restores them from logical backups in
+// CSV, JSON lines or SQL INSERT statements. It runs through a squealx.DB, so
+// that small backup jobs share the application's connection pool instead of
+// shelling out to pg_dump or mysqldump.
+//
+// Dumps paged on a key column and restores both report checkpoints, from
+// which an interrupted job resumes.
+package backup
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oarkflow/squealx"
+)
+
+var (
+	// ErrUnknownFormat is returned for a Format other than CSV, JSONL and
+	// SQLInserts.
+	ErrUnknownFormat = errors.New("backup: unknown format")
+	// ErrNoTable is returned by Restore when CSV or JSONL input is given
+	// without RestoreOptions.Table.
+	ErrNoTable = errors.New("backup: table required to restore CSV and JSONL")
+)
+
+// Format is the encoding of a backup.
+type Format int
+
+const (
+	// CSV writes a header record of column names followed by one record per
+	// row. NULL is written as Null and binary values as \x followed by their
+	// hex digits. Text starting with a backslash gets another one, so that
+	// it cannot be read as either.
+	CSV Format = iota
+	// JSONL writes one JSON object per row and line, keyed by column.
+	// Binary values are written as {"$base64": "..."}.
+	JSONL
+	// SQLInserts writes one multi-row INSERT statement per batch, in the
+	// dialect of the database dumped.
+	SQLInserts
+)
+
+// Null is the CSV field standing for NULL.
+const Null = `\N`
+
+const defaultBatchSize = 1000
+
+// DumpOptions configures DumpTable.
+type DumpOptions struct {
+	// Key is a unique column the table is paged on, in batches of
+	// BatchSize rows ordered by it. Without Key the table is read in a
+	// single query and cannot be resumed.
+	Key string
+	// BatchSize is the number of rows per batch, 1000 by default.
+	BatchSize int
+	// After resumes an interrupted dump after the row whose key is After,
+	// as passed to Checkpoint. The CSV header is not written again.
+	After any
+	// Checkpoint is called once every batch is written, with the key of its
+	// last row. Returning an error stops the dump.
+	Checkpoint func(ctx context.Context, key any) error
+}
+
+// DumpTable writes the rows of table to w in format and returns the number
+// of rows written.
+func DumpTable(ctx context.Context, db *squealx.DB, table string, w io.Writer, format Format, opts DumpOptions) (int, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	dialect := squealx.Dialect(db.DriverName())
+	var enc encoder
+	switch format {
+	case CSV:
+		enc = &csvEncoder{w: csv.NewWriter(w), header: opts.After == nil}
+	case JSONL:
+		enc = &jsonEncoder{w: bufio.NewWriter(w)}
+	case SQLInserts:
+		enc = &sqlEncoder{w: w, dialect: dialect, table: table}
+	default:
+		return 0, ErrUnknownFormat
+	}
+	if opts.Key == "" {
+		n, _, err := dumpBatch(ctx, db, enc, "SELECT * FROM "+table, nil, "", opts.BatchSize)
+		return n, err
+	}
+	after := opts.After
+	total := 0
+	for {
+		query, args := db.KeysetQuery(table, opts.Key, opts.BatchSize, after)
+		n, last, err := dumpBatch(ctx, db, enc, query, args, opts.Key, 0)
+		total += n
+		if err != nil {
+			return total, err
+		}
+		if n == 0 {
+			return total, nil
+		}
+		after = last
+		if opts.Checkpoint != nil {
+			if err := opts.Checkpoint(ctx, after); err != nil {
+				return total, err
+			}
+		}
+		if n < opts.BatchSize {
+			return total, nil
+		}
+	}
+}
+
+// dumpBatch encodes the rows of query and returns their number and the
+// value of key in the last one. The encoder is flushed every flushEvery
+// rows when it is positive, and at the end.
+func dumpBatch(ctx context.Context, db *squealx.DB, enc encoder, query string, args []any, key string, flushEvery int) (int, any, error) {
+	rows, err := db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, nil, err
+	}
+	keyIndex := -1
+	if key != "" {
+		for i, column := range columns {
+			if strings.EqualFold(column, key) {
+				keyIndex = i
+				break
+			}
+		}
+		if keyIndex < 0 {
+			return 0, nil, fmt.Errorf("backup: key column %q not found", key)
+		}
+	}
+	if err := enc.columns(columns); err != nil {
+		return 0, nil, err
+	}
+	n := 0
+	var last any
+	for rows.Next() {
+		values, err := rows.SliceScan()
+		if err != nil {
+			return n, last, err
+		}
+		if err := enc.row(values); err != nil {
+			return n, last, err
+		}
+		if keyIndex >= 0 {
+			last = values[keyIndex]
+		}
+		n++
+		if flushEvery > 0 && n%flushEvery == 0 {
+			if err := enc.flush(); err != nil {
+				return n, last, err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return n, last, err
+	}
+	return n, last, enc.flush()
+}
+
+// encoder writes rows in a Format. columns is called before the rows of
+// every batch, flush at the end of a batch.
+type encoder interface {
+	columns(columns []string) error
+	row(values []any) error
+	flush() error
+}
+
+type csvEncoder struct {
+	w      *csv.Writer
+	header bool
+	record []string
+}
+
+func (e *csvEncoder) columns(columns []string) error {
+	e.record = make([]string, len(columns))
+	if !e.header {
+		return nil
+	}
+	e.header = false
+	return e.w.Write(columns)
+}
+
+func (e *csvEncoder) row(values []any) error {
+	for i, v := range values {
+		e.record[i] = csvField(v)
+	}
+	return e.w.Write(e.record)
+}
+
+func (e *csvEncoder) flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func csvField(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return Null
+	case string:
+		if strings.HasPrefix(v, `\`) {
+			return `\` + v
+		}
+		return v
+	case []byte:
+		return `\x` + hex.EncodeToString(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+// base64Key is the key of the JSON object standing for a binary value.
+const base64Key = "$base64"
+
+type jsonEncoder struct {
+	w    *bufio.Writer
+	keys [][]byte
+}
+
+func (e *jsonEncoder) columns(columns []string) error {
+	e.keys = make([][]byte, len(columns))
+	for i, column := range columns {
+		key, err := json.Marshal(column)
+		if err != nil {
+			return err
+		}
+		e.keys[i] = append(key, ':')
+	}
+	return nil
+}
+
+func (e *jsonEncoder) row(values []any) error {
+	e.w.WriteByte('{')
+	for i, v := range values {
+		if i > 0 {
+			e.w.WriteByte(',')
+		}
+		e.w.Write(e.keys[i])
+		if b, ok := v.([]byte); ok {
+			v = map[string]string{base64Key: base64.StdEncoding.EncodeToString(b)}
+		}
+		value, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		e.w.Write(value)
+	}
+	_, err := e.w.WriteString("}\n")
+	return err
+}
+
+func (e *jsonEncoder) flush() error {
+	return e.w.Flush()
+}
+
+type sqlEncoder struct {
+	w       io.Writer
+	dialect string
+	table   string
+	insert  string
+	values  strings.Builder
+	rows    int
+}
+
+func (e *sqlEncoder) columns(columns []string) error {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = squealx.QuoteIdent(e.dialect, column)
+	}
+	e.insert = "INSERT INTO " + e.table + " (" + strings.Join(quoted, ", ") + ") VALUES "
+	return nil
+}
+
+func (e *sqlEncoder) row(values []any) error {
+	if e.rows > 0 {
+		e.values.WriteString(", ")
+	}
+	e.values.WriteByte('(')
+	for i, v := range values {
+		if i > 0 {
+			e.values.WriteString(", ")
+		}
+		e.values.WriteString(literal(e.dialect, v))
+	}
+	e.values.WriteByte(')')
+	e.rows++
+	return nil
+}
+
+func (e *sqlEncoder) flush() error {
+	if e.rows == 0 {
+		return nil
+	}
+	_, err := io.WriteString(e.w, e.insert+e.values.String()+";\n")
+	e.values.Reset()
+	e.rows = 0
+	return err
+}
+
+// literal returns v as an SQL literal of dialect.
+func literal(dialect string, v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return quoteString(dialect, v)
+	case []byte:
+		switch dialect {
+		case squealx.DialectPostgres:
+			return `'\x` + hex.EncodeToString(v) + `'`
+		case squealx.DialectMSSQL:
+			return "0x" + hex.EncodeToString(v)
+		}
+		return "X'" + hex.EncodeToString(v) + "'"
+	case time.Time:
+		if dialect == squealx.DialectMySQL {
+			return "'" + v.UTC().Format("2006-01-02 15:04:05.999999") + "'"
+		}
+		return "'" + v.Format("2006-01-02 15:04:05.999999Z07:00") + "'"
+	case bool:
+		if dialect == squealx.DialectMSSQL || dialect == squealx.DialectSQLite {
+			if v {
+				return "1"
+			}
+			return "0"
+		}
+		return strings.ToUpper(strconv.FormatBool(v))
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case int, int8, int16, int32, uint, uint8, uint16, uint32, uint64, float32:
+		return fmt.Sprint(v)
+	}
+	return quoteString(dialect, fmt.Sprint(v))
+}
+
+func quoteString(dialect, s string) string {
+	s = strings.ReplaceAll(s, "'", "''")
+	if dialect == squealx.DialectMySQL {
+		s = strings.ReplaceAll(s, `\`, `\\`)
+	}
+	return "'" + s + "'"
+}
+
+// RestoreOptions configures Restore.
+type RestoreOptions struct {
+	// Format is the encoding of the backup.
+	Format Format
+	// Table receives the rows of CSV and JSONL backups. SQLInserts backups
+	// name their table.
+	Table string
+	// BatchSize is the number of records restored per transaction, 1000 by
+	// default. Records are rows for CSV and JSONL, statements for
+	// SQLInserts.
+	BatchSize int
+	// Skip resumes an interrupted restore, skipping the records already
+	// restored, as passed to Checkpoint.
+	Skip int
+	// Checkpoint is called once every batch is committed, with the number
+	// of records restored so far, Skip included. Returning an error stops
+	// the restore.
+	Checkpoint func(ctx context.Context, restored int) error
+}
+
+// Restore applies the backup read from r to db and returns the number of
+// records restored, RestoreOptions.Skip included. Every batch is applied in
+// a transaction of its own.
+func Restore(ctx context.Context, db *squealx.DB, r io.Reader, opts RestoreOptions) (int, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	var dec decoder
+	switch opts.Format {
+	case CSV:
+		dec = &csvDecoder{r: csv.NewReader(r)}
+	case JSONL:
+		d := json.NewDecoder(r)
+		d.UseNumber()
+		dec = &jsonDecoder{d: d}
+	case SQLInserts:
+		dec = &sqlDecoder{r: bufio.NewReader(r)}
+	default:
+		return 0, ErrUnknownFormat
+	}
+	if opts.Format != SQLInserts && opts.Table == "" {
+		return 0, ErrNoTable
+	}
+	dialect := squealx.Dialect(db.DriverName())
+	restored := 0
+	var batch []record
+	apply := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		tx, err := db.BeginTxx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if err := applyBatch(ctx, tx, dialect, opts.Table, batch, db.MaxBindParams()); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		restored += len(batch)
+		batch = batch[:0]
+		if opts.Checkpoint != nil {
+			return opts.Checkpoint(ctx, restored)
+		}
+		return nil
+	}
+	for {
+		rec, err := dec.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return restored, err
+		}
+		if restored < opts.Skip {
+			restored++
+			continue
+		}
+		batch = append(batch, rec)
+		if len(batch) >= opts.BatchSize {
+			if err := apply(); err != nil {
+				return restored, err
+			}
+		}
+	}
+	return restored, apply()
+}
+
+// record is a row of CSV and JSONL backups, or a statement of SQLInserts
+// backups.
+type record struct {
+	columns   []string
+	values    []any
+	statement string
+}
+
+type decoder interface {
+	// next returns the next record, or io.EOF.
+	next() (record, error)
+}
+
+type csvDecoder struct {
+	r       *csv.Reader
+	columns []string
+}
+
+func (d *csvDecoder) next() (record, error) {
+	if d.columns == nil {
+		header, err := d.r.Read()
+		if err != nil {
+			return record{}, err
+		}
+		d.columns = header
+	}
+	fields, err := d.r.Read()
+	if err != nil {
+		return record{}, err
+	}
+	values := make([]any, len(fields))
+	for i, field := range fields {
+		if values[i], err = csvValue(field); err != nil {
+			return record{}, err
+		}
+	}
+	return record{columns: d.columns, values: values}, nil
+}
+
+// csvValue reads a field written by csvField.
+func csvValue(field string) (any, error) {
+	switch {
+	case field == Null:
+		return nil, nil
+	case strings.HasPrefix(field, `\\`):
+		return field[1:], nil
+	case strings.HasPrefix(field, `\x`):
+		b, err := hex.DecodeString(field[2:])
+		if err != nil {
+			return nil, fmt.Errorf("backup: binary field: %w", err)
+		}
+		return b, nil
+	}
+	return field, nil
+}
+
+type jsonDecoder struct {
+	d *json.Decoder
+}
+
+func (d *jsonDecoder) next() (record, error) {
+	var object map[string]any
+	if err := d.d.Decode(&object); err != nil {
+		return record{}, err
+	}
+	rec := record{columns: make([]string, 0, len(object)), values: make([]any, 0, len(object))}
+	for column := range object {
+		rec.columns = append(rec.columns, column)
+	}
+	// Keys are sorted, so that rows with the same columns share an INSERT.
+	slices.Sort(rec.columns)
+	for _, column := range rec.columns {
+		switch v := object[column].(type) {
+		case json.Number:
+			rec.values = append(rec.values, v.String())
+		case map[string]any:
+			if encoded, ok := v[base64Key].(string); ok && len(v) == 1 {
+				b, err := base64.StdEncoding.DecodeString(encoded)
+				if err != nil {
+					return record{}, fmt.Errorf("backup: binary value of %s: %w", column, err)
+				}
+				rec.values = append(rec.values, b)
+				continue
+			}
+			b, err := json.Marshal(v)
+			if err != nil {
+				return record{}, err
+			}
+			rec.values = append(rec.values, string(b))
+		case []any:
+			b, err := json.Marshal(v)
+			if err != nil {
+				return record{}, err
+			}
+			rec.values = append(rec.values, string(b))
+		default:
+			rec.values = append(rec.values, v)
+		}
+	}
+	return rec, nil
+}
+
+type sqlDecoder struct {
+	r *bufio.Reader
+}
+
+// next returns the next statement, ending at a semicolon outside quotes.
+func (d *sqlDecoder) next() (record, error) {
+	var b strings.Builder
+	var quote rune
+	for {
+		c, _, err := d.r.ReadRune()
+		if err == io.EOF {
+			if s := strings.TrimSpace(b.String()); s != "" {
+				return record{statement: s}, nil
+			}
+			return record{}, io.EOF
+		}
+		if err != nil {
+			return record{}, err
+		}
+		switch {
+		case quote != 0:
+			// A doubled quote is read as closing and reopening.
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == ';':
+			if s := strings.TrimSpace(b.String()); s != "" {
+				return record{statement: s}, nil
+			}
+			b.Reset()
+			continue
+		}
+		b.WriteRune(c)
+	}
+}
+
+// applyBatch inserts the rows of batch into table, grouping consecutive rows
+// with the same columns into statements binding at most maxParams
+// parameters, or executes its statements.
+func applyBatch(ctx context.Context, tx *squealx.Tx, dialect, table string, batch []record, maxParams int) error {
+	for start := 0; start < len(batch); {
+		rec := batch[start]
+		if rec.statement != "" {
+			if _, err := tx.ExecContext(ctx, rec.statement); err != nil {
+				return err
+			}
+			start++
+			continue
+		}
+		end := start + 1
+		limit := max(1, maxParams/max(1, len(rec.columns)))
+		for end < len(batch) && end-start < limit && slices.Equal(batch[end].columns, rec.columns) {
+			end++
+		}
+		if err := insertRows(ctx, tx, dialect, table, batch[start:end]); err != nil {
+			return err
+		}
+		start = end
+	}
+	return nil
+}
+
+func insertRows(ctx context.Context, tx *squealx.Tx, dialect, table string, rows []record) error {
+	quoted := make([]string, len(rows[0].columns))
+	for i, column := range rows[0].columns {
+		quoted[i] = squealx.QuoteIdent(dialect, column)
+	}
+	placeholders := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(quoted)), ", ") + ")"
+	var b strings.Builder
+	b.WriteString("INSERT INTO " + table + " (" + strings.Join(quoted, ", ") + ") VALUES ")
+	args := make([]any, 0, len(rows)*len(quoted))
+	for i, row := range rows {
+		if len(row.values) != len(quoted) {
+			return fmt.Errorf("backup: record has %d values for %d columns", len(row.values), len(quoted))
+		}
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(placeholders)
+		args = append(args, row.values...)
+	}
+	_, err := tx.ExecContext(ctx, tx.Rebind(b.String()), args...)
+	return err
+}
@@ -0,0 +1,54 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/oarkflow/squealx"
+	_ "modernc.org/sqlite"
+)
+
+func TestDumpRestoreBinary(t *testing.T) {
+	blob := []byte{0x00, 0xff, 0xfe, '\\', 'x', '\n', 0x80}
+	for _, format := range []Format{CSV, JSONL, SQLInserts} {
+		db, err := squealx.Connect("sqlite", ":memory:", "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		db.SetMaxOpenConns(1)
+		defer db.Close()
+		db.MustExec(`CREATE TABLE files (id INTEGER PRIMARY KEY, name TEXT, data BLOB)`)
+		db.MustExec(`CREATE TABLE copies (id INTEGER PRIMARY KEY, name TEXT, data BLOB)`)
+		db.MustExec(`INSERT INTO files (id, name, data) VALUES (1, 'a', ?), (2, '\x00', NULL)`, blob)
+
+		ctx := context.Background()
+		var buf bytes.Buffer
+		if _, err := DumpTable(ctx, db, "files", &buf, format, DumpOptions{}); err != nil {
+			t.Fatalf("format %d: dump: %v", format, err)
+		}
+		table := "copies"
+		if format == SQLInserts {
+			db.MustExec(`DELETE FROM files`)
+			table = "files"
+		}
+		if _, err := Restore(ctx, db, &buf, RestoreOptions{Format: format, Table: table}); err != nil {
+			t.Fatalf("format %d: restore: %v", format, err)
+		}
+		var data []byte
+		if err := db.SQLDB.QueryRow(`SELECT data FROM ` + table + ` WHERE id = 1`).Scan(&data); err != nil {
+			t.Fatalf("format %d: %v", format, err)
+		}
+		if !bytes.Equal(data, blob) {
+			t.Errorf("format %d: data = %x, want %x", format, data, blob)
+		}
+		var name string
+		var null []byte
+		if err := db.SQLDB.QueryRow(`SELECT name, data FROM `+table+` WHERE id = 2`).Scan(&name, &null); err != nil {
+			t.Fatalf("format %d: %v", format, err)
+		}
+		if name != `\x00` || null != nil {
+			t.Errorf("format %d: row 2 = %q, %x, want text \\x00 and NULL", format, name, null)
+		}
+	}
+}
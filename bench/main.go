@@ -0,0 +1,195 @@
+// Command bench runs the squealx benchmark suite against SQLite and, when
+// their DSNs are set, PostgreSQL and MySQL, such as the containers of
+// docker-compose.yml. Results are printed in the format of `go test -bench`,
+// so runs can be compared with benchstat; see `make bench-compare`.
+//
+//	go run ./bench -count 6 > new.txt
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"runtime"
+	"testing"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+
+	"github.com/oarkflow/squealx"
+	"github.com/oarkflow/squealx/dbresolver"
+)
+
+type user struct {
+	ID        int64     `db:"id"`
+	Name      string    `db:"name"`
+	Email     string    `db:"email"`
+	Age       int       `db:"age"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type target struct {
+	name   string
+	driver string
+	dsn    string
+}
+
+type benchmark struct {
+	name string
+	fn   func(b *testing.B, db *squealx.DB)
+}
+
+var benchmarks = []benchmark{
+	{"ScanStructs", func(b *testing.B, db *squealx.DB) {
+		for i := 0; i < b.N; i++ {
+			var users []user
+			if err := db.Select(&users, "SELECT id, name, email, age, created_at FROM bench_users"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}},
+	{"ScanMaps", func(b *testing.B, db *squealx.DB) {
+		for i := 0; i < b.N; i++ {
+			var users []map[string]any
+			if err := db.Select(&users, "SELECT id, name, email, age, created_at FROM bench_users"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}},
+	{"Get", func(b *testing.B, db *squealx.DB) {
+		query := db.Rebind("SELECT id, name, email, age, created_at FROM bench_users WHERE id = ?")
+		for i := 0; i < b.N; i++ {
+			var u user
+			if err := db.Get(&u, query, i%*rows+1); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}},
+	{"NamedBinding", func(b *testing.B, db *squealx.DB) {
+		arg := map[string]any{"name": "user", "email": "user@example.com", "age": 30}
+		for i := 0; i < b.N; i++ {
+			if _, _, err := db.BindNamed("SELECT * FROM bench_users WHERE name = :name AND email = :email AND age > :age", arg); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}},
+	{"InExpansion", func(b *testing.B, db *squealx.DB) {
+		ids := make([]int, 100)
+		for i := range ids {
+			ids[i] = i + 1
+		}
+		for i := 0; i < b.N; i++ {
+			if _, _, err := squealx.In("SELECT * FROM bench_users WHERE id IN (?) AND age > ?", ids, 30); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}},
+	{"Paginate", func(b *testing.B, db *squealx.DB) {
+		for i := 0; i < b.N; i++ {
+			var users []user
+			resp := squealx.Paginate(db, "SELECT id, name, email, age, created_at FROM bench_users ORDER BY id", &users, squealx.Paging{Page: 2, Limit: 50})
+			if resp.Error != nil {
+				b.Fatal(resp.Error)
+			}
+		}
+	}},
+	{"ResolverRouting", func(b *testing.B, db *squealx.DB) {
+		r, err := dbresolver.New(dbresolver.WithMasterDBs(db), dbresolver.WithReplicaDBs(db))
+		if err != nil {
+			b.Fatal(err)
+		}
+		query := db.Rebind("SELECT id, name, email, age, created_at FROM bench_users WHERE id = ?")
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			var u user
+			if err := r.GetContext(context.Background(), &u, query, i%*rows+1); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}},
+}
+
+var (
+	rows  = flag.Int("rows", 1000, "number of rows in the benchmark table")
+	count = flag.Int("count", 1, "number of times to run each benchmark")
+	run   = flag.String("run", ".", "regular expression selecting the benchmarks to run")
+)
+
+func main() {
+	sqliteDSN := flag.String("sqlite", "file:bench.db?mode=memory&cache=shared", "SQLite DSN, empty to skip")
+	postgresDSN := flag.String("postgres", os.Getenv("BENCH_POSTGRES_DSN"), "PostgreSQL DSN, empty to skip")
+	mysqlDSN := flag.String("mysql", os.Getenv("BENCH_MYSQL_DSN"), "MySQL DSN, empty to skip")
+	flag.Parse()
+	filter, err := regexp.Compile(*run)
+	if err != nil {
+		log.Fatal(err)
+	}
+	targets := []target{
+		{"sqlite", "sqlite", *sqliteDSN},
+		{"postgres", "pgx", *postgresDSN},
+		{"mysql", "mysql", *mysqlDSN},
+	}
+	fmt.Printf("goos: %s\ngoarch: %s\npkg: github.com/oarkflow/squealx/bench\n", runtime.GOOS, runtime.GOARCH)
+	for _, t := range targets {
+		if t.dsn == "" {
+			continue
+		}
+		db, err := squealx.Connect(t.driver, t.dsn, t.name)
+		if err != nil {
+			log.Fatalf("%s: %v", t.name, err)
+		}
+		if err := seed(db, t.name); err != nil {
+			log.Fatalf("%s: seeding: %v", t.name, err)
+		}
+		for _, bm := range benchmarks {
+			name := fmt.Sprintf("Benchmark%s/%s", bm.name, t.name)
+			if !filter.MatchString(name) {
+				continue
+			}
+			for i := 0; i < *count; i++ {
+				result := testing.Benchmark(func(b *testing.B) {
+					b.ReportAllocs()
+					bm.fn(b, db)
+				})
+				fmt.Printf("%s-%d\t%s\t%s\n", name, runtime.GOMAXPROCS(0), result.String(), result.MemString())
+			}
+		}
+		db.Close()
+	}
+}
+
+// seed recreates the benchmark table with the configured number of rows.
+func seed(db *squealx.DB, dialect string) error {
+	idColumn := "BIGINT PRIMARY KEY"
+	if dialect == "sqlite" {
+		idColumn = "INTEGER PRIMARY KEY"
+	}
+	if _, err := db.Exec("DROP TABLE IF EXISTS bench_users"); err != nil {
+		return err
+	}
+	_, err := db.Exec(fmt.Sprintf(`CREATE TABLE bench_users (
+	id %s,
+	name VARCHAR(100) NOT NULL,
+	email VARCHAR(200) NOT NULL,
+	age INTEGER NOT NULL,
+	created_at TIMESTAMP NOT NULL
+)`, idColumn))
+	if err != nil {
+		return err
+	}
+	return db.WithTxx(context.Background(), nil, func(tx *squealx.Tx) error {
+		query := db.Rebind("INSERT INTO bench_users (id, name, email, age, created_at) VALUES (?, ?, ?, ?, ?)")
+		now := time.Now().UTC().Truncate(time.Second)
+		for i := 1; i <= *rows; i++ {
+			if _, err := tx.Exec(query, i, fmt.Sprintf("user %d", i), fmt.Sprintf("user%d@example.com", i), 18+i%60, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -5,11 +5,11 @@ import (
 	"database/sql/driver"
 	"errors"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 	"sync"
 
-	"github.com/oarkflow/squealx/reflectx"
 	"github.com/oarkflow/squealx/sqltoken"
 )
 
@@ -170,26 +170,37 @@ func asSliceForIn(i any) (v reflect.Value, ok bool) {
 		return reflect.Value{}, false
 	}
 
-	v = reflect.ValueOf(i)
-	t := reflectx.Deref(v.Type())
-
-	// Only expand slices
-	if t.Kind() != reflect.Slice {
+	// Expand slices of any type, such as type IDs []int64, and pointers to
+	// them
+	v = reflect.Indirect(reflect.ValueOf(i))
+	if !v.IsValid() || v.Kind() != reflect.Slice {
 		return reflect.Value{}, false
 	}
 
-	// []byte is a driver.Value type so it should not be expanded
-	if t == reflect.TypeOf([]byte{}) {
+	// []byte, and types of it such as json.RawMessage, are driver.Value
+	// types so they should not be expanded
+	if v.Type().Elem().Kind() == reflect.Uint8 {
 		return reflect.Value{}, false
-
 	}
 
 	return v, true
 }
 
+// inListRE matches queries ending with the opening of an IN list.
+var inListRE = regexp.MustCompile(`(?i)\bIN\s*\(\s*$`)
+
 // In expands slice values in args, returning the modified query string
 // and a new arg list that can be executed by a database. The `query` should
 // use the `?` bindVar.  The return value uses the `?` bindVar.
+//
+// A slice of tuples, such as [][2]any, expands to a list of row values for
+// composite keys: `WHERE (a, b) IN (?)` becomes `WHERE (a, b) IN ((?, ?),
+// (?, ?))`.
+//
+// Slices of any type expand, such as type IDs []int64 or []driver.Valuer,
+// except []byte. Slice types implementing driver.Valuer themselves, such as
+// vectors, only expand as the list of an IN, as in `id IN (?)`, and are
+// bound as their Value elsewhere.
 func In(query string, args ...any) (string, []any, error) {
 	// argMeta stores reflect.Value and length for slices and
 	// the value itself for non-slice arguments
@@ -197,6 +208,10 @@ func In(query string, args ...any) (string, []any, error) {
 		v      reflect.Value
 		i      any
 		length int
+		// valuer is set for slices implementing driver.Valuer, such as
+		// vectors, which are only expanded as the list of an IN and are
+		// bound as i otherwise.
+		valuer bool
 	}
 
 	var flatArgsCount int
@@ -212,22 +227,27 @@ func In(query string, args ...any) (string, []any, error) {
 	}
 
 	for i, arg := range args {
+		v, isSlice := asSliceForIn(arg)
 		if a, ok := arg.(driver.Valuer); ok {
 			var err error
 			arg, err = a.Value()
 			if err != nil {
 				return "", nil, err
 			}
+			if isSlice {
+				meta[i].valuer = true
+				meta[i].i = arg
+			}
 		}
 
-		if v, ok := asSliceForIn(arg); ok {
+		if isSlice {
 			meta[i].length = v.Len()
 			meta[i].v = v
 
 			anySlices = true
 			flatArgsCount += meta[i].length
 
-			if meta[i].length == 0 {
+			if meta[i].length == 0 && !meta[i].valuer {
 				return "", nil, errors.New("empty slice passed to 'in' query")
 			}
 		} else {
@@ -261,6 +281,14 @@ func In(query string, args ...any) (string, []any, error) {
 		argMeta := meta[arg]
 		arg++
 
+		if argMeta.valuer {
+			if !inListRE.MatchString(query[:offset+i]) {
+				argMeta.length = 0
+			} else if argMeta.length == 0 {
+				return "", nil, errors.New("empty slice passed to 'in' query")
+			}
+		}
+
 		// not a slice, continue.
 		// our questionmark will either be written before the next expansion
 		// of a slice or after the loop when writing the rest of the query
@@ -270,14 +298,24 @@ func In(query string, args ...any) (string, []any, error) {
 			continue
 		}
 
-		// write everything up to and including our ? character
-		buf.WriteString(query[:offset+i+1])
+		if isTupleSlice(argMeta.v) {
+			// write everything up to our ? character, which is replaced
+			// by a row value per tuple
+			buf.WriteString(query[:offset+i])
+			var err error
+			if newArgs, err = appendTuples(&buf, newArgs, argMeta.v); err != nil {
+				return "", nil, err
+			}
+		} else {
+			// write everything up to and including our ? character
+			buf.WriteString(query[:offset+i+1])
 
-		for si := 1; si < argMeta.length; si++ {
-			buf.WriteString(", ?")
-		}
+			for si := 1; si < argMeta.length; si++ {
+				buf.WriteString(", ?")
+			}
 
-		newArgs = appendReflectSlice(newArgs, argMeta.v, argMeta.length)
+			newArgs = appendReflectSlice(newArgs, argMeta.v, argMeta.length)
+		}
 
 		// slice the query and reset the offset. this avoids some bookkeeping for
 		// the write after the loop
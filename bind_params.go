@@ -0,0 +1,172 @@
+package squealx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"sync"
+)
+
+var (
+	maxBindParamsMu sync.RWMutex
+	maxBindParams   = map[string]int{
+		DialectPostgres: 65535,
+		DialectMySQL:    65535,
+		// SQLITE_MAX_VARIABLE_NUMBER defaults to 32766 since 3.32.
+		DialectSQLite: 32766,
+		DialectMSSQL:  2100,
+	}
+)
+
+// MaxBindParams returns the number of parameters a single statement may bind
+// on dialect. Statements expanding slices past it, such as bulk inserts and
+// IN lists, are split into several statements.
+func MaxBindParams(dialect string) int {
+	maxBindParamsMu.RLock()
+	defer maxBindParamsMu.RUnlock()
+	if n, ok := maxBindParams[dialect]; ok {
+		return n
+	}
+	return 65535
+}
+
+// SetMaxBindParams sets the number of parameters a single statement may bind
+// on dialect, for servers built with another limit.
+func SetMaxBindParams(dialect string, n int) {
+	maxBindParamsMu.Lock()
+	defer maxBindParamsMu.Unlock()
+	maxBindParams[dialect] = n
+}
+
+// MaxBindParams returns the number of parameters a single statement may bind
+// on db.
+func (db *DB) MaxBindParams() int {
+	return MaxBindParams(Dialect(db.driverName))
+}
+
+// namedChunks splits arg, a slice of rows bound to the named query, such as
+// those of a bulk insert, into chunks binding at most limit parameters each.
+// It returns nil when arg is not a slice or binds few enough parameters.
+func namedChunks(query string, arg any, limit int) []any {
+	v := reflect.ValueOf(arg)
+	if v.Kind() != reflect.Slice {
+		return nil
+	}
+	_, names, err := compileNamedQuery([]byte(query), QUESTION)
+	if err != nil || len(names) == 0 || len(names)*v.Len() <= limit {
+		return nil
+	}
+	return sliceChunks(v, max(limit/len(names), 1))
+}
+
+// inChunks splits the slice arg of args expanded by In into chunks such that
+// every statement binds at most limit parameters. It returns the index of
+// that arg, or -1 when args bind few enough parameters or hold several
+// slices, which cannot be split together.
+func inChunks(args []any, limit int) (int, []any) {
+	index, count, width := -1, 0, 1
+	for i, arg := range args {
+		v, ok := asSliceForIn(arg)
+		if !ok {
+			count++
+			continue
+		}
+		if index != -1 {
+			return -1, nil
+		}
+		index = i
+		if v = reflect.Indirect(v); isTupleSlice(v) && v.Len() > 0 {
+			width = max(v.Index(0).Len(), 1)
+		}
+		count += v.Len() * width
+	}
+	if index == -1 || count <= limit {
+		return -1, nil
+	}
+	size := max((limit-len(args)+1)/width, 1)
+	return index, sliceChunks(reflect.Indirect(reflect.ValueOf(args[index])), size)
+}
+
+func sliceChunks(v reflect.Value, size int) []any {
+	chunks := make([]any, 0, (v.Len()+size-1)/size)
+	for i := 0; i < v.Len(); i += size {
+		chunks = append(chunks, v.Slice(i, min(i+size, v.Len())).Interface())
+	}
+	return chunks
+}
+
+// ErrSplitOutsideTx is returned for a statement binding more parameters
+// than the database allows, which would have to be split into several
+// statements, run on an executor which can neither begin a transaction nor
+// is one.
+var ErrSplitOutsideTx = errors.New("squealx: statement binds too many parameters to run outside a transaction")
+
+// execChunks runs exec for each chunk, returning their results as one, as
+// the statements of a transaction.
+func execChunks(ctx context.Context, dialect string, chunks []any, exec func(ctx context.Context, chunk any) (sql.Result, error)) (sql.Result, error) {
+	results := chunkResults{firstID: dialect == DialectMySQL}
+	for _, chunk := range chunks {
+		result, err := exec(ctx, chunk)
+		if err != nil {
+			return nil, err
+		}
+		results.results = append(results.results, result)
+	}
+	return results, nil
+}
+
+// execChunksTx runs the statements bound by bind for each chunk within a
+// transaction of db, so that a statement split into several applies in
+// whole or not at all.
+func (db *DB) execChunksTx(ctx context.Context, chunks []any, bind func(chunk any) (string, []any, error)) (sql.Result, error) {
+	var result sql.Result
+	err := db.WithTxx(ctx, nil, func(tx *Tx) error {
+		var err error
+		result, err = execChunks(ctx, Dialect(db.driverName), chunks, func(ctx context.Context, chunk any) (sql.Result, error) {
+			query, args, err := bind(chunk)
+			if err != nil {
+				return nil, err
+			}
+			if query, args, err = db.rewrite(ctx, query, args); err != nil {
+				return nil, err
+			}
+			return tx.SQLTx.ExecContext(ctx, query, args...)
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// chunkResults is the result of a statement split into several statements.
+type chunkResults struct {
+	results []sql.Result
+	// firstID reports the id of the first statement, as MySQL reports the
+	// first id generated by a multi-row insert.
+	firstID bool
+}
+
+// LastInsertId returns the id the statement would have reported unsplit:
+// the one of the first statement on MySQL, and of the last one elsewhere.
+func (r chunkResults) LastInsertId() (int64, error) {
+	if r.firstID {
+		return r.results[0].LastInsertId()
+	}
+	return r.results[len(r.results)-1].LastInsertId()
+}
+
+// RowsAffected returns the rows affected by all statements.
+func (r chunkResults) RowsAffected() (int64, error) {
+	var total int64
+	for _, result := range r.results {
+		n, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
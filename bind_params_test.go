@@ -0,0 +1,175 @@
+package squealx
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+func TestInChunksPerDialect(t *testing.T) {
+	for _, dialect := range []string{DialectPostgres, DialectMySQL, DialectSQLite, DialectMSSQL} {
+		limit := MaxBindParams(dialect)
+		ids := make([]int, 2*limit+5)
+		tuples := make([][]any, limit)
+		for i := range tuples {
+			tuples[i] = []any{i, i, i}
+		}
+		for _, c := range []struct {
+			name  string
+			args  []any
+			width int
+		}{
+			{"values", []any{"kind", ids, 1}, 1},
+			{"tuples", []any{tuples, "kind"}, 3},
+		} {
+			index, chunks := inChunks(c.args, limit)
+			if chunks == nil {
+				t.Fatalf("%s %s: not split", dialect, c.name)
+			}
+			total := 0
+			for _, chunk := range chunks {
+				n := reflect.ValueOf(chunk).Len()
+				if params := n*c.width + len(c.args) - 1; params > limit {
+					t.Errorf("%s %s: chunk binds %d parameters, more than %d", dialect, c.name, params, limit)
+				}
+				total += n
+			}
+			if want := reflect.ValueOf(c.args[index]).Len(); total != want {
+				t.Errorf("%s %s: chunks hold %d values, want %d", dialect, c.name, total, want)
+			}
+		}
+	}
+	if _, chunks := inChunks([]any{"kind", make([]int, MaxBindParams(DialectMSSQL)-1)}, MaxBindParams(DialectMSSQL)); chunks != nil {
+		t.Errorf("inChunks split a statement binding no more parameters than allowed")
+	}
+}
+
+// openChunkDB returns a database of items 1 to n allowing statements to
+// bind limit parameters, with the number of parameters bound by each
+// statement recorded in params.
+func openChunkDB(t *testing.T, n, limit int) (db *DB, params *[]int) {
+	t.Helper()
+	db, err := Connect("sqlite", ":memory:", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	db.MustExec(`CREATE TABLE items (id INTEGER PRIMARY KEY, kind TEXT, v INTEGER CHECK (v < 2))`)
+	for i := 1; i <= n; i++ {
+		db.MustExec(`INSERT INTO items (id, kind, v) VALUES (?, 'a', 0)`, i)
+	}
+	SetMaxBindParams(DialectSQLite, limit)
+	t.Cleanup(func() { SetMaxBindParams(DialectSQLite, 32766) })
+	params = new([]int)
+	db.UseRewriter(QueryRewriterFunc(func(ctx context.Context, stmt Statement) (Statement, error) {
+		*params = append(*params, len(stmt.Args))
+		return stmt, nil
+	}))
+	return db, params
+}
+
+func itemIDs(n int) []int {
+	ids := make([]int, n)
+	for i := range ids {
+		ids[i] = i + 1
+	}
+	return ids
+}
+
+func sumItems(t *testing.T, db *DB) int {
+	t.Helper()
+	var sum int
+	if err := db.SQLDB.QueryRow(`SELECT SUM(v) FROM items`).Scan(&sum); err != nil {
+		t.Fatal(err)
+	}
+	return sum
+}
+
+const bumpItems = `UPDATE items SET v = v + 1 WHERE kind = ? AND id IN (?)`
+
+func TestInExecContextChunks(t *testing.T) {
+	db, params := openChunkDB(t, 25, 10)
+	result, err := db.InExecContext(context.Background(), bumpItems, "a", itemIDs(25))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{10, 10, 8}; !reflect.DeepEqual(*params, want) {
+		t.Errorf("statements bound %v parameters, want %v", *params, want)
+	}
+	if n, err := result.RowsAffected(); err != nil || n != 25 {
+		t.Errorf("RowsAffected = %d, %v, want 25", n, err)
+	}
+	if sum := sumItems(t, db); sum != 25 {
+		t.Errorf("sum = %d, want 25", sum)
+	}
+}
+
+func TestInExecContextChunksAllOrNothing(t *testing.T) {
+	db, params := openChunkDB(t, 25, 10)
+	// The last chunk breaks the check constraint of item 25.
+	db.MustExec(`UPDATE items SET v = 1 WHERE id = 25`)
+	*params = nil
+	if _, err := db.InExecContext(context.Background(), bumpItems, "a", itemIDs(25)); err == nil {
+		t.Fatal("InExecContext breaking a constraint succeeded")
+	}
+	if len(*params) != 3 {
+		t.Errorf("ran %d statements, want 3", len(*params))
+	}
+	if sum := sumItems(t, db); sum != 1 {
+		t.Errorf("sum = %d, want the first chunks rolled back to 1", sum)
+	}
+}
+
+func TestTxInExecContextChunks(t *testing.T) {
+	db, params := openChunkDB(t, 25, 10)
+	ctx := context.Background()
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	// With a single connection, a transaction of its own would block.
+	result, err := tx.InExecContext(ctx, bumpItems, "a", itemIDs(25))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{10, 10, 8}; !reflect.DeepEqual(*params, want) {
+		t.Errorf("statements bound %v parameters, want %v", *params, want)
+	}
+	if n, err := result.RowsAffected(); err != nil || n != 25 {
+		t.Errorf("RowsAffected = %d, %v, want 25", n, err)
+	}
+	var sum int
+	if err := tx.SQLTx.QueryRow(`SELECT SUM(v) FROM items`).Scan(&sum); err != nil || sum != 25 {
+		t.Errorf("sum in the transaction = %d, %v, want 25", sum, err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if sum := sumItems(t, db); sum != 0 {
+		t.Errorf("sum = %d, want the statements rolled back with the transaction", sum)
+	}
+}
+
+func TestInSelectChunkedContextStopsOnError(t *testing.T) {
+	db, _ := openChunkDB(t, 25, 100)
+	var statements atomic.Int32
+	failed := errors.New("chunk failed")
+	db.UseRewriter(QueryRewriterFunc(func(ctx context.Context, stmt Statement) (Statement, error) {
+		statements.Add(1)
+		return stmt, failed
+	}))
+	var ids []int
+	err := db.InSelectChunkedContext(context.Background(), &ids, `SELECT id FROM items WHERE id IN (?)`, itemIDs(25), ChunkOptions{Size: 5, Concurrency: 2})
+	if !errors.Is(err, failed) {
+		t.Fatalf("error = %v, want %v", err, failed)
+	}
+	if n := statements.Load(); n > 2 {
+		t.Errorf("ran %d chunks, want no more than the 2 in flight when the first failed", n)
+	}
+}
@@ -0,0 +1,138 @@
+package squealx_test
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/oarkflow/squealx"
+)
+
+type IDs []int64
+
+// Status is a driver.Valuer binding as its name.
+type Status int
+
+func (s Status) Value() (driver.Value, error) {
+	return []string{"draft", "published"}[s], nil
+}
+
+func TestInExpansion(t *testing.T) {
+	raw := []json.RawMessage{json.RawMessage(`{"a":1}`), json.RawMessage(`{"b":2}`)}
+	uuids := [][16]byte{{1}, {2}}
+	cases := []struct {
+		name     string
+		query    string
+		args     []any
+		want     string
+		wantArgs []any
+	}{
+		{
+			name:     "defined slice type",
+			query:    "SELECT * FROM t WHERE id IN (?) AND x = ?",
+			args:     []any{IDs{1, 2, 3}, "x"},
+			want:     "SELECT * FROM t WHERE id IN (?, ?, ?) AND x = ?",
+			wantArgs: []any{int64(1), int64(2), int64(3), "x"},
+		},
+		{
+			name:     "slice pointer",
+			query:    "SELECT * FROM t WHERE id IN (?)",
+			args:     []any{&IDs{1, 2}},
+			want:     "SELECT * FROM t WHERE id IN (?, ?)",
+			wantArgs: []any{int64(1), int64(2)},
+		},
+		{
+			name:     "valuer slice",
+			query:    "SELECT * FROM t WHERE status IN (?)",
+			args:     []any{[]driver.Valuer{Status(0), Status(1)}},
+			want:     "SELECT * FROM t WHERE status IN (?, ?)",
+			wantArgs: []any{Status(0), Status(1)},
+		},
+		{
+			name:     "bytes",
+			query:    "SELECT * FROM t WHERE data = ?",
+			args:     []any{json.RawMessage(`{}`)},
+			want:     "SELECT * FROM t WHERE data = ?",
+			wantArgs: []any{json.RawMessage(`{}`)},
+		},
+		{
+			name:     "slice of json values",
+			query:    "SELECT * FROM t WHERE data IN (?)",
+			args:     []any{raw},
+			want:     "SELECT * FROM t WHERE data IN (?, ?)",
+			wantArgs: []any{raw[0], raw[1]},
+		},
+		{
+			name:     "slice of byte arrays",
+			query:    "SELECT * FROM t WHERE id IN (?)",
+			args:     []any{uuids},
+			want:     "SELECT * FROM t WHERE id IN (?, ?)",
+			wantArgs: []any{uuids[0], uuids[1]},
+		},
+		{
+			name:     "tuples",
+			query:    "SELECT * FROM t WHERE (a, b) IN (?)",
+			args:     []any{[][2]any{{1, "x"}, {2, "y"}}},
+			want:     "SELECT * FROM t WHERE (a, b) IN ((?, ?), (?, ?))",
+			wantArgs: []any{1, "x", 2, "y"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			query, args, err := squealx.In(c.query, c.args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if query != c.want {
+				t.Errorf("query = %s, want %s", query, c.want)
+			}
+			if !reflect.DeepEqual(args, c.wantArgs) {
+				t.Errorf("args = %v, want %v", args, c.wantArgs)
+			}
+		})
+	}
+}
+
+func TestInBindvars(t *testing.T) {
+	query, args, err := squealx.In("SELECT * FROM t WHERE id IN (?) AND (a, b) IN (?) AND x = ?",
+		IDs{1, 2}, [][]any{{3, 4}}, "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantArgs := []any{int64(1), int64(2), 3, 4, "x"}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("args = %v, want %v", args, wantArgs)
+	}
+	for _, c := range []struct {
+		bindType int
+		want     string
+	}{
+		{squealx.QUESTION, "SELECT * FROM t WHERE id IN (?, ?) AND (a, b) IN ((?, ?)) AND x = ?"},
+		{squealx.DOLLAR, "SELECT * FROM t WHERE id IN ($1, $2) AND (a, b) IN (($3, $4)) AND x = $5"},
+		{squealx.NAMED, "SELECT * FROM t WHERE id IN (:arg1, :arg2) AND (a, b) IN ((:arg3, :arg4)) AND x = :arg5"},
+		{squealx.AT, "SELECT * FROM t WHERE id IN (@p1, @p2) AND (a, b) IN ((@p3, @p4)) AND x = @p5"},
+	} {
+		if got := squealx.Rebind(c.bindType, query); got != c.want {
+			t.Errorf("Rebind(%d) = %s, want %s", c.bindType, got, c.want)
+		}
+	}
+}
+
+func TestNamedInMapOfSlices(t *testing.T) {
+	arg := map[string]IDs{"ids": {1, 2}, "other": {3}}
+	query, args, err := squealx.Named("SELECT * FROM t WHERE id IN (:ids) OR id IN (:other)", arg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query, args, err = squealx.In(query, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "SELECT * FROM t WHERE id IN ($1, $2) OR id IN ($3)"; squealx.Rebind(squealx.DOLLAR, query) != want {
+		t.Errorf("query = %s, want %s", squealx.Rebind(squealx.DOLLAR, query), want)
+	}
+	if want := []any{int64(1), int64(2), int64(3)}; !reflect.DeepEqual(args, want) {
+		t.Errorf("args = %v, want %v", args, want)
+	}
+}
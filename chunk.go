@@ -0,0 +1,93 @@
+package squealx
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// ChunkOptions configures InSelectChunkedContext.
+type ChunkOptions struct {
+	// Size is the number of keys per query. By default, chunks bind as many
+	// keys as MaxBindParams allows.
+	Size int
+	// Concurrency is the number of chunks queried at once, 1 by default.
+	Concurrency int
+}
+
+// InSelectChunkedContext is like InSelectContext for a query selecting rows
+// by a list of keys, such as `SELECT * FROM users WHERE id IN (?)`, with
+// keys bound to the first bindVar and args to the following ones. Lists
+// longer than the parameter limit of the database are split into chunks,
+// queried separately, whose rows are appended to dest in the order of the
+// chunks. keys may be a slice of tuples for composite keys.
+func (db *DB) InSelectChunkedContext(ctx context.Context, dest any, query string, keys any, opts ChunkOptions, args ...any) error {
+	keysValue := reflect.Indirect(reflect.ValueOf(keys))
+	if keysValue.Kind() != reflect.Slice {
+		return errors.New("squealx: keys must be a slice")
+	}
+	destValue := reflect.ValueOf(dest)
+	if destValue.Kind() != reflect.Ptr || destValue.Elem().Kind() != reflect.Slice {
+		return errors.New("squealx: dest must be a pointer to a slice")
+	}
+	size := opts.Size
+	if size <= 0 {
+		width := 1
+		if isTupleSlice(keysValue) && keysValue.Len() > 0 {
+			width = max(keysValue.Index(0).Len(), 1)
+		}
+		size = max((db.MaxBindParams()-len(args))/width, 1)
+	}
+	n := keysValue.Len()
+	if n <= size {
+		return db.InSelectContext(ctx, dest, query, append([]any{keys}, args...)...)
+	}
+
+	chunks := make([]reflect.Value, (n+size-1)/size)
+	run := func(ctx context.Context, i int) error {
+		rows := reflect.New(destValue.Elem().Type())
+		chunk := keysValue.Slice(i*size, min((i+1)*size, n)).Interface()
+		if err := db.InSelectContext(ctx, rows.Interface(), query, append([]any{chunk}, args...)...); err != nil {
+			return err
+		}
+		chunks[i] = rows.Elem()
+		return nil
+	}
+	if opts.Concurrency <= 1 {
+		for i := range chunks {
+			if err := run(ctx, i); err != nil {
+				return err
+			}
+		}
+	} else {
+		// No chunk is scheduled once one has failed or ctx is done.
+		g, gctx := errgroup.WithContext(ctx)
+		g.SetLimit(opts.Concurrency)
+		for i := range chunks {
+			if gctx.Err() != nil {
+				break
+			}
+			g.Go(func() error {
+				if err := gctx.Err(); err != nil {
+					return err
+				}
+				return run(gctx, i)
+			})
+		}
+		if err := g.Wait(); err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+
+	out := destValue.Elem()
+	for _, rows := range chunks {
+		out = reflect.AppendSlice(out, rows)
+	}
+	destValue.Elem().Set(out)
+	return nil
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/oarkflow/squealx"
+)
+
+const help = `Statements end with a semicolon and may span lines. Commands:
+  \nodes                 status of the databases of the resolver
+  \migrate DIR           apply the .sql files of DIR not applied yet, in name order
+  \queries               list the named queries
+  \reload                reload the named queries from the config's "queries"
+  \run NAME [ARGS...]    run the named query NAME
+  \slow [DURATION|off]   report statements slower than DURATION, 200ms by default
+  \format STATEMENT      pretty-print STATEMENT
+  \help                  this help
+  \q                     quit`
+
+// command runs the command line and reports whether the shell quits.
+func (s *shell) command(line string) (quit bool) {
+	fields := strings.Fields(line)
+	name, args := fields[0], fields[1:]
+	var err error
+	switch name {
+	case `\q`, `\quit`:
+		return true
+	case `\help`, `\?`:
+		fmt.Fprintln(s.out, help)
+	case `\nodes`:
+		s.nodes()
+	case `\migrate`:
+		if len(args) != 1 {
+			err = fmt.Errorf(`usage: \migrate DIR`)
+			break
+		}
+		err = s.migrate(args[0])
+	case `\queries`:
+		s.listQueries()
+	case `\reload`:
+		err = s.reload()
+	case `\run`:
+		if len(args) == 0 {
+			err = fmt.Errorf(`usage: \run NAME [ARGS...]`)
+			break
+		}
+		err = s.runQuery(args[0], args[1:])
+	case `\slow`:
+		err = s.setSlow(args)
+	case `\format`:
+		fmt.Fprintln(s.out, squealx.FormatSQL(strings.TrimSpace(strings.TrimPrefix(line, name)), squealx.StylePretty))
+	default:
+		err = fmt.Errorf(`unknown command %s, see \help`, name)
+	}
+	s.report(err)
+	return false
+}
+
+// rowWords start the statements returning rows.
+var rowWords = map[string]bool{
+	"SELECT": true, "WITH": true, "SHOW": true, "EXPLAIN": true, "VALUES": true,
+	"TABLE": true, "DESCRIBE": true, "DESC": true, "PRAGMA": true,
+}
+
+// exec runs statement, printing the rows it returns or the number of rows
+// it affected. Reads go to the replicas through the resolver.
+func (s *shell) exec(statement string) error {
+	statement = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(statement), ";"))
+	ctx, cancel := s.context()
+	defer cancel()
+	minified := strings.ToUpper(squealx.FormatSQL(statement, squealx.StyleMinify))
+	first, _, _ := strings.Cut(minified, " ")
+	start := time.Now()
+	if rowWords[first] || strings.Contains(minified, " RETURNING ") {
+		rows, err := s.resolver.QueryxContext(ctx, statement)
+		if err != nil {
+			return err
+		}
+		return s.printRows(rows, start)
+	}
+	result, err := s.resolver.ExecContext(ctx, statement)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil {
+		fmt.Fprintf(s.out, "%d rows affected (%s)\n", affected, time.Since(start).Round(time.Microsecond))
+	} else {
+		fmt.Fprintf(s.out, "OK (%s)\n", time.Since(start).Round(time.Microsecond))
+	}
+	return nil
+}
+
+// printRows prints rows as a table and closes them.
+func (s *shell) printRows(rows *squealx.Rows, start time.Time) error {
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(s.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(columns, "\t"))
+	separators := make([]string, len(columns))
+	for i, column := range columns {
+		separators[i] = strings.Repeat("-", len(column))
+	}
+	fmt.Fprintln(tw, strings.Join(separators, "\t"))
+	n := 0
+	for rows.Next() {
+		row := make(map[string]any, len(columns))
+		if err := rows.MapScan(row); err != nil {
+			return err
+		}
+		values := make([]string, len(columns))
+		for i, column := range columns {
+			values[i] = formatValue(row[column])
+		}
+		fmt.Fprintln(tw, strings.Join(values, "\t"))
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(s.out, "(%d rows, %s)\n", n, time.Since(start).Round(time.Microsecond))
+	return nil
+}
+
+// formatValue returns v as a table cell, on a single line.
+func formatValue(v any) string {
+	var text string
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		text = string(v)
+	case time.Time:
+		text = v.Format(time.RFC3339Nano)
+	default:
+		text = fmt.Sprint(v)
+	}
+	return strings.NewReplacer("\t", `\t`, "\n", `\n`, "\r", `\r`).Replace(text)
+}
+
+// nodes prints the role, health, connections and latency of the databases
+// of the resolver.
+func (s *shell) nodes() {
+	roles := map[string][]string{}
+	for _, db := range s.resolver.MasterDBs() {
+		roles[db.ID] = append(roles[db.ID], "master")
+	}
+	for _, db := range s.resolver.ReplicaDBs() {
+		roles[db.ID] = append(roles[db.ID], "replica")
+	}
+	dbs := map[string]*squealx.DB{}
+	for _, db := range append(s.resolver.MasterDBs(), s.resolver.ReplicaDBs()...) {
+		dbs[db.ID] = db
+	}
+	tw := tabwriter.NewWriter(s.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tROLE\tDRIVER\tHEALTHY\tIN USE\tIDLE\tOPEN\tWAITS\tP95")
+	for _, node := range s.resolver.NodeStats() {
+		db := dbs[node.ID]
+		if db == nil {
+			continue
+		}
+		stats := db.Stats()
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%t\t%d\t%d\t%d\t%d\t%s\n", node.ID, strings.Join(roles[node.ID], ","), db.DriverName(),
+			node.Healthy, node.InUse, stats.Idle, stats.OpenConnections, stats.WaitCount, node.P95Latency)
+	}
+	tw.Flush()
+}
+
+// migrationsTable records the migrations applied by \migrate.
+const migrationsTable = "squealx_migrations"
+
+// migrate applies the .sql files of dir not recorded in migrationsTable, in
+// name order, each in a transaction with its record.
+func (s *shell) migrate(dir string) error {
+	ctx, cancel := s.context()
+	defer cancel()
+	db, err := s.resolver.UseDefault()
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+migrationsTable+` (
+	name VARCHAR(255) NOT NULL PRIMARY KEY,
+	applied_at TIMESTAMP NOT NULL
+)`)
+	if err != nil {
+		return err
+	}
+	var applied []string
+	if err := db.SelectContext(ctx, &applied, `SELECT name FROM `+migrationsTable); err != nil {
+		return err
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return err
+	}
+	slices.Sort(files)
+	n := 0
+	for _, file := range files {
+		name := filepath.Base(file)
+		if slices.Contains(applied, name) {
+			continue
+		}
+		start := time.Now()
+		err := db.WithTxx(ctx, nil, func(tx *squealx.Tx) error {
+			if _, err := squealx.LoadFileContext(ctx, tx, file); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, db.Rebind(`INSERT INTO `+migrationsTable+` (name, applied_at) VALUES (?, ?)`), name, time.Now().UTC())
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		fmt.Fprintf(s.out, "applied %s (%s)\n", name, time.Since(start).Round(time.Millisecond))
+		n++
+	}
+	fmt.Fprintf(s.out, "%d migrations applied, %d already\n", n, len(applied))
+	return nil
+}
+
+func (s *shell) listQueries() {
+	if s.queries == nil {
+		fmt.Fprintln(s.out, `no named queries, set "queries" in the config`)
+		return
+	}
+	queries := s.queries.Queries()
+	names := make([]string, 0, len(queries))
+	for name := range queries {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	tw := tabwriter.NewWriter(s.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tCONNECTION\tDOC")
+	for _, name := range names {
+		q := queries[name]
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", name, q.Connection, q.Doc)
+	}
+	tw.Flush()
+}
+
+// reload loads the named queries again, replacing those the resolver
+// runs.
+func (s *shell) reload() error {
+	if s.queries == nil {
+		return fmt.Errorf(`no named queries, set "queries" in the config`)
+	}
+	loaded, err := loadQueries(s.queriesPath)
+	if err != nil {
+		return err
+	}
+	for _, q := range loaded.Queries() {
+		s.queries.AddQuery(q)
+	}
+	fmt.Fprintf(s.out, "%d queries loaded from %s\n", len(loaded.Queries()), s.queriesPath)
+	return nil
+}
+
+// runQuery runs the named query name with args, as strings.
+func (s *shell) runQuery(name string, args []string) error {
+	q := s.resolver.GetQuery(name)
+	if q == nil {
+		return fmt.Errorf("no query named %s", name)
+	}
+	ctx, cancel := s.context()
+	defer cancel()
+	values := make([]any, len(args))
+	for i, arg := range args {
+		values[i] = arg
+	}
+	start := time.Now()
+	rows, err := s.resolver.QueryxContext(ctx, q.Query, values...)
+	if err != nil {
+		return err
+	}
+	return s.printRows(rows, start)
+}
+
+// setSlow sets the threshold of the slow query report from args.
+func (s *shell) setSlow(args []string) error {
+	switch {
+	case len(args) == 0:
+		s.slow = 200 * time.Millisecond
+	case args[0] == "off":
+		s.slow = 0
+		fmt.Fprintln(s.out, "slow query report off")
+		return nil
+	default:
+		d, err := time.ParseDuration(args[0])
+		if err != nil {
+			return err
+		}
+		s.slow = d
+	}
+	fmt.Fprintf(s.out, "reporting statements slower than %s\n", s.slow)
+	return nil
+}
+
+// slowQuery is the notifier of the slow query hook, reporting the
+// statements slower than the threshold set with \slow.
+func (s *shell) slowQuery(query string, args []any, latency string) {
+	since, err := time.ParseDuration(latency)
+	if s.slow <= 0 || err != nil || since <= s.slow {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "slow query (%s): %s %v\n", since.Round(time.Microsecond),
+		squealx.FormatSQL(query, squealx.StyleCompact), args)
+}
@@ -0,0 +1,251 @@
+// Command squealx-cli is an interactive shell for the databases of an
+// application using squealx: it runs ad-hoc queries, shows the status of
+// the resolver's databases, applies migrations, reloads named queries and
+// reports slow queries.
+//
+//	squealx-cli -config db.json
+//
+// The config file holds a squealx.Config, as read by squealx.DecodeConfig,
+// or several under "masters" and "replicas", with "queries" naming a file
+// or directory of named queries:
+//
+//	{
+//		"masters": [{"name": "primary", "driver": "postgres", "host": "localhost", ...}],
+//		"replicas": [{"name": "replica", "driver": "postgres", "host": "replica", ...}],
+//		"queries": "./queries"
+//	}
+//
+// Statements end with a semicolon and may span lines. Commands start with a
+// backslash; \help lists them.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/oarkflow/squealx"
+	"github.com/oarkflow/squealx/dbresolver"
+	"github.com/oarkflow/squealx/drivers/mssql"
+	"github.com/oarkflow/squealx/drivers/mysql"
+	"github.com/oarkflow/squealx/drivers/postgres"
+	"github.com/oarkflow/squealx/drivers/sqlite"
+	"github.com/oarkflow/squealx/hooks"
+)
+
+// config is the content of the config file.
+type config struct {
+	Masters  []json.RawMessage `json:"masters"`
+	Replicas []json.RawMessage `json:"replicas"`
+	Queries  string            `json:"queries"`
+}
+
+func main() {
+	configPath := flag.String("config", "squealx.json", "config file of the databases")
+	timeout := flag.Duration("timeout", 0, "timeout of each statement, none when zero")
+	flag.Parse()
+
+	s, err := newShell(*configPath, *timeout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "squealx-cli:", err)
+		os.Exit(1)
+	}
+	defer s.resolver.Close()
+	s.run(os.Stdin)
+}
+
+// loadConfig reads the config file at path.
+func loadConfig(path string) (*config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(cfg.Masters) == 0 {
+		// A single database.
+		cfg.Masters = []json.RawMessage{data}
+	}
+	return &cfg, nil
+}
+
+// open connects to the database described by data, a squealx.Config,
+// identified by its name, key or else fallback.
+func open(data []byte, fallback string) (*squealx.DB, error) {
+	cfg, err := squealx.DecodeConfig(data)
+	if err != nil {
+		return nil, err
+	}
+	id := cfg.Name
+	if id == "" {
+		id = cfg.Key
+	}
+	if id == "" {
+		id = fallback
+	}
+	var db *squealx.DB
+	switch cfg.Driver {
+	case "postgres", "psql", "postgresql":
+		db, err = postgres.Open(cfg.ToString(), id)
+	case "mysql", "mariadb":
+		db, err = mysql.Open(cfg.ToString(), id)
+	case "sql-server", "sqlserver", "mssql", "ms-sql":
+		db, err = mssql.Open(cfg.ToString(), id)
+	case "sqlite", "sqlite3":
+		db, err = sqlite.Open(cfg.Database, id)
+	default:
+		return nil, fmt.Errorf("%s: unsupported driver %q", id, cfg.Driver)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", id, err)
+	}
+	if cfg.MaxOpenCons > 0 {
+		db.SetMaxOpenConns(cfg.MaxOpenCons)
+	}
+	if cfg.MaxIdleCons > 0 {
+		db.SetMaxIdleConns(cfg.MaxIdleCons)
+	}
+	return db, nil
+}
+
+// shell reads statements and commands and runs them on a resolver.
+type shell struct {
+	resolver dbresolver.DBResolver
+	queries  *squealx.FileLoader
+	// queriesPath is the file or directory queries was loaded from.
+	queriesPath string
+	timeout     time.Duration
+	// slow is the threshold of the slow query hook, zero when off.
+	slow time.Duration
+	out  io.Writer
+}
+
+func newShell(configPath string, timeout time.Duration) (*shell, error) {
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	s := &shell{queriesPath: cfg.Queries, timeout: timeout, out: os.Stdout}
+	var masters, replicas []*squealx.DB
+	closeAll := func() {
+		for _, db := range append(masters, replicas...) {
+			db.Close()
+		}
+	}
+	for i, data := range cfg.Masters {
+		db, err := open(data, fmt.Sprintf("master%d", i+1))
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		masters = append(masters, db)
+	}
+	for i, data := range cfg.Replicas {
+		db, err := open(data, fmt.Sprintf("replica%d", i+1))
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		replicas = append(replicas, db)
+	}
+	opts := []dbresolver.OptionFunc{
+		dbresolver.WithMasterDBs(masters...),
+		dbresolver.WithReplicaDBs(replicas...),
+		dbresolver.WithDefaultDB(masters[0]),
+	}
+	if cfg.Queries != "" {
+		if s.queries, err = loadQueries(cfg.Queries); err != nil {
+			closeAll()
+			return nil, err
+		}
+		opts = append(opts, dbresolver.WithFileLoader(s.queries))
+	}
+	if s.resolver, err = dbresolver.New(opts...); err != nil {
+		closeAll()
+		return nil, err
+	}
+	// The hook notifies every statement, slowQuery applies the threshold,
+	// which \slow changes.
+	s.resolver.WithHooks(hooks.NewLogger(nil, true, 0, s.slowQuery))
+	return s, nil
+}
+
+// loadQueries loads the named queries of the file or directory at path.
+func loadQueries(path string) (*squealx.FileLoader, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return squealx.LoadFromDir(path)
+	}
+	return squealx.LoadFromFile(path)
+}
+
+// run reads statements and commands from r until its end or \q.
+func (s *shell) run(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	var statement strings.Builder
+	for {
+		if statement.Len() == 0 {
+			fmt.Fprint(s.out, "squealx> ")
+		} else {
+			fmt.Fprint(s.out, "      -> ")
+		}
+		if !scanner.Scan() {
+			fmt.Fprintln(s.out)
+			return
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if statement.Len() == 0 && strings.HasPrefix(line, `\`) {
+			if quit := s.command(line); quit {
+				return
+			}
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		statement.WriteString(line)
+		statement.WriteByte('\n')
+		if strings.HasSuffix(line, ";") {
+			s.report(s.exec(statement.String()))
+			statement.Reset()
+		}
+	}
+}
+
+// context returns the context of a statement, canceled by the timeout of
+// the shell or an interrupt.
+func (s *shell) context() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	if s.timeout <= 0 {
+		return ctx, stop
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+func (s *shell) report(err error) {
+	switch {
+	case err == nil:
+	case errors.Is(err, context.Canceled):
+		fmt.Fprintln(s.out, "canceled")
+	default:
+		fmt.Fprintln(s.out, "error:", err)
+	}
+}
@@ -0,0 +1,124 @@
+package squealx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+)
+
+// SessionConn is a new pooled connection, passed to the function of
+// WithConnInit before the pool hands it out.
+type SessionConn struct {
+	driver.Conn
+}
+
+// Exec runs a statement returning no rows on the connection, such as
+// `SET search_path TO app` or `SET time_zone = '+00:00'`.
+func (c SessionConn) Exec(ctx context.Context, query string, args ...any) error {
+	named := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	if execer, ok := c.Conn.(driver.ExecerContext); ok {
+		_, err := execer.ExecContext(ctx, query, named)
+		if err != driver.ErrSkip {
+			return err
+		}
+	}
+	var stmt driver.Stmt
+	var err error
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	if execer, ok := stmt.(driver.StmtExecContext); ok {
+		_, err = execer.ExecContext(ctx, named)
+		return err
+	}
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg
+	}
+	_, err = stmt.Exec(values)
+	return err
+}
+
+// WithConnInit makes Open run init on every new connection of the pool,
+// such as to set the search_path, time zone, application_name or sql_mode
+// of its session. Settings made this way survive the pool closing and
+// reopening connections. A connection whose init fails is discarded and
+// the error returned to the query which needed it.
+//
+// It is only honoured by Open and the functions built on it, such as
+// Connect; NewDb and OpenExist wrap an already opened *sql.DB.
+func WithConnInit(init func(ctx context.Context, conn SessionConn) error) DBOption {
+	return func(o *dbOptions) {
+		o.connInit = init
+	}
+}
+
+// openInit opens dataSourceName through a connector running init on every
+// new connection.
+func openInit(driverName, dataSourceName string, init func(ctx context.Context, conn SessionConn) error) (*sql.DB, error) {
+	// sql.Open does not connect, it only looks the driver up.
+	probe, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	drv := probe.Driver()
+	_ = probe.Close()
+	var connector driver.Connector = dsnConnector{dsn: dataSourceName, driver: drv}
+	if dc, ok := drv.(driver.DriverContext); ok {
+		if connector, err = dc.OpenConnector(dataSourceName); err != nil {
+			return nil, err
+		}
+	}
+	return sql.OpenDB(&initConnector{Connector: connector, init: init}), nil
+}
+
+// initConnector runs init on the connections of Connector.
+type initConnector struct {
+	driver.Connector
+	init func(ctx context.Context, conn SessionConn) error
+}
+
+func (c *initConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.init(ctx, SessionConn{Conn: conn}); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// Close closes Connector when it holds resources, as sql.DB.Close does.
+func (c *initConnector) Close() error {
+	if closer, ok := c.Connector.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// dsnConnector is the connector of drivers not implementing
+// driver.DriverContext.
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c dsnConnector) Connect(_ context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
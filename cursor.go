@@ -0,0 +1,295 @@
+package squealx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+var cursorSeq atomic.Int64
+
+// Cursor reads the result set of a huge SELECT in batches of rows, so that
+// exports neither hold the whole result set in memory nor keep a single
+// result set open for the duration of the export.
+//
+// On PostgreSQL the rows are read from a server-side cursor, declared in a
+// read-only transaction held until Close. Other databases read batches with
+// keyset pagination on the first column of the query, which must therefore
+// be unique, sortable and non NULL, and the query must not be ordered
+// itself.
+type Cursor struct {
+	db        *DB
+	query     string
+	args      []any
+	batchSize int
+	tx        *Tx
+	name      string
+	key       string
+	after     any
+	rows      *Rows
+	current   *cursorRows
+	done      bool
+	err       error
+}
+
+// Cursor returns a Cursor reading the rows of query in batches of at most
+// batchSize rows. The caller must Close it.
+func (db *DB) Cursor(ctx context.Context, query string, args []any, batchSize int) (*Cursor, error) {
+	if batchSize <= 0 {
+		return nil, fmt.Errorf("invalid batch size %d", batchSize)
+	}
+	query = strings.TrimRight(strings.TrimSpace(SanitizeQuery(query, args...)), ";")
+	c := &Cursor{db: db, query: query, args: args, batchSize: batchSize}
+	if Dialect(db.driverName) != DialectPostgres {
+		return c, nil
+	}
+	tx, err := db.BeginTxx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	c.name = fmt.Sprintf("squealx_cursor_%d", cursorSeq.Add(1))
+	if _, err := tx.ExecContext(ctx, "DECLARE "+c.name+" NO SCROLL CURSOR FOR "+query, args...); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	c.tx = tx
+	return c, nil
+}
+
+// Next reads the next batch, reporting false when the rows are exhausted or
+// on error. The rows left unread in the previous batch are discarded.
+func (c *Cursor) Next(ctx context.Context) bool {
+	if c.err != nil || c.done {
+		return false
+	}
+	if c.rows != nil {
+		if c.err = c.finishBatch(); c.err != nil || c.done {
+			return false
+		}
+	}
+	rows, err := c.fetch(ctx)
+	if err != nil {
+		c.err = err
+		return false
+	}
+	current := &cursorRows{SQLRows: rows.SQLRows}
+	if !current.peek() {
+		c.err = rows.Err()
+		c.done = true
+		rows.Close()
+		return false
+	}
+	rows.SQLRows = current
+	c.rows, c.current = rows, current
+	return true
+}
+
+// Rows returns the rows of the batch read by Next, valid until the next
+// call.
+func (c *Cursor) Rows() *Rows {
+	return c.rows
+}
+
+// Scan scans the rows of the batch read by Next into dest like Select does.
+func (c *Cursor) Scan(dest any) error {
+	if c.rows == nil {
+		return sql.ErrNoRows
+	}
+	return ScannAll(c.rows, dest, false)
+}
+
+// Err returns the error which stopped Next, if any.
+func (c *Cursor) Err() error {
+	return c.err
+}
+
+// Close releases the current batch and, on PostgreSQL, closes the cursor
+// and ends its transaction.
+func (c *Cursor) Close() error {
+	c.done = true
+	var err error
+	if c.rows != nil {
+		err = c.rows.Close()
+		c.rows, c.current = nil, nil
+	}
+	if c.tx != nil {
+		if _, cerr := c.tx.ExecContext(context.Background(), "CLOSE "+c.name); cerr != nil {
+			_ = c.tx.Rollback()
+			c.tx = nil
+			return cerr
+		}
+		if cerr := c.tx.Commit(); cerr != nil && err == nil {
+			err = cerr
+		}
+		c.tx = nil
+	}
+	return err
+}
+
+func (c *Cursor) fetch(ctx context.Context) (*Rows, error) {
+	if c.tx != nil {
+		return c.tx.QueryxContext(ctx, fmt.Sprintf("FETCH FORWARD %d FROM %s", c.batchSize, c.name))
+	}
+	if c.key != "" && c.after == nil {
+		return nil, fmt.Errorf("cursor key %s is NULL", c.key)
+	}
+	query, args := c.db.KeysetQuery("("+c.query+") AS squealx_cursor", c.key, c.batchSize, c.after, c.args...)
+	rows, err := c.db.QueryxContext(ctx, query, args...)
+	if err != nil || c.key != "" {
+		return rows, err
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		rows.Close()
+		return nil, err
+	}
+	if len(columns) == 0 {
+		rows.Close()
+		return nil, fmt.Errorf("cursor query returns no columns")
+	}
+	c.key = columns[0]
+	return rows, nil
+}
+
+// KeysetQuery returns the query reading the size rows of from following the
+// row whose key column is after, in the order of key, and its arguments:
+// args followed by after. from is a table, or a parenthesized query with an
+// alias whose placeholders args bind. Without after, the first rows are
+// read; without key, rows are ordered by their first column.
+//
+// Reading a table in batches this way is stable under concurrent writes,
+// unlike OFFSET, provided key is unique and not NULL.
+func (db *DB) KeysetQuery(from, key string, size int, after any, args ...any) (string, []any) {
+	dialect := Dialect(db.driverName)
+	var b strings.Builder
+	b.WriteString("SELECT ")
+	if dialect == DialectMSSQL {
+		fmt.Fprintf(&b, "TOP %d ", size)
+	}
+	b.WriteString("* FROM " + from)
+	order := "1"
+	if key != "" {
+		order = QuoteIdent(dialect, key)
+		if after != nil {
+			args = append(args[:len(args):len(args)], after)
+			b.WriteString(" WHERE " + order + " > " + bindvar(BindType(db.driverName), len(args)))
+		}
+	}
+	b.WriteString(" ORDER BY " + order)
+	if dialect != DialectMSSQL {
+		fmt.Fprintf(&b, " LIMIT %d", size)
+	}
+	return b.String(), args
+}
+
+// finishBatch reads the rest of the current batch, recording the key of its
+// last row, and closes it.
+func (c *Cursor) finishBatch() error {
+	defer func() {
+		c.rows.Close()
+		c.rows, c.current = nil, nil
+	}()
+	// Scan reads the whole batch and closes its rows.
+	if !c.current.exhausted {
+		columns, err := c.rows.Columns()
+		if err != nil {
+			return err
+		}
+		values := make([]any, len(columns))
+		for i := range values {
+			values[i] = new(any)
+		}
+		for c.rows.Next() {
+			if err := c.rows.Scan(values...); err != nil {
+				return err
+			}
+		}
+		if err := c.rows.Err(); err != nil {
+			return err
+		}
+	}
+	if c.current.count < c.batchSize {
+		c.done = true
+	}
+	c.after = c.current.last
+	return nil
+}
+
+// cursorRows counts the rows of a batch and records the first column of the
+// last row scanned, the key of the next keyset batch.
+type cursorRows struct {
+	SQLRows
+	peeked    bool
+	exhausted bool
+	count     int
+	last      any
+}
+
+// peek advances to the first row, reporting whether there is one. The row is
+// returned by the next call to Next.
+func (r *cursorRows) peek() bool {
+	r.peeked = r.SQLRows.Next()
+	return r.peeked
+}
+
+func (r *cursorRows) Next() bool {
+	if r.peeked {
+		r.peeked = false
+	} else if !r.SQLRows.Next() {
+		r.exhausted = true
+		return false
+	}
+	r.count++
+	return true
+}
+
+func (r *cursorRows) Scan(dest ...any) error {
+	if err := r.SQLRows.Scan(dest...); err != nil {
+		return err
+	}
+	if len(dest) > 0 {
+		r.last = keyValue(dest[0])
+	}
+	return nil
+}
+
+// keyValue returns the value scanned into dest, copying bytes which the
+// driver may reuse.
+func keyValue(dest any) any {
+	v := reflect.ValueOf(dest)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	value := v.Interface()
+	if valuer, ok := value.(driver.Valuer); ok {
+		value, _ = valuer.Value()
+	}
+	switch b := value.(type) {
+	case sql.RawBytes:
+		return string(b)
+	case []byte:
+		return string(b)
+	}
+	return value
+}
+
+// bindvar returns the nth placeholder of bindType.
+func bindvar(bindType, n int) string {
+	switch bindType {
+	case DOLLAR:
+		return "$" + strconv.Itoa(n)
+	case AT:
+		return "@p" + strconv.Itoa(n)
+	case NAMED:
+		return ":arg" + strconv.Itoa(n)
+	}
+	return "?"
+}
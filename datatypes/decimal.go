@@ -0,0 +1,294 @@
+package datatypes
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/oarkflow/squealx"
+)
+
+// Decimal is an exact decimal number for NUMERIC and DECIMAL columns,
+// which keeps the digits scanned from the database instead of rounding them
+// to a float64. Like shopspring/decimal, it is written to the database and
+// to JSON as a string.
+type Decimal struct {
+	// unscaled is the value multiplied by 10^scale; nil is zero.
+	unscaled *big.Int
+	scale    int32
+}
+
+// NewDecimal returns unscaled * 10^-scale.
+func NewDecimal(unscaled int64, scale int32) Decimal {
+	d := Decimal{unscaled: big.NewInt(unscaled), scale: scale}
+	if scale < 0 {
+		d.unscaled.Mul(d.unscaled, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(-scale)), nil))
+		d.scale = 0
+	}
+	return d
+}
+
+// maxDecimalScale bounds the scale of the decimals ParseDecimal accepts,
+// either way, so that an exponent such as 1e-2000000000 cannot exhaust
+// memory. It is the number of digits PostgreSQL allows before the point of
+// a NUMERIC, and more than it allows after.
+const maxDecimalScale = 131072
+
+// ParseDecimal parses a decimal number such as "-12.340" or "1.5e3". Its
+// scale, the number of digits after the point once the exponent is applied,
+// must be within ±131072.
+func ParseDecimal(s string) (Decimal, error) {
+	s = strings.TrimSpace(s)
+	mantissa, exponent := s, int64(0)
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		exp, err := strconv.ParseInt(s[i+1:], 10, 32)
+		if err != nil {
+			return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+		}
+		mantissa, exponent = s[:i], exp
+	}
+	intPart, fracPart, _ := strings.Cut(mantissa, ".")
+	digits := intPart + fracPart
+	if digits == "" || digits == "-" || digits == "+" {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+	}
+	unscaled, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+	}
+	scale := int64(len(fracPart)) - exponent
+	if scale > maxDecimalScale || scale < -maxDecimalScale {
+		return Decimal{}, fmt.Errorf("decimal %q out of range", s)
+	}
+	if scale < 0 {
+		unscaled.Mul(unscaled, new(big.Int).Exp(big.NewInt(10), big.NewInt(-scale), nil))
+		scale = 0
+	}
+	return Decimal{unscaled: unscaled, scale: int32(scale)}, nil
+}
+
+func (d Decimal) int() *big.Int {
+	if d.unscaled == nil {
+		return new(big.Int)
+	}
+	return d.unscaled
+}
+
+// String returns d with its scale, such as "12.340".
+func (d Decimal) String() string {
+	digits := new(big.Int).Abs(d.int()).String()
+	sign := ""
+	if d.int().Sign() < 0 {
+		sign = "-"
+	}
+	if d.scale <= 0 {
+		return sign + digits
+	}
+	if pad := int(d.scale) + 1 - len(digits); pad > 0 {
+		digits = strings.Repeat("0", pad) + digits
+	}
+	point := len(digits) - int(d.scale)
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+// Scale returns the number of digits of d after the decimal point.
+func (d Decimal) Scale() int32 {
+	return d.scale
+}
+
+// Rat returns d as a big.Rat.
+func (d Decimal) Rat() *big.Rat {
+	denom := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(d.scale)), nil)
+	return new(big.Rat).SetFrac(d.int(), denom)
+}
+
+// Float64 returns the nearest float64 to d.
+func (d Decimal) Float64() float64 {
+	f, _ := d.Rat().Float64()
+	return f
+}
+
+// Cmp compares d and o, returning -1, 0 or +1.
+func (d Decimal) Cmp(o Decimal) int {
+	return d.Rat().Cmp(o.Rat())
+}
+
+// Scan implements the sql.Scanner interface.
+func (d *Decimal) Scan(src any) error {
+	switch v := src.(type) {
+	case []byte:
+		return d.parse(string(v))
+	case string:
+		return d.parse(v)
+	case int64:
+		*d = NewDecimal(v, 0)
+		return nil
+	case float64:
+		return d.parse(strconv.FormatFloat(v, 'f', -1, 64))
+	case nil:
+		return errors.New("cannot scan NULL into Decimal, use NullDecimal")
+	}
+	return fmt.Errorf("cannot scan %T into Decimal", src)
+}
+
+func (d *Decimal) parse(s string) error {
+	parsed, err := ParseDecimal(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// Value implements the driver.Valuer interface.
+func (d Decimal) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+// MarshalJSON encodes d as a JSON string.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON decodes a JSON string or number.
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	return d.parse(strings.Trim(string(data), `"`))
+}
+
+// NullDecimal is a Decimal that may be NULL.
+type NullDecimal struct {
+	Decimal
+	Valid bool // Valid is true if Decimal is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (n *NullDecimal) Scan(value any) error {
+	if value == nil {
+		n.Decimal, n.Valid = Decimal{}, false
+		return nil
+	}
+	n.Valid = true
+	return n.Decimal.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (n NullDecimal) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	return n.Decimal.Value()
+}
+
+// MarshalJSON encodes n as a JSON string, or null.
+func (n NullDecimal) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return []byte("null"), nil
+	}
+	return n.Decimal.MarshalJSON()
+}
+
+// UnmarshalJSON decodes a JSON string, number or null.
+func (n *NullDecimal) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		n.Decimal, n.Valid = Decimal{}, false
+		return nil
+	}
+	n.Valid = true
+	return n.Decimal.UnmarshalJSON(data)
+}
+
+// Money is an amount of the MONEY type of PostgreSQL and SQL Server. It
+// scans the locale formatted text of PostgreSQL, such as "$1,234.56",
+// "-$1.00" or "($1.00)", assuming "." as decimal separator.
+type Money struct {
+	Decimal
+}
+
+// Scan implements the sql.Scanner interface.
+func (m *Money) Scan(src any) error {
+	switch v := src.(type) {
+	case []byte:
+		return m.parse(string(v))
+	case string:
+		return m.parse(v)
+	case nil:
+		return errors.New("cannot scan NULL into Money, use NullMoney")
+	}
+	return m.Decimal.Scan(src)
+}
+
+func (m *Money) parse(s string) error {
+	negative := strings.Contains(s, "-") || strings.HasPrefix(strings.TrimSpace(s), "(")
+	var b strings.Builder
+	if negative {
+		b.WriteByte('-')
+	}
+	for _, r := range s {
+		if r >= '0' && r <= '9' || r == '.' {
+			b.WriteRune(r)
+		}
+	}
+	return m.Decimal.parse(b.String())
+}
+
+// NullMoney is a Money that may be NULL.
+type NullMoney struct {
+	Money
+	Valid bool // Valid is true if Money is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (n *NullMoney) Scan(value any) error {
+	if value == nil {
+		n.Money, n.Valid = Money{}, false
+		return nil
+	}
+	n.Valid = true
+	return n.Money.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (n NullMoney) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	return n.Money.Value()
+}
+
+// MarshalJSON encodes n as a JSON string, or null.
+func (n NullMoney) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return []byte("null"), nil
+	}
+	return n.Money.MarshalJSON()
+}
+
+// UnmarshalJSON decodes a JSON string, number or null.
+func (n *NullMoney) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		n.Money, n.Valid = Money{}, false
+		return nil
+	}
+	n.Valid = true
+	return n.Money.UnmarshalJSON(data)
+}
+
+// RegisterScanTypes makes squealx.MapScan and squealx.SliceScan, and so
+// selects into maps, scan NUMERIC and DECIMAL columns into NullDecimal,
+// MONEY columns into NullMoney, INTERVAL columns into NullInterval and UUID
+// columns into NullUUID, rather than into floats and strings.
+func RegisterScanTypes() {
+	squealx.RegisterScanType("UUID", func() sql.Scanner { return new(NullUUID) })
+	for _, name := range []string{"NUMERIC", "DECIMAL"} {
+		squealx.RegisterScanType(name, func() sql.Scanner { return new(NullDecimal) })
+	}
+	for _, name := range []string{"MONEY", "SMALLMONEY"} {
+		squealx.RegisterScanType(name, func() sql.Scanner { return new(NullMoney) })
+	}
+	squealx.RegisterScanType("INTERVAL", func() sql.Scanner { return new(NullInterval) })
+}
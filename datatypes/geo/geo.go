@@ -0,0 +1,531 @@
+// Package geo provides spatial column types for PostGIS and MySQL, and
+// predicates for the orm query builder.
+//
+// Point and Polygon scan the (E)WKB returned by PostGIS, as hex text or
+// binary, the internal format returned by MySQL, and WKT such as the output
+// of ST_AsText. Their values are written as WKT, prefixed with the SRID
+// (EWKT) when it is set, which PostGIS casts to geometry. MySQL does not
+// accept text for spatial columns: wrap the placeholder with
+// ST_GeomFromText(?, srid) and pass the WKT of the value.
+package geo
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/oarkflow/squealx/orm"
+)
+
+// ErrUnsupportedGeometry is returned when scanning a geometry of another
+// type than the destination, or one this package does not decode.
+var ErrUnsupportedGeometry = errors.New("geo: unsupported geometry")
+
+// Geometry is a Point or a Polygon.
+type Geometry interface {
+	// WKT returns the well-known text of the geometry.
+	WKT() string
+	spatialRef() int
+	geoJSON() any
+}
+
+// Point is a position; X is the longitude and Y the latitude in geographic
+// reference systems.
+type Point struct {
+	X, Y float64
+	// SRID is the spatial reference system of the point, such as 4326 for
+	// WGS 84. Zero leaves it unspecified.
+	SRID int
+}
+
+// Polygon is an area bounded by an exterior ring, followed by the rings of
+// its holes. Rings are closed: their last position repeats the first.
+type Polygon struct {
+	Rings [][][2]float64
+	SRID  int
+}
+
+func (p Point) WKT() string {
+	return "POINT(" + coord(p.X, p.Y) + ")"
+}
+
+func (p Polygon) WKT() string {
+	var b strings.Builder
+	b.WriteString("POLYGON(")
+	for i, ring := range p.Rings {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteByte('(')
+		for j, c := range ring {
+			if j > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(coord(c[0], c[1]))
+		}
+		b.WriteByte(')')
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+func coord(x, y float64) string {
+	return strconv.FormatFloat(x, 'f', -1, 64) + " " + strconv.FormatFloat(y, 'f', -1, 64)
+}
+
+func (p Point) spatialRef() int   { return p.SRID }
+func (p Polygon) spatialRef() int { return p.SRID }
+
+// Value implements the driver.Valuer interface, returning the (E)WKT of p.
+func (p Point) Value() (driver.Value, error) {
+	return ewkt(p), nil
+}
+
+// Value implements the driver.Valuer interface, returning the (E)WKT of p.
+func (p Polygon) Value() (driver.Value, error) {
+	return ewkt(p), nil
+}
+
+func ewkt(g Geometry) string {
+	if srid := g.spatialRef(); srid != 0 {
+		return "SRID=" + strconv.Itoa(srid) + ";" + g.WKT()
+	}
+	return g.WKT()
+}
+
+// Scan implements the sql.Scanner interface.
+func (p *Point) Scan(src any) error {
+	g, err := scan(src)
+	if err != nil || g == nil {
+		return err
+	}
+	point, ok := g.(Point)
+	if !ok {
+		return fmt.Errorf("%w: %T into Point", ErrUnsupportedGeometry, g)
+	}
+	*p = point
+	return nil
+}
+
+// Scan implements the sql.Scanner interface.
+func (p *Polygon) Scan(src any) error {
+	g, err := scan(src)
+	if err != nil || g == nil {
+		return err
+	}
+	polygon, ok := g.(Polygon)
+	if !ok {
+		return fmt.Errorf("%w: %T into Polygon", ErrUnsupportedGeometry, g)
+	}
+	*p = polygon
+	return nil
+}
+
+func (p Point) geoJSON() any {
+	return map[string]any{"type": "Point", "coordinates": [2]float64{p.X, p.Y}}
+}
+
+func (p Polygon) geoJSON() any {
+	return map[string]any{"type": "Polygon", "coordinates": p.Rings}
+}
+
+// MarshalJSON encodes p as a GeoJSON geometry.
+func (p Point) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.geoJSON())
+}
+
+// MarshalJSON encodes p as a GeoJSON geometry.
+func (p Polygon) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.geoJSON())
+}
+
+// UnmarshalJSON decodes a GeoJSON Point.
+func (p *Point) UnmarshalJSON(data []byte) error {
+	g, err := decodeGeoJSON(data)
+	if err != nil {
+		return err
+	}
+	point, ok := g.(Point)
+	if !ok {
+		return fmt.Errorf("%w: %T into Point", ErrUnsupportedGeometry, g)
+	}
+	*p = point
+	return nil
+}
+
+// UnmarshalJSON decodes a GeoJSON Polygon.
+func (p *Polygon) UnmarshalJSON(data []byte) error {
+	g, err := decodeGeoJSON(data)
+	if err != nil {
+		return err
+	}
+	polygon, ok := g.(Polygon)
+	if !ok {
+		return fmt.Errorf("%w: %T into Polygon", ErrUnsupportedGeometry, g)
+	}
+	*p = polygon
+	return nil
+}
+
+// GeoJSON holds a geometry of any supported type, encoded as GeoJSON. It
+// scans the output of ST_AsGeoJSON as well as the formats scanned by Point
+// and Polygon, and its value is the GeoJSON text, for JSON columns or
+// ST_GeomFromGeoJSON(?).
+type GeoJSON struct {
+	Geometry Geometry
+}
+
+// Scan implements the sql.Scanner interface.
+func (g *GeoJSON) Scan(src any) error {
+	if text, ok := asText(src); ok && strings.HasPrefix(strings.TrimSpace(text), "{") {
+		geometry, err := decodeGeoJSON([]byte(text))
+		if err != nil {
+			return err
+		}
+		g.Geometry = geometry
+		return nil
+	}
+	geometry, err := scan(src)
+	if err != nil {
+		return err
+	}
+	g.Geometry = geometry
+	return nil
+}
+
+// Value implements the driver.Valuer interface.
+func (g GeoJSON) Value() (driver.Value, error) {
+	if g.Geometry == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(g.Geometry.geoJSON())
+	return string(b), err
+}
+
+// MarshalJSON encodes the geometry of g, or null.
+func (g GeoJSON) MarshalJSON() ([]byte, error) {
+	if g.Geometry == nil {
+		return []byte("null"), nil
+	}
+	return json.Marshal(g.Geometry.geoJSON())
+}
+
+// UnmarshalJSON decodes a GeoJSON geometry.
+func (g *GeoJSON) UnmarshalJSON(data []byte) error {
+	if string(bytes.TrimSpace(data)) == "null" {
+		g.Geometry = nil
+		return nil
+	}
+	geometry, err := decodeGeoJSON(data)
+	if err != nil {
+		return err
+	}
+	g.Geometry = geometry
+	return nil
+}
+
+func decodeGeoJSON(data []byte) (Geometry, error) {
+	var object struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, err
+	}
+	switch object.Type {
+	case "Point":
+		var c [2]float64
+		if err := json.Unmarshal(object.Coordinates, &c); err != nil {
+			return nil, err
+		}
+		return Point{X: c[0], Y: c[1]}, nil
+	case "Polygon":
+		var rings [][][2]float64
+		if err := json.Unmarshal(object.Coordinates, &rings); err != nil {
+			return nil, err
+		}
+		return Polygon{Rings: rings}, nil
+	}
+	return nil, fmt.Errorf("%w: GeoJSON %s", ErrUnsupportedGeometry, object.Type)
+}
+
+func asText(src any) (string, bool) {
+	switch src := src.(type) {
+	case string:
+		return src, true
+	case []byte:
+		return string(src), true
+	}
+	return "", false
+}
+
+// scan decodes src, returning nil for NULL.
+func scan(src any) (Geometry, error) {
+	switch src := src.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return decodeText(src)
+	case []byte:
+		if isText(src) {
+			return decodeText(string(src))
+		}
+		g, err := decodeWKB(src)
+		if err == nil || len(src) <= 4 {
+			return g, err
+		}
+		// MySQL stores the SRID ahead of the WKB.
+		g, mysqlErr := decodeWKB(src[4:])
+		if mysqlErr != nil {
+			return nil, err
+		}
+		return withSRID(g, int(binary.LittleEndian.Uint32(src[:4]))), nil
+	}
+	return nil, fmt.Errorf("geo: cannot scan %T", src)
+}
+
+// isText reports whether b is hex text or WKT rather than binary. Binary
+// starts with the byte order of WKB, or the SRID of MySQL.
+func isText(b []byte) bool {
+	for _, c := range b {
+		if c < 0x20 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// decodeText decodes hex encoded (E)WKB or (E)WKT.
+func decodeText(s string) (Geometry, error) {
+	s = strings.TrimSpace(s)
+	if b, err := hex.DecodeString(s); err == nil {
+		return decodeWKB(b)
+	}
+	srid := 0
+	if rest, ok := strings.CutPrefix(strings.ToUpper(s), "SRID="); ok {
+		value, wkt, found := strings.Cut(rest, ";")
+		if !found {
+			return nil, fmt.Errorf("geo: invalid EWKT %q", s)
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("geo: invalid EWKT %q", s)
+		}
+		srid, s = n, wkt
+	}
+	g, err := decodeWKT(s)
+	if err != nil {
+		return nil, err
+	}
+	return withSRID(g, srid), nil
+}
+
+func withSRID(g Geometry, srid int) Geometry {
+	switch g := g.(type) {
+	case Point:
+		g.SRID = srid
+		return g
+	case Polygon:
+		g.SRID = srid
+		return g
+	}
+	return g
+}
+
+func decodeWKT(s string) (Geometry, error) {
+	kind, body, ok := strings.Cut(strings.ToUpper(strings.TrimSpace(s)), "(")
+	if !ok || !strings.HasSuffix(body, ")") {
+		return nil, fmt.Errorf("geo: invalid WKT %q", s)
+	}
+	body = strings.TrimSuffix(body, ")")
+	switch strings.TrimSpace(kind) {
+	case "POINT":
+		c, err := parseCoord(body)
+		if err != nil {
+			return nil, err
+		}
+		return Point{X: c[0], Y: c[1]}, nil
+	case "POLYGON":
+		var p Polygon
+		for _, ring := range strings.Split(body, "),") {
+			ring = strings.Trim(strings.TrimSpace(ring), "()")
+			var coords [][2]float64
+			for _, c := range strings.Split(ring, ",") {
+				parsed, err := parseCoord(c)
+				if err != nil {
+					return nil, err
+				}
+				coords = append(coords, parsed)
+			}
+			p.Rings = append(p.Rings, coords)
+		}
+		return p, nil
+	}
+	return nil, fmt.Errorf("%w: WKT %s", ErrUnsupportedGeometry, strings.TrimSpace(kind))
+}
+
+func parseCoord(s string) ([2]float64, error) {
+	fields := strings.Fields(s)
+	if len(fields) < 2 {
+		return [2]float64{}, fmt.Errorf("geo: invalid coordinate %q", s)
+	}
+	x, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return [2]float64{}, err
+	}
+	y, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return [2]float64{}, err
+	}
+	return [2]float64{x, y}, nil
+}
+
+// EWKB type flags.
+const (
+	ewkbZ    = 0x80000000
+	ewkbM    = 0x40000000
+	ewkbSRID = 0x20000000
+)
+
+// decodeWKB decodes WKB, with the EWKB extensions of PostGIS.
+func decodeWKB(b []byte) (Geometry, error) {
+	r := wkbReader{b: b}
+	g := r.geometry()
+	if r.err == nil && len(r.b) > 0 {
+		r.err = errors.New("geo: trailing bytes after WKB")
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return g, nil
+}
+
+type wkbReader struct {
+	b     []byte
+	order binary.ByteOrder
+	err   error
+}
+
+func (r *wkbReader) uint32() uint32 {
+	if r.err != nil {
+		return 0
+	}
+	if len(r.b) < 4 {
+		r.err = errors.New("geo: truncated WKB")
+		return 0
+	}
+	v := r.order.Uint32(r.b)
+	r.b = r.b[4:]
+	return v
+}
+
+func (r *wkbReader) float64() float64 {
+	if r.err != nil {
+		return 0
+	}
+	if len(r.b) < 8 {
+		r.err = errors.New("geo: truncated WKB")
+		return 0
+	}
+	v := math.Float64frombits(r.order.Uint64(r.b))
+	r.b = r.b[8:]
+	return v
+}
+
+func (r *wkbReader) geometry() Geometry {
+	if len(r.b) < 1 {
+		r.err = errors.New("geo: truncated WKB")
+		return nil
+	}
+	if r.b[0] == 0 {
+		r.order = binary.BigEndian
+	} else {
+		r.order = binary.LittleEndian
+	}
+	r.b = r.b[1:]
+	typ := r.uint32()
+	srid := 0
+	if typ&ewkbSRID != 0 {
+		srid = int(r.uint32())
+	}
+	dims := 2
+	if typ&ewkbZ != 0 {
+		dims++
+	}
+	if typ&ewkbM != 0 {
+		dims++
+	}
+	// ISO WKB encodes Z and M in the thousands of the type.
+	base := typ &^ (ewkbZ | ewkbM | ewkbSRID)
+	switch base / 1000 {
+	case 1, 2:
+		dims = 3
+	case 3:
+		dims = 4
+	}
+	base %= 1000
+	coord := func() [2]float64 {
+		c := [2]float64{r.float64(), r.float64()}
+		for i := 2; i < dims; i++ {
+			r.float64()
+		}
+		return c
+	}
+	switch base {
+	case 1:
+		c := coord()
+		return Point{X: c[0], Y: c[1], SRID: srid}
+	case 3:
+		p := Polygon{SRID: srid}
+		rings := r.uint32()
+		for i := uint32(0); i < rings && r.err == nil; i++ {
+			n := r.uint32()
+			ring := make([][2]float64, 0, min(n, 1024))
+			for j := uint32(0); j < n && r.err == nil; j++ {
+				ring = append(ring, coord())
+			}
+			p.Rings = append(p.Rings, ring)
+		}
+		return p
+	}
+	r.err = fmt.Errorf("%w: WKB type %d", ErrUnsupportedGeometry, base)
+	return nil
+}
+
+// geometryVar returns the expression of g for cond, as a placeholder.
+func geometryVar(cond *orm.Cond, g Geometry) string {
+	return "ST_GeomFromText(" + cond.Var(g.WKT()) + ", " + strconv.Itoa(g.spatialRef()) + ")"
+}
+
+// Within represents "ST_Within(field, g)": the geometry of field lies
+// within g.
+func Within(cond *orm.Cond, field string, g Geometry) string {
+	return "ST_Within(" + orm.Escape(field) + ", " + geometryVar(cond, g) + ")"
+}
+
+// Contains represents "ST_Contains(field, g)": the geometry of field
+// contains g.
+func Contains(cond *orm.Cond, field string, g Geometry) string {
+	return "ST_Contains(" + orm.Escape(field) + ", " + geometryVar(cond, g) + ")"
+}
+
+// DWithin represents that the geometry of field lies within distance of g,
+// in the units of the spatial reference system. It is ST_DWithin on
+// PostGIS, and compares ST_Distance on MySQL, which has no ST_DWithin.
+func DWithin(cond *orm.Cond, field string, g Geometry, distance float64) string {
+	flavor := cond.Args.Flavor
+	if flavor == 0 {
+		flavor = orm.DefaultFlavor
+	}
+	if flavor == orm.MySQL {
+		return "ST_Distance(" + orm.Escape(field) + ", " + geometryVar(cond, g) + ") <= " + cond.Var(distance)
+	}
+	return "ST_DWithin(" + orm.Escape(field) + ", " + geometryVar(cond, g) + ", " + cond.Var(distance) + ")"
+}
@@ -0,0 +1,292 @@
+package datatypes
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Interval is a span of time of the INTERVAL type of PostgreSQL, kept in
+// months, days and microseconds like the database does, as a month or a day
+// has no fixed length. It scans the postgres, postgres_verbose and
+// iso_8601 interval styles and MySQL TIME values, and is written as
+// ISO 8601.
+type Interval struct {
+	Months       int32
+	Days         int32
+	Microseconds int64
+}
+
+// IntervalOf returns the Interval of d.
+func IntervalOf(d time.Duration) Interval {
+	return Interval{Microseconds: d.Microseconds()}
+}
+
+// Duration returns i as a time.Duration, counting a day as 24 hours and a
+// month as 30 days, as PostgreSQL does when justifying intervals.
+func (i Interval) Duration() time.Duration {
+	days := int64(i.Months)*30 + int64(i.Days)
+	return time.Duration(days)*24*time.Hour + time.Duration(i.Microseconds)*time.Microsecond
+}
+
+// String returns i in ISO 8601 format, such as "P1Y2M3DT4H5M6.5S".
+func (i Interval) String() string {
+	if i == (Interval{}) {
+		return "PT0S"
+	}
+	var b strings.Builder
+	b.WriteByte('P')
+	if years := i.Months / 12; years != 0 {
+		fmt.Fprintf(&b, "%dY", years)
+	}
+	if months := i.Months % 12; months != 0 {
+		fmt.Fprintf(&b, "%dM", months)
+	}
+	if i.Days != 0 {
+		fmt.Fprintf(&b, "%dD", i.Days)
+	}
+	if i.Microseconds != 0 {
+		b.WriteByte('T')
+		us := i.Microseconds
+		if hours := us / int64(time.Hour/time.Microsecond); hours != 0 {
+			fmt.Fprintf(&b, "%dH", hours)
+			us -= hours * int64(time.Hour/time.Microsecond)
+		}
+		if minutes := us / int64(time.Minute/time.Microsecond); minutes != 0 {
+			fmt.Fprintf(&b, "%dM", minutes)
+			us -= minutes * int64(time.Minute/time.Microsecond)
+		}
+		if us != 0 {
+			b.WriteString(strconv.FormatFloat(float64(us)/1e6, 'f', -1, 64))
+			b.WriteByte('S')
+		}
+	}
+	return b.String()
+}
+
+// ParseInterval parses an interval in the postgres ("1 year 2 mons 3 days
+// 04:05:06"), postgres_verbose ("@ 1 year 2 mons ago") or ISO 8601
+// ("P1Y2M3DT4H5M6S") styles, or a clock time ("-838:59:59").
+func ParseInterval(s string) (Interval, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "P") || strings.HasPrefix(s, "-P") {
+		return parseISOInterval(s)
+	}
+	var i Interval
+	fields := strings.Fields(strings.TrimPrefix(s, "@"))
+	ago := len(fields) > 0 && fields[len(fields)-1] == "ago"
+	if ago {
+		fields = fields[:len(fields)-1]
+	}
+	for n := 0; n < len(fields); n++ {
+		field := fields[n]
+		if strings.Contains(field, ":") {
+			us, err := parseClock(field)
+			if err != nil {
+				return Interval{}, fmt.Errorf("invalid interval %q", s)
+			}
+			i.Microseconds += us
+			continue
+		}
+		if n+1 >= len(fields) {
+			return Interval{}, fmt.Errorf("invalid interval %q", s)
+		}
+		value, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return Interval{}, fmt.Errorf("invalid interval %q", s)
+		}
+		n++
+		switch unit := strings.TrimSuffix(strings.ToLower(fields[n]), "s"); unit {
+		case "year":
+			i.Months += int32(value * 12)
+		case "mon", "month":
+			i.Months += int32(value)
+		case "week":
+			i.Days += int32(value * 7)
+		case "day":
+			i.Days += int32(value)
+		case "hour":
+			i.Microseconds += int64(value * float64(time.Hour/time.Microsecond))
+		case "min", "minute":
+			i.Microseconds += int64(value * float64(time.Minute/time.Microsecond))
+		case "sec", "second":
+			i.Microseconds += int64(value * 1e6)
+		default:
+			return Interval{}, fmt.Errorf("invalid interval %q: unknown unit %q", s, fields[n])
+		}
+	}
+	if ago {
+		i = Interval{Months: -i.Months, Days: -i.Days, Microseconds: -i.Microseconds}
+	}
+	return i, nil
+}
+
+// parseClock parses [-]hh:mm[:ss[.ffffff]] into microseconds.
+func parseClock(s string) (int64, error) {
+	sign := int64(1)
+	if rest, ok := strings.CutPrefix(s, "-"); ok {
+		sign, s = -1, rest
+	}
+	s = strings.TrimPrefix(s, "+")
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, errors.New("invalid clock")
+	}
+	hours, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	minutes, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	seconds := 0.0
+	if len(parts) == 3 {
+		if seconds, err = strconv.ParseFloat(parts[2], 64); err != nil {
+			return 0, err
+		}
+	}
+	us := hours*int64(time.Hour/time.Microsecond) + minutes*int64(time.Minute/time.Microsecond) + int64(seconds*1e6+0.5)
+	return sign * us, nil
+}
+
+func parseISOInterval(s string) (Interval, error) {
+	sign := 1.0
+	rest := s
+	if r, ok := strings.CutPrefix(rest, "-"); ok {
+		sign, rest = -1, r
+	}
+	rest = strings.TrimPrefix(rest, "P")
+	var i Interval
+	inTime := false
+	for rest != "" {
+		if rest[0] == 'T' {
+			inTime, rest = true, rest[1:]
+			continue
+		}
+		end := strings.IndexAny(rest, "YMWDHS")
+		if end <= 0 {
+			return Interval{}, fmt.Errorf("invalid interval %q", s)
+		}
+		value, err := strconv.ParseFloat(rest[:end], 64)
+		if err != nil {
+			return Interval{}, fmt.Errorf("invalid interval %q", s)
+		}
+		value *= sign
+		switch unit := rest[end]; {
+		case unit == 'Y':
+			i.Months += int32(value * 12)
+		case unit == 'M' && !inTime:
+			i.Months += int32(value)
+		case unit == 'W':
+			i.Days += int32(value * 7)
+		case unit == 'D':
+			i.Days += int32(value)
+		case unit == 'H':
+			i.Microseconds += int64(value * float64(time.Hour/time.Microsecond))
+		case unit == 'M':
+			i.Microseconds += int64(value * float64(time.Minute/time.Microsecond))
+		case unit == 'S':
+			i.Microseconds += int64(value * 1e6)
+		}
+		rest = rest[end+1:]
+	}
+	return i, nil
+}
+
+// Scan implements the sql.Scanner interface.
+func (i *Interval) Scan(src any) error {
+	var s string
+	switch v := src.(type) {
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	case int64:
+		// Drivers reporting intervals as durations, in nanoseconds.
+		*i = IntervalOf(time.Duration(v))
+		return nil
+	case nil:
+		return errors.New("cannot scan NULL into Interval, use NullInterval")
+	default:
+		return fmt.Errorf("cannot scan %T into Interval", src)
+	}
+	parsed, err := ParseInterval(s)
+	if err != nil {
+		return err
+	}
+	*i = parsed
+	return nil
+}
+
+// Value implements the driver.Valuer interface, returning i in ISO 8601
+// format.
+func (i Interval) Value() (driver.Value, error) {
+	return i.String(), nil
+}
+
+// MarshalJSON encodes i as an ISO 8601 JSON string.
+func (i Interval) MarshalJSON() ([]byte, error) {
+	return json.Marshal(i.String())
+}
+
+// UnmarshalJSON decodes a JSON string in any format parsed by
+// ParseInterval.
+func (i *Interval) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := ParseInterval(s)
+	if err != nil {
+		return err
+	}
+	*i = parsed
+	return nil
+}
+
+// NullInterval is an Interval that may be NULL.
+type NullInterval struct {
+	Interval
+	Valid bool // Valid is true if Interval is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (n *NullInterval) Scan(value any) error {
+	if value == nil {
+		n.Interval, n.Valid = Interval{}, false
+		return nil
+	}
+	n.Valid = true
+	return n.Interval.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (n NullInterval) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	return n.Interval.Value()
+}
+
+// MarshalJSON encodes n as a JSON string, or null.
+func (n NullInterval) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return []byte("null"), nil
+	}
+	return n.Interval.MarshalJSON()
+}
+
+// UnmarshalJSON decodes a JSON string or null.
+func (n *NullInterval) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		n.Interval, n.Valid = Interval{}, false
+		return nil
+	}
+	n.Valid = true
+	return n.Interval.UnmarshalJSON(data)
+}
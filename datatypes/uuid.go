@@ -0,0 +1,251 @@
+package datatypes
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/oarkflow/squealx"
+)
+
+// UUID is a UUID for uuid columns of PostgreSQL, uniqueidentifier columns of
+// SQL Server and char(36) columns, to which it is written as text. Its
+// layout is that of github.com/google/uuid, so the two convert to each other
+// with a type conversion. It scans text in the standard, braced, urn or
+// undashed forms as well as the 16 bytes of binary(16) columns; use
+// BinaryUUID to write the bytes of binary(16) columns, as MySQL has no UUID
+// type.
+type UUID [16]byte
+
+// NilUUID is the UUID with all bits zero.
+var NilUUID UUID
+
+// NewUUID returns a new random, version 4, UUID.
+func NewUUID() UUID {
+	var u UUID
+	_, _ = rand.Read(u[:])
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	return u
+}
+
+// NewUUIDv7 returns a new version 7 UUID, which sorts roughly in generation
+// order and so keeps inserts into primary key indexes local.
+func NewUUIDv7() UUID {
+	u, _ := ParseUUID(squealx.NewUUIDv7())
+	return u
+}
+
+// ParseUUID parses s in the forms "xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx",
+// "{xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx}",
+// "urn:uuid:xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx" and
+// "xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx".
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	switch len(s) {
+	case 36:
+	case 38:
+		if s[0] != '{' || s[37] != '}' {
+			return u, fmt.Errorf("invalid UUID %q", s)
+		}
+		s = s[1:37]
+	case 45:
+		if s[:9] != "urn:uuid:" {
+			return u, fmt.Errorf("invalid UUID %q", s)
+		}
+		s = s[9:]
+	case 32:
+		if _, err := hex.Decode(u[:], []byte(s)); err != nil {
+			return u, fmt.Errorf("invalid UUID %q", s)
+		}
+		return u, nil
+	default:
+		return u, fmt.Errorf("invalid UUID %q", s)
+	}
+	if s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, fmt.Errorf("invalid UUID %q", s)
+	}
+	undashed := s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:]
+	if _, err := hex.Decode(u[:], []byte(undashed)); err != nil {
+		return u, fmt.Errorf("invalid UUID %q", s)
+	}
+	return u, nil
+}
+
+// MustParseUUID is like ParseUUID but panics if s cannot be parsed.
+func MustParseUUID(s string) UUID {
+	u, err := ParseUUID(s)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+// String returns u in the standard form, in lower case.
+func (u UUID) String() string {
+	var out [36]byte
+	hex.Encode(out[0:8], u[0:4])
+	out[8] = '-'
+	hex.Encode(out[9:13], u[4:6])
+	out[13] = '-'
+	hex.Encode(out[14:18], u[6:8])
+	out[18] = '-'
+	hex.Encode(out[19:23], u[8:10])
+	out[23] = '-'
+	hex.Encode(out[24:], u[10:])
+	return string(out[:])
+}
+
+// IsNil reports whether u is NilUUID.
+func (u UUID) IsNil() bool {
+	return u == NilUUID
+}
+
+// Version returns the version of u, such as 4 or 7.
+func (u UUID) Version() int {
+	return int(u[6] >> 4)
+}
+
+// Scan implements the sql.Scanner interface.
+func (u *UUID) Scan(src any) error {
+	switch v := src.(type) {
+	case []byte:
+		if len(v) == 16 {
+			copy(u[:], v)
+			return nil
+		}
+		return u.parse(string(v))
+	case string:
+		return u.parse(v)
+	case UUID:
+		*u = v
+		return nil
+	case BinaryUUID:
+		*u = UUID(v)
+		return nil
+	case [16]byte:
+		*u = v
+		return nil
+	case nil:
+		return errors.New("cannot scan NULL into UUID, use NullUUID")
+	}
+	return fmt.Errorf("cannot scan %T into UUID", src)
+}
+
+func (u *UUID) parse(s string) error {
+	parsed, err := ParseUUID(s)
+	if err != nil {
+		return err
+	}
+	*u = parsed
+	return nil
+}
+
+// Value implements the driver.Valuer interface, returning u in the standard
+// form.
+func (u UUID) Value() (driver.Value, error) {
+	return u.String(), nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface, so that u is
+// a string in JSON.
+func (u UUID) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (u *UUID) UnmarshalText(data []byte) error {
+	return u.parse(string(data))
+}
+
+// BinaryUUID is a UUID written as its 16 bytes, for the binary(16) columns
+// UUIDs are stored in on MySQL. It scans and marshals like UUID.
+type BinaryUUID UUID
+
+// String returns u in the standard form, in lower case.
+func (u BinaryUUID) String() string {
+	return UUID(u).String()
+}
+
+// Scan implements the sql.Scanner interface.
+func (u *BinaryUUID) Scan(src any) error {
+	return (*UUID)(u).Scan(src)
+}
+
+// Value implements the driver.Valuer interface, returning the 16 bytes of u.
+func (u BinaryUUID) Value() (driver.Value, error) {
+	return u[:], nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (u BinaryUUID) MarshalText() ([]byte, error) {
+	return UUID(u).MarshalText()
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (u *BinaryUUID) UnmarshalText(data []byte) error {
+	return (*UUID)(u).UnmarshalText(data)
+}
+
+// NullUUID is a UUID that may be NULL.
+type NullUUID struct {
+	UUID
+	Valid bool // Valid is true if UUID is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (n *NullUUID) Scan(value any) error {
+	if value == nil {
+		n.UUID, n.Valid = NilUUID, false
+		return nil
+	}
+	n.Valid = true
+	return n.UUID.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (n NullUUID) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	return n.UUID.Value()
+}
+
+// MarshalJSON encodes n as a JSON string, or null.
+func (n NullUUID) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return []byte("null"), nil
+	}
+	return []byte(`"` + n.UUID.String() + `"`), nil
+}
+
+// UnmarshalJSON decodes a JSON string or null.
+func (n *NullUUID) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		n.UUID, n.Valid = NilUUID, false
+		return nil
+	}
+	if len(data) < 2 || data[0] != '"' || data[len(data)-1] != '"' {
+		return fmt.Errorf("invalid UUID %s", data)
+	}
+	n.Valid = true
+	return n.UUID.parse(string(data[1 : len(data)-1]))
+}
+
+// UUIDGenerator returns a squealx.IDGenerator of version 7 UUIDs for
+// repositories of tables with UUID primary keys, see
+// squealx.WithIDGenerator. The keys are BinaryUUIDs on MySQL, where UUIDs
+// are stored as binary(16), and UUIDs elsewhere.
+func UUIDGenerator(db *squealx.DB) squealx.IDGenerator {
+	binary := squealx.Dialect(db.DriverName()) == squealx.DialectMySQL
+	return squealx.IDGeneratorFunc(func(context.Context) (any, error) {
+		u := NewUUIDv7()
+		if binary {
+			return BinaryUUID(u), nil
+		}
+		return u, nil
+	})
+}
@@ -0,0 +1,169 @@
+// Package vector provides the column type of pgvector embeddings, distance
+// operators for the orm query builder and a helper creating the index of
+// similarity searches.
+//
+// A nearest neighbour search orders by the distance to the query vector:
+//
+//	sb := orm.Select("id").From("items")
+//	sb.OrderBy(vector.Distance(&sb.Cond, "embedding", query, vector.Cosine)).Limit(10)
+package vector
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/oarkflow/squealx"
+	"github.com/oarkflow/squealx/orm"
+)
+
+// ErrNotSupported is returned by EnsureVectorIndex on databases other than
+// PostgreSQL.
+var ErrNotSupported = errors.New("vector: pgvector requires PostgreSQL")
+
+// Vector is an embedding of a pgvector vector column. It is written as the
+// text form of pgvector, such as "[1,2.5,3]", and a NULL column scans into a
+// nil Vector.
+type Vector []float32
+
+// String returns v in the text form of pgvector.
+func (v Vector) String() string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, f := range v {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(f), 'g', -1, 32))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// Value implements the driver.Valuer interface.
+func (v Vector) Value() (driver.Value, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return v.String(), nil
+}
+
+// Scan implements the sql.Scanner interface.
+func (v *Vector) Scan(src any) error {
+	var s string
+	switch src := src.(type) {
+	case []byte:
+		s = string(src)
+	case string:
+		s = src
+	case nil:
+		*v = nil
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into Vector", src)
+	}
+	s = strings.TrimSpace(s)
+	if len(s) < 2 || s[0] != '[' || s[len(s)-1] != ']' {
+		return fmt.Errorf("invalid vector %q", s)
+	}
+	s = strings.TrimSpace(s[1 : len(s)-1])
+	if s == "" {
+		*v = Vector{}
+		return nil
+	}
+	parts := strings.Split(s, ",")
+	vec := make(Vector, len(parts))
+	for i, part := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 32)
+		if err != nil {
+			return fmt.Errorf("invalid vector %q", s)
+		}
+		vec[i] = float32(f)
+	}
+	*v = vec
+	return nil
+}
+
+// Metric is a distance function of pgvector.
+type Metric int
+
+const (
+	// L2 is the Euclidean distance, operator "<->".
+	L2 Metric = iota
+	// InnerProduct is the negative inner product, operator "<#>", so that
+	// smaller is closer like the other metrics.
+	InnerProduct
+	// Cosine is the cosine distance, operator "<=>".
+	Cosine
+)
+
+// Operator returns the SQL operator of m.
+func (m Metric) Operator() string {
+	switch m {
+	case InnerProduct:
+		return "<#>"
+	case Cosine:
+		return "<=>"
+	}
+	return "<->"
+}
+
+// opClass returns the index operator class of m.
+func (m Metric) opClass() string {
+	switch m {
+	case InnerProduct:
+		return "vector_ip_ops"
+	case Cosine:
+		return "vector_cosine_ops"
+	}
+	return "vector_l2_ops"
+}
+
+// Distance represents "field <op> v", the distance of the vector of field to
+// v under m, to select or order by.
+func Distance(cond *orm.Cond, field string, v Vector, m Metric) string {
+	return orm.Escape(field) + " " + m.Operator() + " " + cond.Var(v)
+}
+
+// L2Distance represents "field <-> v".
+func L2Distance(cond *orm.Cond, field string, v Vector) string {
+	return Distance(cond, field, v, L2)
+}
+
+// InnerProductDistance represents "field <#> v".
+func InnerProductDistance(cond *orm.Cond, field string, v Vector) string {
+	return Distance(cond, field, v, InnerProduct)
+}
+
+// CosineDistance represents "field <=> v".
+func CosineDistance(cond *orm.Cond, field string, v Vector) string {
+	return Distance(cond, field, v, Cosine)
+}
+
+// Within represents "field <op> v < maxDistance": the vector of field is
+// closer to v than maxDistance under m.
+func Within(cond *orm.Cond, field string, v Vector, m Metric, maxDistance float64) string {
+	return Distance(cond, field, v, m) + " < " + cond.Var(maxDistance)
+}
+
+// EnsureVectorIndex creates, unless it exists, the IVFFlat index of
+// similarity searches on column of table under m, named
+// "<table>_<column>_<opclass>_idx". lists is the number of clusters the
+// vectors are partitioned into, usually rows / 1000 up to a million rows;
+// the index should be created once the table holds representative data.
+func EnsureVectorIndex(ctx context.Context, db *squealx.DB, table, column string, lists int, m Metric) error {
+	if squealx.Dialect(db.DriverName()) != squealx.DialectPostgres {
+		return ErrNotSupported
+	}
+	if lists <= 0 {
+		return fmt.Errorf("vector: invalid number of lists %d", lists)
+	}
+	name := strings.NewReplacer(".", "_", `"`, "").Replace(table + "_" + column + "_" + m.opClass() + "_idx")
+	query := fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s USING ivfflat (%s %s) WITH (lists = %d)",
+		name, table, column, m.opClass(), lists)
+	_, err := db.ExecContext(ctx, query)
+	return err
+}
@@ -0,0 +1,166 @@
+package dbresolver
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oarkflow/squealx"
+	"github.com/oarkflow/squealx/sqlparse"
+)
+
+// tableCache caches the results of reads whose primary table is configured
+// with WithTableCache, keyed by query and arguments, in a squealx.CacheStore:
+// a bounded squealx.MemoryCache unless WithCacheStore sets another. Entries
+// expire after the TTL of their table and are dropped when a write to the
+// table is observed through the after hooks of the resolver's databases.
+//
+// Results are cached as JSON, so destinations must survive a JSON round
+// trip. Every table a query references is considered, including those of
+// subqueries, common table expressions and lateral joins: a read is only
+// cached when it references a single table of the database, so that a write
+// to any table it depends on invalidates it.
+type tableCache struct {
+	ttls  map[string]time.Duration
+	store squealx.CacheStore
+	// prefix sets the keys of the resolver apart in a shared store.
+	prefix string
+	mu     sync.Mutex
+	// written counts the writes to each table. It is part of the keys of
+	// the table, so a write moves it to new keys; the old ones are left to
+	// expire or be evicted by the store.
+	written map[string]uint64
+}
+
+func newTableCache(ttls map[string]time.Duration, store squealx.CacheStore) *tableCache {
+	if len(ttls) == 0 {
+		return nil
+	}
+	if store == nil {
+		store = squealx.NewMemoryCache()
+	}
+	return &tableCache{ttls: ttls, store: store, prefix: "squealx:resolver:" + squealx.NewULID() + ":", written: make(map[string]uint64)}
+}
+
+// lookup returns the cached table read by query and its TTL, or an empty
+// table when the query is not cached.
+func (c *tableCache) lookup(query string) (string, time.Duration) {
+	if c == nil {
+		return "", 0
+	}
+	stmt := sqlparse.Parse(query)
+	if stmt.Kind != sqlparse.Select || stmt.Opaque || len(stmt.Written()) > 0 {
+		return "", 0
+	}
+	tables := stmt.DatabaseTables()
+	if len(tables) != 1 {
+		return "", 0
+	}
+	table := strings.ToLower(tables[0].Name)
+	ttl, ok := c.ttls[table]
+	if !ok {
+		return "", 0
+	}
+	return table, ttl
+}
+
+// generation returns the write generation of table.
+func (c *tableCache) generation(table string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.written[table]
+}
+
+// storeKey returns the key of the store holding the entry of key for table
+// in generation gen.
+func (c *tableCache) storeKey(table, key string, gen uint64) string {
+	return c.prefix + table + ":" + strconv.FormatUint(gen, 10) + ":" + key
+}
+
+// get decodes the entry of key into dest, reporting whether it was found.
+// It also returns the write generation of table, to pass to set.
+func (c *tableCache) get(ctx context.Context, table, key string, dest any) (bool, uint64) {
+	gen := c.generation(table)
+	value, ok, err := c.store.Get(ctx, c.storeKey(table, key, gen))
+	if err != nil || !ok {
+		return false, gen
+	}
+	return json.Unmarshal(value, dest) == nil, gen
+}
+
+// set caches dest under key, unless table was written since generation gen
+// was read, as the result may predate the write.
+func (c *tableCache) set(ctx context.Context, table, key string, dest any, ttl time.Duration, gen uint64) {
+	value, err := json.Marshal(dest)
+	if err != nil || c.generation(table) != gen {
+		return
+	}
+	_ = c.store.Set(ctx, c.storeKey(table, key, gen), value, ttl)
+}
+
+// invalidate drops the entries of table.
+func (c *tableCache) invalidate(table string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.written[table]++
+}
+
+// After invalidates the tables written by query, including those written
+// by its data-modifying common table expressions.
+func (c *tableCache) After(ctx context.Context, query string, _ ...any) (context.Context, error) {
+	for _, t := range sqlparse.Parse(query).Written() {
+		table := strings.ToLower(t.Name)
+		if _, ok := c.ttls[table]; ok {
+			c.invalidate(table)
+		}
+	}
+	return ctx, nil
+}
+
+// observe registers the invalidation hook on db, under the prefix of the
+// cache so that forget can remove it.
+func (c *tableCache) observe(db *squealx.DB) {
+	if c != nil {
+		db.UseNamed(c.prefix, c)
+	}
+}
+
+// forget removes the invalidation hook from db.
+func (c *tableCache) forget(db *squealx.DB) {
+	if c != nil {
+		db.RemoveNamed(c.prefix)
+	}
+}
+
+func cacheKey(query string, args []any) (string, bool) {
+	b, err := json.Marshal(args)
+	if err != nil {
+		return "", false
+	}
+	return query + "\x00" + string(b), true
+}
+
+// cachedRead serves dest from the table cache when query reads a cached
+// table, and otherwise calls read, caching its result.
+func (r *dbResolver) cachedRead(ctx context.Context, dest any, query string, args []any, read func() error) error {
+	table, ttl := r.cache.lookup(query)
+	if table == "" {
+		return read()
+	}
+	key, ok := cacheKey(query, args)
+	if !ok {
+		return read()
+	}
+	found, gen := r.cache.get(ctx, table, key, dest)
+	if found {
+		return nil
+	}
+	if err := read(); err != nil {
+		return err
+	}
+	r.cache.set(ctx, table, key, dest, ttl, gen)
+	return nil
+}
@@ -0,0 +1,144 @@
+package dbresolver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/oarkflow/squealx"
+)
+
+// AuditReplicaInconsistency is the kind of the audit events reporting a
+// replica whose result differs from the primary's in VerifyConsistency.
+const AuditReplicaInconsistency = "replica_inconsistency"
+
+// ConsistencyReport is the result of VerifyConsistency.
+type ConsistencyReport struct {
+	Query string
+	// Primary is the ID of the primary database read.
+	Primary  string
+	Checksum string
+	Rows     int
+	// Mismatches lists the replicas whose result still differed from the
+	// primary's once the tolerance elapsed.
+	Mismatches []ConsistencyMismatch
+}
+
+// Consistent reports whether every replica returned the primary's result.
+func (r *ConsistencyReport) Consistent() bool {
+	return len(r.Mismatches) == 0
+}
+
+// ConsistencyMismatch is a replica whose result differs from the primary's.
+type ConsistencyMismatch struct {
+	ID       string
+	Checksum string
+	Rows     int
+	// Err is the error reading the replica, if the read failed.
+	Err error
+}
+
+// VerifyConsistency runs the read query on a primary database and on every
+// other readable database, comparing checksums of their results to detect
+// replication anomalies. Row order is ignored. Replicas whose result
+// differs are read again until tolerance elapses, to allow for replication
+// lag; those still differing are reported in the ConsistencyReport and to
+// the audit sink set with WithAuditSink. It is a diagnostic which reads
+// every database: do not run it on the request path.
+func (r *dbResolver) VerifyConsistency(query string, args []any, tolerance time.Duration) (*ConsistencyReport, error) {
+	return r.VerifyConsistencyContext(context.Background(), query, args, tolerance)
+}
+
+// VerifyConsistencyContext is like VerifyConsistency, with a context.
+func (r *dbResolver) VerifyConsistencyContext(ctx context.Context, query string, args []any, tolerance time.Duration) (*ConsistencyReport, error) {
+	query = r.GetQueryString(query)
+	primary, err := r.GetDBErr(ctx, r.masterIDs())
+	if err != nil {
+		return nil, err
+	}
+	checksum, rows, err := resultChecksum(ctx, primary, query, args)
+	if err != nil {
+		return nil, err
+	}
+	report := &ConsistencyReport{Query: query, Primary: primary.ID, Checksum: checksum, Rows: rows}
+	var pending []*squealx.DB
+	for _, db := range r.lookup(r.readIDs()) {
+		if db.ID != primary.ID && !slices.Contains(pending, db) {
+			pending = append(pending, db)
+		}
+	}
+	deadline := time.Now().Add(tolerance)
+	for {
+		var mismatches []ConsistencyMismatch
+		var retry []*squealx.DB
+		for _, db := range pending {
+			sum, n, err := resultChecksum(ctx, db, query, args)
+			if err == nil && sum == checksum {
+				continue
+			}
+			mismatches = append(mismatches, ConsistencyMismatch{ID: db.ID, Checksum: sum, Rows: n, Err: err})
+			retry = append(retry, db)
+		}
+		if len(retry) == 0 || !time.Now().Before(deadline) {
+			report.Mismatches = mismatches
+			break
+		}
+		pending = retry
+		wait := min(time.Until(deadline), max(tolerance/10, 10*time.Millisecond))
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if r.audit != nil {
+		for _, m := range report.Mismatches {
+			detail := map[string]any{
+				"db":               m.ID,
+				"checksum":         m.Checksum,
+				"rows":             m.Rows,
+				"primary":          report.Primary,
+				"primary_checksum": report.Checksum,
+				"primary_rows":     report.Rows,
+			}
+			if m.Err != nil {
+				detail["error"] = m.Err.Error()
+			}
+			r.audit.Audit(ctx, squealx.AuditEvent{
+				Kind:   AuditReplicaInconsistency,
+				Time:   time.Now(),
+				Query:  query,
+				Args:   args,
+				Detail: detail,
+			})
+		}
+	}
+	return report, nil
+}
+
+// resultChecksum reads query on db and returns a checksum of its rows,
+// independent of their order, and their number.
+func resultChecksum(ctx context.Context, db *squealx.DB, query string, args []any) (string, int, error) {
+	var rows []map[string]any
+	if err := db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return "", 0, err
+	}
+	encoded := make([]string, len(rows))
+	for i, row := range rows {
+		b, err := json.Marshal(row)
+		if err != nil {
+			return "", 0, err
+		}
+		encoded[i] = string(b)
+	}
+	slices.Sort(encoded)
+	h := sha256.New()
+	for _, row := range encoded {
+		h.Write([]byte(row))
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil)), len(rows), nil
+}
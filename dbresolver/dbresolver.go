@@ -5,6 +5,9 @@ import (
 	"database/sql"
 	"database/sql/driver"
 	"errors"
+	"fmt"
+	"maps"
+	"slices"
 	"sync"
 	"time"
 
@@ -16,6 +19,13 @@ var (
 	errNoPrimaryDB            = errors.New("dbresolver: no primary database")
 	errInvalidReadWritePolicy = errors.New("dbresolver: invalid read/write policy")
 	errNoDBToRead             = errors.New("dbresolver: no database to read")
+
+	// ErrNoDatabase is returned when there is no database to route a
+	// statement to.
+	ErrNoDatabase = errors.New("dbresolver: no database to route to")
+	// ErrUnknownDatabase is returned when the load balancer chooses a
+	// database ID which is not registered.
+	ErrUnknownDatabase = errors.New("dbresolver: unknown database")
 )
 
 // ReadWritePolicy is the read/write policy for the primary databases.
@@ -45,11 +55,16 @@ type Config struct {
 // This supposed to be aligned with sqlx.DB.
 // Some functions which must select from multiple database are only available for the primary DBResolver
 // or the first primary DBResolver (if using multi-primary). For example, `DriverName()`, `Unsafe()`.
+//
+// A DBResolver is safe for concurrent use: databases may be registered and
+// the default changed while queries run. Hooks registered through the
+// resolver apply to the databases registered at that time.
 type DBResolver interface {
 	Begin() (squealx.SQLTx, error)
 	BeginTx(ctx context.Context, opts *sql.TxOptions) (squealx.SQLTx, error)
 	BeginTxx(ctx context.Context, opts *sql.TxOptions) (*squealx.Tx, error)
 	Beginx() (*squealx.Tx, error)
+	BeginPinned(ctx context.Context, opts *sql.TxOptions) (*Tx, error)
 	BindNamed(query string, arg any) (string, []any, error)
 	Close() error
 	Use(db string) (*squealx.DB, error)
@@ -58,6 +73,7 @@ type DBResolver interface {
 	RegisterReplica(db *squealx.DB)
 	RegisterRead(db *squealx.DB)
 	GetDB(ctx context.Context, dbs []string) *squealx.DB
+	GetDBErr(ctx context.Context, dbs []string) (*squealx.DB, error)
 	Conn(ctx context.Context) (squealx.SQLConn, error)
 	Connx(ctx context.Context) (*squealx.Conn, error)
 	Driver() driver.Driver
@@ -112,8 +128,15 @@ type DBResolver interface {
 	ReplicaDBs() []*squealx.DB
 	ReadDBs() []*squealx.DB
 	LoadBalancer() LoadBalancer
+	NodeStats() []NodeStats
+	HedgeStats() HedgeStats
+	VerifyConsistency(query string, args []any, tolerance time.Duration) (*ConsistencyReport, error)
+	VerifyConsistencyContext(ctx context.Context, query string, args []any, tolerance time.Duration) (*ConsistencyReport, error)
 	GetQuery(string) *squealx.Query
+	SelectByName(dest any, name string, args ...any) error
+	SelectByNameContext(ctx context.Context, dest any, name string, args ...any) error
 	Paginate(query string, result any, paging squealx.Paging, params ...map[string]any) squealx.PaginatedResponse
+	PaginateContext(ctx context.Context, query string, result any, paging squealx.Paging, params ...any) squealx.PaginatedResponse
 	SetDefaultDB(db string)
 	UseDefault() (*squealx.DB, error)
 	UseBefore(hooks ...squealx.Hook)
@@ -131,6 +154,11 @@ type dbResolver struct {
 	policy       ReadWritePolicy
 	loadBalancer LoadBalancer
 	queryLoader  *squealx.FileLoader
+	txSettings   *squealx.TxSettings
+	cache        *tableCache
+	health       *healthTracker
+	hedge        *hedger
+	audit        squealx.AuditSink
 	mu           sync.RWMutex
 }
 
@@ -187,6 +215,16 @@ func New(opts ...OptionFunc) (DBResolver, error) {
 		}
 		defaultDB = options.defaultDB.ID
 	}
+	cache := newTableCache(options.tableTTLs, options.cacheStore)
+	_, stats := options.loadBalancer.(StatsLoadBalancer)
+	health := newHealthTracker(stats)
+	for _, db := range dbs {
+		cache.observe(db)
+		health.observe(db)
+	}
+	if options.healthInterval > 0 {
+		health.start(options.healthInterval)
+	}
 	return &dbResolver{
 		masters:      masterDBs,
 		replicas:     replicaDBs,
@@ -196,6 +234,11 @@ func New(opts ...OptionFunc) (DBResolver, error) {
 		defaultDB:    defaultDB,
 		dbs:          dbs,
 		policy:       options.readWritePolicy,
+		txSettings:   options.txSettings,
+		cache:        cache,
+		health:       health,
+		hedge:        newHedger(options.hedgeDelay),
+		audit:        options.auditSink,
 	}, nil
 }
 
@@ -220,80 +263,94 @@ func MustNew(opts ...OptionFunc) DBResolver {
 	return db
 }
 
-func (r *dbResolver) MasterDBs() (dbs []*squealx.DB) {
-	for _, db := range r.masters {
-		if val, exists := r.dbs[db]; exists {
-			dbs = append(dbs, val)
-		}
-	}
-	return
+func (r *dbResolver) MasterDBs() []*squealx.DB {
+	return r.lookup(r.masterIDs())
 }
 
 func (r *dbResolver) WithHooks(hooks ...any) {
-	for _, db := range r.dbs {
+	for _, db := range r.allDBs() {
 		db.Use(hooks...)
 	}
 }
 
 func (r *dbResolver) UseBefore(hooks ...squealx.Hook) {
-	for _, db := range r.dbs {
+	for _, db := range r.allDBs() {
 		db.UseBefore(hooks...)
 	}
 }
 
 func (r *dbResolver) UseAfter(hooks ...squealx.Hook) {
-	for _, db := range r.dbs {
+	for _, db := range r.allDBs() {
 		db.UseAfter(hooks...)
 	}
 }
 
 func (r *dbResolver) UseOnError(onError ...squealx.ErrorHook) {
-	for _, db := range r.dbs {
+	for _, db := range r.allDBs() {
 		db.UseOnError(onError...)
 	}
 }
 
+// GetDB returns the default database, or the one of dbs chosen by the load
+// balancer. It returns nil when no database can be chosen; GetDBErr reports
+// why.
 func (r *dbResolver) GetDB(ctx context.Context, dbs []string) *squealx.DB {
-	var db *squealx.DB
-	var err error
-	defer func(err error) {
-		if err != nil {
-			panic(err)
-		}
-	}(err)
-	if r.defaultDB != "" {
-		db, err = r.getDB(r.defaultDB)
-		return db
-	}
-	db, err = r.getDB(r.loadBalancer.Select(ctx, dbs))
+	db, _ := r.GetDBErr(ctx, dbs)
 	return db
 }
 
+// GetDBErr is like GetDB, returning ErrNoDatabase when there is no database
+// to choose from and ErrUnknownDatabase when the chosen ID is not
+// registered. The query methods of the resolver return these errors.
+func (r *dbResolver) GetDBErr(ctx context.Context, dbs []string) (*squealx.DB, error) {
+	if id := r.defaultID(); id != "" {
+		return r.getDB(id)
+	}
+	if len(dbs) == 0 {
+		return nil, ErrNoDatabase
+	}
+	return r.getDB(r.selectDB(ctx, dbs))
+}
+
+// selectDB chooses one of dbs with the load balancer, passing it the stats
+// of dbs when it is a StatsLoadBalancer, and otherwise the healthy ones.
+func (r *dbResolver) selectDB(ctx context.Context, dbs []string) string {
+	if lb, ok := r.loadBalancer.(StatsLoadBalancer); ok {
+		return lb.SelectStats(ctx, r.health.stats(dbs))
+	}
+	return r.loadBalancer.Select(ctx, r.health.healthy(dbs))
+}
+
+// NodeStats returns the stats of every database of the resolver, as passed
+// to a StatsLoadBalancer.
+func (r *dbResolver) NodeStats() []NodeStats {
+	ids := slices.Sorted(maps.Keys(r.allDBs()))
+	return r.health.stats(ids)
+}
+
 func (r *dbResolver) SetDefaultDB(db string) {
 	if db != "" {
+		r.mu.Lock()
 		r.defaultDB = db
+		r.mu.Unlock()
 	}
 }
 
 func (r *dbResolver) UseDefault() (*squealx.DB, error) {
-	if r.defaultDB == "" {
+	id := r.defaultID()
+	if id == "" {
 		return nil, errors.New("no default database set")
 	}
-	return r.Use(r.defaultDB)
+	return r.Use(id)
 }
 
-func (r *dbResolver) ReplicaDBs() (dbs []*squealx.DB) {
-	for _, db := range r.replicas {
-		if val, exists := r.dbs[db]; exists {
-			dbs = append(dbs, val)
-		}
-	}
-	return
+func (r *dbResolver) ReplicaDBs() []*squealx.DB {
+	return r.lookup(r.replicaIDs())
 }
 
 func (r *dbResolver) Use(db string) (*squealx.DB, error) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	if db, exists := r.dbs[db]; exists {
 		return db, nil
 	}
@@ -303,9 +360,7 @@ func (r *dbResolver) Use(db string) (*squealx.DB, error) {
 func (r *dbResolver) Register(db *squealx.DB, useAsDefault bool) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	if _, exists := r.dbs[db.ID]; !exists {
-		r.dbs[db.ID] = db
-	}
+	r.add(db)
 	if useAsDefault {
 		r.defaultDB = db.ID
 	}
@@ -314,12 +369,10 @@ func (r *dbResolver) Register(db *squealx.DB, useAsDefault bool) {
 func (r *dbResolver) RegisterMaster(db *squealx.DB, useAsDefault bool) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.masters = append(r.masters, db.ID)
-	if _, exists := r.dbs[db.ID]; !exists {
-		r.dbs[db.ID] = db
-	}
+	r.masters = append(slices.Clip(r.masters), db.ID)
+	r.add(db)
 	if r.policy == ReadWrite {
-		r.readDBs = append(r.readDBs, db.ID)
+		r.readDBs = append(slices.Clip(r.readDBs), db.ID)
 	}
 	if useAsDefault {
 		r.defaultDB = db.ID
@@ -329,24 +382,74 @@ func (r *dbResolver) RegisterMaster(db *squealx.DB, useAsDefault bool) {
 func (r *dbResolver) RegisterReplica(db *squealx.DB) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.replicas = append(r.replicas, db.ID)
-	if _, exists := r.dbs[db.ID]; !exists {
-		r.dbs[db.ID] = db
-	}
+	r.replicas = append(slices.Clip(r.replicas), db.ID)
+	r.add(db)
 }
 
 func (r *dbResolver) RegisterRead(db *squealx.DB) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.readDBs = append(r.readDBs, db.ID)
-	if _, exists := r.dbs[db.ID]; !exists {
-		r.dbs[db.ID] = db
+	r.readDBs = append(slices.Clip(r.readDBs), db.ID)
+	r.add(db)
+}
+
+// add stores db unless its ID is known. The caller holds the write lock.
+// r.dbs is replaced rather than written, so that maps returned by allDBs
+// and ranged over without the lock stay unchanged.
+func (r *dbResolver) add(db *squealx.DB) {
+	if _, exists := r.dbs[db.ID]; exists {
+		return
 	}
+	dbs := maps.Clone(r.dbs)
+	dbs[db.ID] = db
+	r.dbs = dbs
+	r.cache.observe(db)
+	r.health.observe(db)
+}
+
+func (r *dbResolver) ReadDBs() []*squealx.DB {
+	return r.lookup(r.readIDs())
+}
+
+// The ID slices are only appended to through slices.Clip, so the slices
+// returned by these accessors never change and can be used without the lock.
+
+func (r *dbResolver) masterIDs() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.masters
+}
+
+func (r *dbResolver) replicaIDs() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.replicas
+}
+
+func (r *dbResolver) readIDs() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.readDBs
+}
+
+func (r *dbResolver) defaultID() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.defaultDB
+}
+
+// allDBs returns the databases of the resolver by ID.
+func (r *dbResolver) allDBs() map[string]*squealx.DB {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.dbs
 }
 
-func (r *dbResolver) ReadDBs() (dbs []*squealx.DB) {
-	for _, db := range r.readDBs {
-		if val, exists := r.dbs[db]; exists {
+// lookup returns the databases of ids.
+func (r *dbResolver) lookup(ids []string) (dbs []*squealx.DB) {
+	all := r.allDBs()
+	for _, id := range ids {
+		if val, exists := all[id]; exists {
 			dbs = append(dbs, val)
 		}
 	}
@@ -359,11 +462,11 @@ func (r *dbResolver) LoadBalancer() LoadBalancer {
 
 func (r *dbResolver) getDB(id string) (*squealx.DB, error) {
 	if id == "" {
-		return nil, errors.New("id not provided")
+		return nil, ErrNoDatabase
 	}
-	db, exists := r.dbs[id]
+	db, exists := r.allDBs()[id]
 	if !exists {
-		return nil, errors.New("invalid ID provided")
+		return nil, fmt.Errorf("%w: %q", ErrUnknownDatabase, id)
 	}
 	return db, nil
 }
@@ -371,83 +474,87 @@ func (r *dbResolver) getDB(id string) (*squealx.DB, error) {
 // Begin chooses a primary database and starts a transaction.
 // This supposed to be aligned with sqlx.DB.Begin.
 func (r *dbResolver) Begin() (squealx.SQLTx, error) {
-	db := r.GetDB(context.Background(), r.masters)
-	return db.Begin()
+	return r.BeginTxx(context.Background(), nil)
 }
 
 // BeginTx chooses a primary database and starts a transaction.
 // This supposed to be aligned with sqlx.DB.BeginTx.
 func (r *dbResolver) BeginTx(ctx context.Context, opts *sql.TxOptions) (squealx.SQLTx, error) {
-	db := r.GetDB(ctx, r.masters)
-	return db.BeginTx(ctx, opts)
+	return r.BeginTxx(ctx, opts)
 }
 
 // BeginTxx chooses a primary database, begins a transaction and returns an *squealx.Tx
 // This supposed to be aligned with sqlx.DB.BeginTxx.
 func (r *dbResolver) BeginTxx(ctx context.Context, opts *sql.TxOptions) (*squealx.Tx, error) {
-	db := r.GetDB(ctx, r.masters)
-	return db.BeginTxx(ctx, opts)
+	db, err := r.GetDBErr(ctx, r.masterIDs())
+	if err != nil {
+		return nil, err
+	}
+	return db.BeginTxx(r.txContext(ctx), opts)
+}
+
+// txContext attaches the resolver's transaction settings to ctx unless it
+// already carries some.
+func (r *dbResolver) txContext(ctx context.Context) context.Context {
+	if r.txSettings == nil {
+		return ctx
+	}
+	if _, ok := squealx.TxSettingsFromContext(ctx); ok {
+		return ctx
+	}
+	return squealx.WithTxSettings(ctx, *r.txSettings)
 }
 
 // Beginx chooses a primary database, begins a transaction and returns an *squealx.Tx
 // This supposed to be aligned with sqlx.DB.Beginx.
 func (r *dbResolver) Beginx() (*squealx.Tx, error) {
-	db := r.GetDB(context.Background(), r.masters)
-	return db.Beginx()
+	return r.BeginTxx(context.Background(), nil)
 }
 
 // BindNamed chooses a primary database and binds a query using the DB driver's bindvar type.
 // This supposed to be aligned with sqlx.DB.BindNamed.
 func (r *dbResolver) BindNamed(query string, arg any) (string, []any, error) {
-	db := r.GetDB(context.Background(), r.masters)
+	db, err := r.GetDBErr(context.Background(), r.masterIDs())
+	if err != nil {
+		return "", nil, err
+	}
 	return db.BindNamed(query, arg)
 }
 
 func (r *dbResolver) Paginate(query string, result any, paging squealx.Paging, params ...map[string]any) squealx.PaginatedResponse {
-	query = r.GetQueryString(query)
-	db := r.GetDB(context.Background(), r.readDBs)
-	p := &squealx.Param{
-		DB:     db,
-		Query:  query,
-		Paging: &paging,
-	}
+	var args []any
 	if len(params) > 0 {
-		p.Param = params[0]
+		args = []any{params[0]}
 	}
-	pages, err := squealx.Pages(p, result)
-	if err == nil {
-		return squealx.PaginatedResponse{
-			Items:      result,
-			Pagination: pages,
-		}
+	return r.PaginateContext(context.Background(), query, result, paging, args...)
+}
+
+// PaginateContext is like Paginate, running its queries with ctx. params are
+// the arguments of query, as for squealx.PaginateContext.
+func (r *dbResolver) PaginateContext(ctx context.Context, query string, result any, paging squealx.Paging, params ...any) squealx.PaginatedResponse {
+	query = r.GetQueryString(query)
+	db, err := r.GetDBErr(ctx, r.readIDs())
+	if err != nil {
+		return squealx.PaginatedResponse{Error: err}
 	}
-	if isDBConnectionError(err) {
-		dbPrimary := r.GetDB(context.Background(), r.masters)
-		p := &squealx.Param{
-			DB:     dbPrimary,
-			Query:  query,
-			Paging: &paging,
-		}
-		if len(params) > 0 {
-			p.Param = params[0]
-		}
-		pages, err = squealx.Pages(p, result)
-		if err == nil {
-			return squealx.PaginatedResponse{
-				Items:      result,
-				Pagination: pages,
-			}
+	response := squealx.PaginateContext(ctx, db, query, result, paging, params...)
+	if isDBConnectionError(response.Error) {
+		if dbPrimary, lookupErr := r.GetDBErr(ctx, r.masterIDs()); lookupErr == nil {
+			resetDest(result)
+			return squealx.PaginateContext(ctx, dbPrimary, query, result, paging, params...)
 		}
 	}
-	return squealx.PaginatedResponse{
-		Error: err,
-	}
+	return response
 }
 
-// Close closes all the databases.
+// Close removes the hooks of the resolver from its databases and closes
+// them.
 func (r *dbResolver) Close() error {
+	r.health.close()
 	var errs []error
-	for _, db := range r.dbs {
+	for _, db := range r.allDBs() {
+		r.cache.forget(db)
+		r.health.forget(db)
 		if err := db.Close(); err != nil {
 			errs = append(errs, err)
 		}
@@ -458,40 +565,102 @@ func (r *dbResolver) Close() error {
 // Conn chooses a primary database and returns a squealx.SQLConn.
 // This supposed to be aligned with sqlx.DB.Conn.
 func (r *dbResolver) Conn(ctx context.Context) (squealx.SQLConn, error) {
-	db := r.GetDB(ctx, r.masters)
+	db, err := r.GetDBErr(ctx, r.masterIDs())
+	if err != nil {
+		return nil, err
+	}
 	return db.Conn(ctx)
 }
 
 // Connx chooses a primary database and returns a *squealx.Conn.
 // This supposed to be aligned with sqlx.DB.Connx.
 func (r *dbResolver) Connx(ctx context.Context) (*squealx.Conn, error) {
-	db := r.GetDB(ctx, r.masters)
+	db, err := r.GetDBErr(ctx, r.masterIDs())
+	if err != nil {
+		return nil, err
+	}
 	return db.Connx(ctx)
 }
 
 // Driver chooses a primary database and returns a driver.Driver.
 // This supposed to be aligned with sqlx.DB.Driver.
 func (r *dbResolver) Driver() driver.Driver {
-	db := r.GetDB(context.Background(), r.masters)
+	db, err := r.GetDBErr(context.Background(), r.masterIDs())
+	if err != nil {
+		return nil
+	}
 	return db.Driver()
 }
 
 // DriverName chooses a primary database and returns the driverName.
 // This supposed to be aligned with sqlx.DB.DriverName.
 func (r *dbResolver) DriverName() string {
-	db := r.GetDB(context.Background(), r.masters)
+	db, err := r.GetDBErr(context.Background(), r.masterIDs())
+	if err != nil {
+		return ""
+	}
 	return db.DriverName()
 }
 
 func (r *dbResolver) GetQuery(query string) *squealx.Query {
 	if r.queryLoader != nil {
-		if q, e := r.queryLoader.Queries()[query]; e {
-			return q
-		}
+		return r.queryLoader.GetQuery(query)
 	}
 	return nil
 }
 
+// SelectByName runs the FileLoader query name into dest after validating
+// args and dest against the params and result shape it declares. Queries
+// bound to a connection run on that database.
+func (r *dbResolver) SelectByName(dest any, name string, args ...any) error {
+	return r.SelectByNameContext(context.Background(), dest, name, args...)
+}
+
+// SelectByNameContext is like SelectByName, with a context.
+func (r *dbResolver) SelectByNameContext(ctx context.Context, dest any, name string, args ...any) error {
+	q := r.GetQuery(name)
+	if q == nil {
+		return fmt.Errorf("dbresolver: unknown query %q", name)
+	}
+	if q.Connection == "" {
+		// Struct arguments are read as the databases of the resolver map
+		// them, taken to share their mapper.
+		db, err := r.getDB(firstID(r.readIDs()))
+		if err != nil {
+			return err
+		}
+		if err := validateQuery(q, db, dest, args); err != nil {
+			return err
+		}
+		return r.SelectContext(ctx, dest, q.Query, args...)
+	}
+	db, err := r.Use(q.Connection)
+	if err != nil {
+		return err
+	}
+	if err := validateQuery(q, db, dest, args); err != nil {
+		return err
+	}
+	return db.SelectContext(ctx, dest, q.Query, args...)
+}
+
+// validateQuery checks args and dest against the params and result shape
+// declared by q, run on db.
+func validateQuery(q *squealx.Query, db *squealx.DB, dest any, args []any) error {
+	if err := q.ValidateArgsFor(db, args...); err != nil {
+		return err
+	}
+	return q.ValidateDest(dest)
+}
+
+// firstID returns the first of ids, "" when there is none.
+func firstID(ids []string) string {
+	if len(ids) == 0 {
+		return ""
+	}
+	return ids[0]
+}
+
 func (r *dbResolver) GetQueryString(query string) string {
 	q := r.GetQuery(query)
 	if q == nil {
@@ -507,7 +676,10 @@ func (r *dbResolver) Exec(query string, args ...any) (sql.Result, error) {
 	if squealx.IsNamedQuery(query) && len(args) > 0 {
 		return r.NamedExec(query, args[0])
 	}
-	db := r.GetDB(context.Background(), r.masters)
+	db, err := r.GetDBErr(context.Background(), r.masterIDs())
+	if err != nil {
+		return nil, err
+	}
 	return db.Exec(query, args...)
 }
 
@@ -518,39 +690,58 @@ func (r *dbResolver) ExecContext(ctx context.Context, query string, args ...any)
 	if squealx.IsNamedQuery(query) && len(args) > 0 {
 		return r.NamedExecContext(ctx, query, args[0])
 	}
-	db := r.GetDB(ctx, r.masters)
-	return db.Exec(query, args...)
+	db, err := r.GetDBErr(ctx, r.masterIDs())
+	if err != nil {
+		return nil, err
+	}
+	return db.ExecContext(ctx, query, args...)
 }
 
 // Get chooses a readable database and Get using chosen DB.
 // This supposed to be aligned with sqlx.DB.Get.
 func (r *dbResolver) Get(dest any, query string, args ...any) error {
 	query = r.GetQueryString(query)
-	db := r.GetDB(context.Background(), r.readDBs)
-	err := db.Get(dest, query, args...)
-	if isDBConnectionError(err) {
-		dbPrimary := r.GetDB(context.Background(), r.masters)
-		err = dbPrimary.Get(dest, query, args...)
-	}
-	return err
+	return r.cachedRead(context.Background(), dest, query, args, func() error {
+		db, err := r.GetDBErr(context.Background(), r.readIDs())
+		if err != nil {
+			return err
+		}
+		err = r.hedgedRead(context.Background(), db, dest, query, func(ctx context.Context, db *squealx.DB, dest any) error {
+			return db.GetContext(ctx, dest, query, args...)
+		})
+		if isDBConnectionError(err) {
+			if dbPrimary, lookupErr := r.GetDBErr(context.Background(), r.masterIDs()); lookupErr == nil {
+				err = dbPrimary.Get(dest, query, args...)
+			}
+		}
+		return err
+	})
 }
 
 // GetContext chooses a readable database and Get using chosen DB.
 // This supposed to be aligned with sqlx.DB.GetContext.
 func (r *dbResolver) GetContext(ctx context.Context, dest any, query string, args ...any) error {
 	query = r.GetQueryString(query)
-	db := r.GetDB(ctx, r.readDBs)
-	err := db.GetContext(ctx, dest, query, args...)
-	if isDBConnectionError(err) {
-		dbPrimary := r.GetDB(ctx, r.masters)
-		err = dbPrimary.GetContext(ctx, dest, query, args...)
-	}
-	return err
+	return r.cachedRead(ctx, dest, query, args, func() error {
+		db, err := r.GetDBErr(ctx, r.readIDs())
+		if err != nil {
+			return err
+		}
+		err = r.hedgedRead(ctx, db, dest, query, func(ctx context.Context, db *squealx.DB, dest any) error {
+			return db.GetContext(ctx, dest, query, args...)
+		})
+		if isDBConnectionError(err) {
+			if dbPrimary, lookupErr := r.GetDBErr(ctx, r.masterIDs()); lookupErr == nil {
+				err = dbPrimary.GetContext(ctx, dest, query, args...)
+			}
+		}
+		return err
+	})
 }
 
 // MapperFunc sets the mapper function for the all primary databases and secondary databases.
 func (r *dbResolver) MapperFunc(mf func(string) string) {
-	for _, db := range r.dbs {
+	for _, db := range r.allDBs() {
 		db.MapperFunc(mf)
 	}
 }
@@ -558,22 +749,31 @@ func (r *dbResolver) MapperFunc(mf func(string) string) {
 // MustBegin chooses a primary database, starts a transaction and returns an *squealx.Tx or panic.
 // This supposed to be aligned with sqlx.DB.MustBegin.
 func (r *dbResolver) MustBegin() *squealx.Tx {
-	db := r.GetDB(context.Background(), r.masters)
-	return db.MustBegin()
+	db, err := r.GetDBErr(context.Background(), r.masterIDs())
+	if err != nil {
+		panic(err)
+	}
+	return db.MustBeginTx(r.txContext(context.Background()), nil)
 }
 
 // MustBeginTx chooses a primary database, starts a transaction and returns an *squealx.Tx or panic.
 // This supposed to be aligned with sqlx.DB.MustBeginTx.
 func (r *dbResolver) MustBeginTx(ctx context.Context, opts *sql.TxOptions) *squealx.Tx {
-	db := r.GetDB(ctx, r.masters)
-	return db.MustBeginTx(ctx, opts)
+	db, err := r.GetDBErr(ctx, r.masterIDs())
+	if err != nil {
+		panic(err)
+	}
+	return db.MustBeginTx(r.txContext(ctx), opts)
 }
 
 // MustExec chooses a primary database and executes a query or panic.
 // This supposed to be aligned with sqlx.DB.MustExec.
 func (r *dbResolver) MustExec(query string, args ...any) sql.Result {
 	query = r.GetQueryString(query)
-	db := r.GetDB(context.Background(), r.masters)
+	db, err := r.GetDBErr(context.Background(), r.masterIDs())
+	if err != nil {
+		panic(err)
+	}
 	if squealx.IsNamedQuery(query) && len(args) > 0 {
 		rs, err := db.Exec(query, args[0])
 		if err != nil {
@@ -588,7 +788,10 @@ func (r *dbResolver) MustExec(query string, args ...any) sql.Result {
 // This supposed to be aligned with sqlx.DB.MustExecContext.
 func (r *dbResolver) MustExecContext(ctx context.Context, query string, args ...any) sql.Result {
 	query = r.GetQueryString(query)
-	db := r.GetDB(ctx, r.masters)
+	db, err := r.GetDBErr(ctx, r.masterIDs())
+	if err != nil {
+		panic(err)
+	}
 	if squealx.IsNamedQuery(query) && len(args) > 0 {
 		rs, err := db.ExecContext(ctx, query, args[0])
 		if err != nil {
@@ -603,7 +806,10 @@ func (r *dbResolver) MustExecContext(ctx context.Context, query string, args ...
 // This supposed to be aligned with sqlx.DB.NamedExec.
 func (r *dbResolver) NamedExec(query string, arg any) (sql.Result, error) {
 	query = r.GetQueryString(query)
-	db := r.GetDB(context.Background(), r.masters)
+	db, err := r.GetDBErr(context.Background(), r.masterIDs())
+	if err != nil {
+		return nil, err
+	}
 	return db.NamedExec(query, arg)
 }
 
@@ -611,7 +817,10 @@ func (r *dbResolver) NamedExec(query string, arg any) (sql.Result, error) {
 // This supposed to be aligned with sqlx.DB.NamedExecContext.
 func (r *dbResolver) NamedExecContext(ctx context.Context, query string, arg any) (sql.Result, error) {
 	query = r.GetQueryString(query)
-	db := r.GetDB(ctx, r.masters)
+	db, err := r.GetDBErr(ctx, r.masterIDs())
+	if err != nil {
+		return nil, err
+	}
 	return db.NamedExecContext(ctx, query, arg)
 }
 
@@ -619,11 +828,15 @@ func (r *dbResolver) NamedExecContext(ctx context.Context, query string, arg any
 // This supposed to be aligned with sqlx.DB.NamedQuery.
 func (r *dbResolver) NamedQuery(query string, arg any) (*squealx.Rows, error) {
 	query = r.GetQueryString(query)
-	db := r.GetDB(context.Background(), r.readDBs)
+	db, err := r.GetDBErr(context.Background(), r.readIDs())
+	if err != nil {
+		return nil, err
+	}
 	rows, err := db.NamedQuery(query, arg)
 	if isDBConnectionError(err) {
-		dbPrimary := r.GetDB(context.Background(), r.masters)
-		rows, err = dbPrimary.NamedQuery(query, arg)
+		if dbPrimary, lookupErr := r.GetDBErr(context.Background(), r.masterIDs()); lookupErr == nil {
+			rows, err = dbPrimary.NamedQuery(query, arg)
+		}
 	}
 	return rows, err
 }
@@ -632,11 +845,15 @@ func (r *dbResolver) NamedQuery(query string, arg any) (*squealx.Rows, error) {
 // This supposed to be aligned with sqlx.DB.NamedQueryContext.
 func (r *dbResolver) NamedQueryContext(ctx context.Context, query string, arg any) (*squealx.Rows, error) {
 	query = r.GetQueryString(query)
-	db := r.GetDB(ctx, r.readDBs)
+	db, err := r.GetDBErr(ctx, r.readIDs())
+	if err != nil {
+		return nil, err
+	}
 	rows, err := db.NamedQueryContext(ctx, query, arg)
 	if isDBConnectionError(err) {
-		dbPrimary := r.GetDB(ctx, r.masters)
-		rows, err = dbPrimary.NamedQueryContext(ctx, query, arg)
+		if dbPrimary, lookupErr := r.GetDBErr(ctx, r.masterIDs()); lookupErr == nil {
+			rows, err = dbPrimary.NamedQueryContext(ctx, query, arg)
+		}
 	}
 	return rows, err
 }
@@ -644,7 +861,7 @@ func (r *dbResolver) NamedQueryContext(ctx context.Context, query string, arg an
 // Ping sends a ping to the all databases.
 func (r *dbResolver) Ping() error {
 	var errs []error
-	for _, db := range r.dbs {
+	for _, db := range r.allDBs() {
 		if err := db.Ping(); err != nil {
 			errs = append(errs, err)
 		}
@@ -658,7 +875,7 @@ func (r *dbResolver) Ping() error {
 // PingContext sends a ping to the all databases.
 func (r *dbResolver) PingContext(ctx context.Context) error {
 	var errs []error
-	for _, db := range r.dbs {
+	for _, db := range r.allDBs() {
 		if err := db.PingContext(ctx); err != nil {
 			errs = append(errs, err)
 		}
@@ -673,11 +890,11 @@ func (r *dbResolver) PingContext(ctx context.Context) error {
 // This supposed to be aligned with sqlx.DB.Prepare.
 func (r *dbResolver) Prepare(query string) (Stmt, error) {
 	query = r.GetQueryString(query)
-	primaryDBStmts := make(map[*squealx.DB]*squealx.Stmt, len(r.masters))
-	readDBStmts := make(map[*squealx.DB]*squealx.Stmt, len(r.readDBs))
+	primaryDBStmts := make(map[*squealx.DB]*squealx.Stmt, len(r.masterIDs()))
+	readDBStmts := make(map[*squealx.DB]*squealx.Stmt, len(r.readIDs()))
 
 	var errs []error
-	for _, id := range r.masters {
+	for _, id := range r.masterIDs() {
 		db, err := r.getDB(id)
 		if err != nil {
 			return nil, err
@@ -690,7 +907,7 @@ func (r *dbResolver) Prepare(query string) (Stmt, error) {
 
 		primaryDBStmts[db] = stmt
 	}
-	for _, id := range r.readDBs {
+	for _, id := range r.readIDs() {
 		db, err := r.getDB(id)
 		if err != nil {
 			return nil, err
@@ -708,8 +925,8 @@ func (r *dbResolver) Prepare(query string) (Stmt, error) {
 	}
 
 	return &stmt{
-		masters:      r.masters,
-		readReplicas: r.readDBs,
+		masters:      r.masterIDs(),
+		readReplicas: r.readIDs(),
 		masterStmts:  primaryDBStmts,
 		replicaStmts: readDBStmts,
 		db:           r,
@@ -720,11 +937,11 @@ func (r *dbResolver) Prepare(query string) (Stmt, error) {
 // This supposed to be aligned with sqlx.DB.PrepareContext.
 func (r *dbResolver) PrepareContext(ctx context.Context, query string) (Stmt, error) {
 	query = r.GetQueryString(query)
-	primaryDBStmts := make(map[*squealx.DB]*squealx.Stmt, len(r.masters))
-	readDBStmts := make(map[*squealx.DB]*squealx.Stmt, len(r.readDBs))
+	primaryDBStmts := make(map[*squealx.DB]*squealx.Stmt, len(r.masterIDs()))
+	readDBStmts := make(map[*squealx.DB]*squealx.Stmt, len(r.readIDs()))
 
 	var errs []error
-	for _, id := range r.masters {
+	for _, id := range r.masterIDs() {
 		db, err := r.getDB(id)
 		if err != nil {
 			return nil, err
@@ -737,7 +954,7 @@ func (r *dbResolver) PrepareContext(ctx context.Context, query string) (Stmt, er
 
 		primaryDBStmts[db] = stmt
 	}
-	for _, id := range r.readDBs {
+	for _, id := range r.readIDs() {
 		db, err := r.getDB(id)
 		if err != nil {
 			return nil, err
@@ -755,8 +972,8 @@ func (r *dbResolver) PrepareContext(ctx context.Context, query string) (Stmt, er
 	}
 
 	return &stmt{
-		masters:      r.masters,
-		readReplicas: r.readDBs,
+		masters:      r.masterIDs(),
+		readReplicas: r.readIDs(),
 		masterStmts:  primaryDBStmts,
 		replicaStmts: readDBStmts,
 		db:           r,
@@ -767,11 +984,11 @@ func (r *dbResolver) PrepareContext(ctx context.Context, query string) (Stmt, er
 // This supposed to be aligned with sqlx.DB.PrepareNamed.
 func (r *dbResolver) PrepareNamed(query string) (NamedStmt, error) {
 	query = r.GetQueryString(query)
-	primaryDBStmts := make(map[*squealx.DB]*squealx.NamedStmt, len(r.masters))
-	readDBStmts := make(map[*squealx.DB]*squealx.NamedStmt, len(r.readDBs))
+	primaryDBStmts := make(map[*squealx.DB]*squealx.NamedStmt, len(r.masterIDs()))
+	readDBStmts := make(map[*squealx.DB]*squealx.NamedStmt, len(r.readIDs()))
 
 	var errs []error
-	for _, id := range r.masters {
+	for _, id := range r.masterIDs() {
 		db, err := r.getDB(id)
 		if err != nil {
 			return nil, err
@@ -784,7 +1001,7 @@ func (r *dbResolver) PrepareNamed(query string) (NamedStmt, error) {
 
 		primaryDBStmts[db] = stmt
 	}
-	for _, id := range r.readDBs {
+	for _, id := range r.readIDs() {
 		db, err := r.getDB(id)
 		if err != nil {
 			return nil, err
@@ -802,8 +1019,8 @@ func (r *dbResolver) PrepareNamed(query string) (NamedStmt, error) {
 	}
 
 	return &namedStmt{
-		masters:      r.masters,
-		readReplicas: r.readDBs,
+		masters:      r.masterIDs(),
+		readReplicas: r.readIDs(),
 		masterStmts:  primaryDBStmts,
 		replicaStmts: readDBStmts,
 		db:           r,
@@ -814,11 +1031,11 @@ func (r *dbResolver) PrepareNamed(query string) (NamedStmt, error) {
 // This supposed to be aligned with sqlx.DB.PrepareNamedContext.
 func (r *dbResolver) PrepareNamedContext(ctx context.Context, query string) (NamedStmt, error) {
 	query = r.GetQueryString(query)
-	primaryDBStmts := make(map[*squealx.DB]*squealx.NamedStmt, len(r.masters))
-	readDBStmts := make(map[*squealx.DB]*squealx.NamedStmt, len(r.readDBs))
+	primaryDBStmts := make(map[*squealx.DB]*squealx.NamedStmt, len(r.masterIDs()))
+	readDBStmts := make(map[*squealx.DB]*squealx.NamedStmt, len(r.readIDs()))
 
 	var errs []error
-	for _, id := range r.masters {
+	for _, id := range r.masterIDs() {
 		db, err := r.getDB(id)
 		if err != nil {
 			return nil, err
@@ -831,7 +1048,7 @@ func (r *dbResolver) PrepareNamedContext(ctx context.Context, query string) (Nam
 
 		primaryDBStmts[db] = stmt
 	}
-	for _, id := range r.readDBs {
+	for _, id := range r.readIDs() {
 		db, err := r.getDB(id)
 		if err != nil {
 			return nil, err
@@ -849,8 +1066,8 @@ func (r *dbResolver) PrepareNamedContext(ctx context.Context, query string) (Nam
 	}
 
 	return &namedStmt{
-		masters:      r.masters,
-		readReplicas: r.readDBs,
+		masters:      r.masterIDs(),
+		readReplicas: r.readIDs(),
 		masterStmts:  primaryDBStmts,
 		replicaStmts: readDBStmts,
 		db:           r,
@@ -861,11 +1078,11 @@ func (r *dbResolver) PrepareNamedContext(ctx context.Context, query string) (Nam
 // This supposed to be aligned with sqlx.DB.Preparex.
 func (r *dbResolver) Preparex(query string) (Stmt, error) {
 	query = r.GetQueryString(query)
-	primaryDBStmts := make(map[*squealx.DB]*squealx.Stmt, len(r.masters))
-	readDBStmts := make(map[*squealx.DB]*squealx.Stmt, len(r.readDBs))
+	primaryDBStmts := make(map[*squealx.DB]*squealx.Stmt, len(r.masterIDs()))
+	readDBStmts := make(map[*squealx.DB]*squealx.Stmt, len(r.readIDs()))
 
 	var errs []error
-	for _, id := range r.masters {
+	for _, id := range r.masterIDs() {
 		db, err := r.getDB(id)
 		if err != nil {
 			return nil, err
@@ -878,7 +1095,7 @@ func (r *dbResolver) Preparex(query string) (Stmt, error) {
 
 		primaryDBStmts[db] = stmt
 	}
-	for _, id := range r.readDBs {
+	for _, id := range r.readIDs() {
 		db, err := r.getDB(id)
 		if err != nil {
 			return nil, err
@@ -896,8 +1113,8 @@ func (r *dbResolver) Preparex(query string) (Stmt, error) {
 	}
 
 	return &stmt{
-		masters:      r.masters,
-		readReplicas: r.readDBs,
+		masters:      r.masterIDs(),
+		readReplicas: r.readIDs(),
 		masterStmts:  primaryDBStmts,
 		replicaStmts: readDBStmts,
 		db:           r,
@@ -908,11 +1125,11 @@ func (r *dbResolver) Preparex(query string) (Stmt, error) {
 // This supposed to be aligned with sqlx.DB.PreparexContext.
 func (r *dbResolver) PreparexContext(ctx context.Context, query string) (Stmt, error) {
 	query = r.GetQueryString(query)
-	primaryDBStmts := make(map[*squealx.DB]*squealx.Stmt, len(r.masters))
-	readDBStmts := make(map[*squealx.DB]*squealx.Stmt, len(r.readDBs))
+	primaryDBStmts := make(map[*squealx.DB]*squealx.Stmt, len(r.masterIDs()))
+	readDBStmts := make(map[*squealx.DB]*squealx.Stmt, len(r.readIDs()))
 
 	var errs []error
-	for _, id := range r.masters {
+	for _, id := range r.masterIDs() {
 		db, err := r.getDB(id)
 		if err != nil {
 			return nil, err
@@ -925,7 +1142,7 @@ func (r *dbResolver) PreparexContext(ctx context.Context, query string) (Stmt, e
 
 		primaryDBStmts[db] = stmt
 	}
-	for _, id := range r.readDBs {
+	for _, id := range r.readIDs() {
 		db, err := r.getDB(id)
 		if err != nil {
 			return nil, err
@@ -943,8 +1160,8 @@ func (r *dbResolver) PreparexContext(ctx context.Context, query string) (Stmt, e
 	}
 
 	return &stmt{
-		masters:      r.masters,
-		readReplicas: r.readDBs,
+		masters:      r.masterIDs(),
+		readReplicas: r.readIDs(),
 		masterStmts:  primaryDBStmts,
 		replicaStmts: readDBStmts,
 		db:           r,
@@ -955,11 +1172,15 @@ func (r *dbResolver) PreparexContext(ctx context.Context, query string) (Stmt, e
 // This supposed to be aligned with sqlx.DB.Query.
 func (r *dbResolver) Query(query string, args ...any) (squealx.SQLRows, error) {
 	query = r.GetQueryString(query)
-	db := r.GetDB(context.Background(), r.readDBs)
+	db, err := r.GetDBErr(context.Background(), r.readIDs())
+	if err != nil {
+		return nil, err
+	}
 	rows, err := db.Query(query, args...)
 	if isDBConnectionError(err) {
-		dbPrimary := r.GetDB(context.Background(), r.masters)
-		rows, err = dbPrimary.Query(query, args...)
+		if dbPrimary, lookupErr := r.GetDBErr(context.Background(), r.masterIDs()); lookupErr == nil {
+			rows, err = dbPrimary.Query(query, args...)
+		}
 	}
 	return rows, err
 }
@@ -968,11 +1189,15 @@ func (r *dbResolver) Query(query string, args ...any) (squealx.SQLRows, error) {
 // This supposed to be aligned with sqlx.DB.QueryContext.
 func (r *dbResolver) QueryContext(ctx context.Context, query string, args ...any) (squealx.SQLRows, error) {
 	query = r.GetQueryString(query)
-	db := r.GetDB(ctx, r.readDBs)
+	db, err := r.GetDBErr(ctx, r.readIDs())
+	if err != nil {
+		return nil, err
+	}
 	rows, err := db.QueryContext(ctx, query, args...)
 	if isDBConnectionError(err) {
-		dbPrimary := r.GetDB(ctx, r.masters)
-		rows, err = dbPrimary.QueryContext(ctx, query, args...)
+		if dbPrimary, lookupErr := r.GetDBErr(ctx, r.masterIDs()); lookupErr == nil {
+			rows, err = dbPrimary.QueryContext(ctx, query, args...)
+		}
 	}
 	return rows, err
 }
@@ -981,11 +1206,15 @@ func (r *dbResolver) QueryContext(ctx context.Context, query string, args ...any
 // This supposed to be aligned with sqlx.DB.QueryRow.
 func (r *dbResolver) QueryRow(query string, args ...any) squealx.SQLRow {
 	query = r.GetQueryString(query)
-	db := r.GetDB(context.Background(), r.readDBs)
+	db, err := r.GetDBErr(context.Background(), r.readIDs())
+	if err != nil {
+		return squealx.RowError(err)
+	}
 	row := db.QueryRow(query, args...)
 	if isDBConnectionError(row.Err()) {
-		dbPrimary := r.GetDB(context.Background(), r.masters)
-		row = dbPrimary.QueryRow(query, args...)
+		if dbPrimary, lookupErr := r.GetDBErr(context.Background(), r.masterIDs()); lookupErr == nil {
+			row = dbPrimary.QueryRow(query, args...)
+		}
 	}
 	return row
 }
@@ -994,11 +1223,15 @@ func (r *dbResolver) QueryRow(query string, args ...any) squealx.SQLRow {
 // This supposed to be aligned with sqlx.DB.QueryRowContext.
 func (r *dbResolver) QueryRowContext(ctx context.Context, query string, args ...any) squealx.SQLRow {
 	query = r.GetQueryString(query)
-	db := r.GetDB(ctx, r.readDBs)
+	db, err := r.GetDBErr(ctx, r.readIDs())
+	if err != nil {
+		return squealx.RowError(err)
+	}
 	row := db.QueryRowContext(ctx, query, args...)
 	if isDBConnectionError(row.Err()) {
-		dbPrimary := r.GetDB(ctx, r.masters)
-		row = dbPrimary.QueryRowContext(ctx, query, args...)
+		if dbPrimary, lookupErr := r.GetDBErr(ctx, r.masterIDs()); lookupErr == nil {
+			row = dbPrimary.QueryRowContext(ctx, query, args...)
+		}
 	}
 	return row
 }
@@ -1007,11 +1240,15 @@ func (r *dbResolver) QueryRowContext(ctx context.Context, query string, args ...
 // This supposed to be aligned with sqlx.DB.QueryRowx.
 func (r *dbResolver) QueryRowx(query string, args ...any) *squealx.Row {
 	query = r.GetQueryString(query)
-	db := r.GetDB(context.Background(), r.readDBs)
+	db, err := r.GetDBErr(context.Background(), r.readIDs())
+	if err != nil {
+		return squealx.RowError(err)
+	}
 	row := db.QueryRowx(query, args...)
 	if isDBConnectionError(row.Err()) {
-		dbPrimary := r.GetDB(context.Background(), r.masters)
-		row = dbPrimary.QueryRowx(query, args...)
+		if dbPrimary, lookupErr := r.GetDBErr(context.Background(), r.masterIDs()); lookupErr == nil {
+			row = dbPrimary.QueryRowx(query, args...)
+		}
 	}
 	return row
 }
@@ -1020,11 +1257,15 @@ func (r *dbResolver) QueryRowx(query string, args ...any) *squealx.Row {
 // This supposed to be aligned with sqlx.DB.QueryRowxContext.
 func (r *dbResolver) QueryRowxContext(ctx context.Context, query string, args ...any) *squealx.Row {
 	query = r.GetQueryString(query)
-	db := r.GetDB(ctx, r.readDBs)
+	db, err := r.GetDBErr(ctx, r.readIDs())
+	if err != nil {
+		return squealx.RowError(err)
+	}
 	row := db.QueryRowxContext(ctx, query, args...)
 	if isDBConnectionError(row.Err()) {
-		dbPrimary := r.GetDB(ctx, r.masters)
-		row = dbPrimary.QueryRowxContext(ctx, query, args...)
+		if dbPrimary, lookupErr := r.GetDBErr(ctx, r.masterIDs()); lookupErr == nil {
+			row = dbPrimary.QueryRowxContext(ctx, query, args...)
+		}
 	}
 	return row
 }
@@ -1033,11 +1274,15 @@ func (r *dbResolver) QueryRowxContext(ctx context.Context, query string, args ..
 // This supposed to be aligned with sqlx.DB.Queryx.
 func (r *dbResolver) Queryx(query string, args ...any) (*squealx.Rows, error) {
 	query = r.GetQueryString(query)
-	db := r.GetDB(context.Background(), r.readDBs)
+	db, err := r.GetDBErr(context.Background(), r.readIDs())
+	if err != nil {
+		return nil, err
+	}
 	rows, err := db.Queryx(query, args...)
 	if isDBConnectionError(err) {
-		dbPrimary := r.GetDB(context.Background(), r.masters)
-		rows, err = dbPrimary.Queryx(query, args...)
+		if dbPrimary, lookupErr := r.GetDBErr(context.Background(), r.masterIDs()); lookupErr == nil {
+			rows, err = dbPrimary.Queryx(query, args...)
+		}
 	}
 	return rows, err
 }
@@ -1046,11 +1291,15 @@ func (r *dbResolver) Queryx(query string, args ...any) (*squealx.Rows, error) {
 // This supposed to be aligned with sqlx.DB.QueryxContext.
 func (r *dbResolver) QueryxContext(ctx context.Context, query string, args ...any) (*squealx.Rows, error) {
 	query = r.GetQueryString(query)
-	db := r.GetDB(ctx, r.readDBs)
+	db, err := r.GetDBErr(ctx, r.readIDs())
+	if err != nil {
+		return nil, err
+	}
 	rows, err := db.QueryxContext(ctx, query, args...)
 	if isDBConnectionError(err) {
-		dbPrimary := r.GetDB(ctx, r.masters)
-		rows, err = dbPrimary.QueryxContext(ctx, query, args...)
+		if dbPrimary, lookupErr := r.GetDBErr(ctx, r.masterIDs()); lookupErr == nil {
+			rows, err = dbPrimary.QueryxContext(ctx, query, args...)
+		}
 	}
 	return rows, err
 }
@@ -1060,7 +1309,10 @@ func (r *dbResolver) QueryxContext(ctx context.Context, query string, args ...an
 // This supposed to be aligned with sqlx.DB.Rebind.
 func (r *dbResolver) Rebind(query string) string {
 	query = r.GetQueryString(query)
-	db := r.GetDB(context.Background(), r.masters)
+	db, err := r.GetDBErr(context.Background(), r.masterIDs())
+	if err != nil {
+		return query
+	}
 	return db.Rebind(query)
 }
 
@@ -1071,46 +1323,66 @@ func (r *dbResolver) Select(dest any, query string, args ...any) error {
 	if squealx.IsNamedQuery(query) && len(args) > 0 {
 		return r.NamedSelect(dest, query, args[0])
 	}
-	db := r.GetDB(context.Background(), r.readDBs)
-	err := db.Select(dest, query, args...)
-	if isDBConnectionError(err) {
-		dbPrimary := r.GetDB(context.Background(), r.masters)
-		err = dbPrimary.Select(dest, query, args...)
-	}
-	return err
+	return r.cachedRead(context.Background(), dest, query, args, func() error {
+		db, err := r.GetDBErr(context.Background(), r.readIDs())
+		if err != nil {
+			return err
+		}
+		err = r.hedgedRead(context.Background(), db, dest, query, func(ctx context.Context, db *squealx.DB, dest any) error {
+			return db.SelectContext(ctx, dest, query, args...)
+		})
+		if isDBConnectionError(err) {
+			if dbPrimary, lookupErr := r.GetDBErr(context.Background(), r.masterIDs()); lookupErr == nil {
+				err = dbPrimary.Select(dest, query, args...)
+			}
+		}
+		return err
+	})
 }
 
 func (r *dbResolver) ExecWithReturn(query string, args any) error {
-	db := r.GetDB(context.Background(), r.readDBs)
-	err := db.ExecWithReturn(query, args)
+	db, err := r.GetDBErr(context.Background(), r.readIDs())
+	if err != nil {
+		return err
+	}
+	err = db.ExecWithReturn(query, args)
 	if isDBConnectionError(err) {
-		dbPrimary := r.GetDB(context.Background(), r.masters)
-		err = dbPrimary.ExecWithReturn(query, args)
+		if dbPrimary, lookupErr := r.GetDBErr(context.Background(), r.masterIDs()); lookupErr == nil {
+			err = dbPrimary.ExecWithReturn(query, args)
+		}
 	}
 	return err
 }
 func (r *dbResolver) LazyExec(query string) func(args ...any) (sql.Result, error) {
 	return func(args ...any) (sql.Result, error) {
-		db := r.GetDB(context.Background(), r.readDBs)
+		db, err := r.GetDBErr(context.Background(), r.readIDs())
+		if err != nil {
+			return nil, err
+		}
 		fn := db.LazyExec(query)
 		rs, err := fn(args...)
 		if isDBConnectionError(err) {
-			dbPrimary := r.GetDB(context.Background(), r.masters)
-			fn := dbPrimary.LazyExec(query)
-			rs, err = fn(args...)
+			if dbPrimary, lookupErr := r.GetDBErr(context.Background(), r.masterIDs()); lookupErr == nil {
+				fn := dbPrimary.LazyExec(query)
+				rs, err = fn(args...)
+			}
 		}
 		return rs, err
 	}
 }
 func (r *dbResolver) LazyExecWithReturn(query string) func(args any) error {
 	return func(args any) error {
-		db := r.GetDB(context.Background(), r.readDBs)
+		db, err := r.GetDBErr(context.Background(), r.readIDs())
+		if err != nil {
+			return err
+		}
 		fn := db.LazyExecWithReturn(query)
-		err := fn(args)
+		err = fn(args)
 		if isDBConnectionError(err) {
-			dbPrimary := r.GetDB(context.Background(), r.masters)
-			fn = dbPrimary.LazyExecWithReturn(query)
-			err = fn(args)
+			if dbPrimary, lookupErr := r.GetDBErr(context.Background(), r.masterIDs()); lookupErr == nil {
+				fn = dbPrimary.LazyExecWithReturn(query)
+				err = fn(args)
+			}
 		}
 		return err
 	}
@@ -1118,13 +1390,17 @@ func (r *dbResolver) LazyExecWithReturn(query string) func(args any) error {
 
 func (r *dbResolver) LazySelect(query string) func(dest any, args ...any) error {
 	return func(dest any, args ...any) error {
-		db := r.GetDB(context.Background(), r.readDBs)
+		db, err := r.GetDBErr(context.Background(), r.readIDs())
+		if err != nil {
+			return err
+		}
 		fn := db.LazySelect(query)
-		err := fn(dest, args...)
+		err = fn(dest, args...)
 		if isDBConnectionError(err) {
-			dbPrimary := r.GetDB(context.Background(), r.masters)
-			fn = dbPrimary.LazySelect(query)
-			err = fn(dest, args...)
+			if dbPrimary, lookupErr := r.GetDBErr(context.Background(), r.masterIDs()); lookupErr == nil {
+				fn = dbPrimary.LazySelect(query)
+				err = fn(dest, args...)
+			}
 		}
 		return err
 	}
@@ -1134,17 +1410,21 @@ func (r *dbResolver) LazySelect(query string) func(dest any, args ...any) error
 // This supposed to be aligned with sqlx.DB.Select.
 func (r *dbResolver) NamedSelect(dest any, query string, args any) error {
 	query = r.GetQueryString(query)
-	db := r.GetDB(context.Background(), r.readDBs)
+	db, err := r.GetDBErr(context.Background(), r.readIDs())
+	if err != nil {
+		return err
+	}
 	rows, err := db.NamedQuery(query, args)
 	if isDBConnectionError(err) {
-		dbPrimary := r.GetDB(context.Background(), r.masters)
-		rows, err := dbPrimary.NamedQuery(query, args)
-		if err != nil {
-			return err
+		if dbPrimary, lookupErr := r.GetDBErr(context.Background(), r.masterIDs()); lookupErr == nil {
+			rows, err := dbPrimary.NamedQuery(query, args)
+			if err != nil {
+				return err
+			}
+			// if something happens here, we want to make sure the rows are Closed
+			defer rows.Close()
+			return squealx.ScannAll(rows, dest, false)
 		}
-		// if something happens here, we want to make sure the rows are Closed
-		defer rows.Close()
-		return squealx.ScannAll(rows, dest, false)
 	}
 	if err != nil {
 		return err
@@ -1161,11 +1441,15 @@ func (r *dbResolver) NamedSelect(dest any, query string, args any) error {
 // This supposed to be aligned with sqlx.DB.Select.
 func (r *dbResolver) NamedGet(dest any, query string, args any) error {
 	query = r.GetQueryString(query)
-	db := r.GetDB(context.Background(), r.readDBs)
-	err := db.NamedGet(dest, query, args)
+	db, err := r.GetDBErr(context.Background(), r.readIDs())
+	if err != nil {
+		return err
+	}
+	err = db.NamedGet(dest, query, args)
 	if isDBConnectionError(err) {
-		dbPrimary := r.GetDB(context.Background(), r.masters)
-		return dbPrimary.NamedGet(dest, query, args)
+		if dbPrimary, lookupErr := r.GetDBErr(context.Background(), r.masterIDs()); lookupErr == nil {
+			return dbPrimary.NamedGet(dest, query, args)
+		}
 	}
 	return err
 }
@@ -1177,20 +1461,31 @@ func (r *dbResolver) SelectContext(ctx context.Context, dest any, query string,
 	if squealx.IsNamedQuery(query) {
 		return r.NamedSelectContext(ctx, dest, query, args...)
 	}
-	db := r.GetDB(ctx, r.readDBs)
-	err := db.SelectContext(ctx, dest, query, args...)
-	if isDBConnectionError(err) {
-		dbPrimary := r.GetDB(ctx, r.masters)
-		err = dbPrimary.SelectContext(ctx, dest, query, args...)
-	}
-	return err
+	return r.cachedRead(ctx, dest, query, args, func() error {
+		db, err := r.GetDBErr(ctx, r.readIDs())
+		if err != nil {
+			return err
+		}
+		err = r.hedgedRead(ctx, db, dest, query, func(ctx context.Context, db *squealx.DB, dest any) error {
+			return db.SelectContext(ctx, dest, query, args...)
+		})
+		if isDBConnectionError(err) {
+			if dbPrimary, lookupErr := r.GetDBErr(ctx, r.masterIDs()); lookupErr == nil {
+				err = dbPrimary.SelectContext(ctx, dest, query, args...)
+			}
+		}
+		return err
+	})
 }
 
 // NamedSelectContext chooses a readable database and execute SELECT using chosen DB.
 // This supposed to be aligned with sqlx.DB.SelectContext.
 func (r *dbResolver) NamedSelectContext(ctx context.Context, dest any, query string, args ...any) error {
 	query = r.GetQueryString(query)
-	db := r.GetDB(ctx, r.readDBs)
+	db, err := r.GetDBErr(ctx, r.readIDs())
+	if err != nil {
+		return err
+	}
 	rows, err := db.NamedQueryContext(ctx, query, args[0])
 	if err != nil {
 		return err
@@ -1205,28 +1500,28 @@ func (r *dbResolver) NamedSelectContext(ctx context.Context, dest any, query str
 
 // SetConnMaxIdleTime sets the maximum amount of time a connection may be idle to all databases.
 func (r *dbResolver) SetConnMaxIdleTime(d time.Duration) {
-	for _, db := range r.dbs {
+	for _, db := range r.allDBs() {
 		db.SetConnMaxIdleTime(d)
 	}
 }
 
 // SetConnMaxLifetime sets the maximum amount of time a connection may be reused to all databases.
 func (r *dbResolver) SetConnMaxLifetime(d time.Duration) {
-	for _, db := range r.dbs {
+	for _, db := range r.allDBs() {
 		db.SetConnMaxLifetime(d)
 	}
 }
 
 // SetMaxIdleConns sets the maximum number of connections in the idle connection pool to all databases.
 func (r *dbResolver) SetMaxIdleConns(n int) {
-	for _, db := range r.dbs {
+	for _, db := range r.allDBs() {
 		db.SetMaxIdleConns(n)
 	}
 }
 
 // SetMaxOpenConns sets the maximum number of open connections to all databases.
 func (r *dbResolver) SetMaxOpenConns(n int) {
-	for _, db := range r.dbs {
+	for _, db := range r.allDBs() {
 		db.SetMaxOpenConns(n)
 	}
 }
@@ -1234,7 +1529,7 @@ func (r *dbResolver) SetMaxOpenConns(n int) {
 // Stats returns first primary database statistics.
 func (r *dbResolver) Stats() sql.DBStats {
 	var d *squealx.DB
-	for _, v := range r.dbs {
+	for _, v := range r.allDBs() {
 		d = v
 		break
 	}
@@ -1246,6 +1541,9 @@ func (r *dbResolver) Stats() sql.DBStats {
 // when columns in the SQL result have no fields in the destination struct.
 // This supposed to be aligned with sqlx.DB.Unsafe.
 func (r *dbResolver) Unsafe() *squealx.DB {
-	db := r.GetDB(context.Background(), r.masters)
+	db, err := r.GetDBErr(context.Background(), r.masterIDs())
+	if err != nil {
+		return nil
+	}
 	return db.Unsafe()
 }
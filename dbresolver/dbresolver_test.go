@@ -0,0 +1,39 @@
+package dbresolver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/oarkflow/squealx"
+	_ "modernc.org/sqlite"
+)
+
+func TestPaginateContextETag(t *testing.T) {
+	db, err := squealx.Connect("sqlite", ":memory:", "primary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	db.MustExec(`CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT)`)
+	db.MustExec(`INSERT INTO items (name) VALUES ('a'), ('b'), ('c')`)
+	r, err := New(WithMasterDBs(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	paging := squealx.Paging{Page: 1, Limit: 2, ETag: true}
+	var items []map[string]any
+	response := r.PaginateContext(context.Background(), "SELECT * FROM items", &items, paging)
+	if response.Error != nil {
+		t.Fatal(response.Error)
+	}
+	if len(items) != 2 {
+		t.Errorf("%d items, want 2", len(items))
+	}
+	var direct []map[string]any
+	want := squealx.PaginateContext(context.Background(), db, "SELECT * FROM items", &direct, paging)
+	if response.ETag == "" || response.ETag != want.ETag {
+		t.Errorf("ETag = %q, want %q as set by squealx.PaginateContext", response.ETag, want.ETag)
+	}
+}
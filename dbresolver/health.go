@@ -0,0 +1,213 @@
+package dbresolver
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/oarkflow/squealx"
+)
+
+// latencySamples is the number of recent statement latencies kept per
+// database to compute NodeStats.P95Latency.
+const latencySamples = 128
+
+// NodeStats describes a database of the resolver to a StatsLoadBalancer.
+type NodeStats struct {
+	ID string
+	// Healthy is false while the last health check of the database failed.
+	// Without WithHealthCheck, databases are always healthy.
+	Healthy bool
+	// InUse is the number of connections of the database in use.
+	InUse int
+	// P95Latency is the 95th percentile latency of the recent statements
+	// run on the database, or zero before any ran. Latencies are only
+	// recorded when the load balancer is a StatsLoadBalancer.
+	P95Latency time.Duration
+}
+
+// healthTracker keeps the stats of the databases of a resolver: latencies
+// of recent statements, recorded by hooks when the load balancer is a
+// StatsLoadBalancer, and health, updated by the checks started with
+// WithHealthCheck.
+type healthTracker struct {
+	mu    sync.Mutex
+	nodes map[string]*nodeHealth
+	// hooks names the latency hooks, empty when latencies are not recorded.
+	hooks string
+	stop  chan struct{}
+	done  sync.WaitGroup
+}
+
+type nodeHealth struct {
+	db        *squealx.DB
+	unhealthy bool
+	samples   [latencySamples]time.Duration
+	recorded  int
+}
+
+type statementStartKey struct{}
+
+// newHealthTracker returns a healthTracker, recording the latencies of
+// statements if latency is set.
+func newHealthTracker(latency bool) *healthTracker {
+	h := &healthTracker{nodes: make(map[string]*nodeHealth)}
+	if latency {
+		h.hooks = "squealx:resolver:latency:" + squealx.NewULID()
+	}
+	return h
+}
+
+// observe tracks db, registering the hooks recording the latency of its
+// statements if latencies are recorded.
+func (h *healthTracker) observe(db *squealx.DB) {
+	h.mu.Lock()
+	if _, ok := h.nodes[db.ID]; ok {
+		h.mu.Unlock()
+		return
+	}
+	h.nodes[db.ID] = &nodeHealth{db: db}
+	h.mu.Unlock()
+	if h.hooks != "" {
+		db.UseNamed(h.hooks, latencyHooks{tracker: h, id: db.ID})
+	}
+}
+
+// forget removes the latency hooks from db.
+func (h *healthTracker) forget(db *squealx.DB) {
+	if h.hooks != "" {
+		db.RemoveNamed(h.hooks)
+	}
+}
+
+// latencyHooks record the latency of the statements of database id.
+type latencyHooks struct {
+	tracker *healthTracker
+	id      string
+}
+
+func (l latencyHooks) Before(ctx context.Context, _ string, _ ...any) (context.Context, error) {
+	return context.WithValue(ctx, statementStartKey{}, time.Now()), nil
+}
+
+func (l latencyHooks) After(ctx context.Context, _ string, _ ...any) (context.Context, error) {
+	if start, ok := ctx.Value(statementStartKey{}).(time.Time); ok {
+		l.tracker.record(l.id, time.Since(start))
+	}
+	return ctx, nil
+}
+
+func (h *healthTracker) record(id string, latency time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if n, ok := h.nodes[id]; ok {
+		n.samples[n.recorded%latencySamples] = latency
+		n.recorded++
+	}
+}
+
+// healthy returns the healthy databases of ids, or ids when none is.
+func (h *healthTracker) healthy(ids []string) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var healthy []string
+	for i, id := range ids {
+		if n, ok := h.nodes[id]; ok && n.unhealthy {
+			if healthy == nil {
+				healthy = append(make([]string, 0, len(ids)), ids[:i]...)
+			}
+			continue
+		}
+		if healthy != nil {
+			healthy = append(healthy, id)
+		}
+	}
+	if len(healthy) == 0 {
+		return ids
+	}
+	return healthy
+}
+
+// stats returns the stats of the databases of ids.
+func (h *healthTracker) stats(ids []string) []NodeStats {
+	stats := make([]NodeStats, len(ids))
+	h.mu.Lock()
+	var dbs []*squealx.DB
+	for i, id := range ids {
+		stats[i] = NodeStats{ID: id, Healthy: true}
+		n, ok := h.nodes[id]
+		if !ok {
+			dbs = append(dbs, nil)
+			continue
+		}
+		dbs = append(dbs, n.db)
+		stats[i].Healthy = !n.unhealthy
+		stats[i].P95Latency = n.p95()
+	}
+	h.mu.Unlock()
+	for i, db := range dbs {
+		if db != nil {
+			stats[i].InUse = db.Stats().InUse
+		}
+	}
+	return stats
+}
+
+func (n *nodeHealth) p95() time.Duration {
+	count := min(n.recorded, latencySamples)
+	if count == 0 {
+		return 0
+	}
+	samples := slices.Clone(n.samples[:count])
+	slices.Sort(samples)
+	return samples[(count*95+99)/100-1]
+}
+
+// start checks the health of the observed databases every interval, until
+// close is called.
+func (h *healthTracker) start(interval time.Duration) {
+	stop := make(chan struct{})
+	h.stop = stop
+	h.done.Add(1)
+	go func() {
+		defer h.done.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			h.check(interval)
+			select {
+			case <-ticker.C:
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// check pings every observed database, waiting at most timeout for each.
+func (h *healthTracker) check(timeout time.Duration) {
+	h.mu.Lock()
+	nodes := make([]*nodeHealth, 0, len(h.nodes))
+	for _, n := range h.nodes {
+		nodes = append(nodes, n)
+	}
+	h.mu.Unlock()
+	for _, n := range nodes {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		err := n.db.PingContext(ctx)
+		cancel()
+		h.mu.Lock()
+		n.unhealthy = err != nil
+		h.mu.Unlock()
+	}
+}
+
+// close stops the health checks.
+func (h *healthTracker) close() {
+	if h.stop != nil {
+		close(h.stop)
+		h.done.Wait()
+		h.stop = nil
+	}
+}
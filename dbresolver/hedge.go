@@ -0,0 +1,154 @@
+package dbresolver
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/oarkflow/squealx"
+)
+
+// HedgeStats counts the reads hedged by the resolver, see WithHedging.
+type HedgeStats struct {
+	// Reads is the number of reads which could be hedged.
+	Reads uint64
+	// Hedged is the number of reads for which a second query was issued.
+	Hedged uint64
+	// Wins is the number of hedged reads answered first by the second
+	// query.
+	Wins uint64
+}
+
+// Rate returns the share of the reads which were hedged.
+func (s HedgeStats) Rate() float64 {
+	if s.Reads == 0 {
+		return 0
+	}
+	return float64(s.Hedged) / float64(s.Reads)
+}
+
+// hedger issues a second query for reads not answered within delay.
+type hedger struct {
+	delay  time.Duration
+	reads  atomic.Uint64
+	hedged atomic.Uint64
+	wins   atomic.Uint64
+}
+
+func newHedger(delay time.Duration) *hedger {
+	if delay <= 0 {
+		return nil
+	}
+	return &hedger{delay: delay}
+}
+
+func (h *hedger) stats() HedgeStats {
+	if h == nil {
+		return HedgeStats{}
+	}
+	return HedgeStats{Reads: h.reads.Load(), Hedged: h.hedged.Load(), Wins: h.wins.Load()}
+}
+
+// HedgeStats returns the counts of the reads hedged by the resolver.
+func (r *dbResolver) HedgeStats() HedgeStats {
+	return r.hedge.stats()
+}
+
+// hedgeable reports whether query can be sent to two databases at once: a
+// SELECT without locking clauses, read into a pointer.
+func hedgeable(query string, dest any) bool {
+	if kind, _ := squealx.ClassifyStatement(query); kind != squealx.StatementSelect {
+		return false
+	}
+	if squealx.IsLockingRead(query) {
+		return false
+	}
+	t := reflect.TypeOf(dest)
+	return t != nil && t.Kind() == reflect.Ptr
+}
+
+type hedgeResult struct {
+	dest  any
+	err   error
+	hedge bool
+}
+
+// hedgedRead runs read on db. When hedging is enabled and db has not
+// answered query within the hedging delay, read also runs on another
+// readable database; the first successful answer is stored in dest and
+// the other read is cancelled.
+func (r *dbResolver) hedgedRead(ctx context.Context, db *squealx.DB, dest any, query string, read func(ctx context.Context, db *squealx.DB, dest any) error) error {
+	if r.hedge == nil || !hedgeable(query, dest) {
+		return read(ctx, db, dest)
+	}
+	others := slices.DeleteFunc(slices.Clone(r.readIDs()), func(id string) bool { return id == db.ID })
+	if len(others) == 0 {
+		return read(ctx, db, dest)
+	}
+	r.hedge.reads.Add(1)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan hedgeResult, 2)
+	run := func(db *squealx.DB, hedge bool) {
+		d := newDest(dest)
+		results <- hedgeResult{dest: d, err: read(ctx, db, d), hedge: hedge}
+	}
+	go run(db, false)
+	timer := time.NewTimer(r.hedge.delay)
+	defer timer.Stop()
+	pending := 1
+	var firstErr error
+	for {
+		select {
+		case <-timer.C:
+			// The load balancer chooses among the others, bypassing the
+			// default database, which may well be db itself.
+			if second, err := r.getDB(r.selectDB(ctx, others)); err == nil {
+				r.hedge.hedged.Add(1)
+				pending++
+				go run(second, true)
+			}
+		case res := <-results:
+			pending--
+			if res.err == nil {
+				if res.hedge {
+					r.hedge.wins.Add(1)
+				}
+				storeDest(dest, res.dest)
+				return nil
+			}
+			if firstErr == nil {
+				firstErr = res.err
+			}
+			if pending == 0 {
+				return firstErr
+			}
+		}
+	}
+}
+
+// newDest returns a new destination of the type of dest, a pointer.
+func newDest(dest any) any {
+	return reflect.New(reflect.TypeOf(dest).Elem()).Interface()
+}
+
+// resetDest zeroes what a failed read may have stored in dest, a pointer,
+// before it is read again.
+func resetDest(dest any) {
+	if v := reflect.ValueOf(dest); v.Kind() == reflect.Ptr && !v.IsNil() {
+		v.Elem().SetZero()
+	}
+}
+
+// storeDest stores the result read into src in dest. Slices are appended
+// to, as Select does.
+func storeDest(dest, src any) {
+	d, s := reflect.ValueOf(dest).Elem(), reflect.ValueOf(src).Elem()
+	if d.Kind() == reflect.Slice {
+		d.Set(reflect.AppendSlice(d, s))
+		return
+	}
+	d.Set(s)
+}
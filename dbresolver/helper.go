@@ -1,16 +1,11 @@
 package dbresolver
 
 import (
-	"net"
+	"github.com/oarkflow/squealx"
 )
 
+// isDBConnectionError reports whether err, possibly wrapped, is a
+// connection failure worth retrying on another database.
 func isDBConnectionError(err error) bool {
-	if _, ok := err.(net.Error); ok {
-		return ok
-	}
-
-	if _, ok := err.(*net.OpError); ok {
-		return ok
-	}
-	return false
+	return squealx.IsConnectionError(err)
 }
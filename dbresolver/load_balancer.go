@@ -14,6 +14,7 @@ const (
 	RoundRobinLB         LoadBalancerPolicy = "ROUND_ROBIN"
 	RandomLB             LoadBalancerPolicy = "RANDOM"
 	InjectedLoadBalancer LoadBalancerPolicy = "INJECTED_LOAD_BALANCER"
+	LeastLoadedLB        LoadBalancerPolicy = "LEAST_LOADED"
 )
 
 // LoadBalancer chooses a database from the given databases.
@@ -22,6 +23,43 @@ type LoadBalancer interface {
 	Name() LoadBalancerPolicy
 }
 
+// StatsLoadBalancer is a LoadBalancer which chooses a database from the
+// stats of the given databases, as tracked by the resolver. The resolver
+// calls SelectStats instead of Select on load balancers implementing it.
+type StatsLoadBalancer interface {
+	LoadBalancer
+	SelectStats(ctx context.Context, nodes []NodeStats) string
+}
+
+// AdaptLoadBalancer returns lb as a StatsLoadBalancer. A load balancer
+// implementing only LoadBalancer chooses among the healthy databases, or
+// among all of them when none is healthy, as the resolver does with it.
+func AdaptLoadBalancer(lb LoadBalancer) StatsLoadBalancer {
+	if s, ok := lb.(StatsLoadBalancer); ok {
+		return s
+	}
+	return statsAdapter{lb}
+}
+
+type statsAdapter struct {
+	LoadBalancer
+}
+
+func (a statsAdapter) SelectStats(ctx context.Context, nodes []NodeStats) string {
+	dbs := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		if n.Healthy {
+			dbs = append(dbs, n.ID)
+		}
+	}
+	if len(dbs) == 0 {
+		for _, n := range nodes {
+			dbs = append(dbs, n.ID)
+		}
+	}
+	return a.Select(ctx, dbs)
+}
+
 // RandomLoadBalancer is a load balancer that chooses a database randomly.
 type RandomLoadBalancer struct{}
 
@@ -80,3 +118,49 @@ func (b *RoundRobinLoadBalancer) Select(_ context.Context, dbs []string) string
 func (b *RoundRobinLoadBalancer) Name() LoadBalancerPolicy {
 	return RoundRobinLB
 }
+
+// LeastLoadedLoadBalancer is a load balancer that chooses the healthy
+// database with the fewest connections in use, then the lowest p95 latency.
+type LeastLoadedLoadBalancer struct {
+	random RandomLoadBalancer
+}
+
+var _ StatsLoadBalancer = (*LeastLoadedLoadBalancer)(nil)
+
+func NewLeastLoadedLoadBalancer() *LeastLoadedLoadBalancer {
+	return &LeastLoadedLoadBalancer{}
+}
+
+// Select chooses a database randomly, as there are no stats to go by.
+func (b *LeastLoadedLoadBalancer) Select(ctx context.Context, dbs []string) string {
+	return b.random.Select(ctx, dbs)
+}
+
+// SelectStats returns the least loaded database of nodes. Unhealthy
+// databases are only chosen when none is healthy.
+func (b *LeastLoadedLoadBalancer) SelectStats(_ context.Context, nodes []NodeStats) string {
+	best := -1
+	for i, n := range nodes {
+		if best < 0 || lessLoaded(n, nodes[best]) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return ""
+	}
+	return nodes[best].ID
+}
+
+func lessLoaded(a, b NodeStats) bool {
+	if a.Healthy != b.Healthy {
+		return a.Healthy
+	}
+	if a.InUse != b.InUse {
+		return a.InUse < b.InUse
+	}
+	return a.P95Latency < b.P95Latency
+}
+
+func (b *LeastLoadedLoadBalancer) Name() LoadBalancerPolicy {
+	return LeastLoadedLB
+}
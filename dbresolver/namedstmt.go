@@ -72,7 +72,10 @@ func (s *namedStmt) Close() error {
 // Exec chooses a primary database's named statement and executes a named statement given argument.
 // Exec wraps sqlx.NamedStmt.Exec.
 func (s *namedStmt) Exec(arg any) (sql.Result, error) {
-	db := s.db.GetDB(context.Background(), s.masters)
+	db, err := s.db.GetDBErr(context.Background(), s.masters)
+	if err != nil {
+		return nil, err
+	}
 	stmt, ok := s.masterStmts[db]
 	if !ok {
 		// Should not happen.
@@ -84,7 +87,10 @@ func (s *namedStmt) Exec(arg any) (sql.Result, error) {
 // ExecContext chooses a primary database's named statement and executes a named statement given argument.
 // ExecContext wraps sqlx.NamedStmt.ExecContext.
 func (s *namedStmt) ExecContext(ctx context.Context, arg any) (sql.Result, error) {
-	db := s.db.GetDB(ctx, s.masters)
+	db, err := s.db.GetDBErr(ctx, s.masters)
+	if err != nil {
+		return nil, err
+	}
 	stmt, ok := s.masterStmts[db]
 	if !ok {
 		// Should not happen.
@@ -96,22 +102,26 @@ func (s *namedStmt) ExecContext(ctx context.Context, arg any) (sql.Result, error
 // Get chooses a readable database's named statement and Get using chosen statement.
 // Get wraps sqlx.NamedStmt.Get.
 func (s *namedStmt) Get(dest any, arg any) error {
-	db := s.db.GetDB(context.Background(), s.readReplicas)
+	db, err := s.db.GetDBErr(context.Background(), s.readReplicas)
+	if err != nil {
+		return err
+	}
 	stmt, ok := s.replicaStmts[db]
 	if !ok {
 		// Should not happen.
 		return errors.Join(errSelectedNamedStmtNotFound, fmt.Errorf("readable db: %v", db))
 	}
-	err := stmt.Get(dest, arg)
+	err = stmt.Get(dest, arg)
 
 	if isDBConnectionError(err) {
-		dbPrimary := s.db.GetDB(context.Background(), s.masters)
-		stmtPrimary, ok := s.replicaStmts[dbPrimary]
-		if !ok {
-			// Should not happen.
-			return errors.Join(errSelectedNamedStmtNotFound, fmt.Errorf("readable db: %v", db))
+		if dbPrimary, lookupErr := s.db.GetDBErr(context.Background(), s.masters); lookupErr == nil {
+			stmtPrimary, ok := s.replicaStmts[dbPrimary]
+			if !ok {
+				// Should not happen.
+				return errors.Join(errSelectedNamedStmtNotFound, fmt.Errorf("readable db: %v", db))
+			}
+			err = stmtPrimary.Get(dest, arg)
 		}
-		err = stmtPrimary.Get(dest, arg)
 	}
 	return err
 }
@@ -119,22 +129,26 @@ func (s *namedStmt) Get(dest any, arg any) error {
 // GetContext chooses a readable database's named statement and Get using chosen statement.
 // GetContext wraps sqlx.NamedStmt.GetContext.
 func (s *namedStmt) GetContext(ctx context.Context, dest any, arg any) error {
-	db := s.db.GetDB(ctx, s.readReplicas)
+	db, err := s.db.GetDBErr(ctx, s.readReplicas)
+	if err != nil {
+		return err
+	}
 	stmt, ok := s.replicaStmts[db]
 	if !ok {
 		// Should not happen.
 		return errors.Join(errSelectedNamedStmtNotFound, fmt.Errorf("readable db: %v", db))
 	}
-	err := stmt.GetContext(ctx, dest, arg)
+	err = stmt.GetContext(ctx, dest, arg)
 
 	if isDBConnectionError(err) {
-		dbPrimary := s.db.GetDB(ctx, s.masters)
-		stmtPrimary, ok := s.replicaStmts[dbPrimary]
-		if !ok {
-			// Should not happen.
-			return errors.Join(errSelectedNamedStmtNotFound, fmt.Errorf("readable db: %v", db))
+		if dbPrimary, lookupErr := s.db.GetDBErr(ctx, s.masters); lookupErr == nil {
+			stmtPrimary, ok := s.replicaStmts[dbPrimary]
+			if !ok {
+				// Should not happen.
+				return errors.Join(errSelectedNamedStmtNotFound, fmt.Errorf("readable db: %v", db))
+			}
+			err = stmtPrimary.GetContext(ctx, dest, arg)
 		}
-		err = stmtPrimary.GetContext(ctx, dest, arg)
 	}
 	return err
 }
@@ -143,7 +157,10 @@ func (s *namedStmt) GetContext(ctx context.Context, dest any, arg any) error {
 // and executes chosen statement with given argument.
 // MustExec wraps sqlx.NamedStmt.MustExec.
 func (s *namedStmt) MustExec(arg any) sql.Result {
-	db := s.db.GetDB(context.Background(), s.masters)
+	db, err := s.db.GetDBErr(context.Background(), s.masters)
+	if err != nil {
+		panic(err)
+	}
 	stmt, ok := s.masterStmts[db]
 	if !ok {
 		// Should not happen.
@@ -156,7 +173,10 @@ func (s *namedStmt) MustExec(arg any) sql.Result {
 // and executes chosen statement with given argument.
 // MustExecContext wraps sqlx.NamedStmt.MustExecContext.
 func (s *namedStmt) MustExecContext(ctx context.Context, arg any) sql.Result {
-	db := s.db.GetDB(ctx, s.masters)
+	db, err := s.db.GetDBErr(ctx, s.masters)
+	if err != nil {
+		panic(err)
+	}
 	stmt, ok := s.masterStmts[db]
 	if !ok {
 		// Should not happen.
@@ -169,7 +189,10 @@ func (s *namedStmt) MustExecContext(ctx context.Context, arg any) sql.Result {
 // and returns sql.Rows.
 // Query wraps sqlx.NamedStmt.Query.
 func (s *namedStmt) Query(arg any) (squealx.SQLRow, error) {
-	db := s.db.GetDB(context.Background(), s.readReplicas)
+	db, err := s.db.GetDBErr(context.Background(), s.readReplicas)
+	if err != nil {
+		return nil, err
+	}
 	stmt, ok := s.replicaStmts[db]
 	if !ok {
 		// Should not happen.
@@ -178,13 +201,14 @@ func (s *namedStmt) Query(arg any) (squealx.SQLRow, error) {
 	rows, err := stmt.Query(arg)
 
 	if isDBConnectionError(err) {
-		dbPrimary := s.db.GetDB(context.Background(), s.masters)
-		stmtPrimary, ok := s.replicaStmts[dbPrimary]
-		if !ok {
-			// Should not happen.
-			return nil, errors.Join(errSelectedNamedStmtNotFound, fmt.Errorf("readable db: %v", db))
+		if dbPrimary, lookupErr := s.db.GetDBErr(context.Background(), s.masters); lookupErr == nil {
+			stmtPrimary, ok := s.replicaStmts[dbPrimary]
+			if !ok {
+				// Should not happen.
+				return nil, errors.Join(errSelectedNamedStmtNotFound, fmt.Errorf("readable db: %v", db))
+			}
+			rows, err = stmtPrimary.Query(arg)
 		}
-		rows, err = stmtPrimary.Query(arg)
 	}
 	return rows, err
 }
@@ -193,7 +217,10 @@ func (s *namedStmt) Query(arg any) (squealx.SQLRow, error) {
 // and returns sql.Rows.
 // QueryContext wraps sqlx.NamedStmt.QueryContext.
 func (s *namedStmt) QueryContext(ctx context.Context, arg any) (squealx.SQLRow, error) {
-	db := s.db.GetDB(ctx, s.readReplicas)
+	db, err := s.db.GetDBErr(ctx, s.readReplicas)
+	if err != nil {
+		return nil, err
+	}
 	stmt, ok := s.replicaStmts[db]
 	if !ok {
 		// Should not happen.
@@ -202,13 +229,14 @@ func (s *namedStmt) QueryContext(ctx context.Context, arg any) (squealx.SQLRow,
 	rows, err := stmt.QueryContext(ctx, arg)
 
 	if isDBConnectionError(err) {
-		dbPrimary := s.db.GetDB(ctx, s.masters)
-		stmtPrimary, ok := s.replicaStmts[dbPrimary]
-		if !ok {
-			// Should not happen.
-			return nil, errors.Join(errSelectedNamedStmtNotFound, fmt.Errorf("readable db: %v", db))
+		if dbPrimary, lookupErr := s.db.GetDBErr(ctx, s.masters); lookupErr == nil {
+			stmtPrimary, ok := s.replicaStmts[dbPrimary]
+			if !ok {
+				// Should not happen.
+				return nil, errors.Join(errSelectedNamedStmtNotFound, fmt.Errorf("readable db: %v", db))
+			}
+			rows, err = stmtPrimary.QueryContext(ctx, arg)
 		}
-		rows, err = stmtPrimary.QueryContext(ctx, arg)
 	}
 	return rows, err
 }
@@ -218,7 +246,10 @@ func (s *namedStmt) QueryContext(ctx context.Context, arg any) (squealx.SQLRow,
 // If selected statement is not found, returns nil.
 // QueryRow wraps sqlx.NamedStmt.QueryRow.
 func (s *namedStmt) QueryRow(arg any) *squealx.Row {
-	db := s.db.GetDB(context.Background(), s.readReplicas)
+	db, err := s.db.GetDBErr(context.Background(), s.readReplicas)
+	if err != nil {
+		return squealx.RowError(err)
+	}
 	stmt, ok := s.replicaStmts[db]
 	if !ok {
 		// Should not happen.
@@ -227,13 +258,14 @@ func (s *namedStmt) QueryRow(arg any) *squealx.Row {
 	row := stmt.QueryRow(arg)
 
 	if isDBConnectionError(row.Err()) {
-		dbPrimary := s.db.GetDB(context.Background(), s.masters)
-		stmtPrimary, ok := s.replicaStmts[dbPrimary]
-		if !ok {
-			// Should not happen.
-			return nil
+		if dbPrimary, lookupErr := s.db.GetDBErr(context.Background(), s.masters); lookupErr == nil {
+			stmtPrimary, ok := s.replicaStmts[dbPrimary]
+			if !ok {
+				// Should not happen.
+				return nil
+			}
+			row = stmtPrimary.QueryRow(arg)
 		}
-		row = stmtPrimary.QueryRow(arg)
 	}
 	return row
 }
@@ -243,7 +275,10 @@ func (s *namedStmt) QueryRow(arg any) *squealx.Row {
 // If selected statement is not found, returns nil.
 // QueryRowContext wraps sqlx.NamedStmt.QueryRowContext.
 func (s *namedStmt) QueryRowContext(ctx context.Context, arg any) *squealx.Row {
-	db := s.db.GetDB(ctx, s.readReplicas)
+	db, err := s.db.GetDBErr(ctx, s.readReplicas)
+	if err != nil {
+		return squealx.RowError(err)
+	}
 	stmt, ok := s.replicaStmts[db]
 	if !ok {
 		// Should not happen.
@@ -252,13 +287,14 @@ func (s *namedStmt) QueryRowContext(ctx context.Context, arg any) *squealx.Row {
 	row := stmt.QueryRowContext(ctx, arg)
 
 	if isDBConnectionError(row.Err()) {
-		dbPrimary := s.db.GetDB(ctx, s.masters)
-		stmtPrimary, ok := s.replicaStmts[dbPrimary]
-		if !ok {
-			// Should not happen.
-			return nil
+		if dbPrimary, lookupErr := s.db.GetDBErr(ctx, s.masters); lookupErr == nil {
+			stmtPrimary, ok := s.replicaStmts[dbPrimary]
+			if !ok {
+				// Should not happen.
+				return nil
+			}
+			row = stmtPrimary.QueryRowContext(ctx, arg)
 		}
-		row = stmtPrimary.QueryRowContext(ctx, arg)
 	}
 	return row
 }
@@ -268,7 +304,10 @@ func (s *namedStmt) QueryRowContext(ctx context.Context, arg any) *squealx.Row {
 // If selected statement is not found, returns nil.
 // QueryRowx wraps sqlx.NamedStmt.QueryRowx.
 func (s *namedStmt) QueryRowx(arg any) *squealx.Row {
-	db := s.db.GetDB(context.Background(), s.readReplicas)
+	db, err := s.db.GetDBErr(context.Background(), s.readReplicas)
+	if err != nil {
+		return squealx.RowError(err)
+	}
 	stmt, ok := s.replicaStmts[db]
 	if !ok {
 		// Should not happen.
@@ -277,13 +316,14 @@ func (s *namedStmt) QueryRowx(arg any) *squealx.Row {
 	row := stmt.QueryRowx(arg)
 
 	if isDBConnectionError(row.Err()) {
-		dbPrimary := s.db.GetDB(context.Background(), s.masters)
-		stmtPrimary, ok := s.replicaStmts[dbPrimary]
-		if !ok {
-			// Should not happen.
-			return nil
+		if dbPrimary, lookupErr := s.db.GetDBErr(context.Background(), s.masters); lookupErr == nil {
+			stmtPrimary, ok := s.replicaStmts[dbPrimary]
+			if !ok {
+				// Should not happen.
+				return nil
+			}
+			row = stmtPrimary.QueryRowx(arg)
 		}
-		row = stmtPrimary.QueryRowx(arg)
 	}
 	return row
 }
@@ -293,7 +333,10 @@ func (s *namedStmt) QueryRowx(arg any) *squealx.Row {
 // If selected statement is not found, returns nil.
 // QueryRowxContext wraps sqlx.NamedStmt.QueryRowxContext.
 func (s *namedStmt) QueryRowxContext(ctx context.Context, arg any) *squealx.Row {
-	db := s.db.GetDB(ctx, s.readReplicas)
+	db, err := s.db.GetDBErr(ctx, s.readReplicas)
+	if err != nil {
+		return squealx.RowError(err)
+	}
 	stmt, ok := s.replicaStmts[db]
 	if !ok {
 		// Should not happen.
@@ -302,13 +345,14 @@ func (s *namedStmt) QueryRowxContext(ctx context.Context, arg any) *squealx.Row
 	row := stmt.QueryRowxContext(ctx, arg)
 
 	if isDBConnectionError(row.Err()) {
-		dbPrimary := s.db.GetDB(ctx, s.masters)
-		stmtPrimary, ok := s.replicaStmts[dbPrimary]
-		if !ok {
-			// Should not happen.
-			return nil
+		if dbPrimary, lookupErr := s.db.GetDBErr(ctx, s.masters); lookupErr == nil {
+			stmtPrimary, ok := s.replicaStmts[dbPrimary]
+			if !ok {
+				// Should not happen.
+				return nil
+			}
+			row = stmtPrimary.QueryRowxContext(ctx, arg)
 		}
-		row = stmtPrimary.QueryRowxContext(ctx, arg)
 	}
 	return row
 }
@@ -317,7 +361,10 @@ func (s *namedStmt) QueryRowxContext(ctx context.Context, arg any) *squealx.Row
 // and returns sqlx.Rows.
 // Queryx wraps sqlx.NamedStmt.Queryx.
 func (s *namedStmt) Queryx(arg any) (*squealx.Rows, error) {
-	db := s.db.GetDB(context.Background(), s.readReplicas)
+	db, err := s.db.GetDBErr(context.Background(), s.readReplicas)
+	if err != nil {
+		return nil, err
+	}
 	stmt, ok := s.replicaStmts[db]
 	if !ok {
 		// Should not happen.
@@ -326,13 +373,14 @@ func (s *namedStmt) Queryx(arg any) (*squealx.Rows, error) {
 	rows, err := stmt.Queryx(arg)
 
 	if isDBConnectionError(err) {
-		dbPrimary := s.db.GetDB(context.Background(), s.masters)
-		stmtPrimary, ok := s.replicaStmts[dbPrimary]
-		if !ok {
-			// Should not happen.
-			return nil, errors.Join(errSelectedNamedStmtNotFound, fmt.Errorf("readable db: %v", db))
+		if dbPrimary, lookupErr := s.db.GetDBErr(context.Background(), s.masters); lookupErr == nil {
+			stmtPrimary, ok := s.replicaStmts[dbPrimary]
+			if !ok {
+				// Should not happen.
+				return nil, errors.Join(errSelectedNamedStmtNotFound, fmt.Errorf("readable db: %v", db))
+			}
+			rows, err = stmtPrimary.Queryx(arg)
 		}
-		rows, err = stmtPrimary.Queryx(arg)
 	}
 	return rows, err
 }
@@ -341,7 +389,10 @@ func (s *namedStmt) Queryx(arg any) (*squealx.Rows, error) {
 // and returns sqlx.Rows.
 // QueryxContext wraps sqlx.NamedStmt.QueryxContext.
 func (s *namedStmt) QueryxContext(ctx context.Context, arg any) (*squealx.Rows, error) {
-	db := s.db.GetDB(ctx, s.readReplicas)
+	db, err := s.db.GetDBErr(ctx, s.readReplicas)
+	if err != nil {
+		return nil, err
+	}
 	stmt, ok := s.replicaStmts[db]
 	if !ok {
 		// Should not happen.
@@ -350,13 +401,14 @@ func (s *namedStmt) QueryxContext(ctx context.Context, arg any) (*squealx.Rows,
 	rows, err := stmt.QueryxContext(ctx, arg)
 
 	if isDBConnectionError(err) {
-		dbPrimary := s.db.GetDB(ctx, s.masters)
-		stmtPrimary, ok := s.replicaStmts[dbPrimary]
-		if !ok {
-			// Should not happen.
-			return nil, errors.Join(errSelectedNamedStmtNotFound, fmt.Errorf("readable db: %v", db))
+		if dbPrimary, lookupErr := s.db.GetDBErr(ctx, s.masters); lookupErr == nil {
+			stmtPrimary, ok := s.replicaStmts[dbPrimary]
+			if !ok {
+				// Should not happen.
+				return nil, errors.Join(errSelectedNamedStmtNotFound, fmt.Errorf("readable db: %v", db))
+			}
+			rows, err = stmtPrimary.QueryxContext(ctx, arg)
 		}
-		rows, err = stmtPrimary.QueryxContext(ctx, arg)
 	}
 	return rows, err
 }
@@ -364,22 +416,26 @@ func (s *namedStmt) QueryxContext(ctx context.Context, arg any) (*squealx.Rows,
 // Select chooses a readable database's named statement, executes chosen statement with given argument
 // Select wraps sqlx.NamedStmt.Select.
 func (s *namedStmt) Select(dest any, arg any) error {
-	db := s.db.GetDB(context.Background(), s.readReplicas)
+	db, err := s.db.GetDBErr(context.Background(), s.readReplicas)
+	if err != nil {
+		return err
+	}
 	stmt, ok := s.replicaStmts[db]
 	if !ok {
 		// Should not happen.
 		return errors.Join(errSelectedNamedStmtNotFound, fmt.Errorf("readable db: %v", db))
 	}
-	err := stmt.Select(dest, arg)
+	err = stmt.Select(dest, arg)
 
 	if isDBConnectionError(err) {
-		dbPrimary := s.db.GetDB(context.Background(), s.masters)
-		stmtPrimary, ok := s.replicaStmts[dbPrimary]
-		if !ok {
-			// Should not happen.
-			return errors.Join(errSelectedNamedStmtNotFound, fmt.Errorf("readable db: %v", db))
+		if dbPrimary, lookupErr := s.db.GetDBErr(context.Background(), s.masters); lookupErr == nil {
+			stmtPrimary, ok := s.replicaStmts[dbPrimary]
+			if !ok {
+				// Should not happen.
+				return errors.Join(errSelectedNamedStmtNotFound, fmt.Errorf("readable db: %v", db))
+			}
+			err = stmtPrimary.Select(dest, arg)
 		}
-		err = stmtPrimary.Select(dest, arg)
 	}
 	return err
 }
@@ -387,22 +443,26 @@ func (s *namedStmt) Select(dest any, arg any) error {
 // SelectContext chooses a readable database's named statement, executes chosen statement with given argument
 // SelectContext wraps sqlx.NamedStmt.SelectContext.
 func (s *namedStmt) SelectContext(ctx context.Context, dest any, arg any) error {
-	db := s.db.GetDB(ctx, s.readReplicas)
+	db, err := s.db.GetDBErr(ctx, s.readReplicas)
+	if err != nil {
+		return err
+	}
 	stmt, ok := s.replicaStmts[db]
 	if !ok {
 		// Should not happen.
 		return errors.Join(errSelectedNamedStmtNotFound, fmt.Errorf("readable db: %v", db))
 	}
-	err := stmt.SelectContext(ctx, dest, arg)
+	err = stmt.SelectContext(ctx, dest, arg)
 
 	if isDBConnectionError(err) {
-		dbPrimary := s.db.GetDB(ctx, s.masters)
-		stmtPrimary, ok := s.replicaStmts[dbPrimary]
-		if !ok {
-			// Should not happen.
-			return errors.Join(errSelectedNamedStmtNotFound, fmt.Errorf("readable db: %v", db))
+		if dbPrimary, lookupErr := s.db.GetDBErr(ctx, s.masters); lookupErr == nil {
+			stmtPrimary, ok := s.replicaStmts[dbPrimary]
+			if !ok {
+				// Should not happen.
+				return errors.Join(errSelectedNamedStmtNotFound, fmt.Errorf("readable db: %v", db))
+			}
+			err = stmtPrimary.SelectContext(ctx, dest, arg)
 		}
-		err = stmtPrimary.SelectContext(ctx, dest, arg)
 	}
 	return err
 }
@@ -411,7 +471,10 @@ func (s *namedStmt) SelectContext(ctx context.Context, dest any, arg any) error
 // If selected statement is not found, returns nil.
 // Unsafe wraps sqlx.NamedStmt.Unsafe.
 func (s *namedStmt) Unsafe() *squealx.NamedStmt {
-	db := s.db.GetDB(context.Background(), s.masters)
+	db, err := s.db.GetDBErr(context.Background(), s.masters)
+	if err != nil {
+		return nil
+	}
 	stmt, ok := s.masterStmts[db]
 	if !ok {
 		// Should not happen.
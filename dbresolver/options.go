@@ -1,6 +1,9 @@
 package dbresolver
 
 import (
+	"strings"
+	"time"
+
 	"github.com/oarkflow/squealx"
 )
 
@@ -12,6 +15,12 @@ type Options struct {
 	loadBalancer    LoadBalancer
 	fileLoader      *squealx.FileLoader
 	readWritePolicy ReadWritePolicy
+	txSettings      *squealx.TxSettings
+	tableTTLs       map[string]time.Duration
+	cacheStore      squealx.CacheStore
+	healthInterval  time.Duration
+	hedgeDelay      time.Duration
+	auditSink       squealx.AuditSink
 }
 
 // OptionFunc is a function that configures a Options.
@@ -58,3 +67,58 @@ func WithFileLoader(fileLoader *squealx.FileLoader) OptionFunc {
 		opt.fileLoader = fileLoader
 	}
 }
+
+// WithTxSettings sets the settings applied to transactions begun through the
+// resolver whose context carries none.
+func WithTxSettings(settings squealx.TxSettings) OptionFunc {
+	return func(opt *Options) {
+		opt.txSettings = &settings
+	}
+}
+
+// WithTableCache caches the results of Select and Get reading table for ttl.
+// Cached entries are also dropped when a write to table runs through any
+// database of the resolver.
+func WithTableCache(table string, ttl time.Duration) OptionFunc {
+	return func(opt *Options) {
+		if opt.tableTTLs == nil {
+			opt.tableTTLs = make(map[string]time.Duration)
+		}
+		opt.tableTTLs[strings.ToLower(table)] = ttl
+	}
+}
+
+// WithCacheStore sets the store of the results cached with WithTableCache,
+// a squealx.MemoryCache of squealx.DefaultMemoryCacheEntries by default.
+func WithCacheStore(store squealx.CacheStore) OptionFunc {
+	return func(opt *Options) {
+		opt.cacheStore = store
+	}
+}
+
+// WithHealthCheck pings the databases of the resolver every interval. Load
+// balancers only choose databases whose last ping failed when no other is
+// healthy.
+func WithHealthCheck(interval time.Duration) OptionFunc {
+	return func(opt *Options) {
+		opt.healthInterval = interval
+	}
+}
+
+// WithHedging hedges reads: when the database chosen for a SELECT run by
+// Select or Get has not answered within delay, the query is also sent to
+// another readable database, and the first answer is used. Locking reads
+// are not hedged. HedgeStats reports how often reads are hedged.
+func WithHedging(delay time.Duration) OptionFunc {
+	return func(opt *Options) {
+		opt.hedgeDelay = delay
+	}
+}
+
+// WithAuditSink sets the sink receiving the audit events of the resolver,
+// such as the replicas found inconsistent by VerifyConsistency.
+func WithAuditSink(sink squealx.AuditSink) OptionFunc {
+	return func(opt *Options) {
+		opt.auditSink = sink
+	}
+}
@@ -66,7 +66,10 @@ func (s *stmt) Close() error {
 // Exec chooses a primary database's statement and executes using chosen statement.
 // Exec is a wrapper around sqlx.Stmt.Exec.
 func (s *stmt) Exec(args ...any) (sql.Result, error) {
-	db := s.db.GetDB(context.Background(), s.masters)
+	db, err := s.db.GetDBErr(context.Background(), s.masters)
+	if err != nil {
+		return nil, err
+	}
 	stmt, ok := s.masterStmts[db]
 	if !ok {
 		// Should not happen.
@@ -78,7 +81,10 @@ func (s *stmt) Exec(args ...any) (sql.Result, error) {
 // ExecContext chooses a primary database's statement and executes using chosen statement.
 // ExecContext is a wrapper around sqlx.Stmt.ExecContext.
 func (s *stmt) ExecContext(ctx context.Context, args ...any) (sql.Result, error) {
-	db := s.db.GetDB(ctx, s.masters)
+	db, err := s.db.GetDBErr(ctx, s.masters)
+	if err != nil {
+		return nil, err
+	}
 	stmt, ok := s.masterStmts[db]
 	if !ok {
 		// Should not happen.
@@ -90,22 +96,26 @@ func (s *stmt) ExecContext(ctx context.Context, args ...any) (sql.Result, error)
 // Get chooses a readable database's statement and Get using chosen statement.
 // Get is a wrapper around sqlx.Stmt.Get.
 func (s *stmt) Get(dest any, args ...any) error {
-	db := s.db.GetDB(context.Background(), s.readReplicas)
+	db, err := s.db.GetDBErr(context.Background(), s.readReplicas)
+	if err != nil {
+		return err
+	}
 	stmt, ok := s.replicaStmts[db]
 	if !ok {
 		// Should not happen.
 		return errors.Join(errSelectedStmtNotFound, fmt.Errorf("readable db: %v", db))
 	}
-	err := stmt.Get(dest, args...)
+	err = stmt.Get(dest, args...)
 
 	if isDBConnectionError(err) {
-		dbPrimary := s.db.GetDB(context.Background(), s.masters)
-		stmtPrimary, ok := s.replicaStmts[dbPrimary]
-		if !ok {
-			// Should not happen.
-			return errors.Join(errSelectedNamedStmtNotFound, fmt.Errorf("readable db: %v", db))
+		if dbPrimary, lookupErr := s.db.GetDBErr(context.Background(), s.masters); lookupErr == nil {
+			stmtPrimary, ok := s.replicaStmts[dbPrimary]
+			if !ok {
+				// Should not happen.
+				return errors.Join(errSelectedNamedStmtNotFound, fmt.Errorf("readable db: %v", db))
+			}
+			err = stmtPrimary.Get(dest, args...)
 		}
-		err = stmtPrimary.Get(dest, args...)
 	}
 	return err
 }
@@ -113,22 +123,26 @@ func (s *stmt) Get(dest any, args ...any) error {
 // GetContext chooses a readable database's statement and Get using chosen statement.
 // GetContext is a wrapper around sqlx.Stmt.GetContext.
 func (s *stmt) GetContext(ctx context.Context, dest any, args ...any) error {
-	db := s.db.GetDB(ctx, s.readReplicas)
+	db, err := s.db.GetDBErr(ctx, s.readReplicas)
+	if err != nil {
+		return err
+	}
 	stmt, ok := s.replicaStmts[db]
 	if !ok {
 		// Should not happen.
 		return errors.Join(errSelectedStmtNotFound, fmt.Errorf("readable db: %v", db))
 	}
-	err := stmt.GetContext(ctx, dest, args...)
+	err = stmt.GetContext(ctx, dest, args...)
 
 	if isDBConnectionError(err) {
-		dbPrimary := s.db.GetDB(ctx, s.masters)
-		stmtPrimary, ok := s.replicaStmts[dbPrimary]
-		if !ok {
-			// Should not happen.
-			return errors.Join(errSelectedNamedStmtNotFound, fmt.Errorf("readable db: %v", db))
+		if dbPrimary, lookupErr := s.db.GetDBErr(ctx, s.masters); lookupErr == nil {
+			stmtPrimary, ok := s.replicaStmts[dbPrimary]
+			if !ok {
+				// Should not happen.
+				return errors.Join(errSelectedNamedStmtNotFound, fmt.Errorf("readable db: %v", db))
+			}
+			err = stmtPrimary.GetContext(ctx, dest, args...)
 		}
-		err = stmtPrimary.GetContext(ctx, dest, args...)
 	}
 	return err
 }
@@ -136,7 +150,10 @@ func (s *stmt) GetContext(ctx context.Context, dest any, args ...any) error {
 // MustExec chooses a primary database's statement and executes using chosen statement or panic.
 // MustExec is a wrapper around sqlx.Stmt.MustExec.
 func (s *stmt) MustExec(args ...any) sql.Result {
-	db := s.db.GetDB(context.Background(), s.masters)
+	db, err := s.db.GetDBErr(context.Background(), s.masters)
+	if err != nil {
+		panic(err)
+	}
 	stmt, ok := s.masterStmts[db]
 	if !ok {
 		// Should not happen.
@@ -148,7 +165,10 @@ func (s *stmt) MustExec(args ...any) sql.Result {
 // MustExecContext chooses a primary database's statement and executes using chosen statement or panic.
 // MustExecContext is a wrapper around sqlx.Stmt.MustExecContext.
 func (s *stmt) MustExecContext(ctx context.Context, args ...any) sql.Result {
-	db := s.db.GetDB(ctx, s.masters)
+	db, err := s.db.GetDBErr(ctx, s.masters)
+	if err != nil {
+		panic(err)
+	}
 	stmt, ok := s.masterStmts[db]
 	if !ok {
 		// Should not happen.
@@ -160,7 +180,10 @@ func (s *stmt) MustExecContext(ctx context.Context, args ...any) sql.Result {
 // Query chooses a readable database's statement and executes using chosen statement.
 // Query is a wrapper around sqlx.Stmt.Query.
 func (s *stmt) Query(args ...any) (squealx.SQLRows, error) {
-	db := s.db.GetDB(context.Background(), s.readReplicas)
+	db, err := s.db.GetDBErr(context.Background(), s.readReplicas)
+	if err != nil {
+		return nil, err
+	}
 	stmt, ok := s.replicaStmts[db]
 	if !ok {
 		// Should not happen.
@@ -169,13 +192,14 @@ func (s *stmt) Query(args ...any) (squealx.SQLRows, error) {
 	rows, err := stmt.Query(args...)
 
 	if isDBConnectionError(err) {
-		dbPrimary := s.db.GetDB(context.Background(), s.masters)
-		stmtPrimary, ok := s.replicaStmts[dbPrimary]
-		if !ok {
-			// Should not happen.
-			return nil, errors.Join(errSelectedNamedStmtNotFound, fmt.Errorf("readable db: %v", db))
+		if dbPrimary, lookupErr := s.db.GetDBErr(context.Background(), s.masters); lookupErr == nil {
+			stmtPrimary, ok := s.replicaStmts[dbPrimary]
+			if !ok {
+				// Should not happen.
+				return nil, errors.Join(errSelectedNamedStmtNotFound, fmt.Errorf("readable db: %v", db))
+			}
+			rows, err = stmtPrimary.Query(args...)
 		}
-		rows, err = stmtPrimary.Query(args...)
 	}
 	return rows, err
 }
@@ -183,7 +207,10 @@ func (s *stmt) Query(args ...any) (squealx.SQLRows, error) {
 // QueryContext chooses a readable database's statement and executes using chosen statement.
 // QueryContext is a wrapper around sqlx.Stmt.QueryContext.
 func (s *stmt) QueryContext(ctx context.Context, args ...any) (squealx.SQLRows, error) {
-	db := s.db.GetDB(ctx, s.readReplicas)
+	db, err := s.db.GetDBErr(ctx, s.readReplicas)
+	if err != nil {
+		return nil, err
+	}
 	stmt, ok := s.replicaStmts[db]
 	if !ok {
 		// Should not happen.
@@ -192,13 +219,14 @@ func (s *stmt) QueryContext(ctx context.Context, args ...any) (squealx.SQLRows,
 	rows, err := stmt.QueryContext(ctx, args...)
 
 	if isDBConnectionError(err) {
-		dbPrimary := s.db.GetDB(ctx, s.masters)
-		stmtPrimary, ok := s.replicaStmts[dbPrimary]
-		if !ok {
-			// Should not happen.
-			return nil, errors.Join(errSelectedNamedStmtNotFound, fmt.Errorf("readable db: %v", db))
+		if dbPrimary, lookupErr := s.db.GetDBErr(ctx, s.masters); lookupErr == nil {
+			stmtPrimary, ok := s.replicaStmts[dbPrimary]
+			if !ok {
+				// Should not happen.
+				return nil, errors.Join(errSelectedNamedStmtNotFound, fmt.Errorf("readable db: %v", db))
+			}
+			rows, err = stmtPrimary.QueryContext(ctx, args...)
 		}
-		rows, err = stmtPrimary.QueryContext(ctx, args...)
 	}
 	return rows, err
 }
@@ -207,7 +235,10 @@ func (s *stmt) QueryContext(ctx context.Context, args ...any) (squealx.SQLRows,
 // If selected statement is not found, returns nil.
 // QueryRow is a wrapper around sqlx.Stmt.QueryRow.
 func (s *stmt) QueryRow(args ...any) squealx.SQLRow {
-	db := s.db.GetDB(context.Background(), s.readReplicas)
+	db, err := s.db.GetDBErr(context.Background(), s.readReplicas)
+	if err != nil {
+		return squealx.RowError(err)
+	}
 	stmt, ok := s.replicaStmts[db]
 	if !ok {
 		// Should not happen.
@@ -216,13 +247,14 @@ func (s *stmt) QueryRow(args ...any) squealx.SQLRow {
 	row := stmt.QueryRow(args...)
 
 	if isDBConnectionError(row.Err()) {
-		dbPrimary := s.db.GetDB(context.Background(), s.masters)
-		stmtPrimary, ok := s.replicaStmts[dbPrimary]
-		if !ok {
-			// Should not happen.
-			return nil
+		if dbPrimary, lookupErr := s.db.GetDBErr(context.Background(), s.masters); lookupErr == nil {
+			stmtPrimary, ok := s.replicaStmts[dbPrimary]
+			if !ok {
+				// Should not happen.
+				return nil
+			}
+			row = stmtPrimary.QueryRow(args...)
 		}
-		row = stmtPrimary.QueryRow(args...)
 	}
 	return row
 }
@@ -231,7 +263,10 @@ func (s *stmt) QueryRow(args ...any) squealx.SQLRow {
 // If selected statement is not found, returns nil.
 // QueryRowContext is a wrapper around sqlx.Stmt.QueryRowContext.
 func (s *stmt) QueryRowContext(ctx context.Context, args ...any) squealx.SQLRow {
-	db := s.db.GetDB(ctx, s.readReplicas)
+	db, err := s.db.GetDBErr(ctx, s.readReplicas)
+	if err != nil {
+		return squealx.RowError(err)
+	}
 	stmt, ok := s.replicaStmts[db]
 	if !ok {
 		// Should not happen.
@@ -240,13 +275,14 @@ func (s *stmt) QueryRowContext(ctx context.Context, args ...any) squealx.SQLRow
 	row := stmt.QueryRowContext(ctx, args...)
 
 	if isDBConnectionError(row.Err()) {
-		dbPrimary := s.db.GetDB(ctx, s.masters)
-		stmtPrimary, ok := s.replicaStmts[dbPrimary]
-		if !ok {
-			// Should not happen.
-			return nil
+		if dbPrimary, lookupErr := s.db.GetDBErr(ctx, s.masters); lookupErr == nil {
+			stmtPrimary, ok := s.replicaStmts[dbPrimary]
+			if !ok {
+				// Should not happen.
+				return nil
+			}
+			row = stmtPrimary.QueryRowContext(ctx, args...)
 		}
-		row = stmtPrimary.QueryRowContext(ctx, args...)
 	}
 	return row
 }
@@ -255,7 +291,10 @@ func (s *stmt) QueryRowContext(ctx context.Context, args ...any) squealx.SQLRow
 // If selected statement is not found, returns nil.
 // QueryRowx is a wrapper around sqlx.Stmt.QueryRowx.
 func (s *stmt) QueryRowx(args ...any) *squealx.Row {
-	db := s.db.GetDB(context.Background(), s.readReplicas)
+	db, err := s.db.GetDBErr(context.Background(), s.readReplicas)
+	if err != nil {
+		return squealx.RowError(err)
+	}
 	stmt, ok := s.replicaStmts[db]
 	if !ok {
 		// Should not happen.
@@ -264,13 +303,14 @@ func (s *stmt) QueryRowx(args ...any) *squealx.Row {
 	row := stmt.QueryRowx(args...)
 
 	if isDBConnectionError(row.Err()) {
-		dbPrimary := s.db.GetDB(context.Background(), s.masters)
-		stmtPrimary, ok := s.replicaStmts[dbPrimary]
-		if !ok {
-			// Should not happen.
-			return nil
+		if dbPrimary, lookupErr := s.db.GetDBErr(context.Background(), s.masters); lookupErr == nil {
+			stmtPrimary, ok := s.replicaStmts[dbPrimary]
+			if !ok {
+				// Should not happen.
+				return nil
+			}
+			row = stmtPrimary.QueryRowx(args...)
 		}
-		row = stmtPrimary.QueryRowx(args...)
 	}
 	return row
 }
@@ -279,7 +319,10 @@ func (s *stmt) QueryRowx(args ...any) *squealx.Row {
 // If selected statement is not found, returns nil.
 // QueryRowxContext is a wrapper around sqlx.Stmt.QueryRowxContext.
 func (s *stmt) QueryRowxContext(ctx context.Context, args ...any) *squealx.Row {
-	db := s.db.GetDB(ctx, s.readReplicas)
+	db, err := s.db.GetDBErr(ctx, s.readReplicas)
+	if err != nil {
+		return squealx.RowError(err)
+	}
 	stmt, ok := s.replicaStmts[db]
 	if !ok {
 		// Should not happen.
@@ -288,13 +331,14 @@ func (s *stmt) QueryRowxContext(ctx context.Context, args ...any) *squealx.Row {
 	row := stmt.QueryRowxContext(ctx, args...)
 
 	if isDBConnectionError(row.Err()) {
-		dbPrimary := s.db.GetDB(ctx, s.masters)
-		stmtPrimary, ok := s.replicaStmts[dbPrimary]
-		if !ok {
-			// Should not happen.
-			return nil
+		if dbPrimary, lookupErr := s.db.GetDBErr(ctx, s.masters); lookupErr == nil {
+			stmtPrimary, ok := s.replicaStmts[dbPrimary]
+			if !ok {
+				// Should not happen.
+				return nil
+			}
+			row = stmtPrimary.QueryRowxContext(ctx, args...)
 		}
-		row = stmtPrimary.QueryRowxContext(ctx, args...)
 	}
 	return row
 }
@@ -302,7 +346,10 @@ func (s *stmt) QueryRowxContext(ctx context.Context, args ...any) *squealx.Row {
 // Queryx chooses a readable database's statement, executes using chosen statement and returns *squealx.Rows.
 // Queryx is a wrapper around sqlx.Stmt.Queryx.
 func (s *stmt) Queryx(args ...any) (*squealx.Rows, error) {
-	db := s.db.GetDB(context.Background(), s.readReplicas)
+	db, err := s.db.GetDBErr(context.Background(), s.readReplicas)
+	if err != nil {
+		return nil, err
+	}
 	stmt, ok := s.replicaStmts[db]
 	if !ok {
 		// Should not happen.
@@ -311,13 +358,14 @@ func (s *stmt) Queryx(args ...any) (*squealx.Rows, error) {
 	rows, err := stmt.Queryx(args...)
 
 	if isDBConnectionError(err) {
-		dbPrimary := s.db.GetDB(context.Background(), s.masters)
-		stmtPrimary, ok := s.replicaStmts[dbPrimary]
-		if !ok {
-			// Should not happen.
-			return nil, errors.Join(errSelectedNamedStmtNotFound, fmt.Errorf("readable db: %v", db))
+		if dbPrimary, lookupErr := s.db.GetDBErr(context.Background(), s.masters); lookupErr == nil {
+			stmtPrimary, ok := s.replicaStmts[dbPrimary]
+			if !ok {
+				// Should not happen.
+				return nil, errors.Join(errSelectedNamedStmtNotFound, fmt.Errorf("readable db: %v", db))
+			}
+			rows, err = stmtPrimary.Queryx(args...)
 		}
-		rows, err = stmtPrimary.Queryx(args...)
 	}
 	return rows, err
 }
@@ -325,7 +373,10 @@ func (s *stmt) Queryx(args ...any) (*squealx.Rows, error) {
 // QueryxContext chooses a readable database's statement, executes using chosen statement and returns *squealx.Rows.
 // QueryxContext is a wrapper around sqlx.Stmt.QueryxContext.
 func (s *stmt) QueryxContext(ctx context.Context, args ...any) (*squealx.Rows, error) {
-	db := s.db.GetDB(ctx, s.readReplicas)
+	db, err := s.db.GetDBErr(ctx, s.readReplicas)
+	if err != nil {
+		return nil, err
+	}
 	stmt, ok := s.replicaStmts[db]
 	if !ok {
 		// Should not happen.
@@ -334,13 +385,14 @@ func (s *stmt) QueryxContext(ctx context.Context, args ...any) (*squealx.Rows, e
 	rows, err := stmt.QueryxContext(ctx, args...)
 
 	if isDBConnectionError(err) {
-		dbPrimary := s.db.GetDB(ctx, s.masters)
-		stmtPrimary, ok := s.replicaStmts[dbPrimary]
-		if !ok {
-			// Should not happen.
-			return nil, errors.Join(errSelectedNamedStmtNotFound, fmt.Errorf("readable db: %v", db))
+		if dbPrimary, lookupErr := s.db.GetDBErr(ctx, s.masters); lookupErr == nil {
+			stmtPrimary, ok := s.replicaStmts[dbPrimary]
+			if !ok {
+				// Should not happen.
+				return nil, errors.Join(errSelectedNamedStmtNotFound, fmt.Errorf("readable db: %v", db))
+			}
+			rows, err = stmtPrimary.QueryxContext(ctx, args...)
 		}
-		rows, err = stmtPrimary.QueryxContext(ctx, args...)
 	}
 	return rows, err
 }
@@ -348,22 +400,26 @@ func (s *stmt) QueryxContext(ctx context.Context, args ...any) (*squealx.Rows, e
 // Select chooses a readable database's statement, executes using chosen statement.
 // Select is a wrapper around sqlx.Stmt.Select.
 func (s *stmt) Select(dest any, args ...any) error {
-	db := s.db.GetDB(context.Background(), s.readReplicas)
+	db, err := s.db.GetDBErr(context.Background(), s.readReplicas)
+	if err != nil {
+		return err
+	}
 	stmt, ok := s.replicaStmts[db]
 	if !ok {
 		// Should not happen.
 		return errors.Join(errSelectedStmtNotFound, fmt.Errorf("readable db: %v", db))
 	}
-	err := stmt.Select(dest, args...)
+	err = stmt.Select(dest, args...)
 
 	if isDBConnectionError(err) {
-		dbPrimary := s.db.GetDB(context.Background(), s.masters)
-		stmtPrimary, ok := s.replicaStmts[dbPrimary]
-		if !ok {
-			// Should not happen.
-			return errors.Join(errSelectedNamedStmtNotFound, fmt.Errorf("readable db: %v", db))
+		if dbPrimary, lookupErr := s.db.GetDBErr(context.Background(), s.masters); lookupErr == nil {
+			stmtPrimary, ok := s.replicaStmts[dbPrimary]
+			if !ok {
+				// Should not happen.
+				return errors.Join(errSelectedNamedStmtNotFound, fmt.Errorf("readable db: %v", db))
+			}
+			err = stmtPrimary.Select(dest, args...)
 		}
-		err = stmtPrimary.Select(dest, args...)
 	}
 	return err
 }
@@ -371,22 +427,26 @@ func (s *stmt) Select(dest any, args ...any) error {
 // SelectContext chooses a readable database's statement, executes using chosen statement.
 // SelectContext is a wrapper around sqlx.Stmt.SelectContext.
 func (s *stmt) SelectContext(ctx context.Context, dest any, args ...any) error {
-	db := s.db.GetDB(ctx, s.readReplicas)
+	db, err := s.db.GetDBErr(ctx, s.readReplicas)
+	if err != nil {
+		return err
+	}
 	stmt, ok := s.replicaStmts[db]
 	if !ok {
 		// Should not happen.
 		return errors.Join(errSelectedStmtNotFound, fmt.Errorf("readable db: %v", db))
 	}
-	err := stmt.SelectContext(ctx, dest, args...)
+	err = stmt.SelectContext(ctx, dest, args...)
 
 	if isDBConnectionError(err) {
-		dbPrimary := s.db.GetDB(ctx, s.masters)
-		stmtPrimary, ok := s.replicaStmts[dbPrimary]
-		if !ok {
-			// Should not happen.
-			return errors.Join(errSelectedNamedStmtNotFound, fmt.Errorf("readable db: %v", db))
+		if dbPrimary, lookupErr := s.db.GetDBErr(ctx, s.masters); lookupErr == nil {
+			stmtPrimary, ok := s.replicaStmts[dbPrimary]
+			if !ok {
+				// Should not happen.
+				return errors.Join(errSelectedNamedStmtNotFound, fmt.Errorf("readable db: %v", db))
+			}
+			err = stmtPrimary.SelectContext(ctx, dest, args...)
 		}
-		err = stmtPrimary.SelectContext(ctx, dest, args...)
 	}
 	return err
 }
@@ -395,7 +455,10 @@ func (s *stmt) SelectContext(ctx context.Context, dest any, args ...any) error {
 // If selected statement is not found, returns nil.
 // Unsafe wraps sqlx.Stmt.Unsafe.
 func (s *stmt) Unsafe() *squealx.Stmt {
-	db := s.db.GetDB(context.Background(), s.masters)
+	db, err := s.db.GetDBErr(context.Background(), s.masters)
+	if err != nil {
+		return nil
+	}
 	stmt, ok := s.masterStmts[db]
 	if !ok {
 		// Should not happen.
@@ -0,0 +1,96 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/oarkflow/squealx"
+)
+
+// Tx is a transaction begun through the resolver, pinned to the primary
+// database it was opened on. Besides the methods of squealx.Tx, it binds
+// the statements prepared through the resolver to that database.
+type Tx struct {
+	*squealx.Tx
+	db *squealx.DB
+}
+
+// BeginPinned chooses a primary database and begins a transaction on it,
+// returning a Tx which runs the resolver's prepared statements.
+func (r *dbResolver) BeginPinned(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	db, err := r.GetDBErr(ctx, r.masterIDs())
+	if err != nil {
+		return nil, err
+	}
+	tx, err := db.BeginTxx(r.txContext(ctx), opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx, db: db}, nil
+}
+
+// DB returns the database the transaction runs on.
+func (tx *Tx) DB() *squealx.DB {
+	return tx.db
+}
+
+// Stmtx returns a version of the prepared statement which runs within the
+// transaction. Besides the statements accepted by squealx.Tx.Stmtx, stmt
+// can be a Stmt prepared through the resolver.
+func (tx *Tx) Stmtx(stmt any) *squealx.Stmt {
+	return tx.Tx.Stmtx(tx.nodeStmt(stmt))
+}
+
+// StmtxContext is like Stmtx, with a context.
+func (tx *Tx) StmtxContext(ctx context.Context, stmt any) *squealx.Stmt {
+	return tx.Tx.StmtxContext(ctx, tx.nodeStmt(stmt))
+}
+
+// NamedStmt returns a version of the named statement which runs within the
+// transaction. stmt can be a *squealx.NamedStmt or a NamedStmt prepared
+// through the resolver.
+func (tx *Tx) NamedStmt(stmt any) *squealx.NamedStmt {
+	return tx.Tx.NamedStmt(tx.nodeNamedStmt(stmt))
+}
+
+// NamedStmtContext is like NamedStmt, with a context.
+func (tx *Tx) NamedStmtContext(ctx context.Context, stmt any) *squealx.NamedStmt {
+	return tx.Tx.NamedStmtContext(ctx, tx.nodeNamedStmt(stmt))
+}
+
+// nodeStmt returns the statement of stmt prepared on the database of tx.
+func (tx *Tx) nodeStmt(v any) any {
+	s, ok := v.(*stmt)
+	if !ok {
+		return v
+	}
+	if prepared, ok := s.masterStmts[tx.db]; ok {
+		return prepared
+	}
+	if prepared, ok := s.replicaStmts[tx.db]; ok {
+		return prepared
+	}
+	// Should not happen.
+	panic(errors.Join(errSelectedStmtNotFound, fmt.Errorf("primary db: %v", tx.db)))
+}
+
+// nodeNamedStmt returns the named statement of stmt prepared on the database
+// of tx.
+func (tx *Tx) nodeNamedStmt(stmt any) *squealx.NamedStmt {
+	switch s := stmt.(type) {
+	case *squealx.NamedStmt:
+		return s
+	case *namedStmt:
+		if prepared, ok := s.masterStmts[tx.db]; ok {
+			return prepared
+		}
+		if prepared, ok := s.replicaStmts[tx.db]; ok {
+			return prepared
+		}
+		// Should not happen.
+		panic(errors.Join(errSelectedNamedStmtNotFound, fmt.Errorf("primary db: %v", tx.db)))
+	}
+	panic(fmt.Sprintf("non-statement type %T passed to NamedStmt", stmt))
+}
@@ -0,0 +1,108 @@
+// Package ddl runs schema changes through a squealx.DB, so that they share
+// the application's connection pool and hooks: building indexes without
+// blocking writes, retrying the statements which time out waiting for
+// their locks.
+package ddl
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/oarkflow/squealx"
+)
+
+// RetryPolicy controls how statements failing to get their locks are
+// retried. Zero fields take the defaults: 3 attempts, 1s initial backoff
+// doubling up to 30s, and lock timeouts and deadlocks as retryable errors.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Retryable      func(err error) bool
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = 3
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = time.Second
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = 30 * time.Second
+	}
+	if p.Retryable == nil {
+		p.Retryable = isLockError
+	}
+	return p
+}
+
+func isLockError(err error) bool {
+	switch squealx.ClassifyError(err) {
+	case squealx.ErrorLockTimeout, squealx.ErrorDeadlock:
+		return true
+	}
+	return false
+}
+
+// retry runs fn until it succeeds, fails with an error policy does not
+// retry, or runs out of attempts, and returns the number of attempts made.
+func (p RetryPolicy) retry(ctx context.Context, fn func() error) (int, error) {
+	backoff := p.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.MaxAttempts || !p.Retryable(err) {
+			return attempt, err
+		}
+		timer := time.NewTimer(backoff/2 + rand.N(backoff/2+1))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return attempt, err
+		case <-timer.C:
+		}
+		backoff = min(2*backoff, p.MaxBackoff)
+	}
+}
+
+// unsupported returns the error reported for operations the database of db
+// does not support.
+func unsupported(operation string, db *squealx.DB) error {
+	return fmt.Errorf("ddl: %s is not supported on %s", operation, db.DriverName())
+}
+
+// namePattern matches a possibly quoted identifier of any dialect.
+const namePattern = `(?:"[^"]+"|` + "`[^`]+`" + `|\[[^\]]+\]|[\w$]+)`
+
+var qualifiedNameRE = regexp.MustCompile(`^(` + namePattern + `)(?:\s*\.\s*(` + namePattern + `))?$`)
+
+// splitName splits a possibly schema qualified name into its schema and
+// name, unquoted. Unquoted names are lower cased on PostgreSQL, which
+// folds them so.
+func splitName(dialect, name string) (schema, object string) {
+	m := qualifiedNameRE.FindStringSubmatch(strings.TrimSpace(name))
+	if m == nil {
+		return "", name
+	}
+	if m[2] == "" {
+		return "", unquote(dialect, m[1])
+	}
+	return unquote(dialect, m[1]), unquote(dialect, m[2])
+}
+
+func unquote(dialect, name string) string {
+	if len(name) >= 2 {
+		switch name[0] {
+		case '"', '`', '[':
+			return name[1 : len(name)-1]
+		}
+	}
+	if dialect == squealx.DialectPostgres {
+		return strings.ToLower(name)
+	}
+	return name
+}
@@ -0,0 +1,249 @@
+package ddl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/oarkflow/squealx"
+)
+
+// ErrBlocked is matched by the errors of statements a Guard did not run
+// because of long-running transactions, see BlockedError.
+var ErrBlocked = errors.New("ddl: blocked by long-running transactions")
+
+// Blocker is a transaction open long enough to block a schema change.
+type Blocker struct {
+	PID        int64   `db:"pid"`
+	User       string  `db:"usename"`
+	State      string  `db:"state"`
+	AgeSeconds float64 `db:"age_seconds"`
+	Query      string  `db:"query"`
+}
+
+// Age returns how long the transaction has been open.
+func (b Blocker) Age() time.Duration {
+	return time.Duration(b.AgeSeconds * float64(time.Second))
+}
+
+// BlockedError is returned by a Guard for a statement it did not run
+// because of the transactions of Blockers.
+type BlockedError struct {
+	Statement string
+	MaxAge    time.Duration
+	Blockers  []Blocker
+}
+
+func (e *BlockedError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "ddl: %q blocked by %d transactions open longer than %s", e.Statement, len(e.Blockers), e.MaxAge)
+	for _, blocker := range e.Blockers {
+		fmt.Fprintf(&b, "; pid %d (%s, %s, open %s): %s", blocker.PID, blocker.User, blocker.State,
+			blocker.Age().Round(time.Second), blocker.Query)
+	}
+	return b.String()
+}
+
+func (e *BlockedError) Is(target error) bool {
+	return target == ErrBlocked
+}
+
+// Guard runs schema changes so that they cannot take a busy database down.
+// An ALTER TABLE waiting for a lock held by a long transaction makes every
+// query on its table queue behind it; a Guard bounds that wait with a lock
+// timeout, and does not start while transactions which would block the
+// statement are open.
+type Guard struct {
+	// LockTimeout bounds how long statements wait for their locks, 5s by
+	// default. It maps to lock_timeout on PostgreSQL, lock_wait_timeout on
+	// MySQL and LOCK_TIMEOUT on SQL Server.
+	LockTimeout time.Duration
+	// MaxTransactionAge is the age from which open transactions are taken
+	// to block statements, 30s by default. On PostgreSQL, only those holding
+	// locks on the altered table count, except for concurrent index builds,
+	// which wait for all of them. SQLite and SQL Server are not checked.
+	MaxTransactionAge time.Duration
+	// Wait makes statements blocked by transactions wait for them, checking
+	// again with the backoff of Retry, instead of failing with a
+	// BlockedError.
+	Wait bool
+	// Retry is how statements failing to get their locks, and with Wait
+	// blocked ones, are retried.
+	Retry RetryPolicy
+}
+
+func (g Guard) withDefaults() Guard {
+	if g.LockTimeout <= 0 {
+		g.LockTimeout = 5 * time.Second
+	}
+	if g.MaxTransactionAge <= 0 {
+		g.MaxTransactionAge = 30 * time.Second
+	}
+	g.Retry = g.retryPolicy(g.Retry.withDefaults())
+	return g
+}
+
+// retryPolicy returns p also retrying blocked statements when g waits.
+func (g Guard) retryPolicy(p RetryPolicy) RetryPolicy {
+	retryable := p.Retryable
+	p.Retryable = func(err error) bool {
+		if errors.Is(err, ErrBlocked) {
+			return g.Wait
+		}
+		return retryable(err)
+	}
+	return p
+}
+
+// Exec runs statement on db once no transaction blocks it, waiting at most
+// LockTimeout for its locks.
+//
+//	guard := ddl.Guard{LockTimeout: 3 * time.Second, Wait: true}
+//	err := guard.Exec(ctx, db, "ALTER TABLE users ADD COLUMN nickname text")
+func (g Guard) Exec(ctx context.Context, db *squealx.DB, statement string) error {
+	g = g.withDefaults()
+	_, err := g.Retry.retry(ctx, func() error {
+		return g.run(ctx, db, statement)
+	})
+	return err
+}
+
+// run checks statement is not blocked and runs it with the lock timeout of
+// g, once.
+func (g Guard) run(ctx context.Context, db *squealx.DB, statement string) error {
+	if err := g.check(ctx, db, statement); err != nil {
+		return err
+	}
+	set, reset := lockTimeout(squealx.Dialect(db.DriverName()), g.LockTimeout)
+	if set == "" {
+		_, err := db.ExecContext(ctx, statement)
+		return err
+	}
+	// The lock timeout is a setting of the session, so the statement runs
+	// on the connection it is set on, which is reset before being released.
+	conn, err := db.Connx(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, set); err != nil {
+		return err
+	}
+	_, err = conn.ExecContext(ctx, statement)
+	if _, resetErr := conn.ExecContext(context.WithoutCancel(ctx), reset); resetErr != nil {
+		return errors.Join(err, resetErr)
+	}
+	return err
+}
+
+// lockTimeout returns the statements setting and resetting the lock
+// timeout of a session of dialect.
+func lockTimeout(dialect string, timeout time.Duration) (set, reset string) {
+	switch dialect {
+	case squealx.DialectPostgres:
+		return fmt.Sprintf("SET lock_timeout = %d", timeout.Milliseconds()), "RESET lock_timeout"
+	case squealx.DialectMySQL:
+		return fmt.Sprintf("SET SESSION lock_wait_timeout = %d", max(1, int64(timeout.Round(time.Second)/time.Second))),
+			"SET SESSION lock_wait_timeout = DEFAULT"
+	case squealx.DialectMSSQL:
+		return fmt.Sprintf("SET LOCK_TIMEOUT %d", timeout.Milliseconds()), "SET LOCK_TIMEOUT -1"
+	}
+	return "", ""
+}
+
+const (
+	postgresBlockers = `SELECT a.pid, coalesce(a.usename, '') AS usename, coalesce(a.state, '') AS state,
+	extract(epoch FROM now() - a.xact_start)::float8 AS age_seconds, a.query
+	FROM pg_stat_activity a
+	WHERE a.datname = current_database() AND a.pid <> pg_backend_pid()
+	AND a.xact_start < now() - make_interval(secs => ?)
+	AND (? = '' OR EXISTS (SELECT 1 FROM pg_locks l WHERE l.pid = a.pid AND l.relation = to_regclass(?)))
+	ORDER BY a.xact_start`
+	mysqlBlockers = `SELECT t.trx_mysql_thread_id AS pid, coalesce(p.user, '') AS usename, t.trx_state AS state,
+	TIMESTAMPDIFF(MICROSECOND, t.trx_started, NOW(6)) / 1000000 AS age_seconds,
+	coalesce(t.trx_query, '') AS query
+	FROM information_schema.innodb_trx t
+	LEFT JOIN information_schema.processlist p ON p.id = t.trx_mysql_thread_id
+	WHERE t.trx_mysql_thread_id <> CONNECTION_ID() AND t.trx_started < NOW(6) - INTERVAL ? SECOND
+	ORDER BY t.trx_started`
+)
+
+// check fails with a BlockedError when transactions older than
+// MaxTransactionAge would block statement.
+func (g Guard) check(ctx context.Context, db *squealx.DB, statement string) error {
+	var query string
+	var args []any
+	age := g.MaxTransactionAge.Seconds()
+	switch squealx.Dialect(db.DriverName()) {
+	case squealx.DialectPostgres:
+		table := ddlTable(statement)
+		query, args = postgresBlockers, []any{age, table, table}
+	case squealx.DialectMySQL:
+		query, args = mysqlBlockers, []any{age}
+	default:
+		return nil
+	}
+	var blockers []Blocker
+	if err := db.SelectContext(ctx, &blockers, db.Rebind(query), args...); err != nil {
+		return err
+	}
+	if len(blockers) > 0 {
+		return &BlockedError{Statement: statement, MaxAge: g.MaxTransactionAge, Blockers: blockers}
+	}
+	return nil
+}
+
+var (
+	ddlRE = regexp.MustCompile(`(?is)^\s*(?:ALTER\s+TABLE|CREATE\s+(?:UNIQUE\s+)?INDEX|DROP\s+(?:TABLE|INDEX)|TRUNCATE|RENAME\s+TABLE|REINDEX|CLUSTER|VACUUM\s+FULL)\b`)
+	// alterTableRE and indexTableRE match the table altered or indexed by a
+	// statement, the latter unless the index is built concurrently.
+	alterTableRE = regexp.MustCompile(`(?is)^\s*ALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?(?:ONLY\s+)?(` + namePattern + `(?:\s*\.\s*` + namePattern + `)?)`)
+	indexTableRE = regexp.MustCompile(`(?is)^\s*CREATE\s+(?:UNIQUE\s+)?INDEX\s+(?:IF\s+NOT\s+EXISTS\s+)?` + namePattern + `\s+ON\s+(?:ONLY\s+)?(` + namePattern + `(?:\s*\.\s*` + namePattern + `)?)`)
+)
+
+// isDDL reports whether statement is a schema change taking locks which
+// block the queries on its table.
+func isDDL(statement string) bool {
+	return ddlRE.MatchString(statement)
+}
+
+// ddlTable returns the table statement locks, empty when the statement
+// waits for all transactions or its table is unknown.
+func ddlTable(statement string) string {
+	if m := alterTableRE.FindStringSubmatch(statement); m != nil {
+		return m[1]
+	}
+	if m := indexTableRE.FindStringSubmatch(statement); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// Hook returns a squealx before hook failing, or with Wait delaying, the
+// schema changes run on db while transactions which would block them are
+// open, such as those of migration tools. Unlike Exec, it cannot set the
+// lock timeout of the connection the statement then runs on; set it with
+// squealx.WithConnInit on the DB running migrations.
+//
+//	db.Use(ddl.Guard{Wait: true}.Hook(db))
+func (g Guard) Hook(db *squealx.DB) squealx.BeforeHook {
+	return guardHook{guard: g.withDefaults(), db: db}
+}
+
+type guardHook struct {
+	guard Guard
+	db    *squealx.DB
+}
+
+func (h guardHook) Before(ctx context.Context, query string, _ ...any) (context.Context, error) {
+	if !isDDL(query) {
+		return ctx, nil
+	}
+	_, err := h.guard.Retry.retry(ctx, func() error {
+		return h.guard.check(ctx, h.db, query)
+	})
+	return ctx, err
+}
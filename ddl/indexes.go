@@ -0,0 +1,367 @@
+package ddl
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oarkflow/squealx"
+	"github.com/oarkflow/squealx/sqltoken"
+)
+
+// IndexSpec is an index to create, as parsed by ParseIndex.
+type IndexSpec struct {
+	Name  string
+	Table string
+	// Columns are the key columns or expressions of the index, as written.
+	Columns []string
+	Unique  bool
+	// Method is the index method of USING before the columns, such as GIN.
+	Method string
+	// Suffix is what follows the columns, such as INCLUDE and WHERE
+	// clauses, as written.
+	Suffix string
+}
+
+var createIndexRE = regexp.MustCompile(`(?is)^\s*CREATE\s+(UNIQUE\s+)?INDEX\s+(?:CONCURRENTLY\s+)?(?:IF\s+NOT\s+EXISTS\s+)?(` +
+	namePattern + `)\s+ON\s+(?:ONLY\s+)?(` + namePattern + `(?:\s*\.\s*` + namePattern + `)?)\s*(?:USING\s+(\w+)\s*)?\(`)
+
+// ParseIndex parses a CREATE INDEX statement. CONCURRENTLY and IF NOT
+// EXISTS are dropped, CreateIndexes deciding on them.
+func ParseIndex(statement string) (IndexSpec, error) {
+	statement = strings.TrimRight(strings.TrimSpace(statement), ";")
+	m := createIndexRE.FindStringSubmatchIndex(statement)
+	if m == nil {
+		return IndexSpec{}, fmt.Errorf("ddl: not a CREATE INDEX statement: %q", statement)
+	}
+	spec := IndexSpec{
+		Unique: m[2] >= 0,
+		Name:   statement[m[4]:m[5]],
+		Table:  statement[m[6]:m[7]],
+	}
+	if m[8] >= 0 {
+		spec.Method = statement[m[8]:m[9]]
+	}
+	columns, rest, ok := splitColumns(statement[m[1]:])
+	if !ok || len(columns) == 0 {
+		return IndexSpec{}, fmt.Errorf("ddl: unbalanced column list in %q", statement)
+	}
+	spec.Columns = columns
+	spec.Suffix = strings.TrimSpace(rest)
+	return spec, nil
+}
+
+// ParseIndexes parses the CREATE INDEX statements of script, separated by
+// semicolons, such as a file of index definitions.
+func ParseIndexes(script string) ([]IndexSpec, error) {
+	var specs []IndexSpec
+	for _, statement := range sqltoken.TokenizePostgreSQL(script).CmdSplit().Strings() {
+		spec, err := ParseIndex(statement)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// splitColumns splits s, following the opening parenthesis of a column
+// list, into the comma separated columns of the list and what follows it.
+func splitColumns(s string) (columns []string, rest string, ok bool) {
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')' && depth > 0:
+			depth--
+		case c == ')':
+			columns = append(columns, strings.TrimSpace(s[start:i]))
+			return columns, s[i+1:], true
+		case c == ',' && depth == 0:
+			columns = append(columns, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	return nil, "", false
+}
+
+// SQL returns the CREATE INDEX statement of s, building the index without
+// blocking writes when concurrently is set, which only PostgreSQL
+// supports.
+func (s IndexSpec) SQL(concurrently bool) string {
+	var b strings.Builder
+	b.WriteString("CREATE ")
+	if s.Unique {
+		b.WriteString("UNIQUE ")
+	}
+	b.WriteString("INDEX ")
+	if concurrently {
+		b.WriteString("CONCURRENTLY ")
+	}
+	b.WriteString(s.Name + " ON " + s.Table + " ")
+	if s.Method != "" {
+		b.WriteString("USING " + s.Method + " ")
+	}
+	b.WriteString("(" + strings.Join(s.Columns, ", ") + ")")
+	if s.Suffix != "" {
+		b.WriteString(" " + s.Suffix)
+	}
+	return b.String()
+}
+
+// Options configures CreateIndexes.
+type Options struct {
+	// Concurrently builds the indexes with CREATE INDEX CONCURRENTLY on
+	// PostgreSQL, which does not block writes to their tables. Other
+	// databases ignore it; InnoDB builds secondary indexes online anyway.
+	Concurrently bool
+	// SkipExisting skips the indexes which already exist. On PostgreSQL, an
+	// invalid index left by a failed concurrent build is dropped and built
+	// again.
+	SkipExisting bool
+	// MaxParallel is the number of tables whose indexes are built at once,
+	// 1 by default. The indexes of a table are always built one at a time.
+	MaxParallel int
+	// Retry is how the statements failing to get their locks are retried.
+	Retry RetryPolicy
+	// Guard, if set, builds the indexes with its lock timeout once no
+	// transaction blocks them, see Guard. Its own Retry is not used.
+	Guard *Guard
+}
+
+// IndexStatus is what CreateIndexes did with an index.
+type IndexStatus string
+
+const (
+	IndexCreated IndexStatus = "created"
+	IndexSkipped IndexStatus = "skipped"
+	IndexFailed  IndexStatus = "failed"
+)
+
+// IndexResult reports the creation of an index.
+type IndexResult struct {
+	Spec   IndexSpec
+	Status IndexStatus
+	// Replaced is set for invalid indexes dropped before being built again.
+	Replaced bool
+	Attempts int
+	Duration time.Duration
+	Err      error
+}
+
+// Report is returned by CreateIndexes, with the results in the order of
+// the specs.
+type Report struct {
+	Results []IndexResult
+}
+
+// Count returns the number of indexes of status.
+func (r Report) Count(status IndexStatus) int {
+	n := 0
+	for _, result := range r.Results {
+		if result.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// Failed returns the results of the indexes which could not be created.
+func (r Report) Failed() []IndexResult {
+	var failed []IndexResult
+	for _, result := range r.Results {
+		if result.Status == IndexFailed {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// CreateIndexes creates the indexes of specs as opts say, carrying on with
+// the others when one fails. The returned error joins those of the failed
+// indexes, which the report details.
+//
+//	specs, err := ddl.ParseIndexes(script)
+//	...
+//	report, err := ddl.CreateIndexes(ctx, db, specs, ddl.Options{
+//		Concurrently: true,
+//		SkipExisting: true,
+//		MaxParallel:  4,
+//	})
+func CreateIndexes(ctx context.Context, db *squealx.DB, specs []IndexSpec, opts Options) (Report, error) {
+	dialect := squealx.Dialect(db.DriverName())
+	if dialect == squealx.DialectUnknown {
+		return Report{}, unsupported("index creation", db)
+	}
+	opts.Retry = opts.Retry.withDefaults()
+	if opts.Guard != nil {
+		guard := opts.Guard.withDefaults()
+		opts.Guard = &guard
+		opts.Retry = guard.retryPolicy(opts.Retry)
+	}
+	report := Report{Results: make([]IndexResult, len(specs))}
+
+	// The indexes of a table are built in turn: concurrent builds on one
+	// table wait for each other.
+	var tables [][]int
+	byTable := make(map[string]int)
+	for i, spec := range specs {
+		schema, table := splitName(dialect, spec.Table)
+		key := schema + "." + table
+		n, ok := byTable[key]
+		if !ok {
+			n = len(tables)
+			byTable[key] = n
+			tables = append(tables, nil)
+		}
+		tables[n] = append(tables[n], i)
+	}
+	work := make(chan []int)
+	var wg sync.WaitGroup
+	for range max(1, min(opts.MaxParallel, len(tables))) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for indexes := range work {
+				for _, i := range indexes {
+					report.Results[i] = createIndex(ctx, db, dialect, specs[i], opts)
+				}
+			}
+		}()
+	}
+	for _, indexes := range tables {
+		work <- indexes
+	}
+	close(work)
+	wg.Wait()
+
+	var errs []error
+	for _, result := range report.Results {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("ddl: index %s: %w", result.Spec.Name, result.Err))
+		}
+	}
+	return report, errors.Join(errs...)
+}
+
+func createIndex(ctx context.Context, db *squealx.DB, dialect string, spec IndexSpec, opts Options) IndexResult {
+	result := IndexResult{Spec: spec, Status: IndexFailed}
+	start := time.Now()
+	defer func() { result.Duration = time.Since(start) }()
+	if err := ctx.Err(); err != nil {
+		result.Err = err
+		return result
+	}
+	if opts.SkipExisting {
+		exists, valid, err := indexExists(ctx, db, dialect, spec)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		if exists && valid {
+			result.Status = IndexSkipped
+			return result
+		}
+		if exists {
+			if result.Err = dropIndex(ctx, db, spec, opts); result.Err != nil {
+				return result
+			}
+			result.Replaced = true
+		}
+	}
+	concurrently := opts.Concurrently && dialect == squealx.DialectPostgres
+	statement := spec.SQL(concurrently)
+	result.Attempts, result.Err = opts.Retry.retry(ctx, func() error {
+		// A concurrent build failing, such as on its lock timeout, leaves
+		// an invalid index behind.
+		if concurrently && result.Attempts > 0 {
+			if exists, valid, err := indexExists(ctx, db, dialect, spec); err != nil {
+				return err
+			} else if exists && !valid {
+				if err := dropIndex(ctx, db, spec, opts); err != nil {
+					return err
+				}
+			}
+		}
+		result.Attempts++
+		if opts.Guard != nil {
+			return opts.Guard.run(ctx, db, statement)
+		}
+		_, err := db.ExecContext(ctx, statement)
+		return err
+	})
+	if result.Err == nil {
+		result.Status = IndexCreated
+	}
+	return result
+}
+
+// dropIndex drops the index of spec, concurrently when opts build indexes
+// so.
+func dropIndex(ctx context.Context, db *squealx.DB, spec IndexSpec, opts Options) error {
+	drop := "DROP INDEX IF EXISTS " + qualifiedIndex(spec)
+	if opts.Concurrently {
+		drop = "DROP INDEX CONCURRENTLY IF EXISTS " + qualifiedIndex(spec)
+	}
+	_, err := db.ExecContext(ctx, drop)
+	return err
+}
+
+// qualifiedIndex returns the name of the index of spec qualified with the
+// schema of its table, where PostgreSQL creates it.
+func qualifiedIndex(spec IndexSpec) string {
+	m := qualifiedNameRE.FindStringSubmatch(strings.TrimSpace(spec.Table))
+	if m == nil || m[2] == "" {
+		return spec.Name
+	}
+	return m[1] + "." + spec.Name
+}
+
+const (
+	postgresIndexExists = `SELECT i.indisvalid FROM pg_index i
+	JOIN pg_class c ON c.oid = i.indexrelid
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+	WHERE c.relname = ? AND n.nspname = coalesce(nullif(?, ''), current_schema())`
+	mysqlIndexExists = `SELECT true FROM information_schema.statistics
+	WHERE index_name = ? AND table_schema = coalesce(nullif(?, ''), DATABASE()) AND table_name = ? LIMIT 1`
+	sqliteIndexExists = `SELECT true FROM sqlite_master WHERE type = 'index' AND name = ?`
+	mssqlIndexExists  = `SELECT CAST(1 AS BIT) FROM sys.indexes WHERE name = ? AND object_id = OBJECT_ID(?)`
+)
+
+// indexExists reports whether the index of spec exists, and whether it is
+// valid, which only PostgreSQL indexes may not be.
+func indexExists(ctx context.Context, db *squealx.DB, dialect string, spec IndexSpec) (exists, valid bool, err error) {
+	_, name := splitName(dialect, spec.Name)
+	schema, table := splitName(dialect, spec.Table)
+	var query string
+	var args []any
+	switch dialect {
+	case squealx.DialectPostgres:
+		query, args = postgresIndexExists, []any{name, schema}
+	case squealx.DialectMySQL:
+		query, args = mysqlIndexExists, []any{name, schema, table}
+	case squealx.DialectSQLite:
+		query, args = sqliteIndexExists, []any{name}
+	case squealx.DialectMSSQL:
+		query, args = mssqlIndexExists, []any{name, spec.Table}
+	}
+	err = db.GetContext(ctx, &valid, db.Rebind(query), args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, false, nil
+	}
+	return err == nil, valid, err
+}
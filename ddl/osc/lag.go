@@ -0,0 +1,61 @@
+package osc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/oarkflow/squealx"
+)
+
+// ErrReplicationStopped is returned by ReplicaLag for replicas whose
+// replication is not running, which report no lag.
+var ErrReplicationStopped = errors.New("osc: replication is not running")
+
+// ReplicaLag returns the replication lag of the MySQL replica db, from
+// SHOW REPLICA STATUS, or SHOW SLAVE STATUS before MySQL 8.0.22.
+func ReplicaLag(ctx context.Context, db *squealx.DB) (time.Duration, error) {
+	status := map[string]any{}
+	row := db.QueryRowxContext(ctx, "SHOW REPLICA STATUS")
+	err := row.MapScan(status)
+	if err != nil {
+		row = db.QueryRowxContext(ctx, "SHOW SLAVE STATUS")
+		if err = row.MapScan(status); err != nil {
+			return 0, err
+		}
+	}
+	value, ok := status["Seconds_Behind_Source"]
+	if !ok {
+		value = status["Seconds_Behind_Master"]
+	}
+	if b, ok := value.([]byte); ok {
+		value = string(b)
+	}
+	if value == nil {
+		return 0, ErrReplicationStopped
+	}
+	seconds, err := strconv.ParseInt(fmt.Sprint(value), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("osc: replication lag %v: %w", value, err)
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// MaxReplicaLag returns a Lag function for Change reporting the largest
+// lag of replicas. Replicas whose replication is stopped report it as an
+// error, which stops the change.
+func MaxReplicaLag(replicas ...*squealx.DB) func(ctx context.Context) (time.Duration, error) {
+	return func(ctx context.Context) (time.Duration, error) {
+		var lag time.Duration
+		for _, replica := range replicas {
+			replicaLag, err := ReplicaLag(ctx, replica)
+			if err != nil {
+				return 0, err
+			}
+			lag = max(lag, replicaLag)
+		}
+		return lag, nil
+	}
+}
@@ -0,0 +1,373 @@
+// Package osc changes the schema of large MySQL tables online, where a
+// direct ALTER TABLE would lock them for too long. It uses the shadow table
+// pattern: the change is applied to an empty copy of the table, triggers
+// mirror the writes made to the table into the copy, the rows are copied in
+// batches of primary keys, throttled while replicas lag, and the tables are
+// finally swapped with an atomic RENAME TABLE.
+//
+// Tables must have a single column primary key. Progress is saved after
+// every batch, so that an interrupted change resumes where it stopped.
+package osc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/oarkflow/squealx"
+	"github.com/oarkflow/squealx/ddl"
+)
+
+var (
+	// ErrNoPrimaryKey is returned for tables without a single column
+	// primary key to copy them by.
+	ErrNoPrimaryKey = errors.New("osc: table needs a single column primary key")
+	// ErrShadowExists is returned when the shadow table of a change exists
+	// without saved state to resume it from, such as after a change run
+	// without a StateStore was interrupted. It must be dropped by hand.
+	ErrShadowExists = errors.New("osc: shadow table exists without state to resume")
+	// ErrOldExists is returned when the table the original table is
+	// renamed to exists without saved state to resume from, such as when
+	// kept by a previous change with KeepOld. It must be dropped by hand.
+	ErrOldExists = errors.New("osc: old table exists without state to resume")
+	// ErrAlterChanged is returned when resuming a change whose saved state
+	// is that of different ALTER clauses.
+	ErrAlterChanged = errors.New("osc: saved state is for a different change")
+)
+
+// Change is a schema change of a table.
+type Change struct {
+	// Table is the table to change, in the database of the connection.
+	Table string
+	// Alter are the clauses of the ALTER TABLE statement applied to the
+	// table, such as "ADD COLUMN nickname varchar(64), ADD INDEX (email)".
+	// Columns it drops are not copied; columns it adds take their default.
+	Alter string
+	// ChunkSize is the number of rows copied per batch, 1000 by default.
+	ChunkSize int
+	// MaxLag is the replication lag above which copying pauses, 5s by
+	// default, as reported by Lag.
+	MaxLag time.Duration
+	// Lag returns the replication lag, such as the largest of ReplicaLag
+	// over the replicas. Without it copying is not throttled.
+	Lag func(ctx context.Context) (time.Duration, error)
+	// State saves the progress of the change so that it can be resumed.
+	// Without it, an interrupted change must be cleaned up by hand.
+	State StateStore
+	// Guard runs the statements taking metadata locks on the table:
+	// creating the triggers and swapping the tables. It waits for the
+	// transactions which would block them by default.
+	Guard *ddl.Guard
+	// KeepOld keeps the original table, renamed _<table>_old, once
+	// swapped, instead of dropping it.
+	KeepOld bool
+	// Progress, if set, is called after every batch.
+	Progress func(State)
+}
+
+func (c Change) withDefaults() Change {
+	if c.ChunkSize <= 0 {
+		c.ChunkSize = 1000
+	}
+	if c.MaxLag <= 0 {
+		c.MaxLag = 5 * time.Second
+	}
+	if c.Guard == nil {
+		c.Guard = &ddl.Guard{Wait: true}
+	}
+	return c
+}
+
+func (c Change) shadow() string { return "_" + c.Table + "_new" }
+func (c Change) old() string    { return "_" + c.Table + "_old" }
+func (c Change) trigger(event string) string {
+	return "_" + c.Table + "_osc_" + event
+}
+
+// Run applies the change to its table on db, resuming it from its saved
+// state if any. It returns once the tables are swapped, or with the error
+// which stopped it, in which case it may be run again.
+func Run(ctx context.Context, db *squealx.DB, c Change) error {
+	if squealx.Dialect(db.DriverName()) != squealx.DialectMySQL {
+		return fmt.Errorf("osc: online schema change is not supported on %s", db.DriverName())
+	}
+	c = c.withDefaults()
+	key, err := primaryKey(ctx, db, c.Table)
+	if err != nil {
+		return err
+	}
+	state, err := c.start(ctx, db, key)
+	if err != nil {
+		return err
+	}
+	if state.Copied && !state.Swapped {
+		// A run stopped right after the swap could not save it, but the
+		// shadow table is gone then.
+		exists, err := tableExists(ctx, db, c.shadow())
+		if err != nil {
+			return err
+		}
+		state.Swapped = !exists
+	}
+	if state.Swapped {
+		return c.swap(ctx, db, state)
+	}
+	columns, err := sharedColumns(ctx, db, c.Table, c.shadow())
+	if err != nil {
+		return err
+	}
+	if !state.Copied {
+		if err := c.copyRows(ctx, db, state, key, columns); err != nil {
+			return err
+		}
+	}
+	return c.swap(ctx, db, state)
+}
+
+// start returns the saved state of the change, or creates its shadow table
+// and triggers and returns its initial state.
+func (c Change) start(ctx context.Context, db *squealx.DB, key string) (*State, error) {
+	if c.State != nil {
+		state, err := c.State.Load(ctx, c.Table)
+		if err != nil {
+			return nil, err
+		}
+		if state != nil {
+			if state.Alter != c.Alter {
+				return nil, ErrAlterChanged
+			}
+			return state, nil
+		}
+	}
+	for _, leftover := range []struct {
+		table string
+		err   error
+	}{{c.shadow(), ErrShadowExists}, {c.old(), ErrOldExists}} {
+		exists, err := tableExists(ctx, db, leftover.table)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, leftover.err
+		}
+	}
+	statements := []string{
+		fmt.Sprintf("CREATE TABLE %s LIKE %s", quote(c.shadow()), quote(c.Table)),
+		fmt.Sprintf("ALTER TABLE %s %s", quote(c.shadow()), c.Alter),
+	}
+	for _, statement := range statements {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return nil, err
+		}
+	}
+	columns, err := sharedColumns(ctx, db, c.Table, c.shadow())
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(columns, key) {
+		return nil, fmt.Errorf("osc: change drops primary key column %s", key)
+	}
+	for _, statement := range c.triggers(key, columns) {
+		if err := c.Guard.Exec(ctx, db, statement); err != nil {
+			return nil, err
+		}
+	}
+	state := &State{Table: c.Table, Alter: c.Alter, Started: time.Now()}
+	if c.State != nil {
+		if err := c.State.Save(ctx, state); err != nil {
+			return nil, err
+		}
+	}
+	return state, nil
+}
+
+// triggers returns the statements creating the triggers mirroring the
+// writes to the table into the shadow table.
+func (c Change) triggers(key string, columns []string) []string {
+	shadow, table := quote(c.shadow()), quote(c.Table)
+	names := make([]string, len(columns))
+	values := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = quote(column)
+		values[i] = "NEW." + quote(column)
+	}
+	replace := fmt.Sprintf("REPLACE INTO %s (%s) VALUES (%s)", shadow, strings.Join(names, ", "), strings.Join(values, ", "))
+	deleteOld := fmt.Sprintf("DELETE IGNORE FROM %s WHERE %s = OLD.%s", shadow, quote(key), quote(key))
+	return []string{
+		fmt.Sprintf("CREATE TRIGGER %s AFTER INSERT ON %s FOR EACH ROW %s", quote(c.trigger("ins")), table, replace),
+		fmt.Sprintf("CREATE TRIGGER %s AFTER UPDATE ON %s FOR EACH ROW BEGIN %s; %s; END", quote(c.trigger("upd")), table, deleteOld, replace),
+		fmt.Sprintf("CREATE TRIGGER %s AFTER DELETE ON %s FOR EACH ROW %s", quote(c.trigger("del")), table, deleteOld),
+	}
+}
+
+// copyRows copies the rows of the table into the shadow table in batches,
+// from the last key copied.
+func (c Change) copyRows(ctx context.Context, db *squealx.DB, state *State, key string, columns []string) error {
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = quote(column)
+	}
+	list := strings.Join(names, ", ")
+	table, pk := quote(c.Table), quote(key)
+	for {
+		if err := c.throttle(ctx); err != nil {
+			return err
+		}
+		after, args := "", []any{}
+		if state.LastKey != nil {
+			after, args = fmt.Sprintf(" WHERE %s > ?", pk), []any{*state.LastKey}
+		}
+		// The key ending the batch, none for the last one.
+		var end sql.NullString
+		err := db.GetContext(ctx, &end, fmt.Sprintf("SELECT %s FROM %s%s ORDER BY %s LIMIT 1 OFFSET %d",
+			pk, table, after, pk, c.ChunkSize-1), args...)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		where := after
+		if end.Valid {
+			if where == "" {
+				where = fmt.Sprintf(" WHERE %s <= ?", pk)
+			} else {
+				where += fmt.Sprintf(" AND %s <= ?", pk)
+			}
+			args = append(args, end.String)
+		}
+		res, err := db.ExecContext(ctx, fmt.Sprintf("INSERT IGNORE INTO %s (%s) SELECT %s FROM %s%s ORDER BY %s LOCK IN SHARE MODE",
+			quote(c.shadow()), list, list, table, where, pk), args...)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err == nil {
+			state.Rows += n
+		}
+		if end.Valid {
+			state.LastKey = &end.String
+		} else {
+			state.Copied = true
+		}
+		if c.State != nil {
+			if err := c.State.Save(ctx, state); err != nil {
+				return err
+			}
+		}
+		if c.Progress != nil {
+			c.Progress(*state)
+		}
+		if state.Copied {
+			return nil
+		}
+	}
+}
+
+// throttle waits while the replication lag is above MaxLag.
+func (c Change) throttle(ctx context.Context) error {
+	if c.Lag == nil {
+		return nil
+	}
+	wait := 100 * time.Millisecond
+	for {
+		lag, err := c.Lag(ctx)
+		if err != nil {
+			return err
+		}
+		if lag <= c.MaxLag {
+			return nil
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		wait = min(2*wait, 10*time.Second)
+	}
+}
+
+// swap replaces the table with the shadow table, unless state tells it is
+// already, then drops the triggers and, unless kept, the original table.
+func (c Change) swap(ctx context.Context, db *squealx.DB, state *State) error {
+	if !state.Swapped {
+		rename := fmt.Sprintf("RENAME TABLE %s TO %s, %s TO %s",
+			quote(c.Table), quote(c.old()), quote(c.shadow()), quote(c.Table))
+		if err := c.Guard.Exec(ctx, db, rename); err != nil {
+			return err
+		}
+		state.Swapped = true
+		if c.State != nil {
+			if err := c.State.Save(ctx, state); err != nil {
+				return err
+			}
+		}
+	}
+	statements := []string{
+		"DROP TRIGGER IF EXISTS " + quote(c.trigger("ins")),
+		"DROP TRIGGER IF EXISTS " + quote(c.trigger("upd")),
+		"DROP TRIGGER IF EXISTS " + quote(c.trigger("del")),
+	}
+	if !c.KeepOld {
+		statements = append(statements, "DROP TABLE IF EXISTS "+quote(c.old()))
+	}
+	for _, statement := range statements {
+		if err := c.Guard.Exec(ctx, db, statement); err != nil {
+			return err
+		}
+	}
+	if c.State != nil {
+		return c.State.Delete(ctx, c.Table)
+	}
+	return nil
+}
+
+// tableExists reports whether table exists in the database of db.
+func tableExists(ctx context.Context, db *squealx.DB, table string) (bool, error) {
+	var exists bool
+	err := db.GetContext(ctx, &exists, `SELECT true FROM information_schema.tables
+	WHERE table_schema = DATABASE() AND table_name = ?`, table)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return exists, err
+}
+
+// primaryKey returns the primary key column of table.
+func primaryKey(ctx context.Context, db *squealx.DB, table string) (string, error) {
+	var columns []string
+	err := db.SelectContext(ctx, &columns, `SELECT column_name FROM information_schema.key_column_usage
+	WHERE table_schema = DATABASE() AND table_name = ? AND constraint_name = 'PRIMARY'
+	ORDER BY ordinal_position`, table)
+	if err != nil {
+		return "", err
+	}
+	if len(columns) != 1 {
+		return "", ErrNoPrimaryKey
+	}
+	return columns[0], nil
+}
+
+// sharedColumns returns the columns of table also in shadow, other than
+// generated ones, which cannot be written.
+func sharedColumns(ctx context.Context, db *squealx.DB, table, shadow string) ([]string, error) {
+	const query = `SELECT column_name FROM information_schema.columns
+	WHERE table_schema = DATABASE() AND table_name = ? AND extra NOT LIKE '%GENERATED%'
+	ORDER BY ordinal_position`
+	var columns, shadowColumns []string
+	if err := db.SelectContext(ctx, &columns, query, table); err != nil {
+		return nil, err
+	}
+	if err := db.SelectContext(ctx, &shadowColumns, query, shadow); err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(columns, func(column string) bool {
+		return !slices.ContainsFunc(shadowColumns, func(c string) bool { return strings.EqualFold(c, column) })
+	}), nil
+}
+
+func quote(name string) string {
+	return squealx.QuoteIdent(squealx.DialectMySQL, name)
+}
@@ -0,0 +1,102 @@
+package osc
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/oarkflow/squealx"
+)
+
+// State is the progress of a change.
+type State struct {
+	Table string `json:"table"`
+	Alter string `json:"alter"`
+	// LastKey is the primary key of the last row copied, nil before the
+	// first batch.
+	LastKey *string `json:"last_key,omitempty"`
+	// Rows counts the rows copied so far.
+	Rows int64 `json:"rows"`
+	// Copied is set once all rows are copied, before the swap.
+	Copied bool `json:"copied"`
+	// Swapped is set once the tables are swapped, before the triggers and
+	// the original table are dropped.
+	Swapped bool      `json:"swapped"`
+	Started time.Time `json:"started"`
+}
+
+// StateStore saves the state of changes, keyed by table.
+type StateStore interface {
+	// Load returns the saved state of the change of table, nil if none.
+	Load(ctx context.Context, table string) (*State, error)
+	Save(ctx context.Context, state *State) error
+	Delete(ctx context.Context, table string) error
+}
+
+// StateTable is the table DBStateStore saves states in.
+const StateTable = "squealx_osc_state"
+
+// DBStateStore returns a StateStore saving states in the StateTable of db,
+// created on first use, typically the database of the changed tables.
+func DBStateStore(db *squealx.DB) StateStore {
+	return &dbStateStore{db: db}
+}
+
+type dbStateStore struct {
+	db      *squealx.DB
+	created bool
+}
+
+func (s *dbStateStore) create(ctx context.Context) error {
+	if s.created {
+		return nil
+	}
+	_, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+StateTable+` (
+	table_name VARCHAR(64) NOT NULL PRIMARY KEY,
+	state JSON NOT NULL,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP)`)
+	s.created = err == nil
+	return err
+}
+
+func (s *dbStateStore) Load(ctx context.Context, table string) (*State, error) {
+	if err := s.create(ctx); err != nil {
+		return nil, err
+	}
+	var data []byte
+	err := s.db.GetContext(ctx, &data, `SELECT state FROM `+StateTable+` WHERE table_name = ?`, table)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	state := new(State)
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func (s *dbStateStore) Save(ctx context.Context, state *State) error {
+	if err := s.create(ctx); err != nil {
+		return err
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `INSERT INTO `+StateTable+` (table_name, state) VALUES (?, ?)
+	ON DUPLICATE KEY UPDATE state = VALUES(state)`, state.Table, data)
+	return err
+}
+
+func (s *dbStateStore) Delete(ctx context.Context, table string) error {
+	if err := s.create(ctx); err != nil {
+		return err
+	}
+	_, err := s.db.ExecContext(ctx, `DELETE FROM `+StateTable+` WHERE table_name = ?`, table)
+	return err
+}
@@ -0,0 +1,363 @@
+// Package diag captures the database activity of an application for a
+// window of time into a bundle to attach to bug reports.
+//
+// Capture records every query run through a squealx.DB with its timing and
+// error, samples the connection pool, and optionally explains the slowest
+// statements. Bundles are redacted: string and number literals are removed
+// from query texts and arguments are reduced to their types, unless
+// WithArgs is given.
+package diag
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"github.com/oarkflow/squealx"
+)
+
+// Query is a query run during a capture.
+type Query struct {
+	Query       string        `json:"query"`
+	Fingerprint string        `json:"fingerprint"`
+	Args        []string      `json:"args,omitempty"`
+	Start       time.Time     `json:"start"`
+	Duration    time.Duration `json:"duration"`
+	Error       string        `json:"error,omitempty"`
+}
+
+// Summary aggregates the runs of a query, by the hash of
+// squealx.FingerprintDialect for its text.
+type Summary struct {
+	Query       string        `json:"query"`
+	Fingerprint string        `json:"fingerprint"`
+	Count       int           `json:"count"`
+	Errors      int           `json:"errors"`
+	Total       time.Duration `json:"total"`
+	Max         time.Duration `json:"max"`
+	// Plan is the output of EXPLAIN for the query, with WithPlans.
+	Plan      string `json:"plan,omitempty"`
+	PlanError string `json:"plan_error,omitempty"`
+
+	// original and args are the text and arguments of a run of the query,
+	// to explain it.
+	original string
+	args     []any
+}
+
+// PoolSample is the state of the connection pool at a point of a capture.
+type PoolSample struct {
+	Time  time.Time   `json:"time"`
+	Stats sql.DBStats `json:"stats"`
+}
+
+// Bundle is the result of a capture.
+type Bundle struct {
+	Driver    string    `json:"driver"`
+	GoVersion string    `json:"go_version"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	// Queries are the queries run, up to the limit set by WithMaxQueries;
+	// Dropped counts the queries past it, which are still summarized.
+	Queries []Query `json:"queries"`
+	Dropped int     `json:"dropped,omitempty"`
+	// Summary aggregates the queries by fingerprint, most time consuming first.
+	Summary []*Summary   `json:"summary"`
+	Pool    []PoolSample `json:"pool"`
+}
+
+type options struct {
+	plans        int
+	args         bool
+	maxQueries   int
+	poolInterval time.Duration
+}
+
+// Option configures Capture.
+type Option func(*options)
+
+// WithPlans explains the n most time consuming SELECT statements once the
+// capture window ends. The plans are computed with the arguments of one of
+// their runs, which are not included in the bundle.
+func WithPlans(n int) Option {
+	return func(o *options) {
+		o.plans = n
+	}
+}
+
+// WithArgs includes the values of query arguments in the bundle instead of
+// their types. They may hold personal data.
+func WithArgs() Option {
+	return func(o *options) {
+		o.args = true
+	}
+}
+
+// WithMaxQueries sets the number of queries recorded individually, 10000 by
+// default.
+func WithMaxQueries(n int) Option {
+	return func(o *options) {
+		o.maxQueries = n
+	}
+}
+
+// WithPoolInterval sets the interval between samples of the connection pool,
+// one second by default.
+func WithPoolInterval(d time.Duration) Option {
+	return func(o *options) {
+		o.poolInterval = d
+	}
+}
+
+var captures atomic.Int64
+
+// Capture records the activity of db for duration, or until ctx is done, in
+// which case the partial bundle is returned with the error of ctx. Queries
+// run by db concurrently are recorded as they complete.
+func Capture(ctx context.Context, db *squealx.DB, duration time.Duration, opts ...Option) (*Bundle, error) {
+	o := options{maxQueries: 10000, poolInterval: time.Second}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	r := &recorder{
+		opts:    o,
+		dialect: squealx.Dialect(db.DriverName()),
+		summary: map[string]*Summary{},
+		bundle: &Bundle{
+			Driver:    db.DriverName(),
+			GoVersion: runtime.Version(),
+			Start:     time.Now(),
+		},
+	}
+	name := fmt.Sprintf("squealx/diag#%d", captures.Add(1))
+	db.UseNamed(name, r)
+
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	ticker := time.NewTicker(o.poolInterval)
+	defer ticker.Stop()
+	r.sample(db)
+	var err error
+loop:
+	for {
+		select {
+		case <-timer.C:
+			break loop
+		case <-ctx.Done():
+			err = ctx.Err()
+			break loop
+		case <-ticker.C:
+			r.sample(db)
+		}
+	}
+	r.sample(db)
+	db.RemoveNamed(name)
+
+	// Queries still running record nothing once the capture is closed.
+	r.mu.Lock()
+	r.closed = true
+	r.mu.Unlock()
+	b := r.bundle
+	b.End = time.Now()
+	for _, s := range r.summary {
+		b.Summary = append(b.Summary, s)
+	}
+	sort.Slice(b.Summary, func(i, j int) bool { return b.Summary[i].Total > b.Summary[j].Total })
+	if err == nil && o.plans > 0 {
+		r.explain(ctx, db)
+	}
+	return b, err
+}
+
+type startKey struct{}
+
+// recorder is the hook recording queries during a capture.
+type recorder struct {
+	opts    options
+	dialect string
+
+	mu      sync.Mutex
+	closed  bool
+	bundle  *Bundle
+	summary map[string]*Summary
+}
+
+func (r *recorder) Before(ctx context.Context, query string, args ...any) (context.Context, error) {
+	return context.WithValue(ctx, startKey{}, time.Now()), nil
+}
+
+func (r *recorder) After(ctx context.Context, query string, args ...any) (context.Context, error) {
+	r.record(ctx, query, args, nil)
+	return ctx, nil
+}
+
+func (r *recorder) OnError(ctx context.Context, err error, query string, args ...any) error {
+	r.record(ctx, query, args, err)
+	return err
+}
+
+func (r *recorder) record(ctx context.Context, query string, args []any, err error) {
+	start, _ := ctx.Value(startKey{}).(time.Time)
+	if start.IsZero() {
+		start = time.Now()
+	}
+	redacted, fingerprint := squealx.FingerprintDialect(r.dialect, query)
+	q := Query{Query: redacted, Fingerprint: fingerprint, Start: start, Duration: time.Since(start)}
+	if err != nil {
+		q.Error = err.Error()
+	}
+	for _, arg := range args {
+		if r.opts.args {
+			q.Args = append(q.Args, fmt.Sprintf("%v", arg))
+		} else {
+			q.Args = append(q.Args, fmt.Sprintf("%T", arg))
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return
+	}
+	s, ok := r.summary[q.Fingerprint]
+	if !ok {
+		s = &Summary{Query: q.Query, Fingerprint: q.Fingerprint, original: query, args: args}
+		r.summary[q.Fingerprint] = s
+	}
+	s.Count++
+	s.Total += q.Duration
+	s.Max = max(s.Max, q.Duration)
+	if err != nil {
+		s.Errors++
+	}
+	if len(r.bundle.Queries) < r.opts.maxQueries {
+		r.bundle.Queries = append(r.bundle.Queries, q)
+	} else {
+		r.bundle.Dropped++
+	}
+}
+
+func (r *recorder) sample(db *squealx.DB) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bundle.Pool = append(r.bundle.Pool, PoolSample{Time: time.Now(), Stats: db.Stats()})
+}
+
+// explain sets the plans of the most time consuming SELECT statements of
+// the bundle.
+func (r *recorder) explain(ctx context.Context, db *squealx.DB) {
+	var prefix string
+	switch r.dialect {
+	case squealx.DialectPostgres, squealx.DialectMySQL:
+		prefix = "EXPLAIN "
+	case squealx.DialectSQLite:
+		prefix = "EXPLAIN QUERY PLAN "
+	default:
+		return
+	}
+	explained := 0
+	for _, s := range r.bundle.Summary {
+		if explained == r.opts.plans {
+			return
+		}
+		if kind, _ := squealx.ClassifyStatement(s.Query); kind != squealx.StatementSelect || s.Errors == s.Count {
+			continue
+		}
+		explained++
+		plan, err := explainQuery(ctx, db, prefix+s.original, s.args)
+		if err != nil {
+			s.PlanError = err.Error()
+			continue
+		}
+		s.Plan = plan
+	}
+}
+
+func explainQuery(ctx context.Context, db *squealx.DB, query string, args []any) (string, error) {
+	rows, err := db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	var b strings.Builder
+	for rows.Next() {
+		values, err := rows.SliceScan()
+		if err != nil {
+			return "", err
+		}
+		for i, v := range values {
+			if i > 0 {
+				b.WriteByte('\t')
+			}
+			if bytes, ok := v.([]byte); ok {
+				v = string(bytes)
+			}
+			fmt.Fprint(&b, v)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String(), rows.Err()
+}
+
+// Redact returns query with its comments and string and number literals
+// removed, as normalized by squealx.FingerprintDialect: literals, including
+// PostgreSQL dollar quoted strings and MySQL double quoted ones, and
+// placeholders become ?, lists of them (?+), words are lower cased and
+// whitespace is collapsed. Double quoted identifiers are kept elsewhere.
+func Redact(dialect, query string) string {
+	normalized, _ := squealx.FingerprintDialect(dialect, query)
+	return normalized
+}
+
+// WriteJSON writes b as indented JSON.
+func (b *Bundle) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(b)
+}
+
+// WriteZip writes b as a zip archive holding bundle.json, summary.txt, a
+// table of the query summary for reading at a glance, and queries.sql, the
+// summarized queries laid out for reading, most time consuming first.
+func (b *Bundle) WriteZip(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	f, err := zw.Create("bundle.json")
+	if err != nil {
+		return err
+	}
+	if err := b.WriteJSON(f); err != nil {
+		return err
+	}
+	f, err = zw.Create("summary.txt")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(f, "driver %s, %s to %s, %d queries\n\n", b.Driver, b.Start.Format(time.RFC3339), b.End.Format(time.RFC3339), len(b.Queries)+b.Dropped)
+	tw := tabwriter.NewWriter(f, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "COUNT\tERRORS\tTOTAL\tMAX\tQUERY")
+	for _, s := range b.Summary {
+		fmt.Fprintf(tw, "%d\t%d\t%s\t%s\t%s\n", s.Count, s.Errors, s.Total, s.Max, s.Query)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	f, err = zw.Create("queries.sql")
+	if err != nil {
+		return err
+	}
+	for i, s := range b.Summary {
+		fmt.Fprintf(f, "-- %d. %d runs, %d errors, %s total, %s max\n%s;\n\n", i+1, s.Count, s.Errors, s.Total, s.Max,
+			squealx.FormatSQL(s.Query, squealx.StylePretty))
+	}
+	return zw.Close()
+}
@@ -0,0 +1,51 @@
+package squealx
+
+import "strings"
+
+// Dialects recognised from driver names.
+const (
+	DialectUnknown  = ""
+	DialectPostgres = "postgres"
+	DialectMySQL    = "mysql"
+	DialectSQLite   = "sqlite"
+	DialectMSSQL    = "mssql"
+)
+
+// Dialect returns the SQL dialect spoken by driverName, based on the same
+// registry as BindType.
+func Dialect(driverName string) string {
+	switch BindType(driverName) {
+	case DOLLAR:
+		return DialectPostgres
+	case AT:
+		return DialectMSSQL
+	case QUESTION:
+		if strings.Contains(driverName, "sqlite") {
+			return DialectSQLite
+		}
+		return DialectMySQL
+	}
+	return DialectUnknown
+}
+
+// QuoteIdent quotes the identifier name for dialect: with backticks on
+// MySQL, brackets on SQL Server and double quotes otherwise.
+func QuoteIdent(dialect, name string) string {
+	switch dialect {
+	case DialectMySQL:
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	case DialectMSSQL:
+		return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// QuoteName quotes each dot-separated part of the schema qualified name
+// with QuoteIdent.
+func QuoteName(dialect, name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = QuoteIdent(dialect, part)
+	}
+	return strings.Join(parts, ".")
+}
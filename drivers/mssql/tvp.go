@@ -0,0 +1,86 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+
+	mssql "github.com/microsoft/go-mssqldb"
+
+	"github.com/oarkflow/squealx"
+)
+
+// TVP returns rows, a slice of structs, as a table-valued parameter of the
+// user-defined table type typeName, such as "dbo.OrderLines". The fields of
+// the structs map to the columns of the type in order; fields tagged
+// `tvp:"-"` are skipped.
+func TVP(typeName string, rows any) mssql.TVP {
+	return mssql.TVP{TypeName: typeName, Value: rows}
+}
+
+// ExecTVP runs the stored procedure procedure with rows passed as its
+// table-valued parameter param, of the table type typeName.
+func ExecTVP(ctx context.Context, db *squealx.DB, procedure, param, typeName string, rows any) (sql.Result, error) {
+	param = strings.TrimPrefix(param, "@")
+	query := fmt.Sprintf("EXEC %s @%s = @%s", procedure, param, param)
+	return db.ExecContext(ctx, query, sql.Named(param, TVP(typeName, rows)))
+}
+
+// InsertTVP inserts rows into table in a single statement, sending them as a
+// table-valued parameter of the table type typeName, whose columns must be
+// those of columns, or of table when columns are not given.
+func InsertTVP(ctx context.Context, db *squealx.DB, table, typeName string, rows any, columns ...string) (int64, error) {
+	query := "INSERT INTO " + table + " SELECT * FROM @rows"
+	if len(columns) > 0 {
+		list := strings.Join(columns, ", ")
+		query = "INSERT INTO " + table + " (" + list + ") SELECT " + list + " FROM @rows"
+	}
+	result, err := db.ExecContext(ctx, query, sql.Named("rows", TVP(typeName, rows)))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// MergeTVP upserts rows into table with a MERGE statement: rows matching
+// a row of table on keys update its other columns and the others are
+// inserted. The rows are sent as a table-valued parameter of the table type
+// typeName, whose columns include keys and columns.
+func MergeTVP(ctx context.Context, db *squealx.DB, table, typeName string, rows any, keys, columns []string) (int64, error) {
+	if len(keys) == 0 {
+		return 0, fmt.Errorf("mssql: merge into %s requires key columns", table)
+	}
+	on := make([]string, len(keys))
+	for i, key := range keys {
+		on[i] = "t." + key + " = s." + key
+	}
+	var sets []string
+	for _, column := range columns {
+		if !slices.Contains(keys, column) {
+			sets = append(sets, column+" = s."+column)
+		}
+	}
+	all := slices.Clone(keys)
+	for _, column := range columns {
+		if !slices.Contains(all, column) {
+			all = append(all, column)
+		}
+	}
+	values := make([]string, len(all))
+	for i, column := range all {
+		values[i] = "s." + column
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "MERGE INTO %s WITH (HOLDLOCK) AS t USING @rows AS s ON %s", table, strings.Join(on, " AND "))
+	if len(sets) > 0 {
+		b.WriteString(" WHEN MATCHED THEN UPDATE SET " + strings.Join(sets, ", "))
+	}
+	fmt.Fprintf(&b, " WHEN NOT MATCHED THEN INSERT (%s) VALUES (%s);", strings.Join(all, ", "), strings.Join(values, ", "))
+	result, err := db.ExecContext(ctx, b.String(), sql.Named("rows", TVP(typeName, rows)))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,236 @@
+package mysql
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+
+	gomysql "github.com/go-sql-driver/mysql"
+
+	"github.com/oarkflow/squealx"
+)
+
+// ErrLocalInfileDisabled is returned by LoadDataInfile when the server does
+// not allow LOAD DATA LOCAL INFILE and the fallback is disabled.
+var ErrLocalInfileDisabled = errors.New("mysql: local infile is disabled on the server")
+
+// LoadOptions configures LoadDataInfile.
+type LoadOptions struct {
+	// Columns are the columns of the CSV fields, in order. When empty, the
+	// fields fill the columns of the table in order, unless Header is set.
+	Columns []string
+	// Header skips the first line of the data, and takes the columns from
+	// it when Columns is empty.
+	Header bool
+	// Delimiter separates fields, ',' by default.
+	Delimiter rune
+	// Replace replaces rows with the same primary or unique key as a loaded
+	// row. By default such rows fail the load, unless Ignore is set to skip
+	// the loaded rows instead.
+	Replace bool
+	Ignore  bool
+	// NoFallback fails with ErrLocalInfileDisabled instead of inserting the
+	// rows with batched INSERT statements when the server disallows local
+	// infile.
+	NoFallback bool
+	// BatchSize is the number of rows per INSERT of the fallback, 500 by
+	// default.
+	BatchSize int
+}
+
+var readers atomic.Int64
+
+// LoadDataInfile loads CSV data from r into table with LOAD DATA LOCAL
+// INFILE, streaming r through a reader handler of the driver, and returns
+// the number of rows loaded. Fields may be enclosed in double quotes, which
+// are doubled within them, and \N is NULL. Lines end with "\n"; a trailing
+// "\r" is dropped.
+//
+// Servers disallow local infile unless local_infile is enabled; the rows are
+// then inserted with batched INSERT statements in a transaction instead.
+func LoadDataInfile(ctx context.Context, db *squealx.DB, table string, r io.Reader, opts LoadOptions) (int64, error) {
+	if opts.Delimiter == 0 {
+		opts.Delimiter = ','
+	}
+	if opts.Replace && opts.Ignore {
+		return 0, errors.New("mysql: Replace and Ignore are exclusive")
+	}
+	reader := &lineReader{r: bufio.NewReader(r)}
+	if opts.Header && len(opts.Columns) == 0 {
+		line, err := reader.r.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return 0, fmt.Errorf("mysql: reading CSV header: %w", err)
+		}
+		header, err := csvReader(strings.NewReader(line), opts.Delimiter).Read()
+		if err != nil {
+			return 0, fmt.Errorf("mysql: reading CSV header: %w", err)
+		}
+		opts.Columns, opts.Header = header, false
+	}
+
+	name := fmt.Sprintf("squealx-%d", readers.Add(1))
+	gomysql.RegisterReaderHandler(name, func() io.Reader { return reader })
+	defer gomysql.DeregisterReaderHandler(name)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "LOAD DATA LOCAL INFILE 'Reader::%s'", name)
+	switch {
+	case opts.Replace:
+		b.WriteString(" REPLACE")
+	case opts.Ignore:
+		b.WriteString(" IGNORE")
+	}
+	fmt.Fprintf(&b, " INTO TABLE %s CHARACTER SET utf8mb4 FIELDS TERMINATED BY %s OPTIONALLY ENCLOSED BY '\"' LINES TERMINATED BY '\\n'",
+		table, quote(string(opts.Delimiter)))
+	if opts.Header {
+		b.WriteString(" IGNORE 1 LINES")
+	}
+	if len(opts.Columns) > 0 {
+		b.WriteString(" (" + strings.Join(opts.Columns, ", ") + ")")
+	}
+	result, err := db.ExecContext(ctx, b.String())
+	if err == nil {
+		return result.RowsAffected()
+	}
+	if !localInfileDisabled(err) || reader.read {
+		return 0, err
+	}
+	if opts.NoFallback {
+		return 0, ErrLocalInfileDisabled
+	}
+	return insertCSV(ctx, db, table, reader, opts)
+}
+
+// localInfileDisabled reports whether err is the refusal of the server to
+// load local data.
+func localInfileDisabled(err error) bool {
+	var mysqlErr *gomysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return false
+	}
+	// ER_NOT_ALLOWED_COMMAND and ER_CLIENT_LOCAL_FILES_DISABLED.
+	return mysqlErr.Number == 1148 || mysqlErr.Number == 3948
+}
+
+// insertCSV inserts the CSV rows of r into table with multi-row INSERT
+// statements, in a transaction.
+func insertCSV(ctx context.Context, db *squealx.DB, table string, r io.Reader, opts LoadOptions) (int64, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+	records := csvReader(r, opts.Delimiter)
+	if opts.Header {
+		if _, err := records.Read(); err != nil {
+			return 0, err
+		}
+	}
+	verb := "INSERT INTO "
+	switch {
+	case opts.Replace:
+		verb = "REPLACE INTO "
+	case opts.Ignore:
+		verb = "INSERT IGNORE INTO "
+	}
+	prefix := verb + table
+	if len(opts.Columns) > 0 {
+		prefix += " (" + strings.Join(opts.Columns, ", ") + ")"
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	var batch [][]string
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		var args []any
+		rows := make([]string, len(batch))
+		for i, record := range batch {
+			rows[i] = "(" + strings.TrimSuffix(strings.Repeat("?, ", len(record)), ", ") + ")"
+			for _, field := range record {
+				if field == `\N` {
+					args = append(args, nil)
+				} else {
+					args = append(args, field)
+				}
+			}
+		}
+		result, err := tx.ExecContext(ctx, prefix+" VALUES "+strings.Join(rows, ", "), args...)
+		if err != nil {
+			return err
+		}
+		n, err := result.RowsAffected()
+		total += n
+		batch = batch[:0]
+		return err
+	}
+	for {
+		record, err := records.Read()
+		if err == io.EOF {
+			break
+		}
+		if err == nil {
+			batch = append(batch, record)
+			if len(batch) == opts.BatchSize {
+				err = flush()
+			}
+		}
+		if err != nil {
+			_ = tx.Rollback()
+			return 0, err
+		}
+	}
+	if err := flush(); err != nil {
+		_ = tx.Rollback()
+		return 0, err
+	}
+	return total, tx.Commit()
+}
+
+func csvReader(r io.Reader, delimiter rune) *csv.Reader {
+	records := csv.NewReader(r)
+	records.Comma = delimiter
+	records.FieldsPerRecord = -1
+	records.ReuseRecord = false
+	return records
+}
+
+func quote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+// lineReader records whether the driver read from r, in which case the data
+// cannot be replayed into the fallback, and drops the "\r" of "\r\n" line
+// endings.
+type lineReader struct {
+	r    *bufio.Reader
+	read bool
+}
+
+func (l *lineReader) Read(p []byte) (int, error) {
+	l.read = true
+	n, err := l.r.Read(p)
+	out := p[:0]
+	for i := 0; i < n; i++ {
+		if p[i] == '\r' {
+			if i+1 < n && p[i+1] == '\n' {
+				continue
+			}
+			if i+1 == n {
+				if next, _ := l.r.Peek(1); len(next) == 1 && next[0] == '\n' {
+					continue
+				}
+			}
+		}
+		out = append(out, p[i])
+	}
+	return len(out), err
+}
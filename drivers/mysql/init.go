@@ -10,12 +10,12 @@ import (
 /*
 root:T#sT1234@tcp(localhost:3306)/datav
 */
-func Open(dsn string, id string) (*squealx.DB, error) {
-	return squealx.Connect("mysql", dsn, id)
+func Open(dsn string, id string, opts ...squealx.DBOption) (*squealx.DB, error) {
+	return squealx.Connect("mysql", dsn, id, opts...)
 }
 
-func MustOpen(dsn string, id string) *squealx.DB {
-	db, err := Open(dsn, id)
+func MustOpen(dsn string, id string, opts ...squealx.DBOption) *squealx.DB {
+	db, err := Open(dsn, id, opts...)
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,117 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	msqlite "modernc.org/sqlite"
+
+	"github.com/oarkflow/squealx"
+)
+
+// ErrNotSQLite is returned by the backup helpers for a DB whose connections
+// are not connections of the modernc.org/sqlite driver.
+var ErrNotSQLite = errors.New("sqlite: not a modernc.org/sqlite connection")
+
+// backupPages is the number of pages copied per step of an online backup,
+// between which writers may take the database.
+const backupPages = 256
+
+type backupConn interface {
+	NewBackup(dstURI string) (*msqlite.Backup, error)
+	NewRestore(srcURI string) (*msqlite.Backup, error)
+	Serialize() ([]byte, error)
+}
+
+// raw runs fn with the driver connection of a connection of db.
+func raw(ctx context.Context, db *squealx.DB, fn func(c backupConn) error) error {
+	conn, err := db.SQLDB.DB().Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.Raw(func(driverConn any) error {
+		c, ok := driverConn.(backupConn)
+		if !ok {
+			return ErrNotSQLite
+		}
+		return fn(c)
+	})
+}
+
+// BackupTo copies the database of db to the file at path with the online
+// backup API of SQLite, while it stays in use. Pages are copied in steps,
+// between which other connections may write; the backup restarts whenever
+// they do, so it completes once writers leave it a quiet moment.
+func BackupTo(ctx context.Context, db *squealx.DB, path string) error {
+	return raw(ctx, db, func(c backupConn) error {
+		backup, err := c.NewBackup(path)
+		if err != nil {
+			return err
+		}
+		return step(ctx, backup)
+	})
+}
+
+// RestoreFrom replaces the database of db with the database in the file at
+// path, for every connection of db.
+func RestoreFrom(ctx context.Context, db *squealx.DB, path string) error {
+	return raw(ctx, db, func(c backupConn) error {
+		backup, err := c.NewRestore(path)
+		if err != nil {
+			return err
+		}
+		return step(ctx, backup)
+	})
+}
+
+// step runs backup to completion, releasing it.
+func step(ctx context.Context, backup *msqlite.Backup) error {
+	for {
+		more, err := backup.Step(backupPages)
+		if err != nil || !more {
+			return errors.Join(err, backup.Finish())
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Join(ctx.Err(), backup.Finish())
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+// Serialize returns the content of the database of db, as it would be
+// written to a file.
+func Serialize(ctx context.Context, db *squealx.DB) ([]byte, error) {
+	var data []byte
+	err := raw(ctx, db, func(c backupConn) (err error) {
+		data, err = c.Serialize()
+		return err
+	})
+	return data, err
+}
+
+// Deserialize replaces the database of db with data returned by Serialize.
+// It restores through a temporary file, so that every connection of db,
+// not only one, sees the new content.
+func Deserialize(ctx context.Context, db *squealx.DB, data []byte) error {
+	f, err := os.CreateTemp("", "squealx-*.sqlite")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(data)
+	if err = errors.Join(err, f.Close()); err != nil {
+		return fmt.Errorf("sqlite: writing database: %w", err)
+	}
+	return RestoreFrom(ctx, db, f.Name())
+}
+
+// Vacuum rebuilds the database of db, reclaiming the space of deleted rows.
+func Vacuum(ctx context.Context, db *squealx.DB) error {
+	_, err := db.ExecContext(ctx, "VACUUM")
+	return err
+}
@@ -7,12 +7,12 @@ import (
 )
 
 // Open - sqlite.db
-func Open(dsn string, id string) (*squealx.DB, error) {
-	return squealx.Connect("sqlite", dsn, id)
+func Open(dsn string, id string, opts ...squealx.DBOption) (*squealx.DB, error) {
+	return squealx.Connect("sqlite", dsn, id, opts...)
 }
 
-func MustOpen(dsn string, id string) *squealx.DB {
-	db, err := Open(dsn, id)
+func MustOpen(dsn string, id string, opts ...squealx.DBOption) *squealx.DB {
+	db, err := Open(dsn, id, opts...)
 	if err != nil {
 		panic(err)
 	}
@@ -1,6 +1,9 @@
 package squealx
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type Entity interface {
 	TableName() string
@@ -43,17 +46,41 @@ type QueryParams struct {
 	Except []string `json:"except"`
 }
 
+// LockOptions sets how FirstForUpdate waits for rows locked by other
+// transactions. At most one of SkipLocked and NoWait may be set.
+type LockOptions struct {
+	// SkipLocked skips locked rows instead of waiting for them.
+	SkipLocked bool
+	// NoWait fails immediately instead of waiting for locked rows.
+	NoWait bool
+}
+
 type Repository[T any] interface {
 	Find(context.Context, map[string]any) ([]T, error)
 	All(context.Context) ([]T, error)
+	FindInBatches(ctx context.Context, cond map[string]any, batchSize int, fn func(batch []T) error) error
 	Create(context.Context, any) error
 	Update(context.Context, any, map[string]any) error
 	Delete(context.Context, any) error
 	SoftDelete(context.Context, map[string]any) error
 	First(context.Context, map[string]any) (T, error)
+	Exists(ctx context.Context, cond map[string]any) (bool, error)
+	Select(ctx context.Context, cond map[string]any, columns ...string) ([]map[string]any, error)
+	Count(ctx context.Context, cond map[string]any) (int64, error)
+	CountDistinct(ctx context.Context, column string, cond map[string]any) (int64, error)
+	Sum(ctx context.Context, column string, cond map[string]any) (float64, error)
+	Avg(ctx context.Context, column string, cond map[string]any) (float64, error)
+	Min(ctx context.Context, column string, cond map[string]any, dest any) error
+	Max(ctx context.Context, column string, cond map[string]any, dest any) error
+	Pluck(ctx context.Context, column string, dest any, condition ...map[string]any) error
+	Refresh(ctx context.Context, cond map[string]any) (T, error)
+	FirstForUpdate(ctx context.Context, cond map[string]any, opts LockOptions) (T, error)
+	FirstOrCreate(ctx context.Context, cond, defaults map[string]any) (T, error)
+	UpdateOrCreate(ctx context.Context, cond, values map[string]any) (T, error)
 	Raw(ctx context.Context, query string, args ...any) ([]T, error)
 	RawExec(ctx context.Context, query string, args any) error
 	Paginate(context.Context, Paging, ...map[string]any) PaginatedResponse
 	PaginateRaw(ctx context.Context, paging Paging, query string, condition ...map[string]any) PaginatedResponse
 	GetDB() *DB
+	Cached(store CacheStore, ttl time.Duration) *CachedRepository[T]
 }
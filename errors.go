@@ -0,0 +1,232 @@
+package squealx
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrorKind is a driver independent classification of a database error.
+type ErrorKind int
+
+const (
+	ErrorUnknown ErrorKind = iota
+	// ErrorSerialization is a serialization failure of a SERIALIZABLE or
+	// REPEATABLE READ transaction.
+	ErrorSerialization
+	// ErrorDeadlock is a transaction chosen as deadlock victim.
+	ErrorDeadlock
+	// ErrorLockTimeout is a lock wait that timed out or a busy database.
+	ErrorLockTimeout
+	// ErrorUniqueViolation is an insert or update conflicting with a unique
+	// index or primary key.
+	ErrorUniqueViolation
+	// ErrorConnection is a database which cannot be reached or a connection
+	// lost while in use.
+	ErrorConnection
+	// ErrorStatementTimeout is a statement canceled for running past its
+	// deadline or the statement timeout of the server.
+	ErrorStatementTimeout
+)
+
+// mysqlErrorReg matches the text of go-sql-driver errors, which expose
+// their number and state only as fields.
+var mysqlErrorReg = regexp.MustCompile(`^Error (\d+)(?: \(([0-9A-Z]{5})\))?:`)
+
+// ClassifyError returns the kind of err for the drivers supported by
+// squealx, looking through wrapped errors.
+func ClassifyError(err error) ErrorKind {
+	if err == nil {
+		return ErrorUnknown
+	}
+	// context.DeadlineExceeded is a net.Error too.
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorStatementTimeout
+	}
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.As(err, &netErr) {
+		return ErrorConnection
+	}
+	var state interface{ SQLState() string }
+	if errors.As(err, &state) {
+		return classifySQLState(state.SQLState())
+	}
+	var mssql interface{ SQLErrorNumber() int32 }
+	if errors.As(err, &mssql) {
+		switch mssql.SQLErrorNumber() {
+		case 1205:
+			return ErrorDeadlock
+		case 1222:
+			return ErrorLockTimeout
+		case 3960:
+			return ErrorSerialization
+		case 2601, 2627:
+			return ErrorUniqueViolation
+		}
+		return ErrorUnknown
+	}
+	var sqlite interface{ Code() int }
+	if errors.As(err, &sqlite) {
+		switch code := sqlite.Code(); {
+		// SQLITE_CONSTRAINT_PRIMARYKEY and SQLITE_CONSTRAINT_UNIQUE.
+		case code == 1555 || code == 2067:
+			return ErrorUniqueViolation
+		// SQLITE_BUSY and SQLITE_LOCKED, including extended codes.
+		case code&0xff == 5 || code&0xff == 6:
+			return ErrorLockTimeout
+		// SQLITE_INTERRUPT, raised when the context of a query is done.
+		case code == 9:
+			return ErrorStatementTimeout
+		}
+		return ErrorUnknown
+	}
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if m := mysqlErrorReg.FindStringSubmatch(e.Error()); m != nil {
+			switch number, _ := strconv.Atoi(m[1]); number {
+			case 1213:
+				return ErrorDeadlock
+			case 1205:
+				return ErrorLockTimeout
+			case 1062:
+				return ErrorUniqueViolation
+			case 2002, 2003, 2006, 2013:
+				return ErrorConnection
+			// ER_QUERY_TIMEOUT, and its MariaDB counterpart.
+			case 3024, 1969:
+				return ErrorStatementTimeout
+			}
+			return classifySQLState(m[2])
+		}
+	}
+	return ErrorUnknown
+}
+
+func classifySQLState(state string) ErrorKind {
+	switch state {
+	case "40001":
+		return ErrorSerialization
+	case "40P01":
+		return ErrorDeadlock
+	case "55P03":
+		return ErrorLockTimeout
+	case "23505":
+		return ErrorUniqueViolation
+	case "57P01", "57P02", "57P03":
+		return ErrorConnection
+	case "57014":
+		return ErrorStatementTimeout
+	}
+	// Class 08 is connection exception.
+	if strings.HasPrefix(state, "08") {
+		return ErrorConnection
+	}
+	return ErrorUnknown
+}
+
+// IsRetryable reports whether err is a transient transaction conflict which
+// is expected to succeed when the whole transaction is retried.
+func IsRetryable(err error) bool {
+	switch ClassifyError(err) {
+	case ErrorSerialization, ErrorDeadlock, ErrorLockTimeout:
+		return true
+	}
+	return false
+}
+
+// IsUniqueViolation reports whether err is a unique index or primary key
+// violation.
+func IsUniqueViolation(err error) bool {
+	return ClassifyError(err) == ErrorUniqueViolation
+}
+
+// IsStatementTimeout reports whether err is a statement canceled for running
+// past its deadline or the statement timeout of the server.
+func IsStatementTimeout(err error) bool {
+	return ClassifyError(err) == ErrorStatementTimeout
+}
+
+// IsConnectionError reports whether err is caused by a database which cannot
+// be reached or by a connection lost while in use.
+func IsConnectionError(err error) bool {
+	return ClassifyError(err) == ErrorConnection
+}
+
+var constraintRegs = []*regexp.Regexp{
+	// PostgreSQL: violates unique constraint "users_email_key".
+	regexp.MustCompile(`constraint "([^"]+)"`),
+	// MySQL: Duplicate entry 'a' for key 'users.users_email_key'.
+	regexp.MustCompile(`for key '([^']+)'`),
+	// SQL Server: Violation of UNIQUE KEY constraint 'uq_users_email'.
+	regexp.MustCompile(`constraint '([^']+)'`),
+	// SQL Server: ... with unique index 'ix_users_email'.
+	regexp.MustCompile(`unique index '([^']+)'`),
+	// SQLite: UNIQUE constraint failed: users.email
+	regexp.MustCompile(`constraint failed: (.+)$`),
+}
+
+// ConstraintName returns the name of the constraint or index violated by
+// err, as reported in the message of the driver, or "". SQLite does not
+// name constraints: its name is the list of the columns violating it, such
+// as "users.email" or "users.org_id, users.email".
+func ConstraintName(err error) string {
+	if err == nil {
+		return ""
+	}
+	msg := err.Error()
+	for _, reg := range constraintRegs {
+		if m := reg.FindStringSubmatch(msg); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// ConstraintError is a constraint violation mapped to an error of the
+// application by MapConstraintErrors. errors.Is matches both the mapped
+// error and the error of the driver.
+type ConstraintError struct {
+	Constraint string
+	Err        error
+	Cause      error
+}
+
+func (e *ConstraintError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ConstraintError) Unwrap() []error {
+	return []error{e.Err, e.Cause}
+}
+
+// MapConstraintErrors returns err as a *ConstraintError wrapping the error
+// errs maps the violated constraint to, such as "users_email_key" to
+// ErrEmailTaken, so services can answer 409 Conflict without parsing
+// driver messages. MySQL 8 prefixes index names with their table: they match
+// with or without it. Other errors are returned unchanged.
+func MapConstraintErrors(err error, errs map[string]error) error {
+	if err == nil || len(errs) == 0 {
+		return err
+	}
+	var mapped *ConstraintError
+	if errors.As(err, &mapped) {
+		return err
+	}
+	name := ConstraintName(err)
+	if name == "" {
+		return err
+	}
+	target, ok := errs[name]
+	if !ok {
+		if i := strings.LastIndexByte(name, '.'); i >= 0 && !strings.Contains(name, ",") {
+			target, ok = errs[name[i+1:]]
+		}
+	}
+	if !ok {
+		return err
+	}
+	return &ConstraintError{Constraint: name, Err: target, Cause: err}
+}
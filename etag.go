@@ -0,0 +1,173 @@
+package squealx
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"time"
+)
+
+// HashOptions configures HashRows.
+type HashOptions struct {
+	// IgnoreColumnOrder hashes the columns of each row by name, so that
+	// queries selecting the same columns in another order hash alike.
+	IgnoreColumnOrder bool
+	// IgnoreRowOrder hashes the set of rows rather than their sequence, for
+	// queries without a total ORDER BY.
+	IgnoreRowOrder bool
+}
+
+type HashOption func(opts *HashOptions)
+
+// HashIgnoreColumnOrder sets HashOptions.IgnoreColumnOrder.
+func HashIgnoreColumnOrder() HashOption {
+	return func(opts *HashOptions) {
+		opts.IgnoreColumnOrder = true
+	}
+}
+
+// HashIgnoreRowOrder sets HashOptions.IgnoreRowOrder.
+func HashIgnoreRowOrder() HashOption {
+	return func(opts *HashOptions) {
+		opts.IgnoreRowOrder = true
+	}
+}
+
+// HashRows reads rows to the end, closing them, and returns a hex SHA-256
+// checksum of the result set: of its column names and of the type and
+// value of every column, so that the checksum changes with any row. Equal
+// results hash alike across connections and drivers returning the same Go
+// types, which makes the checksum suitable as an HTTP ETag or to compare
+// replicas.
+func HashRows(rows *Rows, options ...HashOption) (string, error) {
+	opts := new(HashOptions)
+	for _, option := range options {
+		option(opts)
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	order := make([]int, len(columns))
+	for i := range order {
+		order[i] = i
+	}
+	if opts.IgnoreColumnOrder {
+		sort.SliceStable(order, func(a, b int) bool { return columns[order[a]] < columns[order[b]] })
+	}
+	h := sha256.New()
+	for _, i := range order {
+		writeHashField(h, []byte(columns[i]))
+	}
+	var rowSums [][]byte
+	var buf bytes.Buffer
+	for rows.Next() {
+		values, err := rows.SliceScan()
+		if err != nil {
+			return "", err
+		}
+		buf.Reset()
+		for _, i := range order {
+			hashValue(&buf, values[i])
+		}
+		if opts.IgnoreRowOrder {
+			sum := sha256.Sum256(buf.Bytes())
+			rowSums = append(rowSums, sum[:])
+			continue
+		}
+		writeHashField(h, buf.Bytes())
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	slices.SortFunc(rowSums, bytes.Compare)
+	for _, sum := range rowSums {
+		h.Write(sum)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeHashField writes b prefixed with its length, so that adjacent fields
+// cannot run into each other.
+func writeHashField(w interface{ Write([]byte) (int, error) }, b []byte) {
+	var n [8]byte
+	binary.BigEndian.PutUint64(n[:], uint64(len(b)))
+	_, _ = w.Write(n[:])
+	_, _ = w.Write(b)
+}
+
+// hashValue writes the canonical encoding of a scanned value to buf: a type
+// tag followed by the value.
+func hashValue(buf *bytes.Buffer, v any) {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteByte('n')
+	case []byte:
+		buf.WriteByte('b')
+		writeHashField(buf, v)
+	case string:
+		buf.WriteByte('s')
+		writeHashField(buf, []byte(v))
+	case int64:
+		buf.WriteByte('i')
+		_ = binary.Write(buf, binary.BigEndian, v)
+	case float64:
+		buf.WriteByte('f')
+		_ = binary.Write(buf, binary.BigEndian, math.Float64bits(v))
+	case bool:
+		buf.WriteByte('t')
+		if v {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+	case time.Time:
+		buf.WriteByte('d')
+		writeHashField(buf, []byte(v.UTC().Format(time.RFC3339Nano)))
+	default:
+		buf.WriteByte('v')
+		writeHashField(buf, []byte(fmt.Sprintf("%T:%v", v, v)))
+	}
+}
+
+// ETagContext runs query and returns the checksum of its result as a strong
+// HTTP ETag, quoted, to answer conditional requests with 304 Not Modified
+// when it matches If-None-Match.
+func (db *DB) ETagContext(ctx context.Context, query string, args []any, options ...HashOption) (string, error) {
+	rows, err := db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return "", err
+	}
+	sum, err := HashRows(rows, options...)
+	if err != nil {
+		return "", err
+	}
+	return `"` + sum + `"`, nil
+}
+
+// ETag is like ETagContext with the background context.
+func (db *DB) ETag(query string, args []any, options ...HashOption) (string, error) {
+	return db.ETagContext(context.Background(), query, args, options...)
+}
+
+// resultETag returns the quoted checksum of the JSON encoding of a page of
+// results and its pagination.
+func resultETag(result any, pagination *Pagination) (string, error) {
+	b, err := json.Marshal(struct {
+		Items      any         `json:"data"`
+		Pagination *Pagination `json:"pagination"`
+	}{result, pagination})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return `"` + hex.EncodeToString(sum[:]) + `"`, nil
+}
@@ -1,11 +1,12 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"github.com/oarkflow/squealx/drivers/postgres"
 	"log"
-	"os"
-	"strings"
+
+	"github.com/oarkflow/squealx/ddl"
+	"github.com/oarkflow/squealx/drivers/postgres"
 )
 
 var queries = `
@@ -232,32 +233,18 @@ func main() {
 	if err != nil {
 		log.Fatalln(err)
 	}
-	indexes := strings.Split(queries, ";")
-	results := make(map[string][]string)
-	f, err := os.OpenFile("indexes.sql", os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+	specs, err := ddl.ParseIndexes(queries)
 	if err != nil {
-		panic(err)
-	}
-
-	defer f.Close()
-
-	for _, index := range indexes {
-		_, err := db.Exec(strings.TrimSpace(index))
-		if err != nil {
-			er := err.Error()
-			results[er] = append(results[er], index)
-		} else {
-			results["success"] = append(results["success"], index)
-		}
+		log.Fatalln(err)
 	}
-	for err, indices := range results {
-		if _, err := f.WriteString(fmt.Sprintf("*********** %s ************\n", err)); err != nil {
-			panic(err)
-		}
-		for _, i := range indices {
-			if _, err := f.WriteString(fmt.Sprintf("%s;\n", i)); err != nil {
-				panic(err)
-			}
-		}
+	report, err := ddl.CreateIndexes(context.Background(), db, specs, ddl.Options{
+		Concurrently: true,
+		SkipExisting: true,
+		MaxParallel:  4,
+	})
+	fmt.Printf("created %d, skipped %d, failed %d\n",
+		report.Count(ddl.IndexCreated), report.Count(ddl.IndexSkipped), report.Count(ddl.IndexFailed))
+	if err != nil {
+		log.Fatalln(err)
 	}
 }
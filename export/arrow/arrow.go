@@ -0,0 +1,368 @@
+// Package arrow converts squealx result sets into Apache Arrow record batches
+// and Parquet files.
+//
+// It is a separate module, so that applications not exporting to Arrow do not
+// depend on it. The Arrow schema is inferred from the column types reported
+// by the driver; columns whose type is not recognized are exported as
+// strings.
+package arrow
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	goarrow "github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+
+	"github.com/oarkflow/squealx"
+)
+
+// Options configures a Reader.
+type Options struct {
+	// BatchSize is the number of rows per record batch, 8192 by default.
+	BatchSize int
+	// Allocator allocates the record batches, memory.DefaultAllocator by
+	// default.
+	Allocator memory.Allocator
+}
+
+// Schema returns the Arrow schema of columns of the given types.
+func Schema(colTypes []*sql.ColumnType) *goarrow.Schema {
+	fields := make([]goarrow.Field, len(colTypes))
+	for i, ct := range colTypes {
+		nullable, ok := ct.Nullable()
+		fields[i] = goarrow.Field{Name: ct.Name(), Type: dataType(ct), Nullable: nullable || !ok}
+	}
+	return goarrow.NewSchema(fields, nil)
+}
+
+var timestampType = &goarrow.TimestampType{Unit: goarrow.Microsecond, TimeZone: "UTC"}
+
+func dataType(ct *sql.ColumnType) goarrow.DataType {
+	if t := ct.ScanType(); t != nil {
+		switch t {
+		case reflect.TypeOf(sql.NullInt64{}), reflect.TypeOf(sql.NullInt32{}), reflect.TypeOf(sql.NullInt16{}), reflect.TypeOf(sql.NullByte{}):
+			return goarrow.PrimitiveTypes.Int64
+		case reflect.TypeOf(sql.NullFloat64{}):
+			return goarrow.PrimitiveTypes.Float64
+		case reflect.TypeOf(sql.NullBool{}):
+			return goarrow.FixedWidthTypes.Boolean
+		case reflect.TypeOf(sql.NullTime{}), reflect.TypeOf(time.Time{}):
+			return timestampType
+		}
+		switch t.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint8, reflect.Uint16, reflect.Uint32:
+			return goarrow.PrimitiveTypes.Int64
+		case reflect.Uint, reflect.Uint64:
+			return goarrow.PrimitiveTypes.Uint64
+		case reflect.Float32, reflect.Float64:
+			return goarrow.PrimitiveTypes.Float64
+		case reflect.Bool:
+			return goarrow.FixedWidthTypes.Boolean
+		}
+	}
+	name := strings.ToUpper(ct.DatabaseTypeName())
+	switch {
+	case name == "BOOL" || name == "BOOLEAN" || name == "BIT":
+		return goarrow.FixedWidthTypes.Boolean
+	case strings.Contains(name, "INT") || name == "YEAR":
+		return goarrow.PrimitiveTypes.Int64
+	case name == "REAL" || name == "FLOAT" || name == "FLOAT4" || name == "FLOAT8" || name == "DOUBLE":
+		return goarrow.PrimitiveTypes.Float64
+	case name == "DATE" || name == "DATETIME" || name == "DATETIME2" || strings.HasPrefix(name, "TIMESTAMP"):
+		return timestampType
+	case strings.Contains(name, "BLOB") || strings.Contains(name, "BINARY") || name == "BYTEA" || name == "IMAGE":
+		return goarrow.BinaryTypes.Binary
+	}
+	// DECIMAL and NUMERIC are exported as strings, keeping their precision.
+	return goarrow.BinaryTypes.String
+}
+
+// Reader reads a result set as Arrow record batches. It implements
+// array.RecordReader, so it can be handed to Arrow IPC and Flight writers.
+type Reader struct {
+	refs     atomic.Int64
+	rows     squealx.Rowsi
+	schema   *goarrow.Schema
+	builder  *array.RecordBuilder
+	size     int
+	values   []any
+	pointers []any
+	record   goarrow.Record
+	err      error
+}
+
+var _ array.RecordReader = (*Reader)(nil)
+
+// NewReader returns a Reader over the remaining rows of rows. Rows are
+// scanned lazily, one batch per call to Next; rows is not closed.
+func NewReader(rows squealx.Rowsi, opts Options) (*Reader, error) {
+	colTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 8192
+	}
+	if opts.Allocator == nil {
+		opts.Allocator = memory.DefaultAllocator
+	}
+	schema := Schema(colTypes)
+	r := &Reader{
+		rows:     rows,
+		schema:   schema,
+		builder:  array.NewRecordBuilder(opts.Allocator, schema),
+		size:     opts.BatchSize,
+		values:   make([]any, len(colTypes)),
+		pointers: make([]any, len(colTypes)),
+	}
+	for i := range r.values {
+		r.pointers[i] = &r.values[i]
+	}
+	r.refs.Add(1)
+	return r, nil
+}
+
+// Retain increases the reference count of r.
+func (r *Reader) Retain() {
+	r.refs.Add(1)
+}
+
+// Release decreases the reference count of r, releasing its memory when it
+// reaches zero.
+func (r *Reader) Release() {
+	if r.refs.Add(-1) == 0 {
+		if r.record != nil {
+			r.record.Release()
+			r.record = nil
+		}
+		r.builder.Release()
+	}
+}
+
+// Schema returns the schema of the record batches.
+func (r *Reader) Schema() *goarrow.Schema {
+	return r.schema
+}
+
+// Next reads the next record batch, reporting false at the end of the rows
+// or on error.
+func (r *Reader) Next() bool {
+	if r.record != nil {
+		r.record.Release()
+		r.record = nil
+	}
+	if r.err != nil {
+		return false
+	}
+	n := 0
+	for n < r.size && r.rows.Next() {
+		if r.err = r.rows.Scan(r.pointers...); r.err != nil {
+			return false
+		}
+		for i, v := range r.values {
+			if r.err = appendValue(r.builder.Field(i), v); r.err != nil {
+				r.err = fmt.Errorf("arrow: column %s: %w", r.schema.Field(i).Name, r.err)
+				return false
+			}
+		}
+		n++
+	}
+	if r.err = r.rows.Err(); r.err != nil || n == 0 {
+		return false
+	}
+	r.record = r.builder.NewRecord()
+	return true
+}
+
+// Record returns the batch read by Next, valid until the next call.
+func (r *Reader) Record() goarrow.Record {
+	return r.record
+}
+
+// Err returns the error which stopped Next, if any.
+func (r *Reader) Err() error {
+	return r.err
+}
+
+// WriteParquet writes the remaining rows of rows to w as a Parquet file and
+// returns the number of rows written. props may be nil for the default
+// writer properties.
+func WriteParquet(w io.Writer, rows squealx.Rowsi, opts Options, props *parquet.WriterProperties) (int64, error) {
+	r, err := NewReader(rows, opts)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Release()
+	fw, err := pqarrow.NewFileWriter(r.Schema(), w, props, pqarrow.DefaultWriterProps())
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	for r.Next() {
+		if err := fw.Write(r.Record()); err != nil {
+			fw.Close()
+			return n, err
+		}
+		n += r.Record().NumRows()
+	}
+	if err := r.Err(); err != nil {
+		fw.Close()
+		return n, err
+	}
+	return n, fw.Close()
+}
+
+func appendValue(b array.Builder, v any) error {
+	if v == nil {
+		b.AppendNull()
+		return nil
+	}
+	switch b := b.(type) {
+	case *array.Int64Builder:
+		i, err := toInt64(v)
+		if err != nil {
+			return err
+		}
+		b.Append(i)
+	case *array.Uint64Builder:
+		i, err := toInt64(v)
+		if err != nil {
+			return err
+		}
+		b.Append(uint64(i))
+	case *array.Float64Builder:
+		f, err := toFloat64(v)
+		if err != nil {
+			return err
+		}
+		b.Append(f)
+	case *array.BooleanBuilder:
+		t, err := toBool(v)
+		if err != nil {
+			return err
+		}
+		b.Append(t)
+	case *array.TimestampBuilder:
+		t, err := toTime(v)
+		if err != nil {
+			return err
+		}
+		ts, err := goarrow.TimestampFromTime(t, goarrow.Microsecond)
+		if err != nil {
+			return err
+		}
+		b.Append(ts)
+	case *array.BinaryBuilder:
+		if s, ok := v.(string); ok {
+			b.AppendString(s)
+		} else if p, ok := v.([]byte); ok {
+			b.Append(p)
+		} else {
+			return fmt.Errorf("cannot convert %T to binary", v)
+		}
+	case *array.StringBuilder:
+		switch v := v.(type) {
+		case string:
+			b.Append(v)
+		case []byte:
+			b.Append(string(v))
+		case time.Time:
+			b.Append(v.Format(time.RFC3339Nano))
+		default:
+			b.Append(fmt.Sprint(v))
+		}
+	default:
+		return fmt.Errorf("unsupported builder %T", b)
+	}
+	return nil
+}
+
+func toInt64(v any) (int64, error) {
+	switch v := v.(type) {
+	case int64:
+		return v, nil
+	case int32:
+		return int64(v), nil
+	case int:
+		return int64(v), nil
+	case uint64:
+		return int64(v), nil
+	case float64:
+		return int64(v), nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case []byte:
+		return strconv.ParseInt(string(v), 10, 64)
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	}
+	return 0, fmt.Errorf("cannot convert %T to int64", v)
+}
+
+func toFloat64(v any) (float64, error) {
+	switch v := v.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case []byte:
+		return strconv.ParseFloat(string(v), 64)
+	case string:
+		return strconv.ParseFloat(v, 64)
+	}
+	return 0, fmt.Errorf("cannot convert %T to float64", v)
+}
+
+func toBool(v any) (bool, error) {
+	switch v := v.(type) {
+	case bool:
+		return v, nil
+	case int64:
+		return v != 0, nil
+	case []byte:
+		return strconv.ParseBool(string(v))
+	case string:
+		return strconv.ParseBool(v)
+	}
+	return false, fmt.Errorf("cannot convert %T to bool", v)
+}
+
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999Z07:00", "2006-01-02 15:04:05.999999999", "2006-01-02"}
+
+func toTime(v any) (time.Time, error) {
+	var s string
+	switch v := v.(type) {
+	case time.Time:
+		return v, nil
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return time.Time{}, fmt.Errorf("cannot convert %T to time", v)
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse time %q", s)
+}
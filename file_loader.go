@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"database/sql/driver"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -16,10 +17,14 @@ import (
 )
 
 type Query struct {
-	Doc        string `json:"doc"`
-	Name       string `json:"name"`
-	Query      string `json:"query"`
-	Connection string `json:"connection"`
+	Doc        string       `json:"doc"`
+	Name       string       `json:"name"`
+	Query      string       `json:"query"`
+	Connection string       `json:"connection"`
+	Params     []QueryParam `json:"params,omitempty"`
+	Returns    string       `json:"returns,omitempty"`
+	// paramErr records an invalid param declaration, reported on validation.
+	paramErr error
 }
 
 type FileLoader struct {
@@ -34,13 +39,24 @@ func (f *FileLoader) DriverName(db *DB) string {
 	return db.DriverName()
 }
 
+// GetQuery returns the query named query, or nil. It is safe to call
+// concurrently with AddQuery.
 func (f *FileLoader) GetQuery(query string) *Query {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
 	if sqlQuery, exists := f.queries[query]; exists {
 		return sqlQuery
 	}
 	return nil
 }
 
+// AddQuery registers q under its name, replacing any query of that name.
+func (f *FileLoader) AddQuery(q *Query) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queries[q.Name] = q
+}
+
 func (f *FileLoader) Rebind(db *DB, sql string) string {
 	st := f.GetQuery(sql)
 	if st == nil {
@@ -348,8 +364,12 @@ func (f *FileLoader) Close(db *DB) error {
 	return db.Close()
 }
 
+// Queries returns a copy of the loaded queries by name. Use AddQuery to
+// register more.
 func (f *FileLoader) Queries() map[string]*Query {
-	return f.queries
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return maps.Clone(f.queries)
 }
 
 func LoadFromFile(file string) (*FileLoader, error) {
@@ -396,6 +416,8 @@ var (
 	sqlTemplateRE        = regexp.MustCompile(`(?s)--\s*sql-name:\s*(.+?)\s*\n(.*?)\s*--\s*sql-end`)
 	docTemplateRE        = regexp.MustCompile(`(?s)--\s*doc:\s*(.+?)\s*\n`)
 	connectionTemplateRE = regexp.MustCompile(`(?s)--\s*connection:\s*(.+?)\s*\n`)
+	paramTemplateRE      = regexp.MustCompile(`(?m)^\s*--\s*param:[ \t]*(.*?)\s*$`)
+	returnsTemplateRE    = regexp.MustCompile(`(?m)^\s*--\s*returns:[ \t]*(.*?)\s*$`)
 )
 
 func scanContent(content string) map[string]*Query {
@@ -415,6 +437,21 @@ func scanContent(content string) map[string]*Query {
 			query = connectionTemplateRE.ReplaceAllString(query, "")
 			q.Connection = connectionMatches[1]
 		}
+		for _, param := range paramTemplateRE.FindAllStringSubmatch(query, -1) {
+			p, err := parseQueryParam(param[1])
+			if err != nil && q.paramErr == nil {
+				q.paramErr = err
+			}
+			q.Params = append(q.Params, p)
+		}
+		query = paramTemplateRE.ReplaceAllString(query, "")
+		if returnsMatches := returnsTemplateRE.FindStringSubmatch(query); len(returnsMatches) == 2 {
+			query = returnsTemplateRE.ReplaceAllString(query, "")
+			q.Returns = returnsMatches[1]
+			if q.Returns != ReturnsOne && q.Returns != ReturnsMany && q.paramErr == nil {
+				q.paramErr = fmt.Errorf("returns %q: expected %s or %s", q.Returns, ReturnsOne, ReturnsMany)
+			}
+		}
 		query = strings.TrimSpace(query)
 		q.Query = query
 		if name != "" && query != "" {
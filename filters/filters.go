@@ -0,0 +1,262 @@
+// Package filters translates filters received from APIs into SQL.
+//
+// A filter is a tree of conditions on fields, combined with and, or and not,
+// usually decoded from JSON such as
+//
+//	{"and": [
+//		{"field": "age", "op": "gte", "value": 18},
+//		{"or": [
+//			{"field": "country", "op": "in", "value": ["FR", "DE"]},
+//			{"field": "vip", "op": "eq", "value": true}
+//		]}
+//	]}
+//
+// A Schema lists the fields a table can be filtered on, the column of each
+// and the operators allowed on it, and turns a filter into a WHERE clause
+// with named parameters. Field names and operators never reach the SQL
+// unless they are in the Schema, and values are always bound as parameters.
+package filters
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Operator compares a field with the value of a condition.
+type Operator string
+
+const (
+	Eq   Operator = "eq"
+	Ne   Operator = "ne"
+	Gt   Operator = "gt"
+	Gte  Operator = "gte"
+	Lt   Operator = "lt"
+	Lte  Operator = "lte"
+	In   Operator = "in"
+	Nin  Operator = "nin"
+	Like Operator = "like"
+	// Null matches NULL fields when its value is true, and non-NULL fields
+	// when it is false.
+	Null Operator = "null"
+)
+
+var comparisons = map[Operator]string{
+	Eq:   "=",
+	Ne:   "<>",
+	Gt:   ">",
+	Gte:  ">=",
+	Lt:   "<",
+	Lte:  "<=",
+	In:   "IN",
+	Nin:  "NOT IN",
+	Like: "LIKE",
+}
+
+// DefaultOperators are the operators allowed on fields declared without any.
+var DefaultOperators = []Operator{Eq, Ne, Gt, Gte, Lt, Lte, In, Nin, Null}
+
+// Filter is a node of a filter tree: either a condition on a field, or a
+// combination of filters.
+type Filter struct {
+	And   []Filter `json:"and,omitempty"`
+	Or    []Filter `json:"or,omitempty"`
+	Not   *Filter  `json:"not,omitempty"`
+	Field string   `json:"field,omitempty"`
+	Op    Operator `json:"op,omitempty"`
+	Value any      `json:"value,omitempty"`
+}
+
+// Parse decodes a filter from JSON.
+func Parse(data []byte) (Filter, error) {
+	var f Filter
+	err := json.Unmarshal(data, &f)
+	return f, err
+}
+
+// ErrInvalidFilter is wrapped by the errors of filters rejected by a Schema.
+var ErrInvalidFilter = errors.New("filters: invalid filter")
+
+// Field declares a field of a Schema.
+type Field struct {
+	// Column is the SQL expression of the field. It is not checked, so it
+	// must never come from user input.
+	Column string
+	// Operators are the operators allowed on the field, DefaultOperators
+	// when empty.
+	Operators []Operator
+}
+
+// Schema is the allowlist of the fields of a table which can be filtered.
+type Schema struct {
+	Fields map[string]Field
+	// MaxDepth limits the nesting of filters. Defaults to 8.
+	MaxDepth int
+	// MaxConditions limits the number of conditions. Defaults to 100.
+	MaxConditions int
+	// ParamPrefix prefixes the names of the parameters, "filter_" by
+	// default, so they do not collide with other parameters of the query.
+	ParamPrefix string
+}
+
+// Allow returns a Schema allowing the default operators on columns, using
+// their names as field names.
+func Allow(columns ...string) Schema {
+	fields := make(map[string]Field, len(columns))
+	for _, column := range columns {
+		fields[column] = Field{Column: column}
+	}
+	return Schema{Fields: fields}
+}
+
+// Where returns the WHERE clause, without the WHERE keyword, matching f and
+// its named parameters. An empty filter returns an empty clause.
+func (s Schema) Where(f Filter) (string, map[string]any, error) {
+	b := &builder{schema: s, params: make(map[string]any)}
+	if b.schema.MaxDepth <= 0 {
+		b.schema.MaxDepth = 8
+	}
+	if b.schema.MaxConditions <= 0 {
+		b.schema.MaxConditions = 100
+	}
+	if b.schema.ParamPrefix == "" {
+		b.schema.ParamPrefix = "filter_"
+	}
+	clause, err := b.build(f, 0)
+	if err != nil {
+		return "", nil, err
+	}
+	return clause, b.params, nil
+}
+
+type builder struct {
+	schema     Schema
+	params     map[string]any
+	conditions int
+}
+
+func (b *builder) build(f Filter, depth int) (string, error) {
+	if depth > b.schema.MaxDepth {
+		return "", fmt.Errorf("%w: nested deeper than %d", ErrInvalidFilter, b.schema.MaxDepth)
+	}
+	var parts []string
+	if f.Field != "" || f.Op != "" {
+		part, err := b.condition(f)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, part)
+	}
+	if len(f.And) > 0 {
+		part, err := b.combine(f.And, " AND ", depth)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, part)
+	}
+	if len(f.Or) > 0 {
+		part, err := b.combine(f.Or, " OR ", depth)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, part)
+	}
+	if f.Not != nil {
+		part, err := b.build(*f.Not, depth+1)
+		if err != nil {
+			return "", err
+		}
+		if part != "" {
+			parts = append(parts, "NOT ("+part+")")
+		}
+	}
+	return strings.Join(parts, " AND "), nil
+}
+
+func (b *builder) combine(filters []Filter, sep string, depth int) (string, error) {
+	parts := make([]string, 0, len(filters))
+	for _, f := range filters {
+		part, err := b.build(f, depth+1)
+		if err != nil {
+			return "", err
+		}
+		if part != "" {
+			parts = append(parts, "("+part+")")
+		}
+	}
+	return strings.Join(parts, sep), nil
+}
+
+func (b *builder) condition(f Filter) (string, error) {
+	b.conditions++
+	if b.conditions > b.schema.MaxConditions {
+		return "", fmt.Errorf("%w: more than %d conditions", ErrInvalidFilter, b.schema.MaxConditions)
+	}
+	field, ok := b.schema.Fields[f.Field]
+	if !ok {
+		return "", fmt.Errorf("%w: unknown field %q", ErrInvalidFilter, f.Field)
+	}
+	allowed := field.Operators
+	if len(allowed) == 0 {
+		allowed = DefaultOperators
+	}
+	if !contains(allowed, f.Op) {
+		return "", fmt.Errorf("%w: operator %q is not allowed on %s", ErrInvalidFilter, f.Op, f.Field)
+	}
+	if f.Op == Null {
+		isNull, ok := f.Value.(bool)
+		if !ok {
+			return "", fmt.Errorf("%w: %s takes true or false", ErrInvalidFilter, f.Op)
+		}
+		if isNull {
+			return field.Column + " IS NULL", nil
+		}
+		return field.Column + " IS NOT NULL", nil
+	}
+	comparison, ok := comparisons[f.Op]
+	if !ok {
+		return "", fmt.Errorf("%w: unknown operator %q", ErrInvalidFilter, f.Op)
+	}
+	name := fmt.Sprintf("%s%d", b.schema.ParamPrefix, len(b.params)+1)
+	if f.Op == In || f.Op == Nin {
+		values, ok := f.Value.([]any)
+		if !ok || len(values) == 0 {
+			return "", fmt.Errorf("%w: %s takes a non-empty list", ErrInvalidFilter, f.Op)
+		}
+		for _, v := range values {
+			if !scalar(v) {
+				return "", fmt.Errorf("%w: %s takes a list of scalars", ErrInvalidFilter, f.Op)
+			}
+		}
+		b.params[name] = values
+		return fmt.Sprintf("%s %s (:%s)", field.Column, comparison, name), nil
+	}
+	if f.Value == nil || !scalar(f.Value) {
+		return "", fmt.Errorf("%w: %s takes a scalar value", ErrInvalidFilter, f.Op)
+	}
+	b.params[name] = f.Value
+	return fmt.Sprintf("%s %s :%s", field.Column, comparison, name), nil
+}
+
+func scalar(v any) bool {
+	if _, ok := v.(time.Time); ok || v == nil {
+		return true
+	}
+	switch reflect.TypeOf(v).Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct, reflect.Ptr, reflect.Func, reflect.Chan:
+		return false
+	}
+	return true
+}
+
+func contains(ops []Operator, op Operator) bool {
+	for _, o := range ops {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package filters_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/oarkflow/squealx/filters"
+)
+
+func TestWhere(t *testing.T) {
+	f, err := filters.Parse([]byte(`{"and": [
+		{"field": "age", "op": "gte", "value": 18},
+		{"or": [
+			{"field": "country", "op": "in", "value": ["FR", "DE"]},
+			{"field": "vip", "op": "eq", "value": true}
+		]},
+		{"not": {"field": "deleted_at", "op": "null", "value": false}}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := filters.Allow("age", "country", "vip", "deleted_at")
+	clause, params, err := schema.Where(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "(age >= :filter_1) AND ((country IN (:filter_2)) OR (vip = :filter_3)) AND (NOT (deleted_at IS NOT NULL))"
+	if clause != want {
+		t.Errorf("clause = %s, want %s", clause, want)
+	}
+	wantParams := map[string]any{"filter_1": 18.0, "filter_2": []any{"FR", "DE"}, "filter_3": true}
+	if !reflect.DeepEqual(params, wantParams) {
+		t.Errorf("params = %v, want %v", params, wantParams)
+	}
+
+	clause, params, err = schema.Where(filters.Filter{})
+	if err != nil || clause != "" || len(params) != 0 {
+		t.Errorf("Where(empty) = %q, %v, %v, want an empty clause", clause, params, err)
+	}
+}
+
+func TestWhereOperators(t *testing.T) {
+	schema := filters.Schema{
+		Fields: map[string]filters.Field{
+			"name":  {Column: "users.name", Operators: []filters.Operator{filters.Eq, filters.Like, filters.Nin}},
+			"email": {Column: "users.email", Operators: []filters.Operator{filters.Null}},
+		},
+		ParamPrefix: "p",
+	}
+	cases := []struct {
+		filter filters.Filter
+		want   string
+	}{
+		{filters.Filter{Field: "name", Op: filters.Like, Value: "a%"}, "users.name LIKE :p1"},
+		{filters.Filter{Field: "name", Op: filters.Nin, Value: []any{"a", "b"}}, "users.name NOT IN (:p1)"},
+		{filters.Filter{Field: "email", Op: filters.Null, Value: true}, "users.email IS NULL"},
+		{filters.Filter{Field: "email", Op: filters.Null, Value: false}, "users.email IS NOT NULL"},
+	}
+	for _, c := range cases {
+		clause, _, err := schema.Where(c.filter)
+		if err != nil {
+			t.Errorf("Where(%+v): %v", c.filter, err)
+			continue
+		}
+		if clause != c.want {
+			t.Errorf("Where(%+v) = %s, want %s", c.filter, clause, c.want)
+		}
+	}
+}
+
+func TestWhereRejected(t *testing.T) {
+	schema := filters.Schema{
+		Fields: map[string]filters.Field{
+			"age":  {Column: "age"},
+			"name": {Column: "name", Operators: []filters.Operator{filters.Eq}},
+		},
+		MaxDepth:      2,
+		MaxConditions: 3,
+	}
+	deep := filters.Filter{Field: "age", Op: filters.Eq, Value: 1}
+	for range 4 {
+		deep = filters.Filter{Not: &deep}
+	}
+	many := filters.Filter{}
+	for range 4 {
+		many.Or = append(many.Or, filters.Filter{Field: "age", Op: filters.Eq, Value: 1})
+	}
+	cases := []struct {
+		name   string
+		filter filters.Filter
+	}{
+		{"unknown field", filters.Filter{Field: "salary", Op: filters.Eq, Value: 1}},
+		{"injected field", filters.Filter{Field: "age = 1 OR 1", Op: filters.Eq, Value: 1}},
+		{"field with comment", filters.Filter{Field: "age--", Op: filters.Eq, Value: 1}},
+		{"nested unknown field", filters.Filter{And: []filters.Filter{{Or: []filters.Filter{{Field: "age; DROP TABLE users", Op: filters.Eq, Value: 1}}}}}},
+		{"operator not allowed", filters.Filter{Field: "name", Op: filters.Like, Value: "a%"}},
+		{"like not a default", filters.Filter{Field: "age", Op: filters.Like, Value: "1%"}},
+		{"unknown operator", filters.Filter{Field: "age", Op: "= 1 OR 1 =", Value: 1}},
+		{"missing operator", filters.Filter{Field: "age", Value: 1}},
+		{"in without list", filters.Filter{Field: "age", Op: filters.In, Value: 1}},
+		{"in with empty list", filters.Filter{Field: "age", Op: filters.In, Value: []any{}}},
+		{"in with nested list", filters.Filter{Field: "age", Op: filters.In, Value: []any{[]any{1}}}},
+		{"null without bool", filters.Filter{Field: "age", Op: filters.Null, Value: "yes"}},
+		{"comparison with nil", filters.Filter{Field: "age", Op: filters.Eq}},
+		{"comparison with list", filters.Filter{Field: "age", Op: filters.Gt, Value: []any{1}}},
+		{"comparison with object", filters.Filter{Field: "age", Op: filters.Eq, Value: map[string]any{"$gt": 1}}},
+		{"too deep", deep},
+		{"too many conditions", many},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clause, _, err := schema.Where(c.filter)
+			if !errors.Is(err, filters.ErrInvalidFilter) {
+				t.Errorf("Where = %q, %v, want ErrInvalidFilter", clause, err)
+			}
+		})
+	}
+}
+
+func TestWhereBindsValues(t *testing.T) {
+	value := "x'; DROP TABLE users; --"
+	clause, params, err := filters.Allow("name").Where(filters.Filter{Field: "name", Op: filters.Eq, Value: value})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(clause, "DROP") || clause != "name = :filter_1" {
+		t.Errorf("clause = %s, want the value bound as a parameter", clause)
+	}
+	if params["filter_1"] != value {
+		t.Errorf("params = %v, want the value as given", params)
+	}
+}
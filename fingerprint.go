@@ -0,0 +1,67 @@
+package squealx
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strings"
+
+	"github.com/oarkflow/squealx/sqltoken"
+)
+
+// fingerprintConfig tokenizes the queries of every supported dialect well
+// enough to tell their literals and placeholders apart.
+var fingerprintConfig = func() sqltoken.Config {
+	config := sqltoken.PostgreSQLConfig()
+	config.NoticeQuestionMark = true
+	config.NoticeAtWord = true
+	config.NoticeNotionalStrings = true
+	return config
+}()
+
+// fingerprintMySQLConfig tokenizes MySQL queries, with their # comments and
+// hex and binary literals.
+var fingerprintMySQLConfig = func() sqltoken.Config {
+	config := sqltoken.MySQLConfig()
+	config.NoticeAtWord = true
+	return config
+}()
+
+var (
+	// fingerprintList matches a list of placeholders, such as an expanded IN
+	// list or a row of VALUES.
+	fingerprintList = regexp.MustCompile(`\(\?(?:, \?)+\)`)
+	// fingerprintRows matches the repeated rows of a multi-row VALUES.
+	fingerprintRows = regexp.MustCompile(`(\(\?\+?\))(?:, \(\?\+?\))+`)
+	// fingerprintIn matches an IN list of a single placeholder.
+	fingerprintIn = regexp.MustCompile(`\bin\(\?\)`)
+)
+
+// Fingerprint returns the normalized form of query, which is the same for
+// queries differing only in their parameters, and a stable hash of it, as
+// 16 hex digits, for use as metrics label, cache key or to aggregate slow
+// queries.
+//
+// Comments are dropped and tokens are separated by single spaces, except
+// around parentheses, brackets, dots and colons and before commas. Unquoted
+// words are lower cased, and string and number literals and placeholders
+// become ?. Lists of them, such as those of IN, become (?+), as does an IN
+// list of a single one, and the rows of a multi-row VALUES a single one:
+//
+//	SELECT * FROM users WHERE id IN ($1, $2, $3) AND name='bob'
+//	select * from users where id in(?+) and name = ?
+func Fingerprint(query string) (normalized, hash string) {
+	return FingerprintDialect("", query)
+}
+
+// FingerprintDialect is like Fingerprint for a query of dialect. On MySQL,
+// double quoted strings become ? and # starts a comment.
+func FingerprintDialect(dialect, query string) (normalized, hash string) {
+	normalized = strings.TrimRight(minify(query, dialect, true), ";")
+	normalized = fingerprintList.ReplaceAllString(normalized, "(?+)")
+	normalized = fingerprintIn.ReplaceAllString(normalized, "in(?+)")
+	normalized = fingerprintRows.ReplaceAllString(normalized, "$1")
+	h := fnv.New64a()
+	h.Write([]byte(normalized))
+	return normalized, fmt.Sprintf("%016x", h.Sum64())
+}
@@ -0,0 +1,345 @@
+package squealx
+
+import (
+	"strings"
+
+	"github.com/oarkflow/squealx/sqltoken"
+)
+
+// Style is the layout FormatSQL gives a query.
+type Style int
+
+const (
+	// StylePretty lays a query out over several lines for reading: keywords
+	// are upper cased, clauses start a line, conditions joined by AND and OR
+	// a line indented under their clause, and subqueries are indented.
+	StylePretty Style = iota
+	// StyleCompact puts a query on a single line with its keywords upper
+	// cased and tokens separated by single spaces, for logs.
+	StyleCompact
+	// StyleMinify drops everything but the tokens of a query, separated by
+	// as few spaces as Fingerprint uses, keeping their case; it is the
+	// form Fingerprint normalizes.
+	StyleMinify
+)
+
+// formatConfig tokenizes the queries of every supported dialect well enough
+// to lay them out.
+var formatConfig = func() sqltoken.Config {
+	config := fingerprintConfig
+	config.NoticeColonWord = true
+	return config
+}()
+
+// FormatSQL returns query laid out in style. The result only depends on the
+// tokens of query, not on its whitespace or comments, so it is stable across
+// runs and suitable for logs, diagnostics and golden files. Comments are
+// dropped, except optimizer hints with StylePretty and StyleCompact.
+//
+//	FormatSQL("select id,name from users where id=$1 and name like 'a%'", StylePretty)
+//
+//	SELECT id, name
+//	FROM users
+//	WHERE id = $1
+//	  AND name LIKE 'a%'
+func FormatSQL(query string, style Style) string {
+	if style == StyleMinify {
+		return minify(query, "", false)
+	}
+	f := &formatter{pretty: style == StylePretty, frames: []formatFrame{{subquery: true}}, start: true}
+	tokens := formatTokens(query)
+	for i, t := range tokens {
+		f.token(t, tokens[i+1:])
+	}
+	return strings.TrimSpace(f.b.String())
+}
+
+// minify writes the tokens of query, without comments, separated by single
+// spaces except after opening parentheses and brackets, dots and colons and
+// before closing ones, commas and semicolons. With normalize, it returns the
+// form of FingerprintDialect before lists are collapsed: words are lower
+// cased, literals and placeholders become ? and punctuation is split into
+// single characters.
+func minify(query, dialect string, normalize bool) string {
+	var b strings.Builder
+	var last string
+	write := func(s string) {
+		if last == "," || last != "" && !strings.Contains("(.[:", last) && last != "::" &&
+			!strings.Contains("().,;[]:", s) && s != "::" {
+			b.WriteByte(' ')
+		}
+		last = s
+		b.WriteString(s)
+	}
+	config := formatConfig
+	// MySQL strings may be double quoted, unless in ANSI_QUOTES mode.
+	mysql := dialect == DialectMySQL
+	if normalize {
+		config = fingerprintConfig
+		if mysql {
+			config = fingerprintMySQLConfig
+		}
+	}
+	for _, t := range sqltoken.Tokenize(query, config) {
+		switch t.Type {
+		case sqltoken.Comment, sqltoken.Whitespace:
+		case sqltoken.Number, sqltoken.QuestionMark, sqltoken.DollarNumber, sqltoken.AtWord:
+			if normalize {
+				write("?")
+			} else {
+				write(t.Text)
+			}
+		case sqltoken.Literal:
+			// Strings end with a single quote, dollar quoted ones start
+			// with a dollar and quoted identifiers end with another quote.
+			if normalize && (strings.HasSuffix(t.Text, "'") || strings.HasPrefix(t.Text, "$") ||
+				mysql && strings.HasPrefix(t.Text, `"`)) {
+				write("?")
+			} else {
+				write(t.Text)
+			}
+		case sqltoken.Word:
+			if normalize {
+				write(strings.ToLower(t.Text))
+			} else {
+				write(t.Text)
+			}
+		case sqltoken.Punctuation:
+			if !normalize {
+				for _, s := range splitPunctuation(t.Text) {
+					write(s)
+				}
+				continue
+			}
+			// Adjacent punctuation is a single token, such as "),(".
+			for _, r := range t.Text {
+				write(string(r))
+			}
+		case sqltoken.Semicolon:
+			write(";")
+		default:
+			write(t.Text)
+		}
+	}
+	return b.String()
+}
+
+// splitPunctuation splits adjacent punctuation, such as "),(", into
+// parentheses, brackets, commas and dots and the operators between them,
+// such as "<>" or "::".
+func splitPunctuation(s string) []string {
+	var parts []string
+	start := 0
+	for i, r := range s {
+		if strings.ContainsRune("(),.[]", r) {
+			if start < i {
+				parts = append(parts, s[start:i])
+			}
+			parts = append(parts, string(r))
+			start = i + 1
+		}
+	}
+	if start < len(s) {
+		parts = append(parts, s[start:])
+	}
+	return parts
+}
+
+// formatToken is a significant token of a query being formatted; keywords
+// are upper cased.
+type formatToken struct {
+	typ     sqltoken.TokenType
+	text    string
+	keyword bool
+}
+
+func formatTokens(query string) []formatToken {
+	var tokens []formatToken
+	for _, t := range sqltoken.Tokenize(query, formatConfig) {
+		switch t.Type {
+		case sqltoken.Whitespace:
+		case sqltoken.Comment:
+			if strings.HasPrefix(t.Text, "/*+") {
+				tokens = append(tokens, formatToken{typ: t.Type, text: t.Text})
+			}
+		case sqltoken.Punctuation:
+			for _, s := range splitPunctuation(t.Text) {
+				tokens = append(tokens, formatToken{typ: t.Type, text: s})
+			}
+		case sqltoken.Word:
+			if upper := strings.ToUpper(t.Text); sqlKeywords[upper] {
+				tokens = append(tokens, formatToken{typ: t.Type, text: upper, keyword: true})
+			} else {
+				tokens = append(tokens, formatToken{typ: t.Type, text: t.Text})
+			}
+		default:
+			tokens = append(tokens, formatToken{typ: t.Type, text: t.Text})
+		}
+	}
+	return tokens
+}
+
+// sqlKeywords are the words FormatSQL upper cases. Function names are left
+// as written.
+var sqlKeywords = func() map[string]bool {
+	keywords := map[string]bool{}
+	for _, word := range strings.Fields(`ADD ALL ALTER AND ANALYZE AS ASC BEGIN BETWEEN BY
+		CASCADE CASE CHECK COLUMN COMMIT CONFLICT CONSTRAINT CREATE CROSS DEFAULT DELETE
+		DESC DISTINCT DO DROP DUPLICATE ELSE END ESCAPE EXCEPT EXISTS EXPLAIN FALSE FETCH
+		FOREIGN FROM FULL GROUP HAVING IF ILIKE IN INDEX INNER INSERT INTERSECT INTO IS
+		JOIN KEY LATERAL LEFT LIKE LIMIT LOCKED NATURAL NOT NOTHING NOWAIT NULL NULLS
+		OFFSET ON ONLY OR ORDER OUTER OVER PARTITION PRIMARY RECURSIVE REFERENCES
+		RETURNING RIGHT ROLLBACK SELECT SET SKIP TABLE THEN TRUE TRUNCATE UNION UNIQUE
+		UPDATE USING VALUES VIEW WHEN WHERE WINDOW WITH`) {
+		keywords[word] = true
+	}
+	return keywords
+}()
+
+// clauseKeywords start a line with StylePretty, when they are not within
+// parentheses other than those of a subquery.
+var clauseKeywords = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "GROUP": true, "ORDER": true,
+	"HAVING": true, "WINDOW": true, "LIMIT": true, "OFFSET": true, "FETCH": true,
+	"UNION": true, "INTERSECT": true, "EXCEPT": true, "INSERT": true, "VALUES": true,
+	"UPDATE": true, "SET": true, "DELETE": true, "RETURNING": true, "ON": true,
+	"JOIN": true, "INNER": true, "LEFT": true, "RIGHT": true, "FULL": true,
+	"CROSS": true, "NATURAL": true,
+}
+
+// formatFrame is a level of parentheses of a query being formatted.
+type formatFrame struct {
+	// subquery is set for the parentheses of a subquery, and for the
+	// statement itself, whose clauses start lines.
+	subquery bool
+	indent   int
+	// cases counts the CASE expressions open, whose conditions stay on a
+	// line; between is set after BETWEEN, whose AND does too.
+	cases   int
+	between bool
+}
+
+type formatter struct {
+	b      strings.Builder
+	pretty bool
+	frames []formatFrame
+	// last is the last token written, start is set at the start of a
+	// statement and a line.
+	last  formatToken
+	start bool
+}
+
+func (f *formatter) frame() *formatFrame {
+	return &f.frames[len(f.frames)-1]
+}
+
+func (f *formatter) newline(indent int) {
+	if !f.pretty {
+		return
+	}
+	f.b.WriteByte('\n')
+	f.b.WriteString(strings.Repeat("  ", indent))
+	f.start = true
+}
+
+func (f *formatter) token(t formatToken, next []formatToken) {
+	frame := f.frame()
+	switch {
+	case t.keyword && frame.subquery && frame.cases == 0 && f.breaks(t, next):
+		if !f.start {
+			f.newline(frame.indent)
+		}
+	case t.keyword && (t.text == "AND" || t.text == "OR") && frame.subquery && frame.cases == 0:
+		if frame.between && t.text == "AND" {
+			frame.between = false
+		} else {
+			f.newline(frame.indent + 1)
+		}
+	case t.text == ")" && t.typ == sqltoken.Punctuation && len(f.frames) > 1:
+		f.frames = f.frames[:len(f.frames)-1]
+		if frame.subquery {
+			f.newline(f.frame().indent)
+		}
+	}
+	f.write(t)
+	switch {
+	case t.typ == sqltoken.Semicolon:
+		f.frames = []formatFrame{{subquery: true}}
+		f.newline(0)
+	case t.text == "(" && t.typ == sqltoken.Punctuation:
+		subquery := len(next) > 0 && (next[0].text == "SELECT" || next[0].text == "WITH")
+		indent := frame.indent
+		if subquery {
+			indent++
+		}
+		f.frames = append(f.frames, formatFrame{subquery: subquery, indent: indent})
+	case t.text == "CASE":
+		frame.cases++
+	case t.text == "END" && frame.cases > 0:
+		frame.cases--
+	case t.text == "BETWEEN":
+		frame.between = true
+	}
+}
+
+// breaks reports whether the keyword t starts a line, at the level of a
+// statement or subquery.
+func (f *formatter) breaks(t formatToken, next []formatToken) bool {
+	if !clauseKeywords[t.text] {
+		return false
+	}
+	last := ""
+	if f.last.keyword {
+		last = f.last.text
+	}
+	switch t.text {
+	case "JOIN":
+		return !strings.Contains(" INNER LEFT RIGHT FULL OUTER CROSS NATURAL ", " "+last+" ")
+	case "INNER", "LEFT", "RIGHT", "FULL", "CROSS":
+		return last != "NATURAL"
+	case "ON":
+		// ON CONFLICT and ON DUPLICATE KEY UPDATE, not the ON of a join.
+		return len(next) > 0 && (next[0].text == "CONFLICT" || next[0].text == "DUPLICATE")
+	case "FROM":
+		return last != "DELETE" && last != "DISTINCT"
+	case "UPDATE", "DELETE":
+		return last != "ON" && last != "DO" && last != "FOR" && last != "KEY"
+	case "SET":
+		return len(next) == 0 || next[0].text != "NULL" && next[0].text != "DEFAULT"
+	case "UNION", "INTERSECT", "EXCEPT":
+		return true
+	case "GROUP", "ORDER":
+		return len(next) > 0 && next[0].text == "BY"
+	}
+	return true
+}
+
+func (f *formatter) write(t formatToken) {
+	if !f.start && f.space(t) {
+		f.b.WriteByte(' ')
+	}
+	f.b.WriteString(t.text)
+	f.last = t
+	f.start = false
+}
+
+// space reports whether a space separates t from the last token written.
+func (f *formatter) space(t formatToken) bool {
+	last := f.last
+	if last.typ == sqltoken.Punctuation && (last.text == "(" || last.text == "[" || last.text == "." || last.text == "::") {
+		return false
+	}
+	if t.typ == sqltoken.Semicolon {
+		return false
+	}
+	if t.typ == sqltoken.Punctuation {
+		switch t.text {
+		case ")", "]", ",", ".", "::":
+			return false
+		case "(", "[":
+			// Calls and subscripts follow their function or value.
+			return last.keyword || last.typ != sqltoken.Word && last.text != ")" && last.text != "]"
+		}
+	}
+	return true
+}
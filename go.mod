@@ -8,6 +8,7 @@ require (
 	github.com/microsoft/go-mssqldb v1.8.0
 	github.com/oarkflow/jet v0.0.4
 	github.com/oarkflow/log v1.0.79
+	golang.org/x/sync v0.10.0
 	modernc.org/sqlite v1.34.4
 )
 
@@ -25,7 +26,6 @@ require (
 	github.com/ncruces/go-strftime v0.1.9 // indirect
 	github.com/remyoudompheng/bigfft v0.0.0-20230129092748-24d4a6f8daec // indirect
 	golang.org/x/crypto v0.31.0 // indirect
-	golang.org/x/sync v0.10.0 // indirect
 	golang.org/x/sys v0.28.0 // indirect
 	golang.org/x/text v0.21.0 // indirect
 	modernc.org/gc/v3 v3.0.0-20240107210532-573471604cb6 // indirect
@@ -0,0 +1,112 @@
+package squealx
+
+import (
+	"context"
+	"strings"
+
+	"github.com/oarkflow/squealx/sqltoken"
+)
+
+type hintsKey struct{}
+
+// hintDialects maps the prefixes restricting a hint to a dialect to it.
+var hintDialects = map[string]string{
+	"mysql":        DialectMySQL,
+	"postgres":     DialectPostgres,
+	"pg_hint_plan": DialectPostgres,
+	"mssql":        DialectMSSQL,
+}
+
+// WithHints returns a context under which the statements of a DB carry the
+// optimizer hints hints, injected where their database expects them:
+//
+//   - MySQL hints, such as "MAX_EXECUTION_TIME(1000)", in a /*+ */ comment
+//     after the SELECT, INSERT, UPDATE or DELETE keyword;
+//   - pg_hint_plan hints, such as "IndexScan(users)", in a /*+ */ comment
+//     heading the statement;
+//   - SQL Server query hints, such as "RECOMPILE", in an OPTION clause
+//     ending SELECT, INSERT, UPDATE and DELETE statements, the only ones
+//     accepting it; others, such as DDL, are left alone.
+//
+// A hint prefixed with "mysql:", "postgres:", "pg_hint_plan:" or "mssql:"
+// only applies to that database, so that one context may carry hints for
+// several. Hints add to those of ctx. SQLite has no hints and ignores them.
+func WithHints(ctx context.Context, hints ...string) context.Context {
+	all := append(HintsFromContext(ctx), hints...)
+	return context.WithValue(ctx, hintsKey{}, all[:len(all):len(all)])
+}
+
+// HintsFromContext returns the hints set on ctx with WithHints.
+func HintsFromContext(ctx context.Context) []string {
+	hints, _ := ctx.Value(hintsKey{}).([]string)
+	return hints
+}
+
+// HintRewriter returns the QueryRewriter injecting the hints of WithHints.
+// Every DB applies it after its own rewriters, so that hints heading the
+// statement stay first; it is exported for wrappers of other databases.
+func HintRewriter() QueryRewriter {
+	return QueryRewriterFunc(func(ctx context.Context, stmt Statement) (Statement, error) {
+		hints := dialectHints(stmt.Dialect, HintsFromContext(ctx))
+		if len(hints) == 0 {
+			return stmt, nil
+		}
+		switch stmt.Dialect {
+		case DialectMySQL:
+			stmt.Query = injectAfterVerb(stmt, "/*+ "+strings.Join(hints, " ")+" */")
+		case DialectPostgres:
+			stmt.Query = "/*+ " + strings.Join(hints, " ") + " */ " + stmt.Query
+		case DialectMSSQL:
+			switch kind, _ := ClassifyStatement(stmt.Query); kind {
+			case StatementSelect, StatementInsert, StatementUpdate, StatementDelete:
+				query := strings.TrimRight(strings.TrimSpace(stmt.Query), ";")
+				stmt.Query = query + " OPTION (" + strings.Join(hints, ", ") + ")"
+			}
+		}
+		return stmt, nil
+	})
+}
+
+var hintRewriter = HintRewriter()
+
+// dialectHints returns the hints applying to dialect, without their prefix.
+func dialectHints(dialect string, hints []string) []string {
+	var applied []string
+	for _, hint := range hints {
+		if prefix, rest, ok := strings.Cut(hint, ":"); ok {
+			if target, ok := hintDialects[strings.TrimSpace(prefix)]; ok {
+				if target != dialect {
+					continue
+				}
+				hint = rest
+			}
+		}
+		if hint = commentReplacer.Replace(strings.TrimSpace(hint)); hint != "" {
+			applied = append(applied, hint)
+		}
+	}
+	return applied
+}
+
+// injectAfterVerb returns the query of stmt with comment inserted after its
+// first SELECT, INSERT, REPLACE, UPDATE or DELETE keyword, where MySQL reads
+// optimizer hints.
+func injectAfterVerb(stmt Statement, comment string) string {
+	var b strings.Builder
+	injected := false
+	for _, t := range stmt.Tokens() {
+		b.WriteString(t.Text)
+		if injected || t.Type != sqltoken.Word {
+			continue
+		}
+		switch strings.ToUpper(t.Text) {
+		case "SELECT", "INSERT", "REPLACE", "UPDATE", "DELETE":
+			b.WriteString(" " + comment)
+			injected = true
+		}
+	}
+	if !injected {
+		return stmt.Query
+	}
+	return b.String()
+}
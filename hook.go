@@ -2,6 +2,11 @@ package squealx
 
 import (
 	"context"
+	"fmt"
+	"runtime/debug"
+	"slices"
+	"sync"
+	"sync/atomic"
 )
 
 // Hook is the hook callback signature
@@ -21,3 +26,183 @@ type AfterHook interface {
 type ErrorerHook interface {
 	OnError(ctx context.Context, err error, query string, args ...interface{}) error
 }
+
+// HookPhase identifies the hook chain an error came from.
+type HookPhase string
+
+const (
+	HookPhaseBefore HookPhase = "before"
+	HookPhaseAfter  HookPhase = "after"
+	HookPhaseError  HookPhase = "error"
+)
+
+// HookFailureMode controls what happens when a hook returns an error.
+type HookFailureMode int
+
+const (
+	// FailClosed aborts: a before hook error stops the query and an after
+	// hook error is returned to the caller. This is the default.
+	FailClosed HookFailureMode = iota
+	// FailOpen ignores before hook errors and runs the query anyway. For
+	// after hooks it is the same as ObserveOnly.
+	FailOpen
+	// ObserveOnly ignores after hook errors so they never override a
+	// successful result.
+	ObserveOnly
+)
+
+// HookPolicy configures how hook errors affect query execution.
+//
+// Hooks always run in registration order. Before hooks are chained, each
+// receiving the context returned by the previous one; after hooks receive
+// the context produced by the before hooks; every error hook runs and
+// receives the original query error.
+type HookPolicy struct {
+	Before HookFailureMode
+	After  HookFailureMode
+	// Panic decides whether a panicking hook fails the query (FailClosed)
+	// or is skipped (FailOpen). Panics are always recovered.
+	Panic HookFailureMode
+	// OnIgnored, if set, receives hook errors discarded by FailOpen or
+	// ObserveOnly.
+	OnIgnored func(ctx context.Context, err *HookError, query string)
+}
+
+// HookError reports an error returned by a hook. Cause holds the query error
+// for error hooks, so errors.Is and errors.As match either of them.
+type HookError struct {
+	Phase HookPhase
+	Err   error
+	Cause error
+}
+
+func (e *HookError) Error() string {
+	if e.Cause != nil {
+		return fmt.Sprintf("squealx: %s hook: %v (query error: %v)", e.Phase, e.Err, e.Cause)
+	}
+	return fmt.Sprintf("squealx: %s hook: %v", e.Phase, e.Err)
+}
+
+func (e *HookError) Unwrap() []error {
+	if e.Cause != nil {
+		return []error{e.Err, e.Cause}
+	}
+	return []error{e.Err}
+}
+
+// HookPanic is the error produced when a hook panics. Stack is captured at
+// the point of recovery.
+type HookPanic struct {
+	Phase HookPhase
+	Value any
+	Stack []byte
+}
+
+func (p *HookPanic) Error() string {
+	return fmt.Sprintf("squealx: %s hook panicked: %v", p.Phase, p.Value)
+}
+
+// callHook runs call, converting a panic into a *HookPanic error.
+func callHook(phase HookPhase, call func() (context.Context, error)) (ctx context.Context, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			ctx, err = nil, &HookPanic{Phase: phase, Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return call()
+}
+
+type namedHook[T any] struct {
+	name string
+	hook T
+}
+
+type skipHooksKey struct{}
+
+// WithoutHooks returns a context under which hooks registered with UseNamed
+// under any of names are not run. Without names, every hook is skipped,
+// which suits health checks and migrations that must not be traced or
+// audited.
+func WithoutHooks(ctx context.Context, names ...string) context.Context {
+	skip := map[string]bool{}
+	if prev, ok := ctx.Value(skipHooksKey{}).(map[string]bool); ok {
+		for name := range prev {
+			skip[name] = true
+		}
+	}
+	if len(names) == 0 {
+		skip["*"] = true
+	}
+	for _, name := range names {
+		skip[name] = true
+	}
+	return context.WithValue(ctx, skipHooksKey{}, skip)
+}
+
+// hookSkipped reports whether the hook registered under name is disabled
+// for ctx.
+func hookSkipped(ctx context.Context, name string) bool {
+	skip, ok := ctx.Value(skipHooksKey{}).(map[string]bool)
+	if !ok {
+		return false
+	}
+	return skip["*"] || (name != "" && skip[name])
+}
+
+// hookSet is an immutable snapshot of the hooks of a DB.
+type hookSet struct {
+	before       []namedHook[Hook]
+	after        []namedHook[Hook]
+	onError      []namedHook[ErrorHook]
+	tx           []namedHook[any]
+	transformers []RowTransformer
+	rewriters    []QueryRewriter
+	policy       HookPolicy
+	onPanic      func(recovered any, query string)
+}
+
+var emptyHookSet = &hookSet{}
+
+// hookRegistry holds the hooks of a DB. Registration copies the current
+// set, changes the copy and publishes it, so queries read their hooks
+// without locking while hooks are being registered concurrently.
+type hookRegistry struct {
+	mu  sync.Mutex
+	set atomic.Pointer[hookSet]
+}
+
+// load returns the current hooks. The set must not be modified.
+func (r *hookRegistry) load() *hookSet {
+	if r == nil {
+		return emptyHookSet
+	}
+	if s := r.set.Load(); s != nil {
+		return s
+	}
+	return emptyHookSet
+}
+
+// update publishes the set produced by fn from a copy of the current one.
+// The slices of the copy are clipped, so appending to them never writes to
+// the arrays of the published set.
+func (r *hookRegistry) update(fn func(s *hookSet)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	next := *r.load()
+	next.before = slices.Clip(next.before)
+	next.after = slices.Clip(next.after)
+	next.onError = slices.Clip(next.onError)
+	next.tx = slices.Clip(next.tx)
+	next.transformers = slices.Clip(next.transformers)
+	next.rewriters = slices.Clip(next.rewriters)
+	fn(&next)
+	r.set.Store(&next)
+}
+
+// fork returns a registry starting with the hooks of r, whose later
+// registrations are independent of r.
+func (r *hookRegistry) fork() *hookRegistry {
+	f := &hookRegistry{}
+	f.set.Store(r.load())
+	return f
+}
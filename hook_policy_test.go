@@ -0,0 +1,42 @@
+package squealx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/oarkflow/squealx"
+	_ "modernc.org/sqlite"
+)
+
+func TestHookPolicyAfter(t *testing.T) {
+	for _, c := range []struct {
+		mode    squealx.HookFailureMode
+		fails   bool
+		ignored int
+	}{
+		{squealx.FailClosed, true, 0},
+		{squealx.FailOpen, false, 1},
+		{squealx.ObserveOnly, false, 1},
+	} {
+		db := openTestDB(t)
+		ignored := 0
+		db.SetHookPolicy(squealx.HookPolicy{
+			After: c.mode,
+			OnIgnored: func(ctx context.Context, err *squealx.HookError, query string) {
+				ignored++
+			},
+		})
+		hookErr := errors.New("after")
+		db.UseAfter(func(ctx context.Context, query string, args ...any) (context.Context, error) {
+			return ctx, hookErr
+		})
+		_, err := db.ExecContext(context.Background(), "SELECT 1")
+		if failed := errors.Is(err, hookErr); failed != c.fails {
+			t.Errorf("mode %d: error = %v, want failed %t", c.mode, err, c.fails)
+		}
+		if ignored != c.ignored {
+			t.Errorf("mode %d: %d errors ignored, want %d", c.mode, ignored, c.ignored)
+		}
+	}
+}
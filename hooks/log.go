@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/oarkflow/log"
+	"github.com/oarkflow/squealx"
+	"github.com/oarkflow/squealx/sqlctx"
 )
 
 type Notifier func(query string, args []any, latency string)
@@ -44,8 +46,10 @@ func (h *Hook) After(ctx context.Context, query string, args ...any) (context.Co
 	if h.logSlowQuery {
 		if since > h.duration {
 			h.logger.Warn().
-				Str("query", query).
+				Str("query", squealx.FormatSQL(query, squealx.StyleCompact)).
+				Str("fingerprint", fingerprint(ctx, query)).
 				Any("arguments", args).
+				Any("context", contextFields(ctx)).
 				Str("latency", fmt.Sprintf("%s", since)).
 				Msg("Slow query")
 			if h.notify != nil {
@@ -54,8 +58,10 @@ func (h *Hook) After(ctx context.Context, query string, args ...any) (context.Co
 		}
 	} else {
 		h.logger.Info().
-			Str("query", query).
+			Str("query", squealx.FormatSQL(query, squealx.StyleCompact)).
+			Str("fingerprint", fingerprint(ctx, query)).
 			Any("arguments", args).
+			Any("context", contextFields(ctx)).
 			Str("latency", fmt.Sprintf("%s", since)).
 			Msg("Query log")
 	}
@@ -63,10 +69,52 @@ func (h *Hook) After(ctx context.Context, query string, args ...any) (context.Co
 }
 
 func (h *Hook) OnError(ctx context.Context, err error, query string, args ...any) error {
+	if h.logger == nil {
+		return err
+	}
 	h.logger.Error().
 		Err(err).
-		Str("query", query).
+		Str("query", squealx.FormatSQL(query, squealx.StyleCompact)).
+		Str("fingerprint", fingerprint(ctx, query)).
 		Any("arguments", args).
+		Any("context", contextFields(ctx)).
 		Msg("Error on query")
 	return err
 }
+
+// fingerprint returns the hash of squealx.FingerprintDialect for query, in
+// the dialect of the driver carried by ctx, so that the runs of a query can
+// be aggregated whatever their arguments.
+func fingerprint(ctx context.Context, query string) string {
+	driver, _ := sqlctx.DriverNameFromContext(ctx)
+	_, hash := squealx.FingerprintDialect(squealx.Dialect(driver), query)
+	return hash
+}
+
+// contextFields returns the values of sqlctx carried by ctx, or nil when
+// there are none.
+func contextFields(ctx context.Context) map[string]any {
+	var fields map[string]any
+	set := func(key string, value any) {
+		if fields == nil {
+			fields = map[string]any{}
+		}
+		fields[key] = value
+	}
+	if driver, ok := sqlctx.DriverNameFromContext(ctx); ok {
+		set("driver", driver)
+	}
+	if id, ok := sqlctx.DBIDFromContext(ctx); ok {
+		set("db", id)
+	}
+	if tx, ok := sqlctx.TxInfoFromContext(ctx); ok {
+		set("tx", tx.ID)
+	}
+	if tenant, ok := sqlctx.TenantFromContext(ctx); ok {
+		set("tenant", tenant)
+	}
+	if tags := sqlctx.QueryTagsFromContext(ctx); len(tags) > 0 {
+		set("tags", tags)
+	}
+	return fields
+}
@@ -0,0 +1,130 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/oarkflow/squealx"
+)
+
+// ErrRateLimited is returned for write statements run past the limit of a
+// RateLimitHook.
+var ErrRateLimited = errors.New("hooks: rate limited")
+
+// Limiter decides whether a statement keyed by key may run now.
+type Limiter interface {
+	Allow(key string) bool
+}
+
+// TokenBucket is a Limiter holding a token bucket per key, refilled at a
+// rate per second up to a burst. Limits can be changed at runtime, for all
+// keys or for individual ones.
+type TokenBucket struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	limits  map[string][2]float64
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket returns a TokenBucket allowing rate statements per second
+// per key, in bursts of up to burst.
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	return &TokenBucket{
+		rate:    rate,
+		burst:   float64(burst),
+		limits:  map[string][2]float64{},
+		buckets: map[string]*bucket{},
+	}
+}
+
+// SetLimit changes the limit of the keys without a limit of their own.
+func (t *TokenBucket) SetLimit(rate float64, burst int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rate, t.burst = rate, float64(burst)
+}
+
+// SetKeyLimit sets the limit of key, a table with PerTable.
+func (t *TokenBucket) SetKeyLimit(key string, rate float64, burst int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.limits[key] = [2]float64{rate, float64(burst)}
+}
+
+// Allow takes a token from the bucket of key, reporting whether there was
+// one.
+func (t *TokenBucket) Allow(key string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	rate, burst := t.rate, t.burst
+	if limit, ok := t.limits[key]; ok {
+		rate, burst = limit[0], limit[1]
+	}
+	now := time.Now()
+	b, ok := t.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		t.buckets[key] = b
+	}
+	b.tokens = min(burst, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// RateLimitHook is a before hook limiting the INSERT, UPDATE and DELETE
+// statements of a DB, to keep bulk jobs from saturating a shared database.
+// Register one per DB to limit each separately. Reads are never limited.
+type RateLimitHook struct {
+	limiter  Limiter
+	perTable bool
+}
+
+// RateLimitOption configures a RateLimitHook.
+type RateLimitOption func(*RateLimitHook)
+
+// PerTable keys the limiter by the table written to rather than by DB.
+func PerTable() RateLimitOption {
+	return func(h *RateLimitHook) {
+		h.perTable = true
+	}
+}
+
+// RateLimiter returns a hook failing write statements with ErrRateLimited
+// when limiter does not allow them. Without PerTable, every statement is
+// keyed by "".
+func RateLimiter(limiter Limiter, opts ...RateLimitOption) *RateLimitHook {
+	h := &RateLimitHook{limiter: limiter}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+func (h *RateLimitHook) Before(ctx context.Context, query string, args ...any) (context.Context, error) {
+	kind, table := squealx.ClassifyStatement(query)
+	switch kind {
+	case squealx.StatementInsert, squealx.StatementUpdate, squealx.StatementDelete:
+	default:
+		return ctx, nil
+	}
+	key := ""
+	if h.perTable {
+		key = table
+	}
+	if !h.limiter.Allow(key) {
+		return ctx, ErrRateLimited
+	}
+	return ctx, nil
+}
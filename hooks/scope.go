@@ -0,0 +1,462 @@
+package hooks
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/oarkflow/squealx"
+	"github.com/oarkflow/squealx/sqlctx"
+	"github.com/oarkflow/squealx/sqlparse"
+	"github.com/oarkflow/squealx/sqltoken"
+)
+
+var (
+	// ErrScopeViolation is returned for statements setting another scope
+	// than the one of their context.
+	ErrScopeViolation = errors.New("hooks: scope violation")
+	// ErrUnscoped is returned in strict mode for statements on scoped tables
+	// whose scope cannot be resolved or verified.
+	ErrUnscoped = errors.New("hooks: statement cannot be scoped")
+)
+
+// ScopeRule scopes the rows of Table by Column, such as tenant_id.
+type ScopeRule struct {
+	Table  string `json:"table"`
+	Column string `json:"column"`
+}
+
+// ScopeResolver returns the scope value of the statements run under ctx,
+// such as the tenant it carries, and false when there is none.
+type ScopeResolver func(ctx context.Context) (any, bool)
+
+// TenantScope is a ScopeResolver scoping statements by the tenant set with
+// sqlctx.WithTenant, or tenantdb.WithTenant.
+func TenantScope(ctx context.Context) (any, bool) {
+	return sqlctx.TenantFromContext(ctx)
+}
+
+// ResourceScopeHook is a squealx.QueryRewriter scoping the statements on
+// scoped tables to the value of the resolver. Register it with
+// DB.UseRewriter.
+//
+// The rows of scoped tables read, updated or deleted are filtered by a
+// predicate on their scope column, added to the join condition of tables
+// joined with ON and to the WHERE clause of their query otherwise. INSERTs
+// into a scoped table get the scope column added to their column list, for
+// every row of VALUES or for the rows of their SELECT source, and the value
+// they set is verified when they list it, as it is in the SET lists of
+// UPDATE and ON CONFLICT DO UPDATE, whose rows are filtered too.
+//
+// The tables of a statement are found at every depth, in common table
+// expressions and lateral subqueries too. Statements it cannot scope, such
+// as an INSERT without column list or with ON DUPLICATE KEY UPDATE, whose
+// updated rows cannot be filtered, statements reading from table functions,
+// and statements run without scope pass unchanged unless the hook is
+// Strict. Stats counts its decisions, and AuditScope reports them.
+type ResourceScopeHook struct {
+	rules     map[string]ScopeRule
+	resolve   ScopeResolver
+	strict    bool
+	audit     squealx.AuditSink
+	sample    float64
+	collector ScopeCollector
+	mu        sync.Mutex
+	counts    map[scopeKey]uint64
+}
+
+// ScopeOption configures a ResourceScopeHook.
+type ScopeOption func(*ResourceScopeHook)
+
+// Strict fails with ErrUnscoped the statements on scoped tables the hook
+// cannot scope, and those reading from table functions, whose tables are
+// unknown, and with ErrScopeViolation those setting another scope.
+func Strict() ScopeOption {
+	return func(h *ResourceScopeHook) {
+		h.strict = true
+	}
+}
+
+// ResourceScope returns a hook scoping the tables of rules by the value of
+// resolve. Tables are matched case-insensitively, by name or by qualified
+// name. Rules can be loaded with ScopeRulesFromConfig or derived from the
+// schema with ScopeRulesFromSchema.
+func ResourceScope(resolve ScopeResolver, rules []ScopeRule, opts ...ScopeOption) *ResourceScopeHook {
+	h := &ResourceScopeHook{
+		rules:   make(map[string]ScopeRule, len(rules)),
+		resolve: resolve,
+		counts:  map[scopeKey]uint64{},
+	}
+	for _, rule := range rules {
+		h.rules[strings.ToLower(rule.Table)] = rule
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// insertion is text inserted in a query at offset, binding the scope to the
+// number of placeholders args it has.
+type insertion struct {
+	offset int
+	text   string
+	args   int
+}
+
+// Rewrite scopes stmt when it references a scoped table.
+func (h *ResourceScopeHook) Rewrite(ctx context.Context, stmt squealx.Statement) (squealx.Statement, error) {
+	scoped, decision, err := h.rewrite(ctx, stmt)
+	if decision != ScopePassthrough {
+		h.record(ctx, stmt, decision, err)
+	}
+	return scoped, err
+}
+
+func (h *ResourceScopeHook) rewrite(ctx context.Context, stmt squealx.Statement) (squealx.Statement, ScopeDecision, error) {
+	tokens := sqlparse.Tokens(stmt.Tokens())
+	parsed := sqlparse.ParseTokens(tokens)
+	// The tables of the statement are those of the database it references
+	// at every depth, in its common table expressions and lateral
+	// subqueries too, while the references to its common table expressions
+	// are scoped through their bodies.
+	var scoped []sqlparse.Table
+	for _, t := range parsed.Tables {
+		if _, ok := h.rule(t); ok && !t.CTE {
+			scoped = append(scoped, t)
+		}
+	}
+	// The tables read by table functions are unknown, and strict hooks
+	// cannot tell whether they are scoped.
+	if len(scoped) == 0 && !(h.strict && parsed.Opaque) {
+		return stmt, ScopePassthrough, nil
+	}
+	if scopeBypassed(ctx) {
+		return stmt, ScopeBypassed, nil
+	}
+	// The scope of a prepared statement cannot be bound, nor follow the
+	// context of its executions.
+	if stmt.Prepared || parsed.Opaque {
+		return h.unscoped(stmt)
+	}
+	scope, ok := h.resolve(ctx)
+	if !ok {
+		return h.unscoped(stmt)
+	}
+	var inserts []insertion
+	target, ok := insertTarget(parsed)
+	if parsed.Kind == sqlparse.Insert && ok {
+		if rule, ok := h.rule(target); ok {
+			ins, ok, err := h.insert(stmt, tokens, target, rule, scope)
+			switch {
+			case err != nil:
+				return stmt, ScopeRejected, err
+			case !ok:
+				return h.unscoped(stmt)
+			}
+			inserts = ins
+		}
+	} else {
+		target = sqlparse.Table{}
+	}
+	// Every other scoped table is filtered by a predicate on its scope
+	// column, added to its join condition or to the WHERE clause of its
+	// query, and its scope column is verified in the SET list of UPDATEs.
+	columns := map[string]bool{}
+	for _, t := range scoped {
+		rule, _ := h.rule(t)
+		columns[strings.ToLower(rule.Column)] = true
+	}
+	var conditions []condition
+	predicates := map[condition][]string{}
+	updates := map[int]bool{}
+	placeholder := scopePlaceholder(stmt)
+	for _, t := range scoped {
+		if t == target {
+			continue
+		}
+		c, start, ok := tableCondition(tokens, t)
+		if !ok {
+			return h.unscoped(stmt)
+		}
+		if isWord(tokens[start], "UPDATE") && !updates[start] {
+			updates[start] = true
+			set := start
+			for set < len(tokens) && !(tokens[set].Depth == tokens[start].Depth && isWord(tokens[set], "SET")) {
+				set++
+			}
+			if set < len(tokens) {
+				if err := h.verifyAssignments(stmt, tokens, set, columns, scope); err != nil {
+					return stmt, ScopeRejected, err
+				}
+			}
+		}
+		if _, ok := predicates[c]; !ok {
+			conditions = append(conditions, c)
+		}
+		rule, _ := h.rule(t)
+		predicates[c] = append(predicates[c], t.Qualifier+"."+rule.Column+" = "+placeholder)
+	}
+	inserts = append(inserts, scopePredicates(conditions, predicates)...)
+	return applyInsertions(stmt, tokens, inserts, scope), ScopeScoped, nil
+}
+
+// insert scopes the INSERT statement stmt into target by rule, returning
+// the insertions adding the scope column, and false when it cannot.
+func (h *ResourceScopeHook) insert(stmt squealx.Statement, tokens []sqlparse.Token, target sqlparse.Table, rule ScopeRule, scope any) ([]insertion, bool, error) {
+	i := tokenAt(tokens, target.End)
+	if i >= len(tokens) || tokens[i].Text != "(" {
+		return nil, false, nil
+	}
+	columns, end := sqlparse.Items(tokens, i)
+	position := -1
+	for n, column := range columns {
+		if len(column) == 1 && strings.EqualFold(unquote(column[0].Text), rule.Column) {
+			position = n
+		}
+	}
+	i = end + 1
+	if i >= len(tokens) {
+		return nil, false, nil
+	}
+	placeholder := scopePlaceholder(stmt)
+	inserts := []insertion{{offset: tokens[end].Offset, text: ", " + rule.Column}}
+	switch tokens[i].Upper {
+	case "VALUES", "VALUE":
+		for i++; i < len(tokens) && tokens[i].Text == "("; i++ {
+			row, end := sqlparse.Items(tokens, i)
+			if position >= 0 {
+				if position >= len(row) {
+					return nil, false, nil
+				}
+				if err := h.verify(stmt, tokens, row[position], scope); err != nil {
+					return nil, false, err
+				}
+			} else {
+				inserts = append(inserts, insertion{offset: tokens[end].Offset, text: ", " + placeholder, args: 1})
+			}
+			if i = end + 1; i >= len(tokens) || tokens[i].Text != "," {
+				break
+			}
+		}
+	case "SELECT", "WITH", "(":
+		list, offset, ok := sqlparse.SelectList(tokens, i)
+		switch {
+		case !ok || position >= len(list):
+			return nil, false, nil
+		case position >= 0:
+			// The scope column is set by an expression of the SELECT list,
+			// which is only verified when it is a literal or a placeholder.
+			if err := h.verify(stmt, tokens, list[position], scope); err != nil {
+				return nil, false, err
+			}
+		default:
+			inserts = append(inserts, insertion{offset: offset, text: ", " + placeholder, args: 1})
+		}
+	default:
+		return nil, false, nil
+	}
+	if position >= 0 {
+		inserts = nil
+	}
+	// The rows updated on conflict must be of the scope too.
+	for i := range tokens {
+		if tokens[i].Depth != 0 || !isWord(tokens[i], "ON") || i+1 >= len(tokens) {
+			continue
+		}
+		switch {
+		case isWord(tokens[i+1], "DUPLICATE"):
+			// The rows updated by ON DUPLICATE KEY UPDATE cannot be
+			// filtered.
+			return nil, false, nil
+		case isWord(tokens[i+1], "CONFLICT"):
+			set := i
+			for set < len(tokens) && !isWord(tokens[set], "SET") {
+				set++
+			}
+			if set == len(tokens) {
+				// DO NOTHING.
+				break
+			}
+			if err := h.verifyAssignments(stmt, tokens, set, map[string]bool{strings.ToLower(rule.Column): true}, scope); err != nil {
+				return nil, false, err
+			}
+			c := whereCondition(tokens, set)
+			predicates := map[condition][]string{c: {target.Qualifier + "." + rule.Column + " = " + placeholder}}
+			inserts = append(inserts, scopePredicates([]condition{c}, predicates)...)
+		}
+	}
+	return inserts, true, nil
+}
+
+// insertTarget returns the table an INSERT statement inserts into, at the
+// top level.
+func insertTarget(stmt sqlparse.Statement) (sqlparse.Table, bool) {
+	for _, t := range stmt.Tables {
+		if t.Write && !t.Nested && !t.CTE {
+			return t, true
+		}
+	}
+	return sqlparse.Table{}, false
+}
+
+// rule returns the rule of table, matched by qualified name first.
+func (h *ResourceScopeHook) rule(table sqlparse.Table) (ScopeRule, bool) {
+	if table.Schema != "" {
+		if rule, ok := h.rules[strings.ToLower(table.Schema+"."+table.Name)]; ok {
+			return rule, true
+		}
+	}
+	rule, ok := h.rules[strings.ToLower(table.Name)]
+	return rule, ok
+}
+
+// tokenAt returns the index of the first of tokens at or after offset.
+func tokenAt(tokens []sqlparse.Token, offset int) int {
+	return sort.Search(len(tokens), func(i int) bool { return tokens[i].Offset >= offset })
+}
+
+// unscoped returns stmt unchanged, or ErrUnscoped in strict mode.
+func (h *ResourceScopeHook) unscoped(stmt squealx.Statement) (squealx.Statement, ScopeDecision, error) {
+	if h.strict {
+		return stmt, ScopeRejected, ErrUnscoped
+	}
+	return stmt, ScopeBypassed, nil
+}
+
+// verify checks that the expression item sets scope. Expressions that are
+// neither literals nor placeholders cannot be verified.
+func (h *ResourceScopeHook) verify(stmt squealx.Statement, tokens []sqlparse.Token, item []sqlparse.Token, scope any) error {
+	if len(item) > 2 && item[len(item)-2].Upper == "AS" {
+		item = item[:len(item)-2]
+	}
+	var value any
+	ok := len(item) == 1
+	if ok {
+		switch t := item[0]; {
+		case isPlaceholder(t):
+			value, ok = placeholderArg(stmt, tokens, t)
+		case t.Type == sqltoken.Number:
+			value = t.Text
+		case t.Type == sqltoken.Literal && strings.HasSuffix(t.Text, "'"):
+			value = strings.ReplaceAll(strings.Trim(t.Text, "'"), "''", "'")
+		default:
+			ok = false
+		}
+	}
+	switch {
+	case !h.strict:
+		return nil
+	case !ok:
+		return ErrUnscoped
+	case !sameScope(value, scope):
+		return ErrScopeViolation
+	}
+	return nil
+}
+
+func isPlaceholder(t sqlparse.Token) bool {
+	switch t.Type {
+	case sqltoken.QuestionMark, sqltoken.DollarNumber:
+		return true
+	case sqltoken.AtWord:
+		return strings.HasPrefix(strings.ToLower(t.Text), "@p")
+	}
+	return false
+}
+
+// placeholderArg returns the argument bound by the placeholder t of tokens.
+func placeholderArg(stmt squealx.Statement, tokens []sqlparse.Token, t sqlparse.Token) (any, bool) {
+	n := sqlparse.Arg(tokens, tokenAt(tokens, t.Offset))
+	if n < 1 || n > len(stmt.Args) {
+		return nil, false
+	}
+	return stmt.Args[n-1], true
+}
+
+// scopePlaceholder returns the placeholder binding the scope in stmt, an
+// argument appended to those of the statement.
+func scopePlaceholder(stmt squealx.Statement) string {
+	switch stmt.Dialect {
+	case squealx.DialectPostgres:
+		return "$" + strconv.Itoa(len(stmt.Args)+1)
+	case squealx.DialectMSSQL:
+		return "@p" + strconv.Itoa(len(stmt.Args)+1)
+	}
+	return "?"
+}
+
+// applyInsertions returns stmt with inserts applied, binding scope for the
+// placeholders inserted. Numbered placeholders all bind a single argument
+// appended to those of stmt, while ? placeholders bind an argument each, at
+// their rank in the query.
+func applyInsertions(stmt squealx.Statement, tokens []sqlparse.Token, inserts []insertion, scope any) squealx.Statement {
+	sort.SliceStable(inserts, func(i, j int) bool { return inserts[i].offset < inserts[j].offset })
+	positional := scopePlaceholder(stmt) == "?"
+	args := make([]any, 0, len(stmt.Args)+len(inserts))
+	var b strings.Builder
+	last, bound, binds := 0, 0, 0
+	for _, ins := range inserts {
+		b.WriteString(stmt.Query[last:ins.offset])
+		b.WriteString(ins.text)
+		last = ins.offset
+		binds += ins.args
+		if ins.args == 0 || !positional {
+			continue
+		}
+		rank := 0
+		for _, t := range tokens {
+			if t.Offset >= ins.offset {
+				break
+			}
+			if t.Type == sqltoken.QuestionMark {
+				rank++
+			}
+		}
+		rank = min(rank, len(stmt.Args))
+		args = append(args, stmt.Args[bound:rank]...)
+		for range ins.args {
+			args = append(args, scope)
+		}
+		bound = rank
+	}
+	b.WriteString(stmt.Query[last:])
+	args = append(args, stmt.Args[bound:]...)
+	if !positional && binds > 0 {
+		args = append(args, scope)
+	}
+	stmt.Query = b.String()
+	stmt.Args = args
+	return stmt
+}
+
+// sameScope reports whether value, as bound or written in a statement, is
+// the scope.
+func sameScope(value, scope any) bool {
+	if valuer, ok := value.(driver.Valuer); ok {
+		if v, err := valuer.Value(); err == nil {
+			value = v
+		}
+	}
+	if valuer, ok := scope.(driver.Valuer); ok {
+		if v, err := valuer.Value(); err == nil {
+			scope = v
+		}
+	}
+	if b, ok := value.([]byte); ok {
+		value = string(b)
+	}
+	if b, ok := scope.([]byte); ok {
+		scope = string(b)
+	}
+	return value == scope || fmt.Sprint(value) == fmt.Sprint(scope)
+}
+
+func unquote(name string) string {
+	return strings.Trim(name, "\"`[]")
+}
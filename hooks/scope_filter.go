@@ -0,0 +1,202 @@
+package hooks
+
+import (
+	"strings"
+
+	"github.com/oarkflow/squealx"
+	"github.com/oarkflow/squealx/sqlparse"
+	"github.com/oarkflow/squealx/sqltoken"
+)
+
+// clauseEnds are the words ending the FROM and WHERE clauses of a query.
+var clauseEnds = map[string]bool{
+	"GROUP": true, "HAVING": true, "WINDOW": true, "ORDER": true, "LIMIT": true,
+	"OFFSET": true, "FETCH": true, "UNION": true, "INTERSECT": true,
+	"EXCEPT": true, "RETURNING": true, "FOR": true, "OPTION": true, "LOCK": true,
+}
+
+// joinEnds are the words ending the join condition of a table.
+var joinEnds = map[string]bool{
+	"JOIN": true, "INNER": true, "LEFT": true, "RIGHT": true, "FULL": true,
+	"CROSS": true, "NATURAL": true, "STRAIGHT_JOIN": true, "WHERE": true,
+	"SET": true,
+}
+
+// condition is where the scope predicates of the tables of a query go: the
+// join condition of a table or the WHERE clause of the query, from offset
+// open to end, or a WHERE clause to add at end when open is negative.
+type condition struct {
+	open int
+	end  int
+}
+
+func isWord(t sqlparse.Token, word string) bool {
+	return t.Type == sqltoken.Word && t.Upper == word
+}
+
+// endsClause reports whether tokens[i] ends the clauses of the query at
+// depth.
+func endsClause(tokens []sqlparse.Token, i, depth int) bool {
+	t := tokens[i]
+	switch {
+	case t.Depth < depth || t.Type == sqltoken.Semicolon:
+		return true
+	case t.Depth > depth || t.Type != sqltoken.Word:
+		return false
+	case t.Upper == "ON":
+		// ON CONFLICT and ON DUPLICATE KEY follow the source of an INSERT.
+		return i+1 < len(tokens) && (isWord(tokens[i+1], "CONFLICT") || isWord(tokens[i+1], "DUPLICATE"))
+	}
+	return clauseEnds[t.Upper]
+}
+
+// endOf returns the offset following the token before tokens[i].
+func endOf(tokens []sqlparse.Token, i int) int {
+	return tokens[i-1].Offset + len(tokens[i-1].Text)
+}
+
+// queryStart returns the index of the SELECT, UPDATE or DELETE keyword of
+// the query the table at tokens[i] is in, and false when it is in no such
+// query, such as the target of an INSERT, or in parentheses of its own.
+func queryStart(tokens []sqlparse.Token, i int) (int, bool) {
+	depth := tokens[i].Depth
+	for j := i - 1; j >= 0; j-- {
+		t := tokens[j]
+		switch {
+		case t.Depth < depth:
+			return 0, false
+		case t.Depth > depth || t.Type != sqltoken.Word:
+		case t.Upper == "SELECT" || t.Upper == "UPDATE" || t.Upper == "DELETE":
+			return j, true
+		case t.Upper == "INSERT" || t.Upper == "REPLACE" || t.Upper == "MERGE":
+			return 0, false
+		}
+	}
+	return 0, false
+}
+
+// whereCondition returns the WHERE clause of the query tokens[i] is in,
+// from tokens[i] on.
+func whereCondition(tokens []sqlparse.Token, i int) condition {
+	depth := tokens[i].Depth
+	c := condition{open: -1}
+	for i++; i < len(tokens) && !endsClause(tokens, i, depth); i++ {
+		if c.open < 0 && tokens[i].Depth == depth && isWord(tokens[i], "WHERE") && i+1 < len(tokens) {
+			c.open = tokens[i+1].Offset
+		}
+	}
+	c.end = endOf(tokens, i)
+	return c
+}
+
+// joinCondition returns the join condition following the ON at tokens[i].
+func joinCondition(tokens []sqlparse.Token, i int) condition {
+	depth := tokens[i].Depth
+	c := condition{open: tokens[i+1].Offset}
+	for i++; i < len(tokens) && !endsClause(tokens, i, depth); i++ {
+		t := tokens[i]
+		if t.Depth != depth {
+			continue
+		}
+		// LEFT and RIGHT are also functions.
+		function := i+1 < len(tokens) && tokens[i+1].Text == "("
+		if t.Text == "," || t.Type == sqltoken.Word && joinEnds[t.Upper] && !function {
+			break
+		}
+	}
+	c.end = endOf(tokens, i)
+	return c
+}
+
+// tableCondition returns the condition scoping the rows of table in its
+// query, and the index of the keyword starting the query. A table joined
+// with ON is scoped in its join condition, so that outer joins keep their
+// rows, and other tables in the WHERE clause.
+func tableCondition(tokens []sqlparse.Token, table sqlparse.Table) (condition, int, bool) {
+	i := tokenAt(tokens, table.Offset)
+	if i >= len(tokens) {
+		return condition{}, 0, false
+	}
+	start, ok := queryStart(tokens, i)
+	if !ok {
+		return condition{}, 0, false
+	}
+	before := i - 1
+	if isWord(tokens[before], "LATERAL") || isWord(tokens[before], "ONLY") {
+		before--
+	}
+	on := tokenAt(tokens, table.End)
+	if isWord(tokens[before], "JOIN") && on+1 < len(tokens) && isWord(tokens[on], "ON") {
+		return joinCondition(tokens, on), start, true
+	}
+	return whereCondition(tokens, i), start, true
+}
+
+// scopePredicates returns the insertions adding the predicates to their
+// conditions, every one binding the scope.
+func scopePredicates(conditions []condition, predicates map[condition][]string) []insertion {
+	var inserts []insertion
+	for _, c := range conditions {
+		text := strings.Join(predicates[c], " AND ")
+		n := len(predicates[c])
+		if c.open < 0 {
+			inserts = append(inserts, insertion{offset: c.end, text: " WHERE " + text, args: n})
+			continue
+		}
+		inserts = append(inserts,
+			insertion{offset: c.open, text: "("},
+			insertion{offset: c.end, text: ") AND " + text, args: n})
+	}
+	return inserts
+}
+
+// verifyAssignments verifies the assignments of the scope columns of the
+// SET list at tokens[i]. Assignments from the row proposed for insertion,
+// EXCLUDED.column, keep the scope the hook verified or injected.
+func (h *ResourceScopeHook) verifyAssignments(stmt squealx.Statement, tokens []sqlparse.Token, i int, columns map[string]bool, scope any) error {
+	depth := tokens[i].Depth
+	var items [][]sqlparse.Token
+	var item []sqlparse.Token
+	for i++; i < len(tokens) && !endsClause(tokens, i, depth); i++ {
+		t := tokens[i]
+		if t.Depth == depth && t.Type == sqltoken.Word && (t.Upper == "FROM" || t.Upper == "WHERE" || t.Upper == "OUTPUT") {
+			break
+		}
+		if t.Depth == depth && t.Text == "," {
+			items = append(items, item)
+			item = nil
+			continue
+		}
+		item = append(item, t)
+	}
+	items = append(items, item)
+	for _, item := range items {
+		eq := 0
+		for eq < len(item) && item[eq].Text != "=" {
+			eq++
+		}
+		if eq == len(item) {
+			continue
+		}
+		target, value := item[:eq], item[eq+1:]
+		assigned := false
+		for _, t := range target {
+			if (t.Type == sqltoken.Word || t.Type == sqltoken.Identifier) && columns[strings.ToLower(unquote(t.Text))] {
+				assigned = true
+			}
+		}
+		switch {
+		case !assigned:
+			continue
+		case len(value) == 3 && isWord(value[0], "EXCLUDED") && value[1].Text == "." && strings.EqualFold(unquote(value[2].Text), unquote(target[len(target)-1].Text)):
+			continue
+		case len(target) != 1 && !(len(target) == 3 && target[1].Text == "."):
+			// A column list, as in SET (a, b) = (...), cannot be verified.
+			value = nil
+		}
+		if err := h.verify(stmt, tokens, value, scope); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/oarkflow/squealx"
+)
+
+// scopeRulesConfig is the object form of the configuration of
+// ScopeRulesFromConfig.
+type scopeRulesConfig struct {
+	Column string      `json:"column"`
+	Tables []string    `json:"tables"`
+	Rules  []ScopeRule `json:"rules"`
+}
+
+// ScopeRulesFromConfig returns the validated scope rules of the JSON data,
+// either an array of rules:
+//
+//	[{"table": "orders", "column": "tenant_id"}, {"table": "audit", "column": "org_id"}]
+//
+// or an object scoping tables by a shared column, with rules of their own
+// for the others:
+//
+//	{"column": "tenant_id", "tables": ["orders", "invoices"], "rules": [{"table": "audit", "column": "org_id"}]}
+func ScopeRulesFromConfig(data []byte) ([]ScopeRule, error) {
+	var rules []ScopeRule
+	if data = bytes.TrimSpace(data); bytes.HasPrefix(data, []byte("[")) {
+		if err := json.Unmarshal(data, &rules); err != nil {
+			return nil, fmt.Errorf("hooks: scope rules: %w", err)
+		}
+		return ValidateScopeRules(rules)
+	}
+	var config scopeRulesConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("hooks: scope rules: %w", err)
+	}
+	if len(config.Tables) > 0 && config.Column == "" {
+		return nil, fmt.Errorf("hooks: scope rules: tables %v without column", config.Tables)
+	}
+	for _, table := range config.Tables {
+		rules = append(rules, ScopeRule{Table: table, Column: config.Column})
+	}
+	return ValidateScopeRules(append(rules, config.Rules...))
+}
+
+// scopeName matches table and column names, possibly qualified for tables.
+var scopeName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*(\.[A-Za-z_][A-Za-z0-9_$]*)*$`)
+
+// ValidateScopeRules checks that rules name their table and column, and
+// that no table is scoped by two columns. It returns the rules without
+// duplicates, in their order.
+func ValidateScopeRules(rules []ScopeRule) ([]ScopeRule, error) {
+	valid := make([]ScopeRule, 0, len(rules))
+	columns := map[string]string{}
+	for _, rule := range rules {
+		if !scopeName.MatchString(rule.Table) {
+			return nil, fmt.Errorf("hooks: scope rule: invalid table %q", rule.Table)
+		}
+		if !scopeName.MatchString(rule.Column) || strings.Contains(rule.Column, ".") {
+			return nil, fmt.Errorf("hooks: scope rule: invalid column %q of table %s", rule.Column, rule.Table)
+		}
+		table := strings.ToLower(rule.Table)
+		if column, ok := columns[table]; ok {
+			if !strings.EqualFold(column, rule.Column) {
+				return nil, fmt.Errorf("hooks: scope rule: table %s scoped by both %s and %s", rule.Table, column, rule.Column)
+			}
+			continue
+		}
+		columns[table] = rule.Column
+		valid = append(valid, rule)
+	}
+	return valid, nil
+}
+
+// ScopeRulesFromSchema returns rules scoping by column every table of the
+// current schema of db that has it, such as every table with a tenant_id
+// column, but the tables of exclude. PostgreSQL, MySQL, SQLite and SQL
+// Server are supported.
+func ScopeRulesFromSchema(ctx context.Context, db *squealx.DB, column string, exclude ...string) ([]ScopeRule, error) {
+	var query string
+	switch squealx.Dialect(db.DriverName()) {
+	case squealx.DialectPostgres:
+		query = `SELECT c.table_name FROM information_schema.columns c
+		JOIN information_schema.tables t ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+		WHERE c.table_schema = current_schema() AND t.table_type = 'BASE TABLE' AND c.column_name = $1
+		ORDER BY c.table_name`
+	case squealx.DialectMySQL:
+		query = `SELECT c.table_name FROM information_schema.columns c
+		JOIN information_schema.tables t ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+		WHERE c.table_schema = DATABASE() AND t.table_type = 'BASE TABLE' AND c.column_name = ?
+		ORDER BY c.table_name`
+	case squealx.DialectSQLite:
+		query = `SELECT m.name FROM sqlite_master m JOIN pragma_table_info(m.name) p
+		WHERE m.type = 'table' AND m.name NOT LIKE 'sqlite_%' AND p.name = ?
+		ORDER BY m.name`
+	case squealx.DialectMSSQL:
+		query = `SELECT c.TABLE_NAME FROM INFORMATION_SCHEMA.COLUMNS c
+		JOIN INFORMATION_SCHEMA.TABLES t ON t.TABLE_SCHEMA = c.TABLE_SCHEMA AND t.TABLE_NAME = c.TABLE_NAME
+		WHERE c.TABLE_SCHEMA = SCHEMA_NAME() AND t.TABLE_TYPE = 'BASE TABLE' AND c.COLUMN_NAME = @p1
+		ORDER BY c.TABLE_NAME`
+	default:
+		return nil, fmt.Errorf("hooks: scope rules from schema are not supported on %s", db.DriverName())
+	}
+	var tables []string
+	if err := db.SelectContext(ctx, &tables, query, column); err != nil {
+		return nil, err
+	}
+	excluded := make(map[string]bool, len(exclude))
+	for _, table := range exclude {
+		excluded[strings.ToLower(table)] = true
+	}
+	var rules []ScopeRule
+	for _, table := range tables {
+		if !excluded[strings.ToLower(table)] {
+			rules = append(rules, ScopeRule{Table: table, Column: column})
+		}
+	}
+	return ValidateScopeRules(rules)
+}
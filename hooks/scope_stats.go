@@ -0,0 +1,156 @@
+package hooks
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/oarkflow/squealx"
+)
+
+// ScopeDecision is what a ResourceScopeHook did with a statement.
+type ScopeDecision string
+
+const (
+	// ScopeScoped statements had their scope injected or verified.
+	ScopeScoped ScopeDecision = "scoped"
+	// ScopeRejected statements failed with ErrScopeViolation or
+	// ErrUnscoped.
+	ScopeRejected ScopeDecision = "rejected"
+	// ScopeBypassed statements on scoped tables ran unscoped, under
+	// WithoutScope or because the hook could not scope them and is not
+	// strict.
+	ScopeBypassed ScopeDecision = "bypassed"
+	// ScopePassthrough statements are not subject to scoping. They are
+	// neither counted nor audited.
+	ScopePassthrough ScopeDecision = "passthrough"
+)
+
+// AuditScopeDecision is the kind of the audit events reporting the
+// decisions of a ResourceScopeHook, see AuditScope.
+const AuditScopeDecision = "scope_decision"
+
+// ScopeCount is the number of statements of a kind on a table for which a
+// ResourceScopeHook made a decision.
+type ScopeCount struct {
+	Table     string
+	Statement squealx.StatementKind
+	Decision  ScopeDecision
+	Count     uint64
+}
+
+// ScopeCollector collects the decisions of a ResourceScopeHook, such as
+// into the counters of a metrics system. Implementations must be safe for
+// concurrent use.
+type ScopeCollector interface {
+	Collect(table string, statement squealx.StatementKind, decision ScopeDecision)
+}
+
+// ScopeCollectorFunc is a ScopeCollector calling itself.
+type ScopeCollectorFunc func(table string, statement squealx.StatementKind, decision ScopeDecision)
+
+// Collect calls f.
+func (f ScopeCollectorFunc) Collect(table string, statement squealx.StatementKind, decision ScopeDecision) {
+	f(table, statement, decision)
+}
+
+type scopeKey struct {
+	table     string
+	statement squealx.StatementKind
+	decision  ScopeDecision
+}
+
+type withoutScopeKey struct{}
+
+// WithoutScope returns a context under which ResourceScopeHooks let
+// statements run unscoped, such as for migrations and cross-tenant
+// maintenance jobs. They are counted as bypassed.
+func WithoutScope(ctx context.Context) context.Context {
+	return context.WithValue(ctx, withoutScopeKey{}, true)
+}
+
+func scopeBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(withoutScopeKey{}).(bool)
+	return bypass
+}
+
+// AuditScope reports the decisions of the hook to sink. Rejections are
+// always reported, while other decisions are sampled at rate, from 0 for
+// none to 1 for all, so that auditing a busy database does not flood the
+// sink. The events are redacted: their query is normalized as by
+// squealx.FingerprintDialect, without literals, and their arguments are
+// replaced by their type names.
+func AuditScope(sink squealx.AuditSink, rate float64) ScopeOption {
+	return func(h *ResourceScopeHook) {
+		h.audit = sink
+		h.sample = rate
+	}
+}
+
+// CollectScope reports the decisions of the hook counted by Stats to
+// collector as well.
+func CollectScope(collector ScopeCollector) ScopeOption {
+	return func(h *ResourceScopeHook) {
+		h.collector = collector
+	}
+}
+
+// Stats returns the counts of the decisions of the hook per table and kind
+// of statement, ordered by table, kind and decision. Tables are lower cased,
+// and empty for statements without one.
+func (h *ResourceScopeHook) Stats() []ScopeCount {
+	h.mu.Lock()
+	counts := make([]ScopeCount, 0, len(h.counts))
+	for key, count := range h.counts {
+		counts = append(counts, ScopeCount{Table: key.table, Statement: key.statement, Decision: key.decision, Count: count})
+	}
+	h.mu.Unlock()
+	slices.SortFunc(counts, func(a, b ScopeCount) int {
+		return cmp.Or(
+			cmp.Compare(a.Table, b.Table),
+			cmp.Compare(a.Statement, b.Statement),
+			cmp.Compare(a.Decision, b.Decision),
+		)
+	})
+	return counts
+}
+
+// record counts the decision made for stmt and audits it.
+func (h *ResourceScopeHook) record(ctx context.Context, stmt squealx.Statement, decision ScopeDecision, err error) {
+	kind, table := squealx.ClassifyStatement(stmt.Query)
+	key := scopeKey{table: strings.ToLower(table), statement: kind, decision: decision}
+	h.mu.Lock()
+	h.counts[key]++
+	h.mu.Unlock()
+	if h.collector != nil {
+		h.collector.Collect(key.table, kind, decision)
+	}
+	if h.audit == nil || decision != ScopeRejected && (h.sample <= 0 || rand.Float64() >= h.sample) {
+		return
+	}
+	detail := map[string]any{
+		"table":     key.table,
+		"statement": string(kind),
+		"decision":  string(decision),
+	}
+	if err != nil {
+		detail["error"] = err.Error()
+	}
+	query, fingerprint := squealx.FingerprintDialect(stmt.Dialect, stmt.Query)
+	detail["fingerprint"] = fingerprint
+	args := make([]any, len(stmt.Args))
+	for i, arg := range stmt.Args {
+		args[i] = fmt.Sprintf("%T", arg)
+	}
+	h.audit.Audit(ctx, squealx.AuditEvent{
+		Kind:   AuditScopeDecision,
+		Time:   time.Now(),
+		Query:  query,
+		Args:   args,
+		Detail: detail,
+	})
+}
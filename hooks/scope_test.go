@@ -0,0 +1,396 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/oarkflow/squealx"
+	_ "modernc.org/sqlite"
+)
+
+var testScopeRules = []ScopeRule{
+	{Table: "users", Column: "tenant_id"},
+	{Table: "orders", Column: "tenant_id"},
+}
+
+func testScope(ctx context.Context) (any, bool) {
+	return 7, true
+}
+
+type scopeCase struct {
+	name  string
+	query string
+	args  []any
+	// want is the rewritten query, the query itself when empty.
+	want     string
+	wantArgs []any
+	err      error
+	decision ScopeDecision
+}
+
+func runScopeCases(t *testing.T, h *ResourceScopeHook, ctx context.Context, dialect string, cases []scopeCase) {
+	t.Helper()
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			stmt := squealx.Statement{Query: c.query, Args: c.args, Dialect: dialect}
+			got, decision, err := h.rewrite(ctx, stmt)
+			if !errors.Is(err, c.err) {
+				t.Fatalf("error = %v, want %v", err, c.err)
+			}
+			if decision != c.decision {
+				t.Errorf("decision = %s, want %s", decision, c.decision)
+			}
+			want, wantArgs := c.want, c.wantArgs
+			if want == "" {
+				want, wantArgs = c.query, c.args
+			}
+			if got.Query != want {
+				t.Errorf("query = %s\nwant %s", got.Query, want)
+			}
+			if !reflect.DeepEqual(got.Args, wantArgs) {
+				t.Errorf("args = %v, want %v", got.Args, wantArgs)
+			}
+		})
+	}
+}
+
+func TestResourceScopeStrictTables(t *testing.T) {
+	h := ResourceScope(testScope, testScopeRules, Strict())
+	runScopeCases(t, h, context.Background(), squealx.DialectPostgres, []scopeCase{
+		{
+			name:     "insert",
+			query:    "INSERT INTO users (name) VALUES ($1)",
+			args:     []any{"ann"},
+			want:     "INSERT INTO users (name, tenant_id) VALUES ($1, $2)",
+			wantArgs: []any{"ann", 7},
+			decision: ScopeScoped,
+		},
+		{
+			name:     "unscoped table",
+			query:    "SELECT * FROM products WHERE id = $1",
+			args:     []any{1},
+			decision: ScopePassthrough,
+		},
+		{
+			name:     "cte shadowing a scoped table",
+			query:    "WITH users AS (SELECT 1 AS id) SELECT * FROM users",
+			decision: ScopePassthrough,
+		},
+		{
+			name:     "scoped table in cte body",
+			query:    "WITH u AS (SELECT id FROM users) SELECT * FROM u",
+			want:     "WITH u AS (SELECT id FROM users WHERE users.tenant_id = $1) SELECT * FROM u",
+			wantArgs: []any{7},
+			decision: ScopeScoped,
+		},
+		{
+			name:     "scoped table in recursive cte body",
+			query:    "WITH RECURSIVE t AS (SELECT id FROM products UNION ALL SELECT o.id FROM t JOIN orders o ON o.parent = t.id) SELECT * FROM t",
+			want:     "WITH RECURSIVE t AS (SELECT id FROM products UNION ALL SELECT o.id FROM t JOIN orders o ON (o.parent = t.id) AND o.tenant_id = $1) SELECT * FROM t",
+			wantArgs: []any{7},
+			decision: ScopeScoped,
+		},
+		{
+			name:     "scoped table in lateral subquery",
+			query:    "SELECT * FROM products p, LATERAL (SELECT * FROM orders o WHERE o.product_id = p.id) x",
+			want:     "SELECT * FROM products p, LATERAL (SELECT * FROM orders o WHERE (o.product_id = p.id) AND o.tenant_id = $1) x",
+			wantArgs: []any{7},
+			decision: ScopeScoped,
+		},
+		{
+			name:     "scoped table in lateral join",
+			query:    "SELECT * FROM products p LEFT JOIN LATERAL (SELECT * FROM orders o WHERE o.product_id = p.id) x ON true",
+			want:     "SELECT * FROM products p LEFT JOIN LATERAL (SELECT * FROM orders o WHERE (o.product_id = p.id) AND o.tenant_id = $1) x ON true",
+			wantArgs: []any{7},
+			decision: ScopeScoped,
+		},
+		{
+			name:     "data-modifying cte",
+			query:    "WITH d AS (DELETE FROM orders WHERE id = $1 RETURNING *) SELECT * FROM d",
+			args:     []any{1},
+			want:     "WITH d AS (DELETE FROM orders WHERE (id = $1) AND orders.tenant_id = $2 RETURNING *) SELECT * FROM d",
+			wantArgs: []any{1, 7},
+			decision: ScopeScoped,
+		},
+		{
+			name:     "insert in data-modifying cte",
+			query:    "WITH x AS (INSERT INTO users (name) VALUES ($1) RETURNING id) SELECT * FROM x",
+			args:     []any{"ann"},
+			err:      ErrUnscoped,
+			decision: ScopeRejected,
+		},
+		{
+			name:     "parenthesized join",
+			query:    "SELECT * FROM (users u JOIN orders o ON o.user_id = u.id)",
+			err:      ErrUnscoped,
+			decision: ScopeRejected,
+		},
+		{
+			name:     "table function",
+			query:    "SELECT * FROM unnest($1::int[]) AS ids",
+			args:     []any{"{1}"},
+			err:      ErrUnscoped,
+			decision: ScopeRejected,
+		},
+	})
+}
+
+func TestResourceScopeLenientTables(t *testing.T) {
+	h := ResourceScope(testScope, testScopeRules)
+	runScopeCases(t, h, context.Background(), squealx.DialectPostgres, []scopeCase{
+		{
+			name:     "insert in data-modifying cte",
+			query:    "WITH x AS (INSERT INTO users (name) VALUES ($1) RETURNING id) SELECT * FROM x",
+			args:     []any{"ann"},
+			decision: ScopeBypassed,
+		},
+		{
+			name:     "table function",
+			query:    "SELECT * FROM unnest($1::int[]) AS ids",
+			args:     []any{"{1}"},
+			decision: ScopePassthrough,
+		},
+	})
+}
+
+func TestResourceScopeWithoutScope(t *testing.T) {
+	h := ResourceScope(testScope, testScopeRules, Strict())
+	runScopeCases(t, h, WithoutScope(context.Background()), squealx.DialectPostgres, []scopeCase{
+		{
+			name:     "scoped table in lateral subquery",
+			query:    "SELECT * FROM products p, LATERAL (SELECT * FROM orders o WHERE o.product_id = p.id) x",
+			decision: ScopeBypassed,
+		},
+		{
+			name:     "table function",
+			query:    "SELECT * FROM unnest($1::int[]) AS ids",
+			args:     []any{"{1}"},
+			decision: ScopeBypassed,
+		},
+	})
+}
+
+func TestResourceScopeStatements(t *testing.T) {
+	h := ResourceScope(testScope, testScopeRules, Strict())
+	runScopeCases(t, h, context.Background(), squealx.DialectPostgres, []scopeCase{
+		{
+			name:     "select",
+			query:    "SELECT * FROM users WHERE a = $1 OR b = $2 ORDER BY id LIMIT 1",
+			args:     []any{1, 2},
+			want:     "SELECT * FROM users WHERE (a = $1 OR b = $2) AND users.tenant_id = $3 ORDER BY id LIMIT 1",
+			wantArgs: []any{1, 2, 7},
+			decision: ScopeScoped,
+		},
+		{
+			name:     "outer join",
+			query:    "SELECT * FROM products p LEFT JOIN orders o ON o.product_id = p.id",
+			want:     "SELECT * FROM products p LEFT JOIN orders o ON (o.product_id = p.id) AND o.tenant_id = $1",
+			wantArgs: []any{7},
+			decision: ScopeScoped,
+		},
+		{
+			name:     "subquery",
+			query:    "SELECT * FROM products WHERE id IN (SELECT product_id FROM orders)",
+			want:     "SELECT * FROM products WHERE id IN (SELECT product_id FROM orders WHERE orders.tenant_id = $1)",
+			wantArgs: []any{7},
+			decision: ScopeScoped,
+		},
+		{
+			name:     "update",
+			query:    "UPDATE users SET name = $1 WHERE id = $2 RETURNING id",
+			args:     []any{"ann", 1},
+			want:     "UPDATE users SET name = $1 WHERE (id = $2) AND users.tenant_id = $3 RETURNING id",
+			wantArgs: []any{"ann", 1, 7},
+			decision: ScopeScoped,
+		},
+		{
+			name:     "update of the scope",
+			query:    "UPDATE users SET tenant_id = $1",
+			args:     []any{8},
+			err:      ErrScopeViolation,
+			decision: ScopeRejected,
+		},
+		{
+			name:     "update of a column list",
+			query:    "UPDATE users SET (name, tenant_id) = ($1, $2)",
+			args:     []any{"ann", 7},
+			err:      ErrUnscoped,
+			decision: ScopeRejected,
+		},
+		{
+			name:     "delete",
+			query:    "DELETE FROM users",
+			want:     "DELETE FROM users WHERE users.tenant_id = $1",
+			wantArgs: []any{7},
+			decision: ScopeScoped,
+		},
+		{
+			name:     "insert select",
+			query:    "INSERT INTO users (name) SELECT name FROM orders WHERE id = $1",
+			args:     []any{1},
+			want:     "INSERT INTO users (name, tenant_id) SELECT name, $2 FROM orders WHERE (id = $1) AND orders.tenant_id = $2",
+			wantArgs: []any{1, 7},
+			decision: ScopeScoped,
+		},
+		{
+			name:     "insert select setting another scope",
+			query:    "INSERT INTO users (name, tenant_id) SELECT name, 8 FROM products",
+			err:      ErrScopeViolation,
+			decision: ScopeRejected,
+		},
+		{
+			name:     "upsert",
+			query:    "INSERT INTO users AS u (id, name) VALUES ($1, $2) ON CONFLICT (id) DO UPDATE SET name = excluded.name, tenant_id = excluded.tenant_id",
+			args:     []any{1, "ann"},
+			want:     "INSERT INTO users AS u (id, name, tenant_id) VALUES ($1, $2, $3) ON CONFLICT (id) DO UPDATE SET name = excluded.name, tenant_id = excluded.tenant_id WHERE u.tenant_id = $3",
+			wantArgs: []any{1, "ann", 7},
+			decision: ScopeScoped,
+		},
+		{
+			name:     "upsert of the scope",
+			query:    "INSERT INTO users (id, name) VALUES ($1, $2) ON CONFLICT (id) DO UPDATE SET tenant_id = 8",
+			args:     []any{1, "ann"},
+			err:      ErrScopeViolation,
+			decision: ScopeRejected,
+		},
+	})
+}
+
+func TestResourceScopeDialects(t *testing.T) {
+	h := ResourceScope(testScope, testScopeRules, Strict())
+	runScopeCases(t, h, context.Background(), squealx.DialectMySQL, []scopeCase{
+		{
+			name:     "select",
+			query:    "SELECT * FROM `users` `u` WHERE `u`.id = ? OR `u`.id = ?",
+			args:     []any{1, 2},
+			want:     "SELECT * FROM `users` `u` WHERE (`u`.id = ? OR `u`.id = ?) AND `u`.tenant_id = ?",
+			wantArgs: []any{1, 2, 7},
+			decision: ScopeScoped,
+		},
+		{
+			name:     "insert select",
+			query:    "INSERT INTO users (name) SELECT name FROM orders WHERE id = ?",
+			args:     []any{1},
+			want:     "INSERT INTO users (name, tenant_id) SELECT name, ? FROM orders WHERE (id = ?) AND orders.tenant_id = ?",
+			wantArgs: []any{7, 1, 7},
+			decision: ScopeScoped,
+		},
+		{
+			name:     "quoted scope column",
+			query:    "INSERT INTO `users` (`name`, `tenant_id`) VALUES (?, ?)",
+			args:     []any{"ann", 7},
+			decision: ScopeScoped,
+		},
+		{
+			name:     "on duplicate key update",
+			query:    "INSERT INTO users (id, name) VALUES (?, ?) ON DUPLICATE KEY UPDATE name = VALUES(name)",
+			args:     []any{1, "ann"},
+			err:      ErrUnscoped,
+			decision: ScopeRejected,
+		},
+	})
+	runScopeCases(t, h, context.Background(), squealx.DialectMSSQL, []scopeCase{
+		{
+			name:     "delete",
+			query:    "DELETE FROM [dbo].[users] WHERE id = @p1",
+			args:     []any{1},
+			want:     "DELETE FROM [dbo].[users] WHERE (id = @p1) AND [dbo].[users].tenant_id = @p2",
+			wantArgs: []any{1, 7},
+			decision: ScopeScoped,
+		},
+		{
+			name:     "insert setting another scope",
+			query:    "INSERT INTO [users] ([name], [tenant_id]) VALUES (@p1, @p2)",
+			args:     []any{"ann", 8},
+			err:      ErrScopeViolation,
+			decision: ScopeRejected,
+		},
+	})
+}
+
+func TestResourceScopeAudit(t *testing.T) {
+	var events []squealx.AuditEvent
+	var collected []ScopeCount
+	h := ResourceScope(testScope, testScopeRules, Strict(),
+		AuditScope(squealx.AuditFunc(func(ctx context.Context, event squealx.AuditEvent) {
+			events = append(events, event)
+		}), 1),
+		CollectScope(ScopeCollectorFunc(func(table string, statement squealx.StatementKind, decision ScopeDecision) {
+			collected = append(collected, ScopeCount{Table: table, Statement: statement, Decision: decision, Count: 1})
+		})))
+	ctx := context.Background()
+	for _, stmt := range []squealx.Statement{
+		{Query: "SELECT * FROM products WHERE name = 'secret'", Dialect: squealx.DialectPostgres},
+		{Query: "UPDATE users SET name = 'secret' WHERE id = $1", Args: []any{"key"}, Dialect: squealx.DialectPostgres},
+		{Query: "UPDATE users SET tenant_id = $1", Args: []any{8}, Dialect: squealx.DialectPostgres},
+	} {
+		h.Rewrite(ctx, stmt)
+	}
+
+	want := []ScopeCount{
+		{Table: "users", Statement: squealx.StatementUpdate, Decision: ScopeScoped, Count: 1},
+		{Table: "users", Statement: squealx.StatementUpdate, Decision: ScopeRejected, Count: 1},
+	}
+	if !reflect.DeepEqual(collected, want) {
+		t.Errorf("collected = %v, want %v", collected, want)
+	}
+	if stats := h.Stats(); !reflect.DeepEqual(stats, []ScopeCount{want[1], want[0]}) {
+		t.Errorf("Stats = %v, want %v", stats, want)
+	}
+	if len(events) != 2 {
+		t.Fatalf("%d events, want 2", len(events))
+	}
+	for _, event := range events {
+		if strings.Contains(event.Query, "secret") || strings.Contains(fmt.Sprint(event.Args), "key") {
+			t.Errorf("event is not redacted: %s %v", event.Query, event.Args)
+		}
+	}
+	if got := events[1].Args; !reflect.DeepEqual(got, []any{"int"}) {
+		t.Errorf("args = %v, want their types", got)
+	}
+}
+
+func TestResourceScopeConn(t *testing.T) {
+	db, err := squealx.Connect("sqlite", ":memory:", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+	db.MustExec(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, tenant_id INTEGER)`)
+	db.MustExec(`CREATE TABLE orders (id INTEGER PRIMARY KEY, user_id INTEGER, tenant_id INTEGER)`)
+	db.MustExec(`INSERT INTO users (name, tenant_id) VALUES ('ann', 7), ('bob', 8)`)
+	db.UseRewriter(ResourceScope(testScope, testScopeRules, Strict()))
+
+	ctx := context.Background()
+	conn, err := db.Connx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var names []string
+	if err := conn.SelectContext(ctx, &names, "SELECT name FROM users"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"ann"}) {
+		t.Errorf("names = %v, want the users of the scope", names)
+	}
+	if _, err := conn.ExecContext(ctx, "INSERT INTO users (name) VALUES (?)", "cat"); err != nil {
+		t.Fatal(err)
+	}
+	var tenant int
+	if err := conn.QueryRowContext(ctx, "SELECT tenant_id FROM users WHERE name = ?", "cat").Scan(&tenant); err != nil || tenant != 7 {
+		t.Errorf("tenant_id = %d, %v, want 7", tenant, err)
+	}
+	rows, err := conn.QueryContext(ctx, "SELECT * FROM (users u JOIN orders o ON o.user_id = u.id)")
+	if err == nil {
+		rows.Close()
+	}
+	if !errors.Is(err, ErrUnscoped) {
+		t.Errorf("error = %v, want %v", err, ErrUnscoped)
+	}
+}
@@ -0,0 +1,197 @@
+package squealx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// ErrSequenceNotSupported is returned by NextID on databases without
+// sequences.
+var ErrSequenceNotSupported = errors.New("squealx: sequences are not supported by this database")
+
+// IDGenerator produces primary key values.
+type IDGenerator interface {
+	NextID(ctx context.Context) (any, error)
+}
+
+// IDGeneratorFunc adapts a function to IDGenerator.
+type IDGeneratorFunc func(ctx context.Context) (any, error)
+
+func (f IDGeneratorFunc) NextID(ctx context.Context) (any, error) {
+	return f(ctx)
+}
+
+var (
+	// ULIDGenerator generates ULID strings with NewULID.
+	ULIDGenerator IDGenerator = IDGeneratorFunc(func(context.Context) (any, error) {
+		return NewULID(), nil
+	})
+	// UUIDv7Generator generates UUID version 7 strings with NewUUIDv7.
+	UUIDv7Generator IDGenerator = IDGeneratorFunc(func(context.Context) (any, error) {
+		return NewUUIDv7(), nil
+	})
+)
+
+// NextID returns the next value of a database sequence. It must run against
+// the primary: sequences cannot be advanced on read replicas. PostgreSQL,
+// SQL Server and MariaDB have sequences; MySQL and SQLite do not.
+func NextID(ctx context.Context, db *DB, sequence string) (int64, error) {
+	var query string
+	var args []any
+	dialect := Dialect(db.driverName)
+	switch dialect {
+	case DialectPostgres:
+		query, args = "SELECT nextval($1)", []any{sequence}
+	case DialectMSSQL:
+		query = "SELECT NEXT VALUE FOR " + QuoteName(dialect, sequence)
+	case DialectMySQL:
+		info, err := db.ServerInfo(ctx)
+		if err != nil {
+			return 0, err
+		}
+		if !info.MariaDB {
+			return 0, ErrSequenceNotSupported
+		}
+		query = "SELECT NEXTVAL(" + QuoteName(dialect, sequence) + ")"
+	default:
+		return 0, ErrSequenceNotSupported
+	}
+	var id int64
+	err := db.QueryRowxContext(ctx, query, args...).Scan(&id)
+	return id, err
+}
+
+// Sequence returns an IDGenerator drawing int64 values from a database
+// sequence with NextID.
+func Sequence(db *DB, name string) IDGenerator {
+	return IDGeneratorFunc(func(ctx context.Context) (any, error) {
+		return NextID(ctx, db, name)
+	})
+}
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var ulidState struct {
+	sync.Mutex
+	ms      uint64
+	entropy [10]byte
+}
+
+// NewULID returns a new ULID: 48 bits of millisecond timestamp followed by
+// 80 random bits, in Crockford base32. ULIDs generated within the same
+// millisecond increment the random part, so they sort in generation order.
+func NewULID() string {
+	ms := uint64(time.Now().UnixMilli())
+	ulidState.Lock()
+	if ms <= ulidState.ms {
+		ms = ulidState.ms
+		for i := len(ulidState.entropy) - 1; i >= 0; i-- {
+			ulidState.entropy[i]++
+			if ulidState.entropy[i] != 0 {
+				break
+			}
+		}
+	} else {
+		ulidState.ms = ms
+		_, _ = rand.Read(ulidState.entropy[:])
+	}
+	var id [16]byte
+	binary.BigEndian.PutUint16(id[0:], uint16(ms>>32))
+	binary.BigEndian.PutUint32(id[2:], uint32(ms))
+	copy(id[6:], ulidState.entropy[:])
+	ulidState.Unlock()
+
+	hi := binary.BigEndian.Uint64(id[:8])
+	lo := binary.BigEndian.Uint64(id[8:])
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// NewUUIDv7 returns a new version 7 UUID, which starts with the millisecond
+// timestamp and so sorts roughly in generation order.
+func NewUUIDv7() string {
+	var id [16]byte
+	_, _ = rand.Read(id[6:])
+	ms := uint64(time.Now().UnixMilli())
+	binary.BigEndian.PutUint16(id[0:], uint16(ms>>32))
+	binary.BigEndian.PutUint32(id[2:], uint32(ms))
+	id[6] = id[6]&0x0f | 0x70
+	id[8] = id[8]&0x3f | 0x80
+	var out [36]byte
+	hex.Encode(out[0:8], id[0:4])
+	out[8] = '-'
+	hex.Encode(out[9:13], id[4:6])
+	out[13] = '-'
+	hex.Encode(out[14:18], id[6:8])
+	out[18] = '-'
+	hex.Encode(out[19:23], id[8:10])
+	out[23] = '-'
+	hex.Encode(out[24:], id[10:])
+	return string(out[:])
+}
+
+// SnowflakeEpoch is the epoch of Snowflake IDs.
+var SnowflakeEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Snowflake generates 63-bit IDs from 41 bits of milliseconds since
+// SnowflakeEpoch, a 10-bit node number and a 12-bit sequence. IDs are unique
+// as long as every process uses its own node number.
+type Snowflake struct {
+	mu   sync.Mutex
+	node int64
+	last int64
+	seq  int64
+}
+
+// NewSnowflake returns a Snowflake generator for node, which is taken modulo
+// 1024.
+func NewSnowflake(node int64) *Snowflake {
+	return &Snowflake{node: node & 1023}
+}
+
+// SnowflakeFor returns a Snowflake generator whose node number is derived
+// from the ID of db. Give each process its own DB ID to keep IDs unique.
+func SnowflakeFor(db *DB) *Snowflake {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(db.ID))
+	return NewSnowflake(int64(h.Sum32()))
+}
+
+// Next returns the next ID. It waits for the next millisecond when the
+// sequence is exhausted, and holds back when the clock moves backwards.
+func (s *Snowflake) Next() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Since(SnowflakeEpoch).Milliseconds()
+	if now < s.last {
+		now = s.last
+	}
+	if now == s.last {
+		s.seq = (s.seq + 1) & 4095
+		if s.seq == 0 {
+			for now <= s.last {
+				time.Sleep(100 * time.Microsecond)
+				now = time.Since(SnowflakeEpoch).Milliseconds()
+			}
+		}
+	} else {
+		s.seq = 0
+	}
+	s.last = now
+	return now<<22 | s.node<<12 | s.seq
+}
+
+func (s *Snowflake) NextID(context.Context) (any, error) {
+	return s.Next(), nil
+}
@@ -0,0 +1,67 @@
+package squealx
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// IdentityMap remembers the entities loaded by primary key within a context,
+// typically one request, so that repeated First calls for the same key are
+// answered without a query. It is enabled with WithIdentityMap.
+type IdentityMap struct {
+	mu     sync.Mutex
+	tables map[string]map[string]any
+}
+
+type identityMapKey struct{}
+
+// WithIdentityMap returns ctx carrying a new IdentityMap, or ctx itself when
+// it already carries one.
+func WithIdentityMap(ctx context.Context) context.Context {
+	if IdentityMapFromContext(ctx) != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, identityMapKey{}, &IdentityMap{tables: make(map[string]map[string]any)})
+}
+
+// IdentityMapFromContext returns the IdentityMap carried by ctx, or nil.
+func IdentityMapFromContext(ctx context.Context) *IdentityMap {
+	m, _ := ctx.Value(identityMapKey{}).(*IdentityMap)
+	return m
+}
+
+// Clear forgets all entities.
+func (m *IdentityMap) Clear() {
+	m.mu.Lock()
+	m.tables = make(map[string]map[string]any)
+	m.mu.Unlock()
+}
+
+func (m *IdentityMap) get(table string, id any) (any, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entity, ok := m.tables[table][fmt.Sprint(id)]
+	return entity, ok
+}
+
+func (m *IdentityMap) set(table string, id any, entity any) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.tables[table] == nil {
+		m.tables[table] = make(map[string]any)
+	}
+	m.tables[table][fmt.Sprint(id)] = entity
+}
+
+func (m *IdentityMap) forget(table string, id any) {
+	m.mu.Lock()
+	delete(m.tables[table], fmt.Sprint(id))
+	m.mu.Unlock()
+}
+
+func (m *IdentityMap) forgetTable(table string) {
+	m.mu.Lock()
+	delete(m.tables, table)
+	m.mu.Unlock()
+}
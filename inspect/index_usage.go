@@ -0,0 +1,141 @@
+package inspect
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/oarkflow/squealx"
+)
+
+// UnusedIndex is an index that has not been scanned since statistics were
+// last reset. Primary keys and unique indexes are never reported.
+type UnusedIndex struct {
+	Table     string `db:"table_name"`
+	Index     string `db:"index_name"`
+	SizeBytes int64  `db:"size_bytes"`
+}
+
+// TableScans counts how a table was read. On MySQL IndexScans is zero and
+// SeqScans counts rows read without an index.
+type TableScans struct {
+	Table       string `db:"table_name"`
+	SeqScans    int64  `db:"seq_scans"`
+	SeqRowsRead int64  `db:"seq_rows_read"`
+	IndexScans  int64  `db:"index_scans"`
+	LiveRows    int64  `db:"live_rows"`
+}
+
+// IndexSuggestion is a table, and on PostgreSQL candidate columns, that
+// would likely benefit from an index. It is a heuristic to investigate, not
+// something to apply blindly.
+type IndexSuggestion struct {
+	Table   string
+	Columns []string
+	Reason  string
+}
+
+// IndexUsageReport is returned by IndexUsage.
+type IndexUsageReport struct {
+	Unused []UnusedIndex
+	// SeqScanned lists tables read sequentially more often than through an
+	// index, most rows read first.
+	SeqScanned  []TableScans
+	Suggestions []IndexSuggestion
+}
+
+// SuggestMinRows is the size a table must reach before sequential scans on it
+// lead to an index suggestion.
+var SuggestMinRows int64 = 10000
+
+// IndexUsage reports unused indexes, tables mostly read without indexes and
+// index suggestions derived from them, from pg_stat_user_indexes and
+// pg_stat_user_tables on PostgreSQL and performance_schema on MySQL.
+func IndexUsage(ctx context.Context, db *squealx.DB) (IndexUsageReport, error) {
+	switch squealx.Dialect(db.DriverName()) {
+	case squealx.DialectPostgres:
+		return postgresIndexUsage(ctx, db)
+	case squealx.DialectMySQL:
+		return mysqlIndexUsage(ctx, db)
+	}
+	return IndexUsageReport{}, unsupported("index usage", db)
+}
+
+func postgresIndexUsage(ctx context.Context, db *squealx.DB) (IndexUsageReport, error) {
+	var report IndexUsageReport
+	err := db.SelectContext(ctx, &report.Unused, `SELECT s.relname AS table_name, s.indexrelname AS index_name,
+	pg_relation_size(s.indexrelid) AS size_bytes
+	FROM pg_stat_user_indexes s JOIN pg_index i ON i.indexrelid = s.indexrelid
+	WHERE s.idx_scan = 0 AND NOT i.indisunique AND NOT i.indisprimary
+	ORDER BY size_bytes DESC`)
+	if err != nil {
+		return report, err
+	}
+	err = db.SelectContext(ctx, &report.SeqScanned, `SELECT relname AS table_name, seq_scan AS seq_scans,
+	seq_tup_read AS seq_rows_read, coalesce(idx_scan, 0) AS index_scans, n_live_tup AS live_rows
+	FROM pg_stat_user_tables WHERE seq_scan > coalesce(idx_scan, 0)
+	ORDER BY seq_tup_read DESC`)
+	if err != nil {
+		return report, err
+	}
+	for _, t := range report.SeqScanned {
+		if t.LiveRows < SuggestMinRows {
+			continue
+		}
+		// Selective columns not leading any index are the likely filters.
+		var columns []string
+		err := db.SelectContext(ctx, &columns, `SELECT s.attname FROM pg_stats s
+		WHERE s.schemaname = current_schema() AND s.tablename = $1
+		AND (s.n_distinct < -0.1 OR s.n_distinct > 100)
+		AND NOT EXISTS (
+			SELECT 1 FROM pg_index i
+			JOIN pg_class c ON c.oid = i.indrelid
+			JOIN pg_attribute a ON a.attrelid = c.oid AND a.attnum = i.indkey[0]
+			WHERE c.relname = s.tablename AND a.attname = s.attname
+		) ORDER BY s.attname`, t.Table)
+		if err != nil {
+			return report, err
+		}
+		report.Suggestions = append(report.Suggestions, IndexSuggestion{
+			Table:   t.Table,
+			Columns: columns,
+			Reason:  fmt.Sprintf("%d sequential scans read %d rows of a %d row table, against %d index scans", t.SeqScans, t.SeqRowsRead, t.LiveRows, t.IndexScans),
+		})
+	}
+	return report, nil
+}
+
+func mysqlIndexUsage(ctx context.Context, db *squealx.DB) (IndexUsageReport, error) {
+	var report IndexUsageReport
+	err := db.SelectContext(ctx, &report.Unused, `SELECT u.object_name AS table_name, u.index_name AS index_name,
+	coalesce(MAX(st.stat_value) * @@innodb_page_size, 0) AS size_bytes
+	FROM performance_schema.table_io_waits_summary_by_index_usage u
+	JOIN information_schema.statistics s ON s.table_schema = u.object_schema AND s.table_name = u.object_name
+		AND s.index_name = u.index_name AND s.seq_in_index = 1 AND s.non_unique = 1
+	LEFT JOIN mysql.innodb_index_stats st ON st.database_name = u.object_schema AND st.table_name = u.object_name
+		AND st.index_name = u.index_name AND st.stat_name = 'size'
+	WHERE u.object_schema = DATABASE() AND u.count_star = 0
+	GROUP BY u.object_name, u.index_name
+	ORDER BY size_bytes DESC`)
+	if err != nil {
+		return report, err
+	}
+	err = db.SelectContext(ctx, &report.SeqScanned, `SELECT u.object_name AS table_name, u.count_read AS seq_scans,
+	u.count_read AS seq_rows_read, 0 AS index_scans, coalesce(t.table_rows, 0) AS live_rows
+	FROM performance_schema.table_io_waits_summary_by_index_usage u
+	LEFT JOIN information_schema.tables t ON t.table_schema = u.object_schema AND t.table_name = u.object_name
+	WHERE u.object_schema = DATABASE() AND u.index_name IS NULL AND u.count_read > 0
+	ORDER BY u.count_read DESC`)
+	if err != nil {
+		return report, err
+	}
+	for _, t := range report.SeqScanned {
+		if t.LiveRows < SuggestMinRows {
+			continue
+		}
+		report.Suggestions = append(report.Suggestions, IndexSuggestion{
+			Table:  t.Table,
+			Reason: fmt.Sprintf("%d rows of a %d row table were read without an index", t.SeqRowsRead, t.LiveRows),
+		})
+	}
+	return report, nil
+}
@@ -0,0 +1,16 @@
+// Package inspect reports on the runtime state of a database from its
+// statistics and catalog views, for diagnostics endpoints and alerting.
+// PostgreSQL and MySQL are supported.
+package inspect
+
+import (
+	"fmt"
+
+	"github.com/oarkflow/squealx"
+)
+
+// unsupported returns the error reported for databases without the
+// statistics a report relies on.
+func unsupported(report string, db *squealx.DB) error {
+	return fmt.Errorf("inspect: %s is not supported on %s", report, db.DriverName())
+}
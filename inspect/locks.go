@@ -0,0 +1,91 @@
+package inspect
+
+import (
+	"context"
+	"time"
+
+	"github.com/oarkflow/squealx"
+)
+
+// LockWait is a session waiting on a lock held by another one. A session
+// blocked by several others appears once per blocker.
+type LockWait struct {
+	BlockedPID    int64   `db:"blocked_pid"`
+	BlockedUser   string  `db:"blocked_user"`
+	BlockedQuery  string  `db:"blocked_query"`
+	WaitSeconds   float64 `db:"wait_seconds"`
+	LockType      string  `db:"lock_type"`
+	BlockingPID   int64   `db:"blocking_pid"`
+	BlockingUser  string  `db:"blocking_user"`
+	BlockingQuery string  `db:"blocking_query"`
+	BlockingState string  `db:"blocking_state"`
+}
+
+// Wait returns how long the blocked session has been waiting.
+func (l LockWait) Wait() time.Duration {
+	return time.Duration(l.WaitSeconds * float64(time.Second))
+}
+
+const postgresLocks = `SELECT blocked.pid AS blocked_pid, coalesce(blocked.usename, '') AS blocked_user,
+	blocked.query AS blocked_query,
+	extract(epoch FROM now() - coalesce(blocked.state_change, blocked.query_start))::float8 AS wait_seconds,
+	coalesce(blocked.wait_event, '') AS lock_type,
+	blocking.pid AS blocking_pid, coalesce(blocking.usename, '') AS blocking_user,
+	blocking.query AS blocking_query, coalesce(blocking.state, '') AS blocking_state
+	FROM pg_stat_activity blocked
+	CROSS JOIN LATERAL unnest(pg_blocking_pids(blocked.pid)) AS b(pid)
+	JOIN pg_stat_activity blocking ON blocking.pid = b.pid
+	ORDER BY wait_seconds DESC`
+
+const mysqlLocks = `SELECT r.trx_mysql_thread_id AS blocked_pid, coalesce(rp.user, '') AS blocked_user,
+	coalesce(r.trx_query, '') AS blocked_query,
+	TIMESTAMPDIFF(MICROSECOND, r.trx_wait_started, NOW(6)) / 1000000 AS wait_seconds,
+	coalesce(w.lock_type, '') AS lock_type,
+	b.trx_mysql_thread_id AS blocking_pid, coalesce(bp.user, '') AS blocking_user,
+	coalesce(b.trx_query, '') AS blocking_query, b.trx_state AS blocking_state
+	FROM (
+		SELECT l.requesting_engine_transaction_id AS requesting_trx_id,
+			l.blocking_engine_transaction_id AS blocking_trx_id, d.lock_type
+		FROM performance_schema.data_lock_waits l
+		JOIN performance_schema.data_locks d ON d.engine_lock_id = l.requesting_engine_lock_id
+	) w
+	JOIN information_schema.innodb_trx r ON r.trx_id = w.requesting_trx_id
+	JOIN information_schema.innodb_trx b ON b.trx_id = w.blocking_trx_id
+	LEFT JOIN information_schema.processlist rp ON rp.id = r.trx_mysql_thread_id
+	LEFT JOIN information_schema.processlist bp ON bp.id = b.trx_mysql_thread_id
+	ORDER BY wait_seconds DESC`
+
+// Locks returns the current lock waits, longest first, pairing every blocked
+// session with the sessions blocking it. It uses pg_blocking_pids on
+// PostgreSQL and performance_schema.data_lock_waits with innodb_trx on MySQL
+// 8.0 or later.
+func Locks(ctx context.Context, db *squealx.DB) ([]LockWait, error) {
+	var query string
+	switch squealx.Dialect(db.DriverName()) {
+	case squealx.DialectPostgres:
+		query = postgresLocks
+	case squealx.DialectMySQL:
+		query = mysqlLocks
+	default:
+		return nil, unsupported("lock inspection", db)
+	}
+	var waits []LockWait
+	err := db.SelectContext(ctx, &waits, query)
+	return waits, err
+}
+
+// LongLockWaits returns the lock waits that have lasted longer than
+// threshold, for alerting.
+func LongLockWaits(ctx context.Context, db *squealx.DB, threshold time.Duration) ([]LockWait, error) {
+	waits, err := Locks(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	long := waits[:0]
+	for _, wait := range waits {
+		if wait.Wait() > threshold {
+			long = append(long, wait)
+		}
+	}
+	return long, nil
+}
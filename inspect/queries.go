@@ -0,0 +1,169 @@
+package inspect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/oarkflow/squealx"
+)
+
+// ErrNoSession is returned by Cancel and Terminate when pid does not match a
+// session.
+var ErrNoSession = errors.New("inspect: no such session")
+
+// ErrNoTag is returned by Guard.Check when the Guard has no Tag, which
+// would let it cancel the statements of every application on the server.
+var ErrNoTag = errors.New("inspect: guard requires a tag")
+
+// ActiveQuery is a statement currently running on the server.
+type ActiveQuery struct {
+	PID             int64   `db:"pid"`
+	User            string  `db:"user_name"`
+	Application     string  `db:"application"`
+	State           string  `db:"state"`
+	Query           string  `db:"query"`
+	DurationSeconds float64 `db:"duration_seconds"`
+	WaitEvent       string  `db:"wait_event"`
+}
+
+// Duration returns how long the statement has been running.
+func (q ActiveQuery) Duration() time.Duration {
+	return time.Duration(q.DurationSeconds * float64(time.Second))
+}
+
+const postgresActiveQueries = `SELECT pid, coalesce(usename, '') AS user_name,
+	coalesce(application_name, '') AS application, coalesce(state, '') AS state, query,
+	coalesce(extract(epoch FROM now() - query_start), 0)::float8 AS duration_seconds,
+	coalesce(wait_event, '') AS wait_event
+	FROM pg_stat_activity
+	WHERE state <> 'idle' AND pid <> pg_backend_pid() AND backend_type = 'client backend'
+	ORDER BY duration_seconds DESC`
+
+const mysqlActiveQueries = `SELECT id AS pid, coalesce(user, '') AS user_name, coalesce(host, '') AS application,
+	coalesce(state, command) AS state, coalesce(info, '') AS query, time AS duration_seconds, '' AS wait_event
+	FROM information_schema.processlist
+	WHERE command NOT IN ('Sleep', 'Daemon', 'Binlog Dump') AND id <> CONNECTION_ID()
+	ORDER BY time DESC`
+
+// ActiveQueries returns the statements running on the server, longest
+// running first, excluding the session issuing the inspection. On MySQL
+// Application holds the client host.
+func ActiveQueries(ctx context.Context, db *squealx.DB) ([]ActiveQuery, error) {
+	var query string
+	switch squealx.Dialect(db.DriverName()) {
+	case squealx.DialectPostgres:
+		query = postgresActiveQueries
+	case squealx.DialectMySQL:
+		query = mysqlActiveQueries
+	default:
+		return nil, unsupported("query inspection", db)
+	}
+	var queries []ActiveQuery
+	err := db.SelectContext(ctx, &queries, query)
+	return queries, err
+}
+
+// Cancel cancels the statement running in session pid, leaving the session
+// open.
+func Cancel(ctx context.Context, db *squealx.DB, pid int64) error {
+	return signal(ctx, db, pid, "pg_cancel_backend", "KILL QUERY")
+}
+
+// Terminate closes session pid, rolling back its open transaction.
+func Terminate(ctx context.Context, db *squealx.DB, pid int64) error {
+	return signal(ctx, db, pid, "pg_terminate_backend", "KILL CONNECTION")
+}
+
+func signal(ctx context.Context, db *squealx.DB, pid int64, pgFunc, mysqlStmt string) error {
+	switch squealx.Dialect(db.DriverName()) {
+	case squealx.DialectPostgres:
+		var ok bool
+		if err := db.QueryRowxContext(ctx, "SELECT "+pgFunc+"($1)", pid).Scan(&ok); err != nil {
+			return err
+		}
+		if !ok {
+			return ErrNoSession
+		}
+		return nil
+	case squealx.DialectMySQL:
+		// KILL does not accept placeholders; pid is an integer.
+		_, err := db.ExecContext(ctx, fmt.Sprintf("%s %d", mysqlStmt, pid))
+		if err != nil && strings.Contains(err.Error(), "Unknown thread id") {
+			return ErrNoSession
+		}
+		return err
+	}
+	return unsupported("query cancellation", db)
+}
+
+// Guard cancels statements of this application that run for longer than
+// MaxDuration. Statements are recognised by Tag, a marker the application
+// adds to its queries and which is required. The application usually
+// registers squealx.QueryTagsRewriter and tags its contexts with
+// sqlctx.WithQueryTags, the Guard looking for one of these tags:
+//
+//	db.UseRewriter(squealx.QueryTagsRewriter())
+//	ctx = sqlctx.WithQueryTags(ctx, map[string]string{"app": "billing"})
+//	guard := &inspect.Guard{DB: db, MaxDuration: time.Minute, Tag: squealx.QueryTag("app", "billing")}
+type Guard struct {
+	DB          *squealx.DB
+	MaxDuration time.Duration
+	Tag         string
+	// Terminate closes the offending sessions instead of cancelling the
+	// statement.
+	Terminate bool
+	// OnAction, if set, is called for every statement acted upon, with the
+	// result of the cancellation.
+	OnAction func(query ActiveQuery, err error)
+}
+
+// Check cancels the offending statements once and returns them.
+func (g *Guard) Check(ctx context.Context) ([]ActiveQuery, error) {
+	if g.Tag == "" {
+		return nil, ErrNoTag
+	}
+	queries, err := ActiveQueries(ctx, g.DB)
+	if err != nil {
+		return nil, err
+	}
+	var acted []ActiveQuery
+	for _, q := range queries {
+		if q.Duration() <= g.MaxDuration || !strings.Contains(q.Query, g.Tag) {
+			continue
+		}
+		if g.Terminate {
+			err = Terminate(ctx, g.DB, q.PID)
+		} else {
+			err = Cancel(ctx, g.DB, q.PID)
+		}
+		if errors.Is(err, ErrNoSession) {
+			continue
+		}
+		if g.OnAction != nil {
+			g.OnAction(q, err)
+		}
+		acted = append(acted, q)
+	}
+	return acted, nil
+}
+
+// Run calls Check every interval until ctx is done. It returns at once for
+// a Guard without Tag.
+func (g *Guard) Run(ctx context.Context, interval time.Duration) {
+	if g.Tag == "" {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, _ = g.Check(ctx)
+		}
+	}
+}
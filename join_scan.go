@@ -0,0 +1,227 @@
+package squealx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/oarkflow/squealx/reflectx"
+)
+
+// JoinSpec maps column prefixes, such as "u.", to a value of the struct
+// scanned from the columns carrying the prefix, such as &User{}. The query
+// must alias its columns with the prefix, as in `SELECT u.id AS "u.id"`,
+// since drivers report bare column names.
+type JoinSpec map[string]any
+
+// JoinedRow holds the structs of one row scanned by SelectJoined, keyed by
+// the prefixes of the JoinSpec. Each value is a pointer to a new struct of
+// the type given in the spec, or nil when all its columns are NULL.
+type JoinedRow map[string]any
+
+// Joined returns the struct of row scanned from the columns of prefix, or
+// nil when it is missing or of another type.
+func Joined[T any](row JoinedRow, prefix string) *T {
+	v, _ := row[prefix].(*T)
+	return v
+}
+
+// SelectJoined executes a query joining several tables and splits the
+// columns of each row by the prefixes of spec, scanning each group into its
+// own struct. dest is a pointer to a slice of either
+//
+//   - JoinedRow, receiving one struct per prefix; or
+//   - a parent struct, receiving the columns of the prefix whose spec value
+//     has the parent's type, while the other prefixes are scanned into the
+//     parent's fields of their type, which may be pointers.
+//
+// Child structs whose columns are all NULL, as produced by a LEFT JOIN
+// without match, are left nil. Columns matching no prefix are ignored.
+func SelectJoined(q Queryer, dest any, query string, spec JoinSpec, args ...any) error {
+	query = SanitizeQuery(query, args...)
+	rows, err := q.Queryx(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	return scanJoined(rows, dest, spec)
+}
+
+// SelectJoinedContext is like SelectJoined, with a context.
+func SelectJoinedContext(ctx context.Context, q QueryerContext, dest any, query string, spec JoinSpec, args ...any) error {
+	rows, err := q.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	return scanJoined(rows, dest, spec)
+}
+
+var joinedRowType = reflect.TypeOf(JoinedRow{})
+
+// joinGroup is the set of columns of one prefix of a JoinSpec.
+type joinGroup struct {
+	prefix  string
+	typ     reflect.Type
+	root    bool
+	field   []int
+	columns []int
+	fields  [][]int
+}
+
+func scanJoined(rows *Rows, dest any, spec JoinSpec) error {
+	value := reflect.ValueOf(dest)
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return errors.New("must pass a non-nil pointer to SelectJoined destination")
+	}
+	slice, err := baseType(value.Type(), reflect.Slice)
+	if err != nil {
+		return err
+	}
+	direct := reflect.Indirect(value)
+	direct.SetLen(0)
+	isPtr := slice.Elem().Kind() == reflect.Ptr
+	base := reflectx.Deref(slice.Elem())
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	groups, err := joinGroups(rows.Mapper, base, columns, spec)
+	if err != nil {
+		return err
+	}
+	values := make([]any, len(columns))
+	for i := range values {
+		values[i] = new(any)
+	}
+	for rows.Next() {
+		if err := rows.Scan(values...); err != nil {
+			return err
+		}
+		vp := reflect.New(base)
+		if base == joinedRowType {
+			vp.Elem().Set(reflect.MakeMap(base))
+		}
+		for _, g := range groups {
+			if err := g.assign(vp.Elem(), values); err != nil {
+				return err
+			}
+		}
+		if isPtr {
+			direct.Set(reflect.Append(direct, vp))
+		} else {
+			direct.Set(reflect.Append(direct, vp.Elem()))
+		}
+	}
+	return rows.Err()
+}
+
+// joinGroups splits columns by the prefixes of spec, longest first, and
+// locates the struct of each prefix in base.
+func joinGroups(m *reflectx.Mapper, base reflect.Type, columns []string, spec JoinSpec) ([]*joinGroup, error) {
+	prefixes := make([]string, 0, len(spec))
+	for prefix := range spec {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+	groups := make(map[string]*joinGroup, len(spec))
+	var ordered []*joinGroup
+	for i, column := range columns {
+		for _, prefix := range prefixes {
+			if !strings.HasPrefix(column, prefix) {
+				continue
+			}
+			g, ok := groups[prefix]
+			if !ok {
+				var err error
+				if g, err = newJoinGroup(m, base, prefix, spec[prefix]); err != nil {
+					return nil, err
+				}
+				groups[prefix] = g
+				ordered = append(ordered, g)
+			}
+			g.columns = append(g.columns, i)
+			g.fields = append(g.fields, m.TraversalsByName(g.typ, []string{column[len(prefix):]})[0])
+			break
+		}
+	}
+	return ordered, nil
+}
+
+func newJoinGroup(m *reflectx.Mapper, base reflect.Type, prefix string, proto any) (*joinGroup, error) {
+	typ := reflectx.Deref(reflect.TypeOf(proto))
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("join spec %q: expected a struct, got %T", prefix, proto)
+	}
+	g := &joinGroup{prefix: prefix, typ: typ}
+	switch {
+	case base == joinedRowType:
+	case base == typ:
+		g.root = true
+	default:
+		if base.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("SelectJoined destination must be a struct or JoinedRow, got %s", base)
+		}
+		for _, fi := range m.TypeMap(base).Index {
+			if fi.Field.Type == typ || fi.Field.Type == reflect.PointerTo(typ) {
+				g.field = fi.Index
+				break
+			}
+		}
+		if g.field == nil {
+			return nil, fmt.Errorf("join spec %q: %s has no field of type %s", prefix, base, typ)
+		}
+	}
+	return g, nil
+}
+
+// assign sets the struct of g in row from the scanned values.
+func (g *joinGroup) assign(row reflect.Value, values []any) error {
+	if !g.root {
+		null := true
+		for _, i := range g.columns {
+			if *values[i].(*any) != nil {
+				null = false
+				break
+			}
+		}
+		if null {
+			return nil
+		}
+	}
+	var target reflect.Value
+	switch {
+	case g.root:
+		target = row
+	case g.field != nil:
+		target = reflect.Indirect(reflectx.FieldByIndexes(row, g.field))
+	default:
+		vp := reflect.New(g.typ)
+		row.SetMapIndex(reflect.ValueOf(g.prefix), vp)
+		target = vp.Elem()
+	}
+	for n, i := range g.columns {
+		src := *values[i].(*any)
+		if len(g.fields[n]) == 0 || src == nil {
+			continue
+		}
+		if err := setJoinedField(reflectx.FieldByIndexes(target, g.fields[n]), src); err != nil {
+			return fmt.Errorf("join spec %q: %w", g.prefix, err)
+		}
+	}
+	return nil
+}
+
+func setJoinedField(f reflect.Value, src any) error {
+	if scanner, ok := reflectx.ScanDest(f).(sql.Scanner); ok {
+		return scanner.Scan(src)
+	}
+	if f.Kind() == reflect.Ptr {
+		f = f.Elem()
+	}
+	return reflectx.Assign(f, src)
+}
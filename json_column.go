@@ -0,0 +1,80 @@
+package squealx
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// JSONColumn is a JSON column holding a T, for tables storing a document
+// next to their relational columns. It scans by unmarshalling the column
+// into Data and is written as the JSON encoding of Data, as text so that
+// MySQL JSON columns accept it. A NULL column scans with Valid false, and
+// JSONColumn is written as NULL while Valid is false.
+type JSONColumn[T any] struct {
+	Data  T
+	Valid bool // Valid is true if the column is not NULL
+}
+
+// NewJSONColumn returns a valid JSONColumn holding data.
+func NewJSONColumn[T any](data T) JSONColumn[T] {
+	return JSONColumn[T]{Data: data, Valid: true}
+}
+
+// Scan implements the sql.Scanner interface.
+func (j *JSONColumn[T]) Scan(src any) error {
+	var data []byte
+	switch src := src.(type) {
+	case nil:
+		var zero T
+		j.Data, j.Valid = zero, false
+		return nil
+	case []byte:
+		data = src
+	case string:
+		data = []byte(src)
+	default:
+		return fmt.Errorf("cannot scan %T into JSONColumn", src)
+	}
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	j.Data, j.Valid = v, true
+	return nil
+}
+
+// Value implements the driver.Valuer interface.
+func (j JSONColumn[T]) Value() (driver.Value, error) {
+	if !j.Valid {
+		return nil, nil
+	}
+	b, err := json.Marshal(j.Data)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// MarshalJSON encodes Data, or null when j is not valid.
+func (j JSONColumn[T]) MarshalJSON() ([]byte, error) {
+	if !j.Valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(j.Data)
+}
+
+// UnmarshalJSON decodes data into Data, null making j not valid.
+func (j *JSONColumn[T]) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		var zero T
+		j.Data, j.Valid = zero, false
+		return nil
+	}
+	if err := json.Unmarshal(data, &j.Data); err != nil {
+		return err
+	}
+	j.Valid = true
+	return nil
+}
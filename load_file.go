@@ -0,0 +1,242 @@
+package squealx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/oarkflow/squealx/sqltoken"
+)
+
+// ErrLoadNoTx is returned by LoadFile run with LoadInTx on an Execer which
+// cannot begin a transaction.
+var ErrLoadNoTx = errors.New("squealx: LoadFile in a transaction needs a *DB or *Tx")
+
+// LoadOptions configures LoadFile and LoadFileContext, see their options.
+type LoadOptions struct {
+	InTx            bool
+	Progress        func(LoadStatement)
+	ContinueOnError bool
+	Vars            map[string]string
+}
+
+type LoadOption func(opts *LoadOptions)
+
+func loadLoadOptions(options ...LoadOption) *LoadOptions {
+	opts := new(LoadOptions)
+	for _, option := range options {
+		option(opts)
+	}
+	return opts
+}
+
+// LoadInTx makes LoadFile run the statements of the file in a transaction,
+// committed when all of them succeed and rolled back otherwise, so that a
+// script is applied whole or not at all on databases with transactional
+// DDL. On a *Tx, the statements run in it and it is left to the caller.
+func LoadInTx() LoadOption {
+	return func(opts *LoadOptions) {
+		opts.InTx = true
+	}
+}
+
+// LoadProgress makes LoadFile call fn after every statement of the file.
+func LoadProgress(fn func(LoadStatement)) LoadOption {
+	return func(opts *LoadOptions) {
+		opts.Progress = fn
+	}
+}
+
+// LoadContinueOnError makes LoadFile run the statements following a
+// failed one, returning a *LoadError listing the failures once all have
+// run. In a transaction, it is still rolled back; PostgreSQL fails every
+// statement following an error in a transaction.
+func LoadContinueOnError() LoadOption {
+	return func(opts *LoadOptions) {
+		opts.ContinueOnError = true
+	}
+}
+
+// LoadVars makes LoadFile replace ${NAME} in the file with vars["NAME"],
+// such as the schema to create objects in. The values are inserted as is,
+// and must come from trusted configuration. Variables missing from vars
+// fail the load before any statement runs.
+func LoadVars(vars map[string]string) LoadOption {
+	return func(opts *LoadOptions) {
+		opts.Vars = vars
+	}
+}
+
+// LoadStatement reports a statement of a file run by LoadFile. Index counts
+// statements from 0 out of Total; Err is that of the statement.
+type LoadStatement struct {
+	Index     int
+	Total     int
+	Statement string
+	Duration  time.Duration
+	Err       error
+}
+
+// LoadError is returned by LoadFile with LoadContinueOnError when
+// statements failed, listing them in order.
+type LoadError struct {
+	Total  int
+	Failed []LoadStatement
+}
+
+func (e *LoadError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "squealx: %d of %d statements failed", len(e.Failed), e.Total)
+	for _, failed := range e.Failed {
+		fmt.Fprintf(&b, "; statement %d: %v", failed.Index+1, failed.Err)
+	}
+	return b.String()
+}
+
+// Unwrap returns the errors of the failed statements.
+func (e *LoadError) Unwrap() []error {
+	errs := make([]error, len(e.Failed))
+	for i, failed := range e.Failed {
+		errs[i] = failed.Err
+	}
+	return errs
+}
+
+// readLoadFile returns the contents of the file at path.
+func readLoadFile(path string) (string, error) {
+	realpath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	contents, err := os.ReadFile(realpath)
+	if err != nil {
+		return "", err
+	}
+	return string(contents), nil
+}
+
+var loadVarRE = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// substituteVars replaces the ${NAME} variables of contents with their
+// value in vars.
+func substituteVars(contents string, vars map[string]string) (string, error) {
+	var missing []string
+	contents = loadVarRE.ReplaceAllStringFunc(contents, func(match string) string {
+		name := match[2 : len(match)-1]
+		value, ok := vars[name]
+		if !ok {
+			if !slices.Contains(missing, name) {
+				missing = append(missing, name)
+			}
+			return match
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("squealx: undefined variables %s", strings.Join(missing, ", "))
+	}
+	return contents, nil
+}
+
+// splitStatements splits contents into its statements, tokenized as the
+// dialect of driverName so that semicolons in literals, quoted identifiers,
+// comments and dollar-quoted bodies do not split them.
+func splitStatements(driverName, contents string) []string {
+	var config sqltoken.Config
+	switch Dialect(driverName) {
+	case DialectMySQL:
+		config = sqltoken.MySQLConfig()
+	case DialectMSSQL:
+		config = sqltoken.SQLServerConfig()
+	default:
+		config = sqltoken.PostgreSQLConfig()
+	}
+	return sqltoken.Tokenize(contents, config).CmdSplit().Strings()
+}
+
+// loadFile runs contents on e as opts say: as a single statement without
+// options, as LoadFile always did, and statement by statement otherwise.
+func loadFile(ctx context.Context, e ExecerContext, contents string, opts *LoadOptions) (*sql.Result, error) {
+	if opts.Vars != nil {
+		var err error
+		if contents, err = substituteVars(contents, opts.Vars); err != nil {
+			return nil, err
+		}
+	}
+	if !opts.InTx && opts.Progress == nil && !opts.ContinueOnError {
+		res, err := e.ExecContext(ctx, contents)
+		return &res, err
+	}
+	var driverName string
+	if d, ok := e.(interface{ DriverName() string }); ok {
+		driverName = d.DriverName()
+	}
+	statements := splitStatements(driverName, contents)
+	if !opts.InTx {
+		return execStatements(ctx, e, statements, opts)
+	}
+	if _, ok := e.(*Tx); ok {
+		return execStatements(ctx, e, statements, opts)
+	}
+	db, ok := e.(interface {
+		BeginTxx(ctx context.Context, opts *sql.TxOptions) (*Tx, error)
+	})
+	if !ok {
+		return nil, ErrLoadNoTx
+	}
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := execStatements(ctx, tx, statements, opts)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return res, errors.Join(err, rbErr)
+		}
+		return res, err
+	}
+	return res, tx.Commit()
+}
+
+// execStatements runs statements on e in order, returning the result of the
+// last one.
+func execStatements(ctx context.Context, e ExecerContext, statements []string, opts *LoadOptions) (*sql.Result, error) {
+	var res sql.Result
+	var failed []LoadStatement
+	for i, statement := range statements {
+		start := time.Now()
+		r, err := e.ExecContext(ctx, statement)
+		step := LoadStatement{Index: i, Total: len(statements), Statement: statement, Duration: time.Since(start), Err: err}
+		if opts.Progress != nil {
+			opts.Progress(step)
+		}
+		if err != nil {
+			if !opts.ContinueOnError || ctx.Err() != nil {
+				return &res, fmt.Errorf("squealx: statement %d of %d: %w", i+1, len(statements), err)
+			}
+			failed = append(failed, step)
+			continue
+		}
+		res = r
+	}
+	if len(failed) > 0 {
+		return &res, &LoadError{Total: len(statements), Failed: failed}
+	}
+	return &res, nil
+}
+
+// execerContext adapts an Execer to ExecerContext, ignoring the context.
+type execerContext struct {
+	Execer
+}
+
+func (e execerContext) ExecContext(_ context.Context, query string, args ...any) (sql.Result, error) {
+	return e.Exec(query, args...)
+}
@@ -0,0 +1,216 @@
+// Package loader batches lookups by key, in the manner of DataLoader.
+//
+// Loads of single keys issued concurrently, such as by the resolvers of a
+// GraphQL list, are collected for a short window and fetched together with
+// one IN query, whose rows are then handed back to each caller by key.
+package loader
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oarkflow/squealx"
+)
+
+// Loader loads rows of type V by keys of type K.
+type Loader[K comparable, V any] struct {
+	db        *squealx.DB
+	query     string
+	keyFields []string
+	wait      time.Duration
+	maxBatch  int
+
+	mu    sync.Mutex
+	batch *batch[K, V]
+}
+
+type batch[K comparable, V any] struct {
+	ctx    context.Context
+	keys   []K
+	seen   map[K]bool
+	rows   map[string][]V
+	err    error
+	done   chan struct{}
+	closed bool
+}
+
+// Option configures a Loader.
+type Option func(*options)
+
+type options struct {
+	wait     time.Duration
+	maxBatch int
+}
+
+// WithWait sets how long keys are collected before the batch is fetched.
+// Defaults to 2ms.
+func WithWait(wait time.Duration) Option {
+	return func(o *options) {
+		o.wait = wait
+	}
+}
+
+// WithMaxBatch fetches a batch as soon as it has n keys. Defaults to 500.
+func WithMaxBatch(n int) Option {
+	return func(o *options) {
+		o.maxBatch = n
+	}
+}
+
+// New returns a Loader running query, which must take the batched keys as
+// its single IN (?) argument, e.g.
+//
+//	SELECT * FROM users WHERE id IN (?)
+//
+// keyField is the column of the rows holding their key.
+//
+// For composite keys, keyField lists the columns of the key separated by
+// commas and K is an array of their values, batched as row values:
+//
+//	loader.New[[2]int64, Note](db,
+//		"SELECT * FROM notes WHERE (work_item_id, encounter_id) IN (?)",
+//		"work_item_id,encounter_id")
+func New[K comparable, V any](db *squealx.DB, query, keyField string, opts ...Option) *Loader[K, V] {
+	o := options{wait: 2 * time.Millisecond, maxBatch: 500}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	keyFields := strings.Split(keyField, ",")
+	for i := range keyFields {
+		keyFields[i] = strings.TrimSpace(keyFields[i])
+	}
+	return &Loader[K, V]{db: db, query: query, keyFields: keyFields, wait: o.wait, maxBatch: o.maxBatch}
+}
+
+// Load returns the row with key, or sql.ErrNoRows when there is none.
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	var v V
+	rows, err := l.LoadAll(ctx, key)
+	if err != nil {
+		return v, err
+	}
+	if len(rows) == 0 {
+		return v, sql.ErrNoRows
+	}
+	return rows[0], nil
+}
+
+// LoadAll returns all rows with key, for one-to-many relations.
+func (l *Loader[K, V]) LoadAll(ctx context.Context, key K) ([]V, error) {
+	b := l.add(ctx, key)
+	select {
+	case <-b.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if b.err != nil {
+		return nil, b.err
+	}
+	return b.rows[l.keyString(key)], nil
+}
+
+// add adds key to the pending batch, starting a new one if needed.
+func (l *Loader[K, V]) add(ctx context.Context, key K) *batch[K, V] {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b := l.batch
+	if b == nil {
+		// The batch outlives the caller starting it, but keeps the values
+		// of its context, such as those read by hooks.
+		b = &batch[K, V]{ctx: context.WithoutCancel(ctx), seen: make(map[K]bool), done: make(chan struct{})}
+		l.batch = b
+		time.AfterFunc(l.wait, func() {
+			l.dispatch(b)
+		})
+	}
+	if !b.seen[key] {
+		b.seen[key] = true
+		b.keys = append(b.keys, key)
+	}
+	if len(b.keys) >= l.maxBatch {
+		l.batch = nil
+		b.closed = true
+		go l.fetch(b)
+	}
+	return b
+}
+
+// dispatch fetches b unless it was already fetched for being full.
+func (l *Loader[K, V]) dispatch(b *batch[K, V]) {
+	l.mu.Lock()
+	if b.closed {
+		l.mu.Unlock()
+		return
+	}
+	b.closed = true
+	if l.batch == b {
+		l.batch = nil
+	}
+	l.mu.Unlock()
+	l.fetch(b)
+}
+
+func (l *Loader[K, V]) fetch(b *batch[K, V]) {
+	defer close(b.done)
+	var rows []V
+	if b.err = l.db.InSelectChunkedContext(b.ctx, &rows, l.query, b.keys, squealx.ChunkOptions{}); b.err != nil {
+		return
+	}
+	b.rows = make(map[string][]V, len(b.keys))
+	for _, row := range rows {
+		fields, err := l.db.GetFields(row)
+		if err != nil {
+			b.err = err
+			return
+		}
+		parts := make([]string, len(l.keyFields))
+		for i, keyField := range l.keyFields {
+			key, ok := fields[keyField]
+			if !ok {
+				b.err = fmt.Errorf("loader: rows have no field %s", keyField)
+				return
+			}
+			parts[i] = keyString(key)
+		}
+		k := strings.Join(parts, keySeparator)
+		b.rows[k] = append(b.rows[k], row)
+	}
+}
+
+// keySeparator separates the values of composite keys in their text.
+const keySeparator = "\x1f"
+
+// keyString returns the text key is matched by, joining the values of a
+// composite key.
+func (l *Loader[K, V]) keyString(key K) string {
+	v := reflect.ValueOf(key)
+	if len(l.keyFields) == 1 || v.Kind() != reflect.Array {
+		return keyString(key)
+	}
+	parts := make([]string, v.Len())
+	for i := range parts {
+		parts[i] = keyString(v.Index(i).Interface())
+	}
+	return strings.Join(parts, keySeparator)
+}
+
+// keyString returns the text keys are matched by, as drivers may scan them
+// into another type than K.
+func keyString(key any) string {
+	if b, ok := key.([]byte); ok {
+		return string(b)
+	}
+	v := reflect.ValueOf(key)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.IsValid() && v.CanInterface() {
+		key = v.Interface()
+	}
+	return fmt.Sprint(key)
+}
@@ -0,0 +1,124 @@
+// Package maintenance runs routine database maintenance, such as refreshing
+// planner statistics and rebuilding bloated indexes, through a squealx.DB so
+// that it shares the application's connection pool and hooks.
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/oarkflow/squealx"
+	"github.com/oarkflow/squealx/monitor/queries"
+)
+
+// ErrTablesRequired is returned by Analyze and Vacuum on MySQL, which has no
+// statement covering the whole database.
+var ErrTablesRequired = errors.New("maintenance: tables must be given on MySQL")
+
+// Analyze refreshes the planner statistics of tables, or of the whole
+// database when none are given.
+func Analyze(ctx context.Context, db *squealx.DB, tables ...string) error {
+	switch squealx.Dialect(db.DriverName()) {
+	case squealx.DialectMySQL:
+		if len(tables) == 0 {
+			return ErrTablesRequired
+		}
+		return exec(ctx, db, "ANALYZE TABLE %s", tables)
+	case squealx.DialectMSSQL:
+		if len(tables) == 0 {
+			_, err := db.ExecContext(ctx, "EXEC sp_updatestats")
+			return err
+		}
+		return exec(ctx, db, "UPDATE STATISTICS %s", tables)
+	}
+	if len(tables) == 0 {
+		_, err := db.ExecContext(ctx, "ANALYZE")
+		return err
+	}
+	return exec(ctx, db, "ANALYZE %s", tables)
+}
+
+// Vacuum reclaims the space of dead rows in tables, or in the whole database
+// when none are given. It maps to VACUUM on PostgreSQL and SQLite and to
+// OPTIMIZE TABLE on MySQL.
+func Vacuum(ctx context.Context, db *squealx.DB, tables ...string) error {
+	switch squealx.Dialect(db.DriverName()) {
+	case squealx.DialectMySQL:
+		if len(tables) == 0 {
+			return ErrTablesRequired
+		}
+		return exec(ctx, db, "OPTIMIZE TABLE %s", tables)
+	case squealx.DialectSQLite:
+		_, err := db.ExecContext(ctx, "VACUUM")
+		return err
+	case squealx.DialectPostgres:
+		if len(tables) == 0 {
+			_, err := db.ExecContext(ctx, "VACUUM")
+			return err
+		}
+		return exec(ctx, db, "VACUUM %s", tables)
+	}
+	return fmt.Errorf("maintenance: vacuum is not supported on %s", db.DriverName())
+}
+
+// ReindexConcurrently rebuilds indexes without blocking writes to their
+// tables. It requires PostgreSQL 12 or later, and must not run inside a
+// transaction.
+func ReindexConcurrently(ctx context.Context, db *squealx.DB, indexes ...string) error {
+	if squealx.Dialect(db.DriverName()) != squealx.DialectPostgres {
+		return fmt.Errorf("maintenance: concurrent reindex is not supported on %s", db.DriverName())
+	}
+	return exec(ctx, db, "REINDEX INDEX CONCURRENTLY %s", indexes)
+}
+
+// Bloat is the estimated bloat of a table and one of its indexes.
+type Bloat struct {
+	Table              string  `db:"relation"`
+	Tuples             int64   `db:"tups"`
+	Pages              int64   `db:"pages"`
+	ExpectedPages      float64 `db:"otta"`
+	TableBloat         float64 `db:"tbloat"`
+	WastedPages        float64 `db:"wastedpages"`
+	WastedBytes        float64 `db:"wastedbytes"`
+	WastedSize         int64   `db:"wastedsize"`
+	Index              string  `db:"iname"`
+	IndexTuples        int64   `db:"itups"`
+	IndexPages         int64   `db:"ipages"`
+	ExpectedIndexPages float64 `db:"iotta"`
+	IndexBloat         float64 `db:"ibloat"`
+	WastedIndexPages   float64 `db:"wastedipages"`
+	WastedIndexBytes   float64 `db:"wastedibytes"`
+	WastedIndexSize    int64   `db:"wastedisize"`
+	TotalWastedBytes   float64 `db:"totalwastedbytes"`
+}
+
+// BloatReport estimates table and index bloat in the public schema of a
+// PostgreSQL database, most wasteful first. It uses the query behind the
+// table_and_index_bloat statistic of the monitor package, which relies on
+// current statistics, so run Analyze first on tables that changed a lot.
+func BloatReport(ctx context.Context, db *squealx.DB) ([]Bloat, error) {
+	if squealx.Dialect(db.DriverName()) != squealx.DialectPostgres {
+		return nil, fmt.Errorf("maintenance: bloat report is not supported on %s", db.DriverName())
+	}
+	var report []Bloat
+	if err := db.SelectContext(ctx, &report, queries.GetTableAndIndexBloat()); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(report, func(i, j int) bool {
+		return report[i].TotalWastedBytes > report[j].TotalWastedBytes
+	})
+	return report, nil
+}
+
+// exec runs the statement of format for each of names, quoted.
+func exec(ctx context.Context, db *squealx.DB, format string, names []string) error {
+	dialect := squealx.Dialect(db.DriverName())
+	for _, name := range names {
+		if _, err := db.ExecContext(ctx, fmt.Sprintf(format, squealx.QuoteName(dialect, name))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
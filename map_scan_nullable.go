@@ -0,0 +1,56 @@
+package squealx
+
+import "strings"
+
+// Value is a column value scanned by MapScanNullable.
+type Value struct {
+	// Valid is false when the value is NULL.
+	Valid bool
+	// V is the value, as MapScan returns it, and nil when it is NULL.
+	V any
+	// Type is the upper case type name of the column reported by the
+	// driver, such as "VARCHAR", empty when unknown.
+	Type string
+}
+
+// MapScanNullable is like MapScan, but scans into Values telling NULL apart
+// from empty strings and zero values, which the conversions of the map scans
+// of Select and Get do not. Row transformers do not apply to it.
+func MapScanNullable(r ColScanner, dest map[string]Value, opts ...ScanOption) error {
+	columns, err := r.Columns()
+	if err != nil {
+		return err
+	}
+	keys, err := loadScanOptions(opts...).mapKeys(columns)
+	if err != nil {
+		return err
+	}
+	columnTypes, err := r.ColumnTypes()
+	if err != nil {
+		return err
+	}
+	values := make([]any, len(columns))
+	prepareValues(values, columnTypes, columns)
+	if err := r.Scan(values...); err != nil {
+		return err
+	}
+	for idx := range columns {
+		value := Value{V: rowsTimePolicy(r).scanned(columnTypes, idx, scannedValue(columnTypes, idx, values[idx]))}
+		value.Valid = value.V != nil
+		if idx < len(columnTypes) {
+			value.Type = strings.ToUpper(columnTypes[idx].DatabaseTypeName())
+		}
+		dest[keys[idx]] = value
+	}
+	return r.Err()
+}
+
+// MapScanNullable using this Rows.
+func (r *Rows) MapScanNullable(dest map[string]Value, opts ...ScanOption) error {
+	return MapScanNullable(r, dest, opts...)
+}
+
+// MapScanNullable using this Row.
+func (r *Row) MapScanNullable(dest map[string]Value, opts ...ScanOption) error {
+	return MapScanNullable(r, dest, opts...)
+}
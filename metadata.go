@@ -0,0 +1,91 @@
+package squealx
+
+import (
+	"database/sql"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ColumnMetadata describes a column of a result set, as reported by the
+// driver but normalized across drivers. Facts the driver does not report
+// are nil.
+type ColumnMetadata struct {
+	Name string `json:"name"`
+	// DatabaseType is the upper case type name of the driver, such as
+	// "VARCHAR" or "INT8", empty when unknown.
+	DatabaseType string `json:"database_type,omitempty"`
+	Nullable     *bool  `json:"nullable,omitempty"`
+	// Length is the length of variable length types, such as VARCHAR(255).
+	// Unbounded types, such as TEXT, have none.
+	Length *int64 `json:"length,omitempty"`
+	// Precision and Scale are those of decimal types, such as
+	// NUMERIC(10, 2).
+	Precision *int64 `json:"precision,omitempty"`
+	Scale     *int64 `json:"scale,omitempty"`
+	// ScanType is the Go type of the values of the column returned by
+	// MapScan and SliceScan: that of the scanner registered with
+	// RegisterScanType for DatabaseType, or the type scanned by the driver
+	// with the sql.Null wrappers removed and sql.RawBytes as string.
+	ScanType reflect.Type `json:"-"`
+}
+
+// Metadata returns the metadata of the columns of the result set, in order.
+func (r *Rows) Metadata() ([]ColumnMetadata, error) {
+	columnTypes, err := r.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	metadata := make([]ColumnMetadata, len(columnTypes))
+	for i := range columnTypes {
+		metadata[i] = columnMetadata(columnTypes, i)
+	}
+	return metadata, nil
+}
+
+var (
+	anyType      = reflect.TypeOf((*any)(nil)).Elem()
+	rawBytesType = reflect.TypeOf(sql.RawBytes(nil))
+	// nullTypes maps the sql.Null wrappers to the type of their values.
+	nullTypes = map[reflect.Type]reflect.Type{
+		reflect.TypeOf(sql.NullString{}):  reflect.TypeOf(""),
+		reflect.TypeOf(sql.NullInt64{}):   reflect.TypeOf(int64(0)),
+		reflect.TypeOf(sql.NullInt32{}):   reflect.TypeOf(int32(0)),
+		reflect.TypeOf(sql.NullInt16{}):   reflect.TypeOf(int16(0)),
+		reflect.TypeOf(sql.NullByte{}):    reflect.TypeOf(byte(0)),
+		reflect.TypeOf(sql.NullFloat64{}): reflect.TypeOf(float64(0)),
+		reflect.TypeOf(sql.NullBool{}):    reflect.TypeOf(false),
+		reflect.TypeOf(sql.NullTime{}):    reflect.TypeOf(time.Time{}),
+	}
+)
+
+func columnMetadata(columnTypes []*sql.ColumnType, idx int) ColumnMetadata {
+	columnType := columnTypes[idx]
+	m := ColumnMetadata{
+		Name:         columnType.Name(),
+		DatabaseType: strings.ToUpper(columnType.DatabaseTypeName()),
+		ScanType:     anyType,
+	}
+	if nullable, ok := columnType.Nullable(); ok {
+		m.Nullable = &nullable
+	}
+	// Drivers report unbounded types with the largest length.
+	if length, ok := columnType.Length(); ok && length > 0 && length < math.MaxInt32 {
+		m.Length = &length
+	}
+	if precision, scale, ok := columnType.DecimalSize(); ok && precision > 0 {
+		m.Precision, m.Scale = &precision, &scale
+	}
+	if newValue, ok := registeredScanType(columnTypes, idx); ok {
+		m.ScanType = reflect.TypeOf(newValue()).Elem()
+	} else if t := columnType.ScanType(); t != nil {
+		m.ScanType = t
+		if base, ok := nullTypes[t]; ok {
+			m.ScanType = base
+		} else if t == rawBytesType {
+			m.ScanType = reflect.TypeOf("")
+		}
+	}
+	return m
+}
@@ -89,7 +89,7 @@ func (n *NamedStmt) Queryx(arg any) (*Rows, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Rows{SQLRows: r, Mapper: n.Stmt.Mapper, unsafe: isUnsafe(n)}, err
+	return &Rows{SQLRows: r, Mapper: n.Stmt.Mapper, unsafe: isUnsafe(n), table: n.Stmt.db.statementTable(n.Stmt.query), transformers: n.Stmt.db.rowTransformers(), times: n.Stmt.db.timePolicy(), traversals: n.Stmt.db.traversalCache()}, err
 }
 
 // QueryRowx this NamedStmt.  Because of limitations with QueryRow, this is
@@ -151,16 +151,27 @@ func prepareNamed(p namedPreparer, query string) (*NamedStmt, error) {
 
 // convertMapStringInterface attempts to convert v to map[string]any.
 // Unlike v.(map[string]any), this function works on named types that
-// are convertible to map[string]any as well.
+// are convertible to map[string]any as well, and copies the entries of
+// other maps with string keys, such as map[string][]int64.
 func convertMapStringInterface(v any) (map[string]any, bool) {
 	var m map[string]any
 	mtype := reflect.TypeOf(m)
 	t := reflect.TypeOf(v)
-	if !t.ConvertibleTo(mtype) {
+	if t == nil {
 		return nil, false
 	}
-	return reflect.ValueOf(v).Convert(mtype).Interface().(map[string]any), true
-
+	if t.ConvertibleTo(mtype) {
+		return reflect.ValueOf(v).Convert(mtype).Interface().(map[string]any), true
+	}
+	if t.Kind() != reflect.Map || t.Key().Kind() != reflect.String {
+		return nil, false
+	}
+	rv := reflect.ValueOf(v)
+	m = make(map[string]any, rv.Len())
+	for iter := rv.MapRange(); iter.Next(); {
+		m[iter.Key().String()] = iter.Value().Interface()
+	}
+	return m, true
 }
 
 func bindAnyArgs(names []string, arg any, m *reflectx.Mapper) ([]any, error) {
@@ -181,6 +192,9 @@ func bindArgs(names []string, arg any, m *reflectx.Mapper) ([]any, error) {
 	for v = reflect.ValueOf(arg); v.Kind() == reflect.Ptr; {
 		v = v.Elem()
 	}
+	if v.Kind() == reflect.Struct {
+		return bindStructArgs(names, v, m)
+	}
 
 	err := m.TraversalsByNameFunc(v.Type(), names, func(i int, t []int) error {
 		if len(t) == 0 {
@@ -656,10 +670,11 @@ func NamedQuery(e Ext, query string, arg any) (*Rows, error) {
 
 // NamedExec uses BindStruct to get a query executable by the driver and
 // then runs Exec on the result.  Returns an error from the binding
-// or the query execution itself.
+// or the query execution itself.  Struct fields may rename their parameter or
+// require the query to use it with an arg tag, see NamedArgsError.
 func NamedExec(e Ext, query string, arg any) (sql.Result, error) {
 	query = SanitizeQuery(query, arg)
-	query, arg = prepareNamedInQuery(query, arg)
+	query, arg = prepareNamedInQuery(query, arg, mapperFor(e))
 	q, args, err := bindNamedMapper(BindType(e.DriverName()), query, arg, mapperFor(e))
 	if err != nil {
 		return nil, err
@@ -676,7 +691,7 @@ var (
 // use the `?` bindVar.  The return value uses the `?` bindVar.
 func NamedIn(e Ext, query string, args any) (*Rows, error) {
 	query = SanitizeQuery(query, args)
-	query, args = prepareNamedInQuery(query, args)
+	query, args = prepareNamedInQuery(query, args, mapperFor(e))
 	q, p, err := bindNamedMapper(BindType(e.DriverName()), query, args, mapperFor(e))
 	if err != nil {
 		return nil, err
@@ -685,25 +700,51 @@ func NamedIn(e Ext, query string, args any) (*Rows, error) {
 	return e.Queryx(q, p...)
 }
 
-func prepareNamedInQuery(query string, args any) (string, any) {
+func prepareNamedInQuery(query string, args any, m *reflectx.Mapper) (string, any) {
 	matches := InReg.FindAllStringSubmatch(query, -1)
-	switch args := args.(type) {
-	case map[string]any:
-		for _, match := range matches {
-			key := strings.TrimPrefix(match[1], ":")
-			switch reflect.TypeOf(args[key]).Kind() {
-			case reflect.Slice:
-				s := reflect.ValueOf(args[key])
-				var keys []string
-				for i := 0; i < s.Len(); i++ {
-					keyToStore := fmt.Sprintf("%s_%d", key, i)
-					args[keyToStore] = s.Index(i).Interface()
-					keys = append(keys, ":"+keyToStore)
-				}
-				keyReplace := strings.Join(keys, ",")
-				query = strings.ReplaceAll(query, match[1], keyReplace)
+	if len(matches) == 0 {
+		return query, args
+	}
+	var values map[string]any
+	if mv, ok := convertMapStringInterface(args); ok {
+		values = mv
+	} else {
+		v := reflect.Indirect(reflect.ValueOf(args))
+		if v.Kind() != reflect.Struct {
+			return query, args
+		}
+		values = make(map[string]any)
+		for name, field := range m.FieldMap(v) {
+			if field.CanInterface() {
+				values[name] = field.Interface()
 			}
 		}
 	}
-	return query, args
+	// The expanded values go to a copy, so the caller's map can be reused,
+	// even concurrently.
+	var expanded map[string]any
+	for _, match := range matches {
+		key := strings.TrimPrefix(match[1], ":")
+		s, ok := asSliceForIn(values[key])
+		if !ok {
+			continue
+		}
+		if expanded == nil {
+			expanded = make(map[string]any, len(values))
+			for k, v := range values {
+				expanded[k] = v
+			}
+		}
+		var keys []string
+		for i := 0; i < s.Len(); i++ {
+			keyToStore := fmt.Sprintf("%s_%d", key, i)
+			expanded[keyToStore] = s.Index(i).Interface()
+			keys = append(keys, ":"+keyToStore)
+		}
+		query = strings.ReplaceAll(query, match[1], strings.Join(keys, ","))
+	}
+	if expanded == nil {
+		return query, args
+	}
+	return query, expanded
 }
@@ -0,0 +1,113 @@
+package squealx
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/oarkflow/squealx/reflectx"
+)
+
+// NamedArgsError is returned when binding a struct to a named query, such
+// as by NamedExec or NamedQuery, before the query runs. It lists the
+// parameters of the query the struct has no field for, and the fields of
+// the struct tagged `arg:",required"` the query does not use.
+type NamedArgsError struct {
+	Type    reflect.Type
+	Missing []string
+	Unused  []string
+}
+
+func (e *NamedArgsError) Error() string {
+	var parts []string
+	if len(e.Missing) > 0 {
+		parts = append(parts, fmt.Sprintf("could not find name %s in %s", strings.Join(e.Missing, ", "), e.Type))
+	}
+	if len(e.Unused) > 0 {
+		parts = append(parts, fmt.Sprintf("required fields %s of %s are not used by the query", strings.Join(e.Unused, ", "), e.Type))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// argField is a struct field bound to a named parameter.
+type argField struct {
+	index    []int
+	path     string
+	required bool
+}
+
+type argFieldsKey struct {
+	mapper *reflectx.Mapper
+	t      reflect.Type
+}
+
+// argFieldsCache caches the argFields of struct types per Mapper.
+var argFieldsCache sync.Map
+
+// argFields returns the fields of the struct type t bound to named
+// parameters, keyed by parameter name. Fields are named by m, unless their
+// arg tag names them, as in `arg:"name"`; `arg:"-"` fields are not bound,
+// and `arg:",required"` ones must be used by the query.
+func argFields(m *reflectx.Mapper, t reflect.Type) map[string]argField {
+	key := argFieldsKey{mapper: m, t: t}
+	if fields, ok := argFieldsCache.Load(key); ok {
+		return fields.(map[string]argField)
+	}
+	tm := m.TypeMap(t)
+	fields := make(map[string]argField, len(tm.Names))
+	for name, fi := range tm.Names {
+		field := argField{index: fi.Index, path: fi.Path}
+		if tag, ok := fi.Field.Tag.Lookup("arg"); ok {
+			tagName, options, _ := strings.Cut(tag, ",")
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+			for _, option := range strings.Split(options, ",") {
+				if strings.TrimSpace(option) == "required" {
+					field.required = true
+				}
+			}
+		}
+		fields[name] = field
+	}
+	argFieldsCache.Store(key, fields)
+	return fields
+}
+
+// bindStructArgs returns the values of the fields of the struct v bound to
+// names, failing with a NamedArgsError for names without field and for
+// required fields not among names.
+func bindStructArgs(names []string, v reflect.Value, m *reflectx.Mapper) ([]any, error) {
+	fields := argFields(m, v.Type())
+	arglist := make([]any, 0, len(names))
+	used := make(map[string]bool, len(names))
+	var missing []string
+	for _, name := range names {
+		field, ok := fields[name]
+		if !ok {
+			if !slices.Contains(missing, name) {
+				missing = append(missing, name)
+			}
+			continue
+		}
+		used[name] = true
+		arglist = append(arglist, reflectx.FieldByIndexesReadOnly(v, field.index).Interface())
+	}
+	var unused []string
+	for name, field := range fields {
+		if field.required && !used[name] {
+			unused = append(unused, field.path)
+		}
+	}
+	if len(missing) > 0 || len(unused) > 0 {
+		sort.Strings(unused)
+		return arglist, &NamedArgsError{Type: v.Type(), Missing: missing, Unused: unused}
+	}
+	return arglist, nil
+}
@@ -78,7 +78,7 @@ func (n *NamedStmt) QueryxContext(ctx context.Context, arg any) (*Rows, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Rows{SQLRows: r, Mapper: n.Stmt.Mapper, unsafe: isUnsafe(n)}, err
+	return &Rows{SQLRows: r, Mapper: n.Stmt.Mapper, unsafe: isUnsafe(n), table: n.Stmt.db.statementTable(n.Stmt.query), transformers: n.Stmt.db.rowTransformers(), times: n.Stmt.db.timePolicy(), traversals: n.Stmt.db.traversalCache()}, err
 }
 
 // QueryRowxContext this NamedStmt.  Because of limitations with QueryRow, this is
@@ -124,17 +124,34 @@ func NamedQueryContext(ctx context.Context, e ExtContext, query string, arg any)
 
 // NamedExecContext uses BindStruct to get a query executable by the driver and
 // then runs Exec on the result.  Returns an error from the binding
-// or the query execution itself.
+// or the query execution itself. A slice of rows binding more parameters
+// than the database allows is split into several statements, within a
+// transaction for a *DB; other executors than *DB and *Tx return
+// ErrSplitOutsideTx instead.
 func NamedExecContext(ctx context.Context, e ExtContext, query string, arg any) (sql.Result, error) {
-	q, args, err := bindNamedMapper(BindType(e.DriverName()), query, arg, mapperFor(e))
-	if err != nil {
-		return nil, err
+	if db, ok := e.(*DB); ok {
+		return db.NamedExecContext(ctx, query, arg)
+	}
+	query = SanitizeQuery(query, arg)
+	query, arg = prepareNamedInQuery(query, arg, mapperFor(e))
+	exec := func(ctx context.Context, arg any) (sql.Result, error) {
+		q, args, err := bindNamedMapper(BindType(e.DriverName()), query, arg, mapperFor(e))
+		if err != nil {
+			return nil, err
+		}
+		return e.ExecContext(ctx, q, args...)
+	}
+	if chunks := namedChunks(query, arg, MaxBindParams(Dialect(e.DriverName()))); chunks != nil {
+		if _, ok := e.(*Tx); !ok {
+			return nil, ErrSplitOutsideTx
+		}
+		return execChunks(ctx, Dialect(e.DriverName()), chunks, exec)
 	}
-	return e.ExecContext(ctx, q, args...)
+	return exec(ctx, arg)
 }
 
 func NamedInContext(ctx context.Context, e ExtContext, query string, args any) (*Rows, error) {
-	query, args = prepareNamedInQuery(query, args)
+	query, args = prepareNamedInQuery(query, args, mapperFor(e))
 	q, p, err := bindNamedMapper(BindType(e.DriverName()), query, args, mapperFor(e))
 	if err != nil {
 		return nil, err
@@ -0,0 +1,53 @@
+package squealx_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/oarkflow/squealx"
+	_ "modernc.org/sqlite"
+)
+
+func TestNamedInStructWithTagName(t *testing.T) {
+	db, err := squealx.Connect("sqlite", ":memory:", "test", squealx.WithTagName("json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	db.MustExec(`CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT)`)
+	db.MustExec(`INSERT INTO notes (body) VALUES ('a'), ('b'), ('c')`)
+
+	type filter struct {
+		IDs  []int  `json:"ids"`
+		Body string `json:"body"`
+	}
+	ctx := context.Background()
+	arg := filter{IDs: []int{1, 3}, Body: "x"}
+	result, err := db.NamedExecContext(ctx, `UPDATE notes SET body = :body WHERE id IN (:ids)`, arg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := result.RowsAffected(); err != nil || n != 2 {
+		t.Errorf("RowsAffected = %d, %v, want 2", n, err)
+	}
+	rows, err := squealx.NamedInContext(ctx, db, `SELECT id FROM notes WHERE body = :body AND id IN (:ids) ORDER BY id`, arg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 3 {
+		t.Errorf("ids = %v, want [1 3]", ids)
+	}
+}
@@ -0,0 +1,98 @@
+package squealx
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/oarkflow/squealx/reflectx"
+	"github.com/oarkflow/squealx/utils/xstrings"
+)
+
+// SnakeCase maps field names to snake_case columns, "UserID" to "user_id".
+// It is the mapping of the default NameMapper.
+func SnakeCase(name string) string {
+	return xstrings.ToSnakeCase(name)
+}
+
+// LowerCamel maps field names to lowerCamel columns, "UserID" to "userID"
+// and "HTTPServer" to "httpServer".
+func LowerCamel(name string) string {
+	runes := []rune(name)
+	upper := 0
+	for upper < len(runes) && unicode.IsUpper(runes[upper]) {
+		upper++
+	}
+	// Keep the last capital of a leading acronym when a word follows it.
+	if upper > 1 && upper < len(runes) && unicode.IsLower(runes[upper]) {
+		upper--
+	}
+	for i := 0; i < upper; i++ {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
+
+// SameCase maps field names to columns of the same name.
+func SameCase(name string) string {
+	return name
+}
+
+// ScreamingSnake maps field names to SCREAMING_SNAKE_CASE columns, "UserID"
+// to "USER_ID".
+func ScreamingSnake(name string) string {
+	return strings.ToUpper(xstrings.ToSnakeCase(name))
+}
+
+// presetStrategies are shared by all DBs using them, so their field mappings
+// are only computed once per tag configuration.
+var presetStrategies = map[uintptr]string{
+	reflect.ValueOf(SnakeCase).Pointer():      "snake",
+	reflect.ValueOf(LowerCamel).Pointer():     "lower_camel",
+	reflect.ValueOf(SameCase).Pointer():       "same",
+	reflect.ValueOf(ScreamingSnake).Pointer(): "screaming_snake",
+}
+
+type strategyMapperKey struct {
+	strategy     string
+	tagName      string
+	fallbackTags string
+}
+
+var strategyMappers sync.Map
+
+// strategyMapper returns a mapper naming fields with strategy. Mappers of
+// the preset strategies are cached, other strategies get a new mapper.
+func strategyMapper(tagName string, fallbackTags []string, strategy func(string) string) *reflectx.Mapper {
+	preset, ok := presetStrategies[reflect.ValueOf(strategy).Pointer()]
+	if !ok {
+		return reflectx.NewMapperFallbackFunc(tagName, fallbackTags, strategy)
+	}
+	key := strategyMapperKey{strategy: preset, tagName: tagName, fallbackTags: strings.Join(fallbackTags, ",")}
+	if m, ok := strategyMappers.Load(key); ok {
+		return m.(*reflectx.Mapper)
+	}
+	m, _ := strategyMappers.LoadOrStore(key, reflectx.NewMapperFallbackFunc(tagName, fallbackTags, strategy))
+	return m.(*reflectx.Mapper)
+}
+
+// WithNamingStrategy makes the DB map untagged field names to columns with
+// strategy, such as LowerCamel, instead of the global NameMapper.
+func WithNamingStrategy(strategy func(string) string) DBOption {
+	return func(o *dbOptions) {
+		o.strategy = strategy
+	}
+}
+
+// SetNamingStrategy makes db map untagged field names to columns with
+// strategy, keeping the struct tags it obeys. Unlike setting NameMapper, it
+// only affects db; Stmt and Tx values already created from db keep their
+// mapper.
+//
+// Like MapperFunc, it sets db.Mapper without synchronization, so it must be
+// called before db is used by other goroutines. Prefer WithNamingStrategy,
+// which sets the strategy when db is created.
+func (db *DB) SetNamingStrategy(strategy func(string) string) {
+	db.Mapper = strategyMapper(db.Mapper.TagName(), db.Mapper.FallbackTags(), strategy)
+}
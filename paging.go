@@ -1,9 +1,18 @@
 package squealx
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"math"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
 	"strings"
+
+	"github.com/oarkflow/squealx/sqlparse"
 )
 
 type Pagination struct {
@@ -13,25 +22,98 @@ type Pagination struct {
 	Limit        int   `json:"limit" query:"limit" form:"limit"`
 	Page         int   `json:"page" query:"page" form:"page"`
 	PrevPage     int   `json:"prev_page" query:"prev_page" form:"prev_page"`
-	NextPage     int   `json:"next_page" query:"" form:""`
+	NextPage     int   `json:"next_page" query:"next_page" form:"next_page"`
+	HasPrev      bool  `json:"has_prev" query:"has_prev" form:"has_prev"`
+	HasNext      bool  `json:"has_next" query:"has_next" form:"has_next"`
+	// From and To are the 1-based numbers of the first and last rows of the
+	// page, both 0 when it is empty.
+	From int64 `json:"from" query:"from" form:"from"`
+	To   int64 `json:"to" query:"to" form:"to"`
+	// Partial is set when one of the count and page queries failed and
+	// Paging.Partial returned the other. Without the count, TotalRecords
+	// and TotalPage are lower bounds and HasNext tells whether the page is
+	// full; without the page, the page holds no rows.
+	Partial bool `json:"partial,omitempty" query:"-" form:"-"`
+}
+
+// PageStage is the query of a paginated query that failed.
+type PageStage string
+
+const (
+	// PageCount is the query counting the total number of rows.
+	PageCount PageStage = "count"
+	// PageData is the query selecting the rows of the page.
+	PageData PageStage = "data"
+)
+
+// PageError is the failure of the count or page query of Pages.
+type PageError struct {
+	Stage PageStage
+	Err   error
+}
+
+func (e *PageError) Error() string {
+	return fmt.Sprintf("squealx: %s query of page: %v", e.Stage, e.Err)
+}
+
+func (e *PageError) Unwrap() error {
+	return e.Err
+}
+
+// Timeout reports whether the query failed for running past its deadline or
+// the statement timeout of the server.
+func (e *PageError) Timeout() bool {
+	return IsStatementTimeout(e.Err)
 }
 
 type Paging struct {
 	Limit  int `json:"limit" query:"limit" form:"limit"`
 	Page   int `json:"page" query:"page" form:"page"`
 	offset int
+	// Strategy selects how the total number of rows is counted.
+	Strategy PagingStrategy `json:"-" query:"-" form:"-"`
+	// ETag makes Paginate and PaginateTyped set the ETag of their
+	// response.
+	ETag bool `json:"-" query:"-" form:"-"`
+	// Partial makes Pages return the result of the count or page query
+	// when only the other one fails, flagged with Pagination.Partial,
+	// along with the *PageError of the failed query.
+	Partial bool `json:"-" query:"-" form:"-"`
+	// Sequential runs the count query after the page query rather than
+	// alongside it on a second connection, for pools too small to hold
+	// both.
+	Sequential bool `json:"-" query:"-" form:"-"`
 }
 
+// PagingStrategy is how Pages counts the total number of rows.
+type PagingStrategy int
+
+const (
+	// PagingCount runs a separate count query alongside the page query.
+	PagingCount PagingStrategy = iota
+	// PagingWindow fetches the count with the page in a single query, using
+	// COUNT(*) OVER(). It applies to queries starting with SELECT, but not
+	// SELECT DISTINCT nor UNION, INTERSECT and EXCEPT, on databases with
+	// window functions, and falls back to PagingCount otherwise.
+	PagingWindow
+)
+
 type PaginatedResponse struct {
 	Items      any         `json:"data"`
 	Pagination *Pagination `json:"pagination"`
 	Error      error       `json:"error,omitempty"`
+	// ETag is a strong HTTP ETag of the page and its pagination, set when
+	// Paging.ETag is.
+	ETag string `json:"-"`
 }
 
 type Param struct {
-	DB     *DB
-	Query  string
-	Param  map[string]any
+	DB    *DB
+	Query string
+	// Param is the named argument of Query, a map or a struct.
+	Param any
+	// Args are the positional arguments of Query, used when Param is nil.
+	Args   []any
 	Paging *Paging
 }
 
@@ -57,121 +139,419 @@ func prepareRawQuery(db *DB, query string, paging *Paging) string {
 		paging.offset = (paging.Page - 1) * paging.Limit
 	}
 	queryWithoutLimit := strings.Split(query, "LIMIT")[0]
-	switch db.driverName {
-	case "mysql", "sqlite3", "nrmysql", "nrsqlite3", "mariadb":
-		queryWithoutLimit += " LIMIT :limit, :offset"
-	case "postgres", "pgx", "pgx/v4", "pgx/v5", "pq-timeouts", "cloudsqlpostgres", "ql", "nrpostgres", "cockroach":
-		queryWithoutLimit += " LIMIT :limit OFFSET :offset"
-	case "sql-server", "sqlserver", "mssql", "ms-sql":
-		queryWithoutLimit += " LIMIT :limit, :offset"
+	if Dialect(db.driverName) == DialectMSSQL {
+		if !strings.Contains(strings.ToLower(queryWithoutLimit), "order by") {
+			queryWithoutLimit += " ORDER BY (SELECT NULL)"
+		}
+		return queryWithoutLimit + fmt.Sprintf(" OFFSET %d ROWS FETCH NEXT %d ROWS ONLY", paging.offset, paging.Limit)
 	}
-	return queryWithoutLimit
+	return queryWithoutLimit + fmt.Sprintf(" LIMIT %d OFFSET %d", paging.Limit, paging.offset)
 }
 
 // Pages Endpoint for pagination
-func Pages(p *Param, result any) (paginator *Pagination, err error) {
+//
+// The count and page queries are run like DB.Select, so they accept named
+// arguments from a map or struct, positional arguments and IN clauses.
+// Their failures are returned as a *PageError telling which one failed.
+//
+// Pages uses context.Background internally; to specify the context, use
+// PagesContext.
+func Pages(p *Param, result any) (*Pagination, error) {
+	return PagesContext(context.Background(), p, result)
+}
+
+// PagesContext is like Pages, running the count and page queries
+// concurrently, on two connections of the pool, unless Paging.Sequential is
+// set. Both queries run with ctx; unless Paging.Partial is set, the failure
+// of either cancels the other.
+func PagesContext(ctx context.Context, p *Param, result any) (paginator *Pagination, err error) {
 	var (
-		done  = make(chan bool, 1)
-		db    = p.DB
-		count int64
+		db       = p.DB
+		count    int64
+		countErr = make(chan error, 1)
+		dataErr  error
+		args     = p.Args
 	)
+	if p.Param != nil {
+		args = []any{p.Param}
+	}
+	if p.Paging == nil {
+		p.Paging = &Paging{}
+	}
 
-	// get all counts
-	go getRawCounts(db, p.Query, done, &count, p.Param)
 	sql := prepareRawQuery(db, p.Query, p.Paging)
-	// get
-	if p.Param == nil {
-		p.Param = make(map[string]any)
+	countQuery := fmt.Sprintf("SELECT count(*) FROM (%s) AS count_query", p.Query)
+	windowQuery, window := "", false
+	if p.Paging.Strategy == PagingWindow {
+		windowQuery, window = windowCountQuery(ctx, db, sql)
 	}
-	p.Param["limit"] = p.Paging.Limit
-	p.Param["offset"] = p.Paging.offset
-	err = db.NamedSelect(result, sql, p.Param)
-	if err != nil {
-		return nil, err
+	if window {
+		// The count and the page come from the same query, which fails as
+		// a whole.
+		counted, err := selectWithCount(ctx, db, result, &count, windowQuery, args...)
+		if err != nil {
+			return nil, &PageError{Stage: PageData, Err: err}
+		}
+		// An empty page, past the last one, has no row to read the count from.
+		if !counted {
+			countErr <- db.SelectContext(ctx, &count, countQuery, args...)
+		} else {
+			countErr <- nil
+		}
+	} else if p.Paging.Sequential {
+		if dataErr = db.SelectContext(ctx, result, sql, args...); dataErr != nil && !p.Paging.Partial {
+			return nil, &PageError{Stage: PageData, Err: dataErr}
+		}
+		countErr <- db.SelectContext(ctx, &count, countQuery, args...)
+	} else {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		// get all counts
+		go func() {
+			err := db.SelectContext(ctx, &count, countQuery, args...)
+			if err != nil && !p.Paging.Partial {
+				cancel()
+			}
+			countErr <- err
+		}()
+		// get
+		if dataErr = db.SelectContext(ctx, result, sql, args...); dataErr != nil && !p.Paging.Partial {
+			cancel()
+		}
 	}
-	<-done
+	if err := <-countErr; err != nil {
+		// The failure of either query cancels the other, which then only
+		// fails with the cancellation.
+		if dataErr != nil && (!errors.Is(dataErr, context.Canceled) || errors.Is(err, context.Canceled)) {
+			return nil, &PageError{Stage: PageData, Err: dataErr}
+		}
+		err = &PageError{Stage: PageCount, Err: err}
+		if dataErr != nil || !p.Paging.Partial {
+			return nil, err
+		}
+		return partialPagination(p.Paging, resultLen(result)), err
+	}
+	if dataErr != nil {
+		err := &PageError{Stage: PageData, Err: dataErr}
+		if !p.Paging.Partial {
+			return nil, err
+		}
+		paginator = pagination(p.Paging, count, 0)
+		paginator.Partial = true
+		return paginator, err
+	}
+	return pagination(p.Paging, count, resultLen(result)), nil
+}
+
+// partialPagination returns the pagination of a page of rows rows without
+// the total count, whose totals count the rows up to the page, and one more
+// when it is full.
+func partialPagination(paging *Paging, rows int) *Pagination {
+	count := int64(paging.offset + rows)
+	if rows == paging.Limit {
+		count++
+	}
+	paginator := pagination(paging, count, rows)
+	paginator.Partial = true
+	return paginator
+}
+
+// pagination returns the pagination of a page of rows rows out of count.
+func pagination(paging *Paging, count int64, rows int) *Pagination {
 	// total pages
-	total := int(math.Ceil(float64(count) / float64(p.Paging.Limit)))
+	total := int(math.Ceil(float64(count) / float64(paging.Limit)))
 
 	// construct pagination
-	paginator = &Pagination{
+	paginator := &Pagination{
 		TotalRecords: count,
-		Page:         p.Paging.Page,
-		Offset:       p.Paging.offset,
-		Limit:        p.Paging.Limit,
+		Page:         paging.Page,
+		Offset:       paging.offset,
+		Limit:        paging.Limit,
 		TotalPage:    total,
-		PrevPage:     p.Paging.Page,
-		NextPage:     p.Paging.Page,
+		PrevPage:     paging.Page,
+		NextPage:     paging.Page,
 	}
 
 	// prev page
-	if p.Paging.Page > 1 {
-		paginator.PrevPage = p.Paging.Page - 1
+	if paging.Page > 1 {
+		paginator.HasPrev = true
+		paginator.PrevPage = paging.Page - 1
 	}
 	// next page
-	if p.Paging.Page != paginator.TotalPage {
-		paginator.NextPage = p.Paging.Page + 1
+	if paging.Page < paginator.TotalPage {
+		paginator.HasNext = true
+		paginator.NextPage = paging.Page + 1
+	}
+	// row numbers
+	if rows > 0 {
+		paginator.From = int64(paging.offset) + 1
+		paginator.To = int64(paging.offset + rows)
+	}
+	return paginator
+}
+
+const windowCountColumn = "squealx_total_count"
+
+// windowCountQuery returns query also selecting the total row count, and
+// whether the query and the database allow it. The count is the first
+// column, or follows the columns of a leading *, which MySQL requires to
+// come first. A UNION, INTERSECT or EXCEPT would only count the rows of
+// its first query, and is left to PagingCount.
+func windowCountQuery(ctx context.Context, db *DB, query string) (string, bool) {
+	query = strings.TrimSpace(query)
+	if len(query) < 7 || !strings.EqualFold(query[:7], "SELECT ") || strings.HasPrefix(strings.ToUpper(strings.TrimSpace(query[7:])), "DISTINCT") {
+		return "", false
+	}
+	if compound(sqlparse.Tokens(Statement{Query: query, Dialect: Dialect(db.driverName)}.Tokens())) {
+		return "", false
+	}
+	if info, err := db.ServerInfo(ctx); err != nil || !info.SupportsWindowFunctions() {
+		return "", false
+	}
+	count := fmt.Sprintf("COUNT(*) OVER() AS %s", windowCountColumn)
+	if list := strings.TrimSpace(query[7:]); strings.HasPrefix(list, "*") {
+		return fmt.Sprintf("SELECT *, %s %s", count, list[1:]), true
+	}
+	return fmt.Sprintf("SELECT %s, %s", count, query[7:]), true
+}
+
+// selectWithCount runs a query built by windowCountQuery like
+// DB.SelectContext, scanning its rows into dest and the count into count.
+// It reports whether there was a row to read the count from.
+func selectWithCount(ctx context.Context, db *DB, dest any, count *int64, query string, args ...any) (bool, error) {
+	args = selectArgs(args)
+	rows, err := db.selectRows(ctx, SanitizeQuery(query, args...), args)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	counting := &countingRows{SQLRows: rows.SQLRows, count: count, index: -1}
+	rows.SQLRows = counting
+	if err := ScannAll(rows, dest, false); err != nil {
+		return false, err
+	}
+	return counting.scanned, nil
+}
+
+// countingRows hides the count column added by windowCountQuery, scanning
+// it into count.
+type countingRows struct {
+	SQLRows
+	count   *int64
+	scanned bool
+	// index is the position of the count column, -1 until Columns finds it.
+	index int
+}
+
+// countIndex returns the position of the count column in columns.
+func (r *countingRows) countIndex(columns []string) int {
+	if r.index < 0 {
+		r.index = slices.Index(columns, windowCountColumn)
+	}
+	return r.index
+}
+
+func (r *countingRows) Columns() ([]string, error) {
+	columns, err := r.SQLRows.Columns()
+	if err != nil {
+		return columns, err
+	}
+	if i := r.countIndex(columns); i >= 0 {
+		return slices.Delete(columns, i, i+1), nil
+	}
+	return columns, nil
+}
+
+func (r *countingRows) ColumnTypes() ([]*sql.ColumnType, error) {
+	types, err := r.SQLRows.ColumnTypes()
+	if err != nil {
+		return types, err
+	}
+	columns, err := r.SQLRows.Columns()
+	if err != nil {
+		return nil, err
 	}
+	if i := r.countIndex(columns); i >= 0 && i < len(types) {
+		return slices.Delete(types, i, i+1), nil
+	}
+	return types, nil
+}
 
-	return paginator, nil
+func (r *countingRows) Scan(dest ...any) error {
+	r.scanned = true
+	if r.index < 0 || r.index > len(dest) {
+		return fmt.Errorf("squealx: column %s not found", windowCountColumn)
+	}
+	return r.SQLRows.Scan(slices.Insert(slices.Clone(dest), r.index, any(r.count))...)
 }
 
-func getRawCounts(db *DB, query string, done chan bool, count *int64, params map[string]any) error {
-	err := db.NamedSelect(count, fmt.Sprintf("SELECT count(*) FROM (%s) AS count_query", query), params)
-	done <- true
-	return err
+// resultLen returns the number of rows scanned into result, a pointer to a
+// slice.
+func resultLen(result any) int {
+	v := reflect.ValueOf(result)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Slice {
+		return 0
+	}
+	return v.Len()
+}
+
+// URL returns baseURL with its page and limit query parameters set to page
+// and the limit of p.
+func (p *Pagination) URL(baseURL string, page int) (string, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	query.Set("page", strconv.Itoa(page))
+	query.Set("limit", strconv.Itoa(p.Limit))
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// Links returns the URLs of the first, previous, next and last pages keyed by
+// their relation, built with URL. prev and next are omitted when there is no
+// such page.
+func (p *Pagination) Links(baseURL string) (map[string]string, error) {
+	links := make(map[string]string, 4)
+	for _, rel := range p.rels() {
+		link, err := p.URL(baseURL, rel.page)
+		if err != nil {
+			return nil, err
+		}
+		links[rel.name] = link
+	}
+	return links, nil
+}
+
+// LinkHeader returns the Links of p formatted as an RFC 5988 Link header.
+func (p *Pagination) LinkHeader(baseURL string) (string, error) {
+	var parts []string
+	for _, rel := range p.rels() {
+		link, err := p.URL(baseURL, rel.page)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, fmt.Sprintf(`<%s>; rel="%s"`, link, rel.name))
+	}
+	return strings.Join(parts, ", "), nil
+}
+
+type pageRel struct {
+	name string
+	page int
+}
+
+func (p *Pagination) rels() []pageRel {
+	last := p.TotalPage
+	if last < 1 {
+		last = 1
+	}
+	rels := []pageRel{{"first", 1}}
+	if p.HasPrev {
+		rels = append(rels, pageRel{"prev", p.PrevPage})
+	}
+	if p.HasNext {
+		rels = append(rels, pageRel{"next", p.NextPage})
+	}
+	return append(rels, pageRel{"last", last})
 }
 
 func (p Pagination) IsEmpty() bool {
 	return p.TotalRecords <= 0
 }
 
+// Paginate runs query for the page of paging, scanning its rows into
+// result. The first of params holds the named arguments of query.
+// PaginateContext also takes positional arguments.
 func Paginate(db *DB, query string, result any, paging Paging, params ...map[string]any) PaginatedResponse {
+	return PaginateContext(context.Background(), db, query, result, paging, namedArgs(params)...)
+}
+
+// namedArgs returns the arguments of a query given the optional map of its
+// named arguments.
+func namedArgs(params []map[string]any) []any {
+	if len(params) == 0 {
+		return nil
+	}
+	return []any{params[0]}
+}
+
+// PaginateContext is like Paginate, running its queries with ctx as
+// PagesContext does. params are the arguments of query, as for DB.Select:
+// a map or struct of named arguments, or positional ones.
+func PaginateContext(ctx context.Context, db *DB, query string, result any, paging Paging, params ...any) PaginatedResponse {
 	p := &Param{
 		DB:     db,
 		Query:  query,
+		Args:   params,
 		Paging: &paging,
 	}
-	if len(params) > 0 {
-		p.Param = params[0]
-	}
-	pages, err := Pages(p, result)
+	pages, err := PagesContext(ctx, p, result)
 	if err != nil {
+		if pages == nil {
+			return PaginatedResponse{
+				Error: err,
+			}
+		}
+		// A partial page, see Paging.Partial.
 		return PaginatedResponse{
-			Error: err,
+			Items:      result,
+			Pagination: pages,
+			Error:      err,
 		}
 	}
-	return PaginatedResponse{
+	response := PaginatedResponse{
 		Items:      result,
 		Pagination: pages,
 	}
+	if paging.ETag {
+		response.ETag, response.Error = resultETag(result, pages)
+	}
+	return response
 }
 
 type PaginatedTypedResponse[T any] struct {
 	Items      []T         `json:"data"`
 	Pagination *Pagination `json:"pagination"`
 	Error      error       `json:"error,omitempty"`
+	// ETag is set when Paging.ETag is, see PaginatedResponse.
+	ETag string `json:"-"`
 }
 
+// PaginateTyped is like Paginate, returning the rows of the page as a []T.
 func PaginateTyped[T any](db *DB, query string, paging Paging, params ...map[string]any) PaginatedTypedResponse[T] {
+	return PaginateTypedContext[T](context.Background(), db, query, paging, namedArgs(params)...)
+}
+
+// PaginateTypedContext is like PaginateTyped, running its queries with ctx
+// as PagesContext does. params are as for PaginateContext.
+func PaginateTypedContext[T any](ctx context.Context, db *DB, query string, paging Paging, params ...any) PaginatedTypedResponse[T] {
 	p := &Param{
 		DB:     db,
 		Query:  query,
+		Args:   params,
 		Paging: &paging,
 	}
-	if len(params) > 0 {
-		p.Param = params[0]
-	}
 	var result []T
-	pages, err := Pages(p, &result)
+	pages, err := PagesContext(ctx, p, &result)
 	if err != nil {
+		// pages is set for a partial page, see Paging.Partial.
 		return PaginatedTypedResponse[T]{
-			Items: result,
-			Error: err,
+			Items:      result,
+			Pagination: pages,
+			Error:      err,
 		}
 	}
-	return PaginatedTypedResponse[T]{
+	response := PaginatedTypedResponse[T]{
 		Items:      result,
 		Pagination: pages,
 	}
+	if paging.ETag {
+		response.ETag, response.Error = resultETag(result, pages)
+	}
+	return response
 }
@@ -0,0 +1,148 @@
+package squealx
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func openPagingDB(t *testing.T) *DB {
+	t.Helper()
+	// Pages runs its count and page queries on two connections, which
+	// would open two databases of :memory:.
+	db, err := Connect("sqlite", filepath.Join(t.TempDir(), "paging.db"), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	db.MustExec(`CREATE TABLE pens (id INTEGER PRIMARY KEY, name TEXT)`)
+	db.MustExec(`CREATE TABLE inks (id INTEGER PRIMARY KEY, name TEXT)`)
+	for i, name := range []string{"red", "green", "blue"} {
+		db.MustExec(`INSERT INTO pens (id, name) VALUES (?, ?)`, i+1, name)
+		db.MustExec(`INSERT INTO inks (id, name) VALUES (?, ?)`, i+3, name)
+	}
+	return db
+}
+
+func TestWindowCountQuery(t *testing.T) {
+	db := openPagingDB(t)
+	cases := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"columns", "SELECT id, name FROM pens LIMIT 2 OFFSET 0", "SELECT COUNT(*) OVER() AS squealx_total_count, id, name FROM pens LIMIT 2 OFFSET 0"},
+		{"star", "SELECT * FROM pens", "SELECT *, COUNT(*) OVER() AS squealx_total_count  FROM pens"},
+		{"union in a subquery", "SELECT * FROM (SELECT id FROM pens UNION SELECT id FROM inks) AS u", "SELECT *, COUNT(*) OVER() AS squealx_total_count  FROM (SELECT id FROM pens UNION SELECT id FROM inks) AS u"},
+		{"union in a literal", "SELECT id FROM pens WHERE name <> 'UNION'", "SELECT COUNT(*) OVER() AS squealx_total_count, id FROM pens WHERE name <> 'UNION'"},
+		{"distinct", "SELECT DISTINCT name FROM pens", ""},
+		{"union", "SELECT id FROM pens UNION SELECT id FROM inks", ""},
+		{"union all", "select id from pens union all select id from inks limit 2", ""},
+		{"intersect", "SELECT name FROM pens INTERSECT SELECT name FROM inks", ""},
+		{"except", "SELECT name FROM pens\nEXCEPT\nSELECT name FROM inks", ""},
+		{"not a select", "UPDATE pens SET name = 'x'", ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, ok := windowCountQuery(context.Background(), db, c.query)
+			if ok != (c.want != "") || got != c.want {
+				t.Errorf("windowCountQuery = %q, %t, want %q", got, ok, c.want)
+			}
+		})
+	}
+}
+
+func TestPagesWindowCompound(t *testing.T) {
+	db := openPagingDB(t)
+	var ids []int
+	paginator, err := PagesContext(context.Background(), &Param{
+		DB:     db,
+		Query:  "SELECT id FROM pens UNION SELECT id FROM inks ORDER BY id",
+		Paging: &Paging{Limit: 2, Strategy: PagingWindow},
+	}, &ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Ids 3 are in both tables.
+	if paginator.TotalRecords != 5 || !reflect.DeepEqual(ids, []int{1, 2}) {
+		t.Errorf("Pages = %d records, %v, want 5 records, [1 2]", paginator.TotalRecords, ids)
+	}
+}
+
+func queryCounting(t *testing.T, db *DB, query string) *countingRows {
+	t.Helper()
+	rows, err := db.SQLDB.Query(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { rows.Close() })
+	return &countingRows{SQLRows: rows, count: new(int64), index: -1}
+}
+
+func TestCountingRows(t *testing.T) {
+	db := openPagingDB(t)
+	for _, query := range []string{
+		"SELECT 7 AS squealx_total_count, id, name FROM pens WHERE id = 1",
+		"SELECT id, 7 AS squealx_total_count, name FROM pens WHERE id = 1",
+		"SELECT id, name, 7 AS squealx_total_count FROM pens WHERE id = 1",
+	} {
+		rows := queryCounting(t, db, query)
+		// ColumnTypes finds the count column before Columns does.
+		types, err := rows.ColumnTypes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(types) != 2 || types[0].Name() != "id" || types[1].Name() != "name" {
+			t.Errorf("%s: ColumnTypes has %d columns, want id and name", query, len(types))
+		}
+		columns, err := rows.Columns()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(columns, []string{"id", "name"}) {
+			t.Errorf("%s: Columns = %v, want [id name]", query, columns)
+		}
+		var (
+			id   int
+			name string
+		)
+		if !rows.Next() {
+			t.Fatalf("%s: no row", query)
+		}
+		if err := rows.Scan(&id, &name); err != nil {
+			t.Fatal(err)
+		}
+		if id != 1 || name != "red" || *rows.count != 7 || !rows.scanned {
+			t.Errorf("%s: Scan = %d, %q, count %d, want 1, red, count 7", query, id, name, *rows.count)
+		}
+	}
+
+	rows := queryCounting(t, db, "SELECT id, name FROM pens")
+	if columns, _ := rows.Columns(); !reflect.DeepEqual(columns, []string{"id", "name"}) {
+		t.Errorf("Columns without a count column = %v, want [id name]", columns)
+	}
+	var id, name any
+	if rows.Next() && rows.Scan(&id, &name) == nil {
+		t.Errorf("Scan without a count column succeeded")
+	}
+}
+
+func TestSelectWithCount(t *testing.T) {
+	db := openPagingDB(t)
+	query, _ := windowCountQuery(context.Background(), db, "SELECT * FROM pens ORDER BY id LIMIT 2")
+	var (
+		count int64
+		pens  []map[string]any
+	)
+	counted, err := selectWithCount(context.Background(), db, &pens, &count, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !counted || count != 3 || len(pens) != 2 {
+		t.Fatalf("selectWithCount = %t, count %d, %d rows, want true, count 3, 2 rows", counted, count, len(pens))
+	}
+	if _, ok := pens[0][windowCountColumn]; ok || len(pens[0]) != 2 {
+		t.Errorf("row = %v, want id and name only", pens[0])
+	}
+}
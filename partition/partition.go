@@ -0,0 +1,218 @@
+// Package partition manages time-partitioned PostgreSQL tables.
+//
+// The parent table must already be declared with PARTITION BY RANGE on the
+// time column. A Manager then keeps partitions for the upcoming periods in
+// place, detaches (and optionally archives or drops) expired ones, and
+// watches the default partition, which only receives rows falling outside
+// every partition. Maintain is idempotent and meant to be run from cron or
+// with Run.
+package partition
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/oarkflow/squealx"
+)
+
+// Interval is the period covered by one partition.
+type Interval int
+
+const (
+	ByMonth Interval = iota
+	ByDay
+)
+
+// Manager maintains the partitions of one table.
+type Manager struct {
+	DB    *squealx.DB
+	Table string
+	// Column is the range partition key the parent table must be declared
+	// with.
+	Column string
+	Every  Interval
+	// Premake is the number of future partitions kept ahead of the current
+	// one. Defaults to 3.
+	Premake int
+	// Retention is the number of past partitions kept attached besides the
+	// current one. Zero keeps them all.
+	Retention int
+	// ArchiveSchema, if set, receives detached partitions. Otherwise they are
+	// dropped when DropDetached is set, and left as standalone tables if not.
+	ArchiveSchema string
+	DropDetached  bool
+}
+
+// Monthly returns a Manager for table partitioned by month on column.
+func Monthly(db *squealx.DB, table, column string) *Manager {
+	return &Manager{DB: db, Table: table, Column: column, Every: ByMonth, Premake: 3}
+}
+
+// Daily returns a Manager for table partitioned by day on column.
+func Daily(db *squealx.DB, table, column string) *Manager {
+	return &Manager{DB: db, Table: table, Column: column, Every: ByDay, Premake: 3}
+}
+
+// Partition is an attached partition managed by a Manager.
+type Partition struct {
+	Name  string
+	Start time.Time
+	End   time.Time
+}
+
+// Report describes what Maintain did.
+type Report struct {
+	Created  []string
+	Detached []string
+	// DefaultRows counts the rows in the default partition. Non-zero means
+	// rows were written outside every partition's range.
+	DefaultRows int64
+}
+
+// Maintain creates the default partition and the partitions for the current
+// and upcoming periods, then detaches partitions older than Retention.
+func (m *Manager) Maintain(ctx context.Context) (Report, error) {
+	var report Report
+	if squealx.Dialect(m.DB.DriverName()) != squealx.DialectPostgres {
+		return report, fmt.Errorf("partition: %s is not a PostgreSQL database", m.DB.DriverName())
+	}
+	var keyDef string
+	err := m.DB.QueryRowxContext(ctx, "SELECT coalesce(pg_get_partkeydef(oid), '') FROM pg_class WHERE relname = $1", m.Table).Scan(&keyDef)
+	if err != nil {
+		return report, err
+	}
+	if !strings.EqualFold(keyDef, "RANGE ("+m.Column+")") {
+		return report, fmt.Errorf("partition: %s is not partitioned by range on %s", m.Table, m.Column)
+	}
+	defaultName := m.Table + "_default"
+	if _, err := m.DB.ExecContext(ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s PARTITION OF %s DEFAULT", defaultName, m.Table)); err != nil {
+		return report, err
+	}
+	existing, err := m.Partitions(ctx)
+	if err != nil {
+		return report, err
+	}
+	attached := make(map[string]bool, len(existing))
+	for _, p := range existing {
+		attached[p.Name] = true
+	}
+	current := m.truncate(time.Now().UTC())
+	premake := m.Premake
+	if premake <= 0 {
+		premake = 3
+	}
+	for i := 0; i <= premake; i++ {
+		start := m.add(current, i)
+		name := m.name(start)
+		if attached[name] {
+			continue
+		}
+		query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM ('%s') TO ('%s')",
+			name, m.Table, start.Format(time.RFC3339), m.add(start, 1).Format(time.RFC3339))
+		if _, err := m.DB.ExecContext(ctx, query); err != nil {
+			return report, err
+		}
+		report.Created = append(report.Created, name)
+	}
+	if m.Retention > 0 {
+		cutoff := m.add(current, -m.Retention)
+		for _, p := range existing {
+			if !p.End.After(cutoff) {
+				if err := m.detach(ctx, p.Name); err != nil {
+					return report, err
+				}
+				report.Detached = append(report.Detached, p.Name)
+			}
+		}
+	}
+	err = m.DB.QueryRowxContext(ctx, "SELECT count(*) FROM "+defaultName).Scan(&report.DefaultRows)
+	return report, err
+}
+
+// Run calls Maintain every interval until ctx is done, passing each result
+// to onReport.
+func (m *Manager) Run(ctx context.Context, interval time.Duration, onReport func(Report, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		report, err := m.Maintain(ctx)
+		if onReport != nil {
+			onReport(report, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Partitions returns the attached partitions whose name follows the naming
+// scheme of the Manager, oldest first.
+func (m *Manager) Partitions(ctx context.Context) ([]Partition, error) {
+	var names []string
+	err := m.DB.SelectContext(ctx, &names, `SELECT c.relname FROM pg_inherits i
+	JOIN pg_class c ON c.oid = i.inhrelid
+	JOIN pg_class p ON p.oid = i.inhparent
+	WHERE p.relname = $1 ORDER BY c.relname`, m.Table)
+	if err != nil {
+		return nil, err
+	}
+	prefix := m.Table + "_p"
+	var partitions []Partition
+	for _, name := range names {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		start, err := time.Parse(m.layout(), strings.TrimPrefix(name, prefix))
+		if err != nil {
+			continue
+		}
+		partitions = append(partitions, Partition{Name: name, Start: start, End: m.add(start, 1)})
+	}
+	return partitions, nil
+}
+
+func (m *Manager) detach(ctx context.Context, name string) error {
+	if _, err := m.DB.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s DETACH PARTITION %s", m.Table, name)); err != nil {
+		return err
+	}
+	var query string
+	switch {
+	case m.ArchiveSchema != "":
+		query = fmt.Sprintf("ALTER TABLE %s SET SCHEMA %s", name, m.ArchiveSchema)
+	case m.DropDetached:
+		query = "DROP TABLE " + name
+	default:
+		return nil
+	}
+	_, err := m.DB.ExecContext(ctx, query)
+	return err
+}
+
+func (m *Manager) layout() string {
+	if m.Every == ByDay {
+		return "2006_01_02"
+	}
+	return "2006_01"
+}
+
+func (m *Manager) name(start time.Time) string {
+	return m.Table + "_p" + start.Format(m.layout())
+}
+
+func (m *Manager) truncate(t time.Time) time.Time {
+	if m.Every == ByDay {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func (m *Manager) add(t time.Time, n int) time.Time {
+	if m.Every == ByDay {
+		return t.AddDate(0, 0, n)
+	}
+	return t.AddDate(0, n, 0)
+}
@@ -0,0 +1,153 @@
+package squealx
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"sync"
+	"time"
+)
+
+// PoolTuning configures TunePool.
+type PoolTuning struct {
+	// MinOpen and MaxOpen bound the maximum number of open connections set
+	// by the tuner.
+	MinOpen int
+	MaxOpen int
+	// Interval is the time between adjustments, 10 seconds by default.
+	Interval time.Duration
+	// TargetLatency is the 95th percentile query latency above which the
+	// database rather than the pool is taken to be the bottleneck, so the
+	// pool stops growing and shrinks. Zero disables the latency check.
+	TargetLatency time.Duration
+	// OnChange, if set, is called with every adjustment.
+	OnChange func(PoolEvent)
+}
+
+// PoolEvent reports an adjustment of the pool by TunePool.
+type PoolEvent struct {
+	Time   time.Time
+	From   int
+	To     int
+	Reason string
+	Stats  sql.DBStats
+	// P95Latency is the 95th percentile latency of the queries of the
+	// interval.
+	P95Latency time.Duration
+}
+
+const poolTunerHooks = "squealx/pool-tuner"
+
+// TunePool adjusts the maximum open and idle connections of db between the
+// bounds of cfg until stop is called. Every interval the pool grows when
+// queries waited for a connection and shrinks when most of its connections
+// sat idle, or when query latency exceeds the target while no query waited,
+// as more connections would then only add load to the database. Only one
+// tuner should run per DB.
+func (db *DB) TunePool(cfg PoolTuning) (stop func()) {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 10 * time.Second
+	}
+	cfg.MinOpen = max(cfg.MinOpen, 1)
+	cfg.MaxOpen = max(cfg.MaxOpen, cfg.MinOpen)
+	t := &poolTuner{db: db, cfg: cfg, size: cfg.MaxOpen, last: db.Stats()}
+	if open := t.last.MaxOpenConnections; open > 0 {
+		t.size = min(max(open, cfg.MinOpen), cfg.MaxOpen)
+	}
+	db.SetMaxOpenConns(t.size)
+	db.SetMaxIdleConns(t.size)
+	db.UseNamed(poolTunerHooks, t)
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(cfg.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				t.adjust()
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			db.RemoveNamed(poolTunerHooks)
+		})
+	}
+}
+
+type poolTunerStartKey struct{}
+
+type poolTuner struct {
+	db   *DB
+	cfg  PoolTuning
+	size int
+	last sql.DBStats
+
+	mu        sync.Mutex
+	latencies []time.Duration
+	peakInUse int
+}
+
+func (t *poolTuner) Before(ctx context.Context, query string, args ...any) (context.Context, error) {
+	t.mu.Lock()
+	t.peakInUse = max(t.peakInUse, t.db.Stats().InUse)
+	t.mu.Unlock()
+	return context.WithValue(ctx, poolTunerStartKey{}, time.Now()), nil
+}
+
+func (t *poolTuner) After(ctx context.Context, query string, args ...any) (context.Context, error) {
+	if start, ok := ctx.Value(poolTunerStartKey{}).(time.Time); ok {
+		t.mu.Lock()
+		// Keep a bounded sample of the latencies of the interval.
+		if len(t.latencies) < 4096 {
+			t.latencies = append(t.latencies, time.Since(start))
+		}
+		t.mu.Unlock()
+	}
+	return ctx, nil
+}
+
+// adjust resizes the pool from the statistics of the last interval.
+func (t *poolTuner) adjust() {
+	stats := t.db.Stats()
+	waits := stats.WaitCount - t.last.WaitCount
+	t.last = stats
+
+	t.mu.Lock()
+	latencies := t.latencies
+	peak := max(t.peakInUse, stats.InUse)
+	t.latencies, t.peakInUse = nil, 0
+	t.mu.Unlock()
+	var p95 time.Duration
+	if len(latencies) > 0 {
+		slices.Sort(latencies)
+		p95 = latencies[(len(latencies)-1)*95/100]
+	}
+	slow := t.cfg.TargetLatency > 0 && p95 > t.cfg.TargetLatency
+
+	step := max(t.size/4, 1)
+	size, reason := t.size, ""
+	switch {
+	case waits > 0 && !slow:
+		size, reason = min(t.size+step, t.cfg.MaxOpen), "queries waited for a connection"
+	case slow && waits == 0:
+		size, reason = max(t.size-step, t.cfg.MinOpen), "query latency above target"
+	case waits == 0 && peak < t.size/2:
+		size, reason = max(t.size-step, peak, t.cfg.MinOpen), "connections idle"
+	}
+	if size == t.size {
+		return
+	}
+	event := PoolEvent{Time: time.Now(), From: t.size, To: size, Reason: reason, Stats: stats, P95Latency: p95}
+	t.size = size
+	t.db.SetMaxOpenConns(size)
+	t.db.SetMaxIdleConns(size)
+	if t.cfg.OnChange != nil {
+		t.cfg.OnChange(event)
+	}
+}
@@ -0,0 +1,227 @@
+package squealx
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/oarkflow/squealx/reflectx"
+)
+
+// ErrInvalidQueryArgs is wrapped by the errors of Query.ValidateArgs and
+// Query.ValidateDest.
+var ErrInvalidQueryArgs = errors.New("squealx: invalid query arguments")
+
+// QueryParam is a parameter declared by a FileLoader query with a line
+// `-- param: name type`. Type is one of string, int, float, bool, time,
+// bytes or any, optionally as a list, []int, for IN expansion. A trailing
+// "?", int?, allows NULL.
+type QueryParam struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Nullable bool   `json:"nullable"`
+}
+
+// Query result shapes declared with `-- returns: one` or `-- returns: many`.
+const (
+	ReturnsOne  = "one"
+	ReturnsMany = "many"
+)
+
+// ValidateArgs checks args against the declared parameters of q. Named
+// queries take a single map or struct holding every parameter; other
+// queries take one argument per parameter, in declaration order. Queries
+// declaring no parameters accept any arguments. Struct arguments are read
+// with the global NameMapper, see ValidateArgsFor.
+func (q *Query) ValidateArgs(args ...any) error {
+	return q.validateArgs(mapper(), args)
+}
+
+// ValidateArgsFor is like ValidateArgs, reading struct arguments with the
+// mapper of db, which runs the query.
+func (q *Query) ValidateArgsFor(db *DB, args ...any) error {
+	return q.validateArgs(db.Mapper, args)
+}
+
+func (q *Query) validateArgs(m *reflectx.Mapper, args []any) error {
+	if q.paramErr != nil {
+		return q.argsError("%v", q.paramErr)
+	}
+	if len(q.Params) == 0 {
+		return nil
+	}
+	if IsNamedQuery(q.Query) {
+		if len(args) != 1 {
+			return q.argsError("expected a map or struct of named parameters, got %d arguments", len(args))
+		}
+		for _, p := range q.Params {
+			v, ok := namedArg(m, args[0], p.Name)
+			if !ok {
+				return q.argsError("missing parameter %q", p.Name)
+			}
+			if err := p.check(v); err != nil {
+				return q.argsError("parameter %q: %v", p.Name, err)
+			}
+		}
+		return nil
+	}
+	if len(args) != len(q.Params) {
+		return q.argsError("expected %d arguments, got %d", len(q.Params), len(args))
+	}
+	for i, p := range q.Params {
+		if err := p.check(args[i]); err != nil {
+			return q.argsError("parameter %q (argument %d): %v", p.Name, i+1, err)
+		}
+	}
+	return nil
+}
+
+// ValidateDest checks that dest suits the result shape declared by q: a
+// pointer to a slice for ReturnsMany, a pointer to anything else for
+// ReturnsOne.
+func (q *Query) ValidateDest(dest any) error {
+	t := reflect.TypeOf(dest)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return q.argsError("destination must be a pointer, got %T", dest)
+	}
+	switch q.Returns {
+	case ReturnsMany:
+		if t.Elem().Kind() != reflect.Slice {
+			return q.argsError("returns many rows, destination must be a pointer to a slice, got %T", dest)
+		}
+	case ReturnsOne:
+		if t.Elem().Kind() == reflect.Slice && t.Elem().Elem().Kind() != reflect.Uint8 {
+			return q.argsError("returns one row, destination must not be a slice, got %T", dest)
+		}
+	}
+	return nil
+}
+
+func (q *Query) argsError(format string, args ...any) error {
+	return fmt.Errorf("%w: query %q: %s", ErrInvalidQueryArgs, q.Name, fmt.Sprintf(format, args...))
+}
+
+// namedArg returns the value of name in arg, a map or a struct mapped by
+// m.
+func namedArg(m *reflectx.Mapper, arg any, name string) (any, bool) {
+	switch values := arg.(type) {
+	case map[string]any:
+		v, ok := values[name]
+		return v, ok
+	case map[string]string:
+		v, ok := values[name]
+		return v, ok
+	}
+	v := reflect.Indirect(reflect.ValueOf(arg))
+	if v.Kind() != reflect.Struct {
+		return nil, false
+	}
+	index := m.TraversalsByName(v.Type(), []string{name})[0]
+	if len(index) == 0 {
+		return nil, false
+	}
+	return reflectx.FieldByIndexesReadOnly(v, index).Interface(), true
+}
+
+var (
+	timeType  = reflect.TypeOf(time.Time{})
+	bytesType = reflect.TypeOf([]byte(nil))
+)
+
+// check reports whether v is a valid value of p.
+func (p QueryParam) check(v any) error {
+	rv := reflect.ValueOf(v)
+	if valuer, ok := v.(driver.Valuer); ok && !(rv.Kind() == reflect.Ptr && rv.IsNil()) {
+		value, err := valuer.Value()
+		if err != nil {
+			return err
+		}
+		v = value
+		rv = reflect.ValueOf(v)
+	}
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() || rv.Kind() == reflect.Ptr {
+		if p.Nullable || p.Type == "any" {
+			return nil
+		}
+		return fmt.Errorf("expected %s, got NULL", p.Type)
+	}
+	if elem, ok := strings.CutPrefix(p.Type, "[]"); ok {
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array || rv.Type() == bytesType {
+			return fmt.Errorf("expected %s, got %T", p.Type, v)
+		}
+		if rv.Len() == 0 {
+			return fmt.Errorf("expected %s, got an empty list", p.Type)
+		}
+		for i := 0; i < rv.Len(); i++ {
+			if !typeMatches(elem, rv.Index(i)) {
+				return fmt.Errorf("expected %s, got %T at index %d", p.Type, rv.Index(i).Interface(), i)
+			}
+		}
+		return nil
+	}
+	if !typeMatches(p.Type, rv) {
+		return fmt.Errorf("expected %s, got %T", p.Type, v)
+	}
+	return nil
+}
+
+func typeMatches(typ string, v reflect.Value) bool {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return true
+		}
+		v = v.Elem()
+	}
+	switch typ {
+	case "any":
+		return true
+	case "string":
+		return v.Kind() == reflect.String
+	case "int":
+		return isIntKind(v.Kind())
+	case "float":
+		return isIntKind(v.Kind()) || v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64
+	case "bool":
+		return v.Kind() == reflect.Bool
+	case "time":
+		return v.Type() == timeType
+	case "bytes":
+		return v.Type() == bytesType || v.Kind() == reflect.String
+	}
+	return false
+}
+
+func isIntKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+var queryParamTypes = map[string]bool{
+	"string": true, "int": true, "float": true, "bool": true, "time": true, "bytes": true, "any": true,
+}
+
+// parseQueryParam parses the declaration `name type` of a parameter.
+func parseQueryParam(decl string) (QueryParam, error) {
+	fields := strings.Fields(decl)
+	if len(fields) != 2 {
+		return QueryParam{}, fmt.Errorf("param %q: expected `name type`", decl)
+	}
+	p := QueryParam{Name: fields[0], Type: fields[1]}
+	if typ, ok := strings.CutSuffix(p.Type, "?"); ok {
+		p.Type, p.Nullable = typ, true
+	}
+	if !queryParamTypes[strings.TrimPrefix(p.Type, "[]")] {
+		return QueryParam{}, fmt.Errorf("param %q: unknown type %q", p.Name, p.Type)
+	}
+	return p, nil
+}
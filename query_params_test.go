@@ -0,0 +1,30 @@
+package squealx_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/oarkflow/squealx"
+)
+
+func TestValidateArgsForDBMapper(t *testing.T) {
+	db, err := squealx.Connect("sqlite", ":memory:", "test", squealx.WithTagName("json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	q := &squealx.Query{
+		Name:   "user_by_email",
+		Query:  "SELECT * FROM users WHERE email = :email_address",
+		Params: []squealx.QueryParam{{Name: "email_address", Type: "string"}},
+	}
+	arg := struct {
+		Email string `json:"email_address"`
+	}{"ann@example.com"}
+	if err := q.ValidateArgsFor(db, arg); err != nil {
+		t.Errorf("ValidateArgsFor: %v", err)
+	}
+	if err := q.ValidateArgs(arg); !errors.Is(err, squealx.ErrInvalidQueryArgs) {
+		t.Errorf("ValidateArgs with the global mapper: %v, want ErrInvalidQueryArgs", err)
+	}
+}
@@ -65,11 +65,12 @@ func (f StructMap) GetByTraversal(index []int) *FieldInfo {
 // behaves like most marshallers in the standard library, obeying a field tag
 // for name mapping but also providing a basic transform function.
 type Mapper struct {
-	cache      map[reflect.Type]*StructMap
-	tagName    string
-	tagMapFunc func(string) string
-	mapFunc    func(string) string
-	mutex      sync.Mutex
+	cache        map[reflect.Type]*StructMap
+	tagName      string
+	fallbackTags []string
+	tagMapFunc   func(string) string
+	mapFunc      func(string) string
+	mutex        sync.Mutex
 }
 
 // NewMapper returns a new mapper using the tagName as its struct field tag.
@@ -104,13 +105,38 @@ func NewMapperFunc(tagName string, f func(string) string) *Mapper {
 	}
 }
 
+// NewMapperFallbackFunc returns a new mapper like NewMapperFunc which, for
+// fields without a tagName tag, takes the name from the first of
+// fallbackTags naming the field, such as `json:"name"`, before mapping the
+// field name with f. Fallback tags without a name or set to "-" are
+// ignored, so that `json:"-"` fields are still scanned.
+func NewMapperFallbackFunc(tagName string, fallbackTags []string, f func(string) string) *Mapper {
+	return &Mapper{
+		cache:        make(map[reflect.Type]*StructMap),
+		tagName:      tagName,
+		fallbackTags: fallbackTags,
+		mapFunc:      f,
+	}
+}
+
+// TagName returns the struct tag obeyed by m.
+func (m *Mapper) TagName() string {
+	return m.tagName
+}
+
+// FallbackTags returns the tags m falls back to for fields without a
+// TagName tag.
+func (m *Mapper) FallbackTags() []string {
+	return m.fallbackTags
+}
+
 // TypeMap returns a mapping of field strings to int slices representing
 // the traversal down the struct to reach the field.
 func (m *Mapper) TypeMap(t reflect.Type) *StructMap {
 	m.mutex.Lock()
 	mapping, ok := m.cache[t]
 	if !ok {
-		mapping = getMapping(t, m.tagName, m.mapFunc, m.tagMapFunc)
+		mapping = getMapping(t, m.tagName, m.fallbackTags, m.mapFunc, m.tagMapFunc)
 		m.cache[t] = mapping
 	}
 	m.mutex.Unlock()
@@ -209,30 +235,56 @@ func (m *Mapper) TraversalsByNameFunc(t reflect.Type, names []string, fn func(in
 // nested struct scanning functionality
 type ObjectContext struct {
 	value reflect.Value
+	// scanners are the nestedFieldScanners handed out for the current row,
+	// reused for the next rows and, through the pool, the next scans.
+	scanners []*nestedFieldScanner
+	next     int
 }
 
 func NewObjectContext() *ObjectContext {
 	return &ObjectContext{}
 }
 
+var objectContextPool = sync.Pool{
+	New: func() any { return &ObjectContext{} },
+}
+
+// AcquireObjectContext returns an ObjectContext from a pool. It must be
+// given back with Release once the values it produced are scanned.
+func AcquireObjectContext() *ObjectContext {
+	return objectContextPool.Get().(*ObjectContext)
+}
+
+// Release returns o to the pool of AcquireObjectContext.
+func (o *ObjectContext) Release() {
+	o.value = reflect.Value{}
+	o.next = 0
+	objectContextPool.Put(o)
+}
+
 // NewRow updates the object reference.
 // This ensures all columns point to the same object
 func (o *ObjectContext) NewRow(value reflect.Value) {
 	o.value = value
+	o.next = 0
 }
 
 // FieldForIndexes returns the value for address. If the address is a nested struct,
-// a nestedFieldScanner is returned instead of the standard value reference
+// a nestedFieldScanner is returned instead of the standard value reference.
+// The nestedFieldScanners of a row are reused after the next NewRow, so the
+// row must be scanned before then.
 func (o *ObjectContext) FieldForIndexes(indexes []int) reflect.Value {
 	if len(indexes) == 1 {
 		val := FieldByIndexes(o.value, indexes)
 		return val
 	}
 
-	obj := &nestedFieldScanner{
-		parent:  o,
-		indexes: indexes,
+	if o.next == len(o.scanners) {
+		o.scanners = append(o.scanners, &nestedFieldScanner{parent: o})
 	}
+	obj := o.scanners[o.next]
+	obj.indexes = indexes
+	o.next++
 
 	v := reflect.ValueOf(obj).Elem()
 	return v
@@ -258,6 +310,50 @@ func (o *nestedFieldScanner) Scan(src any) error {
 		dv.Set(reflect.New(dv.Type().Elem()))
 		dv = dv.Elem()
 	}
+	return Assign(dv, src)
+}
+
+// ScanDest returns the destination to pass to Scan for the field f. An
+// interface field holding a non-nil pointer is scanned into the pointed
+// value, and one holding any other value is scanned into a new value of
+// that runtime type, so that for example a `Payload any` field set to a
+// *MyJSON scanner keeps using it. Other fields are scanned in place.
+func ScanDest(f reflect.Value) any {
+	if f.Kind() != reflect.Interface || f.IsNil() {
+		return f.Addr().Interface()
+	}
+	concrete := f.Elem()
+	if concrete.Kind() == reflect.Pointer {
+		if concrete.IsNil() {
+			f.Set(reflect.New(concrete.Type().Elem()))
+		}
+		return f.Elem().Interface()
+	}
+	return &interfaceFieldScanner{field: f}
+}
+
+// interfaceFieldScanner scans into a new value of the runtime type of an
+// interface field and stores it in the field.
+type interfaceFieldScanner struct {
+	field reflect.Value
+}
+
+// Scan implements sql.Scanner. NULL sets the field to the zero value of its
+// runtime type.
+func (o *interfaceFieldScanner) Scan(src any) error {
+	dv := reflect.New(o.field.Elem().Type()).Elem()
+	if src != nil {
+		if err := Assign(dv, src); err != nil {
+			return err
+		}
+	}
+	o.field.Set(dv)
+	return nil
+}
+
+// Assign stores src, a driver value, into dv, which must be settable,
+// converting it like database/sql does for Scan.
+func Assign(dv reflect.Value, src any) error {
 	iface := dv.Addr().Interface()
 
 	if scan, ok := iface.(sql.Scanner); ok {
@@ -468,8 +564,9 @@ type mapf func(string) string
 // parseName parses the tag and the target name for the given field using
 // the tagName (eg 'json' for `json:"foo"` tags), mapFunc for mapping the
 // field's name to a target name, and tagMapFunc for mapping the tag to
-// a target name.
-func parseName(field reflect.StructField, tagName string, mapFunc, tagMapFunc mapf) (tag, fieldName string) {
+// a target name. Fields without a tagName tag are named by the first of
+// fallbackTags giving them a name.
+func parseName(field reflect.StructField, tagName string, fallbackTags []string, mapFunc, tagMapFunc mapf) (tag, fieldName string) {
 	// first, set the fieldName to the field's name
 	fieldName = field.Name
 	// if a mapFunc is set, use that to override the fieldName
@@ -489,6 +586,11 @@ func parseName(field reflect.StructField, tagName string, mapFunc, tagMapFunc ma
 	//    the value returned by Get is unspecified.
 	// which doesn't sound great.
 	if !strings.Contains(string(field.Tag), tagName+":") {
+		for _, fallback := range fallbackTags {
+			if tag, name := parseFallbackTag(field, fallback); name != "" {
+				return tag, name
+			}
+		}
 		return "", fieldName
 	}
 
@@ -509,6 +611,20 @@ func parseName(field reflect.StructField, tagName string, mapFunc, tagMapFunc ma
 	return tag, fieldName
 }
 
+// parseFallbackTag returns the tag tagName of field and the name it gives,
+// which is empty when the tag is missing, has no name or is "-".
+func parseFallbackTag(field reflect.StructField, tagName string) (tag, name string) {
+	tag, ok := field.Tag.Lookup(tagName)
+	if !ok {
+		return "", ""
+	}
+	name, _, _ = strings.Cut(tag, ",")
+	if name == "-" {
+		return "", ""
+	}
+	return tag, name
+}
+
 // parseOptions parses options out of a tag string, skipping the name
 func parseOptions(tag string) map[string]string {
 	parts := strings.Split(tag, ",")
@@ -529,7 +645,7 @@ func parseOptions(tag string) map[string]string {
 
 // getMapping returns a mapping for the t type, using the tagName, mapFunc and
 // tagMapFunc to determine the canonical names of fields.
-func getMapping(t reflect.Type, tagName string, mapFunc, tagMapFunc mapf) *StructMap {
+func getMapping(t reflect.Type, tagName string, fallbackTags []string, mapFunc, tagMapFunc mapf) *StructMap {
 	m := []*FieldInfo{}
 
 	root := &FieldInfo{}
@@ -561,7 +677,7 @@ QueueLoop:
 			f := tq.t.Field(fieldPos)
 
 			// parse the tag and the target name using the mapping options for this field
-			tag, name := parseName(f, tagName, mapFunc, tagMapFunc)
+			tag, name := parseName(f, tagName, fallbackTags, mapFunc, tagMapFunc)
 
 			// if the name is "-", disabled via a tag, skip it
 			if name == "-" {
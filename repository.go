@@ -2,23 +2,114 @@ package squealx
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"reflect"
+	"slices"
 	"strings"
 	"time"
+
+	"github.com/oarkflow/squealx/reflectx"
 )
 
 type repository[T any] struct {
 	db         *DB
 	table      string
 	primaryKey string
+	repositoryOptions
+}
+
+type repositoryOptions struct {
+	idGenerator      IDGenerator
+	allowedColumns   map[string]string
+	constraintErrors map[string]error
+	softDeleteReads  bool
+}
+
+// RepositoryOption configures a Repository created by New.
+type RepositoryOption func(*repositoryOptions)
+
+// WithIDGenerator makes Create fill in the primary key with gen when it is
+// not set, for tables whose keys are not generated by the database.
+func WithIDGenerator(gen IDGenerator) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.idGenerator = gen
+	}
+}
+
+// WithAllowedColumns restricts the sort and fields of the QueryParams of a
+// context to the given fields, mapped to their SQL columns. Without it the
+// sort and fields only accept plain column names.
+// See SafeOrderBy.
+func WithAllowedColumns(columns map[string]string) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.allowedColumns = columns
+	}
+}
+
+// WithConstraintErrors maps the constraints of the table to errors of the
+// application, returned by the writes of the Repository when they violate
+// them. See MapConstraintErrors.
+func WithConstraintErrors(errs map[string]error) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.constraintErrors = errs
+	}
+}
+
+// WithSoftDeleteReads makes every read of the Repository leave out the rows
+// removed with SoftDelete, as its aggregates always do, unless the context
+// is from WithDeleted. Without it, First, Find, All, Exists, Select,
+// Paginate and FirstForUpdate return them.
+func WithSoftDeleteReads() RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.softDeleteReads = true
+	}
+}
+
+func New[T any](db *DB, table, primaryKey string, opts ...RepositoryOption) Repository[T] {
+	r := &repository[T]{db: db, table: table, primaryKey: primaryKey}
+	for _, opt := range opts {
+		opt(&r.repositoryOptions)
+	}
+	return r
+}
+
+// getQueryParams returns the QueryParams of ctx, their fields validated by
+// SafeColumns: mapped to columns with WithAllowedColumns, and otherwise
+// restricted to plain column names. Their sort is validated by SafeOrderBy
+// when the query is built.
+func (r *repository[T]) getQueryParams(ctx context.Context) (QueryParams, error) {
+	queryParams := getQueryParams(ctx)
+	var err error
+	if len(queryParams.Fields) > 0 {
+		if queryParams.Fields, err = SafeColumns(r.allowedColumns, queryParams.Fields); err != nil {
+			return queryParams, err
+		}
+	}
+	if len(queryParams.Except) > 0 {
+		if queryParams.Except, err = SafeColumns(r.allowedColumns, queryParams.Except); err != nil {
+			return queryParams, err
+		}
+	}
+	return queryParams, nil
 }
 
-func New[T any](db *DB, table, primaryKey string) Repository[T] {
-	return &repository[T]{db: db, table: table, primaryKey: primaryKey}
+// orderBy returns the ORDER BY clause of the sort of queryParams, validated
+// by SafeOrderBy, or "" when there is none.
+func (r *repository[T]) orderBy(queryParams QueryParams) (string, error) {
+	if queryParams.Sort.Field == "" {
+		return "", nil
+	}
+	list, err := SafeOrderBy(r.allowedColumns, []SortSpec{queryParams.Sort})
+	if err != nil {
+		return "", err
+	}
+	return " ORDER BY " + list, nil
 }
 
-func (r *repository[T]) getQueryParams(ctx context.Context) QueryParams {
+func getQueryParams(ctx context.Context) QueryParams {
 	queryParams, ok := ctx.Value("query_params").(QueryParams)
 	if !ok {
 		return QueryParams{}
@@ -32,73 +123,556 @@ func (r *repository[T]) GetDB() *DB {
 
 func (r *repository[T]) First(ctx context.Context, cond map[string]any) (T, error) {
 	var rt T
-	queryParams := r.getQueryParams(ctx)
-	query, _, err := r.buildQuery(cond, queryParams)
+	queryParams, err := r.getQueryParams(ctx)
 	if err != nil {
 		return rt, err
 	}
-	return SelectTyped[T](r.db, fmt.Sprintf(`%s LIMIT 1`, query), cond)
+	identities, id := r.identity(ctx, cond, queryParams)
+	if identities != nil {
+		if entity, ok := identities.get(r.getTableName(), id); ok {
+			return entity.(T), nil
+		}
+	}
+	query, _, err := r.buildQuery(ctx, cond, queryParams)
+	if err != nil {
+		return rt, err
+	}
+	rt, err = selectTyped[T](ctx, r, fmt.Sprintf(`%s LIMIT 1`, query), cond)
+	if err == nil && identities != nil {
+		identities.set(r.getTableName(), id, rt)
+	}
+	return rt, err
+}
+
+// Refresh is like First but reloads a row looked up by primary key from the
+// database instead of taking it from the identity map of ctx.
+func (r *repository[T]) Refresh(ctx context.Context, cond map[string]any) (T, error) {
+	if identities, id := r.identity(ctx, cond, getQueryParams(ctx)); identities != nil {
+		identities.forget(r.getTableName(), id)
+	}
+	return r.First(ctx, cond)
+}
+
+// identity returns the identity map of ctx and the primary key looked up by
+// cond, or a nil map when cond is not a lookup of a whole row by primary key.
+func (r *repository[T]) identity(ctx context.Context, cond map[string]any, queryParams QueryParams) (*IdentityMap, any) {
+	identities := IdentityMapFromContext(ctx)
+	if identities == nil || len(cond) != 1 || len(queryParams.Fields) > 0 || len(queryParams.Except) > 0 {
+		return nil, nil
+	}
+	id, ok := cond[r.getPrimaryKey()]
+	if !ok {
+		return nil, nil
+	}
+	return identities, id
+}
+
+// forgetIdentities drops the rows of the table from the identity map of ctx
+// before a write.
+func (r *repository[T]) forgetIdentities(ctx context.Context) {
+	if identities := IdentityMapFromContext(ctx); identities != nil {
+		identities.forgetTable(r.getTableName())
+	}
+}
+
+// selectContext scans the rows of query into dest as DB.SelectContext
+// does, within the transaction carried by ctx when there is one.
+func (r *repository[T]) selectContext(ctx context.Context, dest any, query string, args ...any) error {
+	tx := txFromContext(ctx)
+	if tx == nil {
+		return r.db.SelectContext(ctx, dest, query, args...)
+	}
+	args = selectArgs(args)
+	query = SanitizeQuery(query, args...)
+	var err error
+	if IsNamedQuery(query) && len(args) > 0 {
+		query, args, err = tx.BindNamed(query, args[0])
+	} else if InReg.MatchString(query) {
+		query, args, err = tx.In(query, args...)
+	}
+	if err != nil {
+		return err
+	}
+	if t := reflect.TypeOf(dest); t != nil && t.Kind() == reflect.Ptr && t.Elem().Kind() != reflect.Slice {
+		return tx.GetContext(ctx, dest, query, args...)
+	}
+	return tx.SelectContext(ctx, dest, query, args...)
+}
+
+// selectTyped is SelectTypedContext running within the transaction carried
+// by ctx when there is one.
+func selectTyped[R, T any](ctx context.Context, r *repository[T], query string, args ...any) (R, error) {
+	var rt R
+	if typ := reflect.TypeOf(rt); typ != nil && typ.Kind() == reflect.Ptr {
+		rt = reflect.New(typ.Elem()).Interface().(R)
+		return rt, r.selectContext(ctx, rt, query, args...)
+	}
+	return rt, r.selectContext(ctx, &rt, query, args...)
+}
+
+// namedExecContext executes query with the named parameters of arg, within
+// the transaction carried by ctx when there is one.
+func (r *repository[T]) namedExecContext(ctx context.Context, query string, arg any) (sql.Result, error) {
+	if tx := txFromContext(ctx); tx != nil {
+		return tx.NamedExecContext(ctx, query, arg)
+	}
+	return r.db.NamedExecContext(ctx, query, arg)
+}
+
+// execWithReturn is DB.ExecWithReturnContext running within the transaction
+// carried by ctx when there is one.
+func (r *repository[T]) execWithReturn(ctx context.Context, query string, args any) error {
+	return r.db.execWithReturn(ctx, query, args, r.selectContext)
+}
+
+// ErrTxRequired is returned by FirstForUpdate when ctx carries no
+// transaction.
+var ErrTxRequired = errors.New("locking reads must run in a transaction, see ContextWithTx")
+
+// FirstForUpdate returns the first row matching cond, locked until the end of
+// the transaction carried by ctx, which is set with ContextWithTx. Rows are
+// ordered by the primary key unless a sort is set on ctx. On SQL Server the
+// locks are taken with table hints, and on SQLite, which locks the whole
+// database for writing transactions, no lock clause is added.
+func (r *repository[T]) FirstForUpdate(ctx context.Context, cond map[string]any, opts LockOptions) (T, error) {
+	var rt T
+	tx := txFromContext(ctx)
+	if tx == nil {
+		return rt, ErrTxRequired
+	}
+	if opts.SkipLocked && opts.NoWait {
+		return rt, errors.New("SkipLocked and NoWait are mutually exclusive")
+	}
+	queryParams, err := r.getQueryParams(ctx)
+	if err != nil {
+		return rt, err
+	}
+	fields := "*"
+	if len(queryParams.Fields) > 0 {
+		fields = strings.Join(queryParams.Fields, ", ")
+	}
+	whereClause, params, err := r.readWhereClause(ctx, cond, r.softDeleteReads)
+	if err != nil {
+		return rt, err
+	}
+	if whereClause != "" {
+		whereClause = " WHERE " + whereClause
+	}
+	orderBy, err := r.orderBy(queryParams)
+	if err != nil {
+		return rt, err
+	}
+	if orderBy == "" {
+		orderBy = " ORDER BY " + r.getPrimaryKey()
+	}
+	var query string
+	switch dialect := Dialect(r.db.driverName); dialect {
+	case DialectMSSQL:
+		hints := "UPDLOCK, ROWLOCK"
+		if opts.SkipLocked {
+			hints += ", READPAST"
+		} else if opts.NoWait {
+			hints += ", NOWAIT"
+		}
+		query = fmt.Sprintf("SELECT TOP 1 %s FROM %s WITH (%s)%s%s", fields, r.getTableName(), hints, whereClause, orderBy)
+	case DialectSQLite:
+		query = fmt.Sprintf("SELECT %s FROM %s%s%s LIMIT 1", fields, r.getTableName(), whereClause, orderBy)
+	default:
+		if opts.SkipLocked && dialect != DialectUnknown && !r.db.SupportsSkipLockedContext(ctx) {
+			return rt, fmt.Errorf("SKIP LOCKED is not supported by this %s server", dialect)
+		}
+		query = fmt.Sprintf("SELECT %s FROM %s%s%s LIMIT 1 FOR UPDATE", fields, r.getTableName(), whereClause, orderBy)
+		if opts.SkipLocked {
+			query += " SKIP LOCKED"
+		} else if opts.NoWait {
+			query += " NOWAIT"
+		}
+	}
+	query, args, err := tx.BindNamed(query, params)
+	if err != nil {
+		return rt, err
+	}
+	return rt, tx.GetContext(ctx, &rt, query, args...)
+}
+
+// FirstOrCreate returns the first row matching cond, inserting cond merged
+// with defaults when there is none. An insert losing a race with a
+// concurrent one is resolved through the unique constraints of the table:
+// it is skipped on conflict where the database supports it, or its unique
+// violation is ignored, and the row that won is returned.
+func (r *repository[T]) FirstOrCreate(ctx context.Context, cond, defaults map[string]any) (_ T, err error) {
+	defer r.mapError(&err)
+	row, err := r.First(ctx, cond)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return row, err
+	}
+	values := mergeFields(defaults, cond)
+	var clause string
+	switch Dialect(r.db.driverName) {
+	case DialectPostgres, DialectSQLite:
+		clause = " ON CONFLICT DO NOTHING"
+	}
+	if err := r.insert(ctx, values, QueryParams{}, clause, false); err != nil && !IsUniqueViolation(err) {
+		return row, err
+	}
+	return r.First(ctx, cond)
+}
+
+// UpdateOrCreate sets values on the row matching cond, inserting cond merged
+// with values when there is none, and returns the resulting row. On
+// PostgreSQL, SQLite and MySQL this is a single upsert, which requires a
+// unique index on the columns of cond. Elsewhere the update is tried first
+// and retried once when the insert hits a unique violation.
+func (r *repository[T]) UpdateOrCreate(ctx context.Context, cond, values map[string]any) (_ T, err error) {
+	defer r.mapError(&err)
+	r.forgetIdentities(ctx)
+	var rt T
+	row := mergeFields(cond, values)
+	upsert, err := r.buildUpsertClause(cond, values)
+	if err != nil {
+		return rt, err
+	}
+	if upsert != "" {
+		if err := r.insert(ctx, row, QueryParams{}, upsert, false); err != nil {
+			return rt, err
+		}
+		return r.First(ctx, cond)
+	}
+	for retried := false; ; retried = true {
+		updated, err := r.updateWhere(ctx, cond, values)
+		if err != nil {
+			return rt, err
+		}
+		if updated {
+			return r.First(ctx, cond)
+		}
+		err = r.insert(ctx, row, QueryParams{}, "", false)
+		if err == nil {
+			return r.First(ctx, cond)
+		}
+		if retried || !IsUniqueViolation(err) {
+			return rt, err
+		}
+	}
+}
+
+// updateWhere sets values on the rows matching cond and reports whether any
+// row matched.
+func (r *repository[T]) updateWhere(ctx context.Context, cond, values map[string]any) (bool, error) {
+	if len(values) == 0 {
+		_, err := r.First(ctx, cond)
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+	whereClause, params, err := buildWhereClause(r.mapper(), cond)
+	if err != nil {
+		return false, err
+	}
+	setClauses := make([]string, 0, len(values))
+	for col, val := range values {
+		setClauses = append(setClauses, fmt.Sprintf("%s = :set_%s", col, col))
+		params["set_"+col] = val
+	}
+	query := fmt.Sprintf("UPDATE %s SET %s", r.getTableName(), strings.Join(setClauses, ", "))
+	if whereClause != "" {
+		query += " WHERE " + whereClause
+	}
+	result, err := r.namedExecContext(ctx, query, params)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
 }
 
 func (r *repository[T]) Find(ctx context.Context, cond map[string]any) ([]T, error) {
 	var rt []T
-	queryParams := r.getQueryParams(ctx)
-	query, _, err := r.buildQuery(cond, queryParams)
+	queryParams, err := r.getQueryParams(ctx)
 	if err != nil {
 		return rt, err
 	}
-	return SelectTyped[[]T](r.db, query, cond)
+	query, _, err := r.buildQuery(ctx, cond, queryParams)
+	if err != nil {
+		return rt, err
+	}
+	return selectTyped[[]T](ctx, r, query, cond)
+}
+
+// FindInBatches calls fn with successive batches of at most batchSize rows
+// matching cond, until all rows are processed or fn returns an error. Batches
+// are read in primary key order with keyset pagination, so the cost of a
+// batch does not grow with the number of rows already processed.
+func (r *repository[T]) FindInBatches(ctx context.Context, cond map[string]any, batchSize int, fn func(batch []T) error) error {
+	if batchSize <= 0 {
+		return fmt.Errorf("invalid batch size %d", batchSize)
+	}
+	queryParams, err := r.getQueryParams(ctx)
+	if err != nil {
+		return err
+	}
+	queryParams.Sort = Sort{}
+	query, params, err := r.buildQuery(ctx, cond, queryParams)
+	if err != nil {
+		return err
+	}
+	pk := r.getPrimaryKey()
+	keyset := fmt.Sprintf(" ORDER BY %s LIMIT %d", pk, batchSize)
+	if Dialect(r.db.driverName) == DialectMSSQL {
+		keyset = fmt.Sprintf(" ORDER BY %s OFFSET 0 ROWS FETCH NEXT %d ROWS ONLY", pk, batchSize)
+	}
+	next := " WHERE "
+	if strings.Contains(query, " WHERE ") {
+		next = " AND "
+	}
+	next += fmt.Sprintf("%s > :batch_after", pk)
+	for after := any(nil); ; {
+		batchQuery := query
+		if after != nil {
+			params["batch_after"] = after
+			batchQuery += next
+		}
+		batch, err := selectTyped[[]T](ctx, r, batchQuery+keyset, params)
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := fn(batch); err != nil {
+			return err
+		}
+		if len(batch) < batchSize {
+			return nil
+		}
+		fields, err := getFields(r.mapper(), batch[len(batch)-1])
+		if err != nil {
+			return err
+		}
+		if after = fields[pk]; after == nil {
+			return fmt.Errorf("rows have no value for primary key %s", pk)
+		}
+	}
 }
 
 func (r *repository[T]) All(ctx context.Context) ([]T, error) {
 	var rt []T
-	queryParams := r.getQueryParams(ctx)
-	query, _, err := r.buildQuery(nil, queryParams)
+	queryParams, err := r.getQueryParams(ctx)
 	if err != nil {
 		return rt, err
 	}
-	return SelectTyped[[]T](r.db, query)
+	query, _, err := r.buildQuery(ctx, nil, queryParams)
+	if err != nil {
+		return rt, err
+	}
+	return selectTyped[[]T](ctx, r, query)
+}
+
+// Exists reports whether any row matches cond, without fetching it.
+func (r *repository[T]) Exists(ctx context.Context, cond map[string]any) (bool, error) {
+	query, params, err := r.buildQuery(ctx, cond, QueryParams{Fields: []string{"1"}})
+	if err != nil {
+		return false, err
+	}
+	query = fmt.Sprintf("SELECT EXISTS(%s)", query)
+	if Dialect(r.db.driverName) == DialectMSSQL {
+		query = fmt.Sprintf("SELECT CASE WHEN EXISTS(%s) THEN 1 ELSE 0 END", query)
+	}
+	var exists bool
+	return exists, r.selectContext(ctx, &exists, query, params)
+}
+
+// Select returns the given columns of the rows matching cond, sorted as by
+// Find.
+func (r *repository[T]) Select(ctx context.Context, cond map[string]any, columns ...string) ([]map[string]any, error) {
+	queryParams, err := r.getQueryParams(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if queryParams.Fields, err = SafeColumns(r.allowedColumns, columns); err != nil {
+		return nil, err
+	}
+	query, params, err := r.buildQuery(ctx, cond, queryParams)
+	if err != nil {
+		return nil, err
+	}
+	var rows []map[string]any
+	return rows, r.selectContext(ctx, &rows, query, params)
+}
+
+// Count returns the number of rows matching cond.
+func (r *repository[T]) Count(ctx context.Context, cond map[string]any) (int64, error) {
+	var n int64
+	return n, r.selectWhere(ctx, &n, "COUNT(*)", cond)
+}
+
+// CountDistinct returns the number of distinct non-NULL values of column in
+// the rows matching cond.
+func (r *repository[T]) CountDistinct(ctx context.Context, column string, cond map[string]any) (int64, error) {
+	var n int64
+	return n, r.selectColumn(ctx, &n, "COUNT(DISTINCT %s)", column, cond)
+}
+
+// Sum returns the sum of column over the rows matching cond, 0 when there
+// are none.
+func (r *repository[T]) Sum(ctx context.Context, column string, cond map[string]any) (float64, error) {
+	var sum float64
+	return sum, r.selectColumn(ctx, &sum, "COALESCE(SUM(%s), 0)", column, cond)
+}
+
+// Avg returns the average of column over the rows matching cond, 0 when
+// there are none.
+func (r *repository[T]) Avg(ctx context.Context, column string, cond map[string]any) (float64, error) {
+	var avg float64
+	return avg, r.selectColumn(ctx, &avg, "COALESCE(AVG(%s), 0)", column, cond)
+}
+
+// Min scans the smallest value of column in the rows matching cond into
+// dest, which must accept NULL when no row matches.
+func (r *repository[T]) Min(ctx context.Context, column string, cond map[string]any, dest any) error {
+	return r.selectColumn(ctx, dest, "MIN(%s)", column, cond)
+}
+
+// Max scans the largest value of column in the rows matching cond into
+// dest, which must accept NULL when no row matches.
+func (r *repository[T]) Max(ctx context.Context, column string, cond map[string]any, dest any) error {
+	return r.selectColumn(ctx, dest, "MAX(%s)", column, cond)
 }
 
+// Pluck scans the values of column in the rows matching the optional
+// condition into dest, a pointer to a slice.
+func (r *repository[T]) Pluck(ctx context.Context, column string, dest any, condition ...map[string]any) error {
+	var cond map[string]any
+	if len(condition) > 0 {
+		cond = condition[0]
+	}
+	return r.selectColumn(ctx, dest, "%s", column, cond)
+}
+
+// selectColumn scans the expression of format applied to column, validated
+// as by SafeColumns, over the rows matching cond into dest.
+func (r *repository[T]) selectColumn(ctx context.Context, dest any, format, column string, cond map[string]any) error {
+	column, err := safeColumn(r.allowedColumns, column)
+	if err != nil {
+		return err
+	}
+	return r.selectWhere(ctx, dest, fmt.Sprintf(format, column), cond)
+}
+
+// selectWhere scans the selected expression of the rows matching cond into
+// dest, leaving out soft deleted rows.
+func (r *repository[T]) selectWhere(ctx context.Context, dest any, expr string, cond map[string]any) error {
+	query, params, err := r.buildSelect(ctx, cond, QueryParams{Fields: []string{expr}}, true)
+	if err != nil {
+		return err
+	}
+	return r.selectContext(ctx, dest, query, params)
+}
+
+// ErrPaginateInTx is returned by Paginate and PaginateRaw when ctx carries a
+// transaction, which the concurrent count and page queries cannot share.
+var ErrPaginateInTx = errors.New("pagination cannot run in a transaction, see ContextWithTx")
+
 func (r *repository[T]) Paginate(ctx context.Context, paging Paging, condition ...map[string]any) PaginatedResponse {
 	var rt []T
-	queryParams := r.getQueryParams(ctx)
+	if txFromContext(ctx) != nil {
+		return PaginatedResponse{Error: ErrPaginateInTx}
+	}
+	queryParams, err := r.getQueryParams(ctx)
+	if err != nil {
+		return PaginatedResponse{Error: err}
+	}
 	var cond map[string]any
 	if len(condition) > 0 {
 		cond = condition[0]
 	}
-	query, _, err := r.buildQuery(cond, queryParams)
+	query, _, err := r.buildQuery(ctx, cond, queryParams)
 	if err != nil {
 		return PaginatedResponse{Error: err}
 	}
-	return Paginate(r.db, query, &rt, paging, condition...)
+	return PaginateContext(ctx, r.db, query, &rt, paging, cond)
 }
 
 func (r *repository[T]) PaginateRaw(ctx context.Context, paging Paging, query string, condition ...map[string]any) PaginatedResponse {
 	var rt []T
-	return Paginate(r.db, query, &rt, paging, condition...)
+	if txFromContext(ctx) != nil {
+		return PaginatedResponse{Error: ErrPaginateInTx}
+	}
+	var cond map[string]any
+	if len(condition) > 0 {
+		cond = condition[0]
+	}
+	return PaginateContext(ctx, r.db, query, &rt, paging, cond)
 }
 
-func (r *repository[T]) Create(ctx context.Context, data any) error {
-	queryParams := r.getQueryParams(ctx)
-	switch data := data.(type) {
+func (r *repository[T]) Create(ctx context.Context, data any) (err error) {
+	defer r.mapError(&err)
+	queryParams, err := r.getQueryParams(ctx)
+	if err != nil {
+		return err
+	}
+	return r.insert(ctx, data, queryParams, "", true)
+}
+
+// insert inserts data as Create does, running its hooks and generating its
+// primary key, with clause, such as the conflict handling of an upsert,
+// appended to the INSERT. With returning, data is filled in with the
+// inserted row.
+//
+// The hooks of a map of columns run on a model of the repository made from
+// it, and the fields they set are inserted along with the columns.
+func (r *repository[T]) insert(ctx context.Context, data any, queryParams QueryParams, clause string, returning bool) error {
+	r.forgetIdentities(ctx)
+	model := data
+	values, isMap := data.(map[string]any)
+	var hooked any
+	if isMap {
+		var err error
+		if hooked, err = r.hookModel(values); err != nil {
+			return err
+		}
+		if hooked != nil {
+			model = hooked
+		}
+	}
+	switch model := model.(type) {
 	case BeforeCreateHook:
-		err := data.BeforeCreate(r.db)
+		err := model.BeforeCreate(r.db)
 		if err != nil {
 			return err
 		}
 	}
+	if hooked != nil {
+		fields, err := dirtyFields(r.mapper(), model)
+		if err != nil {
+			return err
+		}
+		for col, val := range fields {
+			values[col] = val
+		}
+	}
+	if r.idGenerator != nil {
+		if err := r.generateID(ctx, data); err != nil {
+			return err
+		}
+	}
 	query, _, err := r.buildInsertQuery(data, queryParams)
 	if err != nil {
 		return err
 	}
-	err = r.db.ExecWithReturn(query, data)
+	query += clause
+	if returning {
+		err = r.execWithReturn(ctx, query, data)
+	} else {
+		_, err = r.namedExecContext(ctx, query, data)
+	}
 	if err != nil {
 		return err
 	}
-	switch data := data.(type) {
+	switch model := model.(type) {
 	case AfterCreateHook:
-		err := data.AfterCreate(r.db)
+		err := model.AfterCreate(r.db)
 		if err != nil {
 			return err
 		}
@@ -106,8 +680,31 @@ func (r *repository[T]) Create(ctx context.Context, data any) error {
 	return nil
 }
 
-func (r *repository[T]) Update(ctx context.Context, data any, condition map[string]any) error {
-	queryParams := r.getQueryParams(ctx)
+// hookModel returns a model of the repository with the columns of values
+// set, for the create hooks of an insert of values, or nil when the model
+// has none.
+func (r *repository[T]) hookModel(values map[string]any) (any, error) {
+	model := any(new(T))
+	switch model.(type) {
+	case BeforeCreateHook, AfterCreateHook:
+	default:
+		return nil, nil
+	}
+	for col, val := range values {
+		if err := setField(r.mapper(), model, col, val); err != nil {
+			return nil, err
+		}
+	}
+	return model, nil
+}
+
+func (r *repository[T]) Update(ctx context.Context, data any, condition map[string]any) (err error) {
+	defer r.mapError(&err)
+	r.forgetIdentities(ctx)
+	queryParams, err := r.getQueryParams(ctx)
+	if err != nil {
+		return err
+	}
 	switch data := data.(type) {
 	case BeforeUpdateHook:
 		err := data.BeforeUpdate(r.db)
@@ -119,7 +716,7 @@ func (r *repository[T]) Update(ctx context.Context, data any, condition map[stri
 	if err != nil {
 		return err
 	}
-	err = r.db.ExecWithReturn(query, &args)
+	err = r.execWithReturn(ctx, query, &args)
 	if err != nil {
 		return err
 	}
@@ -159,7 +756,9 @@ func (r *repository[T]) Update(ctx context.Context, data any, condition map[stri
 	return nil
 }
 
-func (r *repository[T]) Delete(ctx context.Context, data any) error {
+func (r *repository[T]) Delete(ctx context.Context, data any) (err error) {
+	defer r.mapError(&err)
+	r.forgetIdentities(ctx)
 	query, _, err := r.buildDeleteQuery(data)
 	if err != nil {
 		return err
@@ -171,7 +770,7 @@ func (r *repository[T]) Delete(ctx context.Context, data any) error {
 			return err
 		}
 	}
-	err = r.db.ExecWithReturn(query, data)
+	err = r.execWithReturn(ctx, query, data)
 	if err != nil {
 		return err
 	}
@@ -185,17 +784,39 @@ func (r *repository[T]) Delete(ctx context.Context, data any) error {
 	return nil
 }
 
+// softDeleteColumn is set by SoftDelete. The aggregates of models with this
+// column, and their reads with WithSoftDeleteReads, leave out the rows where
+// it is set.
+const softDeleteColumn = "deleted_at"
+
+type withDeletedKey struct{}
+
+// WithDeleted returns ctx making the aggregates of a Repository, and its
+// reads with WithSoftDeleteReads, include the rows removed with SoftDelete,
+// which they otherwise leave out.
+func WithDeleted(ctx context.Context) context.Context {
+	return context.WithValue(ctx, withDeletedKey{}, true)
+}
+
 func (r *repository[T]) SoftDelete(ctx context.Context, condition map[string]any) error {
-	data := map[string]any{"deleted_at": time.Now()}
+	data := map[string]any{softDeleteColumn: time.Now()}
 	return r.Update(ctx, data, condition)
 }
 
 func (r *repository[T]) Raw(ctx context.Context, query string, args ...any) ([]T, error) {
-	return SelectTyped[[]T](r.db, query, args...)
+	return selectTyped[[]T](ctx, r, query, args...)
+}
+
+func (r *repository[T]) RawExec(ctx context.Context, query string, args any) (err error) {
+	defer r.mapError(&err)
+	r.forgetIdentities(ctx)
+	return r.execWithReturn(ctx, query, args)
 }
 
-func (r *repository[T]) RawExec(ctx context.Context, query string, args any) error {
-	return r.db.ExecWithReturn(query, args)
+// mapError maps the constraint violation in *err to the error set with
+// WithConstraintErrors.
+func (r *repository[T]) mapError(err *error) {
+	*err = MapConstraintErrors(*err, r.constraintErrors)
 }
 
 func (r *repository[T]) getTableName() string {
@@ -207,41 +828,67 @@ func (r *repository[T]) getTableName() string {
 	return r.table
 }
 
-func (r *repository[T]) buildQuery(condition map[string]any, queryParams QueryParams) (string, map[string]any, error) {
+func (r *repository[T]) buildQuery(ctx context.Context, condition map[string]any, queryParams QueryParams) (string, map[string]any, error) {
+	return r.buildSelect(ctx, condition, queryParams, r.softDeleteReads)
+}
+
+// buildSelect builds the query of the rows matching condition, leaving out
+// soft deleted ones when softDelete is set.
+func (r *repository[T]) buildSelect(ctx context.Context, condition map[string]any, queryParams QueryParams, softDelete bool) (string, map[string]any, error) {
 	tableName := r.getTableName()
 	fields := "*"
 	if len(queryParams.Fields) > 0 {
 		fields = strings.Join(queryParams.Fields, ", ")
 	} else if len(queryParams.Except) > 0 {
-		allFields := getAllColumns[T]()
+		allFields := getAllColumns[T](r.mapper())
 		fields = strings.Join(excludeFieldsSlice(allFields, queryParams.Except), ", ")
 	}
 	query := fmt.Sprintf("SELECT %s FROM %s", fields, tableName)
-	whereClause := ""
-	params := map[string]any{}
+	whereClause, params, err := r.readWhereClause(ctx, condition, softDelete)
+	if err != nil {
+		return "", nil, err
+	}
+	if whereClause != "" {
+		query += " WHERE " + whereClause
+	}
+	orderBy, err := r.orderBy(queryParams)
+	if err != nil {
+		return "", nil, err
+	}
+	return query + orderBy, params, nil
+}
+
+// readWhereClause returns the WHERE clause of the reads of the rows matching
+// condition, leaving out soft deleted rows when softDelete is set, unless
+// ctx is from WithDeleted.
+func (r *repository[T]) readWhereClause(ctx context.Context, condition map[string]any, softDelete bool) (string, map[string]any, error) {
+	whereClause, params := "", map[string]any{}
 	if condition != nil {
 		var err error
-		whereClause, params, err = buildWhereClause(condition)
-		if err != nil {
+		if whereClause, params, err = buildWhereClause(r.mapper(), condition); err != nil {
 			return "", nil, err
 		}
 	}
-	if whereClause != "" {
-		query += " WHERE " + whereClause
+	if deleted, _ := ctx.Value(withDeletedKey{}).(bool); !softDelete || deleted || !slices.Contains(getAllColumns[T](r.mapper()), softDeleteColumn) {
+		return whereClause, params, nil
 	}
-	if queryParams.Sort.Field != "" {
-		sortDir := strings.ToUpper(queryParams.Sort.Dir)
-		if sortDir != "ASC" && sortDir != "DESC" {
-			sortDir = "ASC"
-		}
-		query += fmt.Sprintf(" ORDER BY %s %s", queryParams.Sort.Field, sortDir)
+	if whereClause == "" {
+		return softDeleteColumn + " IS NULL", params, nil
 	}
-	return query, params, nil
+	return fmt.Sprintf("(%s) AND %s IS NULL", whereClause, softDeleteColumn), params, nil
+}
+
+func sortDirection(dir string) string {
+	dir = strings.ToUpper(dir)
+	if dir != "ASC" && dir != "DESC" {
+		return "ASC"
+	}
+	return dir
 }
 
 func (r *repository[T]) buildInsertQuery(data any, queryParams QueryParams) (string, map[string]any, error) {
 	tableName := r.getTableName()
-	fields, err := DirtyFields(data)
+	fields, err := dirtyFields(r.mapper(), data)
 	if err != nil {
 		return "", nil, err
 	}
@@ -262,12 +909,47 @@ func (r *repository[T]) buildInsertQuery(data any, queryParams QueryParams) (str
 	return query, values, nil
 }
 
+// buildUpsertClause returns the clause turning an insert of cond and values
+// into an upsert keyed on the columns of cond, or "" when the database has
+// no upsert. cond must not be empty.
+func (r *repository[T]) buildUpsertClause(cond, values map[string]any) (string, error) {
+	if len(cond) == 0 {
+		return "", errors.New("upsert requires a condition naming its unique columns")
+	}
+	keys := sortedKeys(cond)
+	var updates []string
+	for _, col := range sortedKeys(values) {
+		if _, ok := cond[col]; !ok {
+			updates = append(updates, col)
+		}
+	}
+	switch Dialect(r.db.driverName) {
+	case DialectPostgres, DialectSQLite:
+		if len(updates) == 0 {
+			return fmt.Sprintf(" ON CONFLICT (%s) DO NOTHING", strings.Join(keys, ", ")), nil
+		}
+		for i, col := range updates {
+			updates[i] = fmt.Sprintf("%s = EXCLUDED.%s", col, col)
+		}
+		return fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(keys, ", "), strings.Join(updates, ", ")), nil
+	case DialectMySQL:
+		if len(updates) == 0 {
+			return fmt.Sprintf(" ON DUPLICATE KEY UPDATE %s = %s", keys[0], keys[0]), nil
+		}
+		for i, col := range updates {
+			updates[i] = fmt.Sprintf("%s = VALUES(%s)", col, col)
+		}
+		return " ON DUPLICATE KEY UPDATE " + strings.Join(updates, ", "), nil
+	}
+	return "", nil
+}
+
 func (r *repository[T]) buildDeleteQuery(condition any) (string, map[string]any, error) {
 	tableName := r.getTableName()
 	var whereClause string
 	params := make(map[string]any)
 	if condition != nil {
-		condClause, condParams, err := buildWhereClause(condition)
+		condClause, condParams, err := buildWhereClause(r.mapper(), condition)
 		if err != nil {
 			return "", nil, err
 		}
@@ -287,7 +969,7 @@ func (r *repository[T]) buildUpdateQuery(data any, condition map[string]any, que
 	pkColumn := r.getPrimaryKey()
 	switch t := data.(type) {
 	case Entity:
-		fields, err = DirtyFields(t)
+		fields, err = dirtyFields(r.mapper(), t)
 		if err != nil {
 			return "", nil, err
 		}
@@ -312,7 +994,7 @@ func (r *repository[T]) buildUpdateQuery(data any, condition map[string]any, que
 	}
 	whereClause := ""
 	if condition != nil {
-		condClause, condParams, err := buildWhereClause(condition)
+		condClause, condParams, err := buildWhereClause(r.mapper(), condition)
 		if err != nil {
 			return "", nil, err
 		}
@@ -325,6 +1007,33 @@ func (r *repository[T]) buildUpdateQuery(data any, condition map[string]any, que
 	return query, values, nil
 }
 
+// generateID sets the primary key of data from the ID generator unless it
+// already has a value.
+func (r *repository[T]) generateID(ctx context.Context, data any) error {
+	pk := r.getPrimaryKey()
+	fields, err := getFields(r.mapper(), data)
+	if err != nil {
+		return err
+	}
+	if v, ok := fields[pk]; ok && v != nil && !reflect.ValueOf(v).IsZero() {
+		return nil
+	}
+	id, err := r.idGenerator.NextID(ctx)
+	if err != nil {
+		return err
+	}
+	return setField(r.mapper(), data, pk, id)
+}
+
+// mapper returns the mapper naming the columns of the model, that of the
+// DB of the repository.
+func (r *repository[T]) mapper() *reflectx.Mapper {
+	if r.db.Mapper == nil {
+		return mapper()
+	}
+	return r.db.Mapper
+}
+
 func (r *repository[T]) getPrimaryKey() string {
 	var t T
 	switch t := any(t).(type) {
@@ -0,0 +1,253 @@
+package squealx
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// CacheStore is a key-value store with expiry, such as an in-process cache
+// or Redis, used to cache query results.
+type CacheStore interface {
+	// Get returns the value of key and whether it was found.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value under key for ttl, or without expiry when ttl is 0.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+// MemoryCache is a CacheStore keeping values in process memory. It holds at
+// most a bounded number of values, evicting the least recently used ones;
+// expired values are dropped when read or evicted.
+type MemoryCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	items      map[string]*list.Element
+	// lru orders the items from the most to the least recently used.
+	lru *list.List
+}
+
+type memoryCacheItem struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// DefaultMemoryCacheEntries is the number of values a MemoryCache created by
+// NewMemoryCache holds.
+const DefaultMemoryCacheEntries = 10000
+
+// NewMemoryCache returns a MemoryCache holding DefaultMemoryCacheEntries
+// values, or maxEntries when given and positive.
+func NewMemoryCache(maxEntries ...int) *MemoryCache {
+	c := &MemoryCache{maxEntries: DefaultMemoryCacheEntries, items: make(map[string]*list.Element), lru: list.New()}
+	if len(maxEntries) > 0 && maxEntries[0] > 0 {
+		c.maxEntries = maxEntries[0]
+	}
+	return c
+}
+
+func (c *MemoryCache) Get(_ context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+	item := e.Value.(*memoryCacheItem)
+	if !item.expires.IsZero() && time.Now().After(item.expires) {
+		c.remove(e)
+		return nil, false, nil
+	}
+	c.lru.MoveToFront(e)
+	return item.value, true, nil
+}
+
+func (c *MemoryCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	item := &memoryCacheItem{key: key, value: value}
+	if ttl > 0 {
+		item.expires = time.Now().Add(ttl)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[key]; ok {
+		e.Value = item
+		c.lru.MoveToFront(e)
+		return nil
+	}
+	c.items[key] = c.lru.PushFront(item)
+	for c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+	}
+	return nil
+}
+
+func (c *MemoryCache) Delete(_ context.Context, key string) error {
+	c.mu.Lock()
+	if e, ok := c.items[key]; ok {
+		c.remove(e)
+	}
+	c.mu.Unlock()
+	return nil
+}
+
+// Len returns the number of values held, expired ones included until they
+// are read or evicted.
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+func (c *MemoryCache) remove(e *list.Element) {
+	c.lru.Remove(e)
+	delete(c.items, e.Value.(*memoryCacheItem).key)
+}
+
+// CachedRepository is a Repository caching the results of First, Find and
+// Count. Every write through it invalidates all cached results of the table,
+// including those cached by other CachedRepository values sharing the store;
+// writes in a transaction invalidate them again when it commits.
+//
+// Results are cached as JSON, so T must survive a JSON round trip. Writes
+// bypassing the repository are not seen until InvalidateAll is called or
+// the entries expire.
+type CachedRepository[T any] struct {
+	Repository[T]
+	store CacheStore
+	ttl   time.Duration
+	table string
+}
+
+// Cached returns the repository with First, Find and Count results cached
+// in store for ttl.
+func (r *repository[T]) Cached(store CacheStore, ttl time.Duration) *CachedRepository[T] {
+	return &CachedRepository[T]{Repository: r, store: store, ttl: ttl, table: r.getTableName()}
+}
+
+func (c *CachedRepository[T]) First(ctx context.Context, cond map[string]any) (T, error) {
+	return cached(ctx, c.store, c.ttl, c.key(ctx, "first", cond), func() (T, error) {
+		return c.Repository.First(ctx, cond)
+	})
+}
+
+func (c *CachedRepository[T]) Find(ctx context.Context, cond map[string]any) ([]T, error) {
+	return cached(ctx, c.store, c.ttl, c.key(ctx, "find", cond), func() ([]T, error) {
+		return c.Repository.Find(ctx, cond)
+	})
+}
+
+func (c *CachedRepository[T]) Count(ctx context.Context, cond map[string]any) (int64, error) {
+	return cached(ctx, c.store, c.ttl, c.key(ctx, "count", cond), func() (int64, error) {
+		return c.Repository.Count(ctx, cond)
+	})
+}
+
+func (c *CachedRepository[T]) Create(ctx context.Context, data any) error {
+	defer c.invalidate(ctx)
+	return c.Repository.Create(ctx, data)
+}
+
+func (c *CachedRepository[T]) Update(ctx context.Context, data any, condition map[string]any) error {
+	defer c.invalidate(ctx)
+	return c.Repository.Update(ctx, data, condition)
+}
+
+func (c *CachedRepository[T]) Delete(ctx context.Context, data any) error {
+	defer c.invalidate(ctx)
+	return c.Repository.Delete(ctx, data)
+}
+
+func (c *CachedRepository[T]) SoftDelete(ctx context.Context, condition map[string]any) error {
+	defer c.invalidate(ctx)
+	return c.Repository.SoftDelete(ctx, condition)
+}
+
+func (c *CachedRepository[T]) RawExec(ctx context.Context, query string, args any) error {
+	defer c.invalidate(ctx)
+	return c.Repository.RawExec(ctx, query, args)
+}
+
+func (c *CachedRepository[T]) FirstOrCreate(ctx context.Context, cond, defaults map[string]any) (T, error) {
+	defer c.invalidate(ctx)
+	return c.Repository.FirstOrCreate(ctx, cond, defaults)
+}
+
+func (c *CachedRepository[T]) UpdateOrCreate(ctx context.Context, cond, values map[string]any) (T, error) {
+	defer c.invalidate(ctx)
+	return c.Repository.UpdateOrCreate(ctx, cond, values)
+}
+
+// invalidate drops the cached results after a write. In a transaction, the
+// results are dropped again once it commits: reads run before the commit
+// still see, and may cache, the rows it replaces.
+func (c *CachedRepository[T]) invalidate(ctx context.Context) {
+	_ = c.InvalidateAll(ctx)
+	if tx := txFromContext(ctx); tx != nil {
+		tx.onCommit(func() { _ = c.InvalidateAll(context.WithoutCancel(ctx)) })
+	}
+}
+
+// InvalidateAll drops every cached result of the table by moving it to a new
+// cache generation. The results of older generations are no longer read;
+// they stay in the store until they expire or, in a MemoryCache, are
+// evicted as the least recently used.
+func (c *CachedRepository[T]) InvalidateAll(ctx context.Context) error {
+	return c.store.Set(ctx, c.generationKey(), []byte(NewULID()), 0)
+}
+
+func (c *CachedRepository[T]) generationKey() string {
+	return "squealx:" + c.table + ":generation"
+}
+
+// generation returns the current cache generation of the table, starting a
+// new one when the store has none.
+func (c *CachedRepository[T]) generation(ctx context.Context) (string, error) {
+	gen, ok, err := c.store.Get(ctx, c.generationKey())
+	if err != nil || ok {
+		return string(gen), err
+	}
+	gen = []byte(NewULID())
+	return string(gen), c.store.Set(ctx, c.generationKey(), gen, 0)
+}
+
+// key returns the cache key of op for cond in the current generation, or ""
+// when the store fails.
+func (c *CachedRepository[T]) key(ctx context.Context, op string, cond map[string]any) string {
+	gen, err := c.generation(ctx)
+	if err != nil {
+		return ""
+	}
+	// json.Marshal sorts map keys, which normalizes the condition.
+	deleted, _ := ctx.Value(withDeletedKey{}).(bool)
+	params, err := json.Marshal(struct {
+		Cond    map[string]any `json:"cond"`
+		Params  QueryParams    `json:"params"`
+		Deleted bool           `json:"deleted,omitempty"`
+	}{cond, getQueryParams(ctx), deleted})
+	if err != nil {
+		return ""
+	}
+	return "squealx:" + c.table + ":" + gen + ":" + op + ":" + string(params)
+}
+
+// cached returns the result cached under key, or calls query and caches its
+// result. Without a key, or when the store fails, query is called directly.
+func cached[R any](ctx context.Context, store CacheStore, ttl time.Duration, key string, query func() (R, error)) (R, error) {
+	if key != "" {
+		var result R
+		if value, ok, err := store.Get(ctx, key); err == nil && ok && json.Unmarshal(value, &result) == nil {
+			return result, nil
+		}
+	}
+	result, err := query()
+	if err != nil || key == "" {
+		return result, err
+	}
+	if value, err := json.Marshal(result); err == nil {
+		_ = store.Set(ctx, key, value, ttl)
+	}
+	return result, nil
+}
@@ -0,0 +1,341 @@
+package squealx_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oarkflow/squealx"
+	_ "modernc.org/sqlite"
+)
+
+// Model is embedded by the models of the tests, generic in its key type.
+type Model[K comparable] struct {
+	ID K `db:"id"`
+}
+
+type Product struct {
+	Model[string]
+	Name  string  `db:"name"`
+	Price float64 `db:"price"`
+}
+
+// Labeled is a generic model with a field of its type parameter.
+type Labeled[V any] struct {
+	ID    int64  `db:"id"`
+	Label string `db:"label"`
+	Value V      `db:"value"`
+}
+
+// Payload is a model with an interface field.
+type Payload struct {
+	ID   int64 `db:"id"`
+	Data any   `db:"data"`
+}
+
+func openTestDB(t testing.TB, schema ...string) *squealx.DB {
+	t.Helper()
+	db, err := squealx.Connect("sqlite", ":memory:", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: opens a new database.
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	for _, stmt := range schema {
+		db.MustExec(stmt)
+	}
+	return db
+}
+
+func TestRepositoryEmbeddedGenericModel(t *testing.T) {
+	db := openTestDB(t, `CREATE TABLE products (id TEXT PRIMARY KEY, name TEXT, price REAL)`)
+	repo := squealx.New[Product](db, "products", "id", squealx.WithIDGenerator(squealx.ULIDGenerator))
+	ctx := context.Background()
+
+	p := &Product{Name: "pen", Price: 1.5}
+	if err := repo.Create(ctx, p); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if p.ID == "" {
+		t.Fatal("Create did not set the generated key of the embedded model")
+	}
+	got, err := repo.First(ctx, map[string]any{"id": p.ID})
+	if err != nil {
+		t.Fatalf("First: %v", err)
+	}
+	if got != *p {
+		t.Errorf("First = %+v, want %+v", got, *p)
+	}
+	all, err := repo.Find(ctx, map[string]any{"name": "pen"})
+	if err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+	if len(all) != 1 || all[0].ID != p.ID {
+		t.Errorf("Find = %+v, want the created product", all)
+	}
+}
+
+func TestRepositoryTypeParameterField(t *testing.T) {
+	db := openTestDB(t, `CREATE TABLE settings (id INTEGER PRIMARY KEY, label TEXT, value INTEGER)`)
+	repo := squealx.New[Labeled[int]](db, "settings", "id")
+	ctx := context.Background()
+
+	if err := repo.Create(ctx, &map[string]any{"label": "retries", "value": 3}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	got, err := repo.First(ctx, map[string]any{"label": "retries"})
+	if err != nil {
+		t.Fatalf("First: %v", err)
+	}
+	if got.Value != 3 || got.Label != "retries" || got.ID == 0 {
+		t.Errorf("First = %+v, want retries = 3", got)
+	}
+	rows, err := squealx.SelectTyped[[]Labeled[sql.NullString]](db, "SELECT id, label, CAST(value AS TEXT) AS value FROM settings")
+	if err != nil {
+		t.Fatalf("SelectTyped: %v", err)
+	}
+	if len(rows) != 1 || rows[0].Value != (sql.NullString{String: "3", Valid: true}) {
+		t.Errorf("SelectTyped = %+v, want value \"3\"", rows)
+	}
+}
+
+func TestRepositoryInterfaceField(t *testing.T) {
+	db := openTestDB(t,
+		`CREATE TABLE payloads (id INTEGER PRIMARY KEY, data TEXT)`,
+		`INSERT INTO payloads (data) VALUES ('a'), (NULL)`,
+	)
+	var rows []Payload
+	// A nil interface field receives the driver value.
+	if err := db.Select(&rows, "SELECT id, data FROM payloads ORDER BY id"); err != nil {
+		t.Fatalf("Select: %v", err)
+	}
+	if len(rows) != 2 || rows[0].Data != "a" || rows[1].Data != nil {
+		t.Errorf("Select = %+v, want a and NULL", rows)
+	}
+	// A field holding a value is scanned into its runtime type, and one
+	// holding a pointer into the pointed value.
+	byValue := Payload{Data: sql.NullString{}}
+	if err := db.Get(&byValue, "SELECT id, data FROM payloads WHERE id = 1"); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if byValue.Data != (sql.NullString{String: "a", Valid: true}) {
+		t.Errorf("Get by value = %#v, want a valid NullString", byValue.Data)
+	}
+	target := &sql.NullString{}
+	byPointer := Payload{Data: target}
+	if err := db.Get(&byPointer, "SELECT id, data FROM payloads WHERE id = 2"); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if byPointer.Data != target || target.Valid {
+		t.Errorf("Get by pointer = %#v, want the NULL scanned into the given NullString", byPointer.Data)
+	}
+}
+
+// Account is a model with create hooks.
+type Account struct {
+	ID     string `db:"id"`
+	Email  string `db:"email"`
+	Handle string `db:"handle"`
+}
+
+func (a *Account) BeforeCreate(*squealx.DB) error {
+	if a.Handle == "" {
+		a.Handle = strings.Split(a.Email, "@")[0]
+	}
+	return nil
+}
+
+func TestRepositoryFirstOrCreate(t *testing.T) {
+	db := openTestDB(t, `CREATE TABLE accounts (id TEXT PRIMARY KEY, email TEXT UNIQUE, handle TEXT)`)
+	repo := squealx.New[Account](db, "accounts", "id", squealx.WithIDGenerator(squealx.ULIDGenerator))
+	ctx := squealx.WithIdentityMap(context.Background())
+
+	created, err := repo.FirstOrCreate(ctx, map[string]any{"email": "ada@example.com"}, nil)
+	if err != nil {
+		t.Fatalf("FirstOrCreate: %v", err)
+	}
+	if created.ID == "" || created.Handle != "ada" {
+		t.Errorf("FirstOrCreate = %+v, want a generated key and the handle set by BeforeCreate", created)
+	}
+	found, err := repo.FirstOrCreate(ctx, map[string]any{"email": "ada@example.com"}, map[string]any{"handle": "other"})
+	if err != nil {
+		t.Fatalf("FirstOrCreate: %v", err)
+	}
+	if found != created {
+		t.Errorf("FirstOrCreate = %+v, want the existing %+v", found, created)
+	}
+
+	updated, err := repo.UpdateOrCreate(ctx, map[string]any{"email": "bob@example.com"}, map[string]any{"handle": "bobby"})
+	if err != nil {
+		t.Fatalf("UpdateOrCreate: %v", err)
+	}
+	if updated.ID == "" || updated.Handle != "bobby" {
+		t.Errorf("UpdateOrCreate = %+v, want a generated key and the given handle", updated)
+	}
+}
+
+func TestRepositoryContextWithTx(t *testing.T) {
+	db := openTestDB(t, `CREATE TABLE products (id TEXT PRIMARY KEY, name TEXT, price REAL)`)
+	repo := squealx.New[Product](db, "products", "id", squealx.WithIDGenerator(squealx.ULIDGenerator))
+	ctx := context.Background()
+	if err := repo.Create(ctx, &Product{Name: "pen", Price: 1}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// The pool has a single connection, which the transaction holds: an
+	// operation outside of it would block.
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txCtx := squealx.ContextWithTx(ctx, tx)
+	if err := repo.Create(txCtx, &Product{Name: "ink", Price: 2}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := repo.UpdateOrCreate(txCtx, map[string]any{"id": "p1"}, map[string]any{"name": "pad", "price": 4.0}); err != nil {
+		t.Fatalf("UpdateOrCreate: %v", err)
+	}
+	if err := repo.Update(txCtx, map[string]any{"price": 3.0}, map[string]any{"name": "pen"}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	all, err := repo.All(txCtx)
+	if err != nil {
+		t.Fatalf("All: %v", err)
+	}
+	if len(all) != 3 {
+		t.Errorf("All = %+v, want the rows written in the transaction", all)
+	}
+	if n, err := repo.Count(txCtx, map[string]any{"price": 3.0}); err != nil || n != 1 {
+		t.Errorf("Count = %d, %v, want 1", n, err)
+	}
+	if res := repo.Paginate(txCtx, squealx.Paging{}); !errors.Is(res.Error, squealx.ErrPaginateInTx) {
+		t.Errorf("Paginate error = %v, want ErrPaginateInTx", res.Error)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err = repo.All(ctx)
+	if err != nil {
+		t.Fatalf("All: %v", err)
+	}
+	if len(all) != 1 || all[0].Price != 1 {
+		t.Errorf("All = %+v, want the writes of the transaction rolled back", all)
+	}
+}
+
+func TestCachedRepositoryInvalidatesOnCommit(t *testing.T) {
+	// A file database, so that reads can run beside the transaction.
+	db, err := squealx.Connect("sqlite", filepath.Join(t.TempDir(), "test.db"), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	db.MustExec(`CREATE TABLE products (id TEXT PRIMARY KEY, name TEXT, price REAL)`)
+	repo := squealx.New[Product](db, "products", "id").Cached(squealx.NewMemoryCache(), 0)
+	ctx := context.Background()
+	if err := repo.Create(ctx, &Product{Model: Model[string]{ID: "p1"}, Name: "pen", Price: 1}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if err := repo.Update(squealx.ContextWithTx(ctx, tx), map[string]any{"price": 2.0}, map[string]any{"id": "p1"}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	// A read outside the transaction caches the row it has not committed.
+	if p, err := repo.First(ctx, map[string]any{"id": "p1"}); err != nil || p.Price != 1 {
+		t.Fatalf("First before commit = %+v, %v, want the committed row", p, err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if p, err := repo.First(ctx, map[string]any{"id": "p1"}); err != nil || p.Price != 2 {
+		t.Errorf("First after commit = %+v, %v, want the row updated by the transaction", p, err)
+	}
+}
+
+// Member is a model whose columns are named by the mapper of its DB.
+type Member struct {
+	ID       int64 `json:"id"`
+	UserName string
+	Email    string `json:"email_addr"`
+}
+
+func TestRepositoryMapperColumns(t *testing.T) {
+	db, err := squealx.Connect("sqlite", ":memory:", "test",
+		squealx.WithNamingStrategy(squealx.LowerCamel), squealx.WithJSONTagFallback())
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	db.MustExec(`CREATE TABLE members (id INTEGER PRIMARY KEY, userName TEXT, email_addr TEXT)`)
+	repo := squealx.New[Member](db, "members", "id",
+		squealx.WithIDGenerator(squealx.IDGeneratorFunc(func(context.Context) (any, error) { return 7, nil })))
+	ctx := context.Background()
+
+	m := &Member{UserName: "ada", Email: "ada@example.com"}
+	if err := repo.Create(ctx, m); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if m.ID != 7 {
+		t.Errorf("Create set ID %d, want the generated 7", m.ID)
+	}
+	got, err := repo.First(ctx, map[string]any{"email_addr": "ada@example.com"})
+	if err != nil {
+		t.Fatalf("First: %v", err)
+	}
+	if got != *m {
+		t.Errorf("First = %+v, want %+v", got, *m)
+	}
+}
+
+// Note is a model removed with SoftDelete.
+type Note struct {
+	ID        int64      `db:"id"`
+	Body      string     `db:"body"`
+	DeletedAt *time.Time `db:"deleted_at"`
+}
+
+func TestRepositorySoftDeleteReads(t *testing.T) {
+	db := openTestDB(t,
+		`CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT, deleted_at TIMESTAMP)`,
+		`INSERT INTO notes (body) VALUES ('kept'), ('removed')`)
+	ctx := context.Background()
+	repo := squealx.New[Note](db, "notes", "id")
+	if err := repo.SoftDelete(ctx, map[string]any{"body": "removed"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reads keep returning soft deleted rows unless asked not to.
+	if all, err := repo.All(ctx); err != nil || len(all) != 2 {
+		t.Errorf("All = %d rows, %v, want 2", len(all), err)
+	}
+	if _, err := repo.First(ctx, map[string]any{"body": "removed"}); err != nil {
+		t.Errorf("First of a soft deleted row: %v", err)
+	}
+	// Aggregates leave them out.
+	if n, err := repo.Count(ctx, nil); err != nil || n != 1 {
+		t.Errorf("Count = %d, %v, want 1", n, err)
+	}
+	if n, err := repo.Count(squealx.WithDeleted(ctx), nil); err != nil || n != 2 {
+		t.Errorf("Count with deleted = %d, %v, want 2", n, err)
+	}
+
+	filtered := squealx.New[Note](db, "notes", "id", squealx.WithSoftDeleteReads())
+	if all, err := filtered.All(ctx); err != nil || len(all) != 1 || all[0].Body != "kept" {
+		t.Errorf("All with WithSoftDeleteReads = %+v, %v, want the kept note", all, err)
+	}
+	if all, err := filtered.All(squealx.WithDeleted(ctx)); err != nil || len(all) != 2 {
+		t.Errorf("All with deleted = %d rows, %v, want 2", len(all), err)
+	}
+}
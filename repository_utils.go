@@ -1,14 +1,22 @@
 package squealx
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
-	"github.com/oarkflow/squealx/utils/xstrings"
+	"github.com/oarkflow/squealx/reflectx"
 	"reflect"
+	"sort"
 	"strings"
 )
 
+// DirtyFields returns the columns of u with a non-zero value, named by the
+// global NameMapper. A map is returned as is.
 func DirtyFields(u any) (map[string]interface{}, error) {
+	return dirtyFields(mapper(), u)
+}
+
+func dirtyFields(m *reflectx.Mapper, u any) (map[string]interface{}, error) {
 	switch u := u.(type) {
 	case map[string]any:
 		return u, nil
@@ -23,38 +31,75 @@ func DirtyFields(u any) (map[string]interface{}, error) {
 		return nil, fmt.Errorf("expected a struct or struct pointer, got %s", v.Kind())
 	}
 	setFields := make(map[string]interface{})
-	t := v.Type()
-	zero := reflect.Zero(v.Type()).Interface()
-	for i := 0; i < v.NumField(); i++ {
-		field := v.Field(i)
-		fieldType := t.Field(i)
-		fieldName := fieldType.Tag.Get("db")
-		if fieldName == "" {
-			fieldName = xstrings.ToSnakeCase(fieldType.Name)
+	for _, column := range structColumns(m, v.Type()) {
+		field, err := v.FieldByIndexErr(column.index)
+		if err != nil {
+			continue
 		}
-		zeroField := reflect.ValueOf(zero).Field(i)
-		if !reflect.DeepEqual(field.Interface(), zeroField.Interface()) {
-			setFields[fieldName] = field.Interface()
+		if !reflect.DeepEqual(field.Interface(), reflect.Zero(field.Type()).Interface()) {
+			setFields[column.name] = field.Interface()
 		}
 	}
 	return setFields, nil
 }
 
-func getAllColumns[T any]() []string {
+// structColumn is a column of a struct type and the index of its field.
+type structColumn struct {
+	name  string
+	index []int
+}
+
+// structColumns returns the columns of the fields of the struct type t, as
+// named by m, so that its tags, fallback tags and naming strategy apply. The
+// fields of embedded structs without a tag, such as generic base models, are
+// columns of t; other struct fields are columns of their own.
+func structColumns(m *reflectx.Mapper, t reflect.Type) []structColumn {
+	typeMap := m.TypeMap(t)
+	var columns []structColumn
+	var walk func(fi *reflectx.FieldInfo)
+	walk = func(fi *reflectx.FieldInfo) {
+		for _, field := range fi.Children {
+			switch {
+			case field == nil:
+			case field.Embedded && flattened(field):
+				walk(field)
+			case field.Field.IsExported() && typeMap.Paths[field.Path] == field:
+				columns = append(columns, structColumn{name: field.Path, index: field.Index})
+			}
+		}
+	}
+	walk(typeMap.Tree)
+	return columns
+}
+
+// flattened reports whether the fields of the embedded struct field are
+// mapped as fields of the struct embedding it, which is the case when the
+// field has no tag.
+func flattened(field *reflectx.FieldInfo) bool {
+	if reflectx.Deref(field.Field.Type).Kind() != reflect.Struct {
+		return false
+	}
+	for _, child := range field.Children {
+		if child != nil && strings.HasPrefix(child.Path, field.Path+".") {
+			return false
+		}
+	}
+	return true
+}
+
+func getAllColumns[T any](m *reflectx.Mapper) []string {
 	var t T
 	var columns []string
 	tValue := reflect.TypeOf(t)
+	if tValue == nil {
+		return nil
+	}
 	if tValue.Kind() == reflect.Ptr {
 		tValue = tValue.Elem()
 	}
 	if tValue.Kind() == reflect.Struct {
-		for i := 0; i < tValue.NumField(); i++ {
-			field := tValue.Field(i)
-			columnName := field.Tag.Get("db")
-			if columnName == "" {
-				columnName = xstrings.ToSnakeCase(field.Name)
-			}
-			columns = append(columns, columnName)
+		for _, column := range structColumns(m, tValue) {
+			columns = append(columns, column.name)
 		}
 	}
 	return columns
@@ -97,7 +142,46 @@ func excludeFields(fields map[string]any, excluded []string) map[string]any {
 	return filtered
 }
 
+// mergeFields returns the fields of maps merged into one map, later maps
+// taking precedence.
+func mergeFields(maps ...map[string]any) map[string]any {
+	merged := make(map[string]any)
+	for _, m := range maps {
+		for k, v := range m {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// GetFields returns the columns of entity, named by the global NameMapper.
+// A map is returned as is.
 func GetFields(entity any) (map[string]any, error) {
+	return getFields(mapper(), entity)
+}
+
+// GetFields returns the columns of entity, named by the mapper of db as
+// its queries scan them. A map is returned as is.
+func (db *DB) GetFields(entity any) (map[string]any, error) {
+	return getFields(db.Mapper, entity)
+}
+
+// GetFields returns the columns of entity, named by the mapper of the DB
+// of tx. A map is returned as is.
+func (tx *Tx) GetFields(entity any) (map[string]any, error) {
+	return getFields(tx.Mapper, entity)
+}
+
+func getFields(m *reflectx.Mapper, entity any) (map[string]any, error) {
 	switch entity := entity.(type) {
 	case map[string]any:
 		return entity, nil
@@ -117,22 +201,18 @@ func GetFields(entity any) (map[string]any, error) {
 		return nil, errors.New("entity must be a struct")
 	}
 
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
-		value := v.Field(i).Interface()
-
-		columnName := field.Tag.Get("db")
-		if columnName == "" {
-			columnName = xstrings.ToSnakeCase(field.Name)
+	for _, column := range structColumns(m, t) {
+		field, err := v.FieldByIndexErr(column.index)
+		if err != nil {
+			continue
 		}
-
-		fields[columnName] = value
+		fields[column.name] = field.Interface()
 	}
 	return fields, nil
 }
 
 // buildWhereClause generates a WHERE clause from a condition struct or map, using DirtyFields for structs
-func buildWhereClause(condition any) (string, map[string]any, error) {
+func buildWhereClause(m *reflectx.Mapper, condition any) (string, map[string]any, error) {
 	var whereClauses []string
 	params := map[string]any{}
 
@@ -151,7 +231,7 @@ func buildWhereClause(condition any) (string, map[string]any, error) {
 		}
 	default:
 		// Handle struct or struct pointer
-		fields, err := DirtyFields(condition)
+		fields, err := dirtyFields(m, condition)
 		if err != nil {
 			return "", nil, fmt.Errorf("expected map or struct for condition, got %T", condition)
 		}
@@ -163,3 +243,54 @@ func buildWhereClause(condition any) (string, map[string]any, error) {
 	}
 	return strings.Join(whereClauses, " AND "), params, nil
 }
+
+// setField sets the column of a map, or of a pointer to a struct whose
+// fields are named by m, to value. Struct fields are converted from value
+// when their type differs, and fields implementing sql.Scanner scan it.
+func setField(m *reflectx.Mapper, data any, column string, value any) error {
+	switch data := data.(type) {
+	case map[string]any:
+		data[column] = value
+		return nil
+	case *map[string]any:
+		if *data == nil {
+			*data = map[string]any{}
+		}
+		(*data)[column] = value
+		return nil
+	}
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("expected a map or struct pointer, got %T", data)
+	}
+	v = v.Elem()
+	// The mapper also finds fields of embedded, possibly generic, structs.
+	traversal := m.TraversalsByName(v.Type(), []string{column})[0]
+	if len(traversal) == 0 {
+		return fmt.Errorf("%T has no field for column %s", data, column)
+	}
+	field := reflectx.FieldByIndexes(v, traversal)
+	if scanner, ok := reflectx.ScanDest(field).(sql.Scanner); ok {
+		return scanner.Scan(value)
+	}
+	if value == nil {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	val := reflect.ValueOf(value)
+	switch {
+	case val.Type().AssignableTo(field.Type()):
+		field.Set(val)
+	case field.Kind() == reflect.String:
+		field.SetString(fmt.Sprint(value))
+	case val.CanInt() && field.CanInt():
+		field.SetInt(val.Int())
+	case val.CanInt() && field.CanUint():
+		field.SetUint(uint64(val.Int()))
+	case val.Type().ConvertibleTo(field.Type()) && val.Kind() != reflect.String:
+		field.Set(val.Convert(field.Type()))
+	default:
+		return fmt.Errorf("cannot set %s of type %s to %T", column, field.Type(), value)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package squealx
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/oarkflow/squealx/sqlctx"
+	"github.com/oarkflow/squealx/sqltoken"
+)
+
+// Statement is a query on its way to the driver, as seen by QueryRewriters.
+// Query is bound for the driver, with its placeholders rebound and its IN
+// lists expanded.
+type Statement struct {
+	Query   string
+	Args    []any
+	Dialect string
+	// Prepared is set for a statement being prepared, whose arguments are
+	// only known when it is executed: Args is empty and must stay so, and
+	// the rewrite applies to every execution, whatever its context.
+	Prepared bool
+}
+
+// ErrPreparedArgs is returned when preparing a statement which a
+// QueryRewriter gave arguments to, which a prepared statement cannot bind.
+var ErrPreparedArgs = errors.New("squealx: query rewriters cannot bind arguments of prepared statements")
+
+// Tokens returns the tokens of the query, tokenized for its dialect.
+func (s Statement) Tokens() sqltoken.Tokens {
+	switch s.Dialect {
+	case DialectMySQL:
+		return sqltoken.TokenizeMySQL(s.Query)
+	case DialectMSSQL:
+		return sqltoken.Tokenize(s.Query, sqltoken.SQLServerConfig())
+	}
+	return sqltoken.TokenizePostgreSQL(s.Query)
+}
+
+// WithTokens returns s with its query made of tokens, as edited from those
+// returned by Tokens.
+func (s Statement) WithTokens(tokens sqltoken.Tokens) Statement {
+	s.Query = tokens.String()
+	return s
+}
+
+// QueryRewriter rewrites the statements of a DB right before they are sent
+// to the driver, such as to inject optimizer hints or routing comments.
+type QueryRewriter interface {
+	Rewrite(ctx context.Context, stmt Statement) (Statement, error)
+}
+
+// QueryRewriterFunc adapts a function to a QueryRewriter.
+type QueryRewriterFunc func(ctx context.Context, stmt Statement) (Statement, error)
+
+func (f QueryRewriterFunc) Rewrite(ctx context.Context, stmt Statement) (Statement, error) {
+	return f(ctx, stmt)
+}
+
+// UseRewriter registers rewriters applied, in order, to every statement run
+// by this DB and its transactions, and to the statements they prepare once
+// when prepared. They run after the before hooks, which see the statement
+// as written, and an error of a rewriter fails the statement.
+//
+// The chain sees statements as sent to the driver. The steps working on the
+// statement as written run before it and are not rewriters: expanding
+// templates and @ placeholders with SanitizeQuery, looking up named queries
+// of a FileLoader, binding named and IN arguments, and limiting the reads
+// of Get to a single row.
+func (db *DB) UseRewriter(rewriters ...QueryRewriter) {
+	db.hooks.update(func(s *hookSet) {
+		s.rewriters = append(s.rewriters, rewriters...)
+	})
+}
+
+// rewrite passes query and args through the rewriters of db, then through
+// the HintRewriter when ctx carries hints.
+func (db *DB) rewrite(ctx context.Context, query string, args []any) (string, []any, error) {
+	if db == nil {
+		return query, args, nil
+	}
+	stmt, err := db.rewriteStatement(ctx, Statement{Query: query, Args: db.times.bindArgs(args), Dialect: Dialect(db.driverName)})
+	if err != nil {
+		return "", nil, err
+	}
+	return stmt.Query, stmt.Args, nil
+}
+
+// rewritePrepared passes query, being prepared, through the rewriters of
+// db.
+func (db *DB) rewritePrepared(ctx context.Context, query string) (string, error) {
+	if db == nil {
+		return query, nil
+	}
+	stmt, err := db.rewriteStatement(ctx, Statement{Query: query, Dialect: Dialect(db.driverName), Prepared: true})
+	if err != nil {
+		return "", err
+	}
+	if len(stmt.Args) > 0 {
+		return "", ErrPreparedArgs
+	}
+	return stmt.Query, nil
+}
+
+func (db *DB) rewriteStatement(ctx context.Context, stmt Statement) (Statement, error) {
+	rewriters := db.hooks.load().rewriters
+	if len(HintsFromContext(ctx)) > 0 {
+		rewriters = append(slices.Clip(rewriters), hintRewriter)
+	}
+	for _, rewriter := range rewriters {
+		var err error
+		if stmt, err = rewriter.Rewrite(ctx, stmt); err != nil {
+			return Statement{}, err
+		}
+	}
+	return stmt, nil
+}
+
+var commentReplacer = strings.NewReplacer("/*", "", "*/", "")
+
+// CommentRewriter returns a QueryRewriter prefixing statements with the
+// comment returned by comment for their context, such as the shard or
+// tenant a proxy routes them by, when it is not empty. "/*" and "*/" are
+// removed from the comment, which cannot end early or nest.
+func CommentRewriter(comment func(ctx context.Context) string) QueryRewriter {
+	return QueryRewriterFunc(func(ctx context.Context, stmt Statement) (Statement, error) {
+		if text := comment(ctx); text != "" {
+			stmt.Query = "/* " + commentReplacer.Replace(text) + " */ " + stmt.Query
+		}
+		return stmt, nil
+	})
+}
+
+// QueryTag formats the tag key with value as QueryTagsRewriter writes it in
+// its comments, key='value' with both escaped, so that it can be looked for
+// in the text of running statements, as inspect.Guard does.
+func QueryTag(key, value string) string {
+	return url.QueryEscape(key) + "='" + url.QueryEscape(value) + "'"
+}
+
+// QueryTagsRewriter returns a QueryRewriter prefixing statements with a
+// comment listing the tags of their context, set with sqlctx.WithQueryTags,
+// formatted by QueryTag and sorted by key.
+func QueryTagsRewriter() QueryRewriter {
+	return CommentRewriter(func(ctx context.Context) string {
+		tags := sqlctx.QueryTagsFromContext(ctx)
+		if len(tags) == 0 {
+			return ""
+		}
+		formatted := make([]string, 0, len(tags))
+		for _, key := range slices.Sorted(maps.Keys(tags)) {
+			formatted = append(formatted, QueryTag(key, tags[key]))
+		}
+		return strings.Join(formatted, ",")
+	})
+}
@@ -0,0 +1,52 @@
+package squealx
+
+// RowTransformer post-processes a row scanned into a map. table is the
+// primary table of the statement that produced the row, or empty when it
+// cannot be inferred.
+type RowTransformer func(table string, row map[string]any) error
+
+// UseRowTransformer registers transformers applied, in order, to every row
+// scanned into a map by this DB, its transactions, connections and prepared
+// statements (ScannAll into []map[string]any, MapScan, ScanEach and Get into
+// a map).
+func (db *DB) UseRowTransformer(transformers ...RowTransformer) {
+	db.hooks.update(func(s *hookSet) {
+		s.transformers = append(s.transformers, transformers...)
+	})
+}
+
+// transformRow applies the row transformers carried by rows, if any.
+func transformRow(rows any, row map[string]any) error {
+	var table string
+	var transformers []RowTransformer
+	switch r := rows.(type) {
+	case *Rows:
+		table, transformers = r.table, r.transformers
+	case *Row:
+		table, transformers = r.table, r.transformers
+	}
+	for _, transformer := range transformers {
+		if err := transformer(table, row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// statementTable returns the table used as transformer input for query.
+// Inference is skipped when no transformers are registered.
+func (db *DB) statementTable(query string) string {
+	if len(db.rowTransformers()) == 0 {
+		return ""
+	}
+	_, table := ClassifyStatement(query)
+	return table
+}
+
+// rowTransformers returns the row transformers of db, none when db is nil.
+func (db *DB) rowTransformers() []RowTransformer {
+	if db == nil {
+		return nil
+	}
+	return db.hooks.load().transformers
+}
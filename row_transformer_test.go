@@ -0,0 +1,60 @@
+package squealx_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/oarkflow/squealx"
+	_ "modernc.org/sqlite"
+)
+
+func TestRowTransformerOutsideDB(t *testing.T) {
+	db := openTestDB(t,
+		`CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT)`,
+		`INSERT INTO users (email) VALUES ('ann@example.com')`)
+	db.UseRowTransformer(func(table string, row map[string]any) error {
+		if table == "users" {
+			row["email"] = "***"
+		}
+		return nil
+	})
+	const query = `SELECT email FROM users`
+	check := func(name string, row map[string]any) {
+		t.Helper()
+		if row["email"] != "***" {
+			t.Errorf("%s: email = %v, want it masked", name, row["email"])
+		}
+	}
+
+	ctx := context.Background()
+	var row map[string]any
+	err := db.WithTxx(ctx, nil, func(tx *squealx.Tx) error {
+		return tx.GetContext(ctx, &row, query)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("tx", row)
+
+	conn, err := db.Connx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	row = nil
+	if err := conn.GetContext(ctx, &row, query); err != nil {
+		t.Fatal(err)
+	}
+	check("conn", row)
+	conn.Close()
+
+	stmt, err := db.Preparex(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	row = nil
+	if err := stmt.Get(&row); err != nil {
+		t.Fatal(err)
+	}
+	check("stmt", row)
+}
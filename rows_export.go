@@ -0,0 +1,210 @@
+package squealx
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// CSVOptions configures Rows.WriteCSV.
+type CSVOptions struct {
+	// Comma is the field delimiter, ',' by default.
+	Comma rune
+	// NoHeader omits the header record of column names.
+	NoHeader bool
+	// Null is written for NULL values, an empty field by default.
+	Null string
+	// TimeFormat formats time values, time.RFC3339Nano by default.
+	TimeFormat string
+}
+
+// JSONOptions configures Rows.WriteJSON.
+type JSONOptions struct {
+	// Lines writes one object per line (NDJSON) instead of an array.
+	Lines bool
+}
+
+// rowScanner scans the rows of a result set into values reused across rows.
+type rowScanner struct {
+	rows     *Rows
+	columns  []string
+	colTypes []*sql.ColumnType
+	values   []any
+	pointers []any
+	row      map[string]any
+}
+
+func newRowScanner(r *Rows) (*rowScanner, error) {
+	columns, err := r.Columns()
+	if err != nil {
+		return nil, err
+	}
+	colTypes, err := r.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	s := &rowScanner{rows: r, columns: columns, colTypes: colTypes, values: make([]any, len(columns)), pointers: make([]any, len(columns))}
+	for i := range s.values {
+		s.pointers[i] = &s.values[i]
+	}
+	if len(r.transformers) > 0 {
+		s.row = make(map[string]any, len(columns))
+	}
+	return s, nil
+}
+
+// scan scans the current row, converting values as MapScan does. When the
+// rows carry transformers, they are applied and the values read back from
+// the transformed row.
+func (s *rowScanner) scan() error {
+	if err := s.rows.Scan(s.pointers...); err != nil {
+		return err
+	}
+	for i := range s.values {
+		s.values[i] = bytesToAny(s.values[i], s.colTypes[i].DatabaseTypeName(), s.rows.times)
+	}
+	if s.row == nil {
+		return nil
+	}
+	clear(s.row)
+	for i, column := range s.columns {
+		s.row[column] = s.values[i]
+	}
+	if err := transformRow(s.rows, s.row); err != nil {
+		return err
+	}
+	for i, column := range s.columns {
+		s.values[i] = s.row[column]
+	}
+	return nil
+}
+
+// WriteCSV writes the remaining rows to w as CSV, one record per row, and
+// returns the number of rows written. Rows are streamed, so the result set
+// is never held in memory.
+func (r *Rows) WriteCSV(w io.Writer, opts CSVOptions) (int, error) {
+	s, err := newRowScanner(r)
+	if err != nil {
+		return 0, err
+	}
+	if opts.TimeFormat == "" {
+		opts.TimeFormat = time.RFC3339Nano
+	}
+	cw := csv.NewWriter(w)
+	if opts.Comma != 0 {
+		cw.Comma = opts.Comma
+	}
+	if !opts.NoHeader {
+		if err := cw.Write(s.columns); err != nil {
+			return 0, err
+		}
+	}
+	record := make([]string, len(s.columns))
+	n := 0
+	for r.Next() {
+		if err := s.scan(); err != nil {
+			return n, err
+		}
+		for i, v := range s.values {
+			record[i] = csvField(v, opts)
+		}
+		if err := cw.Write(record); err != nil {
+			return n, err
+		}
+		n++
+	}
+	if err := r.Err(); err != nil {
+		return n, err
+	}
+	cw.Flush()
+	return n, cw.Error()
+}
+
+func csvField(v any, opts CSVOptions) string {
+	switch v := v.(type) {
+	case nil:
+		return opts.Null
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(opts.TimeFormat)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []any, map[string]any:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+// WriteJSON writes the remaining rows to w as JSON objects keyed by column,
+// in column order, and returns the number of rows written. The objects form
+// an array, or one line each with opts.Lines. Rows are streamed, so the
+// result set is never held in memory.
+func (r *Rows) WriteJSON(w io.Writer, opts JSONOptions) (int, error) {
+	s, err := newRowScanner(r)
+	if err != nil {
+		return 0, err
+	}
+	keys := make([][]byte, len(s.columns))
+	for i, column := range s.columns {
+		key, err := json.Marshal(column)
+		if err != nil {
+			return 0, err
+		}
+		keys[i] = append(key, ':')
+	}
+	bw := bufio.NewWriter(w)
+	if !opts.Lines {
+		bw.WriteByte('[')
+	}
+	n := 0
+	for r.Next() {
+		if err := s.scan(); err != nil {
+			return n, err
+		}
+		if n > 0 && !opts.Lines {
+			bw.WriteByte(',')
+		}
+		bw.WriteByte('{')
+		for i, v := range s.values {
+			if i > 0 {
+				bw.WriteByte(',')
+			}
+			bw.Write(keys[i])
+			value, err := json.Marshal(v)
+			if err != nil {
+				return n, fmt.Errorf("column %s: %w", s.columns[i], err)
+			}
+			bw.Write(value)
+		}
+		bw.WriteByte('}')
+		if opts.Lines {
+			bw.WriteByte('\n')
+		}
+		n++
+	}
+	if err := r.Err(); err != nil {
+		return n, err
+	}
+	if !opts.Lines {
+		bw.WriteString("]\n")
+	}
+	return n, bw.Flush()
+}
@@ -0,0 +1,62 @@
+package squealx
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// SortSpec is a sort requested by a client, such as the sort of QueryParams.
+type SortSpec = Sort
+
+// ErrColumnNotAllowed is wrapped by the errors of SafeOrderBy and SafeColumns
+// for fields which may not be used.
+var ErrColumnNotAllowed = errors.New("column is not allowed")
+
+var identifierReg = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// SafeOrderBy returns the ORDER BY list, without the ORDER BY keywords, for
+// sorts requested by a client. allowed maps the field names clients may use
+// to their SQL columns; when it is nil, only plain column names, optionally
+// qualified by a table, are accepted. Directions other than ASC and DESC
+// default to ASC.
+func SafeOrderBy(allowed map[string]string, requested []SortSpec) (string, error) {
+	parts := make([]string, 0, len(requested))
+	for _, sort := range requested {
+		column, err := safeColumn(allowed, sort.Field)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, column+" "+sortDirection(sort.Dir))
+	}
+	return strings.Join(parts, ", "), nil
+}
+
+// SafeColumns returns the SQL columns of the fields requested by a client,
+// validated as by SafeOrderBy.
+func SafeColumns(allowed map[string]string, requested []string) ([]string, error) {
+	columns := make([]string, 0, len(requested))
+	for _, field := range requested {
+		column, err := safeColumn(allowed, field)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+func safeColumn(allowed map[string]string, field string) (string, error) {
+	if allowed == nil {
+		if !identifierReg.MatchString(field) {
+			return "", fmt.Errorf("%w: %q", ErrColumnNotAllowed, field)
+		}
+		return field, nil
+	}
+	column, ok := allowed[field]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrColumnNotAllowed, field)
+	}
+	return column, nil
+}
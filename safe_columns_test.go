@@ -0,0 +1,134 @@
+package squealx_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/oarkflow/squealx"
+)
+
+var sortColumns = map[string]string{"name": "users.name", "created": "created_at"}
+
+func TestSafeOrderBy(t *testing.T) {
+	cases := []struct {
+		name    string
+		allowed map[string]string
+		sorts   []squealx.SortSpec
+		want    string
+	}{
+		{"allowed fields", sortColumns, []squealx.SortSpec{{Field: "name", Dir: "desc"}, {Field: "created"}}, "users.name DESC, created_at ASC"},
+		{"direction spoofing", sortColumns, []squealx.SortSpec{{Field: "name", Dir: "DESC; DROP TABLE users"}}, "users.name ASC"},
+		{"direction with nulls", sortColumns, []squealx.SortSpec{{Field: "name", Dir: "DESC NULLS"}}, "users.name ASC"},
+		{"plain columns without allowlist", nil, []squealx.SortSpec{{Field: "Name", Dir: "Desc"}, {Field: "users.id"}}, "Name DESC, users.id ASC"},
+		{"no sort", sortColumns, nil, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := squealx.SafeOrderBy(c.allowed, c.sorts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != c.want {
+				t.Errorf("SafeOrderBy = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestSafeOrderByRejected(t *testing.T) {
+	cases := []struct {
+		name    string
+		allowed map[string]string
+		field   string
+	}{
+		{"unknown field", sortColumns, "email"},
+		{"case of an allowed field", sortColumns, "Name"},
+		{"column instead of field", sortColumns, "users.name"},
+		{"statement", nil, "name; DROP TABLE users"},
+		{"direction in field", nil, "name DESC NULLS"},
+		{"expression", nil, "length(name)"},
+		{"comment", nil, "name--"},
+		{"doubly qualified", nil, "public.users.name"},
+		{"empty", nil, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := squealx.SafeOrderBy(c.allowed, []squealx.SortSpec{{Field: c.field}})
+			if !errors.Is(err, squealx.ErrColumnNotAllowed) {
+				t.Errorf("SafeOrderBy = %q, %v, want ErrColumnNotAllowed", got, err)
+			}
+		})
+	}
+}
+
+func TestSafeColumns(t *testing.T) {
+	got, err := squealx.SafeColumns(sortColumns, []string{"created", "name"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"created_at", "users.name"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SafeColumns = %v, want %v", got, want)
+	}
+	// Without an allowlist, as for the columns of Repository.Select, plain
+	// column names pass through as given.
+	got, err = squealx.SafeColumns(nil, []string{"id", "Email", "users.name"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"id", "Email", "users.name"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SafeColumns = %v, want %v", got, want)
+	}
+	for _, columns := range [][]string{{"id", "password AS id"}, {"*"}, {"count(*)"}, {"id, secret"}} {
+		if got, err := squealx.SafeColumns(nil, columns); !errors.Is(err, squealx.ErrColumnNotAllowed) {
+			t.Errorf("SafeColumns(%q) = %v, %v, want ErrColumnNotAllowed", columns, got, err)
+		}
+	}
+	if got, err := squealx.SafeColumns(sortColumns, []string{"name", "email"}); !errors.Is(err, squealx.ErrColumnNotAllowed) {
+		t.Errorf("SafeColumns with an unknown field = %v, %v, want ErrColumnNotAllowed", got, err)
+	}
+}
+
+func TestRepositorySelectColumns(t *testing.T) {
+	db := openTestDB(t,
+		`CREATE TABLE products (id TEXT PRIMARY KEY, name TEXT, price REAL)`,
+		`INSERT INTO products VALUES ('p1', 'pen', 1.5)`,
+	)
+	repo := squealx.New[Product](db, "products", "id")
+	ctx := context.Background()
+	rows, err := repo.Select(ctx, nil, "name", "price")
+	if err != nil {
+		t.Fatalf("Select: %v", err)
+	}
+	if len(rows) != 1 || rows[0]["name"] != "pen" || len(rows[0]) != 2 {
+		t.Errorf("Select = %v, want the name and price of the product", rows)
+	}
+	if _, err := repo.Select(ctx, nil, "name, (SELECT 1) AS x"); !errors.Is(err, squealx.ErrColumnNotAllowed) {
+		t.Errorf("Select with an expression: %v, want ErrColumnNotAllowed", err)
+	}
+}
+
+func TestRepositoryFieldsWithoutAllowlist(t *testing.T) {
+	db := openTestDB(t,
+		`CREATE TABLE products (id TEXT PRIMARY KEY, name TEXT, price REAL)`,
+		`INSERT INTO products (id, name, price) VALUES ('p1', 'pen', 1.5)`)
+	repo := squealx.New[Product](db, "products", "id")
+	for _, params := range []squealx.QueryParams{
+		{Fields: []string{"id; DROP TABLE products"}},
+		{Except: []string{"name FROM products; --"}},
+	} {
+		ctx := context.WithValue(context.Background(), "query_params", params)
+		if _, err := repo.All(ctx); !errors.Is(err, squealx.ErrColumnNotAllowed) {
+			t.Errorf("All with %+v: %v, want ErrColumnNotAllowed", params, err)
+		}
+	}
+	ctx := context.WithValue(context.Background(), "query_params", squealx.QueryParams{Fields: []string{"id", "products.name"}})
+	got, err := repo.All(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Name != "pen" || got[0].Price != 0 {
+		t.Errorf("All = %+v, want the id and name of the product", got)
+	}
+}
@@ -0,0 +1,73 @@
+package squealx_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/oarkflow/squealx"
+)
+
+// Audit is embedded by benchRow, so its columns are scanned through
+// nested traversals.
+type Audit struct {
+	CreatedBy string `db:"created_by"`
+	UpdatedBy string `db:"updated_by"`
+}
+
+type benchRow struct {
+	Audit
+	ID    int64   `db:"id"`
+	Name  string  `db:"name"`
+	Price float64 `db:"price"`
+}
+
+func openBenchDB(b *testing.B, rows int) *squealx.DB {
+	db := openTestDB(b, `CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT, price REAL, created_by TEXT, updated_by TEXT)`)
+	for i := 0; i < rows; i++ {
+		db.MustExec(`INSERT INTO items (name, price, created_by, updated_by) VALUES (?, ?, ?, ?)`, fmt.Sprint("item", i), float64(i), "alice", "bob")
+	}
+	return db
+}
+
+func BenchmarkSelectStructs(b *testing.B) {
+	db := openBenchDB(b, 100)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var rows []benchRow
+		if err := db.Select(&rows, `SELECT id, name, price, created_by, updated_by FROM items`); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetStruct(b *testing.B) {
+	db := openBenchDB(b, 1)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var row benchRow
+		if err := db.Get(&row, `SELECT id, name, price, created_by, updated_by FROM items WHERE id = ?`, 1); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkStructScan(b *testing.B) {
+	db := openBenchDB(b, 100)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rows, err := db.Queryx(`SELECT id, name, price, created_by, updated_by FROM items`)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for rows.Next() {
+			var row benchRow
+			if err := rows.StructScan(&row); err != nil {
+				b.Fatal(err)
+			}
+		}
+		rows.Close()
+	}
+}
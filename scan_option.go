@@ -1,7 +1,21 @@
 package squealx
 
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// ErrDuplicateColumn is returned by map scans with ErrorOnDuplicateColumns
+// for results with several columns of the same name.
+var ErrDuplicateColumn = errors.New("squealx: duplicate column")
+
 type ScanOptions struct {
 	StringifyRawBytes bool // stringifyRawBytes
+	// SuffixDuplicateColumns and ErrorOnDuplicateColumns handle columns of
+	// the same name in map scans, see their options.
+	SuffixDuplicateColumns  bool
+	ErrorOnDuplicateColumns bool
 }
 
 type ScanOption func(opts *ScanOptions)
@@ -19,3 +33,62 @@ func StringifyRawBytes() ScanOption {
 		opts.StringifyRawBytes = true
 	}
 }
+
+// SuffixDuplicateColumns makes map scans key the repeated columns of a
+// result, as from a join, with a suffix numbering them: name, name_1,
+// name_2. Without it, the last column of a name overwrites the others.
+// Drivers report bare column names, so to key columns by table, alias them
+// in the query, as in `SELECT u.id AS user_id`.
+func SuffixDuplicateColumns() ScanOption {
+	return func(opts *ScanOptions) {
+		opts.SuffixDuplicateColumns = true
+	}
+}
+
+// ErrorOnDuplicateColumns makes map scans fail with ErrDuplicateColumn for
+// results with several columns of the same name.
+func ErrorOnDuplicateColumns() ScanOption {
+	return func(opts *ScanOptions) {
+		opts.ErrorOnDuplicateColumns = true
+	}
+}
+
+// mapKeys returns the map keys of columns under opts.
+func (opts *ScanOptions) mapKeys(columns []string) ([]string, error) {
+	if !opts.SuffixDuplicateColumns && !opts.ErrorOnDuplicateColumns {
+		return columns, nil
+	}
+	if opts.ErrorOnDuplicateColumns {
+		seen := make(map[string]bool, len(columns))
+		for _, column := range columns {
+			if seen[column] {
+				return nil, fmt.Errorf("%w %q", ErrDuplicateColumn, column)
+			}
+			seen[column] = true
+		}
+	}
+	// The first column of each name keeps it, and suffixes skip the names
+	// of other columns.
+	used := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		used[column] = true
+	}
+	keys := make([]string, len(columns))
+	counts := make(map[string]int, len(columns))
+	for i, column := range columns {
+		if counts[column] == 0 {
+			counts[column] = 1
+			keys[i] = column
+			continue
+		}
+		key := column + "_" + strconv.Itoa(counts[column])
+		for used[key] {
+			counts[column]++
+			key = column + "_" + strconv.Itoa(counts[column])
+		}
+		counts[column]++
+		used[key] = true
+		keys[i] = key
+	}
+	return keys, nil
+}
@@ -0,0 +1,44 @@
+package squealx
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// scanTypes maps upper case database type names to the constructors
+// registered with RegisterScanType.
+var scanTypes sync.Map
+
+// RegisterScanType makes MapScan and SliceScan scan the columns of
+// databaseType, the type name reported by the driver such as "NUMERIC",
+// into the Scanner returned by newValue, a pointer, instead of the value of
+// the driver. The result holds the value pointed to, or nil when its Value
+// is nil, so that NUMERIC or INTERVAL columns keep their precision.
+func RegisterScanType(databaseType string, newValue func() sql.Scanner) {
+	scanTypes.Store(strings.ToUpper(databaseType), newValue)
+}
+
+func registeredScanType(columnTypes []*sql.ColumnType, idx int) (func() sql.Scanner, bool) {
+	if idx >= len(columnTypes) {
+		return nil, false
+	}
+	newValue, ok := scanTypes.Load(strings.ToUpper(columnTypes[idx].DatabaseTypeName()))
+	if !ok {
+		return nil, false
+	}
+	return newValue.(func() sql.Scanner), true
+}
+
+// registeredValue returns the value scanned into v, a Scanner returned by a
+// constructor registered with RegisterScanType.
+func registeredValue(v any) any {
+	if valuer, ok := v.(driver.Valuer); ok {
+		if value, err := valuer.Value(); err == nil && value == nil {
+			return nil
+		}
+	}
+	return reflect.Indirect(reflect.ValueOf(v)).Interface()
+}
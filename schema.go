@@ -0,0 +1,245 @@
+package squealx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+)
+
+// ErrSchemaNotSupported is returned for statements whose context carries a
+// schema, set with WithSchema, on a database without per-session schemas.
+var ErrSchemaNotSupported = errors.New("squealx: schema switching is only supported on PostgreSQL and MySQL")
+
+type schemaKey struct{}
+
+// WithSchema returns a context whose statements run against schema, for
+// schema-per-tenant designs. On PostgreSQL schema becomes the search_path,
+// on MySQL the default database.
+//
+// Statements outside a transaction run on a connection taken from the pool
+// and switched for their duration; rows keep the connection until closed.
+// The previous setting, including one made by WithConnInit, is restored
+// before the connection returns to the pool, and the connection is closed
+// instead when it cannot be, such as a MySQL session that had no default
+// database. Transactions begun with the context switch once, with SET
+// LOCAL on PostgreSQL and USE on MySQL, restored once the transaction
+// ends; on MySQL they fail on a session without a default database.
+// Since the context travels with the statement, reads and writes routed by
+// a dbresolver.DBResolver are switched on whichever database serves them.
+func WithSchema(ctx context.Context, schema string) context.Context {
+	return context.WithValue(ctx, schemaKey{}, schema)
+}
+
+// SchemaFromContext returns the schema attached by WithSchema.
+func SchemaFromContext(ctx context.Context) (string, bool) {
+	schema, ok := ctx.Value(schemaKey{}).(string)
+	return schema, ok && schema != ""
+}
+
+// sessionQueryer is implemented by connections and transactions.
+type sessionQueryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) SQLRow
+}
+
+// errNoSchemaReset is returned by switchSchema for a session which cannot
+// return to its previous schema. Outside a transaction the session is
+// switched nonetheless.
+var errNoSchemaReset = errors.New("squealx: the previous schema cannot be restored")
+
+// switchSchema makes schema the default of the session of q and returns the
+// statements restoring the previous one. Within a transaction PostgreSQL
+// uses SET LOCAL, which needs no reset.
+func switchSchema(ctx context.Context, q sessionQueryer, dialect, schema string, inTx bool) ([]string, error) {
+	var reset []string
+	switch dialect {
+	case DialectPostgres:
+		if inTx {
+			_, err := q.ExecContext(ctx, "SET LOCAL search_path TO "+QuoteIdent(dialect, schema))
+			return nil, err
+		}
+		var previous string
+		if err := q.QueryRowContext(ctx, "SHOW search_path").Scan(&previous); err != nil {
+			return nil, err
+		}
+		reset = append(reset, "SET search_path TO "+previous)
+		_, err := q.ExecContext(ctx, "SET search_path TO "+QuoteIdent(dialect, schema))
+		return reset, err
+	case DialectMySQL:
+		var previous sql.NullString
+		if err := q.QueryRowContext(ctx, "SELECT DATABASE()").Scan(&previous); err != nil {
+			return nil, err
+		}
+		// A session without a default database cannot return to having
+		// none, which a transaction cannot make up for by discarding its
+		// connection.
+		if !previous.Valid && inTx {
+			return nil, errNoSchemaReset
+		}
+		if _, err := q.ExecContext(ctx, "USE "+QuoteIdent(dialect, schema)); err != nil {
+			return nil, err
+		}
+		if !previous.Valid {
+			return nil, errNoSchemaReset
+		}
+		return append(reset, "USE "+QuoteIdent(dialect, previous.String)), nil
+	}
+	return nil, ErrSchemaNotSupported
+}
+
+// schemaConn returns a connection of db switched to schema, and the function
+// restoring and releasing it.
+func (db *DB) schemaConn(ctx context.Context, schema string) (SQLConn, func(), error) {
+	conn, err := db.SQLDB.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	reset, err := switchSchema(ctx, conn, Dialect(db.driverName), schema, false)
+	discard := errors.Is(err, errNoSchemaReset)
+	if discard {
+		err = nil
+	}
+	release := func() {
+		for _, stmt := range reset {
+			if _, err := conn.ExecContext(context.Background(), stmt); err != nil {
+				discard = true
+				break
+			}
+		}
+		if discard {
+			discardConn(conn)
+		}
+		_ = conn.Close()
+	}
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+	return conn, release, nil
+}
+
+// discardConn makes conn be closed rather than returned to the pool, so that
+// a session left switched to another schema is not reused.
+func discardConn(conn SQLConn) {
+	if raw, ok := conn.(interface {
+		Raw(func(driverConn any) error) error
+	}); ok {
+		_ = raw.Raw(func(any) error { return driver.ErrBadConn })
+	}
+}
+
+// execContext executes query on db, switched to the schema of ctx if any,
+// once rewritten by the rewriters of db.
+func (db *DB) execContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	query, args, err := db.rewrite(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	schema, ok := SchemaFromContext(ctx)
+	if !ok {
+		return db.SQLDB.ExecContext(ctx, query, args...)
+	}
+	conn, release, err := db.schemaConn(ctx, schema)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return conn.ExecContext(ctx, query, args...)
+}
+
+// queryContext queries db, switched to the schema of ctx if any, once
+// rewritten by the rewriters of db. The connection is released when the
+// rows are closed.
+func (db *DB) queryContext(ctx context.Context, query string, args ...any) (SQLRows, error) {
+	query, args, err := db.rewrite(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	schema, ok := SchemaFromContext(ctx)
+	if !ok {
+		return db.SQLDB.QueryContext(ctx, query, args...)
+	}
+	conn, release, err := db.schemaConn(ctx, schema)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return &schemaRows{SQLRows: rows, release: release}, nil
+}
+
+// schemaRows releases the connection of its rows when closed, or once they
+// have been read to the end or failed, as sql.Rows closes itself then, so
+// that rows left unclosed do not hold a connection switched to the schema.
+type schemaRows struct {
+	SQLRows
+	release func()
+	closed  bool
+	// nextSet is set when the rows were moved to the next result set on
+	// reaching the end of the current one, for NextResultSet to report.
+	nextSet bool
+}
+
+func (r *schemaRows) Next() bool {
+	if r.closed || r.nextSet {
+		return false
+	}
+	if r.SQLRows.Next() {
+		return true
+	}
+	if r.SQLRows.Err() == nil && nextResultSet(r.SQLRows) {
+		r.nextSet = true
+		return false
+	}
+	_ = r.Close()
+	return false
+}
+
+func (r *schemaRows) NextResultSet() bool {
+	if r.nextSet {
+		r.nextSet = false
+		return true
+	}
+	if r.closed {
+		return false
+	}
+	if nextResultSet(r.SQLRows) {
+		return true
+	}
+	_ = r.Close()
+	return false
+}
+
+func (r *schemaRows) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	err := r.SQLRows.Close()
+	r.release()
+	return err
+}
+
+// txSchema switches tx to the schema of ctx, keeping the statements
+// restoring the session for the end of the transaction.
+func (tx *Tx) txSchema(ctx context.Context) error {
+	schema, ok := SchemaFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	reset, err := switchSchema(ctx, tx.SQLTx, Dialect(tx.driverName), schema, true)
+	if errors.Is(err, errNoSchemaReset) {
+		return errors.New("squealx: WithSchema transactions on MySQL need a connection with a default database")
+	}
+	if err != nil {
+		return err
+	}
+	if tx.state != nil {
+		tx.state.reset = append(tx.state.reset, reset...)
+	}
+	return nil
+}
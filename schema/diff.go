@@ -0,0 +1,140 @@
+package schema
+
+import (
+	"context"
+	"slices"
+
+	"github.com/oarkflow/squealx"
+)
+
+// Delta is the difference between the snapshots a and b passed to Compare:
+// what migrates a to b.
+type Delta struct {
+	// Added are the tables of b missing from a, Dropped those of a missing
+	// from b.
+	Added   []Table      `json:"added,omitempty"`
+	Dropped []Table      `json:"dropped,omitempty"`
+	Changed []TableDelta `json:"changed,omitempty"`
+}
+
+// TableDelta is the difference between a table of two snapshots.
+type TableDelta struct {
+	Table              string         `json:"table"`
+	AddedColumns       []Column       `json:"added_columns,omitempty"`
+	DroppedColumns     []Column       `json:"dropped_columns,omitempty"`
+	ChangedColumns     []ColumnChange `json:"changed_columns,omitempty"`
+	AddedIndexes       []Index        `json:"added_indexes,omitempty"`
+	DroppedIndexes     []Index        `json:"dropped_indexes,omitempty"`
+	AddedConstraints   []Constraint   `json:"added_constraints,omitempty"`
+	DroppedConstraints []Constraint   `json:"dropped_constraints,omitempty"`
+}
+
+// ColumnChange is a column whose type, nullability, default or identity
+// differ between two snapshots.
+type ColumnChange struct {
+	From Column `json:"from"`
+	To   Column `json:"to"`
+}
+
+// Empty reports whether the snapshots compared are the same.
+func (d *Delta) Empty() bool {
+	return len(d.Added) == 0 && len(d.Dropped) == 0 && len(d.Changed) == 0
+}
+
+func (d *TableDelta) empty() bool {
+	return len(d.AddedColumns) == 0 && len(d.DroppedColumns) == 0 && len(d.ChangedColumns) == 0 &&
+		len(d.AddedIndexes) == 0 && len(d.DroppedIndexes) == 0 &&
+		len(d.AddedConstraints) == 0 && len(d.DroppedConstraints) == 0
+}
+
+// Diff inspects the databases a and b, of the same dialect, and returns
+// their difference, see Compare.
+func Diff(ctx context.Context, a, b *squealx.DB) (*Delta, error) {
+	snapshotA, err := Inspect(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+	snapshotB, err := Inspect(ctx, b)
+	if err != nil {
+		return nil, err
+	}
+	return Compare(snapshotA, snapshotB), nil
+}
+
+// Compare returns the difference between the snapshots a and b: the
+// tables, columns, indexes and constraints to add, drop or change to turn a
+// into b. Indexes which differ are dropped and added again; constraints
+// are matched by definition and type rather than name.
+func Compare(a, b *Snapshot) *Delta {
+	delta := new(Delta)
+	for _, tableB := range b.Tables {
+		tableA := a.Table(tableB.Name)
+		if tableA == nil {
+			delta.Added = append(delta.Added, tableB)
+			continue
+		}
+		if td := compareTables(tableA, &tableB); !td.empty() {
+			delta.Changed = append(delta.Changed, td)
+		}
+	}
+	for _, tableA := range a.Tables {
+		if b.Table(tableA.Name) == nil {
+			delta.Dropped = append(delta.Dropped, tableA)
+		}
+	}
+	return delta
+}
+
+func compareTables(a, b *Table) TableDelta {
+	td := TableDelta{Table: b.Name}
+	for _, columnB := range b.Columns {
+		columnA := a.Column(columnB.Name)
+		switch {
+		case columnA == nil:
+			td.AddedColumns = append(td.AddedColumns, columnB)
+		case !sameColumn(*columnA, columnB):
+			td.ChangedColumns = append(td.ChangedColumns, ColumnChange{From: *columnA, To: columnB})
+		}
+	}
+	for _, columnA := range a.Columns {
+		if b.Column(columnA.Name) == nil {
+			td.DroppedColumns = append(td.DroppedColumns, columnA)
+		}
+	}
+	for _, indexB := range b.Indexes {
+		i := slices.IndexFunc(a.Indexes, func(index Index) bool { return index.Name == indexB.Name })
+		if i < 0 || !sameIndex(a.Indexes[i], indexB) {
+			td.AddedIndexes = append(td.AddedIndexes, indexB)
+		}
+	}
+	for _, indexA := range a.Indexes {
+		i := slices.IndexFunc(b.Indexes, func(index Index) bool { return index.Name == indexA.Name })
+		if i < 0 || !sameIndex(indexA, b.Indexes[i]) {
+			td.DroppedIndexes = append(td.DroppedIndexes, indexA)
+		}
+	}
+	for _, constraint := range b.Constraints {
+		if !slices.ContainsFunc(a.Constraints, constraint.same) {
+			td.AddedConstraints = append(td.AddedConstraints, constraint)
+		}
+	}
+	for _, constraint := range a.Constraints {
+		if !slices.ContainsFunc(b.Constraints, constraint.same) {
+			td.DroppedConstraints = append(td.DroppedConstraints, constraint)
+		}
+	}
+	return td
+}
+
+func sameColumn(a, b Column) bool {
+	return a.Type == b.Type && a.Nullable == b.Nullable && a.Identity == b.Identity &&
+		(a.Default == nil) == (b.Default == nil) && (a.Default == nil || *a.Default == *b.Default)
+}
+
+func sameIndex(a, b Index) bool {
+	return a.Unique == b.Unique && a.Where == b.Where && slices.Equal(a.Columns, b.Columns)
+}
+
+func (c Constraint) same(other Constraint) bool {
+	return c.Type == other.Type && c.Definition == other.Definition
+}
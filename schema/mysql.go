@@ -0,0 +1,154 @@
+package schema
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/oarkflow/squealx"
+)
+
+const (
+	mysqlColumns = `SELECT c.table_name AS table_name, c.column_name AS column_name, c.column_type AS data_type,
+	c.is_nullable = 'YES' AS nullable, c.column_default AS column_default,
+	c.extra LIKE '%auto_increment%' AS is_identity, c.data_type AS base_type, c.extra AS extra
+	FROM information_schema.columns c
+	JOIN information_schema.tables t ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+	WHERE c.table_schema = DATABASE() AND t.table_type = 'BASE TABLE'
+	ORDER BY c.table_name, c.ordinal_position`
+	mysqlIndexes = `SELECT s.table_name AS table_name, s.index_name AS index_name, s.non_unique = 0 AS is_unique,
+	s.seq_in_index AS position,
+	concat(s.column_name, coalesce(concat('(', s.sub_part, ')'), '')) AS column_name, '' AS predicate
+	FROM information_schema.statistics s
+	WHERE s.table_schema = DATABASE() AND s.index_name <> 'PRIMARY' AND s.column_name IS NOT NULL
+	AND NOT EXISTS (SELECT 1 FROM information_schema.table_constraints c
+		WHERE c.table_schema = s.table_schema AND c.table_name = s.table_name
+		AND c.constraint_name = s.index_name AND c.constraint_type = 'UNIQUE')
+	ORDER BY s.table_name, s.index_name, s.seq_in_index`
+	mysqlKeys = `SELECT k.table_name AS table_name, k.constraint_name AS constraint_name,
+	c.constraint_type AS constraint_type, k.column_name AS column_name,
+	coalesce(k.referenced_table_name, '') AS ref_table, coalesce(k.referenced_column_name, '') AS ref_column,
+	coalesce(r.update_rule, '') AS update_rule, coalesce(r.delete_rule, '') AS delete_rule
+	FROM information_schema.table_constraints c
+	JOIN information_schema.key_column_usage k ON k.constraint_schema = c.constraint_schema
+		AND k.table_name = c.table_name AND k.constraint_name = c.constraint_name
+	LEFT JOIN information_schema.referential_constraints r ON r.constraint_schema = c.constraint_schema
+		AND r.table_name = c.table_name AND r.constraint_name = c.constraint_name
+	WHERE c.table_schema = DATABASE() AND c.constraint_type IN ('PRIMARY KEY', 'UNIQUE', 'FOREIGN KEY')
+	ORDER BY k.table_name, k.constraint_name, k.ordinal_position`
+	// Check constraints are only reported from MySQL 8.0.16.
+	mysqlHasChecks = `SELECT count(*) FROM information_schema.tables
+	WHERE table_schema = 'information_schema' AND table_name = 'CHECK_CONSTRAINTS'`
+	mysqlChecks = `SELECT t.table_name AS table_name, t.constraint_name AS constraint_name,
+	'CHECK' AS constraint_type, concat('CHECK (', c.check_clause, ')') AS definition
+	FROM information_schema.table_constraints t
+	JOIN information_schema.check_constraints c ON c.constraint_schema = t.constraint_schema
+		AND c.constraint_name = t.constraint_name
+	WHERE t.table_schema = DATABASE() AND t.constraint_type = 'CHECK'
+	ORDER BY t.table_name, t.constraint_name`
+)
+
+type mysqlColumnRow struct {
+	columnRow
+	BaseType string `db:"base_type"`
+	Extra    string `db:"extra"`
+}
+
+type mysqlKeyRow struct {
+	Table      string `db:"table_name"`
+	Name       string `db:"constraint_name"`
+	Type       string `db:"constraint_type"`
+	Column     string `db:"column_name"`
+	RefTable   string `db:"ref_table"`
+	RefColumn  string `db:"ref_column"`
+	UpdateRule string `db:"update_rule"`
+	DeleteRule string `db:"delete_rule"`
+}
+
+func inspectMySQL(ctx context.Context, db *squealx.DB) ([]Table, error) {
+	var mysqlColumnRows []mysqlColumnRow
+	if err := db.SelectContext(ctx, &mysqlColumnRows, mysqlColumns); err != nil {
+		return nil, err
+	}
+	columns := make([]columnRow, len(mysqlColumnRows))
+	for i, row := range mysqlColumnRows {
+		row.Default = mysqlDefault(row)
+		columns[i] = row.columnRow
+	}
+	var indexes []indexRow
+	if err := db.SelectContext(ctx, &indexes, mysqlIndexes); err != nil {
+		return nil, err
+	}
+	var keys []mysqlKeyRow
+	if err := db.SelectContext(ctx, &keys, mysqlKeys); err != nil {
+		return nil, err
+	}
+	constraints := mysqlConstraints(keys)
+	var hasChecks int
+	if err := db.GetContext(ctx, &hasChecks, mysqlHasChecks); err != nil {
+		return nil, err
+	}
+	if hasChecks > 0 {
+		var checks []constraintRow
+		if err := db.SelectContext(ctx, &checks, mysqlChecks); err != nil {
+			return nil, err
+		}
+		constraints = append(constraints, checks...)
+	}
+	var tables []Table
+	table := tableIndex(&tables)
+	addColumns(table, columns)
+	addIndexes(table, indexes)
+	addConstraints(table, constraints)
+	return tables, nil
+}
+
+var mysqlNumericTypes = map[string]bool{
+	"tinyint": true, "smallint": true, "mediumint": true, "int": true, "integer": true, "bigint": true,
+	"decimal": true, "numeric": true, "float": true, "double": true, "bit": true, "year": true,
+}
+
+// mysqlDefault returns the default of row as an SQL expression: MySQL
+// reports literal defaults unquoted, and expression defaults without the
+// parentheses they must be declared in.
+func mysqlDefault(row mysqlColumnRow) sql.NullString {
+	if !row.Default.Valid {
+		return row.Default
+	}
+	value := row.Default.String
+	switch upper := strings.ToUpper(value); {
+	case strings.HasPrefix(upper, "CURRENT_TIMESTAMP"), upper == "NULL":
+	case strings.Contains(row.Extra, "DEFAULT_GENERATED"):
+		value = "(" + value + ")"
+	case !mysqlNumericTypes[strings.ToLower(row.BaseType)]:
+		value = quoteLiteral(value)
+	}
+	return sql.NullString{String: value, Valid: true}
+}
+
+// mysqlConstraints returns the definitions of the key constraints of rows,
+// a row per column ordered by table, constraint and position.
+func mysqlConstraints(rows []mysqlKeyRow) []constraintRow {
+	var constraints []constraintRow
+	for start := 0; start < len(rows); {
+		end := start + 1
+		for end < len(rows) && rows[end].Table == rows[start].Table && rows[end].Name == rows[start].Name {
+			end++
+		}
+		first := rows[start]
+		var columns, refColumns []string
+		for _, row := range rows[start:end] {
+			columns = append(columns, squealx.QuoteIdent(squealx.DialectMySQL, row.Column))
+			refColumns = append(refColumns, squealx.QuoteIdent(squealx.DialectMySQL, row.RefColumn))
+		}
+		definition := fmt.Sprintf("%s (%s)", first.Type, strings.Join(columns, ", "))
+		if first.Type == ForeignKey {
+			definition += fmt.Sprintf(" REFERENCES %s (%s) ON UPDATE %s ON DELETE %s",
+				squealx.QuoteIdent(squealx.DialectMySQL, first.RefTable), strings.Join(refColumns, ", "), first.UpdateRule, first.DeleteRule)
+		}
+		constraints = append(constraints, constraintRow{Table: first.Table, Name: first.Name, Type: first.Type, Definition: definition})
+		start = end
+	}
+	return constraints
+}
@@ -0,0 +1,96 @@
+package schema
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/oarkflow/squealx"
+)
+
+const (
+	postgresColumns = `SELECT c.relname AS table_name, a.attname AS column_name,
+	format_type(a.atttypid, a.atttypmod) AS data_type, NOT a.attnotnull AS nullable,
+	pg_get_expr(d.adbin, d.adrelid) AS column_default, a.attidentity <> '' AS is_identity
+	FROM pg_class c
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+	JOIN pg_attribute a ON a.attrelid = c.oid AND a.attnum > 0 AND NOT a.attisdropped
+	LEFT JOIN pg_attrdef d ON d.adrelid = c.oid AND d.adnum = a.attnum
+	WHERE n.nspname = current_schema() AND c.relkind IN ('r', 'p') AND NOT c.relispartition
+	ORDER BY c.relname, a.attnum`
+	postgresIndexes = `SELECT t.relname AS table_name, i.relname AS index_name, ix.indisunique AS is_unique,
+	k.n AS position, pg_get_indexdef(ix.indexrelid, k.n, true) AS column_name,
+	coalesce(pg_get_expr(ix.indpred, ix.indrelid, true), '') AS predicate
+	FROM pg_index ix
+	JOIN pg_class i ON i.oid = ix.indexrelid
+	JOIN pg_class t ON t.oid = ix.indrelid
+	JOIN pg_namespace n ON n.oid = t.relnamespace
+	CROSS JOIN LATERAL generate_series(1, ix.indnatts::int) AS k(n)
+	WHERE n.nspname = current_schema() AND t.relkind IN ('r', 'p') AND NOT t.relispartition
+	AND NOT EXISTS (SELECT 1 FROM pg_constraint c WHERE c.conindid = ix.indexrelid AND c.contype IN ('p', 'u', 'x'))
+	ORDER BY t.relname, i.relname, k.n`
+	postgresConstraints = `SELECT t.relname AS table_name, c.conname AS constraint_name,
+	CASE c.contype WHEN 'p' THEN 'PRIMARY KEY' WHEN 'u' THEN 'UNIQUE' WHEN 'f' THEN 'FOREIGN KEY' ELSE 'CHECK' END AS constraint_type,
+	pg_get_constraintdef(c.oid, true) AS definition
+	FROM pg_constraint c
+	JOIN pg_class t ON t.oid = c.conrelid
+	JOIN pg_namespace n ON n.oid = t.relnamespace
+	WHERE n.nspname = current_schema() AND c.contype IN ('p', 'u', 'f', 'c') AND NOT t.relispartition
+	ORDER BY t.relname, c.conname`
+)
+
+// columnRow is a column, as the inspection queries return them.
+type columnRow struct {
+	Table    string         `db:"table_name"`
+	Column   string         `db:"column_name"`
+	Type     string         `db:"data_type"`
+	Nullable bool           `db:"nullable"`
+	Default  sql.NullString `db:"column_default"`
+	Identity bool           `db:"is_identity"`
+}
+
+// constraintRow is a constraint, as the inspection queries return them.
+type constraintRow struct {
+	Table      string `db:"table_name"`
+	Name       string `db:"constraint_name"`
+	Type       string `db:"constraint_type"`
+	Definition string `db:"definition"`
+}
+
+func inspectPostgres(ctx context.Context, db *squealx.DB) ([]Table, error) {
+	var columns []columnRow
+	if err := db.SelectContext(ctx, &columns, postgresColumns); err != nil {
+		return nil, err
+	}
+	var indexes []indexRow
+	if err := db.SelectContext(ctx, &indexes, postgresIndexes); err != nil {
+		return nil, err
+	}
+	var constraints []constraintRow
+	if err := db.SelectContext(ctx, &constraints, postgresConstraints); err != nil {
+		return nil, err
+	}
+	var tables []Table
+	table := tableIndex(&tables)
+	addColumns(table, columns)
+	addIndexes(table, indexes)
+	addConstraints(table, constraints)
+	return tables, nil
+}
+
+func addColumns(table func(string) *Table, rows []columnRow) {
+	for _, row := range rows {
+		t := table(row.Table)
+		column := Column{Name: row.Column, Type: row.Type, Nullable: row.Nullable, Identity: row.Identity}
+		if row.Default.Valid {
+			column.Default = &row.Default.String
+		}
+		t.Columns = append(t.Columns, column)
+	}
+}
+
+func addConstraints(table func(string) *Table, rows []constraintRow) {
+	for _, row := range rows {
+		t := table(row.Table)
+		t.Constraints = append(t.Constraints, Constraint{Name: row.Name, Type: row.Type, Definition: row.Definition})
+	}
+}
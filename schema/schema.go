@@ -0,0 +1,172 @@
+// Package schema introspects the structure of databases into Snapshots of
+// their tables, columns, indexes and constraints, and compares them: Diff
+// reports how two databases differ and the SQL migrating one to the other.
+//
+// Snapshots marshal to JSON, so that the schema expected by an application
+// can be committed and compared to that of a live database in CI, to detect
+// drift:
+//
+//	var expected schema.Snapshot
+//	_ = json.Unmarshal(committed, &expected)
+//	actual, err := schema.Inspect(ctx, db)
+//	...
+//	if delta := schema.Compare(actual, &expected); !delta.Empty() {
+//		statements, _ := delta.SQL(actual.Dialect)
+//		log.Fatalf("schema drift:\n%s", strings.Join(statements, ";\n"))
+//	}
+//
+// PostgreSQL (the current schema), MySQL (the current database) and SQLite
+// are supported.
+package schema
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/oarkflow/squealx"
+)
+
+// Snapshot is the structure of a database, its tables ordered by name.
+type Snapshot struct {
+	Dialect string  `json:"dialect"`
+	Tables  []Table `json:"tables"`
+}
+
+// Table is a table of a Snapshot. Columns are in table order, indexes and
+// constraints ordered by name.
+type Table struct {
+	Name        string       `json:"name"`
+	Columns     []Column     `json:"columns"`
+	Indexes     []Index      `json:"indexes,omitempty"`
+	Constraints []Constraint `json:"constraints,omitempty"`
+}
+
+// Column is a column of a Table.
+type Column struct {
+	Name string `json:"name"`
+	// Type is the type of the column as the database formats it, such as
+	// "character varying(64)" on PostgreSQL or "varchar(64)" on MySQL.
+	Type     string `json:"type"`
+	Nullable bool   `json:"nullable"`
+	// Default is the SQL expression of the default value, nil for none.
+	Default *string `json:"default,omitempty"`
+	// Identity is set for identity and AUTO_INCREMENT columns.
+	Identity bool `json:"identity,omitempty"`
+}
+
+// Index is an index of a Table, other than those backing its primary key
+// and unique constraints.
+type Index struct {
+	Name string `json:"name"`
+	// Columns are the key columns or expressions of the index.
+	Columns []string `json:"columns"`
+	Unique  bool     `json:"unique,omitempty"`
+	// Where is the predicate of partial indexes.
+	Where string `json:"where,omitempty"`
+}
+
+// Constraint types.
+const (
+	PrimaryKey = "PRIMARY KEY"
+	Unique     = "UNIQUE"
+	ForeignKey = "FOREIGN KEY"
+	Check      = "CHECK"
+)
+
+// Constraint is a constraint of a Table. Constraints are compared by
+// definition, as their names may be generated.
+type Constraint struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Definition is the constraint as in ADD CONSTRAINT, such as
+	// "FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE".
+	Definition string `json:"definition"`
+}
+
+// Table returns the table of s named name, nil if none.
+func (s *Snapshot) Table(name string) *Table {
+	i := slices.IndexFunc(s.Tables, func(t Table) bool { return t.Name == name })
+	if i < 0 {
+		return nil
+	}
+	return &s.Tables[i]
+}
+
+// Column returns the column of t named name, nil if none.
+func (t *Table) Column(name string) *Column {
+	i := slices.IndexFunc(t.Columns, func(c Column) bool { return c.Name == name })
+	if i < 0 {
+		return nil
+	}
+	return &t.Columns[i]
+}
+
+// Inspect returns the snapshot of the structure of db.
+func Inspect(ctx context.Context, db *squealx.DB) (*Snapshot, error) {
+	var tables []Table
+	var err error
+	dialect := squealx.Dialect(db.DriverName())
+	switch dialect {
+	case squealx.DialectPostgres:
+		tables, err = inspectPostgres(ctx, db)
+	case squealx.DialectMySQL:
+		tables, err = inspectMySQL(ctx, db)
+	case squealx.DialectSQLite:
+		tables, err = inspectSQLite(ctx, db)
+	default:
+		return nil, fmt.Errorf("schema: inspection is not supported on %s", db.DriverName())
+	}
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(tables, func(a, b Table) int { return strings.Compare(a.Name, b.Name) })
+	for i := range tables {
+		slices.SortFunc(tables[i].Indexes, func(a, b Index) int { return strings.Compare(a.Name, b.Name) })
+		slices.SortFunc(tables[i].Constraints, func(a, b Constraint) int { return strings.Compare(a.Name, b.Name) })
+	}
+	return &Snapshot{Dialect: dialect, Tables: tables}, nil
+}
+
+// tableIndex returns the function finding, or adding, the table of a name
+// in tables.
+func tableIndex(tables *[]Table) func(name string) *Table {
+	byName := make(map[string]int)
+	for i, t := range *tables {
+		byName[t.Name] = i
+	}
+	return func(name string) *Table {
+		i, ok := byName[name]
+		if !ok {
+			i = len(*tables)
+			byName[name] = i
+			*tables = append(*tables, Table{Name: name})
+		}
+		return &(*tables)[i]
+	}
+}
+
+// indexRow is a key column of an index, as the inspection queries return
+// them.
+type indexRow struct {
+	Table    string `db:"table_name"`
+	Index    string `db:"index_name"`
+	Unique   bool   `db:"is_unique"`
+	Position int    `db:"position"`
+	Column   string `db:"column_name"`
+	Where    string `db:"predicate"`
+}
+
+// addIndexes adds the indexes of rows, ordered by table, index and
+// position, to their tables.
+func addIndexes(table func(string) *Table, rows []indexRow) {
+	for _, row := range rows {
+		t := table(row.Table)
+		if n := len(t.Indexes); n == 0 || t.Indexes[n-1].Name != row.Index {
+			t.Indexes = append(t.Indexes, Index{Name: row.Index, Unique: row.Unique, Where: row.Where})
+		}
+		index := &t.Indexes[len(t.Indexes)-1]
+		index.Columns = append(index.Columns, row.Column)
+	}
+}
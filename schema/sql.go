@@ -0,0 +1,215 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/oarkflow/squealx"
+)
+
+// ErrUnsupportedChange is matched by the error of Delta.SQL for changes the
+// dialect cannot make in place, such as altering a column on SQLite, which
+// requires rebuilding the table.
+var ErrUnsupportedChange = errors.New("schema: change not supported")
+
+// SQL returns the statements migrating the database of the first snapshot
+// compared to the second, in dialect, that of Snapshot.Dialect: constraints
+// and indexes are dropped first, tables created, columns added, changed and
+// dropped, indexes and constraints added, and tables dropped last. Foreign
+// keys of created tables are added once all are created.
+//
+// Changes the dialect cannot make are left out and reported by the error,
+// matching ErrUnsupportedChange; the statements are still returned.
+func (d *Delta) SQL(dialect string) ([]string, error) {
+	g := &generator{dialect: dialect}
+	for _, td := range d.Changed {
+		for _, c := range td.DroppedConstraints {
+			g.dropConstraint(td.Table, c)
+		}
+		for _, index := range td.DroppedIndexes {
+			g.dropIndex(td.Table, index)
+		}
+	}
+	var foreignKeys []func()
+	for _, t := range d.Added {
+		foreignKeys = append(foreignKeys, g.createTable(t))
+	}
+	for _, add := range foreignKeys {
+		add()
+	}
+	for _, td := range d.Changed {
+		table := g.quote(td.Table)
+		for _, column := range td.AddedColumns {
+			g.add("ALTER TABLE %s ADD COLUMN %s", table, g.column(column))
+		}
+		for _, change := range td.ChangedColumns {
+			g.alterColumn(td.Table, change)
+		}
+		for _, column := range td.DroppedColumns {
+			g.add("ALTER TABLE %s DROP COLUMN %s", table, g.quote(column.Name))
+		}
+		for _, index := range td.AddedIndexes {
+			g.createIndex(td.Table, index)
+		}
+		for _, c := range td.AddedConstraints {
+			g.addConstraint(td.Table, c)
+		}
+	}
+	for _, t := range d.Dropped {
+		g.add("DROP TABLE %s", g.quote(t.Name))
+	}
+	return g.statements, errors.Join(g.errs...)
+}
+
+type generator struct {
+	dialect    string
+	statements []string
+	errs       []error
+}
+
+func (g *generator) add(format string, args ...any) {
+	g.statements = append(g.statements, fmt.Sprintf(format, args...))
+}
+
+func (g *generator) unsupported(format string, args ...any) {
+	g.errs = append(g.errs, fmt.Errorf("%w on %s: %s", ErrUnsupportedChange, g.dialect, fmt.Sprintf(format, args...)))
+}
+
+func (g *generator) quote(name string) string {
+	return squealx.QuoteIdent(g.dialect, name)
+}
+
+// column returns the definition of c in CREATE TABLE and ADD COLUMN.
+func (g *generator) column(c Column) string {
+	var b strings.Builder
+	b.WriteString(g.quote(c.Name) + " " + c.Type)
+	if c.Identity {
+		switch g.dialect {
+		case squealx.DialectPostgres:
+			b.WriteString(" GENERATED BY DEFAULT AS IDENTITY")
+		case squealx.DialectMySQL:
+			b.WriteString(" AUTO_INCREMENT")
+		}
+	}
+	if !c.Nullable {
+		b.WriteString(" NOT NULL")
+	}
+	if c.Default != nil {
+		b.WriteString(" DEFAULT " + *c.Default)
+	}
+	return b.String()
+}
+
+// createTable creates t with its columns, primary key, unique and check
+// constraints, and indexes, and returns the function adding its foreign
+// keys, which SQLite declares in CREATE TABLE.
+func (g *generator) createTable(t Table) func() {
+	var lines []string
+	for _, column := range t.Columns {
+		lines = append(lines, g.column(column))
+	}
+	var foreignKeys []Constraint
+	for _, c := range t.Constraints {
+		if c.Type == ForeignKey && g.dialect != squealx.DialectSQLite {
+			foreignKeys = append(foreignKeys, c)
+			continue
+		}
+		lines = append(lines, "CONSTRAINT "+g.quote(c.Name)+" "+c.Definition)
+	}
+	g.add("CREATE TABLE %s (\n\t%s\n)", g.quote(t.Name), strings.Join(lines, ",\n\t"))
+	for _, index := range t.Indexes {
+		g.createIndex(t.Name, index)
+	}
+	return func() {
+		for _, c := range foreignKeys {
+			g.addConstraint(t.Name, c)
+		}
+	}
+}
+
+func (g *generator) alterColumn(table string, change ColumnChange) {
+	from, to := change.From, change.To
+	switch g.dialect {
+	case squealx.DialectMySQL:
+		g.add("ALTER TABLE %s MODIFY COLUMN %s", g.quote(table), g.column(to))
+	case squealx.DialectPostgres:
+		prefix := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s ", g.quote(table), g.quote(to.Name))
+		if from.Type != to.Type {
+			g.statements = append(g.statements, prefix+"TYPE "+to.Type)
+		}
+		if from.Nullable != to.Nullable {
+			if to.Nullable {
+				g.statements = append(g.statements, prefix+"DROP NOT NULL")
+			} else {
+				g.statements = append(g.statements, prefix+"SET NOT NULL")
+			}
+		}
+		if from.Identity && !to.Identity {
+			g.statements = append(g.statements, prefix+"DROP IDENTITY")
+		}
+		if to.Default == nil && from.Default != nil {
+			g.statements = append(g.statements, prefix+"DROP DEFAULT")
+		} else if to.Default != nil && (from.Default == nil || *from.Default != *to.Default) {
+			g.statements = append(g.statements, prefix+"SET DEFAULT "+*to.Default)
+		}
+		if to.Identity && !from.Identity {
+			g.statements = append(g.statements, prefix+"ADD GENERATED BY DEFAULT AS IDENTITY")
+		}
+	default:
+		g.unsupported("alter column %s.%s", table, to.Name)
+	}
+}
+
+func (g *generator) createIndex(table string, index Index) {
+	unique := ""
+	if index.Unique {
+		unique = "UNIQUE "
+	}
+	statement := fmt.Sprintf("CREATE %sINDEX %s ON %s (%s)", unique, g.quote(index.Name), g.quote(table), strings.Join(index.Columns, ", "))
+	if index.Where != "" {
+		statement += " WHERE " + index.Where
+	}
+	g.statements = append(g.statements, statement)
+}
+
+func (g *generator) dropIndex(table string, index Index) {
+	if g.dialect == squealx.DialectMySQL {
+		g.add("DROP INDEX %s ON %s", g.quote(index.Name), g.quote(table))
+		return
+	}
+	g.add("DROP INDEX %s", g.quote(index.Name))
+}
+
+func (g *generator) addConstraint(table string, c Constraint) {
+	if g.dialect == squealx.DialectSQLite {
+		g.unsupported("add constraint %s to %s", c.Definition, table)
+		return
+	}
+	g.add("ALTER TABLE %s ADD CONSTRAINT %s %s", g.quote(table), g.quote(c.Name), c.Definition)
+}
+
+func (g *generator) dropConstraint(table string, c Constraint) {
+	switch g.dialect {
+	case squealx.DialectPostgres:
+		g.add("ALTER TABLE %s DROP CONSTRAINT %s", g.quote(table), g.quote(c.Name))
+	case squealx.DialectMySQL:
+		switch c.Type {
+		case PrimaryKey:
+			g.add("ALTER TABLE %s DROP PRIMARY KEY", g.quote(table))
+		case ForeignKey:
+			g.add("ALTER TABLE %s DROP FOREIGN KEY %s", g.quote(table), g.quote(c.Name))
+		case Unique:
+			g.add("ALTER TABLE %s DROP INDEX %s", g.quote(table), g.quote(c.Name))
+		default:
+			g.add("ALTER TABLE %s DROP CHECK %s", g.quote(table), g.quote(c.Name))
+		}
+	default:
+		g.unsupported("drop constraint %s of %s", c.Definition, table)
+	}
+}
+
+// quoteLiteral quotes s as an SQL string literal.
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
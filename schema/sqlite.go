@@ -0,0 +1,160 @@
+package schema
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/oarkflow/squealx"
+)
+
+type sqliteColumn struct {
+	Name    string         `db:"name"`
+	Type    string         `db:"type"`
+	NotNull bool           `db:"notnull"`
+	Default sql.NullString `db:"dflt_value"`
+	PK      int            `db:"pk"`
+}
+
+type sqliteIndex struct {
+	Name   string `db:"name"`
+	Unique bool   `db:"unique"`
+	Origin string `db:"origin"`
+}
+
+type sqliteForeignKey struct {
+	ID       int    `db:"id"`
+	Table    string `db:"table"`
+	From     string `db:"from"`
+	To       string `db:"to"`
+	OnUpdate string `db:"on_update"`
+	OnDelete string `db:"on_delete"`
+}
+
+var sqliteWhereRE = regexp.MustCompile(`(?is)\)\s*WHERE\s+(.+?)\s*;?\s*$`)
+
+// inspectSQLite inspects the main database. SQLite does not report check
+// constraints, which are therefore left out.
+func inspectSQLite(ctx context.Context, db *squealx.DB) ([]Table, error) {
+	var names []string
+	err := db.SelectContext(ctx, &names, `SELECT name FROM sqlite_master
+	WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	tables := make([]Table, 0, len(names))
+	for _, name := range names {
+		table, err := inspectSQLiteTable(ctx, db, name)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
+
+func inspectSQLiteTable(ctx context.Context, db *squealx.DB, name string) (Table, error) {
+	table := Table{Name: name}
+	var columns []sqliteColumn
+	err := db.SelectContext(ctx, &columns, `SELECT name, type, "notnull", dflt_value, pk FROM pragma_table_info(?) ORDER BY cid`, name)
+	if err != nil {
+		return table, err
+	}
+	var primaryKey []string
+	for pk := 1; ; pk++ {
+		found := false
+		for _, c := range columns {
+			if c.PK == pk {
+				primaryKey = append(primaryKey, squealx.QuoteIdent(squealx.DialectSQLite, c.Name))
+				found = true
+			}
+		}
+		if !found {
+			break
+		}
+	}
+	for _, c := range columns {
+		column := Column{Name: c.Name, Type: c.Type, Nullable: !c.NotNull}
+		if c.Default.Valid {
+			column.Default = &c.Default.String
+		}
+		table.Columns = append(table.Columns, column)
+	}
+	if len(primaryKey) > 0 {
+		table.Constraints = append(table.Constraints, Constraint{
+			Name:       name + "_pkey",
+			Type:       PrimaryKey,
+			Definition: "PRIMARY KEY (" + strings.Join(primaryKey, ", ") + ")",
+		})
+	}
+
+	var indexes []sqliteIndex
+	if err := db.SelectContext(ctx, &indexes, `SELECT name, "unique", origin FROM pragma_index_list(?)`, name); err != nil {
+		return table, err
+	}
+	for _, index := range indexes {
+		if index.Origin == "pk" {
+			continue
+		}
+		var indexColumns []sql.NullString
+		if err := db.SelectContext(ctx, &indexColumns, `SELECT name FROM pragma_index_info(?) ORDER BY seqno`, index.Name); err != nil {
+			return table, err
+		}
+		keys := make([]string, len(indexColumns))
+		for i, column := range indexColumns {
+			// Expressions have no name.
+			keys[i] = "<expression>"
+			if column.Valid {
+				keys[i] = squealx.QuoteIdent(squealx.DialectSQLite, column.String)
+			}
+		}
+		if index.Origin == "u" {
+			table.Constraints = append(table.Constraints, Constraint{
+				Name:       index.Name,
+				Type:       Unique,
+				Definition: "UNIQUE (" + strings.Join(keys, ", ") + ")",
+			})
+			continue
+		}
+		var definition string
+		err := db.GetContext(ctx, &definition, `SELECT coalesce(sql, '') FROM sqlite_master WHERE type = 'index' AND name = ?`, index.Name)
+		if err != nil {
+			return table, err
+		}
+		i := Index{Name: index.Name, Columns: keys, Unique: index.Unique}
+		if m := sqliteWhereRE.FindStringSubmatch(definition); m != nil {
+			i.Where = m[1]
+		}
+		table.Indexes = append(table.Indexes, i)
+	}
+
+	var foreignKeys []sqliteForeignKey
+	err = db.SelectContext(ctx, &foreignKeys, `SELECT id, "table", "from", "to", on_update, on_delete
+	FROM pragma_foreign_key_list(?) ORDER BY id, seq`, name)
+	if err != nil {
+		return table, err
+	}
+	for start := 0; start < len(foreignKeys); {
+		end := start + 1
+		for end < len(foreignKeys) && foreignKeys[end].ID == foreignKeys[start].ID {
+			end++
+		}
+		first := foreignKeys[start]
+		var from, to []string
+		for _, fk := range foreignKeys[start:end] {
+			from = append(from, squealx.QuoteIdent(squealx.DialectSQLite, fk.From))
+			to = append(to, squealx.QuoteIdent(squealx.DialectSQLite, fk.To))
+		}
+		table.Constraints = append(table.Constraints, Constraint{
+			Name: fmt.Sprintf("%s_fkey%d", name, first.ID),
+			Type: ForeignKey,
+			Definition: fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s (%s) ON UPDATE %s ON DELETE %s",
+				strings.Join(from, ", "), squealx.QuoteIdent(squealx.DialectSQLite, first.Table), strings.Join(to, ", "),
+				first.OnUpdate, first.OnDelete),
+		})
+		start = end
+	}
+	return table, nil
+}
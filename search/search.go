@@ -0,0 +1,142 @@
+// Package search builds full-text search predicates over relational columns
+// in the syntax of each database: to_tsvector @@ websearch_to_tsquery on
+// PostgreSQL, MATCH ... AGAINST on MySQL and FTS5 MATCH on SQLite.
+//
+// The expressions take their arguments through an orm condition, to build
+// queries with the orm package, or as a named parameter, to pass to the
+// named queries of squealx and to Repository.PaginateRaw:
+//
+//	s := search.For(db, "title", "body")
+//	sb := orm.Select("id", "title").From("posts")
+//	sb.Where(s.Match(&sb.Cond, q)).OrderBy(s.Rank(&sb.Cond, q)).Desc()
+package search
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/oarkflow/squealx"
+	"github.com/oarkflow/squealx/orm"
+)
+
+// Search describes the text searched and the database searching it.
+type Search struct {
+	// Dialect is the dialect of the database, see squealx.Dialect.
+	Dialect string
+	// Columns are the text columns searched. On MySQL they must be the
+	// columns of a FULLTEXT index, in its order.
+	Columns []string
+	// Table is the FTS5 virtual table searched on SQLite, where the
+	// columns are those of the table.
+	Table string
+	// Language is the text search configuration of PostgreSQL, "english"
+	// by default. Indexes on the tsvector must use the same configuration.
+	Language string
+}
+
+// For returns the Search of columns on db.
+func For(db *squealx.DB, columns ...string) Search {
+	return Search{Dialect: squealx.Dialect(db.DriverName()), Columns: columns}
+}
+
+// Match returns the condition of rows matching query, a web search style
+// query such as `"exact phrase" -excluded` on PostgreSQL, an FTS5 query on
+// SQLite and a natural language query on MySQL.
+func (s Search) Match(cond *orm.Cond, query string) string {
+	return s.match(cond.Var(query))
+}
+
+// Rank returns the relevance of rows to query, higher being more relevant
+// on PostgreSQL and MySQL. FTS5 ranks with bm25, for which lower is more
+// relevant; order by it ascending on SQLite.
+func (s Search) Rank(cond *orm.Cond, query string) string {
+	return s.rank(cond.Var(query))
+}
+
+// Highlight returns column with the terms matching query enclosed in start
+// and stop, such as "<b>" and "</b>". MySQL has no highlighting, and
+// returns column as it is.
+func (s Search) Highlight(cond *orm.Cond, column, query, start, stop string) string {
+	return s.highlight(cond.Var(query), column, start, stop)
+}
+
+// Where returns Match as a condition of a named query, binding query to the
+// parameter param.
+func (s Search) Where(param, query string) (string, map[string]any) {
+	return s.match(":" + param), map[string]any{param: query}
+}
+
+// RankExpr returns Rank for a named query, binding query to param.
+func (s Search) RankExpr(param string) string {
+	return s.rank(":" + param)
+}
+
+// HighlightExpr returns Highlight for a named query, binding query to param.
+func (s Search) HighlightExpr(param, column, start, stop string) string {
+	return s.highlight(":"+param, column, start, stop)
+}
+
+func (s Search) match(arg string) string {
+	switch s.Dialect {
+	case squealx.DialectMySQL:
+		return s.mysqlMatch(arg)
+	case squealx.DialectSQLite:
+		return s.Table + " MATCH " + arg
+	}
+	return s.document() + " @@ " + s.tsquery(arg)
+}
+
+func (s Search) rank(arg string) string {
+	switch s.Dialect {
+	case squealx.DialectMySQL:
+		return s.mysqlMatch(arg)
+	case squealx.DialectSQLite:
+		return "bm25(" + s.Table + ")"
+	}
+	return "ts_rank(" + s.document() + ", " + s.tsquery(arg) + ")"
+}
+
+func (s Search) highlight(arg, column, start, stop string) string {
+	switch s.Dialect {
+	case squealx.DialectMySQL:
+		return column
+	case squealx.DialectSQLite:
+		idx := 0
+		for i, c := range s.Columns {
+			if c == column {
+				idx = i
+			}
+		}
+		return "highlight(" + s.Table + ", " + strconv.Itoa(idx) + ", " + literal(start) + ", " + literal(stop) + ")"
+	}
+	options := "StartSel=" + start + ", StopSel=" + stop
+	return "ts_headline(" + s.language() + ", " + column + ", " + s.tsquery(arg) + ", " + literal(options) + ")"
+}
+
+func (s Search) mysqlMatch(arg string) string {
+	return "MATCH (" + strings.Join(s.Columns, ", ") + ") AGAINST (" + arg + " IN NATURAL LANGUAGE MODE)"
+}
+
+// document returns the tsvector of the columns, concatenated.
+func (s Search) document() string {
+	parts := make([]string, len(s.Columns))
+	for i, c := range s.Columns {
+		parts[i] = "coalesce(" + c + ", '')"
+	}
+	return "to_tsvector(" + s.language() + ", " + strings.Join(parts, " || ' ' || ") + ")"
+}
+
+func (s Search) tsquery(arg string) string {
+	return "websearch_to_tsquery(" + s.language() + ", " + arg + ")"
+}
+
+func (s Search) language() string {
+	if s.Language == "" {
+		return "'english'"
+	}
+	return literal(s.Language)
+}
+
+func literal(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,183 @@
+package squealx
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// ServerInfo describes the database server a DB is connected to.
+type ServerInfo struct {
+	Dialect  string
+	Database string
+	// Version is the version string reported by the server.
+	Version string
+	Major   int
+	Minor   int
+	// MariaDB is set for MariaDB servers, whose dialect is DialectMySQL.
+	MariaDB bool
+}
+
+// AtLeast reports whether the server version is major.minor or later.
+func (s ServerInfo) AtLeast(major, minor int) bool {
+	return s.Major > major || (s.Major == major && s.Minor >= minor)
+}
+
+// SupportsReturning reports whether INSERT, UPDATE and DELETE accept a
+// RETURNING clause.
+func (s ServerInfo) SupportsReturning() bool {
+	switch s.Dialect {
+	case DialectPostgres:
+		return true
+	case DialectSQLite:
+		return s.AtLeast(3, 35)
+	case DialectMySQL:
+		return s.MariaDB && s.AtLeast(10, 5)
+	}
+	return false
+}
+
+// SupportsSkipLocked reports whether SELECT ... FOR UPDATE SKIP LOCKED is
+// available.
+func (s ServerInfo) SupportsSkipLocked() bool {
+	switch s.Dialect {
+	case DialectPostgres:
+		return s.AtLeast(9, 5)
+	case DialectMySQL:
+		if s.MariaDB {
+			return s.AtLeast(10, 6)
+		}
+		return s.AtLeast(8, 0)
+	}
+	return false
+}
+
+// SupportsWindowFunctions reports whether window functions such as
+// COUNT(*) OVER() are available.
+func (s ServerInfo) SupportsWindowFunctions() bool {
+	switch s.Dialect {
+	case DialectPostgres, DialectMSSQL:
+		return true
+	case DialectSQLite:
+		return s.AtLeast(3, 25)
+	case DialectMySQL:
+		if s.MariaDB {
+			return s.AtLeast(10, 2)
+		}
+		return s.AtLeast(8, 0)
+	}
+	return false
+}
+
+// serverInfoCache holds the ServerInfo of a connection pool. It is shared by
+// the DB values wrapping the same pool. Concurrent first calls share a single
+// probe.
+type serverInfoCache struct {
+	probes singleflight.Group
+	mu     sync.Mutex
+	info   *ServerInfo
+}
+
+var serverInfoQueries = map[string]string{
+	DialectPostgres: "SELECT current_database(), current_setting('server_version')",
+	DialectMySQL:    "SELECT coalesce(DATABASE(), ''), VERSION()",
+	DialectMSSQL:    "SELECT DB_NAME(), CAST(SERVERPROPERTY('ProductVersion') AS nvarchar(128))",
+	DialectSQLite:   "SELECT 'main', sqlite_version()",
+}
+
+var versionReg = regexp.MustCompile(`(\d+)\.(\d+)`)
+
+// ServerInfo returns the database name, version and dialect of the server.
+// It is queried once and then cached; callers arriving while it is queried
+// wait for that query, or for ctx to be done.
+func (db *DB) ServerInfo(ctx context.Context) (ServerInfo, error) {
+	cache := db.serverInfo
+	if cache == nil {
+		return db.probeServerInfo(ctx)
+	}
+	cache.mu.Lock()
+	info := cache.info
+	cache.mu.Unlock()
+	if info != nil {
+		return *info, nil
+	}
+	// The probe outlives a caller giving up, so as not to fail the others.
+	probeCtx := context.WithoutCancel(ctx)
+	probe := cache.probes.DoChan("", func() (any, error) {
+		info, err := db.probeServerInfo(probeCtx)
+		if err != nil {
+			return info, err
+		}
+		cache.mu.Lock()
+		cache.info = &info
+		cache.mu.Unlock()
+		return info, nil
+	})
+	select {
+	case result := <-probe:
+		return result.Val.(ServerInfo), result.Err
+	case <-ctx.Done():
+		return ServerInfo{Dialect: Dialect(db.driverName)}, ctx.Err()
+	}
+}
+
+// probeServerInfo queries the ServerInfo of the server.
+func (db *DB) probeServerInfo(ctx context.Context) (ServerInfo, error) {
+	info := ServerInfo{Dialect: Dialect(db.driverName)}
+	query, ok := serverInfoQueries[info.Dialect]
+	if !ok {
+		return info, nil
+	}
+	if err := db.QueryRowxContext(ctx, query).Scan(&info.Database, &info.Version); err != nil {
+		return info, err
+	}
+	if m := versionReg.FindStringSubmatch(info.Version); m != nil {
+		info.Major, _ = strconv.Atoi(m[1])
+		info.Minor, _ = strconv.Atoi(m[2])
+	}
+	info.MariaDB = strings.Contains(strings.ToLower(info.Version), "mariadb")
+	return info, nil
+}
+
+// SupportsReturning reports whether the server accepts RETURNING clauses. It
+// is false when the server version cannot be determined.
+//
+// SupportsReturning uses context.Background internally; to specify the
+// context, use SupportsReturningContext.
+func (db *DB) SupportsReturning() bool {
+	return db.SupportsReturningContext(context.Background())
+}
+
+// SupportsReturningContext reports whether the server accepts RETURNING
+// clauses. PostgreSQL always does, without querying the server; for other
+// dialects it is false when the server version cannot be determined.
+func (db *DB) SupportsReturningContext(ctx context.Context) bool {
+	switch Dialect(db.driverName) {
+	case DialectPostgres:
+		return true
+	case DialectMSSQL, DialectUnknown:
+		return false
+	}
+	info, err := db.ServerInfo(ctx)
+	return err == nil && info.SupportsReturning()
+}
+
+// SupportsSkipLocked reports whether the server supports SKIP LOCKED. It is
+// false when the server version cannot be determined.
+//
+// SupportsSkipLocked uses context.Background internally; to specify the
+// context, use SupportsSkipLockedContext.
+func (db *DB) SupportsSkipLocked() bool {
+	return db.SupportsSkipLockedContext(context.Background())
+}
+
+// SupportsSkipLockedContext reports whether the server supports SKIP LOCKED.
+// It is false when the server version cannot be determined.
+func (db *DB) SupportsSkipLockedContext(ctx context.Context) bool {
+	info, err := db.ServerInfo(ctx)
+	return err == nil && info.SupportsSkipLocked()
+}
@@ -0,0 +1,74 @@
+package squealx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+// ErrSnapshotNotSupported is returned by WithSnapshot for drivers other than
+// PostgreSQL.
+var ErrSnapshotNotSupported = errors.New("squealx: snapshot export is only supported on PostgreSQL")
+
+// Snapshot is an exported PostgreSQL snapshot. It stays importable while the
+// function passed to WithSnapshot runs.
+type Snapshot struct {
+	// ID is the identifier returned by pg_export_snapshot.
+	ID string
+	// Tx is the exporting transaction, usable for reads itself.
+	Tx *Tx
+	db *DB
+}
+
+// WithSnapshot opens a REPEATABLE READ, read-only transaction, exports its
+// snapshot and calls fn with it. Transactions begun with Snapshot.Begin or
+// Snapshot.With see exactly the same data, which lets several workers read
+// or paginate a consistent state in parallel. fn must wait for those workers
+// before returning, as the snapshot is released with the exporting
+// transaction.
+func (db *DB) WithSnapshot(ctx context.Context, fn func(snap *Snapshot) error) error {
+	if Dialect(db.driverName) != DialectPostgres {
+		return ErrSnapshotNotSupported
+	}
+	opts := &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+	return db.WithTxx(ctx, opts, func(tx *Tx) error {
+		snap := &Snapshot{Tx: tx, db: db}
+		if err := tx.QueryRowxContext(ctx, "SELECT pg_export_snapshot()").Scan(&snap.ID); err != nil {
+			return err
+		}
+		return fn(snap)
+	})
+}
+
+// Begin starts a REPEATABLE READ, read-only transaction attached to the
+// snapshot. The caller must end it with Commit or Rollback.
+func (s *Snapshot) Begin(ctx context.Context) (*Tx, error) {
+	tx, err := s.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.SQLTx.ExecContext(ctx, "SET TRANSACTION SNAPSHOT "+quoteLiteral(s.ID)); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	return tx, nil
+}
+
+// With runs fn in a transaction attached to the snapshot.
+func (s *Snapshot) With(ctx context.Context, fn func(tx *Tx) error) error {
+	tx, err := s.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// quoteLiteral quotes s as a standard SQL string literal.
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,99 @@
+// Package sqlctx holds the values carried by the contexts of statements:
+// the driver and database running them, their transaction, the tenant they
+// run for and free-form tags. squealx sets the driver, database and
+// transaction on the context its hooks and rewriters receive; applications
+// set the tenant and tags, which hooks, the resolver and logging read back.
+//
+// It depends on no other package of the module, so that any of them can use
+// it.
+package sqlctx
+
+import (
+	"context"
+	"database/sql"
+	"maps"
+	"time"
+)
+
+// TxInfo describes a transaction for lifecycle hooks. Duration is zero on
+// begin; Statements counts the statements run through the Tx so far.
+type TxInfo struct {
+	ID         uint64
+	Options    *sql.TxOptions
+	Started    time.Time
+	Duration   time.Duration
+	Statements int64
+}
+
+type (
+	driverNameKey struct{}
+	dbIDKey       struct{}
+	txInfoKey     struct{}
+	tenantKey     struct{}
+	queryTagsKey  struct{}
+)
+
+// WithDriverName returns ctx carrying the name of the driver running its
+// statements.
+func WithDriverName(ctx context.Context, driverName string) context.Context {
+	return context.WithValue(ctx, driverNameKey{}, driverName)
+}
+
+// DriverNameFromContext returns the driver name set with WithDriverName.
+func DriverNameFromContext(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(driverNameKey{}).(string)
+	return name, ok && name != ""
+}
+
+// WithDBID returns ctx carrying the ID of the database running its
+// statements, such as a node of a resolver.
+func WithDBID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, dbIDKey{}, id)
+}
+
+// DBIDFromContext returns the database ID set with WithDBID.
+func DBIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(dbIDKey{}).(string)
+	return id, ok && id != ""
+}
+
+// WithTxInfo returns ctx carrying the transaction its statements run in.
+func WithTxInfo(ctx context.Context, info TxInfo) context.Context {
+	return context.WithValue(ctx, txInfoKey{}, info)
+}
+
+// TxInfoFromContext returns the transaction set with WithTxInfo.
+func TxInfoFromContext(ctx context.Context) (TxInfo, bool) {
+	info, ok := ctx.Value(txInfoKey{}).(TxInfo)
+	return info, ok
+}
+
+// WithTenant returns ctx carrying the tenant its statements run for.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant set with WithTenant.
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantKey{}).(string)
+	return tenant, ok && tenant != ""
+}
+
+// WithQueryTags returns ctx carrying tags describing its statements, such
+// as the endpoint or job issuing them, for logging and tracing. Tags add to
+// those of ctx, replacing those of the same key.
+func WithQueryTags(ctx context.Context, tags map[string]string) context.Context {
+	all := maps.Clone(QueryTagsFromContext(ctx))
+	if all == nil {
+		all = make(map[string]string, len(tags))
+	}
+	maps.Copy(all, tags)
+	return context.WithValue(ctx, queryTagsKey{}, all)
+}
+
+// QueryTagsFromContext returns the tags set with WithQueryTags. The map
+// must not be modified.
+func QueryTagsFromContext(ctx context.Context) map[string]string {
+	tags, _ := ctx.Value(queryTagsKey{}).(map[string]string)
+	return tags
+}
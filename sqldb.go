@@ -54,6 +54,19 @@ type SQLRows interface {
 	Next() bool
 }
 
+// resultSets is implemented by the SQLRows of drivers returning several
+// result sets, such as *sql.Rows.
+type resultSets interface {
+	NextResultSet() bool
+}
+
+// nextResultSet moves rows to their next result set, reporting whether
+// there is one. Rows which cannot have several have none.
+func nextResultSet(rows SQLRows) bool {
+	sets, ok := rows.(resultSets)
+	return ok && sets.NextResultSet()
+}
+
 type SQLStmt interface {
 	Close() error
 	Query(args ...any) (SQLRows, error)
@@ -232,6 +232,11 @@ func (s *sqlConnWrapper) Close() error {
 	return s.conn.Close()
 }
 
+// Raw runs f with the driver connection, like sql.Conn.Raw.
+func (s *sqlConnWrapper) Raw(f func(driverConn any) error) error {
+	return s.conn.Raw(f)
+}
+
 func (s *sqlConnWrapper) BeginTx(ctx context.Context, opts *sql.TxOptions) (SQLTx, error) {
 	tx, err := s.conn.BeginTx(ctx, opts)
 	if err != nil {
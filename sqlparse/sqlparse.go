@@ -0,0 +1,525 @@
+// Package sqlparse analyses SQL statements over the tokens of sqltoken,
+// without parsing them into a full syntax tree.
+//
+// Parse tells the kind of a statement, the tables it references with their
+// aliases, the names of its common table expressions, whether it has a
+// WHERE clause or a limit at the top level, and how many placeholders it
+// binds. It is lenient: unknown syntax is skipped rather than rejected, so
+// it suits the needs of hooks, caches and routers deciding on statements
+// they do not run themselves.
+//
+//	stmt := sqlparse.Parse("SELECT * FROM users u JOIN orders o ON o.user_id = u.id WHERE u.id = $1")
+//	// stmt.Kind == sqlparse.Select, stmt.Table() == "users",
+//	// stmt.Tables[1].Name == "orders", stmt.Tables[1].Alias == "o"
+package sqlparse
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/oarkflow/squealx/sqltoken"
+)
+
+// Kind is the kind of a statement, after its leading top-level keyword.
+type Kind string
+
+const (
+	Unknown Kind = ""
+	Select  Kind = "SELECT"
+	Insert  Kind = "INSERT"
+	Update  Kind = "UPDATE"
+	Delete  Kind = "DELETE"
+)
+
+// Table is a table referenced by a statement.
+type Table struct {
+	Schema string
+	Name   string
+	Alias  string
+	// CTE is set when Name is a common table expression of the statement
+	// rather than a table of the database.
+	CTE bool
+	// Nested is set for the tables of subqueries and common table
+	// expressions, within parentheses.
+	Nested bool
+	// Write is set for the tables the statement inserts into, updates or
+	// deletes from.
+	Write bool
+	// Qualifier is the alias of the table, or its possibly qualified name
+	// when it has none, as written in the query with its quotes, which
+	// qualifies the columns of the table in the statement.
+	Qualifier string
+	// Offset and End are the byte offsets of the reference in the query,
+	// End following its alias if any.
+	Offset int
+	End    int
+}
+
+// Statement is the analysis of a statement.
+type Statement struct {
+	Kind Kind
+	// Tables are the tables read after FROM, JOIN and USING, written after
+	// INTO and UPDATE, in the order of the statement, at every depth.
+	Tables []Table
+	// CTEs are the names of the common table expressions of WITH, at
+	// every depth.
+	CTEs []string
+	// HasWhere and HasLimit tell whether the statement has a WHERE clause
+	// and a LIMIT, FETCH or TOP limit at the top level, outside subqueries.
+	HasWhere bool
+	HasLimit bool
+	// Placeholders is the number of ?, $n, @name and :name placeholders.
+	Placeholders int
+	// Opaque is set when the statement reads from a table function, whose
+	// tables cannot be known. Callers deciding on the tables of statements
+	// strictly, such as allowlists, should reject opaque statements.
+	Opaque bool
+}
+
+// Table returns the name of the primary table of s, the one it reads from
+// or writes to at the top level, or "" when there is none. Common table
+// expressions are skipped, so for `WITH x AS (...) SELECT * FROM users` it
+// is "users".
+func (s Statement) Table() string {
+	for _, t := range s.Tables {
+		if !t.Nested {
+			return t.Name
+		}
+	}
+	return ""
+}
+
+var config = func() sqltoken.Config {
+	c := sqltoken.PostgreSQLConfig()
+	c.NoticeQuestionMark = true
+	c.NoticeColonWord = true
+	c.NoticeAtWord = true
+	return c
+}()
+
+// Token is a significant token of a statement, with its byte offset in
+// the query and the parenthesis depth it is at. Punctuation is split into
+// single characters.
+type Token struct {
+	Type   sqltoken.TokenType
+	Text   string
+	Upper  string
+	Offset int
+	Depth  int
+}
+
+// Tokenize returns the significant tokens of query.
+func Tokenize(query string) []Token {
+	return Tokens(sqltoken.Tokenize(query, config))
+}
+
+// Tokens returns the significant tokens of a query tokenized by sqltoken,
+// such as for its dialect. A ? is a placeholder even when the dialect does
+// not notice it, and a name quoted with backticks or brackets is an
+// identifier even when the dialect tokenizes them as punctuation.
+func Tokens(ts sqltoken.Tokens) []Token {
+	var tokens []Token
+	query := ts.String()
+	// skip is the offset up to which the query is a quoted name.
+	offset, depth, skip := 0, 0, 0
+	for _, t := range ts {
+		switch {
+		case offset+len(t.Text) <= skip:
+		case t.Type == sqltoken.Whitespace, t.Type == sqltoken.Comment:
+		case t.Type == sqltoken.Punctuation:
+			for i, r := range t.Text {
+				at := offset + i
+				if at < skip {
+					continue
+				}
+				if end := quotedName(query, at); end > 0 {
+					skip = end
+					tokens = append(tokens, Token{Type: sqltoken.Identifier, Text: query[at:end], Upper: strings.ToUpper(query[at:end]), Offset: at, Depth: depth})
+					continue
+				}
+				if r == ')' {
+					depth--
+				}
+				typ := t.Type
+				if r == '?' {
+					typ = sqltoken.QuestionMark
+				}
+				tokens = append(tokens, Token{Type: typ, Text: string(r), Upper: string(r), Offset: at, Depth: depth})
+				if r == '(' {
+					depth++
+				}
+			}
+		default:
+			tokens = append(tokens, Token{Type: t.Type, Text: t.Text, Upper: strings.ToUpper(t.Text), Offset: offset, Depth: depth})
+		}
+		offset += len(t.Text)
+	}
+	return tokens
+}
+
+// quotedName returns the offset following the name quoted with backticks
+// or brackets at query[at], and 0 when there is none there. A bracketed
+// name is made of letters, digits, spaces and _, $, # or @, and does not
+// start with a digit, so that it is not mistaken for an array subscript.
+func quotedName(query string, at int) int {
+	switch query[at] {
+	case '`':
+		if end := strings.IndexByte(query[at+1:], '`'); end >= 0 {
+			return at + end + 2
+		}
+	case '[':
+		end := strings.IndexByte(query[at+1:], ']')
+		if end <= 0 || query[at+1] >= '0' && query[at+1] <= '9' {
+			return 0
+		}
+		for _, r := range query[at+1 : at+1+end] {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune(" _$#@", r) {
+				return 0
+			}
+		}
+		return at + end + 2
+	}
+	return 0
+}
+
+// clauseWords end a table reference; a word among them is not an alias.
+var clauseWords = map[string]bool{
+	"WHERE": true, "JOIN": true, "INNER": true, "LEFT": true, "RIGHT": true,
+	"FULL": true, "OUTER": true, "CROSS": true, "NATURAL": true, "ON": true,
+	"USING": true, "SET": true, "VALUES": true, "VALUE": true, "SELECT": true,
+	"GROUP": true, "ORDER": true, "HAVING": true, "LIMIT": true, "OFFSET": true,
+	"FETCH": true, "UNION": true, "INTERSECT": true, "EXCEPT": true,
+	"WINDOW": true, "FOR": true, "RETURNING": true, "OUTPUT": true,
+	"DEFAULT": true, "LATERAL": true, "STRAIGHT_JOIN": true, "WITH": true,
+	"OPTION": true, "ONLY": true, "AS": true,
+}
+
+// notCTEs are words following WITH that do not name a common table
+// expression, as in WITH ORDINALITY.
+var notCTEs = map[string]bool{
+	"RECURSIVE": true, "ORDINALITY": true, "TIES": true, "ROLLUP": true,
+	"CHECK": true, "TIME": true, "LOCAL": true, "CASCADED": true, "NO": true,
+	"DATA": true, "GRANT": true, "HOLD": true,
+}
+
+// Parse analyses query. Common table expressions are found at every depth,
+// and the tables of their bodies and of lateral subqueries are listed along
+// with those of the statement.
+func Parse(query string) Statement {
+	return ParseTokens(Tokenize(query))
+}
+
+// ParseTokens analyses the statement made of tokens, as returned by
+// Tokenize or Tokens.
+func ParseTokens(tokens []Token) Statement {
+	var s Statement
+	// withList holds the depths at which a WITH list is open, until the
+	// statement it precedes starts.
+	withList := map[int]bool{}
+	cteNext := false
+	prev := ""
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch t.Type {
+		case sqltoken.QuestionMark, sqltoken.DollarNumber, sqltoken.ColonWord, sqltoken.AtWord:
+			s.Placeholders++
+			continue
+		case sqltoken.Punctuation:
+			// CTEs are separated by commas.
+			if t.Text == "," && withList[t.Depth] {
+				cteNext = true
+			}
+			continue
+		case sqltoken.Word:
+		default:
+			continue
+		}
+		before := prev
+		prev = t.Upper
+
+		switch {
+		case t.Upper == "WITH":
+			withList[t.Depth] = true
+			cteNext = true
+			continue
+		case cteNext && !notCTEs[t.Upper]:
+			cteNext = false
+			// A name is followed by AS or by its column list.
+			if i+1 < len(tokens) && (tokens[i+1].Upper == "AS" || tokens[i+1].Text == "(") {
+				s.CTEs = append(s.CTEs, strings.Trim(t.Text, "\"`[]"))
+				continue
+			}
+		}
+
+		switch Kind(t.Upper) {
+		case Select, Insert, Update, Delete:
+			delete(withList, t.Depth)
+			cteNext = false
+			if t.Depth == 0 && s.Kind == Unknown {
+				s.Kind = Kind(t.Upper)
+			}
+		}
+
+		switch t.Upper {
+		case "WHERE":
+			if t.Depth == 0 {
+				s.HasWhere = true
+			}
+		case "LIMIT", "FETCH", "TOP":
+			if t.Depth == 0 {
+				s.HasLimit = true
+			}
+		case "UPDATE":
+			// Not FOR UPDATE, ON UPDATE or DO UPDATE.
+			if before != "FOR" && before != "ON" && before != "DO" && before != "KEY" {
+				i = s.readTables(tokens, i+1, false, false, true)
+			}
+		case "FROM":
+			i = s.readTables(tokens, i+1, true, true, before == "DELETE")
+		case "USING":
+			i = s.readTables(tokens, i+1, true, true, false)
+		case "JOIN":
+			i = s.readTables(tokens, i+1, false, true, false)
+		case "INTO":
+			i = s.readTables(tokens, i+1, false, false, true)
+		}
+	}
+	for i := range s.Tables {
+		for _, cte := range s.CTEs {
+			if s.Tables[i].Schema == "" && strings.EqualFold(s.Tables[i].Name, cte) {
+				s.Tables[i].CTE = true
+			}
+		}
+	}
+	return s
+}
+
+// DatabaseTables returns the distinct tables of the database referenced by
+// s, at every depth, without its common table expressions.
+func (s Statement) DatabaseTables() []Table {
+	var tables []Table
+	seen := map[string]bool{}
+	for _, t := range s.Tables {
+		key := strings.ToLower(t.Schema + "." + t.Name)
+		if t.CTE || seen[key] {
+			continue
+		}
+		seen[key] = true
+		tables = append(tables, Table{Schema: t.Schema, Name: t.Name})
+	}
+	return tables
+}
+
+// Written returns the distinct tables of the database written by s, at
+// every depth, including those of data-modifying common table expressions.
+func (s Statement) Written() []Table {
+	var tables []Table
+	seen := map[string]bool{}
+	for _, t := range s.Tables {
+		key := strings.ToLower(t.Schema + "." + t.Name)
+		if !t.Write || t.CTE || seen[key] {
+			continue
+		}
+		seen[key] = true
+		tables = append(tables, Table{Schema: t.Schema, Name: t.Name, Write: true})
+	}
+	return tables
+}
+
+// readTables reads the table references starting at tokens[i], separated by
+// commas when list is set, and returns the index of their last token. A
+// name followed by ( is a table function when functions is set, such as
+// after FROM, and is followed by a column list otherwise, such as after
+// INTO. The tables are written by the statement when write is set.
+func (s *Statement) readTables(tokens []Token, i int, list, functions, write bool) int {
+	for i < len(tokens) {
+		start := i
+		table, next, ok := readTable(tokens, i, functions)
+		if !ok {
+			return start - 1
+		}
+		if table.Name == "" {
+			s.Opaque = true
+		} else {
+			table.Nested = tokens[start].Depth > 0
+			table.Write = write
+			table.Offset = tokens[start].Offset
+			table.End = tokens[next-1].Offset + len(tokens[next-1].Text)
+			s.Tables = append(s.Tables, table)
+		}
+		i = next
+		if !list || i >= len(tokens) || tokens[i].Text != "," || tokens[i].Depth != tokens[start].Depth {
+			return i - 1
+		}
+		i++
+	}
+	return i - 1
+}
+
+// readTable reads a table reference, a possibly qualified and quoted name
+// with an optional alias, starting at tokens[i]. It returns the index
+// following it, and false when tokens[i] does not start one, such as for a
+// subquery. A table function is skipped and returned without name.
+func readTable(tokens []Token, i int, functions bool) (Table, int, bool) {
+	if i < len(tokens) && (tokens[i].Upper == "ONLY" || tokens[i].Upper == "LATERAL") {
+		i++
+	}
+	var parts []string
+	var written strings.Builder
+	expect := true
+name:
+	for i < len(tokens) {
+		t := tokens[i]
+		switch {
+		case t.Text == "." && !expect:
+			expect = true
+		case expect && isName(t):
+			if len(parts) == 0 && t.Type == sqltoken.Word && clauseWords[t.Upper] {
+				return Table{}, i, false
+			}
+			parts = append(parts, strings.Trim(t.Text, "\"`[]"))
+			expect = false
+		default:
+			break name
+		}
+		written.WriteString(t.Text)
+		i++
+	}
+	if len(parts) == 0 {
+		return Table{}, i, false
+	}
+	if functions && i < len(tokens) && tokens[i].Text == "(" {
+		return Table{}, i, true
+	}
+	table := Table{Name: parts[len(parts)-1], Schema: strings.Join(parts[:len(parts)-1], "."), Qualifier: written.String()}
+	if i < len(tokens) && tokens[i].Upper == "AS" {
+		i++
+	}
+	if i < len(tokens) && isName(tokens[i]) && !clauseWords[tokens[i].Upper] {
+		table.Alias = strings.Trim(tokens[i].Text, "\"`[]")
+		table.Qualifier = tokens[i].Text
+		i++
+	}
+	return table, i, true
+}
+
+// isName reports whether t may be a name: a word, or an identifier quoted
+// otherwise than as a string.
+func isName(t Token) bool {
+	switch t.Type {
+	case sqltoken.Word, sqltoken.Identifier:
+		return true
+	case sqltoken.Literal:
+		return !strings.HasSuffix(t.Text, "'")
+	}
+	return false
+}
+
+// Items returns the comma-separated items within the parentheses opening at
+// tokens[i], and the index of the closing parenthesis.
+func Items(tokens []Token, i int) ([][]Token, int) {
+	depth := tokens[i].Depth + 1
+	var list [][]Token
+	var item []Token
+	for i++; i < len(tokens); i++ {
+		t := tokens[i]
+		if t.Depth < depth {
+			break
+		}
+		if t.Depth == depth && t.Text == "," {
+			list = append(list, item)
+			item = nil
+			continue
+		}
+		item = append(item, t)
+	}
+	if item != nil || list != nil {
+		list = append(list, item)
+	}
+	return list, i
+}
+
+// selectListEnds are the words ending a SELECT list.
+var selectListEnds = map[string]bool{
+	"FROM": true, "WHERE": true, "GROUP": true, "HAVING": true, "WINDOW": true,
+	"ORDER": true, "LIMIT": true, "OFFSET": true, "FETCH": true, "ON": true,
+	"RETURNING": true, "INTO": true, "FOR": true,
+}
+
+// SelectList returns the items of the SELECT list of the query starting at
+// tokens[i], and the byte offset following its last item. It returns false
+// when there is no SELECT list, and for compound queries, whose SELECT lists
+// are several.
+func SelectList(tokens []Token, i int) ([][]Token, int, bool) {
+	for i < len(tokens) && tokens[i].Upper != "SELECT" {
+		i++
+	}
+	if i >= len(tokens) {
+		return nil, 0, false
+	}
+	depth := tokens[i].Depth
+	var list [][]Token
+	var item []Token
+	end, last := -1, 0
+	for i++; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.Depth == depth && t.Type == sqltoken.Word:
+			switch t.Upper {
+			case "UNION", "INTERSECT", "EXCEPT":
+				return nil, 0, false
+			case "DISTINCT", "ALL":
+				if end < 0 && len(list) == 0 && item == nil {
+					continue
+				}
+			}
+			if end < 0 && selectListEnds[t.Upper] {
+				end = last
+			}
+		case t.Depth < depth || t.Type == sqltoken.Semicolon:
+			if end < 0 {
+				end = last
+			}
+		case end < 0 && t.Depth == depth && t.Text == ",":
+			list = append(list, item)
+			item = nil
+			continue
+		}
+		if end < 0 {
+			item = append(item, t)
+			last = t.Offset + len(t.Text)
+		}
+	}
+	if end < 0 {
+		end = last
+	}
+	return append(list, item), end, true
+}
+
+// Arg returns the position, from 1, of the argument bound by the
+// placeholder tokens[i]: its number for $n and @pn placeholders, and its
+// rank among the ? placeholders of tokens otherwise. It returns 0 for
+// tokens that are not positional placeholders, such as :name.
+func Arg(tokens []Token, i int) int {
+	t := tokens[i]
+	switch t.Type {
+	case sqltoken.DollarNumber:
+		n, _ := strconv.Atoi(t.Text[1:])
+		return n
+	case sqltoken.AtWord:
+		if len(t.Text) > 2 && strings.EqualFold(t.Text[:2], "@p") {
+			n, _ := strconv.Atoi(t.Text[2:])
+			return n
+		}
+	case sqltoken.QuestionMark:
+		n := 1
+		for _, u := range tokens[:i] {
+			if u.Type == sqltoken.QuestionMark {
+				n++
+			}
+		}
+		return n
+	}
+	return 0
+}
@@ -7,14 +7,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
 	"reflect"
 	"strconv"
 	"sync"
 	"time"
 
 	"github.com/oarkflow/squealx/reflectx"
+	"github.com/oarkflow/squealx/sqlctx"
 	"github.com/oarkflow/squealx/utils/xstrings"
 )
 
@@ -175,6 +174,29 @@ func mapperFor(i any) *reflectx.Mapper {
 	}
 }
 
+// hooksFor returns the DB whose hooks apply to statements run through i, or
+// nil when there is none.
+func hooksFor(i any) *DB {
+	switch i := i.(type) {
+	case *DB:
+		return i
+	case *Tx:
+		return i.hookDB()
+	case *Conn:
+		return i.db
+	case *Stmt:
+		return i.db
+	default:
+		return nil
+	}
+}
+
+// txFor returns i when it is a transaction.
+func txFor(i any) *Tx {
+	tx, _ := i.(*Tx)
+	return tx
+}
+
 var _scannerInterface = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
 var _valuerInterface = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
 
@@ -185,6 +207,13 @@ type Row struct {
 	unsafe bool
 	rows   SQLRows
 	Mapper *reflectx.Mapper
+	// table and transformers post-process rows scanned into maps
+	table        string
+	transformers []RowTransformer
+	// times converts the times scanned into maps
+	times *TimePolicy
+	// traversals caches the struct traversals of the DB queried
+	traversals *traversalCache
 }
 
 // Scan is a fixed implementation of sql.Row.Scan, which does not discard the
@@ -250,137 +279,339 @@ func (r *Row) ColumnTypes() ([]*sql.ColumnType, error) {
 
 // Err returns the error encountered while scanning.
 func (r *Row) Err() error {
-	defer r.rows.Close()
+	if r.rows != nil {
+		defer r.rows.Close()
+	}
 	return r.err
 }
 
+// RowError returns a Row whose Scan and Err return err, for wrappers failing
+// before the query runs.
+func RowError(err error) *Row {
+	return &Row{err: err}
+}
+
 // DB is a wrapper around sql.DB which keeps track of the driverName upon Open,
 // used mostly to automatically bind named queries using the right bindvars.
+//
+// A DB is safe for concurrent use. Hooks, the hook policy and row
+// transformers may be registered while queries run; a query uses the hooks
+// registered when it started. Setting Mapper, MapperFunc and
+// SetNamingStrategy are not synchronised and belong to setup.
 type DB struct {
 	SQLDB
-	ID          string
-	driverName  string
-	dbName      string
-	unsafe      bool
-	Mapper      *reflectx.Mapper
-	beforeHooks []Hook
-	afterHooks  []Hook
-	onError     []ErrorHook
+	ID         string
+	driverName string
+	dbName     string
+	serverInfo *serverInfoCache
+	unsafe     bool
+	Mapper     *reflectx.Mapper
+	// hooks holds the hooks, hook policy and row transformers, which may be
+	// registered while queries run.
+	hooks *hookRegistry
+	// times is the TimePolicy set with WithTimePolicy, if any.
+	times *TimePolicy
+	// traversals caches the struct traversals of the results scanned.
+	traversals *traversalCache
+}
+
+type dbOptions struct {
+	mapper       *reflectx.Mapper
+	tagName      string
+	fallbackTags []string
+	strategy     func(string) string
+	connInit     func(ctx context.Context, conn SessionConn) error
+	timePolicy   *TimePolicy
+}
+
+func loadDBOptions(opts []DBOption) dbOptions {
+	var o dbOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// DBOption configures a DB created by Open, NewDb and the other
+// constructors.
+type DBOption func(*dbOptions)
+
+// WithMapper makes the DB map columns to struct fields with m instead of
+// the package default built from NameMapper.
+func WithMapper(m *reflectx.Mapper) DBOption {
+	return func(o *dbOptions) {
+		o.mapper = m
+	}
+}
+
+// WithTagName makes the DB read column names from the struct tag tagName,
+// such as "json", instead of "db". Untagged fields are mapped with
+// NameMapper as it is when the DB is created.
+func WithTagName(tagName string) DBOption {
+	return func(o *dbOptions) {
+		o.tagName = tagName
+	}
+}
+
+// WithJSONTagFallback makes the DB name fields without a "db" tag (or the
+// tag set by WithTagName) after their json tag, and only then map their
+// field name with NameMapper, so API models can be scanned without
+// duplicating their tags.
+func WithJSONTagFallback() DBOption {
+	return func(o *dbOptions) {
+		o.fallbackTags = []string{"json"}
+	}
+}
+
+// newDB wraps db, giving it its own mapper when opts configure one so that
+// DBs in one process may use different naming conventions.
+func newDB(db SQLDB, driverName, id string, opts []DBOption) *DB {
+	o := loadDBOptions(opts)
+	m := o.mapper
+	if m == nil && (o.tagName != "" || len(o.fallbackTags) > 0 || o.strategy != nil) {
+		tagName := o.tagName
+		if tagName == "" {
+			tagName = "db"
+		}
+		strategy := o.strategy
+		if strategy == nil {
+			strategy = NameMapper
+		}
+		m = strategyMapper(tagName, o.fallbackTags, strategy)
+	}
+	if m == nil {
+		m = mapper()
+	}
+	return &DB{SQLDB: db, driverName: driverName, Mapper: m, ID: id, serverInfo: &serverInfoCache{}, hooks: &hookRegistry{}, times: o.timePolicy, traversals: newTraversalCache()}
 }
 
 // NewDb returns a new sqlx DB wrapper for a pre-existing *sql.DB.  The
 // driverName of the original database is required for named query support.
-func NewDb(db *sql.DB, driverName, id string) *DB {
-	return &DB{SQLDB: WrapSQLDB(db), driverName: driverName, Mapper: mapper(), ID: id}
+func NewDb(db *sql.DB, driverName, id string, opts ...DBOption) *DB {
+	return newDB(WrapSQLDB(db), driverName, id, opts)
 }
 
 // NewSQLDb returns a new sqlx DB wrapper for a pre-existing SQLDB.  The
 // driverName of the original database is required for named query support.
-func NewSQLDb(db SQLDB, driverName, id string) *DB {
-	return &DB{SQLDB: db, driverName: driverName, Mapper: mapper(), ID: id}
+func NewSQLDb(db SQLDB, driverName, id string, opts ...DBOption) *DB {
+	return newDB(db, driverName, id, opts)
 }
 
 // OpenExist uses already opened connection instead of creating new one.
-func OpenExist(driverName string, raw *sql.DB) *DB {
-	return &DB{SQLDB: WrapSQLDB(raw), driverName: driverName, Mapper: mapper()}
+func OpenExist(driverName string, raw *sql.DB, opts ...DBOption) *DB {
+	return newDB(WrapSQLDB(raw), driverName, "", opts)
 }
 
 func (db *DB) GetDBName() (string, error) {
 	if db.dbName != "" {
 		return db.dbName, nil
 	}
-	// Query to get the current database name
-	var dbName, query string
-	switch db.driverName {
-	case "pgx":
-		query = "SELECT current_database()"
-	case "mysql":
-		query = "SELECT DATABASE()"
-	case "mssql":
-		query = "PRAGMA database_list"
-	case "sqlite":
-		query = "SELECT DB_NAME()"
-	}
-	err := db.QueryRow(query).Scan(&dbName)
+	info, err := db.ServerInfo(context.Background())
 	if err != nil {
 		return "", err
 	}
-	return dbName, nil
+	return info.Database, nil
+}
+
+// SetHookPolicy sets how hook errors affect queries run through db.
+func (db *DB) SetHookPolicy(policy HookPolicy) {
+	db.hooks.update(func(s *hookSet) { s.policy = policy })
+}
+
+// OnHookPanic registers fn to be called whenever a hook panics. recovered is
+// the *HookPanic holding the panic value and stack. Whether the query then
+// fails is decided by HookPolicy.Panic.
+func (db *DB) OnHookPanic(fn func(recovered any, query string)) {
+	db.hooks.update(func(s *hookSet) { s.onPanic = fn })
+}
+
+// skipHookPanic reports a panic recovered by callHook and whether the policy
+// lets the hook chain continue past it.
+func (db *DB) skipHookPanic(err error, query string) bool {
+	p, ok := err.(*HookPanic)
+	if !ok {
+		return false
+	}
+	hooks := db.hooks.load()
+	if hooks.onPanic != nil {
+		hooks.onPanic(p, query)
+	}
+	return hooks.policy.Panic == FailOpen
+}
+
+// ignoreHookError reports a hook error discarded by the policy.
+func (db *DB) ignoreHookError(ctx context.Context, err *HookError, query string) {
+	if policy := db.hooks.load().policy; policy.OnIgnored != nil {
+		policy.OnIgnored(ctx, err, query)
+	}
 }
 
 func (db *DB) handleBeforeHooks(ctx context.Context, query string, args ...any) (context.Context, error) {
-	var err error
-	for _, hook := range db.beforeHooks {
-		ctx, err = hook(ctx, query, args...)
+	hooks := db.hooks.load()
+	for _, entry := range hooks.before {
+		if hookSkipped(ctx, entry.name) {
+			continue
+		}
+		hook := entry.hook
+		next, err := callHook(HookPhaseBefore, func() (context.Context, error) {
+			return hook(ctx, query, args...)
+		})
 		if err != nil {
-			return ctx, err
+			if db.skipHookPanic(err, query) {
+				continue
+			}
+			hookErr := &HookError{Phase: HookPhaseBefore, Err: err}
+			if _, panicked := err.(*HookPanic); panicked || hooks.policy.Before != FailOpen {
+				return ctx, hookErr
+			}
+			db.ignoreHookError(ctx, hookErr, query)
+		}
+		if next != nil {
+			ctx = next
 		}
 	}
 	return ctx, nil
 }
 
 func (db *DB) handleAfterHooks(ctx context.Context, query string, args ...any) (context.Context, error) {
-	var err error
-	for _, hook := range db.afterHooks {
-		ctx, err = hook(ctx, query, args...)
+	hooks := db.hooks.load()
+	for _, entry := range hooks.after {
+		if hookSkipped(ctx, entry.name) {
+			continue
+		}
+		hook := entry.hook
+		next, err := callHook(HookPhaseAfter, func() (context.Context, error) {
+			return hook(ctx, query, args...)
+		})
 		if err != nil {
-			return ctx, err
+			if db.skipHookPanic(err, query) {
+				continue
+			}
+			hookErr := &HookError{Phase: HookPhaseAfter, Err: err}
+			if _, panicked := err.(*HookPanic); panicked || hooks.policy.After == FailClosed {
+				return ctx, hookErr
+			}
+			db.ignoreHookError(ctx, hookErr, query)
+		}
+		if next != nil {
+			ctx = next
 		}
 	}
 	return ctx, nil
 }
 
+// handleErrorHooks runs every error hook with the query error and returns
+// it, wrapped in a HookError when a hook returned a different error. Hooks
+// returning nil or the error they were given leave it untouched.
 func (db *DB) handleErrorHooks(ctx context.Context, err error, query string, args ...any) error {
-	for _, hook := range db.onError {
-		err := hook(ctx, err, query, args...)
-		if err != nil {
-			return err
+	var hookErrs []error
+	for _, entry := range db.hooks.load().onError {
+		if hookSkipped(ctx, entry.name) {
+			continue
+		}
+		hook := entry.hook
+		_, hookErr := callHook(HookPhaseError, func() (context.Context, error) {
+			return nil, hook(ctx, err, query, args...)
+		})
+		if hookErr == nil || hookErr == err || db.skipHookPanic(hookErr, query) {
+			continue
 		}
+		hookErrs = append(hookErrs, hookErr)
 	}
-	return nil
+	if len(hookErrs) == 0 {
+		return err
+	}
+	return &HookError{Phase: HookPhaseError, Err: errors.Join(hookErrs...), Cause: err}
 }
 
 func (db *DB) Use(hooks ...any) {
-	for _, hook := range hooks {
-		if h, ok := hook.(BeforeHook); ok {
-			db.UseBefore(h.Before)
-		}
+	db.UseNamed("", hooks...)
+}
 
-		if h, ok := hook.(AfterHook); ok {
-			db.UseAfter(h.After)
-		}
+// UseNamed registers hooks like Use under name, so that individual queries
+// can skip them with WithoutHooks.
+func (db *DB) UseNamed(name string, hooks ...any) {
+	db.hooks.update(func(s *hookSet) {
+		for _, hook := range hooks {
+			if h, ok := hook.(BeforeHook); ok {
+				s.before = append(s.before, namedHook[Hook]{name: name, hook: h.Before})
+			}
+
+			if h, ok := hook.(AfterHook); ok {
+				s.after = append(s.after, namedHook[Hook]{name: name, hook: h.After})
+			}
+
+			if h, ok := hook.(ErrorerHook); ok {
+				s.onError = append(s.onError, namedHook[ErrorHook]{name: name, hook: h.OnError})
+			}
 
-		if h, ok := hook.(ErrorerHook); ok {
-			db.UseOnError(h.OnError)
+			switch hook.(type) {
+			case TxBeginHook, TxCommitHook, TxRollbackHook:
+				s.tx = append(s.tx, namedHook[any]{name: name, hook: hook})
+			}
+		}
+	})
+}
+
+// RemoveNamed unregisters the hooks registered with UseNamed under name, so
+// that temporary hooks such as tracers can be detached.
+func (db *DB) RemoveNamed(name string) {
+	db.hooks.update(func(s *hookSet) {
+		s.before = withoutNamed(s.before, name)
+		s.after = withoutNamed(s.after, name)
+		s.onError = withoutNamed(s.onError, name)
+		s.tx = withoutNamed(s.tx, name)
+	})
+}
+
+// withoutNamed returns a copy of hooks without those named name, leaving
+// the published array untouched.
+func withoutNamed[T any](hooks []namedHook[T], name string) []namedHook[T] {
+	kept := make([]namedHook[T], 0, len(hooks))
+	for _, h := range hooks {
+		if h.name != name {
+			kept = append(kept, h)
 		}
 	}
+	return kept
 }
 
 func (db *DB) UseBefore(hooks ...Hook) {
-	db.beforeHooks = append(db.beforeHooks, hooks...)
+	db.hooks.update(func(s *hookSet) {
+		for _, hook := range hooks {
+			s.before = append(s.before, namedHook[Hook]{hook: hook})
+		}
+	})
 }
 
 func (db *DB) UseAfter(hooks ...Hook) {
-	db.afterHooks = append(db.afterHooks, hooks...)
+	db.hooks.update(func(s *hookSet) {
+		for _, hook := range hooks {
+			s.after = append(s.after, namedHook[Hook]{hook: hook})
+		}
+	})
 }
 
 func (db *DB) UseOnError(onError ...ErrorHook) {
-	db.onError = append(db.onError, onError...)
+	db.hooks.update(func(s *hookSet) {
+		for _, hook := range onError {
+			s.onError = append(s.onError, namedHook[ErrorHook]{hook: hook})
+		}
+	})
 }
 
-func handleTwo[T any](fn func() (T, error), db *DB, ctx context.Context, query string, args ...interface{}) (T, error) {
+// handleTwo runs fn between the before and after hooks. The context returned
+// by the before hooks is passed to fn and to the after or error hooks, so
+// values and deadlines set by the caller or by hooks are visible throughout.
+func handleTwo[T any](fn func(ctx context.Context) (T, error), db *DB, ctx context.Context, query string, args ...interface{}) (T, error) {
 	var t T
-	ctx2, err := db.handleBeforeHooks(ctx, query, args...)
+	ctx2, err := db.handleBeforeHooks(db.context(ctx), query, args...)
 	if err != nil {
 		return t, err
 	}
-	data, err := fn()
+	data, err := fn(ctx2)
 	if err != nil {
-		err1 := db.handleErrorHooks(ctx2, err, query, args...)
-		if err1 != nil {
-			return data, err1
-		}
-		return data, err
+		return data, db.handleErrorHooks(ctx2, err, query, args...)
 	}
 	_, err = db.handleAfterHooks(ctx2, query, args...)
 	if err != nil {
@@ -389,6 +620,40 @@ func handleTwo[T any](fn func() (T, error), db *DB, ctx context.Context, query s
 	return data, nil
 }
 
+// withHooks runs fn through the hooks of db, or directly when db is nil, as
+// for a Tx, Conn or Stmt not created from a DB.
+func withHooks[T any](db *DB, ctx context.Context, fn func(ctx context.Context) (T, error), query string, args ...any) (T, error) {
+	if db == nil {
+		return fn(ctx)
+	}
+	return handleTwo(fn, db, ctx, query, args...)
+}
+
+// rowOrErr returns row, or a Row reporting err when a hook stopped the query
+// before it ran.
+func rowOrErr(row SQLRow, err error) SQLRow {
+	if row == nil {
+		return &Row{err: err}
+	}
+	return row
+}
+
+// context returns ctx carrying the driver name and ID of db, for the hooks
+// and rewriters of its statements.
+func (db *DB) context(ctx context.Context) context.Context {
+	ctx = sqlctx.WithDriverName(ctx, db.driverName)
+	if db.ID != "" {
+		ctx = sqlctx.WithDBID(ctx, db.ID)
+	}
+	return ctx
+}
+
+// DriverNameFromContext returns the name of the driver running the statement
+// a hook or rewriter is called for.
+func DriverNameFromContext(ctx context.Context) (string, bool) {
+	return sqlctx.DriverNameFromContext(ctx)
+}
+
 // DriverName returns the driverName passed to the Open function for this DB.
 func (db *DB) DriverName() string {
 	return db.driverName
@@ -420,17 +685,28 @@ func (db *DB) Stats() sql.DBStats {
 }
 
 // Open is the same as sql.Open, but returns an *sqlx.DB instead.
-func Open(driverName, dataSourceName, id string) (*DB, error) {
-	db, err := sql.Open(driverName, dataSourceName)
+func Open(driverName, dataSourceName, id string, opts ...DBOption) (*DB, error) {
+	var db *sql.DB
+	var err error
+	o := loadDBOptions(opts)
+	init := o.connInit
+	if o.timePolicy != nil && o.timePolicy.SessionZone != nil {
+		init = sessionZoneInit(driverName, o.timePolicy.SessionZone, init)
+	}
+	if init != nil {
+		db, err = openInit(driverName, dataSourceName, init)
+	} else {
+		db, err = sql.Open(driverName, dataSourceName)
+	}
 	if err != nil {
 		return nil, err
 	}
-	return &DB{SQLDB: WrapSQLDB(db), driverName: driverName, Mapper: mapper(), ID: id}, err
+	return newDB(WrapSQLDB(db), driverName, id, opts), err
 }
 
 // MustOpen is the same as sql.Open, but returns an *sqlx.DB instead and panics on error.
-func MustOpen(driverName, dataSourceName, id string) *DB {
-	db, err := Open(driverName, dataSourceName, id)
+func MustOpen(driverName, dataSourceName, id string, opts ...DBOption) *DB {
+	db, err := Open(driverName, dataSourceName, id, opts...)
 	if err != nil {
 		panic(err)
 	}
@@ -451,9 +727,13 @@ func (db *DB) Rebind(query string) string {
 // Unsafe returns a version of DB which will silently succeed to scan when
 // columns in the SQL result have no fields in the destination struct.
 // sqlx.Stmt and sqlx.Tx which are created from this DB will inherit its
-// safety behavior.
+// safety behavior. The returned DB keeps the ID, hooks and row transformers
+// of db; hooks registered on either of them afterwards are not shared.
 func (db *DB) Unsafe() *DB {
-	return &DB{SQLDB: db.SQLDB, driverName: db.driverName, unsafe: true, Mapper: db.Mapper}
+	u := *db
+	u.unsafe = true
+	u.hooks = db.hooks.fork()
+	return &u
 }
 
 // BindNamed binds a query using the DB driver's bindvar type.
@@ -471,102 +751,62 @@ func (db *DB) NamedQuery(query string, arg any) (*Rows, error) {
 // NamedSelect using this DB.
 // Any named placeholder parameters are replaced with fields from arg.
 func (db *DB) NamedSelect(dest any, query string, arg any) error {
-	query = SanitizeQuery(query, arg)
-	if !IsNamedQuery(query) {
-		return db.Select(dest, query, arg)
-	}
-	rows, err := NamedQuery(db, query, arg)
-	if err != nil {
-		return err
-	}
-	// if something happens here, we want to make sure the rows are Closed
-	defer rows.Close()
-	return ScannAll(rows, dest, false)
+	return db.NamedSelectContext(context.Background(), dest, query, arg)
 }
 
 // NamedExec using this DB.
 // Any named placeholder parameters are replaced with fields from arg.
 func (db *DB) NamedExec(query string, arg any) (sql.Result, error) {
-	query = SanitizeQuery(query, arg)
-	fn := func() (sql.Result, error) {
-		return NamedExec(db, query, arg)
-	}
-	return handleTwo[sql.Result](fn, db, context.Background(), query, arg)
+	return db.NamedExecContext(context.Background(), query, arg)
+}
+
+// Exec executes a query without returning any rows, running the hooks of db
+// around it.
+//
+// Exec uses context.Background internally; to specify the context, use
+// ExecContext.
+func (db *DB) Exec(query string, args ...any) (sql.Result, error) {
+	return db.ExecContext(context.Background(), query, args...)
 }
 
 func (db *DB) NamedGet(dest any, query string, arg any) error {
-	query = SanitizeQuery(query, arg)
-	matches := InReg.FindAllStringSubmatch(query, -1)
-	if len(matches) > 0 {
-		query, arg = prepareNamedInQuery(query, arg)
-		q, p, err := bindNamedMapper(BindType(db.DriverName()), query, arg, mapperFor(db))
-		if err != nil {
-			return err
-		}
-		r := db.QueryRowx(q, p...)
-		return r.scanAny(dest, false)
-	}
-	q, p, err := bindNamedMapper(BindType(db.DriverName()), query, arg, mapperFor(db))
-	if err != nil {
-		return err
-	}
-	r := db.QueryRowx(q, p...)
-	return r.scanAny(dest, false)
+	return db.NamedGetContext(context.Background(), dest, query, arg)
 }
 
 // Select using this DB.
 // Any placeholder parameters are replaced with supplied args.
 func (db *DB) Select(dest any, query string, arguments ...any) error {
-	var args []any
-	if len(arguments) > 0 && arguments[0] != nil {
-		switch ag := arguments[0].(type) {
-		case map[string]any:
-			if len(ag) > 0 {
-				args = arguments
-			}
-		case map[string]string:
-			if len(ag) > 0 {
-				args = arguments
-			}
-		default:
-			args = arguments
-		}
-	}
-	query = SanitizeQuery(query, args...)
-	t := reflect.TypeOf(dest)
-	if t.Kind() != reflect.Ptr {
-		return errors.New("must pass a pointer, not a value, to StructScan destination")
-	}
-
-	if t.Elem().Kind() != reflect.Slice {
-		if IsNamedQuery(query) && len(args) > 0 {
-			return db.NamedGet(dest, query, args[0])
-		}
-		matches := InReg.FindAllStringSubmatch(query, -1)
-		if len(matches) > 0 {
-			return db.InGet(dest, query, args...)
-		}
-		return Get(db, dest, query, args...)
-	}
-	if IsNamedQuery(query) && len(args) > 0 {
-		return db.NamedSelect(dest, query, args[0])
-	}
-	matches := InReg.FindAllStringSubmatch(query, -1)
-	if len(matches) > 0 {
-		return InSelect(db, dest, query, args...)
-	}
-	return Select(db, dest, query, args...)
+	return db.SelectContext(context.Background(), dest, query, arguments...)
 }
 
 // ExecWithReturn executes an SQL statement (INSERT, UPDATE, DELETE) and appends "RETURNING *".
 func (db *DB) ExecWithReturn(query string, args any) error {
+	return db.ExecWithReturnContext(context.Background(), query, args)
+}
+
+// ExecWithReturnContext is like ExecWithReturn but uses the provided context.
+func (db *DB) ExecWithReturnContext(ctx context.Context, query string, args any) error {
+	return db.execWithReturn(ctx, query, args, db.SelectContext)
+}
+
+// execWithReturn runs query returning the rows it writes into args with
+// selectContext.
+func (db *DB) execWithReturn(ctx context.Context, query string, args any, selectContext func(ctx context.Context, dest any, query string, args ...any) error) error {
 	query = SanitizeQuery(query, args)
 	v := reflect.ValueOf(args)
 	if v.Kind() != reflect.Ptr {
 		return fmt.Errorf("args need to be pointer of map or struct, got %T", args)
 	}
+	// SQL Server returns the written rows with an OUTPUT clause.
+	if Dialect(db.driverName) == DialectMSSQL {
+		query = WithOutput(query)
+	} else if db.SupportsReturningContext(ctx) {
+		query = WithReturning(query)
+	} else {
+		return fmt.Errorf("RETURNING is not supported by %s", db.driverName)
+	}
 	value := v.Elem().Interface()
-	if err := db.Select(args, WithReturning(query), value); err != nil {
+	if err := selectContext(ctx, args, query, value); err != nil {
 		return err
 	}
 	return nil
@@ -601,16 +841,19 @@ func LazySelect[T any](db *DB, query string) func(args ...any) (T, error) {
 }
 
 func SelectTyped[T any](db *DB, query string, args ...any) (T, error) {
+	return SelectTypedContext[T](context.Background(), db, query, args...)
+}
+
+// SelectTypedContext is like SelectTyped but uses the provided context.
+// Non-slice destinations are limited to a single row, as by SelectContext.
+func SelectTypedContext[T any](ctx context.Context, db *DB, query string, args ...any) (T, error) {
 	var t T
-	val := reflect.TypeOf(t)
-	if val.Kind() != reflect.Slice {
-		query = LimitQuery(query)
-	}
-	if val.Kind() == reflect.Ptr {
-		err := db.Select(t, query, args...)
+	if typ := reflect.TypeOf(t); typ != nil && typ.Kind() == reflect.Ptr {
+		t = reflect.New(typ.Elem()).Interface().(T)
+		err := db.SelectContext(ctx, t, query, args...)
 		return t, err
 	}
-	err := db.Select(&t, query, args...)
+	err := db.SelectContext(ctx, &t, query, args...)
 	return t, err
 }
 
@@ -656,11 +899,7 @@ func SelectEach[T any](db *DB, callback func(row T) error, query string, args ..
 // Any placeholder parameters are replaced with supplied args.
 // An error is returned if the result set is empty.
 func (db *DB) Get(dest any, query string, args ...any) error {
-	matches := InReg.FindAllStringSubmatch(query, -1)
-	if len(matches) > 0 {
-		return InGet(db, dest, query, args...)
-	}
-	return Get(db, dest, query, args...)
+	return db.GetContext(context.Background(), dest, query, args...)
 }
 
 // MustBegin starts a transaction, and panics on error.  Returns an *sqlx.Tx instead
@@ -675,11 +914,7 @@ func (db *DB) MustBegin() *Tx {
 
 // Beginx begins a transaction and returns an *sqlx.Tx instead of an *sql.Tx.
 func (db *DB) Beginx() (*Tx, error) {
-	tx, err := db.SQLDB.Begin()
-	if err != nil {
-		return nil, err
-	}
-	return &Tx{SQLTx: tx, driverName: db.driverName, unsafe: db.unsafe, Mapper: db.Mapper}, err
+	return db.BeginTxx(context.Background(), nil)
 }
 
 // Begin starts a transaction and do the given handle. The default isolation level
@@ -688,7 +923,7 @@ func (db *DB) Beginx() (*Tx, error) {
 // With uses context.Background internally; to specify the context, use
 // With Tx.
 func (db *DB) With(handle func(tx SQLTx) error) error {
-	tx, err := db.Begin()
+	tx, err := db.Beginx()
 	if err != nil {
 		return err
 	}
@@ -710,7 +945,7 @@ func (db *DB) With(handle func(tx SQLTx) error) error {
 // If a non-default isolation level is used that the driver doesn't support,
 // an error will be returned.
 func (db *DB) WithTx(ctx context.Context, opts *sql.TxOptions, handle func(tx SQLTx) error) error {
-	tx, err := db.BeginTx(ctx, opts)
+	tx, err := db.BeginTxx(ctx, opts)
 	if err != nil {
 		return err
 	}
@@ -756,8 +991,16 @@ func (db *DB) WithTxx(ctx context.Context, opts *sql.TxOptions, handle func(tx *
 // In expands slice values in args, returning the modified query string
 // and a new arg list that can be executed by a database. The `query` should
 // use the `?` bindVar.  The return value uses had rebinded bindvar type.
+// On SQL Server, which lacks row-value IN, `(a, b) IN (?)` bound to a slice
+// of tuples becomes a chain of ORed equalities.
 func (db *DB) In(query string, args ...any) (string, []any, error) {
 	query = SanitizeQuery(query, args...)
+	if Dialect(db.driverName) == DialectMSSQL {
+		var err error
+		if query, args, err = rewriteTupleIn(query, args); err != nil {
+			return "", nil, err
+		}
+	}
 	q, params, err := In(query, args...)
 	if err != nil {
 		return "", nil, err
@@ -771,75 +1014,48 @@ func (db *DB) In(query string, args ...any) (string, []any, error) {
 // InExec uses context.Background internally; to specify the context, use
 // ExecContext.
 func (db *DB) InExec(query string, args ...any) (sql.Result, error) {
-	query = SanitizeQuery(query, args...)
-	fn := func() (sql.Result, error) {
-		return InExec(db, query, args...)
-	}
-	return handleTwo[sql.Result](fn, db, context.Background(), query, args...)
+	return db.InExecContext(context.Background(), query, args...)
 }
 
 // InSelect using this DB but for in.
 // Any placeholder parameters are replaced with supplied args.
 func (db *DB) InSelect(dest any, query string, args ...any) error {
-	query = SanitizeQuery(query, args...)
-	return InSelect(db, dest, query, args...)
+	return db.InSelectContext(context.Background(), dest, query, args...)
 }
 
 // InGet using this DB but for in.
 // Any placeholder parameters are replaced with supplied args.
 // An error is returned if the result set is empty.
 func (db *DB) InGet(dest any, query string, args ...any) error {
-	query = SanitizeQuery(query, args...)
-	return InGet(db, dest, query, args...)
+	return db.InGetContext(context.Background(), dest, query, args...)
 }
 
 // Queryx queries the database and returns an *sqlx.Rows.
 // Any placeholder parameters are replaced with supplied args.
 func (db *DB) Queryx(query string, args ...any) (*Rows, error) {
-	query = SanitizeQuery(query, args...)
-	fn := func() (*Rows, error) {
-		r, err := db.SQLDB.Query(query, args...)
-		if err != nil {
-			return nil, err
-		}
-		return &Rows{SQLRows: r, unsafe: db.unsafe, Mapper: db.Mapper}, err
-	}
-	return handleTwo[*Rows](fn, db, context.Background(), query, args...)
+	return db.QueryxContext(context.Background(), query, args...)
 }
 
 // QueryRowx queries the database and returns an *sqlx.Row.
 // Any placeholder parameters are replaced with supplied args.
 func (db *DB) QueryRowx(query string, args ...any) *Row {
-	query = SanitizeQuery(query, args...)
-	fn := func() (*Row, error) {
-		rows, err := db.SQLDB.Query(query, args...)
-		return &Row{rows: rows, err: err, unsafe: db.unsafe, Mapper: db.Mapper}, err
-	}
-	row, _ := handleTwo[*Row](fn, db, context.Background(), query, args...)
-	return row
+	return db.QueryRowxContext(context.Background(), query, args...)
 }
 
 // MustExec (panic) runs MustExec using this database.
 // Any placeholder parameters are replaced with supplied args.
 func (db *DB) MustExec(query string, args ...any) sql.Result {
-	query = SanitizeQuery(query, args...)
-	fn := func() (sql.Result, error) {
-		return MustExec(db, query, args...), nil
-	}
-	row, _ := handleTwo[sql.Result](fn, db, context.Background(), query, args...)
-	return row
+	return db.MustExecContext(context.Background(), query, args...)
 }
 
 // MustInExec (panic) runs MustExec using this database for in.
 // Any placeholder parameters are replaced with supplied args.
 func (db *DB) MustInExec(query string, args ...any) sql.Result {
-	query = SanitizeQuery(query, args...)
-	fn := func() (sql.Result, error) {
-		return MustInExec(db, query, args...), nil
+	res, err := db.InExecContext(context.Background(), query, args...)
+	if err != nil {
+		panic(err)
 	}
-	row, _ := handleTwo[sql.Result](fn, db, context.Background(), query, args...)
-	return row
-
+	return res
 }
 
 // Preparex returns an sqlx.Stmt instead of a sql.Stmt
@@ -858,6 +1074,7 @@ type Conn struct {
 	driverName string
 	unsafe     bool
 	Mapper     *reflectx.Mapper
+	db         *DB
 }
 
 // Tx is an sqlx wrapper around sql.Tx with extra functionality
@@ -866,6 +1083,7 @@ type Tx struct {
 	driverName string
 	unsafe     bool
 	Mapper     *reflectx.Mapper
+	state      *txState
 }
 
 // DriverName returns the driverName used by the DB which began this transaction.
@@ -881,7 +1099,7 @@ func (tx *Tx) Rebind(query string) string {
 // Unsafe returns a version of Tx which will silently succeed to scan when
 // columns in the SQL result have no fields in the destination struct.
 func (tx *Tx) Unsafe() *Tx {
-	return &Tx{SQLTx: tx.SQLTx, driverName: tx.driverName, unsafe: true, Mapper: tx.Mapper}
+	return &Tx{SQLTx: tx.SQLTx, driverName: tx.driverName, unsafe: true, Mapper: tx.Mapper, state: tx.state}
 }
 
 // BindNamed binds a query within a transaction's bindvar type.
@@ -900,19 +1118,19 @@ func (tx *Tx) NamedQuery(query string, arg any) (*Rows, error) {
 func (tx *Tx) NamedGet(dest any, query string, arg any) error {
 	matches := InReg.FindAllStringSubmatch(query, -1)
 	if len(matches) > 0 {
-		query, arg = prepareNamedInQuery(query, arg)
+		query, arg = prepareNamedInQuery(query, arg, mapperFor(tx))
 		q, p, err := bindNamedMapper(BindType(tx.DriverName()), query, arg, mapperFor(tx))
 		if err != nil {
 			return err
 		}
-		r := tx.QueryRowx(q, p...)
+		r := tx.QueryRowx(limitOne(tx, q), p...)
 		return r.scanAny(dest, false)
 	}
 	q, p, err := bindNamedMapper(BindType(tx.DriverName()), query, arg, mapperFor(tx))
 	if err != nil {
 		return err
 	}
-	r := tx.QueryRowx(q, p...)
+	r := tx.QueryRowx(limitOne(tx, q), p...)
 	return r.scanAny(dest, false)
 }
 
@@ -939,6 +1157,12 @@ func (tx *Tx) NamedExec(query string, arg any) (sql.Result, error) {
 // and a new arg list that can be executed by a database. The `query` should
 // use the `?` bindVar.  The return value uses had rebinded bindvar type.
 func (tx *Tx) In(query string, args ...any) (string, []any, error) {
+	if Dialect(tx.driverName) == DialectMSSQL {
+		var err error
+		if query, args, err = rewriteTupleIn(query, args); err != nil {
+			return "", nil, err
+		}
+	}
 	q, params, err := In(query, args...)
 	if err != nil {
 		return "", nil, err
@@ -952,7 +1176,7 @@ func (tx *Tx) In(query string, args ...any) (string, []any, error) {
 // Exec uses context.Background internally; to specify the context, use
 // ExecContext.
 func (tx *Tx) InExec(query string, args ...any) (sql.Result, error) {
-	return InExec(tx, query, args...)
+	return tx.InExecContext(context.Background(), query, args...)
 }
 
 // InSelect within a transaction for in.
@@ -974,21 +1198,36 @@ func (tx *Tx) Select(dest any, query string, args ...any) error {
 	return Select(tx, dest, query, args...)
 }
 
+// Query runs a query within the transaction.
+func (tx *Tx) Query(query string, args ...any) (SQLRows, error) {
+	return tx.QueryContext(context.Background(), query, args...)
+}
+
+// QueryRow runs a query expected to return one row within the transaction.
+func (tx *Tx) QueryRow(query string, args ...any) SQLRow {
+	return tx.QueryRowContext(context.Background(), query, args...)
+}
+
+// Exec runs a statement that returns no rows within the transaction.
+func (tx *Tx) Exec(query string, args ...any) (sql.Result, error) {
+	return tx.ExecContext(context.Background(), query, args...)
+}
+
 // Queryx within a transaction.
 // Any placeholder parameters are replaced with supplied args.
 func (tx *Tx) Queryx(query string, args ...any) (*Rows, error) {
-	r, err := tx.SQLTx.Query(query, args...)
+	r, err := tx.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
-	return &Rows{SQLRows: r, unsafe: tx.unsafe, Mapper: tx.Mapper}, err
+	return &Rows{SQLRows: r, unsafe: tx.unsafe, Mapper: tx.Mapper, table: tx.hookDB().statementTable(query), transformers: tx.hookDB().rowTransformers(), times: tx.hookDB().timePolicy(), traversals: tx.hookDB().traversalCache()}, err
 }
 
 // QueryRowx within a transaction.
 // Any placeholder parameters are replaced with supplied args.
 func (tx *Tx) QueryRowx(query string, args ...any) *Row {
-	rows, err := tx.SQLTx.Query(query, args...)
-	return &Row{rows: rows, err: err, unsafe: tx.unsafe, Mapper: tx.Mapper}
+	rows, err := tx.Query(query, args...)
+	return &Row{rows: rows, err: err, unsafe: tx.unsafe, Mapper: tx.Mapper, table: tx.hookDB().statementTable(query), transformers: tx.hookDB().rowTransformers(), times: tx.hookDB().timePolicy(), traversals: tx.hookDB().traversalCache()}
 }
 
 // Get within a transaction.
@@ -1007,7 +1246,11 @@ func (tx *Tx) MustExec(query string, args ...any) sql.Result {
 // MustInExec runs MustExec within a transaction for in.
 // Any placeholder parameters are replaced with supplied args.
 func (tx *Tx) MustInExec(query string, args ...any) sql.Result {
-	return MustInExec(tx, query, args...)
+	res, err := tx.InExecContext(context.Background(), query, args...)
+	if err != nil {
+		panic(err)
+	}
+	return res
 }
 
 // Preparex  a statement within a transaction.
@@ -1019,19 +1262,20 @@ func (tx *Tx) Preparex(query string) (*Stmt, error) {
 // stmt can be either *sql.Stmt or *sqlx.Stmt.
 func (tx *Tx) Stmtx(stmt any) *Stmt {
 	var s SQLStmt
+	var query string
 	switch v := stmt.(type) {
-	case SQLStmt:
-		s = v
 	case Stmt:
-		s = v.SQLStmt
+		s, query = v.SQLStmt, v.query
 	case *Stmt:
-		s = v.SQLStmt
+		s, query = v.SQLStmt, v.query
+	case SQLStmt:
+		s = v
 	case *sql.Stmt:
 		s = &sqlStmtWrapper{stmt: v}
 	default:
 		panic(fmt.Sprintf("non-statement type %v passed to Stmtx", reflect.ValueOf(stmt).Type()))
 	}
-	return &Stmt{SQLStmt: tx.Stmt(s), Mapper: tx.Mapper}
+	return &Stmt{SQLStmt: tx.Stmt(s), Mapper: tx.Mapper, db: tx.hookDB(), tx: tx, query: query}
 }
 
 // NamedStmt returns a version of the prepared statement which runs within a transaction.
@@ -1053,12 +1297,16 @@ type Stmt struct {
 	SQLStmt
 	unsafe bool
 	Mapper *reflectx.Mapper
+	// db runs its hooks around executions of query, within tx if set
+	db    *DB
+	tx    *Tx
+	query string
 }
 
 // Unsafe returns a version of Stmt which will silently succeed to scan when
 // columns in the SQL result have no fields in the destination struct.
 func (s *Stmt) Unsafe() *Stmt {
-	return &Stmt{SQLStmt: s.SQLStmt, unsafe: true, Mapper: s.Mapper}
+	return &Stmt{SQLStmt: s.SQLStmt, unsafe: true, Mapper: s.Mapper, db: s.db, tx: s.tx, query: s.query}
 }
 
 // Select using the prepared statement.
@@ -1095,6 +1343,21 @@ func (s *Stmt) Queryx(args ...any) (*Rows, error) {
 	return qs.Queryx("", args...)
 }
 
+// Query executes the statement through the hooks of the DB it was prepared on.
+func (s *Stmt) Query(args ...any) (SQLRows, error) {
+	return s.QueryContext(context.Background(), args...)
+}
+
+// QueryRow executes the statement, expecting at most one row.
+func (s *Stmt) QueryRow(args ...any) SQLRow {
+	return s.QueryRowContext(context.Background(), args...)
+}
+
+// Exec executes the statement, returning no rows.
+func (s *Stmt) Exec(args ...any) (sql.Result, error) {
+	return s.ExecContext(context.Background(), args...)
+}
+
 // qStmt is an unexposed wrapper which lets you use a Stmt as a Queryer & Execer by
 // implementing those interfaces and ignoring the `query` argument.
 type qStmt struct{ *Stmt }
@@ -1108,12 +1371,12 @@ func (q *qStmt) Queryx(query string, args ...any) (*Rows, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Rows{SQLRows: r, unsafe: q.Stmt.unsafe, Mapper: q.Stmt.Mapper}, err
+	return &Rows{SQLRows: r, unsafe: q.Stmt.unsafe, Mapper: q.Stmt.Mapper, table: q.Stmt.db.statementTable(q.Stmt.query), transformers: q.Stmt.db.rowTransformers(), times: q.Stmt.db.timePolicy(), traversals: q.Stmt.db.traversalCache()}, err
 }
 
 func (q *qStmt) QueryRowx(query string, args ...any) *Row {
 	rows, err := q.Stmt.Query(args...)
-	return &Row{rows: rows, err: err, unsafe: q.Stmt.unsafe, Mapper: q.Stmt.Mapper}
+	return &Row{rows: rows, err: err, unsafe: q.Stmt.unsafe, Mapper: q.Stmt.Mapper, table: q.Stmt.db.statementTable(q.Stmt.query), transformers: q.Stmt.db.rowTransformers(), times: q.Stmt.db.timePolicy(), traversals: q.Stmt.db.traversalCache()}
 }
 
 func (q *qStmt) Exec(query string, args ...any) (sql.Result, error) {
@@ -1126,6 +1389,13 @@ type Rows struct {
 	SQLRows
 	unsafe bool
 	Mapper *reflectx.Mapper
+	// table and transformers post-process rows scanned into maps
+	table        string
+	transformers []RowTransformer
+	// times converts the times scanned into maps
+	times *TimePolicy
+	// traversals caches the struct traversals of the DB queried
+	traversals *traversalCache
 	// these fields cache memory use for a rows during iteration w/ structScan
 	started bool
 	fields  [][]int
@@ -1138,15 +1408,20 @@ func (r *Rows) SliceScan() ([]any, error) {
 }
 
 // MapScan using this Rows.
-func (r *Rows) MapScan(dest map[string]any) error {
-	return MapScan(r, dest)
+func (r *Rows) MapScan(dest map[string]any, opts ...ScanOption) error {
+	if err := MapScan(r, dest, opts...); err != nil {
+		return err
+	}
+	return transformRow(r, dest)
 }
 
 // prepareValues prepare values slice
 func prepareValues(values []any, columnTypes []*sql.ColumnType, columns []string) {
 	if len(columnTypes) > 0 {
 		for idx, columnType := range columnTypes {
-			if columnType.ScanType() != nil {
+			if newValue, ok := registeredScanType(columnTypes, idx); ok {
+				values[idx] = newValue()
+			} else if columnType.ScanType() != nil {
 				values[idx] = reflect.New(reflect.PtrTo(columnType.ScanType())).Interface()
 			} else {
 				values[idx] = new(any)
@@ -1178,9 +1453,7 @@ func (r *Rows) StructScan(dest any) error {
 		if err != nil {
 			return err
 		}
-		m := r.Mapper
-
-		r.fields = m.TraversalsByName(v.Type(), columns)
+		r.fields = r.traversals.traversals(r.Mapper, v.Type(), columns)
 		// if we are not unsafe and are missing fields, return an error
 		/*if f, err := missingFields(r.fields); err != nil && !r.unsafe {
 			return fmt.Errorf("missing destination name %s in %T", columns[f], dest)
@@ -1189,7 +1462,8 @@ func (r *Rows) StructScan(dest any) error {
 		r.started = true
 	}
 
-	octx := reflectx.NewObjectContext()
+	octx := reflectx.AcquireObjectContext()
+	defer octx.Release()
 	err := fieldsByTraversal(octx, v, r.fields, r.values, true)
 	if err != nil {
 		return err
@@ -1202,9 +1476,19 @@ func (r *Rows) StructScan(dest any) error {
 	return r.Err()
 }
 
+// NextResultSet prepares the next result set of a multi-result-set query,
+// such as a stored procedure, for reading. It reports whether there is one;
+// the rows of the current result set are discarded.
+func (r *Rows) NextResultSet() bool {
+	r.started = false
+	r.fields = nil
+	r.values = nil
+	return nextResultSet(r.SQLRows)
+}
+
 // ConnectExist is the same as Connect, but using already opened connection.
-func ConnectExist(driverName string, raw *sql.DB) (*DB, error) {
-	db := OpenExist(driverName, raw)
+func ConnectExist(driverName string, raw *sql.DB, opts ...DBOption) (*DB, error) {
+	db := OpenExist(driverName, raw, opts...)
 	err := db.Ping()
 	if err != nil {
 		db.Close()
@@ -1214,8 +1498,8 @@ func ConnectExist(driverName string, raw *sql.DB) (*DB, error) {
 }
 
 // Connect to a database and verify with a ping.
-func Connect(driverName, dataSourceName, id string) (*DB, error) {
-	db, err := Open(driverName, dataSourceName, id)
+func Connect(driverName, dataSourceName, id string, opts ...DBOption) (*DB, error) {
+	db, err := Open(driverName, dataSourceName, id, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -1228,8 +1512,8 @@ func Connect(driverName, dataSourceName, id string) (*DB, error) {
 }
 
 // MustConnect connects to a database and panics on error.
-func MustConnect(driverName, dataSourceName, id string) *DB {
-	db, err := Connect(driverName, dataSourceName, id)
+func MustConnect(driverName, dataSourceName, id string, opts ...DBOption) *DB {
+	db, err := Connect(driverName, dataSourceName, id, opts...)
 	if err != nil {
 		panic(err)
 	}
@@ -1238,11 +1522,15 @@ func MustConnect(driverName, dataSourceName, id string) *DB {
 
 // Preparex prepares a statement.
 func Preparex(p Preparer, query string) (*Stmt, error) {
-	s, err := p.Prepare(query)
+	prepared, err := hooksFor(p).rewritePrepared(context.Background(), query)
+	if err != nil {
+		return nil, err
+	}
+	s, err := p.Prepare(prepared)
 	if err != nil {
 		return nil, err
 	}
-	return &Stmt{SQLStmt: s, unsafe: isUnsafe(p), Mapper: mapperFor(p)}, err
+	return &Stmt{SQLStmt: s, unsafe: isUnsafe(p), Mapper: mapperFor(p), db: hooksFor(p), tx: txFor(p), query: query}, err
 }
 
 // Select executes a query using the provided Queryer, and StructScans each row
@@ -1261,6 +1549,39 @@ func Select(q Queryer, dest any, query string, args ...any) error {
 	return ScannAll(rows, dest, false)
 }
 
+// ErrMissingResultSet is returned by SelectMulti when the query returns fewer
+// result sets than destinations.
+var ErrMissingResultSet = errors.New("squealx: query returned fewer result sets than destinations")
+
+// SelectMulti executes a query returning several result sets, such as a
+// stored procedure on MSSQL or MySQL, and scans each result set into the
+// dest at the same position like Select does. The *sql.Rows are closed
+// automatically.
+func SelectMulti(q Queryer, query string, dests ...any) error {
+	rows, err := q.Queryx(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	return scanResultSets(rows, dests)
+}
+
+// scanResultSets scans each result set of rows into the matching dest.
+func scanResultSets(rows *Rows, dests []any) error {
+	for i, dest := range dests {
+		if i > 0 && !rows.NextResultSet() {
+			if err := rows.Err(); err != nil {
+				return err
+			}
+			return fmt.Errorf("%w: got %d, want %d", ErrMissingResultSet, i, len(dests))
+		}
+		if err := ScannAll(rows, dest, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Get does a QueryRow using the provided Queryer, and scans the resulting row
 // to dest.  If dest is scannable, the result must only have one column.  Otherwise,
 // StructScan is used.  Get will return sql.ErrNoRows like row.Scan would.
@@ -1268,7 +1589,7 @@ func Select(q Queryer, dest any, query string, args ...any) error {
 // An error is returned if the result set is empty.
 func Get(q Queryer, dest any, query string, args ...any) error {
 	query = SanitizeQuery(query, args...)
-	r := q.QueryRowx(query, args...)
+	r := q.QueryRowx(limitOne(q, query), args...)
 	return r.scanAny(dest, false)
 }
 
@@ -1312,22 +1633,26 @@ func InGet(q QueryIn, dest any, query string, args ...any) error {
 // is not suitable for loading large data dumps, but can be useful for initializing
 // schemas or loading indexes.
 //
+// With LoadInTx, LoadProgress or LoadContinueOnError, the file is split into
+// its statements, run one by one, and the result is that of the last one
+// succeeding; this also works for drivers not supporting multi-statement
+// Execs. See LoadFileContext.
+//
 // FIXME: this does not really work with multi-statement files for mattn/go-sqlite3
 // or the go-mysql-driver/mysql drivers;  pq seems to be an exception here.  Detecting
 // this by requiring something with DriverName() and then attempting to split the
 // queries will be difficult to get right, and its current driver-specific behavior
 // is deemed at least not complex in its incorrectness.
-func LoadFile(e Execer, path string) (*sql.Result, error) {
-	realpath, err := filepath.Abs(path)
+func LoadFile(e Execer, path string, opts ...LoadOption) (*sql.Result, error) {
+	contents, err := readLoadFile(path)
 	if err != nil {
 		return nil, err
 	}
-	contents, err := os.ReadFile(realpath)
-	if err != nil {
-		return nil, err
+	ec, ok := e.(ExecerContext)
+	if !ok {
+		ec = execerContext{Execer: e}
 	}
-	res, err := e.Exec(string(contents))
-	return &res, err
+	return loadFile(context.Background(), ec, contents, loadLoadOptions(opts...))
 }
 
 func handleRawValue(idx int, values []any, option ...ScanOption) (data any) {
@@ -1395,8 +1720,11 @@ func (r *Row) SliceScan() ([]any, error) {
 }
 
 // MapScan using this Rows.
-func (r *Row) MapScan(dest map[string]any) error {
-	return MapScan(r, dest)
+func (r *Row) MapScan(dest map[string]any, opts ...ScanOption) error {
+	if err := MapScan(r, dest, opts...); err != nil {
+		return err
+	}
+	return transformRow(r, dest)
 }
 
 func (r *Row) scanAny(dest any, structOnly bool) error {
@@ -1449,10 +1777,10 @@ func (r *Row) scanAny(dest any, structOnly bool) error {
 			}
 			for i, colName := range columns {
 				val := columnPointers[i].(*any)
-				t := bytesToAny(*val, colTypes[i].DatabaseTypeName())
+				t := bytesToAny(*val, colTypes[i].DatabaseTypeName(), rowsTimePolicy(r))
 				(*dest)[colName] = t
 			}
-			return nil
+			return transformRow(r, *dest)
 		}
 	}
 	if scannable && len(columns) > 1 {
@@ -1463,16 +1791,15 @@ func (r *Row) scanAny(dest any, structOnly bool) error {
 		return r.Scan(dest)
 	}
 
-	m := r.Mapper
-
-	fields := m.TraversalsByName(v.Type(), columns)
+	fields := r.traversals.traversals(r.Mapper, v.Type(), columns)
 	// if we are not unsafe and are missing fields, return an error
 	/*if f, err := missingFields(fields); err != nil && !r.unsafe {
 		return fmt.Errorf("missing destination name %s in %T", columns[f], dest)
 	}*/
 	values := make([]any, len(columns))
 
-	octx := reflectx.NewObjectContext()
+	octx := reflectx.AcquireObjectContext()
+	defer octx.Release()
 
 	err = fieldsByTraversal(octx, v, fields, values, true)
 	if err != nil {
@@ -1509,16 +1836,7 @@ func SliceScan(r ColScanner) ([]any, error) {
 		return nil, err
 	}
 	for idx := range columns {
-		if reflectValue := reflect.Indirect(reflect.Indirect(reflect.ValueOf(values[idx]))); reflectValue.IsValid() {
-			values[idx] = reflectValue.Interface()
-			if valuer, ok := values[idx].(driver.Valuer); ok {
-				values[idx], _ = valuer.Value()
-			} else if b, ok := values[idx].(sql.RawBytes); ok {
-				values[idx] = string(b)
-			}
-		} else {
-			values[idx] = nil
-		}
+		values[idx] = rowsTimePolicy(r).scanned(columnTypes, idx, scannedValue(columnTypes, idx, values[idx]))
 	}
 	return values, r.Err()
 }
@@ -1529,13 +1847,18 @@ func SliceScan(r ColScanner) ([]any, error) {
 // executes SQL from input).  Please do not use this as a primary interface!
 // This will modify the map sent to it in place, so reuse the same map with
 // care.  Columns which occur more than once in the result will overwrite
-// each other!
-func MapScan(r ColScanner, dest map[string]any) error {
+// each other, unless SuffixDuplicateColumns or ErrorOnDuplicateColumns is
+// given!
+func MapScan(r ColScanner, dest map[string]any, opts ...ScanOption) error {
 	// ignore r.started, since we needn't use reflect for anything.
 	columns, err := r.Columns()
 	if err != nil {
 		return err
 	}
+	keys, err := loadScanOptions(opts...).mapKeys(columns)
+	if err != nil {
+		return err
+	}
 	columnTypes, err := r.ColumnTypes()
 	if err != nil {
 		return err
@@ -1547,21 +1870,31 @@ func MapScan(r ColScanner, dest map[string]any) error {
 	if err != nil {
 		return err
 	}
-	for idx, column := range columns {
-		if reflectValue := reflect.Indirect(reflect.Indirect(reflect.ValueOf(values[idx]))); reflectValue.IsValid() {
-			dest[column] = reflectValue.Interface()
-			if valuer, ok := dest[column].(driver.Valuer); ok {
-				dest[column], _ = valuer.Value()
-			} else if b, ok := dest[column].(sql.RawBytes); ok {
-				dest[column] = string(b)
-			}
-		} else {
-			dest[column] = nil
-		}
+	for idx := range columns {
+		dest[keys[idx]] = rowsTimePolicy(r).scanned(columnTypes, idx, scannedValue(columnTypes, idx, values[idx]))
 	}
 	return r.Err()
 }
 
+// scannedValue returns the value of column idx from v, the pointer prepared
+// for it by prepareValues, or nil when it is NULL.
+func scannedValue(columnTypes []*sql.ColumnType, idx int, v any) any {
+	if _, ok := registeredScanType(columnTypes, idx); ok {
+		return registeredValue(v)
+	}
+	reflectValue := reflect.Indirect(reflect.Indirect(reflect.ValueOf(v)))
+	if !reflectValue.IsValid() {
+		return nil
+	}
+	value := reflectValue.Interface()
+	if valuer, ok := value.(driver.Valuer); ok {
+		value, _ = valuer.Value()
+	} else if b, ok := value.(sql.RawBytes); ok {
+		value = string(b)
+	}
+	return value
+}
+
 type Rowsi interface {
 	Close() error
 	Columns() ([]string, error)
@@ -1623,9 +1956,10 @@ func ScannAll(rows Rowsi, dest any, structOnly bool) error {
 	}()
 
 	if !scannable {
-		fields := mapper.TraversalsByName(base, columns)
+		fields := rowsTraversalCache(rows).traversals(mapper, base, columns)
 		values := make([]any, len(columns))
-		octx := reflectx.NewObjectContext()
+		octx := reflectx.AcquireObjectContext()
+		defer octx.Release()
 
 		for rows.Next() {
 			vp := reflect.New(base)
@@ -1691,7 +2025,10 @@ func scanMapSlices(rows Rowsi, columns []string, colTypes []*sql.ColumnType, des
 		m := make(map[string]any)
 		for i, colName := range columns {
 			val := columnPointers[i].(*any)
-			m[colName] = bytesToAny(*val, colTypes[i].DatabaseTypeName())
+			m[colName] = bytesToAny(*val, colTypes[i].DatabaseTypeName(), rowsTimePolicy(rows))
+		}
+		if err := transformRow(rows, m); err != nil {
+			return err
 		}
 		*dest = append(*dest, m)
 	}
@@ -1711,7 +2048,10 @@ func scanAnySlices(rows Rowsi, columns []string, colTypes []*sql.ColumnType, des
 		m := make(map[string]any)
 		for i, colName := range columns {
 			val := columnPointers[i].(*any)
-			m[colName] = bytesToAny(*val, colTypes[i].DatabaseTypeName())
+			m[colName] = bytesToAny(*val, colTypes[i].DatabaseTypeName(), rowsTimePolicy(rows))
+		}
+		if err := transformRow(rows, m); err != nil {
+			return err
 		}
 		*dest = append(*dest, m)
 	}
@@ -1734,9 +2074,10 @@ func ScanEach[T any](rows Rowsi, structOnly bool, callback func(row T) error) er
 		}
 		return reflectx.NewMapperFunc("db", NameMapper)
 	}()
+	traversals := rowsTraversalCache(rows)
 
 	for rows.Next() {
-		row, err := scanRow[T](rows, columns, colTypes, mapper, structOnly)
+		row, err := scanRow[T](rows, columns, colTypes, mapper, traversals, structOnly)
 		if err != nil {
 			return err
 		}
@@ -1749,7 +2090,7 @@ func ScanEach[T any](rows Rowsi, structOnly bool, callback func(row T) error) er
 }
 
 // scanRow is a helper function that scans a single row and returns the result.
-func scanRow[T any](rows Rowsi, columns []string, colTypes []*sql.ColumnType, mapper *reflectx.Mapper, structOnly bool) (T, error) {
+func scanRow[T any](rows Rowsi, columns []string, colTypes []*sql.ColumnType, mapper *reflectx.Mapper, traversals *traversalCache, structOnly bool) (T, error) {
 	var result T
 	var base reflect.Type
 	var isPtr bool
@@ -1768,9 +2109,10 @@ func scanRow[T any](rows Rowsi, columns []string, colTypes []*sql.ColumnType, ma
 	}
 
 	if !scannable {
-		fields := mapper.TraversalsByName(base, columns)
+		fields := traversals.traversals(mapper, base, columns)
 		values := make([]any, len(columns))
-		octx := reflectx.NewObjectContext()
+		octx := reflectx.AcquireObjectContext()
+		defer octx.Release()
 
 		vp := reflect.New(base)
 		v := reflect.Indirect(vp)
@@ -1801,7 +2143,10 @@ func scanRow[T any](rows Rowsi, columns []string, colTypes []*sql.ColumnType, ma
 		m := make(map[string]any)
 		for i, colName := range columns {
 			val := columnPointers[i].(*any)
-			m[colName] = bytesToAny(*val, colTypes[i].DatabaseTypeName())
+			m[colName] = bytesToAny(*val, colTypes[i].DatabaseTypeName(), rowsTimePolicy(rows))
+		}
+		if err := transformRow(rows, m); err != nil {
+			return result, err
 		}
 		return any(m).(T), nil
 	default:
@@ -1816,7 +2161,12 @@ func scanRow[T any](rows Rowsi, columns []string, colTypes []*sql.ColumnType, ma
 	}
 }
 
-func bytesToAny(t any, colType string) any {
+// bytesToAny converts t, scanned from a column of type colType, to the Go
+// value map scans return, with its times handled as policy says.
+func bytesToAny(t any, colType string, policy *TimePolicy) any {
+	if v, ok := t.(time.Time); ok && timestampTypes[colType] {
+		return policy.convert(v)
+	}
 	if v, ok := t.([]byte); ok {
 		value := string(v)
 		switch colType {
@@ -1827,7 +2177,7 @@ func bytesToAny(t any, colType string) any {
 		case "FLOAT", "DOUBLE", "DECIMAL":
 			t, _ = strconv.ParseFloat(value, 64)
 		case "DATETIME", "TIMESTAMP":
-			t, _ = time.Parse("2006-01-02 15:04:05", value)
+			t, _ = policy.parseTime(value)
 		case "DATE":
 			t, _ = time.Parse("2006-01-02", value)
 		case "TIME":
@@ -1894,7 +2244,7 @@ func fieldsByTraversal(octx *reflectx.ObjectContext, v reflect.Value, traversals
 		}
 		f := octx.FieldForIndexes(traversal)
 		if ptrs {
-			values[i] = f.Addr().Interface()
+			values[i] = reflectx.ScanDest(f)
 		} else {
 			values[i] = f.Interface()
 		}
@@ -3,15 +3,15 @@ package squealx
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
 	"reflect"
+	"slices"
 )
 
 // ConnectContext to a database and verify with a ping.
-func ConnectContext(ctx context.Context, driverName, dataSourceName, id string) (*DB, error) {
-	db, err := Open(driverName, dataSourceName, id)
+func ConnectContext(ctx context.Context, driverName, dataSourceName, id string, opts ...DBOption) (*DB, error) {
+	db, err := Open(driverName, dataSourceName, id, opts...)
 	if err != nil {
 		return db, err
 	}
@@ -36,6 +36,12 @@ type ExecerContext interface {
 	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
 }
 
+// QueryInContext is an interface used by InGetContext and InSelectContext
+type QueryInContext interface {
+	QueryerContext
+	In(query string, args ...any) (string, []any, error)
+}
+
 // ExtContext is a union interface which can bind, query, and exec, with Context
 // used by NamedQueryContext and NamedExecContext.
 type ExtContext interface {
@@ -59,16 +65,56 @@ func SelectContext(ctx context.Context, q QueryerContext, dest any, query string
 	return ScannAll(rows, dest, false)
 }
 
+// SelectMultiContext is like SelectMulti, scanning each result set of query
+// into the dest at the same position.
+func SelectMultiContext(ctx context.Context, q QueryerContext, query string, dests ...any) error {
+	rows, err := q.QueryxContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	return scanResultSets(rows, dests)
+}
+
+// InSelectContext for in scene executes a query using the provided
+// QueryerContext, and StructScans each row into dest, which must be a slice.
+// The *sql.Rows are closed automatically.
+// Any placeholder parameters are replaced with supplied args.
+func InSelectContext(ctx context.Context, q QueryInContext, dest any, query string, args ...any) error {
+	newQuery, params, err := q.In(query, args...)
+	if err != nil {
+		return err
+	}
+	return SelectContext(ctx, q, dest, newQuery, params...)
+}
+
+// InGetContext for in scene does a QueryRow using the provided
+// QueryerContext, and scans the resulting row to dest.
+// Any placeholder parameters are replaced with supplied args.
+// An error is returned if the result set is empty.
+func InGetContext(ctx context.Context, q QueryInContext, dest any, query string, args ...any) error {
+	query = SanitizeQuery(query, args...)
+	newQuery, params, err := q.In(query, args...)
+	if err != nil {
+		return err
+	}
+	return GetContext(ctx, q, dest, newQuery, params...)
+}
+
 // PreparexContext prepares a statement.
 //
 // The provided context is used for the preparation of the statement, not for
 // the execution of the statement.
 func PreparexContext(ctx context.Context, p PreparerContext, query string) (*Stmt, error) {
-	s, err := p.PrepareContext(ctx, query)
+	prepared, err := hooksFor(p).rewritePrepared(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	s, err := p.PrepareContext(ctx, prepared)
 	if err != nil {
 		return nil, err
 	}
-	return &Stmt{SQLStmt: s, unsafe: isUnsafe(p), Mapper: mapperFor(p)}, err
+	return &Stmt{SQLStmt: s, unsafe: isUnsafe(p), Mapper: mapperFor(p), db: hooksFor(p), tx: txFor(p), query: query}, err
 }
 
 // GetContext does a QueryRow using the provided Queryer, and scans the
@@ -77,7 +123,7 @@ func PreparexContext(ctx context.Context, p PreparerContext, query string) (*Stm
 // row.Scan would. Any placeholder parameters are replaced with supplied args.
 // An error is returned if the result set is empty.
 func GetContext(ctx context.Context, q QueryerContext, dest any, query string, args ...any) error {
-	r := q.QueryRowxContext(ctx, query, args...)
+	r := q.QueryRowxContext(ctx, limitOne(q, query), args...)
 	return r.scanAny(dest, false)
 }
 
@@ -87,22 +133,31 @@ func GetContext(ctx context.Context, q QueryerContext, dest any, query string, a
 // memory, so it is not suitable for loading large data dumps, but can be useful
 // for initializing schemas or loading indexes.
 //
+// Options make it run schema bootstrap scripts in one call:
+//
+//	_, err := squealx.LoadFileContext(ctx, db, "schema.sql",
+//		squealx.LoadInTx(),
+//		squealx.LoadVars(map[string]string{"SCHEMA": "app"}),
+//		squealx.LoadProgress(func(s squealx.LoadStatement) {
+//			log.Printf("%d/%d %s", s.Index+1, s.Total, s.Duration)
+//		}))
+//
+// With LoadInTx, LoadProgress or LoadContinueOnError, the file is split into
+// its statements, run one by one, and the result is that of the last one
+// succeeding; this also works for drivers not supporting multi-statement
+// Execs.
+//
 // FIXME: this does not really work with multi-statement files for mattn/go-sqlite3
 // or the go-mysql-driver/mysql drivers;  pq seems to be an exception here.  Detecting
 // this by requiring something with DriverName() and then attempting to split the
 // queries will be difficult to get right, and its current driver-specific behavior
 // is deemed at least not complex in its incorrectness.
-func LoadFileContext(ctx context.Context, e ExecerContext, path string) (*sql.Result, error) {
-	realpath, err := filepath.Abs(path)
-	if err != nil {
-		return nil, err
-	}
-	contents, err := os.ReadFile(realpath)
+func LoadFileContext(ctx context.Context, e ExecerContext, path string, opts ...LoadOption) (*sql.Result, error) {
+	contents, err := readLoadFile(path)
 	if err != nil {
 		return nil, err
 	}
-	res, err := e.ExecContext(ctx, string(contents))
-	return &res, err
+	return loadFile(ctx, e, contents, loadLoadOptions(opts...))
 }
 
 // MustExecContext execs the query using e and panics if there was an error.
@@ -127,28 +182,187 @@ func (db *DB) NamedQueryContext(ctx context.Context, query string, arg any) (*Ro
 }
 
 // NamedExecContext using this DB.
-// Any named placeholder parameters are replaced with fields from arg.
+// Any named placeholder parameters are replaced with fields from arg. A
+// slice of rows binding more parameters than the database allows is
+// inserted by several statements, run within a transaction.
 func (db *DB) NamedExecContext(ctx context.Context, query string, arg any) (sql.Result, error) {
 	query = SanitizeQuery(query, arg)
-	fn := func() (sql.Result, error) {
-		return NamedExecContext(ctx, db, query, arg)
+	fn := func(ctx context.Context) (sql.Result, error) {
+		q, args := prepareNamedInQuery(query, arg, db.Mapper)
+		bind := func(args any) (string, []any, error) {
+			return bindNamedMapper(BindType(db.driverName), q, args, db.Mapper)
+		}
+		if chunks := namedChunks(q, args, db.MaxBindParams()); chunks != nil {
+			return db.execChunksTx(ctx, chunks, bind)
+		}
+		q, params, err := bind(args)
+		if err != nil {
+			return nil, err
+		}
+		return db.execContext(ctx, q, params...)
 	}
-	return handleTwo[sql.Result](fn, db, context.Background(), query, arg)
+	return handleTwo[sql.Result](fn, db, ctx, query, arg)
+}
+
+// ExecContext executes a query without returning any rows, running the hooks
+// of db around it. The query is sent as written: unlike the query methods, @
+// is not read as a named placeholder, so that session variables such as
+// MySQL's @x, the @> operator and MSSQL @p1 placeholders reach the database.
+func (db *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	fn := func(ctx context.Context) (sql.Result, error) {
+		return db.execContext(ctx, query, args...)
+	}
+	return handleTwo[sql.Result](fn, db, ctx, query, args...)
+}
+
+// InExecContext executes a query without returning any rows for in.
+// The args are for any placeholder parameters in the query. A slice binding
+// more parameters than the database allows is split over several
+// statements, run within a transaction.
+func (db *DB) InExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	query = SanitizeQuery(query, args...)
+	fn := func(ctx context.Context) (sql.Result, error) {
+		if index, chunks := inChunks(args, db.MaxBindParams()); chunks != nil {
+			return db.execChunksTx(ctx, chunks, func(chunk any) (string, []any, error) {
+				chunkArgs := slices.Clone(args)
+				chunkArgs[index] = chunk
+				return db.In(query, chunkArgs...)
+			})
+		}
+		newQuery, params, err := db.In(query, args...)
+		if err != nil {
+			return nil, err
+		}
+		return db.execContext(ctx, newQuery, params...)
+	}
+	return handleTwo[sql.Result](fn, db, ctx, query, args...)
 }
 
 // SelectContext using this DB.
+// Named queries, map arguments and IN expansion are detected the same way as
+// in Select. When dest is not a pointer to a slice, the first row is scanned
+// into it, and a SELECT not already limited is limited to a single row, as
+// by Get.
 // Any placeholder parameters are replaced with supplied args.
-func (db *DB) SelectContext(ctx context.Context, dest any, query string, args ...any) error {
-	return SelectContext(ctx, db, dest, query, args...)
+func (db *DB) SelectContext(ctx context.Context, dest any, query string, arguments ...any) error {
+	args := selectArgs(arguments)
+	query = SanitizeQuery(query, args...)
+	t := reflect.TypeOf(dest)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return errors.New("must pass a pointer, not a value, to StructScan destination")
+	}
+
+	if t.Elem().Kind() != reflect.Slice {
+		if IsNamedQuery(query) && len(args) > 0 {
+			return db.NamedGetContext(ctx, dest, query, args[0])
+		}
+		if InReg.MatchString(query) {
+			return db.InGetContext(ctx, dest, query, args...)
+		}
+		return GetContext(ctx, db, dest, query, args...)
+	}
+	rows, err := db.selectRows(ctx, query, args)
+	if err != nil {
+		return err
+	}
+	// if something happens here, we want to make sure the rows are Closed
+	defer rows.Close()
+	return ScannAll(rows, dest, false)
+}
+
+// selectRows runs the query of a Select into a slice: with named arguments
+// from a map or struct, with IN expansion, or with positional arguments.
+func (db *DB) selectRows(ctx context.Context, query string, args []any) (*Rows, error) {
+	if IsNamedQuery(query) && len(args) > 0 {
+		return NamedQueryContext(ctx, db, query, args[0])
+	}
+	if InReg.MatchString(query) {
+		newQuery, params, err := db.In(query, args...)
+		if err != nil {
+			return nil, err
+		}
+		return db.QueryxContext(ctx, newQuery, params...)
+	}
+	return db.QueryxContext(ctx, query, args...)
+}
+
+// selectArgs drops a leading nil or empty map argument so that queries
+// without placeholders can be called with an empty parameter map.
+func selectArgs(arguments []any) []any {
+	if len(arguments) == 0 || arguments[0] == nil {
+		return nil
+	}
+	switch ag := arguments[0].(type) {
+	case map[string]any:
+		if len(ag) == 0 {
+			return nil
+		}
+	case map[string]string:
+		if len(ag) == 0 {
+			return nil
+		}
+	}
+	return arguments
 }
 
 // GetContext using this DB.
 // Any placeholder parameters are replaced with supplied args.
 // An error is returned if the result set is empty.
 func (db *DB) GetContext(ctx context.Context, dest any, query string, args ...any) error {
+	query = SanitizeQuery(query, args...)
+	if InReg.MatchString(query) {
+		return InGetContext(ctx, db, dest, query, args...)
+	}
 	return GetContext(ctx, db, dest, query, args...)
 }
 
+// NamedSelectContext using this DB.
+// Any named placeholder parameters are replaced with fields from arg.
+func (db *DB) NamedSelectContext(ctx context.Context, dest any, query string, arg any) error {
+	query = SanitizeQuery(query, arg)
+	if !IsNamedQuery(query) {
+		return db.SelectContext(ctx, dest, query, arg)
+	}
+	rows, err := NamedQueryContext(ctx, db, query, arg)
+	if err != nil {
+		return err
+	}
+	// if something happens here, we want to make sure the rows are Closed
+	defer rows.Close()
+	return ScannAll(rows, dest, false)
+}
+
+// NamedGetContext using this DB.
+// Any named placeholder parameters are replaced with fields from arg.
+// An error is returned if the result set is empty.
+func (db *DB) NamedGetContext(ctx context.Context, dest any, query string, arg any) error {
+	query = SanitizeQuery(query, arg)
+	if InReg.MatchString(query) {
+		query, arg = prepareNamedInQuery(query, arg, mapperFor(db))
+	}
+	q, p, err := bindNamedMapper(BindType(db.DriverName()), query, arg, mapperFor(db))
+	if err != nil {
+		return err
+	}
+	r := db.QueryRowxContext(ctx, limitOne(db, q), p...)
+	return r.scanAny(dest, false)
+}
+
+// InSelectContext using this DB but for in.
+// Any placeholder parameters are replaced with supplied args.
+func (db *DB) InSelectContext(ctx context.Context, dest any, query string, args ...any) error {
+	query = SanitizeQuery(query, args...)
+	return InSelectContext(ctx, db, dest, query, args...)
+}
+
+// InGetContext using this DB but for in.
+// Any placeholder parameters are replaced with supplied args.
+// An error is returned if the result set is empty.
+func (db *DB) InGetContext(ctx context.Context, dest any, query string, args ...any) error {
+	query = SanitizeQuery(query, args...)
+	return InGetContext(ctx, db, dest, query, args...)
+}
+
 // PreparexContext returns an sqlx.Stmt instead of a sql.Stmt.
 //
 // The provided context is used for the preparation of the statement, not for
@@ -160,27 +374,33 @@ func (db *DB) PreparexContext(ctx context.Context, query string) (*Stmt, error)
 // QueryxContext queries the database and returns an *sqlx.Rows.
 // Any placeholder parameters are replaced with supplied args.
 func (db *DB) QueryxContext(ctx context.Context, query string, args ...any) (*Rows, error) {
-	fn := func() (*Rows, error) {
-		query = SanitizeQuery(query, args...)
-		r, err := db.SQLDB.QueryContext(ctx, query, args...)
+	query = SanitizeQuery(query, args...)
+	fn := func(ctx context.Context) (*Rows, error) {
+		r, err := db.queryContext(ctx, query, args...)
 		if err != nil {
 			return nil, err
 		}
-		return &Rows{SQLRows: r, unsafe: db.unsafe, Mapper: db.Mapper}, err
+		return &Rows{SQLRows: r, unsafe: db.unsafe, Mapper: db.Mapper, table: db.statementTable(query), transformers: db.hooks.load().transformers, times: db.times, traversals: db.traversals}, err
 	}
-	return handleTwo[*Rows](fn, db, context.Background(), query, args...)
+	return handleTwo[*Rows](fn, db, ctx, query, args...)
 }
 
 // QueryRowxContext queries the database and returns an *sqlx.Row.
 // Any placeholder parameters are replaced with supplied args.
 func (db *DB) QueryRowxContext(ctx context.Context, query string, args ...any) *Row {
 	query = SanitizeQuery(query, args...)
-	fn := func() (*Row, error) {
-		rows, err := db.SQLDB.QueryContext(ctx, query, args...)
-		return &Row{rows: rows, err: err, unsafe: db.unsafe, Mapper: db.Mapper}, err
+	fn := func(ctx context.Context) (*Row, error) {
+		rows, err := db.queryContext(ctx, query, args...)
+		return &Row{rows: rows, err: err, unsafe: db.unsafe, Mapper: db.Mapper, table: db.statementTable(query), transformers: db.hooks.load().transformers, times: db.times, traversals: db.traversals}, err
+	}
+	row, err := handleTwo[*Row](fn, db, ctx, query, args...)
+	if row == nil {
+		return &Row{err: err}
+	}
+	if err != nil {
+		row.err = err
 	}
-	rows, _ := handleTwo[*Row](fn, db, context.Background(), query, args...)
-	return rows
+	return row
 }
 
 // TransactionTx txWrapper use sql.Tx
@@ -234,11 +454,14 @@ func (db *DB) MustBeginTx(ctx context.Context, opts *sql.TxOptions) *Tx {
 // Any placeholder parameters are replaced with supplied args.
 func (db *DB) MustExecContext(ctx context.Context, query string, args ...any) sql.Result {
 	query = SanitizeQuery(query, args...)
-	fn := func() (sql.Result, error) {
-		return MustExecContext(ctx, db, query, args...), nil
+	fn := func(ctx context.Context) (sql.Result, error) {
+		return db.execContext(ctx, query, args...)
 	}
-	rows, _ := handleTwo[sql.Result](fn, db, context.Background(), query, args...)
-	return rows
+	res, err := handleTwo[sql.Result](fn, db, ctx, query, args...)
+	if err != nil {
+		panic(err)
+	}
+	return res
 }
 
 // BeginTxx begins a transaction and returns an *sqlx.Tx instead of an
@@ -249,11 +472,7 @@ func (db *DB) MustExecContext(ctx context.Context, query string, args ...any) sq
 // transaction. Tx.Commit will return an error if the context provided to
 // BeginxContext is canceled.
 func (db *DB) BeginTxx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
-	tx, err := db.SQLDB.BeginTx(ctx, opts)
-	if err != nil {
-		return nil, err
-	}
-	return &Tx{SQLTx: tx, driverName: db.driverName, unsafe: db.unsafe, Mapper: db.Mapper}, err
+	return db.startTx(ctx, opts, nil)
 }
 
 // Connx returns an *sqlx.Conn instead of an *sql.Conn.
@@ -263,7 +482,7 @@ func (db *DB) Connx(ctx context.Context) (*Conn, error) {
 		return nil, err
 	}
 
-	return &Conn{SQLConn: conn, driverName: db.driverName, unsafe: db.unsafe, Mapper: db.Mapper}, nil
+	return &Conn{SQLConn: conn, driverName: db.driverName, unsafe: db.unsafe, Mapper: db.Mapper, db: db}, nil
 }
 
 // BeginTxx begins a transaction and returns an *sqlx.Tx instead of an
@@ -274,6 +493,9 @@ func (db *DB) Connx(ctx context.Context) (*Conn, error) {
 // transaction. Tx.Commit will return an error if the context provided to
 // BeginxContext is canceled.
 func (c *Conn) BeginTxx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	if c.db != nil {
+		return c.db.startTx(ctx, opts, c.SQLConn)
+	}
 	tx, err := c.SQLConn.BeginTx(ctx, opts)
 	if err != nil {
 		return nil, err
@@ -395,19 +617,54 @@ func (c *Conn) PreparexContext(ctx context.Context, query string) (*Stmt, error)
 // Any placeholder parameters are replaced with supplied args.
 func (c *Conn) QueryxContext(ctx context.Context, query string, args ...any) (*Rows, error) {
 	query = SanitizeQuery(query, args...)
-	r, err := c.SQLConn.QueryContext(ctx, query, args...)
+	r, err := c.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
-	return &Rows{SQLRows: r, unsafe: c.unsafe, Mapper: c.Mapper}, err
+	return &Rows{SQLRows: r, unsafe: c.unsafe, Mapper: c.Mapper, table: c.db.statementTable(query), transformers: c.db.rowTransformers(), times: c.db.timePolicy(), traversals: c.db.traversalCache()}, err
 }
 
 // QueryRowxContext queries the database and returns an *sqlx.Row.
 // Any placeholder parameters are replaced with supplied args.
 func (c *Conn) QueryRowxContext(ctx context.Context, query string, args ...any) *Row {
 	query = SanitizeQuery(query, args...)
-	rows, err := c.SQLConn.QueryContext(ctx, query, args...)
-	return &Row{rows: rows, err: err, unsafe: c.unsafe, Mapper: c.Mapper}
+	rows, err := c.QueryContext(ctx, query, args...)
+	return &Row{rows: rows, err: err, unsafe: c.unsafe, Mapper: c.Mapper, table: c.db.statementTable(query), transformers: c.db.rowTransformers(), times: c.db.timePolicy(), traversals: c.db.traversalCache()}
+}
+
+// QueryContext runs a query on the connection through the hooks of the DB
+// it was taken from.
+func (c *Conn) QueryContext(ctx context.Context, query string, args ...any) (SQLRows, error) {
+	return withHooks(c.db, ctx, func(ctx context.Context) (SQLRows, error) {
+		query, args, err := c.db.rewrite(ctx, query, args)
+		if err != nil {
+			return nil, err
+		}
+		return c.SQLConn.QueryContext(ctx, query, args...)
+	}, query, args...)
+}
+
+// QueryRowContext runs a query expected to return one row on the connection.
+func (c *Conn) QueryRowContext(ctx context.Context, query string, args ...any) SQLRow {
+	row, err := withHooks(c.db, ctx, func(ctx context.Context) (SQLRow, error) {
+		query, args, err := c.db.rewrite(ctx, query, args)
+		if err != nil {
+			return nil, err
+		}
+		return c.SQLConn.QueryRowContext(ctx, query, args...), nil
+	}, query, args...)
+	return rowOrErr(row, err)
+}
+
+// ExecContext runs a statement that returns no rows on the connection.
+func (c *Conn) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return withHooks(c.db, ctx, func(ctx context.Context) (sql.Result, error) {
+		query, args, err := c.db.rewrite(ctx, query, args)
+		if err != nil {
+			return nil, err
+		}
+		return c.SQLConn.ExecContext(ctx, query, args...)
+	}, query, args...)
 }
 
 // Rebind a query within a Conn's bindvar type.
@@ -419,17 +676,18 @@ func (c *Conn) Rebind(query string) string {
 // transaction. Provided stmt can be either *sql.Stmt or *sqlx.Stmt.
 func (tx *Tx) StmtxContext(ctx context.Context, stmt any) *Stmt {
 	var s SQLStmt
+	var query string
 	switch v := stmt.(type) {
 	case Stmt:
-		s = v.SQLStmt
+		s, query = v.SQLStmt, v.query
 	case *Stmt:
-		s = v.SQLStmt
+		s, query = v.SQLStmt, v.query
 	case *sql.Stmt:
 		s = &sqlStmtWrapper{stmt: v}
 	default:
 		panic(fmt.Sprintf("non-statement type %v passed to Stmtx", reflect.ValueOf(stmt).Type()))
 	}
-	return &Stmt{SQLStmt: tx.StmtContext(ctx, s), Mapper: tx.Mapper}
+	return &Stmt{SQLStmt: tx.StmtContext(ctx, s), Mapper: tx.Mapper, db: tx.hookDB(), tx: tx, query: query}
 }
 
 // NamedStmtContext returns a version of the prepared statement which runs
@@ -455,6 +713,44 @@ func (tx *Tx) PrepareNamedContext(ctx context.Context, query string) (*NamedStmt
 	return prepareNamedContext(ctx, tx, query)
 }
 
+// QueryContext runs a query within the transaction.
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...any) (SQLRows, error) {
+	tx.countStatement()
+	return withHooks(tx.hookDB(), tx.context(ctx), func(ctx context.Context) (SQLRows, error) {
+		query, args, err := tx.hookDB().rewrite(ctx, query, args)
+		if err != nil {
+			return nil, err
+		}
+		return tx.SQLTx.QueryContext(ctx, query, args...)
+	}, query, args...)
+}
+
+// QueryRowContext runs a query expected to return one row within the
+// transaction.
+func (tx *Tx) QueryRowContext(ctx context.Context, query string, args ...any) SQLRow {
+	tx.countStatement()
+	row, err := withHooks(tx.hookDB(), tx.context(ctx), func(ctx context.Context) (SQLRow, error) {
+		query, args, err := tx.hookDB().rewrite(ctx, query, args)
+		if err != nil {
+			return nil, err
+		}
+		return tx.SQLTx.QueryRowContext(ctx, query, args...), nil
+	}, query, args...)
+	return rowOrErr(row, err)
+}
+
+// ExecContext runs a statement that returns no rows within the transaction.
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	tx.countStatement()
+	return withHooks(tx.hookDB(), tx.context(ctx), func(ctx context.Context) (sql.Result, error) {
+		query, args, err := tx.hookDB().rewrite(ctx, query, args)
+		if err != nil {
+			return nil, err
+		}
+		return tx.SQLTx.ExecContext(ctx, query, args...)
+	}, query, args...)
+}
+
 // MustExecContext runs MustExecContext within a transaction.
 // Any placeholder parameters are replaced with supplied args.
 func (tx *Tx) MustExecContext(ctx context.Context, query string, args ...any) sql.Result {
@@ -466,11 +762,11 @@ func (tx *Tx) MustExecContext(ctx context.Context, query string, args ...any) sq
 // Any placeholder parameters are replaced with supplied args.
 func (tx *Tx) QueryxContext(ctx context.Context, query string, args ...any) (*Rows, error) {
 	query = SanitizeQuery(query, args...)
-	r, err := tx.SQLTx.QueryContext(ctx, query, args...)
+	r, err := tx.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
-	return &Rows{SQLRows: r, unsafe: tx.unsafe, Mapper: tx.Mapper}, err
+	return &Rows{SQLRows: r, unsafe: tx.unsafe, Mapper: tx.Mapper, table: tx.hookDB().statementTable(query), transformers: tx.hookDB().rowTransformers(), times: tx.hookDB().timePolicy(), traversals: tx.hookDB().traversalCache()}, err
 }
 
 // SelectContext within a transaction and context.
@@ -492,8 +788,8 @@ func (tx *Tx) GetContext(ctx context.Context, dest any, query string, args ...an
 // Any placeholder parameters are replaced with supplied args.
 func (tx *Tx) QueryRowxContext(ctx context.Context, query string, args ...any) *Row {
 	query = SanitizeQuery(query, args...)
-	rows, err := tx.SQLTx.QueryContext(ctx, query, args...)
-	return &Row{rows: rows, err: err, unsafe: tx.unsafe, Mapper: tx.Mapper}
+	rows, err := tx.QueryContext(ctx, query, args...)
+	return &Row{rows: rows, err: err, unsafe: tx.unsafe, Mapper: tx.Mapper, table: tx.hookDB().statementTable(query), transformers: tx.hookDB().rowTransformers(), times: tx.hookDB().timePolicy(), traversals: tx.hookDB().traversalCache()}
 }
 
 // NamedExecContext using this Tx.
@@ -503,6 +799,29 @@ func (tx *Tx) NamedExecContext(ctx context.Context, query string, arg any) (sql.
 	return NamedExecContext(ctx, tx, query, arg)
 }
 
+// InExecContext executes a query without returning any rows for in within
+// the transaction. A slice binding more parameters than the database allows
+// is split over several statements of the transaction.
+func (tx *Tx) InExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	query = SanitizeQuery(query, args...)
+	exec := func(ctx context.Context, args []any) (sql.Result, error) {
+		newQuery, params, err := tx.In(query, args...)
+		if err != nil {
+			return nil, err
+		}
+		return tx.ExecContext(ctx, newQuery, params...)
+	}
+	dialect := Dialect(tx.driverName)
+	if index, chunks := inChunks(args, MaxBindParams(dialect)); chunks != nil {
+		return execChunks(ctx, dialect, chunks, func(ctx context.Context, chunk any) (sql.Result, error) {
+			chunkArgs := slices.Clone(args)
+			chunkArgs[index] = chunk
+			return exec(ctx, chunkArgs)
+		})
+	}
+	return exec(ctx, args)
+}
+
 // SelectContext using the prepared statement.
 // Any placeholder parameters are replaced with supplied args.
 func (s *Stmt) SelectContext(ctx context.Context, dest any, args ...any) error {
@@ -537,9 +856,45 @@ func (s *Stmt) QueryxContext(ctx context.Context, args ...any) (*Rows, error) {
 	return qs.QueryxContext(ctx, "", args...)
 }
 
+// QueryContext executes the statement through the hooks of the DB it was
+// prepared on.
+func (s *Stmt) QueryContext(ctx context.Context, args ...any) (SQLRows, error) {
+	return withHooks(s.db, s.context(ctx), func(ctx context.Context) (SQLRows, error) {
+		args := s.db.timePolicy().bindArgs(args)
+		return s.SQLStmt.QueryContext(ctx, args...)
+	}, s.query, args...)
+}
+
+// QueryRowContext executes the statement, expecting at most one row.
+func (s *Stmt) QueryRowContext(ctx context.Context, args ...any) SQLRow {
+	row, err := withHooks(s.db, s.context(ctx), func(ctx context.Context) (SQLRow, error) {
+		args := s.db.timePolicy().bindArgs(args)
+		return s.SQLStmt.QueryRowContext(ctx, args...), nil
+	}, s.query, args...)
+	return rowOrErr(row, err)
+}
+
+// ExecContext executes the statement, returning no rows.
+func (s *Stmt) ExecContext(ctx context.Context, args ...any) (sql.Result, error) {
+	return withHooks(s.db, s.context(ctx), func(ctx context.Context) (sql.Result, error) {
+		args := s.db.timePolicy().bindArgs(args)
+		return s.SQLStmt.ExecContext(ctx, args...)
+	}, s.query, args...)
+}
+
+// context counts an execution of a statement bound to a transaction and
+// returns ctx carrying that transaction.
+func (s *Stmt) context(ctx context.Context) context.Context {
+	if s.tx == nil {
+		return ctx
+	}
+	s.tx.countStatement()
+	return s.tx.context(ctx)
+}
+
 func (q *qStmt) QueryContext(ctx context.Context, query string, args ...any) (SQLRows, error) {
 	query = SanitizeQuery(query, args...)
-	return q.SQLStmt.QueryContext(ctx, args...)
+	return q.Stmt.QueryContext(ctx, args...)
 }
 
 func (q *qStmt) QueryxContext(ctx context.Context, query string, args ...any) (*Rows, error) {
@@ -548,13 +903,13 @@ func (q *qStmt) QueryxContext(ctx context.Context, query string, args ...any) (*
 	if err != nil {
 		return nil, err
 	}
-	return &Rows{SQLRows: r, unsafe: q.Stmt.unsafe, Mapper: q.Stmt.Mapper}, err
+	return &Rows{SQLRows: r, unsafe: q.Stmt.unsafe, Mapper: q.Stmt.Mapper, table: q.Stmt.db.statementTable(q.Stmt.query), transformers: q.Stmt.db.rowTransformers(), times: q.Stmt.db.timePolicy(), traversals: q.Stmt.db.traversalCache()}, err
 }
 
 func (q *qStmt) QueryRowxContext(ctx context.Context, query string, args ...any) *Row {
 	query = SanitizeQuery(query, args...)
 	rows, err := q.Stmt.QueryContext(ctx, args...)
-	return &Row{rows: rows, err: err, unsafe: q.Stmt.unsafe, Mapper: q.Stmt.Mapper}
+	return &Row{rows: rows, err: err, unsafe: q.Stmt.unsafe, Mapper: q.Stmt.Mapper, table: q.Stmt.db.statementTable(q.Stmt.query), transformers: q.Stmt.db.rowTransformers(), times: q.Stmt.db.timePolicy(), traversals: q.Stmt.db.traversalCache()}
 }
 
 func (q *qStmt) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
@@ -0,0 +1,27 @@
+package squealx
+
+import "github.com/oarkflow/squealx/sqlparse"
+
+// StatementKind classifies a statement by its leading top-level keyword.
+type StatementKind string
+
+const (
+	StatementUnknown StatementKind = ""
+	StatementSelect  StatementKind = "SELECT"
+	StatementInsert  StatementKind = "INSERT"
+	StatementUpdate  StatementKind = "UPDATE"
+	StatementDelete  StatementKind = "DELETE"
+)
+
+// ClassifyStatement returns the kind of the statement and the primary table
+// it reads from or writes to. Common table expressions are skipped, so for
+// `WITH x AS (...) SELECT * FROM users` the table is "users". The table is
+// returned without schema qualifier or quotes, and is empty when it cannot
+// be determined.
+//
+// It is the kind and table of sqlparse.Parse, which tells more about the
+// statement.
+func ClassifyStatement(query string) (StatementKind, string) {
+	stmt := sqlparse.Parse(query)
+	return StatementKind(stmt.Kind), stmt.Table()
+}
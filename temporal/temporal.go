@@ -0,0 +1,264 @@
+// Package temporal keeps the full history of a table in a companion
+// <table>_history table, so rows can be read as they were at any point in
+// time.
+//
+// Every version of a row is stored with the period it was current in,
+// valid_from inclusive and valid_to exclusive; the current version has a NULL
+// valid_to. On PostgreSQL, MySQL and SQLite the history is maintained by
+// triggers created by Enable. Other databases have no triggers installed and
+// must call Table.Record after each change, in the same transaction.
+package temporal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/oarkflow/squealx"
+)
+
+// Operation is the kind of change passed to Table.Record.
+type Operation string
+
+const (
+	Insert Operation = "INSERT"
+	Update Operation = "UPDATE"
+	Delete Operation = "DELETE"
+)
+
+// Table describes a table with history.
+type Table struct {
+	Name       string
+	History    string
+	PrimaryKey string
+	Columns    []string
+	dialect    string
+}
+
+// Option configures Enable.
+type Option func(*Table)
+
+// WithPrimaryKey sets the primary key column, "id" by default.
+func WithPrimaryKey(column string) Option {
+	return func(t *Table) {
+		t.PrimaryKey = column
+	}
+}
+
+// WithHistoryTable sets the name of the history table.
+func WithHistoryTable(name string) Option {
+	return func(t *Table) {
+		t.History = name
+	}
+}
+
+// Enable creates the history table of table, seeds it with the current rows
+// when it is empty, and installs the triggers keeping it up to date. model
+// is a struct, or map, whose fields name the columns to track. Enable can be
+// run repeatedly, e.g. at every start.
+func Enable(ctx context.Context, db *squealx.DB, table string, model any, opts ...Option) (*Table, error) {
+	fields, err := db.GetFields(model)
+	if err != nil {
+		return nil, err
+	}
+	t := &Table{Name: table, History: table + "_history", PrimaryKey: "id", dialect: squealx.Dialect(db.DriverName())}
+	for _, opt := range opts {
+		opt(t)
+	}
+	for column := range fields {
+		t.Columns = append(t.Columns, column)
+	}
+	sort.Strings(t.Columns)
+	if _, ok := fields[t.PrimaryKey]; !ok {
+		return nil, fmt.Errorf("temporal: primary key %q is not a column of the model", t.PrimaryKey)
+	}
+	return t, db.WithTxx(ctx, nil, func(tx *squealx.Tx) error {
+		for _, stmt := range t.setup() {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// AsOf returns a query selecting columns of the rows of table as they were
+// at t, and its arguments, for use with Select or as a subquery. Without
+// columns, every column of the history table is selected, valid_from and
+// valid_to included.
+func AsOf(db *squealx.DB, table string, t time.Time, columns ...string) (string, []any) {
+	return (&Table{Name: table, History: table + "_history", Columns: columns, dialect: squealx.Dialect(db.DriverName())}).AsOf(t)
+}
+
+// SelectAsOf scans the rows of table as they were at t into dest. When dest
+// holds structs, only the columns of their fields are selected; maps receive
+// every column of the history table, valid_from and valid_to included.
+func SelectAsOf(ctx context.Context, db *squealx.DB, dest any, table string, t time.Time) error {
+	columns, err := modelColumns(db, dest)
+	if err != nil {
+		return err
+	}
+	query, args := AsOf(db, table, t, columns...)
+	return db.SelectContext(ctx, dest, db.Rebind(query), args...)
+}
+
+// modelColumns returns the columns of the struct dest points to, or of the
+// elements of the slice it points to, in order, as db maps them; none for
+// other types.
+func modelColumns(db *squealx.DB, dest any) ([]string, error) {
+	typ := reflect.TypeOf(dest)
+	for typ != nil && (typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice) {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct || typ.ConvertibleTo(reflect.TypeOf(time.Time{})) {
+		return nil, nil
+	}
+	fields, err := db.GetFields(reflect.New(typ).Interface())
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]string, 0, len(fields))
+	for column := range fields {
+		if column != "-" {
+			columns = append(columns, column)
+		}
+	}
+	sort.Strings(columns)
+	return columns, nil
+}
+
+// AsOf returns a query selecting the rows of the table as they were at at.
+func (t *Table) AsOf(at time.Time) (string, []any) {
+	columns := "*"
+	if len(t.Columns) > 0 {
+		columns = strings.Join(t.Columns, ", ")
+	}
+	ts := t.timeArg(at)
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE valid_from <= ? AND (valid_to IS NULL OR valid_to > ?)", columns, t.History)
+	return query, []any{ts, ts}
+}
+
+// Record writes the history of a change to row made by op. It is needed only
+// on databases without history triggers and must run in the transaction
+// making the change.
+func (t *Table) Record(ctx context.Context, tx *squealx.Tx, op Operation, row any) error {
+	if t.hasTriggers() {
+		return nil
+	}
+	fields, err := tx.GetFields(row)
+	if err != nil {
+		return err
+	}
+	key, ok := fields[t.PrimaryKey]
+	if !ok {
+		return errors.New("temporal: row has no primary key value")
+	}
+	now := t.timeArg(time.Now())
+	if op != Insert {
+		query := fmt.Sprintf("UPDATE %s SET valid_to = ? WHERE %s = ? AND valid_to IS NULL", t.History, t.PrimaryKey)
+		if _, err := tx.ExecContext(ctx, tx.Rebind(query), now, key); err != nil {
+			return err
+		}
+	}
+	if op == Delete {
+		return nil
+	}
+	args := make([]any, 0, len(t.Columns)+1)
+	for _, column := range t.Columns {
+		args = append(args, fields[column])
+	}
+	args = append(args, now)
+	query := fmt.Sprintf("INSERT INTO %s (%s, valid_from) VALUES (%s?)", t.History, strings.Join(t.Columns, ", "), strings.Repeat("?, ", len(t.Columns)))
+	_, err = tx.ExecContext(ctx, tx.Rebind(query), args...)
+	return err
+}
+
+func (t *Table) hasTriggers() bool {
+	switch t.dialect {
+	case squealx.DialectPostgres, squealx.DialectMySQL, squealx.DialectSQLite:
+		return true
+	}
+	return false
+}
+
+// timeArg converts at to the representation compared against the history
+// columns. SQLite stores the trigger timestamps as UTC text.
+func (t *Table) timeArg(at time.Time) any {
+	if t.dialect == squealx.DialectSQLite {
+		return at.UTC().Format("2006-01-02 15:04:05.000")
+	}
+	return at
+}
+
+// setup returns the statements creating the history table and triggers.
+func (t *Table) setup() []string {
+	cols := strings.Join(t.Columns, ", ")
+	newCols := "NEW." + strings.Join(t.Columns, ", NEW.")
+	var now, timeType string
+	switch t.dialect {
+	case squealx.DialectPostgres:
+		now, timeType = "clock_timestamp()", "timestamptz"
+	case squealx.DialectMySQL:
+		now, timeType = "NOW(6)", "DATETIME(6)"
+	case squealx.DialectSQLite:
+		now, timeType = "strftime('%Y-%m-%d %H:%M:%f', 'now')", "TEXT"
+	case squealx.DialectMSSQL:
+		now, timeType = "SYSDATETIMEOFFSET()", "DATETIMEOFFSET"
+	default:
+		now, timeType = "CURRENT_TIMESTAMP", "TIMESTAMP"
+	}
+	stmts := []string{
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s AS SELECT %s, CAST(NULL AS %s) AS valid_from, CAST(NULL AS %s) AS valid_to FROM %s WHERE 1 = 0",
+			t.History, cols, timeType, timeType, t.Name),
+		fmt.Sprintf("INSERT INTO %s (%s, valid_from) SELECT %s, %s FROM %s WHERE NOT EXISTS (SELECT 1 FROM %s)",
+			t.History, cols, cols, now, t.Name, t.History),
+	}
+	if t.dialect == squealx.DialectMSSQL {
+		stmts[0] = fmt.Sprintf("IF OBJECT_ID('%s') IS NULL SELECT %s, CAST(NULL AS %s) AS valid_from, CAST(NULL AS %s) AS valid_to INTO %s FROM %s WHERE 1 = 0",
+			t.History, cols, timeType, timeType, t.History, t.Name)
+	}
+	closeOld := fmt.Sprintf("UPDATE %s SET valid_to = %s WHERE %s = OLD.%s AND valid_to IS NULL", t.History, now, t.PrimaryKey, t.PrimaryKey)
+	insertNew := fmt.Sprintf("INSERT INTO %s (%s, valid_from) VALUES (%s, %s)", t.History, cols, newCols, now)
+	switch t.dialect {
+	case squealx.DialectPostgres:
+		fn := t.History + "_fn"
+		stmts = append(stmts,
+			fmt.Sprintf(`CREATE OR REPLACE FUNCTION %s() RETURNS trigger AS $$
+BEGIN
+	IF TG_OP IN ('UPDATE', 'DELETE') THEN
+		%s;
+	END IF;
+	IF TG_OP IN ('INSERT', 'UPDATE') THEN
+		%s;
+	END IF;
+	RETURN NULL;
+END
+$$ LANGUAGE plpgsql`, fn, closeOld, insertNew),
+			fmt.Sprintf("DROP TRIGGER IF EXISTS %s_trg ON %s", t.History, t.Name),
+			fmt.Sprintf("CREATE TRIGGER %s_trg AFTER INSERT OR UPDATE OR DELETE ON %s FOR EACH ROW EXECUTE FUNCTION %s()", t.History, t.Name, fn),
+		)
+	case squealx.DialectMySQL:
+		stmts = append(stmts,
+			fmt.Sprintf("DROP TRIGGER IF EXISTS %s_ai", t.History),
+			fmt.Sprintf("CREATE TRIGGER %s_ai AFTER INSERT ON %s FOR EACH ROW %s", t.History, t.Name, insertNew),
+			fmt.Sprintf("DROP TRIGGER IF EXISTS %s_au", t.History),
+			fmt.Sprintf("CREATE TRIGGER %s_au AFTER UPDATE ON %s FOR EACH ROW BEGIN %s; %s; END", t.History, t.Name, closeOld, insertNew),
+			fmt.Sprintf("DROP TRIGGER IF EXISTS %s_ad", t.History),
+			fmt.Sprintf("CREATE TRIGGER %s_ad AFTER DELETE ON %s FOR EACH ROW %s", t.History, t.Name, closeOld),
+		)
+	case squealx.DialectSQLite:
+		stmts = append(stmts,
+			fmt.Sprintf("DROP TRIGGER IF EXISTS %s_ai", t.History),
+			fmt.Sprintf("CREATE TRIGGER %s_ai AFTER INSERT ON %s BEGIN %s; END", t.History, t.Name, insertNew),
+			fmt.Sprintf("DROP TRIGGER IF EXISTS %s_au", t.History),
+			fmt.Sprintf("CREATE TRIGGER %s_au AFTER UPDATE ON %s BEGIN %s; %s; END", t.History, t.Name, closeOld, insertNew),
+			fmt.Sprintf("DROP TRIGGER IF EXISTS %s_ad", t.History),
+			fmt.Sprintf("CREATE TRIGGER %s_ad AFTER DELETE ON %s BEGIN %s; END", t.History, t.Name, closeOld),
+		)
+	}
+	return stmts
+}
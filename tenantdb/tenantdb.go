@@ -0,0 +1,388 @@
+// Package tenantdb routes database-per-tenant applications to the databases
+// of the tenant carried by the context. Each tenant is served by a
+// dbresolver.DBResolver, opened on first use and closed again when the
+// tenant stays idle or the number of open tenants exceeds a limit.
+package tenantdb
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/oarkflow/squealx"
+	"github.com/oarkflow/squealx/dbresolver"
+	"github.com/oarkflow/squealx/sqlctx"
+)
+
+var (
+	// ErrNoTenant is returned when the context carries no tenant.
+	ErrNoTenant = errors.New("tenantdb: no tenant in context")
+	// ErrUnknownTenant is returned for a tenant neither registered nor
+	// openable, as the Registry has no Opener.
+	ErrUnknownTenant = errors.New("tenantdb: unknown tenant")
+	// ErrTooManyTenants is returned when MaxOpen tenants are open and all of
+	// them are in use.
+	ErrTooManyTenants = errors.New("tenantdb: too many open tenants")
+	// ErrClosed is returned once the Registry is closed.
+	ErrClosed = errors.New("tenantdb: registry closed")
+	// ErrNoDeadline is returned by Resolver and DB for a tenant opened by
+	// the Opener and a context that is never done, which would keep the
+	// tenant in use for good.
+	ErrNoDeadline = errors.New("tenantdb: Resolver and DB need a context that is done eventually; use Acquire")
+)
+
+// WithTenant returns a context whose statements run through the databases
+// of tenant. It is sqlctx.WithTenant, so hooks and logging see the tenant
+// too.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return sqlctx.WithTenant(ctx, tenant)
+}
+
+// TenantFromContext returns the tenant attached by WithTenant.
+func TenantFromContext(ctx context.Context) (string, bool) {
+	return sqlctx.TenantFromContext(ctx)
+}
+
+// Opener opens the resolver of tenant, such as one built with dbresolver.New
+// on databases connected with a DSN looked up for the tenant.
+type Opener func(ctx context.Context, tenant string) (dbresolver.DBResolver, error)
+
+// Options configures a Registry.
+type Options struct {
+	// Open opens tenants on first use. Without it only registered tenants
+	// are served.
+	Open Opener
+	// MaxOpen bounds the tenants opened by Open at the same time. When it is
+	// reached the least recently used idle tenant is closed. Zero means no
+	// limit.
+	MaxOpen int
+	// IdleTimeout closes tenants opened by Open that have not been used for
+	// that long, checked every IdleTimeout until the Registry is closed.
+	// Zero keeps them open.
+	IdleTimeout time.Duration
+}
+
+// Registry maps tenants to their resolvers.
+type Registry struct {
+	opts    Options
+	mu      sync.Mutex
+	tenants map[string]*tenant
+	lru     *list.List
+	closed  bool
+	stop    chan struct{}
+	done    sync.WaitGroup
+}
+
+type tenant struct {
+	id         string
+	resolver   dbresolver.DBResolver
+	err        error
+	ready      chan struct{}
+	refs       int
+	lastUsed   time.Time
+	elem       *list.Element
+	registered bool
+}
+
+// New returns an empty Registry. With an IdleTimeout it closes idle tenants
+// in the background until Close is called.
+func New(opts Options) *Registry {
+	r := &Registry{opts: opts, tenants: make(map[string]*tenant), lru: list.New()}
+	if opts.IdleTimeout > 0 {
+		r.stop = make(chan struct{})
+		r.done.Add(1)
+		go r.sweep(opts.IdleTimeout, r.stop)
+	}
+	return r
+}
+
+// sweep closes the tenants idle for longer than IdleTimeout every interval
+// until stop is closed.
+func (r *Registry) sweep(interval time.Duration, stop <-chan struct{}) {
+	defer r.done.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			r.mu.Lock()
+			evicted := r.expired()
+			r.mu.Unlock()
+			_ = closeAll(evicted)
+		}
+	}
+}
+
+// Register serves tenant with resolver. Registered tenants are never
+// evicted; a tenant already open is replaced once it is no longer in use.
+func (r *Registry) Register(id string, resolver dbresolver.DBResolver) error {
+	ready := make(chan struct{})
+	close(ready)
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return ErrClosed
+	}
+	old := r.remove(id)
+	r.tenants[id] = &tenant{id: id, resolver: resolver, ready: ready, lastUsed: time.Now(), registered: true}
+	r.mu.Unlock()
+	return closeTenant(old)
+}
+
+// RegisterDB serves tenant with db alone.
+func (r *Registry) RegisterDB(id string, db *squealx.DB) error {
+	resolver, err := dbresolver.New(dbresolver.WithDefaultDB(db))
+	if err != nil {
+		return err
+	}
+	return r.Register(id, resolver)
+}
+
+// Acquire returns the resolver of the tenant of ctx, opening it if needed,
+// and keeps it from being evicted until release is called.
+func (r *Registry) Acquire(ctx context.Context) (resolver dbresolver.DBResolver, release func(), err error) {
+	id, ok := TenantFromContext(ctx)
+	if !ok {
+		return nil, nil, ErrNoTenant
+	}
+	return r.AcquireTenant(ctx, id)
+}
+
+// AcquireTenant is like Acquire, for the tenant id.
+func (r *Registry) AcquireTenant(ctx context.Context, id string) (dbresolver.DBResolver, func(), error) {
+	t, err := r.acquire(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	var once sync.Once
+	return t.resolver, func() { once.Do(func() { r.release(t) }) }, nil
+}
+
+// acquire returns the tenant id, opened and in use until released.
+func (r *Registry) acquire(ctx context.Context, id string) (*tenant, error) {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil, ErrClosed
+	}
+	evicted := r.expired()
+	t, ok := r.tenants[id]
+	opening := false
+	if !ok {
+		if r.opts.Open == nil {
+			r.mu.Unlock()
+			_ = closeAll(evicted)
+			return nil, ErrUnknownTenant
+		}
+		if r.opts.MaxOpen > 0 && r.lru.Len() >= r.opts.MaxOpen {
+			victim := r.idlest()
+			if victim == nil {
+				r.mu.Unlock()
+				_ = closeAll(evicted)
+				return nil, ErrTooManyTenants
+			}
+			evicted = append(evicted, r.remove(victim.id))
+		}
+		t = &tenant{id: id, ready: make(chan struct{})}
+		t.elem = r.lru.PushFront(t)
+		r.tenants[id] = t
+		opening = true
+	} else if t.elem != nil {
+		r.lru.MoveToFront(t.elem)
+	}
+	t.refs++
+	t.lastUsed = time.Now()
+	r.mu.Unlock()
+	_ = closeAll(evicted)
+
+	if opening {
+		t.resolver, t.err = r.opts.Open(ctx, id)
+		close(t.ready)
+		if t.err != nil {
+			r.mu.Lock()
+			if r.tenants[id] == t {
+				r.remove(id)
+			}
+			r.mu.Unlock()
+		}
+	} else {
+		select {
+		case <-t.ready:
+		case <-ctx.Done():
+			r.release(t)
+			return nil, ctx.Err()
+		}
+	}
+	if t.err != nil {
+		r.release(t)
+		return nil, t.err
+	}
+	return t, nil
+}
+
+// With runs fn with the resolver of the tenant of ctx, which stays open
+// until fn returns.
+func (r *Registry) With(ctx context.Context, fn func(resolver dbresolver.DBResolver) error) error {
+	resolver, release, err := r.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return fn(resolver)
+}
+
+// Resolver returns the resolver of the tenant of ctx, which stays in use
+// until ctx is done. For a context that is never done, a registered tenant
+// is returned as is and one opened by the Opener fails with ErrNoDeadline;
+// use Acquire to release it explicitly.
+func (r *Registry) Resolver(ctx context.Context) (dbresolver.DBResolver, error) {
+	id, ok := TenantFromContext(ctx)
+	if !ok {
+		return nil, ErrNoTenant
+	}
+	t, err := r.acquire(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if ctx.Done() == nil {
+		// Registered tenants are only closed when replaced or evicted
+		// explicitly, which is left to the caller.
+		r.release(t)
+		if !t.registered {
+			return nil, ErrNoDeadline
+		}
+		return t.resolver, nil
+	}
+	context.AfterFunc(ctx, func() { r.release(t) })
+	return t.resolver, nil
+}
+
+// DB returns the default database of the tenant of ctx, or its first
+// primary database when it has no default. Like Resolver, the tenant stays
+// in use until ctx is done.
+func (r *Registry) DB(ctx context.Context) (*squealx.DB, error) {
+	resolver, err := r.Resolver(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if db, err := resolver.UseDefault(); err == nil {
+		return db, nil
+	}
+	if masters := resolver.MasterDBs(); len(masters) > 0 {
+		return masters[0], nil
+	}
+	return nil, ErrUnknownTenant
+}
+
+// Evict closes the resolver of tenant once it is no longer in use,
+// registered or not. It is opened again on next use if the Registry has an
+// Opener.
+func (r *Registry) Evict(id string) error {
+	r.mu.Lock()
+	t := r.remove(id)
+	r.mu.Unlock()
+	return closeTenant(t)
+}
+
+// Len returns the number of open tenants, registered ones included.
+func (r *Registry) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.tenants)
+}
+
+// Close closes the resolvers of all tenants. Tenants in use are closed
+// when released.
+func (r *Registry) Close() error {
+	r.mu.Lock()
+	if r.stop != nil {
+		close(r.stop)
+		r.stop = nil
+	}
+	r.closed = true
+	var tenants []*tenant
+	for id := range r.tenants {
+		tenants = append(tenants, r.remove(id))
+	}
+	r.mu.Unlock()
+	r.done.Wait()
+	return closeAll(tenants)
+}
+
+// remove unregisters tenant id and returns it, to be closed outside the
+// lock, or nil if it is in use, in which case release closes it.
+func (r *Registry) remove(id string) *tenant {
+	t, ok := r.tenants[id]
+	if !ok {
+		return nil
+	}
+	delete(r.tenants, id)
+	if t.elem != nil {
+		r.lru.Remove(t.elem)
+		t.elem = nil
+	}
+	if t.refs > 0 {
+		return nil
+	}
+	return t
+}
+
+// release drops a reference to t, closing it if it was removed meanwhile.
+func (r *Registry) release(t *tenant) {
+	r.mu.Lock()
+	t.refs--
+	t.lastUsed = time.Now()
+	orphan := t.refs == 0 && r.tenants[t.id] != t
+	r.mu.Unlock()
+	if orphan {
+		_ = closeTenant(t)
+	}
+}
+
+// idlest returns the least recently used opened tenant not in use.
+func (r *Registry) idlest() *tenant {
+	for e := r.lru.Back(); e != nil; e = e.Prev() {
+		if t := e.Value.(*tenant); t.refs == 0 {
+			return t
+		}
+	}
+	return nil
+}
+
+// expired removes the opened tenants idle for longer than IdleTimeout.
+func (r *Registry) expired() []*tenant {
+	if r.opts.IdleTimeout <= 0 {
+		return nil
+	}
+	deadline := time.Now().Add(-r.opts.IdleTimeout)
+	var tenants []*tenant
+	for e := r.lru.Back(); e != nil; {
+		t := e.Value.(*tenant)
+		e = e.Prev()
+		if t.refs == 0 && t.lastUsed.Before(deadline) {
+			tenants = append(tenants, r.remove(t.id))
+		}
+	}
+	return tenants
+}
+
+func closeTenant(t *tenant) error {
+	if t == nil || t.resolver == nil {
+		return nil
+	}
+	return t.resolver.Close()
+}
+
+func closeAll(tenants []*tenant) error {
+	var errs []error
+	for _, t := range tenants {
+		if err := closeTenant(t); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,177 @@
+package squealx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimePolicy is how a DB handles time zones, set with WithTimePolicy.
+type TimePolicy struct {
+	// SessionZone is the time zone the sessions of the pool are set to on
+	// connect, with SET TIME ZONE on PostgreSQL and SET time_zone on MySQL,
+	// so that NOW() and zone-less columns agree across servers. SQLite and
+	// SQL Server have no session time zone and ignore it. Like WithConnInit,
+	// it is only honoured by Open and the functions built on it.
+	SessionZone *time.Location
+	// Location is the time zone DATETIME and TIMESTAMP values scanned into
+	// maps and slices are converted to. Values the driver returns as text
+	// without an offset are read in SessionZone, or UTC without one.
+	Location *time.Location
+	// UTC makes time.Time arguments bound in UTC, so that zone-less columns
+	// store the same wall clock for an instant whatever the zone of the
+	// caller.
+	UTC bool
+}
+
+// WithTimePolicy makes the DB handle time zones as policy says. Without
+// it, sessions keep the zone of the server, text DATETIME and TIMESTAMP
+// values are read in UTC and times are bound as given.
+func WithTimePolicy(policy TimePolicy) DBOption {
+	return func(o *dbOptions) {
+		o.timePolicy = &policy
+	}
+}
+
+// timeLayouts are the layouts DATETIME and TIMESTAMP values are read with
+// when the driver returns them as text, such as MySQL without parseTime
+// and SQLite. Fractional seconds are accepted by all of them.
+var timeLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	time.RFC3339,
+}
+
+// timestampTypes are the type names of the columns holding instants, as
+// opposed to dates and times of day, which are not converted.
+var timestampTypes = map[string]bool{
+	"DATETIME":       true,
+	"DATETIME2":      true,
+	"SMALLDATETIME":  true,
+	"DATETIMEOFFSET": true,
+	"TIMESTAMP":      true,
+	"TIMESTAMPTZ":    true,
+}
+
+// parseTime reads value, a DATETIME or TIMESTAMP returned as text, in the
+// session zone of p and converts it to its Location. Values with an offset
+// keep it.
+func (p *TimePolicy) parseTime(value string) (time.Time, error) {
+	zone := time.UTC
+	if p != nil && p.SessionZone != nil {
+		zone = p.SessionZone
+	}
+	var t time.Time
+	var err error
+	for _, layout := range timeLayouts {
+		if t, err = time.ParseInLocation(layout, value, zone); err == nil {
+			return p.convert(t), nil
+		}
+	}
+	return t, err
+}
+
+// convert returns t in the Location of p, if any.
+func (p *TimePolicy) convert(t time.Time) time.Time {
+	if p == nil || p.Location == nil {
+		return t
+	}
+	return t.In(p.Location)
+}
+
+// scanned converts v, scanned from the column idx of columnTypes, to the
+// Location of p when it is an instant.
+func (p *TimePolicy) scanned(columnTypes []*sql.ColumnType, idx int, v any) any {
+	t, ok := v.(time.Time)
+	if !ok || p == nil || idx >= len(columnTypes) || !timestampTypes[strings.ToUpper(columnTypes[idx].DatabaseTypeName())] {
+		return v
+	}
+	return p.convert(t)
+}
+
+// bindArgs returns args with their times in UTC when p says so. args is
+// copied before it is changed.
+func (p *TimePolicy) bindArgs(args []any) []any {
+	if p == nil || !p.UTC {
+		return args
+	}
+	copied := false
+	for i, arg := range args {
+		var t time.Time
+		switch arg := arg.(type) {
+		case time.Time:
+			t = arg
+		case *time.Time:
+			if arg == nil {
+				continue
+			}
+			t = *arg
+		default:
+			continue
+		}
+		if t.Location() == time.UTC {
+			continue
+		}
+		if !copied {
+			args = append([]any(nil), args...)
+			copied = true
+		}
+		args[i] = t.UTC()
+	}
+	return args
+}
+
+// sessionZoneInit returns init preceded by setting the session time zone
+// to zone, for the dialects which have one.
+func sessionZoneInit(driverName string, zone *time.Location, init func(ctx context.Context, conn SessionConn) error) func(ctx context.Context, conn SessionConn) error {
+	var query string
+	name := zone.String()
+	switch Dialect(driverName) {
+	case DialectPostgres:
+		query = "SET TIME ZONE " + quoteZone(name)
+	case DialectMySQL:
+		// Named zones need the time zone tables of the server, which UTC
+		// does not.
+		if name == "UTC" {
+			name = "+00:00"
+		}
+		query = "SET time_zone = " + quoteZone(name)
+	default:
+		return init
+	}
+	return func(ctx context.Context, conn SessionConn) error {
+		if err := conn.Exec(ctx, query); err != nil {
+			return fmt.Errorf("squealx: set session time zone %s: %w", zone, err)
+		}
+		if init == nil {
+			return nil
+		}
+		return init(ctx, conn)
+	}
+}
+
+func quoteZone(name string) string {
+	return "'" + strings.ReplaceAll(name, "'", "''") + "'"
+}
+
+// timePolicy returns the TimePolicy of db, nil when it has none.
+func (db *DB) timePolicy() *TimePolicy {
+	if db == nil {
+		return nil
+	}
+	return db.times
+}
+
+// rowsTimePolicy returns the TimePolicy of the DB rows were queried from.
+func rowsTimePolicy(rows any) *TimePolicy {
+	switch r := rows.(type) {
+	case *Rows:
+		return r.times
+	case *Row:
+		return r.times
+	}
+	return nil
+}
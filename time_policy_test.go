@@ -0,0 +1,73 @@
+package squealx_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oarkflow/squealx"
+	_ "modernc.org/sqlite"
+)
+
+func TestTimePolicyConnAndStmt(t *testing.T) {
+	zone := time.FixedZone("UTC+2", 2*60*60)
+	db, err := squealx.Connect("sqlite", ":memory:", "test", squealx.WithTimePolicy(squealx.TimePolicy{Location: zone, UTC: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	db.MustExec(`CREATE TABLE events (id INTEGER PRIMARY KEY, at TIMESTAMP)`)
+
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, zone)
+	stmt, err := db.Preparex(`INSERT INTO events (at) VALUES (?)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stmt.Exec(at); err != nil {
+		t.Fatal(err)
+	}
+	stmt.Close()
+	var stored string
+	if err := db.SQLDB.QueryRow(`SELECT CAST(at AS TEXT) FROM events`).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if want := at.UTC().Format("2006-01-02 15:04:05"); len(stored) < len(want) || stored[:len(want)] != want {
+		t.Errorf("prepared statement stored %s, want %s in UTC", stored, want)
+	}
+
+	db.MustExec(`DELETE FROM events`)
+	db.MustExec(`INSERT INTO events (at) VALUES ('2024-05-01 10:00:00')`)
+	check := func(name string, row map[string]any) {
+		t.Helper()
+		got, ok := row["at"].(time.Time)
+		if !ok {
+			t.Fatalf("%s: at = %T, want time.Time", name, row["at"])
+		}
+		if !got.Equal(at) || got.Location() != zone {
+			t.Errorf("%s: at = %v, want %v", name, got, at)
+		}
+	}
+	ctx := context.Background()
+	conn, err := db.Connx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var row map[string]any
+	if err := conn.GetContext(ctx, &row, `SELECT at FROM events`); err != nil {
+		t.Fatal(err)
+	}
+	check("conn", row)
+	conn.Close()
+
+	stmt, err = db.Preparex(`SELECT at FROM events`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	row = nil
+	if err := stmt.Get(&row); err != nil {
+		t.Fatal(err)
+	}
+	check("stmt", row)
+}
@@ -0,0 +1,85 @@
+package squealx
+
+import (
+	"container/list"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/oarkflow/squealx/reflectx"
+)
+
+// maxCachedTraversals is the number of column signatures a DB keeps the
+// struct traversals of. The least recently used one is dropped past it, so
+// queries built with ever changing column lists don't grow it unbounded.
+const maxCachedTraversals = 1024
+
+// traversalKey identifies the traversals of the columns of a result into a
+// struct type, as mapped by a Mapper.
+type traversalKey struct {
+	mapper  *reflectx.Mapper
+	t       reflect.Type
+	columns string
+}
+
+type traversalEntry struct {
+	key        traversalKey
+	traversals [][]int
+}
+
+// traversalCache is the LRU cache of the struct traversals of a DB, which
+// saves mapping the columns of every result scanned into structs.
+type traversalCache struct {
+	mu      sync.Mutex
+	entries map[traversalKey]*list.Element
+	lru     *list.List
+}
+
+func newTraversalCache() *traversalCache {
+	return &traversalCache{entries: make(map[traversalKey]*list.Element), lru: list.New()}
+}
+
+// traversals returns m.TraversalsByName(t, columns), cached unless c is
+// nil. The slices are shared and must not be modified.
+func (c *traversalCache) traversals(m *reflectx.Mapper, t reflect.Type, columns []string) [][]int {
+	if c == nil {
+		return m.TraversalsByName(t, columns)
+	}
+	key := traversalKey{mapper: m, t: t, columns: strings.Join(columns, "\x00")}
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(e)
+		c.mu.Unlock()
+		return e.Value.(*traversalEntry).traversals
+	}
+	c.mu.Unlock()
+	traversals := m.TraversalsByName(t, columns)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok {
+		c.entries[key] = c.lru.PushFront(&traversalEntry{key: key, traversals: traversals})
+		if c.lru.Len() > maxCachedTraversals {
+			oldest := c.lru.Back()
+			c.lru.Remove(oldest)
+			delete(c.entries, oldest.Value.(*traversalEntry).key)
+		}
+	}
+	return traversals
+}
+
+// traversalCache returns the traversal cache of db, nil if db is nil.
+func (db *DB) traversalCache() *traversalCache {
+	if db == nil {
+		return nil
+	}
+	return db.traversals
+}
+
+// rowsTraversalCache returns the traversal cache of the DB rows were
+// queried from, nil for rows of another kind.
+func rowsTraversalCache(rows Rowsi) *traversalCache {
+	if r, ok := rows.(*Rows); ok {
+		return r.traversals
+	}
+	return nil
+}
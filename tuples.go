@@ -0,0 +1,211 @@
+package squealx
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/oarkflow/squealx/reflectx"
+)
+
+var valuerType = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+
+// tupleIn matches a row-value IN predicate on a single bindVar, such as
+// `(a, b) IN (?)`, capturing its columns and whether it is negated.
+var tupleIn = regexp.MustCompile(`(?i)\(\s*([\w."\[\]` + "`" + `]+(?:\s*,\s*[\w."\[\]` + "`" + `]+)+)\s*\)\s+(NOT\s+)?IN\s*\(\s*\?\s*\)`)
+
+// InTuples returns the predicate matching columns, such as "(a, b)", against
+// the row values of tuples, a slice of arrays or slices such as [][2]any,
+// along with its args. The predicate uses the `?` bindVar.
+//
+//	pred, args, err := squealx.InTuples("(work_item_id, encounter_id)", keys)
+//	// (work_item_id, encounter_id) IN ((?, ?), (?, ?))
+func InTuples(columns string, tuples any) (string, []any, error) {
+	return inTuples(DialectUnknown, columns, tuples)
+}
+
+// InTuples is like the package function InTuples, but falls back to a chain
+// of ORed equalities on databases without row-value IN, such as SQL Server.
+func (db *DB) InTuples(columns string, tuples any) (string, []any, error) {
+	return inTuples(Dialect(db.driverName), columns, tuples)
+}
+
+func inTuples(dialect, columns string, tuples any) (string, []any, error) {
+	cols := splitColumns(columns)
+	v := reflect.ValueOf(tuples)
+	if !isTupleSlice(v) {
+		return "", nil, fmt.Errorf("squealx: %T is not a slice of tuples", tuples)
+	}
+	var buf strings.Builder
+	var args []any
+	var err error
+	if dialect == DialectMSSQL {
+		args, err = appendTupleEqualities(&buf, nil, cols, false, v)
+	} else {
+		buf.WriteString("(" + strings.Join(cols, ", ") + ") IN (")
+		args, err = appendTuples(&buf, nil, v)
+		buf.WriteString(")")
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	if len(args) != len(cols)*reflect.Indirect(v).Len() {
+		return "", nil, fmt.Errorf("squealx: tuples do not have %d values", len(cols))
+	}
+	return buf.String(), args, nil
+}
+
+// splitColumns returns the columns of a column tuple, with or without its
+// parentheses.
+func splitColumns(columns string) []string {
+	columns = strings.TrimSpace(columns)
+	columns = strings.TrimSuffix(strings.TrimPrefix(columns, "("), ")")
+	cols := strings.Split(columns, ",")
+	for i := range cols {
+		cols[i] = strings.TrimSpace(cols[i])
+	}
+	return cols
+}
+
+// isTupleSlice reports whether v is a slice whose elements are themselves
+// arrays or slices, other than driver values such as a UUID and bytes,
+// whether []byte, a defined type such as json.RawMessage or an array such
+// as [16]byte.
+func isTupleSlice(v reflect.Value) bool {
+	if !v.IsValid() {
+		return false
+	}
+	t := reflectx.Deref(v.Type())
+	if t.Kind() != reflect.Slice {
+		return false
+	}
+	elem := t.Elem()
+	if elem.Implements(valuerType) {
+		return false
+	}
+	switch elem.Kind() {
+	case reflect.Array, reflect.Slice:
+		return elem.Elem().Kind() != reflect.Uint8
+	}
+	return false
+}
+
+// tupleValues returns the values of the tuple at index i of v.
+func tupleValues(v reflect.Value, i int) []any {
+	tuple := v.Index(i)
+	values := make([]any, tuple.Len())
+	for j := range values {
+		values[j] = tuple.Index(j).Interface()
+	}
+	return values
+}
+
+// appendTuples writes the row values of the tuples of v to buf, as in
+// "(?, ?), (?, ?)", and appends their values to args.
+func appendTuples(buf *strings.Builder, args []any, v reflect.Value) ([]any, error) {
+	v = reflect.Indirect(v)
+	if v.Len() == 0 {
+		return nil, errors.New("empty slice passed to 'in' query")
+	}
+	width := -1
+	for i := 0; i < v.Len(); i++ {
+		values := tupleValues(v, i)
+		if width == -1 {
+			width = len(values)
+		}
+		if len(values) == 0 || len(values) != width {
+			return nil, errors.New("tuples passed to 'in' query differ in length")
+		}
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString("(" + strings.TrimSuffix(strings.Repeat("?, ", width), ", ") + ")")
+		args = append(args, values...)
+	}
+	return args, nil
+}
+
+// appendTupleEqualities writes the tuples of v matched against columns as a
+// chain of ORed equalities to buf, as in "((a = ? AND b = ?) OR (a = ? AND
+// b = ?))", negated when not is set, and appends their values to args.
+func appendTupleEqualities(buf *strings.Builder, args []any, columns []string, not bool, v reflect.Value) ([]any, error) {
+	v = reflect.Indirect(v)
+	if v.Len() == 0 {
+		return nil, errors.New("empty slice passed to 'in' query")
+	}
+	if not {
+		buf.WriteString("NOT ")
+	}
+	buf.WriteString("(")
+	for i := 0; i < v.Len(); i++ {
+		values := tupleValues(v, i)
+		if len(values) != len(columns) {
+			return nil, fmt.Errorf("tuple of %d values passed for %d columns", len(values), len(columns))
+		}
+		if i > 0 {
+			buf.WriteString(" OR ")
+		}
+		buf.WriteString("(")
+		for j, column := range columns {
+			if j > 0 {
+				buf.WriteString(" AND ")
+			}
+			buf.WriteString(column + " = ?")
+		}
+		buf.WriteString(")")
+		args = append(args, values...)
+	}
+	buf.WriteString(")")
+	return args, nil
+}
+
+// rewriteTupleIn rewrites the row-value IN predicates of query bound to a
+// slice of tuples, such as `(a, b) IN (?)`, into chains of ORed equalities
+// for databases without row-value IN. Other args are left for In to expand.
+func rewriteTupleIn(query string, args []any) (string, []any, error) {
+	matches := tupleIn.FindAllStringSubmatchIndex(query, -1)
+	if len(matches) == 0 {
+		return query, args, nil
+	}
+	var buf strings.Builder
+	newArgs := make([]any, 0, len(args))
+	var last, arg int
+	for pos := 0; pos < len(query); pos++ {
+		if query[pos] != '?' {
+			continue
+		}
+		if arg >= len(args) {
+			return "", nil, errors.New("number of bindVars exceeds arguments")
+		}
+		a := args[arg]
+		arg++
+		v := reflect.ValueOf(a)
+		if !isTupleSlice(v) {
+			newArgs = append(newArgs, a)
+			continue
+		}
+		var m []int
+		for _, match := range matches {
+			if match[0] <= pos && pos < match[1] {
+				m = match
+				break
+			}
+		}
+		if m == nil {
+			newArgs = append(newArgs, a)
+			continue
+		}
+		buf.WriteString(query[last:m[0]])
+		var err error
+		newArgs, err = appendTupleEqualities(&buf, newArgs, splitColumns(query[m[2]:m[3]]), m[4] != -1, v)
+		if err != nil {
+			return "", nil, err
+		}
+		last, pos = m[1], m[1]-1
+	}
+	buf.WriteString(query[last:])
+	return buf.String(), append(newArgs, args[arg:]...), nil
+}
@@ -0,0 +1,216 @@
+package squealx
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/oarkflow/squealx/sqlctx"
+)
+
+// TxInfo describes a transaction for lifecycle hooks. Duration is zero on
+// begin; Statements counts the statements run through the Tx so far.
+type TxInfo = sqlctx.TxInfo
+
+// TxBeginHook is notified after a transaction has been started.
+type TxBeginHook interface {
+	OnTxBegin(ctx context.Context, info TxInfo)
+}
+
+// TxCommitHook is notified after a transaction commit, with its result.
+type TxCommitHook interface {
+	OnTxCommit(ctx context.Context, info TxInfo, err error)
+}
+
+// TxRollbackHook is notified after a transaction rollback, with its result.
+// It is not called for the Rollback that usually follows a deferred Commit.
+type TxRollbackHook interface {
+	OnTxRollback(ctx context.Context, info TxInfo, err error)
+}
+
+var txCounter atomic.Uint64
+
+// txState is shared by a Tx and the copies returned by Tx.Unsafe.
+type txState struct {
+	db         *DB
+	ctx        context.Context
+	id         uint64
+	opts       *sql.TxOptions
+	started    time.Time
+	statements atomic.Int64
+	finished   atomic.Bool
+	// conn is the connection the transaction runs on when its session
+	// needs a reset, taken from the pool for it when ownConn is set.
+	conn      SQLConn
+	ownConn   bool
+	reset     []string
+	resetDone atomic.Bool
+	// afterCommit holds the functions to run once the transaction commits.
+	mu          sync.Mutex
+	afterCommit []func()
+}
+
+// newTx wraps tx and runs the begin hooks of db.
+func (db *DB) newTx(ctx context.Context, tx SQLTx, opts *sql.TxOptions) *Tx {
+	state := &txState{db: db, ctx: ctx, id: txCounter.Add(1), opts: opts, started: time.Now()}
+	t := &Tx{SQLTx: tx, driverName: db.driverName, unsafe: db.unsafe, Mapper: db.Mapper, state: state}
+	info := t.Info()
+	db.runTxHooks(ctx, func(hook any) {
+		if h, ok := hook.(TxBeginHook); ok {
+			h.OnTxBegin(ctx, info)
+		}
+	})
+	return t
+}
+
+// runTxHooks calls fn with every transaction hook not disabled for ctx.
+// Lifecycle hooks only observe, so panics are reported and skipped.
+func (db *DB) runTxHooks(ctx context.Context, fn func(hook any)) {
+	for _, entry := range db.hooks.load().tx {
+		if hookSkipped(ctx, entry.name) {
+			continue
+		}
+		_, err := callHook(HookPhaseAfter, func() (context.Context, error) {
+			fn(entry.hook)
+			return nil, nil
+		})
+		if err != nil {
+			db.skipHookPanic(err, "")
+		}
+	}
+}
+
+// Info returns the lifecycle information of the transaction. It is empty for
+// transactions not started through a DB.
+func (tx *Tx) Info() TxInfo {
+	if tx.state == nil {
+		return TxInfo{}
+	}
+	return TxInfo{
+		ID:         tx.state.id,
+		Options:    tx.state.opts,
+		Started:    tx.state.started,
+		Duration:   time.Since(tx.state.started),
+		Statements: tx.state.statements.Load(),
+	}
+}
+
+// countStatement records a statement run through the transaction.
+func (tx *Tx) countStatement() {
+	if tx.state != nil {
+		tx.state.statements.Add(1)
+	}
+}
+
+// Commit commits the transaction and runs the commit hooks.
+func (tx *Tx) Commit() error {
+	err := tx.SQLTx.Commit()
+	tx.endSession()
+	if err == nil {
+		tx.runAfterCommit()
+	}
+	tx.finish(err, func(hook any, info TxInfo) {
+		if h, ok := hook.(TxCommitHook); ok {
+			h.OnTxCommit(tx.state.ctx, info, err)
+		}
+	})
+	return err
+}
+
+// onCommit registers fn to run once the transaction commits. It is dropped
+// on rollback, and run at once for transactions not started through a DB.
+func (tx *Tx) onCommit(fn func()) {
+	if tx.state == nil {
+		fn()
+		return
+	}
+	tx.state.mu.Lock()
+	defer tx.state.mu.Unlock()
+	tx.state.afterCommit = append(tx.state.afterCommit, fn)
+}
+
+// runAfterCommit runs, once, the functions registered by onCommit.
+func (tx *Tx) runAfterCommit() {
+	if tx.state == nil {
+		return
+	}
+	tx.state.mu.Lock()
+	fns := tx.state.afterCommit
+	tx.state.afterCommit = nil
+	tx.state.mu.Unlock()
+	for _, fn := range fns {
+		fn()
+	}
+}
+
+// Rollback aborts the transaction and runs the rollback hooks.
+func (tx *Tx) Rollback() error {
+	err := tx.SQLTx.Rollback()
+	tx.endSession()
+	tx.finish(err, func(hook any, info TxInfo) {
+		if h, ok := hook.(TxRollbackHook); ok {
+			h.OnTxRollback(tx.state.ctx, info, err)
+		}
+	})
+	return err
+}
+
+// finish runs fn over the transaction hooks the first time the transaction
+// ends. Calls returning sql.ErrTxDone, such as the Rollback deferred after a
+// Commit, are not reported.
+func (tx *Tx) finish(err error, fn func(hook any, info TxInfo)) {
+	if tx.state == nil || len(tx.state.db.hooks.load().tx) == 0 {
+		return
+	}
+	if err == sql.ErrTxDone || !tx.state.finished.CompareAndSwap(false, true) {
+		return
+	}
+	info := tx.Info()
+	tx.state.db.runTxHooks(tx.state.ctx, func(hook any) {
+		fn(hook, info)
+	})
+}
+
+type txContextKey struct{}
+
+// TxFromContext returns the transaction a statement hook is running for, so
+// hooks can group statements by transaction.
+func TxFromContext(ctx context.Context) (TxInfo, bool) {
+	tx, ok := ctx.Value(txContextKey{}).(*Tx)
+	if !ok {
+		return TxInfo{}, false
+	}
+	return tx.Info(), true
+}
+
+// context returns ctx carrying tx for TxFromContext and its TxInfo for
+// sqlctx.TxInfoFromContext.
+func (tx *Tx) context(ctx context.Context) context.Context {
+	if tx.state == nil {
+		return ctx
+	}
+	return sqlctx.WithTxInfo(context.WithValue(ctx, txContextKey{}, tx), tx.Info())
+}
+
+// ContextWithTx returns ctx carrying tx, so that the repository operations
+// given ctx run in it, as FirstForUpdate requires. Paginate and PaginateRaw,
+// whose queries run concurrently, return ErrPaginateInTx instead.
+func ContextWithTx(ctx context.Context, tx *Tx) context.Context {
+	return context.WithValue(ctx, txContextKey{}, tx)
+}
+
+// txFromContext returns the transaction carried by ctx, if any.
+func txFromContext(ctx context.Context) *Tx {
+	tx, _ := ctx.Value(txContextKey{}).(*Tx)
+	return tx
+}
+
+// hookDB returns the DB whose hooks apply to statements of the transaction.
+func (tx *Tx) hookDB() *DB {
+	if tx.state == nil {
+		return nil
+	}
+	return tx.state.db
+}
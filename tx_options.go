@@ -0,0 +1,200 @@
+package squealx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// WithSerializable runs fn in a SERIALIZABLE transaction. Combine with
+// WithTxRetry when serialization failures are expected.
+func (db *DB) WithSerializable(ctx context.Context, fn func(tx *Tx) error) error {
+	return db.WithTxx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable}, fn)
+}
+
+// WithRepeatableRead runs fn in a REPEATABLE READ transaction.
+func (db *DB) WithRepeatableRead(ctx context.Context, fn func(tx *Tx) error) error {
+	return db.WithTxx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead}, fn)
+}
+
+// WithReadOnlyTx runs fn in a read-only transaction. On SQLite, whose driver
+// accepts but does not enforce read-only transactions, the connection is
+// switched to query_only for the duration of the transaction. SQL Server has
+// no read-only transactions, so there the transaction is a regular one.
+func (db *DB) WithReadOnlyTx(ctx context.Context, fn func(tx *Tx) error) error {
+	return db.WithTxx(ctx, &sql.TxOptions{ReadOnly: true}, fn)
+}
+
+// txSession adapts opts to the driver of db and returns the statements to run
+// at the start of the transaction and before it ends, including the
+// TxSettings attached to ctx.
+func (db *DB) txSession(ctx context.Context, opts *sql.TxOptions) (*sql.TxOptions, []string, []string) {
+	dialect := Dialect(db.driverName)
+	var setup, reset []string
+	if opts != nil && opts.ReadOnly {
+		switch dialect {
+		case DialectSQLite:
+			setup = append(setup, "PRAGMA query_only = ON")
+			reset = append(reset, "PRAGMA query_only = OFF")
+		case DialectMSSQL:
+			adapted := *opts
+			adapted.ReadOnly = false
+			opts = &adapted
+		}
+	}
+	if settings, ok := TxSettingsFromContext(ctx); ok {
+		s, r := settings.statements(dialect)
+		setup = append(setup, s...)
+		reset = append(reset, r...)
+	}
+	return opts, setup, reset
+}
+
+// startTx begins a transaction on conn, or on the pool of db when conn is
+// nil, adapting opts to the driver and applying the session settings they
+// require. A transaction changing its session beyond its own end runs on a
+// connection taken from the pool for it, so that the session can be reset
+// once the transaction is over, whether it committed, rolled back or was
+// rolled back by database/sql when its context was cancelled.
+func (db *DB) startTx(ctx context.Context, opts *sql.TxOptions, conn SQLConn) (*Tx, error) {
+	driverOpts, setup, reset := db.txSession(ctx, opts)
+	ownConn := false
+	if conn == nil && db.txResets(ctx, reset) {
+		var err error
+		if conn, err = db.SQLDB.Conn(ctx); err != nil {
+			return nil, err
+		}
+		ownConn = true
+	}
+	var tx SQLTx
+	var err error
+	if conn != nil {
+		tx, err = conn.BeginTx(ctx, driverOpts)
+	} else {
+		tx, err = db.SQLDB.BeginTx(ctx, driverOpts)
+	}
+	if err != nil {
+		if ownConn {
+			_ = conn.Close()
+		}
+		return nil, err
+	}
+	t := db.newTx(ctx, tx, opts)
+	if t.state != nil {
+		t.state.conn, t.state.ownConn = conn, ownConn
+	}
+	if err := t.session(ctx, setup, reset); err != nil {
+		_ = t.Rollback()
+		return nil, err
+	}
+	if err := t.txSchema(ctx); err != nil {
+		_ = t.Rollback()
+		return nil, err
+	}
+	return t, nil
+}
+
+// txResets reports whether a transaction with the context ctx leaves
+// session state to reset: the reset statements of its options and settings,
+// or the default database a MySQL transaction switches to for WithSchema.
+func (db *DB) txResets(ctx context.Context, reset []string) bool {
+	if len(reset) > 0 {
+		return true
+	}
+	_, ok := SchemaFromContext(ctx)
+	return ok && Dialect(db.driverName) == DialectMySQL
+}
+
+// session runs setup within the transaction and keeps reset to be run once
+// it has ended, so session state does not leak to the pooled connection.
+func (tx *Tx) session(ctx context.Context, setup, reset []string) error {
+	if tx.state != nil {
+		tx.state.reset = reset
+	}
+	for _, stmt := range setup {
+		if _, err := tx.SQLTx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// endSession runs the reset statements on the connection of the ended
+// transaction, once. A connection whose session cannot be reset is closed
+// rather than returned to the pool, and a connection taken for the
+// transaction is released.
+func (tx *Tx) endSession() {
+	state := tx.state
+	if state == nil || state.conn == nil || !state.resetDone.CompareAndSwap(false, true) {
+		return
+	}
+	for _, stmt := range state.reset {
+		if _, err := state.conn.ExecContext(context.Background(), stmt); err != nil {
+			discardConn(state.conn)
+			break
+		}
+	}
+	if state.ownConn {
+		_ = state.conn.Close()
+	}
+}
+
+// TxSettings are session limits applied at the start of a transaction and
+// reset when it ends. Zero fields are left at the server defaults. On
+// PostgreSQL they are local to the transaction; on MySQL and SQL Server
+// they are session settings, reset on the connection of the transaction
+// once it has ended, which is closed instead when the reset fails.
+//
+// StatementTimeout maps to statement_timeout on PostgreSQL and
+// max_execution_time (SELECT only) on MySQL. LockTimeout maps to
+// lock_timeout on PostgreSQL, innodb_lock_wait_timeout on MySQL (whole
+// seconds, at least one) and LOCK_TIMEOUT on SQL Server.
+type TxSettings struct {
+	StatementTimeout time.Duration
+	LockTimeout      time.Duration
+}
+
+type txSettingsKey struct{}
+
+// WithTxSettings returns a context whose transactions, begun with BeginTxx,
+// WithTxx and the helpers built on them, apply settings.
+func WithTxSettings(ctx context.Context, settings TxSettings) context.Context {
+	return context.WithValue(ctx, txSettingsKey{}, settings)
+}
+
+// TxSettingsFromContext returns the settings attached by WithTxSettings.
+func TxSettingsFromContext(ctx context.Context) (TxSettings, bool) {
+	settings, ok := ctx.Value(txSettingsKey{}).(TxSettings)
+	return settings, ok
+}
+
+// statements returns the statements applying s on dialect and the ones
+// restoring the defaults, run after the transaction. PostgreSQL settings use
+// SET LOCAL and need no reset.
+func (s TxSettings) statements(dialect string) (setup, reset []string) {
+	switch dialect {
+	case DialectPostgres:
+		if s.StatementTimeout > 0 {
+			setup = append(setup, fmt.Sprintf("SET LOCAL statement_timeout = %d", s.StatementTimeout.Milliseconds()))
+		}
+		if s.LockTimeout > 0 {
+			setup = append(setup, fmt.Sprintf("SET LOCAL lock_timeout = %d", s.LockTimeout.Milliseconds()))
+		}
+	case DialectMySQL:
+		if s.StatementTimeout > 0 {
+			setup = append(setup, fmt.Sprintf("SET SESSION max_execution_time = %d", s.StatementTimeout.Milliseconds()))
+			reset = append(reset, "SET SESSION max_execution_time = DEFAULT")
+		}
+		if s.LockTimeout > 0 {
+			setup = append(setup, fmt.Sprintf("SET SESSION innodb_lock_wait_timeout = %d", max(int64(s.LockTimeout/time.Second), 1)))
+			reset = append(reset, "SET SESSION innodb_lock_wait_timeout = DEFAULT")
+		}
+	case DialectMSSQL:
+		if s.LockTimeout > 0 {
+			setup = append(setup, fmt.Sprintf("SET LOCK_TIMEOUT %d", s.LockTimeout.Milliseconds()))
+			reset = append(reset, "SET LOCK_TIMEOUT -1")
+		}
+	}
+	return setup, reset
+}
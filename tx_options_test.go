@@ -0,0 +1,58 @@
+package squealx_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/oarkflow/squealx"
+)
+
+func TestReadOnlyTxResetsSession(t *testing.T) {
+	db := openTestDB(t, `CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT)`)
+	write := func() {
+		t.Helper()
+		if _, err := db.ExecContext(context.Background(), "INSERT INTO notes (body) VALUES ('x')"); err != nil {
+			t.Fatalf("write after read-only transaction: %v", err)
+		}
+	}
+
+	err := db.WithReadOnlyTx(context.Background(), func(tx *squealx.Tx) error {
+		_, err := tx.ExecContext(context.Background(), "INSERT INTO notes (body) VALUES ('x')")
+		return err
+	})
+	if err == nil {
+		t.Fatal("write within read-only transaction succeeded")
+	}
+	write()
+
+	// A transaction whose context is cancelled is rolled back by
+	// database/sql before Rollback is called.
+	ctx, cancel := context.WithCancel(context.Background())
+	tx, err := db.BeginTxx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, err := tx.ExecContext(context.Background(), "SELECT 1")
+		if errors.Is(err, sql.ErrTxDone) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("cancelled transaction: %v, want %v", err, sql.ErrTxDone)
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("cancelled transaction was not rolled back within 5s")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := tx.Commit(); err == nil {
+		t.Fatal("Commit of a cancelled transaction succeeded")
+	}
+	_ = tx.Rollback()
+	write()
+}
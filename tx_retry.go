@@ -0,0 +1,63 @@
+package squealx
+
+import (
+	"context"
+	"database/sql"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy controls how WithTxRetry retries a transaction. Zero fields
+// take the defaults: 3 attempts, 10ms initial backoff doubling up to 1s, and
+// IsRetryable to decide which errors are retried.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	Retryable      func(err error) bool
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = 3
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = 10 * time.Millisecond
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = time.Second
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = 2
+	}
+	if p.Retryable == nil {
+		p.Retryable = IsRetryable
+	}
+	return p
+}
+
+// WithTxRetry runs fn in a transaction like WithTxx, and runs the whole
+// transaction again when it fails with a serialization failure, deadlock or
+// lock timeout. fn may therefore be called several times and must not have
+// side effects outside the transaction. Between attempts it waits with
+// exponential backoff and jitter, giving up early when ctx is done.
+func (db *DB) WithTxRetry(ctx context.Context, opts *sql.TxOptions, fn func(tx *Tx) error, policy RetryPolicy) error {
+	policy = policy.withDefaults()
+	backoff := policy.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := db.WithTxx(ctx, opts, fn)
+		if err == nil || attempt >= policy.MaxAttempts || !policy.Retryable(err) {
+			return err
+		}
+		wait := backoff/2 + rand.N(backoff/2+1)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff = min(time.Duration(float64(backoff)*policy.Multiplier), policy.MaxBackoff)
+	}
+}
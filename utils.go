@@ -4,10 +4,14 @@ import (
 	"fmt"
 	"regexp"
 	"runtime"
+	"slices"
 	"strings"
 	"unicode"
 
 	"github.com/oarkflow/jet"
+
+	"github.com/oarkflow/squealx/sqlparse"
+	"github.com/oarkflow/squealx/sqltoken"
 )
 
 // Contains appends '%' on both sides of the input string
@@ -48,6 +52,69 @@ func LimitQuery(query string) string {
 	return strings.TrimSpace(query) + " LIMIT 1"
 }
 
+var (
+	aggregateReg = regexp.MustCompile(`(?i)\b(count|sum|avg|min|max|group_concat|string_agg|array_agg|json_agg)\s*\(`)
+	limitedReg   = regexp.MustCompile(`(?i)\b(limit|offset|fetch\s+(first|next)|top)\b`)
+	// lockingReadReg matches the locking clauses of SELECT statements.
+	lockingReadReg = regexp.MustCompile(`(?i)\bFOR\s+(?:NO\s+KEY\s+)?(?:KEY\s+)?(?:UPDATE|SHARE)\b|\bLOCK\s+IN\s+SHARE\s+MODE\b`)
+	selectReg      = regexp.MustCompile(`(?i)^\s*select\s+((distinct|all)\s+)?`)
+)
+
+// IsLockingRead reports whether query has a locking clause, such as
+// FOR UPDATE, FOR NO KEY UPDATE, FOR KEY SHARE or LOCK IN SHARE MODE.
+func IsLockingRead(query string) bool {
+	return lockingReadReg.MatchString(query)
+}
+
+// limitOne restricts a SELECT read by Get to a single row, so the database
+// can stop at the first one. Queries already limited, locking, or
+// aggregating without GROUP BY are returned unchanged, as are queries of
+// unknown dialects and, on SQL Server, whose TOP only limits the first
+// SELECT, compound queries. LIMIT goes before a trailing semicolon or
+// comment.
+func limitOne(q any, query string) string {
+	d, ok := q.(interface{ DriverName() string })
+	if !ok {
+		return query
+	}
+	dialect := Dialect(d.DriverName())
+	loc := selectReg.FindStringIndex(query)
+	if dialect == DialectUnknown || loc == nil || limitedReg.MatchString(query) || IsLockingRead(query) {
+		return query
+	}
+	lowerQuery := strings.ToLower(query)
+	if aggregateReg.MatchString(query) && !strings.Contains(lowerQuery, "group by") {
+		return query
+	}
+	tokens := sqlparse.Tokens(Statement{Query: query, Dialect: dialect}.Tokens())
+	end := len(tokens) - 1
+	for end >= 0 && tokens[end].Type == sqltoken.Semicolon {
+		end--
+	}
+	if end < 0 {
+		return query
+	}
+	if dialect == DialectMSSQL {
+		if compound(tokens) {
+			return query
+		}
+		return query[:loc[1]] + "TOP 1 " + query[loc[1]:]
+	}
+	offset := tokens[end].Offset + len(tokens[end].Text)
+	return query[:offset] + " LIMIT 1" + query[offset:]
+}
+
+// compound reports whether the query made of tokens combines queries with
+// UNION, INTERSECT or EXCEPT.
+func compound(tokens []sqlparse.Token) bool {
+	for _, t := range tokens {
+		if t.Depth == 0 && t.Type == sqltoken.Word && (t.Upper == "UNION" || t.Upper == "INTERSECT" || t.Upper == "EXCEPT") {
+			return true
+		}
+	}
+	return false
+}
+
 // WithReturning appends or replaces "LIMIT 1" in the SQL query.
 func WithReturning(query string) string {
 	lowerQuery := strings.ToLower(query)
@@ -58,6 +125,57 @@ func WithReturning(query string) string {
 	return strings.TrimSpace(query) + " RETURNING *"
 }
 
+// WithOutput adds "OUTPUT INSERTED.*", or "OUTPUT DELETED.*" for a DELETE,
+// to an INSERT, UPDATE or DELETE statement of SQL Server, which returns the
+// written rows with an OUTPUT clause rather than RETURNING. The clause goes
+// before VALUES or SELECT in an INSERT, before FROM or WHERE following the
+// SET of an UPDATE and before the WHERE of a DELETE. Statements with an
+// OUTPUT clause are returned unchanged.
+func WithOutput(query string) string {
+	query = strings.TrimRight(strings.TrimSpace(query), ";")
+	kind, _ := ClassifyStatement(query)
+	var before []string
+	output := " OUTPUT INSERTED.*"
+	switch kind {
+	case StatementInsert:
+		before = []string{"VALUES", "SELECT", "DEFAULT", "EXEC", "EXECUTE"}
+	case StatementUpdate:
+		before = []string{"FROM", "WHERE"}
+	case StatementDelete:
+		before = []string{"WHERE"}
+		output = " OUTPUT DELETED.*"
+	default:
+		return query
+	}
+	depth, pos, seenSet := 0, 0, false
+	for _, token := range sqltoken.Tokenize(query, sqltoken.SQLServerConfig()) {
+		start := pos
+		pos += len(token.Text)
+		switch token.Type {
+		case sqltoken.Punctuation:
+			depth += strings.Count(token.Text, "(") - strings.Count(token.Text, ")")
+			continue
+		case sqltoken.Word:
+		default:
+			continue
+		}
+		if depth != 0 {
+			continue
+		}
+		word := strings.ToUpper(token.Text)
+		switch {
+		case word == "OUTPUT":
+			return query
+		case word == "SET":
+			seenSet = true
+		case kind == StatementUpdate && !seenSet:
+		case slices.Contains(before, word):
+			return strings.TrimRight(query[:start], " \t\r\n") + output + " " + query[start:]
+		}
+	}
+	return query + output
+}
+
 // ReplacePlaceholders safely replaces placeholders (e.g., @work_item_id) with :work_item_id in an SQL query.
 // It skips replacements inside strings and comments.
 func ReplacePlaceholders(query string) string {
@@ -136,6 +254,10 @@ func ReplacePlaceholders(query string) string {
 			} else if query[i] == '[' {
 				inBracket = true
 				result.WriteByte(query[i])
+			} else if i+1 < len(query) && query[i] == '@' && query[i+1] == '@' {
+				// Keep system variables such as `@@innodb_page_size` as they are.
+				result.WriteString("@@")
+				i++
 			} else if query[i] == '@' {
 				// Replace `@` with `:` and retain the placeholder name.
 				result.WriteByte(':')
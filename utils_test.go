@@ -0,0 +1,48 @@
+package squealx
+
+import "testing"
+
+// namedDriver is a queryer of the named driver.
+type namedDriver string
+
+func (d namedDriver) DriverName() string { return string(d) }
+
+func TestLimitOne(t *testing.T) {
+	cases := []struct {
+		name   string
+		driver string
+		query  string
+		want   string
+	}{
+		{"select", "postgres", "SELECT * FROM t WHERE a = $1", "SELECT * FROM t WHERE a = $1 LIMIT 1"},
+		{"trailing semicolon", "postgres", "SELECT * FROM t;", "SELECT * FROM t LIMIT 1;"},
+		{"trailing line comment", "mysql", "SELECT * FROM t -- newest first\n", "SELECT * FROM t LIMIT 1 -- newest first\n"},
+		{"trailing block comment", "sqlite", "SELECT * FROM t /* lookup */ ;", "SELECT * FROM t LIMIT 1 /* lookup */ ;"},
+		{"common table expression", "postgres", "WITH r AS (SELECT * FROM t) SELECT * FROM r", "WITH r AS (SELECT * FROM t) SELECT * FROM r"},
+		{"union", "postgres", "SELECT a FROM t UNION SELECT a FROM u ORDER BY a", "SELECT a FROM t UNION SELECT a FROM u ORDER BY a LIMIT 1"},
+		{"already limited", "mysql", "SELECT * FROM t LIMIT 5", "SELECT * FROM t LIMIT 5"},
+		{"offset", "postgres", "SELECT * FROM t OFFSET 2", "SELECT * FROM t OFFSET 2"},
+		{"locking read", "postgres", "SELECT * FROM t WHERE id = $1 FOR UPDATE", "SELECT * FROM t WHERE id = $1 FOR UPDATE"},
+		{"shared locking read", "mysql", "SELECT * FROM t LOCK IN SHARE MODE", "SELECT * FROM t LOCK IN SHARE MODE"},
+		{"aggregate", "postgres", "SELECT count(*) FROM t", "SELECT count(*) FROM t"},
+		{"grouped aggregate", "postgres", "SELECT a, count(*) FROM t GROUP BY a", "SELECT a, count(*) FROM t GROUP BY a LIMIT 1"},
+		{"not a select", "postgres", "UPDATE t SET a = 1", "UPDATE t SET a = 1"},
+		{"unknown dialect", "oracle", "SELECT * FROM t", "SELECT * FROM t"},
+		{"sqlserver", "sqlserver", "SELECT * FROM t ORDER BY a", "SELECT TOP 1 * FROM t ORDER BY a"},
+		{"sqlserver distinct", "sqlserver", "SELECT DISTINCT a FROM t", "SELECT DISTINCT TOP 1 a FROM t"},
+		{"sqlserver top", "sqlserver", "SELECT TOP 5 * FROM t", "SELECT TOP 5 * FROM t"},
+		{"sqlserver union", "sqlserver", "SELECT a FROM t\nUNION\nSELECT a FROM u", "SELECT a FROM t\nUNION\nSELECT a FROM u"},
+		{"sqlserver except", "sqlserver", "SELECT a FROM t EXCEPT SELECT a FROM u", "SELECT a FROM t EXCEPT SELECT a FROM u"},
+		{"sqlserver union in subquery", "sqlserver", "SELECT * FROM (SELECT a FROM t UNION SELECT a FROM u) x", "SELECT TOP 1 * FROM (SELECT a FROM t UNION SELECT a FROM u) x"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := limitOne(namedDriver(c.driver), c.query); got != c.want {
+				t.Errorf("limitOne(%s, %q) = %q, want %q", c.driver, c.query, got, c.want)
+			}
+		})
+	}
+	if got := limitOne(nil, "SELECT * FROM t"); got != "SELECT * FROM t" {
+		t.Errorf("limitOne without driver = %q, want the query unchanged", got)
+	}
+}
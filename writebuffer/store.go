@@ -0,0 +1,265 @@
+package writebuffer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/oarkflow/squealx"
+)
+
+// MemoryStore keeps the queued writes in memory, losing them on restart.
+type MemoryStore struct {
+	mu     sync.Mutex
+	writes []Write
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+func (s *MemoryStore) Push(_ context.Context, w Write) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writes = append(s.writes, w)
+	return nil
+}
+
+func (s *MemoryStore) Peek(_ context.Context) (Write, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.writes) == 0 {
+		return Write{}, false, nil
+	}
+	return s.writes[0], true, nil
+}
+
+func (s *MemoryStore) Pop(_ context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.writes) > 0 {
+		s.writes[0] = Write{}
+		s.writes = s.writes[1:]
+	}
+	return nil
+}
+
+func (s *MemoryStore) Len(_ context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.writes), nil
+}
+
+// FileStore keeps the queued writes in memory and persists them to a file
+// as JSON lines, rewriting it atomically on every change. Arguments are
+// stored as JSON, so they must be strings, numbers, booleans, nil or values
+// encoding to those.
+type FileStore struct {
+	mem  MemoryStore
+	path string
+}
+
+// OpenFileStore returns a FileStore persisting to path, loading the writes
+// already queued in it.
+func OpenFileStore(path string) (*FileStore, error) {
+	s := &FileStore{path: path}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 16<<20)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		w, err := decodeWrite(scanner.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("writebuffer: %s: %w", path, err)
+		}
+		s.mem.writes = append(s.mem.writes, w)
+	}
+	return s, scanner.Err()
+}
+
+func (s *FileStore) Push(_ context.Context, w Write) error {
+	s.mem.mu.Lock()
+	defer s.mem.mu.Unlock()
+	// The full slice expression makes append copy, leaving the queue as it
+	// is when the file cannot be saved.
+	writes := append(s.mem.writes[:len(s.mem.writes):len(s.mem.writes)], w)
+	if err := s.save(writes); err != nil {
+		return err
+	}
+	s.mem.writes = writes
+	return nil
+}
+
+func (s *FileStore) Peek(ctx context.Context) (Write, bool, error) {
+	return s.mem.Peek(ctx)
+}
+
+func (s *FileStore) Pop(_ context.Context) error {
+	s.mem.mu.Lock()
+	defer s.mem.mu.Unlock()
+	if len(s.mem.writes) == 0 {
+		return nil
+	}
+	if err := s.save(s.mem.writes[1:]); err != nil {
+		return err
+	}
+	s.mem.writes[0] = Write{}
+	s.mem.writes = s.mem.writes[1:]
+	return nil
+}
+
+func (s *FileStore) Len(ctx context.Context) (int, error) {
+	return s.mem.Len(ctx)
+}
+
+// save replaces the file with writes. The in-memory queue is only changed
+// once it succeeds, so that the two never disagree. s.mem.mu must be held.
+func (s *FileStore) save(writes []Write) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, w := range writes {
+		if err := enc.Encode(w); err != nil {
+			return err
+		}
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// SQLiteStore persists the queued writes in a table of a local SQLite
+// database, opened by the caller with any SQLite driver. Arguments are
+// stored as JSON, like in a FileStore.
+type SQLiteStore struct {
+	db    *squealx.DB
+	table string
+}
+
+// OpenSQLiteStore returns a SQLiteStore keeping the writes in table of db,
+// creating the table if needed.
+func OpenSQLiteStore(ctx context.Context, db *squealx.DB, table string) (*SQLiteStore, error) {
+	_, err := db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	seq INTEGER PRIMARY KEY AUTOINCREMENT,
+	id TEXT NOT NULL,
+	query TEXT NOT NULL,
+	args TEXT NOT NULL,
+	queued_at TIMESTAMP NOT NULL
+)`, table))
+	if err != nil {
+		return nil, err
+	}
+	return &SQLiteStore{db: db, table: table}, nil
+}
+
+func (s *SQLiteStore) Push(ctx context.Context, w Write) error {
+	args, err := json.Marshal(w.Args)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s (id, query, args, queued_at) VALUES (?, ?, ?, ?)", s.table),
+		w.ID, w.Query, string(args), w.QueuedAt.UTC())
+	return err
+}
+
+func (s *SQLiteStore) Peek(ctx context.Context) (Write, bool, error) {
+	var row struct {
+		ID       string    `db:"id"`
+		Query    string    `db:"query"`
+		Args     string    `db:"args"`
+		QueuedAt time.Time `db:"queued_at"`
+	}
+	err := s.db.QueryRowxContext(ctx, fmt.Sprintf("SELECT id, query, args, queued_at FROM %s ORDER BY seq LIMIT 1", s.table)).StructScan(&row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Write{}, false, nil
+	}
+	if err != nil {
+		return Write{}, false, err
+	}
+	args, err := decodeArgs([]byte(row.Args))
+	if err != nil {
+		return Write{}, false, err
+	}
+	return Write{ID: row.ID, Query: row.Query, Args: args, QueuedAt: row.QueuedAt}, true, nil
+}
+
+func (s *SQLiteStore) Pop(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE seq = (SELECT MIN(seq) FROM %s)", s.table, s.table))
+	return err
+}
+
+func (s *SQLiteStore) Len(ctx context.Context) (int, error) {
+	var n int
+	err := s.db.QueryRowxContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", s.table)).Scan(&n)
+	return n, err
+}
+
+func decodeWrite(data []byte) (Write, error) {
+	var w struct {
+		Write
+		Args json.RawMessage `json:"args"`
+	}
+	if err := json.Unmarshal(data, &w); err != nil {
+		return Write{}, err
+	}
+	args, err := decodeArgs(w.Args)
+	if err != nil {
+		return Write{}, err
+	}
+	w.Write.Args = args
+	return w.Write, nil
+}
+
+// decodeArgs decodes JSON encoded arguments, turning integral numbers back
+// into int64 rather than float64.
+func decodeArgs(data []byte) ([]any, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var args []any
+	if err := dec.Decode(&args); err != nil {
+		return nil, err
+	}
+	for i, arg := range args {
+		if n, ok := arg.(json.Number); ok {
+			if v, err := n.Int64(); err == nil {
+				args[i] = v
+			} else if v, err := n.Float64(); err == nil {
+				args[i] = v
+			}
+		}
+	}
+	return args, nil
+}
@@ -0,0 +1,47 @@
+package writebuffer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileStoreSaveFailure(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	s, err := OpenFileStore(filepath.Join(dir, "queue.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Push(ctx, Write{ID: "1", Query: "DELETE FROM a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Saving fails once the directory of the file is gone.
+	s.path = filepath.Join(dir, "missing", "queue.jsonl")
+	if err := s.Push(ctx, Write{ID: "2", Query: "DELETE FROM b"}); err == nil {
+		t.Fatal("Push succeeded without saving")
+	}
+	if n, _ := s.Len(ctx); n != 1 {
+		t.Fatalf("Len = %d after failed Push, want 1", n)
+	}
+	if err := s.Pop(ctx); err == nil {
+		t.Fatal("Pop succeeded without saving")
+	}
+	if n, _ := s.Len(ctx); n != 1 {
+		t.Fatalf("Len = %d after failed Pop, want 1", n)
+	}
+
+	s.path = filepath.Join(dir, "queue.jsonl")
+	reopened, err := OpenFileStore(s.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w, ok, err := reopened.Peek(ctx); err != nil || !ok || w.ID != "1" {
+		t.Errorf("Peek = %v, %t, %v, want write 1", w, ok, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "missing")); !os.IsNotExist(err) {
+		t.Errorf("missing directory was created: %v", err)
+	}
+}
@@ -0,0 +1,178 @@
+// Package writebuffer queues idempotent writes while the database is
+// unreachable and replays them once it is back, for edge and IoT deployments
+// which must keep accepting writes through network outages.
+//
+// Only writes which are safe to apply late and more than once should be
+// buffered, such as upserts keyed by a client generated identifier: a write
+// whose connection is lost after the database applied it is queued and
+// replayed all the same.
+package writebuffer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/oarkflow/squealx"
+)
+
+// ErrBufferFull is returned by Exec when the database is unreachable and the
+// queue already holds Options.MaxSize writes.
+var ErrBufferFull = errors.New("writebuffer: buffer is full")
+
+// Write is a queued statement.
+type Write struct {
+	// ID identifies the write for conflict callbacks, such as the key of the
+	// row it upserts.
+	ID       string    `json:"id"`
+	Query    string    `json:"query"`
+	Args     []any     `json:"args"`
+	QueuedAt time.Time `json:"queued_at"`
+}
+
+// Store persists the queued writes in order. Implementations must be safe
+// for use by a single Buffer, which serializes its calls.
+type Store interface {
+	// Push appends w to the queue.
+	Push(ctx context.Context, w Write) error
+	// Peek returns the oldest write, reporting false when the queue is empty.
+	Peek(ctx context.Context) (Write, bool, error)
+	// Pop removes the oldest write.
+	Pop(ctx context.Context) error
+	// Len returns the number of queued writes.
+	Len(ctx context.Context) (int, error)
+}
+
+// Options configures a Buffer.
+type Options struct {
+	// Store holds the queued writes, a MemoryStore by default. Use a
+	// FileStore or SQLiteStore for writes surviving restarts.
+	Store Store
+	// MaxSize bounds the number of queued writes, 10000 by default.
+	MaxSize int
+	// IsOffline reports whether an error means the database is unreachable,
+	// squealx.IsConnectionError by default.
+	IsOffline func(err error) bool
+	// OnConflict is called when a replayed write fails with an error other
+	// than the database being unreachable. Returning nil drops the write;
+	// returning an error stops the replay, leaving the write queued. By
+	// default the write is dropped.
+	OnConflict func(ctx context.Context, w Write, err error) error
+}
+
+// Buffer executes writes against a database, queuing them while the
+// database is unreachable. A Buffer is safe for concurrent use.
+type Buffer struct {
+	db   *squealx.DB
+	opts Options
+	mu   sync.Mutex
+}
+
+// New returns a Buffer writing to db.
+func New(db *squealx.DB, opts Options) *Buffer {
+	if opts.Store == nil {
+		opts.Store = NewMemoryStore()
+	}
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = 10000
+	}
+	if opts.IsOffline == nil {
+		opts.IsOffline = squealx.IsConnectionError
+	}
+	return &Buffer{db: db, opts: opts}
+}
+
+// Exec executes the idempotent write query identified by id. When the
+// database is unreachable, or older writes are still queued, the write is
+// queued instead and Exec reports queued as true with a nil error.
+func (b *Buffer) Exec(ctx context.Context, id, query string, args ...any) (queued bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	pending, err := b.opts.Store.Len(ctx)
+	if err != nil {
+		return false, err
+	}
+	if pending > 0 {
+		// Writes are replayed in order, so a new write may not overtake
+		// queued ones.
+		if _, err := b.flush(ctx); err != nil {
+			return false, err
+		}
+		if pending, err = b.opts.Store.Len(ctx); err != nil {
+			return false, err
+		}
+	}
+	if pending == 0 {
+		_, err := b.db.ExecContext(ctx, query, args...)
+		if err == nil || !b.opts.IsOffline(err) {
+			return false, err
+		}
+	}
+	if pending >= b.opts.MaxSize {
+		return false, ErrBufferFull
+	}
+	w := Write{ID: id, Query: query, Args: args, QueuedAt: time.Now()}
+	if err := b.opts.Store.Push(ctx, w); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Len returns the number of queued writes.
+func (b *Buffer) Len(ctx context.Context) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.opts.Store.Len(ctx)
+}
+
+// Flush replays the queued writes in order and returns the number replayed.
+// It stops without error when the database is still unreachable.
+func (b *Buffer) Flush(ctx context.Context) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.flush(ctx)
+}
+
+func (b *Buffer) flush(ctx context.Context) (int, error) {
+	n := 0
+	for {
+		w, ok, err := b.opts.Store.Peek(ctx)
+		if err != nil || !ok {
+			return n, err
+		}
+		if _, err := b.db.ExecContext(ctx, w.Query, w.Args...); err != nil {
+			if b.opts.IsOffline(err) {
+				return n, nil
+			}
+			if b.opts.OnConflict != nil {
+				if err := b.opts.OnConflict(ctx, w, err); err != nil {
+					return n, err
+				}
+			}
+		} else {
+			n++
+		}
+		if err := b.opts.Store.Pop(ctx); err != nil {
+			return n, err
+		}
+	}
+}
+
+// Run flushes the queue every interval until ctx is done, replaying the
+// queued writes once the database is reachable again. Flush errors are
+// passed to onError, which may be nil.
+func (b *Buffer) Run(ctx context.Context, interval time.Duration, onError func(err error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := b.Flush(ctx); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}